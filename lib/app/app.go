@@ -80,8 +80,10 @@ type Applications interface {
 
 	// CreateAppWithManifest creates a new application from the specified package bytes (reader)
 	// and an optional set of package labels using locator as destination for the
-	// resulting package, with supplied manifest
-	CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string) (*Application, error)
+	// resulting package, with supplied manifest.
+	// The package bytes are stored unmodified so package signatures provided with
+	// options remain valid
+	CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string, options ...pack.PackageOption) (*Application, error)
 
 	// UpsertApp creates a new application or updates the existing one
 	UpsertApp(locator loc.Locator, reader io.Reader, labels map[string]string) (*Application, error)
@@ -274,6 +276,14 @@ type InstallerRequest struct {
 	CACert string `json:"ca_cert,omitempty"`
 	// EncryptionKey is encryption key to encrypt installer packages with
 	EncryptionKey string `json:"encryption_key,omitempty"`
+	// Signer optionally signs the installer packages.
+	// Only used locally by the application service
+	Signer pack.Signer `json:"-"`
+	// SourceDate, if set, makes the installer reproducible: it is used in
+	// place of the current time for all timestamps recorded in the installer
+	// and the installer entries are normalized.
+	// Only used locally by the application service
+	SourceDate time.Time `json:"-"`
 }
 
 // Check validates this request
@@ -375,6 +385,11 @@ type ImportRequest struct {
 	SetImages []loc.DockerImage `json:"set_images"`
 	// SetDeps defines a list of package dependencies that will be set to the specified version
 	SetDeps []loc.Locator `json:"set_deps"`
+	// SourceDate, if set, makes the application package reproducible: it is
+	// used as the modification time of all files in the package and the
+	// package entries are normalized.
+	// Only used locally by the application service
+	SourceDate time.Time `json:"-"`
 }
 
 // DeleteRequest describes a request to delete an application
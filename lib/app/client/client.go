@@ -32,6 +32,7 @@ import (
 	serviceapi "github.com/gravitational/gravity/lib/app/api"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/storage"
 	helmutils "github.com/gravitational/gravity/lib/utils/helm"
 
@@ -405,8 +406,9 @@ func (c *Client) CreateApp(locator loc.Locator, reader io.Reader, labels map[str
 	return c.createApp(locator, nil, reader, labels, false)
 }
 
-func (c *Client) CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string) (*app.Application, error) {
-	return c.createApp(locator, manifest, reader, labels, false)
+// Only package signatures are sent from the provided options
+func (c *Client) CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string, options ...pack.PackageOption) (*app.Application, error) {
+	return c.createApp(locator, manifest, reader, labels, false, options...)
 }
 
 // POST app/v1/applications/:repository_id
@@ -414,7 +416,7 @@ func (c *Client) UpsertApp(locator loc.Locator, reader io.Reader, labels map[str
 	return c.createApp(locator, nil, reader, labels, true)
 }
 
-func (c *Client) createApp(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string, upsert bool) (*app.Application, error) {
+func (c *Client) createApp(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string, upsert bool, options ...pack.PackageOption) (*app.Application, error) {
 	file := roundtrip.File{
 		Name:     "package",
 		Filename: locator.String(),
@@ -431,6 +433,17 @@ func (c *Client) createApp(locator loc.Locator, manifest []byte, reader io.Reade
 	if len(manifest) != 0 {
 		params.Set("manifest", string(manifest))
 	}
+	var pkg storage.Package
+	for _, option := range options {
+		option(&pkg)
+	}
+	if len(pkg.Signatures) != 0 {
+		signaturesJSON, err := json.Marshal(pkg.Signatures)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		params.Set("signatures", string(signaturesJSON))
+	}
 
 	out, err := c.PostForm(c.Endpoint("applications", locator.Repository), params, file)
 	if err != nil {
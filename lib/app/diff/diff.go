@@ -0,0 +1,574 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+)
+
+// Diff describes changes between two cluster or application images
+type Diff struct {
+	// Old is the locator of the old image
+	Old string `json:"old"`
+	// New is the locator of the new image
+	New string `json:"new"`
+	// Manifest describes changes in the image manifest
+	Manifest ManifestDiff `json:"manifest"`
+	// Images lists container image changes
+	Images []ImageChange `json:"images,omitempty"`
+	// Packages lists package and application dependency changes
+	Packages []PackageChange `json:"packages,omitempty"`
+	// Resources lists Kubernetes resource changes
+	Resources []ResourceChange `json:"resources,omitempty"`
+}
+
+// ManifestDiff describes changes between two image manifests
+type ManifestDiff struct {
+	// OldBase is the base image of the old manifest, if the base image has changed
+	OldBase string `json:"old_base,omitempty"`
+	// NewBase is the base image of the new manifest, if the base image has changed
+	NewBase string `json:"new_base,omitempty"`
+	// Hooks lists application hook changes
+	Hooks []HookChange `json:"hooks,omitempty"`
+	// Profiles lists added and removed node profiles
+	Profiles []ProfileChange `json:"profiles,omitempty"`
+	// Requirements lists new requirements of the node profiles
+	// present in both manifests
+	Requirements []RequirementsChange `json:"requirements,omitempty"`
+	// Sections lists other top-level manifest sections that have changed
+	Sections []string `json:"sections,omitempty"`
+}
+
+// Empty returns true if there are no manifest changes
+func (r ManifestDiff) Empty() bool {
+	return r.OldBase == r.NewBase && len(r.Hooks) == 0 && len(r.Profiles) == 0 &&
+		len(r.Requirements) == 0 && len(r.Sections) == 0
+}
+
+// HookChange describes a change of an application hook
+type HookChange struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// Hook is the hook type
+	Hook schema.HookType `json:"hook"`
+}
+
+// ProfileChange describes an added or removed node profile
+type ProfileChange struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// Name is the node profile name
+	Name string `json:"name"`
+}
+
+// RequirementsChange describes new requirements of a node profile
+type RequirementsChange struct {
+	// Profile is the node profile name
+	Profile string `json:"profile"`
+	// TCP lists new TCP ports
+	TCP []int `json:"tcp,omitempty"`
+	// UDP lists new UDP ports
+	UDP []int `json:"udp,omitempty"`
+	// Volumes lists paths of new volumes
+	Volumes []string `json:"volumes,omitempty"`
+}
+
+// ImageChange describes a change of a container image
+type ImageChange struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// Repository is the image repository
+	Repository string `json:"repository"`
+	// OldTag is the image tag in the old image
+	OldTag string `json:"old_tag,omitempty"`
+	// NewTag is the image tag in the new image
+	NewTag string `json:"new_tag,omitempty"`
+	// OldDigest is the image digest in the old image
+	OldDigest string `json:"old_digest,omitempty"`
+	// NewDigest is the image digest in the new image
+	NewDigest string `json:"new_digest,omitempty"`
+}
+
+// PackageChange describes a change of a package or application dependency
+type PackageChange struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// Name is the package name including the repository
+	Name string `json:"name"`
+	// Kind is either package or application
+	Kind string `json:"kind"`
+	// OldVersion is the package version in the old image
+	OldVersion string `json:"old_version,omitempty"`
+	// NewVersion is the package version in the new image
+	NewVersion string `json:"new_version,omitempty"`
+}
+
+// ResourceChange describes a change of a Kubernetes resource
+type ResourceChange struct {
+	// Type is the change type
+	Type ChangeType `json:"type"`
+	// ObjectKey identifies the resource
+	resources.ObjectKey
+}
+
+// ChangeType defines the type of a change
+type ChangeType string
+
+const (
+	// ChangeAdded means the item is only present in the new image
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved means the item is only present in the old image
+	ChangeRemoved ChangeType = "removed"
+	// ChangeUpdated means the item is present in both images but differs
+	ChangeUpdated ChangeType = "updated"
+)
+
+// Empty returns true if the images have no differences
+func (r Diff) Empty() bool {
+	return r.Manifest.Empty() && len(r.Images) == 0 &&
+		len(r.Packages) == 0 && len(r.Resources) == 0
+}
+
+// Compare returns the differences between the old and the new images
+func Compare(old, new Image) (*Diff, error) {
+	manifest, err := compareManifests(old.Application.Manifest, new.Application.Manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resources, err := compareObjects(old, new)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Diff{
+		Old:       old.Application.Package.String(),
+		New:       new.Application.Package.String(),
+		Manifest:  *manifest,
+		Images:    compareContainerImages(old.ContainerImages, new.ContainerImages),
+		Packages:  comparePackages(old.Packages, new.Packages),
+		Resources: resources,
+	}, nil
+}
+
+// Write outputs the differences to w in the specified format
+func (r Diff) Write(w io.Writer, format constants.Format) error {
+	switch format {
+	case constants.EncodingText:
+		return trace.Wrap(r.writeText(w))
+	case constants.EncodingJSON:
+		data, err := json.MarshalIndent(r, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return trace.Wrap(err)
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v, %v",
+			format, constants.EncodingText, constants.EncodingJSON)
+	}
+}
+
+func (r Diff) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Comparing %v to %v\n", r.Old, r.New)
+	if r.Empty() {
+		fmt.Fprintln(w, "No differences found.")
+		return nil
+	}
+	if !r.Manifest.Empty() {
+		fmt.Fprintln(w, "\nManifest:")
+		r.Manifest.writeText(w)
+	}
+	t := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	if len(r.Images) != 0 {
+		fmt.Fprintln(t, "\nImages:")
+		fmt.Fprintf(t, "Change\tRepository\tOld\tNew\n")
+		fmt.Fprintf(t, "------\t----------\t---\t---\n")
+		for _, image := range r.Images {
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", image.Type, image.Repository,
+				formatImage(image.OldTag, image.OldDigest),
+				formatImage(image.NewTag, image.NewDigest))
+		}
+	}
+	if len(r.Packages) != 0 {
+		fmt.Fprintln(t, "\nPackages:")
+		fmt.Fprintf(t, "Change\tKind\tName\tOld\tNew\n")
+		fmt.Fprintf(t, "------\t----\t----\t---\t---\n")
+		for _, pkg := range r.Packages {
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n", pkg.Type, pkg.Kind, pkg.Name,
+				pkg.OldVersion, pkg.NewVersion)
+		}
+	}
+	if len(r.Resources) != 0 {
+		fmt.Fprintln(t, "\nResources:")
+		fmt.Fprintf(t, "Change\tKind\tNamespace\tName\n")
+		fmt.Fprintf(t, "------\t----\t---------\t----\n")
+		for _, resource := range r.Resources {
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", resource.Type, resource.Kind,
+				resource.Namespace, resource.Name)
+		}
+	}
+	return trace.Wrap(t.Flush())
+}
+
+func (r ManifestDiff) writeText(w io.Writer) {
+	if r.OldBase != r.NewBase {
+		fmt.Fprintf(w, "  base image: %v -> %v\n", formatOptional(r.OldBase), formatOptional(r.NewBase))
+	}
+	for _, hook := range r.Hooks {
+		fmt.Fprintf(w, "  hook %v: %v\n", hook.Hook, hook.Type)
+	}
+	for _, profile := range r.Profiles {
+		fmt.Fprintf(w, "  node profile %v: %v\n", profile.Name, profile.Type)
+	}
+	for _, requirements := range r.Requirements {
+		if len(requirements.TCP) != 0 {
+			fmt.Fprintf(w, "  node profile %v: new TCP ports %v\n", requirements.Profile, formatPorts(requirements.TCP))
+		}
+		if len(requirements.UDP) != 0 {
+			fmt.Fprintf(w, "  node profile %v: new UDP ports %v\n", requirements.Profile, formatPorts(requirements.UDP))
+		}
+		if len(requirements.Volumes) != 0 {
+			fmt.Fprintf(w, "  node profile %v: new volumes %v\n", requirements.Profile, strings.Join(requirements.Volumes, ", "))
+		}
+	}
+	if len(r.Sections) != 0 {
+		fmt.Fprintf(w, "  changed sections: %v\n", strings.Join(r.Sections, ", "))
+	}
+}
+
+func formatImage(tag, digest string) string {
+	if tag == "" {
+		return "-"
+	}
+	// Shorten the digest to the algorithm and the first 12 characters
+	// of the hash similar to docker
+	if index := strings.Index(digest, ":"); index != -1 && len(digest) > index+13 {
+		digest = digest[:index+13]
+	}
+	return fmt.Sprintf("%v (%v)", tag, digest)
+}
+
+func formatPorts(ports []int) string {
+	formatted := make([]string, 0, len(ports))
+	for _, port := range ports {
+		formatted = append(formatted, fmt.Sprint(port))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatOptional(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func compareManifests(old, new schema.Manifest) (*ManifestDiff, error) {
+	var result ManifestDiff
+	oldBase, newBase := formatBase(old), formatBase(new)
+	if oldBase != newBase {
+		result.OldBase, result.NewBase = oldBase, newBase
+	}
+	added, removed, changed := schema.DiffHooks(old, new)
+	for changeType, hooks := range map[ChangeType][]schema.HookType{
+		ChangeAdded:   added,
+		ChangeRemoved: removed,
+		ChangeUpdated: changed,
+	} {
+		for _, hook := range hooks {
+			result.Hooks = append(result.Hooks, HookChange{Type: changeType, Hook: hook})
+		}
+	}
+	sort.Slice(result.Hooks, func(i, j int) bool {
+		return result.Hooks[i].Hook < result.Hooks[j].Hook
+	})
+	addedProfiles, removedProfiles := schema.DiffNodeProfiles(old, new)
+	for _, name := range addedProfiles {
+		result.Profiles = append(result.Profiles, ProfileChange{Type: ChangeAdded, Name: name})
+	}
+	for _, name := range removedProfiles {
+		result.Profiles = append(result.Profiles, ProfileChange{Type: ChangeRemoved, Name: name})
+	}
+	for _, newProfile := range new.NodeProfiles {
+		oldProfile, err := old.NodeProfiles.ByName(newProfile.Name)
+		if err != nil {
+			continue
+		}
+		tcp, udp, err := schema.DiffPorts(old, new, newProfile.Name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		// DiffVolumes appends to its arguments so pass copies
+		// to avoid modifying the manifests
+		volumes := schema.DiffVolumes(
+			append([]schema.Volume(nil), oldProfile.Requirements.Volumes...),
+			append([]schema.Volume(nil), newProfile.Requirements.Volumes...))
+		if len(tcp) == 0 && len(udp) == 0 && len(volumes) == 0 {
+			continue
+		}
+		requirements := RequirementsChange{Profile: newProfile.Name, TCP: tcp, UDP: udp}
+		for _, volume := range volumes {
+			requirements.Volumes = append(requirements.Volumes, volume.Path)
+		}
+		result.Requirements = append(result.Requirements, requirements)
+	}
+	sections, err := compareSections(old, new)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result.Sections = sections
+	return &result, nil
+}
+
+// compareSections returns names of top-level manifest sections that differ
+// between old and new, excluding the ones compared separately
+func compareSections(old, new schema.Manifest) ([]string, error) {
+	oldSections, err := manifestSections(old)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	newSections, err := manifestSections(new)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result []string
+	for name, oldSection := range oldSections {
+		if !reflect.DeepEqual(oldSection, newSections[name]) {
+			result = append(result, name)
+		}
+	}
+	for name := range newSections {
+		if _, ok := oldSections[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func manifestSections(manifest schema.Manifest) (map[string]interface{}, error) {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var sections map[string]interface{}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, name := range ignoredSections {
+		delete(sections, name)
+	}
+	return sections, nil
+}
+
+func formatBase(manifest schema.Manifest) string {
+	if base := manifest.Base(); base != nil {
+		return base.String()
+	}
+	return ""
+}
+
+// compareContainerImages returns container image changes between old and new.
+// Images are matched by repository: an image with the same tag but a different
+// digest is updated, as is the only image of a repository that has a different
+// tag in both images
+func compareContainerImages(old, new []ContainerImage) (result []ImageChange) {
+	oldTags, newTags := imagesByRepository(old), imagesByRepository(new)
+	repositories := make(map[string]struct{})
+	for repository := range oldTags {
+		repositories[repository] = struct{}{}
+	}
+	for repository := range newTags {
+		repositories[repository] = struct{}{}
+	}
+	for repository := range repositories {
+		var oldOnly, newOnly []string
+		for tag, oldDigest := range oldTags[repository] {
+			newDigest, ok := newTags[repository][tag]
+			if !ok {
+				oldOnly = append(oldOnly, tag)
+				continue
+			}
+			if oldDigest != newDigest {
+				result = append(result, ImageChange{
+					Type:       ChangeUpdated,
+					Repository: repository,
+					OldTag:     tag,
+					NewTag:     tag,
+					OldDigest:  oldDigest,
+					NewDigest:  newDigest,
+				})
+			}
+		}
+		for tag := range newTags[repository] {
+			if _, ok := oldTags[repository][tag]; !ok {
+				newOnly = append(newOnly, tag)
+			}
+		}
+		if len(oldOnly) == 1 && len(newOnly) == 1 {
+			result = append(result, ImageChange{
+				Type:       ChangeUpdated,
+				Repository: repository,
+				OldTag:     oldOnly[0],
+				NewTag:     newOnly[0],
+				OldDigest:  oldTags[repository][oldOnly[0]],
+				NewDigest:  newTags[repository][newOnly[0]],
+			})
+			continue
+		}
+		for _, tag := range oldOnly {
+			result = append(result, ImageChange{
+				Type:       ChangeRemoved,
+				Repository: repository,
+				OldTag:     tag,
+				OldDigest:  oldTags[repository][tag],
+			})
+		}
+		for _, tag := range newOnly {
+			result = append(result, ImageChange{
+				Type:       ChangeAdded,
+				Repository: repository,
+				NewTag:     tag,
+				NewDigest:  newTags[repository][tag],
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Repository != result[j].Repository {
+			return result[i].Repository < result[j].Repository
+		}
+		return result[i].OldTag+result[i].NewTag < result[j].OldTag+result[j].NewTag
+	})
+	return result
+}
+
+// imagesByRepository maps image repository to image tags and their digests
+func imagesByRepository(images []ContainerImage) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for _, image := range images {
+		if result[image.Repository] == nil {
+			result[image.Repository] = make(map[string]string)
+		}
+		result[image.Repository][image.Tag] = image.Digest
+	}
+	return result
+}
+
+// comparePackages returns package changes between old and new.
+// Packages are matched by repository and name
+func comparePackages(old, new []Package) (result []PackageChange) {
+	oldPackages, newPackages := packagesByName(old), packagesByName(new)
+	for name, newPackage := range newPackages {
+		oldPackage, ok := oldPackages[name]
+		if !ok {
+			result = append(result, PackageChange{
+				Type:       ChangeAdded,
+				Name:       name,
+				Kind:       newPackage.kind(),
+				NewVersion: newPackage.Locator.Version,
+			})
+			continue
+		}
+		if oldPackage.Locator.Version != newPackage.Locator.Version ||
+			oldPackage.SHA512 != newPackage.SHA512 {
+			result = append(result, PackageChange{
+				Type:       ChangeUpdated,
+				Name:       name,
+				Kind:       newPackage.kind(),
+				OldVersion: oldPackage.Locator.Version,
+				NewVersion: newPackage.Locator.Version,
+			})
+		}
+	}
+	for name, oldPackage := range oldPackages {
+		if _, ok := newPackages[name]; !ok {
+			result = append(result, PackageChange{
+				Type:       ChangeRemoved,
+				Name:       name,
+				Kind:       oldPackage.kind(),
+				OldVersion: oldPackage.Locator.Version,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func packagesByName(packages []Package) map[string]Package {
+	result := make(map[string]Package, len(packages))
+	for _, pkg := range packages {
+		result[fmt.Sprintf("%v/%v", pkg.Locator.Repository, pkg.Locator.Name)] = pkg
+	}
+	return result
+}
+
+func (r Package) kind() string {
+	if r.IsApp {
+		return kindApplication
+	}
+	return kindPackage
+}
+
+func compareObjects(old, new Image) (result []ResourceChange, err error) {
+	added, removed, changed, err := resources.DiffObjects(old.Objects, new.Objects)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for changeType, keys := range map[ChangeType][]resources.ObjectKey{
+		ChangeAdded:   added,
+		ChangeRemoved: removed,
+		ChangeUpdated: changed,
+	} {
+		for _, key := range keys {
+			result = append(result, ResourceChange{Type: changeType, ObjectKey: key})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectKey.String() < result[j].ObjectKey.String()
+	})
+	return result, nil
+}
+
+const (
+	kindPackage     = "package"
+	kindApplication = "application"
+)
+
+// ignoredSections lists manifest sections that are either expected to
+// change between versions or are compared separately
+var ignoredSections = []string{
+	"metadata",
+	"baseImage",
+	"releaseNotes",
+	"logo",
+	"dependencies",
+	"hooks",
+	"nodeProfiles",
+}
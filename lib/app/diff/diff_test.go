@@ -0,0 +1,259 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/service"
+	apptest "github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"gopkg.in/check.v1"
+)
+
+func TestDiff(t *testing.T) { check.TestingT(t) }
+
+type DiffSuite struct{}
+
+var _ = check.Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestComparesImages(c *check.C) {
+	old := Image{
+		Application: app.Application{
+			Package: loc.MustParseLocator("gravitational.io/app:1.0.0"),
+			Manifest: schema.MustParseManifestYAML([]byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+baseImage: gravity:5.5.0
+metadata:
+  name: app
+  resourceVersion: 1.0.0
+hooks:
+  install:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: install
+  update:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: update
+nodeProfiles:
+  - name: node
+    requirements:
+      network:
+        ports:
+          - protocol: tcp
+            ranges: ["8080"]
+  - name: db
+`)),
+		},
+		Packages: []Package{
+			{Locator: loc.MustParseLocator("gravitational.io/planet:5.5.0"), SHA512: "1"},
+			{Locator: loc.MustParseLocator("gravitational.io/teleport:3.0.0"), SHA512: "2"},
+			{Locator: loc.MustParseLocator("gravitational.io/dns-app:0.1.0"), SHA512: "3", IsApp: true},
+		},
+		ContainerImages: []ContainerImage{
+			{Repository: "nginx", Tag: "1.17", Digest: "sha256:1"},
+			{Repository: "redis", Tag: "5", Digest: "sha256:2"},
+			{Repository: "postgres", Tag: "11", Digest: "sha256:3"},
+		},
+	}
+	new := Image{
+		Application: app.Application{
+			Package: loc.MustParseLocator("gravitational.io/app:1.1.0"),
+			Manifest: schema.MustParseManifestYAML([]byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+baseImage: gravity:5.5.1
+metadata:
+  name: app
+  resourceVersion: 1.1.0
+hooks:
+  install:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: install-v2
+  uninstall:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: uninstall
+nodeProfiles:
+  - name: node
+    requirements:
+      network:
+        ports:
+          - protocol: tcp
+            ranges: ["8080", "9090"]
+      volumes:
+        - path: /var/lib/data
+          targetPath: /data
+  - name: worker
+`)),
+		},
+		Packages: []Package{
+			{Locator: loc.MustParseLocator("gravitational.io/planet:5.5.1"), SHA512: "4"},
+			{Locator: loc.MustParseLocator("gravitational.io/teleport:3.0.0"), SHA512: "2"},
+			{Locator: loc.MustParseLocator("gravitational.io/monitoring-app:0.1.0"), SHA512: "5", IsApp: true},
+		},
+		ContainerImages: []ContainerImage{
+			{Repository: "nginx", Tag: "1.17", Digest: "sha256:4"},
+			{Repository: "redis", Tag: "6", Digest: "sha256:5"},
+			{Repository: "memcached", Tag: "1.6", Digest: "sha256:6"},
+		},
+	}
+
+	diff, err := Compare(old, new)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff.Empty(), check.Equals, false)
+	c.Assert(diff.Manifest, compare.DeepEquals, ManifestDiff{
+		OldBase: "gravitational.io/kubernetes:5.5.0",
+		NewBase: "gravitational.io/kubernetes:5.5.1",
+		Hooks: []HookChange{
+			{Type: ChangeUpdated, Hook: schema.HookInstall},
+			{Type: ChangeAdded, Hook: schema.HookUninstall},
+			{Type: ChangeRemoved, Hook: schema.HookUpdate},
+		},
+		Profiles: []ProfileChange{
+			{Type: ChangeAdded, Name: "worker"},
+			{Type: ChangeRemoved, Name: "db"},
+		},
+		Requirements: []RequirementsChange{
+			{Profile: "node", TCP: []int{9090}, Volumes: []string{"/var/lib/data"}},
+		},
+	})
+	c.Assert(diff.Images, compare.DeepEquals, []ImageChange{
+		{Type: ChangeAdded, Repository: "memcached", NewTag: "1.6", NewDigest: "sha256:6"},
+		{Type: ChangeUpdated, Repository: "nginx", OldTag: "1.17", NewTag: "1.17", OldDigest: "sha256:1", NewDigest: "sha256:4"},
+		{Type: ChangeRemoved, Repository: "postgres", OldTag: "11", OldDigest: "sha256:3"},
+		{Type: ChangeUpdated, Repository: "redis", OldTag: "5", NewTag: "6", OldDigest: "sha256:2", NewDigest: "sha256:5"},
+	})
+	c.Assert(diff.Packages, compare.DeepEquals, []PackageChange{
+		{Type: ChangeRemoved, Name: "gravitational.io/dns-app", Kind: kindApplication, OldVersion: "0.1.0"},
+		{Type: ChangeAdded, Name: "gravitational.io/monitoring-app", Kind: kindApplication, NewVersion: "0.1.0"},
+		{Type: ChangeUpdated, Name: "gravitational.io/planet", Kind: kindPackage, OldVersion: "5.5.0", NewVersion: "5.5.1"},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(diff.Write(&buf, constants.EncodingText), check.IsNil)
+	c.Assert(buf.String(), check.Matches, `(?s)Comparing gravitational.io/app:1.0.0 to gravitational.io/app:1.1.0.*node profile node: new TCP ports 9090.*`)
+}
+
+func (s *DiffSuite) TestComparesIdenticalImages(c *check.C) {
+	image := Image{
+		Application: app.Application{
+			Package:  loc.MustParseLocator("gravitational.io/app:1.0.0"),
+			Manifest: schema.MustParseManifestYAML([]byte("apiVersion: bundle.gravitational.io/v2\nkind: Bundle\nmetadata:\n  name: app\n  resourceVersion: 1.0.0\n")),
+		},
+		ContainerImages: []ContainerImage{{Repository: "nginx", Tag: "1.17", Digest: "sha256:1"}},
+	}
+	diff, err := Compare(image, image)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff.Empty(), check.Equals, true)
+	c.Assert(diff.Resources, check.HasLen, 0)
+}
+
+func (s *DiffSuite) TestReadsImage(c *check.C) {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(dir, defaults.GravityDBFile)})
+	c.Assert(err, check.IsNil)
+	objects, err := fs.New(filepath.Join(dir, defaults.PackagesDir))
+	c.Assert(err, check.IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		Objects:     objects,
+		UnpackedDir: filepath.Join(dir, defaults.PackagesDir, defaults.UnpackedDir),
+	})
+	c.Assert(err, check.IsNil)
+	apps, err := service.New(service.Config{
+		Backend:  backend,
+		Packages: packages,
+		StateDir: c.MkDir(),
+	})
+	c.Assert(err, check.IsNil)
+	apptest.CreateRuntimeApplication(apps, c)
+	manifest := "apiVersion: bundle.gravitational.io/v2\nkind: Bundle\nmetadata:\n  name: app\n  resourceVersion: 1.0.0\nsystemOptions:\n  runtime:\n    version: 0.0.1\n"
+	apptest.CreateApplication(apps, loc.MustParseLocator("gravitational.io/app:1.0.0"), []*archive.Item{
+		archive.ItemFromString("resources/app.yaml", manifest),
+		archive.ItemFromString("resources/resources.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"),
+		archive.ItemFromString("registry/docker/registry/v2/repositories/nginx/_manifests/tags/1.17/current/link", "sha256:1"),
+	}, c)
+	c.Assert(backend.Close(), check.IsNil)
+
+	path := filepath.Join(c.MkDir(), "app.tar")
+	f, err := os.Create(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive.CompressDirectory(dir, f, archive.ItemFromString(defaults.ManifestFileName, manifest)), check.IsNil)
+	c.Assert(f.Close(), check.IsNil)
+
+	image, err := ReadImage(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(image.Application.Package.String(), check.Equals, "gravitational.io/app:1.0.0")
+	c.Assert(image.ContainerImages, check.DeepEquals, []ContainerImage{{Repository: "nginx", Tag: "1.17", Digest: "sha256:1"}})
+	c.Assert(image.Objects, check.HasLen, 1)
+}
+
+func (s *DiffSuite) TestParsesTagLinks(c *check.C) {
+	var testCases = []struct {
+		path       string
+		repository string
+		tag        string
+		ok         bool
+	}{
+		{
+			path:       "registry/docker/registry/v2/repositories/gravitational/debian-tall/_manifests/tags/0.0.1/current/link",
+			repository: "gravitational/debian-tall",
+			tag:        "0.0.1",
+			ok:         true,
+		},
+		{path: "registry/docker/registry/v2/repositories/nginx/_manifests/revisions/sha256/abc/link"},
+		{path: "resources/app.yaml"},
+	}
+	for _, tc := range testCases {
+		repository, tag, ok := parseTagLink(tc.path)
+		c.Assert(ok, check.Equals, tc.ok, check.Commentf(tc.path))
+		c.Assert(repository, check.Equals, tc.repository)
+		c.Assert(tag, check.Equals, tc.tag)
+	}
+}
+
+func (s *DiffSuite) TestDetectsResourceFiles(c *check.C) {
+	c.Assert(isResourceFile("resources/resources.yaml"), check.Equals, true)
+	c.Assert(isResourceFile("resources/nested/config.json"), check.Equals, true)
+	c.Assert(isResourceFile("resources/app.yaml"), check.Equals, false)
+	c.Assert(isResourceFile("resources/charts/app/templates/deployment.yaml"), check.Equals, false)
+	c.Assert(isResourceFile("resources/install.sh"), check.Equals, false)
+	c.Assert(isResourceFile("registry/config.yaml"), check.Equals, false)
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/docker"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Image describes the contents of a cluster or application image
+type Image struct {
+	// Application is the application the image was built for
+	Application app.Application
+	// Packages lists gravity packages the application depends on
+	Packages []Package
+	// ContainerImages lists container images vendored into the application
+	ContainerImages []ContainerImage
+	// Objects lists Kubernetes objects from the application resources
+	Objects []runtime.Object
+}
+
+// Package describes a gravity package dependency
+type Package struct {
+	// Locator is the package locator
+	Locator loc.Locator
+	// SHA512 is the package checksum
+	SHA512 string
+	// IsApp is whether the package is an application
+	IsApp bool
+}
+
+// ContainerImage describes a vendored container image
+type ContainerImage struct {
+	// Repository is the image repository
+	Repository string
+	// Tag is the image tag
+	Tag string
+	// Digest is the image manifest digest
+	Digest string
+}
+
+// ReadImage reads the contents of the image tarball at the specified path.
+//
+// Only the package metadata and the application package are read from
+// the tarball, so container images and resources of dependency applications
+// are not included
+func ReadImage(path string) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer file.Close()
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	manifestBytes, err := extractMetadata(file, dir)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read %v", path)
+	}
+	image, err := readMetadata(dir, manifestBytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if err := readApplicationPackage(file, image); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return image, nil
+}
+
+// extractMetadata extracts the package database from the image tarball r
+// into dir and returns the contents of the image manifest
+func extractMetadata(r io.Reader, dir string) (manifestBytes []byte, err error) {
+	var hasDatabase bool
+	tr := tar.NewReader(r)
+	for manifestBytes == nil || !hasDatabase {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		switch filepath.Clean(header.Name) {
+		case defaults.ManifestFileName:
+			manifestBytes, err = ioutil.ReadAll(tr)
+		case databaseFileName:
+			err = writeFile(filepath.Join(dir, databaseFileName), tr)
+			hasDatabase = true
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if manifestBytes == nil || !hasDatabase {
+		return nil, trace.BadParameter("file does not appear to be a cluster or application image")
+	}
+	return manifestBytes, nil
+}
+
+// readMetadata returns the image application and its dependencies from
+// the package database in dir
+func readMetadata(dir string, manifestBytes []byte) (*Image, error) {
+	manifest, err := schema.ParseManifestYAMLNoValidate(manifestBytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path:     filepath.Join(dir, databaseFileName),
+		Readonly: true,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer backend.Close()
+	objects, err := fs.New(filepath.Join(dir, defaults.PackagesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.PackagesDir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	apps, err := service.New(service.Config{
+		Backend:  backend,
+		Packages: packages,
+		StateDir: dir,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	locator := manifest.Locator()
+	if locator.Repository == "" {
+		locator.Repository = defaults.SystemAccountOrg
+	}
+	application, err := apps.GetApp(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dependencies, err := app.GetDependencies(application, apps)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if dependencies == nil {
+		// Application images do not include their dependencies so
+		// only the direct ones are known
+		dependencies = &app.Dependencies{
+			Packages: manifest.Dependencies.GetPackages(),
+			Apps:     manifest.Dependencies.GetApps(),
+		}
+	}
+	image := &Image{Application: *application}
+	for isApp, locators := range map[bool][]loc.Locator{
+		false: dependencies.Packages,
+		true:  dependencies.Apps,
+	} {
+		for _, locator := range locators {
+			pkg := Package{Locator: locator, IsApp: isApp}
+			envelope, err := packages.ReadPackageEnvelope(locator)
+			if err != nil && !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+			if envelope != nil {
+				pkg.SHA512 = envelope.SHA512
+			}
+			image.Packages = append(image.Packages, pkg)
+		}
+	}
+	return image, nil
+}
+
+// readApplicationPackage finds the application package blob in the image
+// tarball r and collects container images and Kubernetes objects from it
+func readApplicationPackage(r io.Reader, image *Image) error {
+	blobName := image.Application.PackageEnvelope.SHA512
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return trace.NotFound("application package %v not found",
+				image.Application.Package)
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		path := filepath.Clean(header.Name)
+		if strings.HasPrefix(path, defaults.PackagesDir+"/") && filepath.Base(path) == blobName {
+			return trace.Wrap(scanApplicationPackage(tr, image))
+		}
+	}
+}
+
+// scanApplicationPackage collects container images and Kubernetes objects
+// from the application package read from r
+func scanApplicationPackage(r io.Reader, image *Image) error {
+	decompressed, err := dockerarchive.DecompressStream(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if header.FileInfo().IsDir() {
+			continue
+		}
+		path := filepath.Clean(header.Name)
+		if repository, tag, ok := parseTagLink(path); ok {
+			digest, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			image.ContainerImages = append(image.ContainerImages, ContainerImage{
+				Repository: repository,
+				Tag:        tag,
+				Digest:     strings.TrimSpace(string(digest)),
+			})
+			continue
+		}
+		if !isResourceFile(path) {
+			continue
+		}
+		resource, err := resources.Decode(tr)
+		if err != nil {
+			log.WithError(err).Debugf("Skipping %v.", path)
+			continue
+		}
+		image.Objects = append(image.Objects, resource.Objects...)
+	}
+}
+
+// parseTagLink returns the image repository and tag if the specified path
+// is a tag link in the registry directory of the application package
+func parseTagLink(path string) (repository, tag string, ok bool) {
+	if !strings.HasPrefix(path, defaults.RegistryDir+"/") {
+		return "", "", false
+	}
+	return docker.ParseTagLink(strings.TrimPrefix(path, defaults.RegistryDir+"/"))
+}
+
+// isResourceFile returns true if the specified path is a Kubernetes
+// resource file in the application package.
+// Helm chart templates are not considered as they cannot be decoded
+// without rendering
+func isResourceFile(path string) bool {
+	if !strings.HasPrefix(path, defaults.ResourcesDir+"/") {
+		return false
+	}
+	if path == filepath.Join(defaults.ResourcesDir, defaults.ManifestFileName) ||
+		strings.Contains(path, "/templates/") {
+		return false
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return trace.ConvertSystemError(err)
+}
+
+const (
+	// databaseFileName is the name of the package database in the image tarball
+	databaseFileName = "gravity.db"
+)
@@ -561,12 +561,14 @@ func (h *WebHandler) createApp(w http.ResponseWriter, req *http.Request,
 	var labelsMap string
 	var upsertS string
 	var manifestS string
+	var signaturesJSON string
 
 	err := form.Parse(req,
 		form.FileSlice("package", &files),
 		form.String("labels", &labelsMap),
 		form.String("upsert", &upsertS),
 		form.String("manifest", &manifestS),
+		form.String("signatures", &signaturesJSON),
 	)
 	if err != nil {
 		return trace.Wrap(err)
@@ -581,6 +583,15 @@ func (h *WebHandler) createApp(w http.ResponseWriter, req *http.Request,
 		return trace.Wrap(err)
 	}
 
+	var options []pack.PackageOption
+	if signaturesJSON != "" {
+		var signatures []storage.PackageSignature
+		if err := json.Unmarshal([]byte(signaturesJSON), &signatures); err != nil {
+			return trace.BadParameter("invalid package signatures: %v", err)
+		}
+		options = append(options, pack.WithSignatures(signatures))
+	}
+
 	defer func() {
 		if err := files.Close(); err != nil {
 			log.Errorf("failed to close files: %v", err)
@@ -604,7 +615,7 @@ func (h *WebHandler) createApp(w http.ResponseWriter, req *http.Request,
 		application, err = context.applications.UpsertApp(*locator, reader, labels)
 	} else {
 		if len(manifestS) != 0 {
-			application, err = context.applications.CreateAppWithManifest(*locator, []byte(manifestS), reader, labels)
+			application, err = context.applications.CreateAppWithManifest(*locator, []byte(manifestS), reader, labels, options...)
 		} else {
 			application, err = context.applications.CreateApp(*locator, reader, labels)
 		}
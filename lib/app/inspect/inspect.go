@@ -0,0 +1,411 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/docker"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+)
+
+// Config defines the image to inspect
+type Config struct {
+	// Manifest is the image manifest
+	Manifest schema.Manifest
+	// Packages is the package service with the image packages
+	Packages pack.PackageService
+	// Apps is the application service with the image applications
+	Apps app.Applications
+}
+
+// CheckAndSetDefaults validates the config
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Packages == nil {
+		return trace.BadParameter("missing Packages")
+	}
+	if c.Apps == nil {
+		return trace.BadParameter("missing Apps")
+	}
+	return nil
+}
+
+// Report describes the contents of a cluster or application image
+type Report struct {
+	// Kind is the image kind, e.g. Cluster
+	Kind string `json:"kind"`
+	// Locator is the locator of the image application
+	Locator string `json:"locator"`
+	// Description is the image description
+	Description string `json:"description,omitempty"`
+	// Author is the image author
+	Author string `json:"author,omitempty"`
+	// Created is the time the image was built
+	Created time.Time `json:"created,omitempty"`
+	// Runtime is the version of the runtime the image is based on
+	Runtime string `json:"runtime,omitempty"`
+	// NodeProfiles lists the image node profiles
+	NodeProfiles []NodeProfile `json:"node_profiles,omitempty"`
+	// Flavors lists the image install flavors
+	Flavors []Flavor `json:"flavors,omitempty"`
+	// Dependencies lists packages and applications the image depends on
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Hooks lists application hooks defined in the image
+	Hooks []schema.HookType `json:"hooks,omitempty"`
+	// Packages lists packages embedded into the image
+	Packages []Package `json:"packages,omitempty"`
+	// Images lists container images vendored into the image application
+	Images []Image `json:"images,omitempty"`
+}
+
+// NodeProfile describes a node profile
+type NodeProfile struct {
+	// Name is the profile name
+	Name string `json:"name"`
+	// Description is the profile description
+	Description string `json:"description,omitempty"`
+	// ServiceRole is the profile system role
+	ServiceRole string `json:"service_role,omitempty"`
+	// CPU is the minimum required number of CPUs
+	CPU int `json:"cpu,omitempty"`
+	// RAM is the minimum required amount of RAM
+	RAM string `json:"ram,omitempty"`
+}
+
+// Flavor describes an install flavor
+type Flavor struct {
+	// Name is the flavor name
+	Name string `json:"name"`
+	// Description is the flavor description
+	Description string `json:"description,omitempty"`
+	// Default is whether this is the default flavor
+	Default bool `json:"default,omitempty"`
+	// Nodes lists numbers of nodes per node profile
+	Nodes []schema.FlavorNode `json:"nodes,omitempty"`
+}
+
+// Package describes a package embedded into the image
+type Package struct {
+	// Locator is the package locator
+	Locator string `json:"locator"`
+	// SizeBytes is the package size
+	SizeBytes int64 `json:"size_bytes"`
+	// SHA512 is the package checksum
+	SHA512 string `json:"sha512"`
+	// Error is the package verification error, empty if the package
+	// contents match its envelope
+	Error string `json:"error,omitempty"`
+}
+
+// Image describes a vendored container image
+type Image struct {
+	// Repository is the image repository
+	Repository string `json:"repository"`
+	// Tag is the image tag
+	Tag string `json:"tag"`
+	// Digest is the image manifest digest
+	Digest string `json:"digest"`
+}
+
+// Inspect returns the report about the image with the specified configuration.
+// Contents of every package in the image are verified against the package
+// envelope and verification failures are recorded in the report
+func Inspect(config Config) (*Report, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest := config.Manifest
+	locator := manifest.Locator()
+	if locator.Repository == "" {
+		locator.Repository = defaults.SystemAccountOrg
+	}
+	application, err := config.Apps.GetApp(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report := &Report{
+		Kind:         manifest.Kind,
+		Locator:      application.Package.String(),
+		Description:  manifest.Metadata.Description,
+		Author:       manifest.Metadata.Author,
+		Created:      manifest.Metadata.CreatedTimestamp,
+		NodeProfiles: nodeProfiles(manifest),
+		Flavors:      flavors(manifest),
+		Dependencies: dependencies(manifest),
+	}
+	if base := manifest.Base(); base != nil {
+		report.Runtime = base.Version
+	}
+	for _, hookType := range schema.AllHooks() {
+		if hook, _ := schema.HookFromString(hookType, manifest); hook != nil {
+			report.Hooks = append(report.Hooks, hookType)
+		}
+	}
+	report.Packages, err = verifyPackages(config.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report.Images, err = images(config.Packages, application.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return report, nil
+}
+
+// Verified returns true if all packages in the image have been verified
+func (r Report) Verified() bool {
+	for _, pkg := range r.Packages {
+		if pkg.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Write outputs the report to w in the specified format
+func (r Report) Write(w io.Writer, format constants.Format) error {
+	switch format {
+	case constants.EncodingText:
+		return trace.Wrap(r.writeText(w))
+	case constants.EncodingJSON:
+		data, err := json.MarshalIndent(r, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return trace.Wrap(err)
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v, %v",
+			format, constants.EncodingText, constants.EncodingJSON)
+	}
+}
+
+func (r Report) writeText(w io.Writer) error {
+	t := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(t, "Kind:\t%v\n", r.Kind)
+	fmt.Fprintf(t, "Image:\t%v\n", r.Locator)
+	if r.Description != "" {
+		fmt.Fprintf(t, "Description:\t%v\n", r.Description)
+	}
+	if r.Author != "" {
+		fmt.Fprintf(t, "Author:\t%v\n", r.Author)
+	}
+	if !r.Created.IsZero() {
+		fmt.Fprintf(t, "Created (UTC):\t%v\n", r.Created.UTC().Format(constants.ShortDateFormat))
+	}
+	if r.Runtime != "" {
+		fmt.Fprintf(t, "Runtime:\t%v\n", r.Runtime)
+	}
+	if len(r.Hooks) != 0 {
+		hooks := make([]string, 0, len(r.Hooks))
+		for _, hook := range r.Hooks {
+			hooks = append(hooks, string(hook))
+		}
+		fmt.Fprintf(t, "Hooks:\t%v\n", strings.Join(hooks, ", "))
+	}
+	if len(r.Dependencies) != 0 {
+		fmt.Fprintf(t, "Dependencies:\t%v\n", strings.Join(r.Dependencies, ", "))
+	}
+	if len(r.NodeProfiles) != 0 {
+		fmt.Fprintln(t, "\nNode profiles:")
+		fmt.Fprintf(t, "Name\tRole\tCPU\tRAM\tDescription\n")
+		fmt.Fprintf(t, "----\t----\t---\t---\t-----------\n")
+		for _, profile := range r.NodeProfiles {
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n", profile.Name, profile.ServiceRole,
+				profile.CPU, profile.RAM, profile.Description)
+		}
+	}
+	if len(r.Flavors) != 0 {
+		fmt.Fprintln(t, "\nFlavors:")
+		fmt.Fprintf(t, "Name\tNodes\tDescription\n")
+		fmt.Fprintf(t, "----\t-----\t-----------\n")
+		for _, flavor := range r.Flavors {
+			name := flavor.Name
+			if flavor.Default {
+				name = fmt.Sprintf("%v (default)", name)
+			}
+			fmt.Fprintf(t, "%v\t%v\t%v\n", name, formatFlavorNodes(flavor.Nodes), flavor.Description)
+		}
+	}
+	if len(r.Packages) != 0 {
+		fmt.Fprintln(t, "\nPackages:")
+		fmt.Fprintf(t, "Package\tSize\tChecksum\tStatus\n")
+		fmt.Fprintf(t, "-------\t----\t--------\t------\n")
+		for _, pkg := range r.Packages {
+			status := "OK"
+			if pkg.Error != "" {
+				status = pkg.Error
+			}
+			fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", pkg.Locator,
+				humanize.Bytes(uint64(pkg.SizeBytes)), shorten(pkg.SHA512), status)
+		}
+	}
+	if len(r.Images) != 0 {
+		fmt.Fprintln(t, "\nImages:")
+		fmt.Fprintf(t, "Repository\tTag\tDigest\n")
+		fmt.Fprintf(t, "----------\t---\t------\n")
+		for _, image := range r.Images {
+			fmt.Fprintf(t, "%v\t%v\t%v\n", image.Repository, image.Tag, image.Digest)
+		}
+	}
+	return trace.Wrap(t.Flush())
+}
+
+func nodeProfiles(manifest schema.Manifest) (result []NodeProfile) {
+	for _, profile := range manifest.NodeProfiles {
+		nodeProfile := NodeProfile{
+			Name:        profile.Name,
+			Description: profile.Description,
+			ServiceRole: string(profile.ServiceRole),
+			CPU:         profile.Requirements.CPU.Min,
+		}
+		if profile.Requirements.RAM.Min != 0 {
+			nodeProfile.RAM = profile.Requirements.RAM.Min.String()
+		}
+		result = append(result, nodeProfile)
+	}
+	return result
+}
+
+func flavors(manifest schema.Manifest) (result []Flavor) {
+	if manifest.Installer == nil {
+		return nil
+	}
+	for _, flavor := range manifest.Installer.Flavors.Items {
+		result = append(result, Flavor{
+			Name:        flavor.Name,
+			Description: flavor.Description,
+			Default:     flavor.Name == manifest.Installer.Flavors.Default,
+			Nodes:       flavor.Nodes,
+		})
+	}
+	return result
+}
+
+func dependencies(manifest schema.Manifest) (result []string) {
+	for _, locator := range append(manifest.Dependencies.GetPackages(), manifest.Dependencies.GetApps()...) {
+		result = append(result, locator.String())
+	}
+	return result
+}
+
+// verifyPackages lists all packages in the package service sorted by locator
+// and verifies their contents
+func verifyPackages(packages pack.PackageService) (result []Package, err error) {
+	err = pack.ForeachPackage(packages, func(envelope pack.PackageEnvelope) error {
+		pkg := Package{
+			Locator:   envelope.Locator.String(),
+			SizeBytes: envelope.SizeBytes,
+			SHA512:    envelope.SHA512,
+		}
+		if err := pack.VerifyPackage(packages, envelope.Locator); err != nil {
+			pkg.Error = trace.UserMessage(err)
+		}
+		result = append(result, pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Locator < result[j].Locator
+	})
+	return result, nil
+}
+
+// images lists container images vendored into the application package
+// specified with locator, sorted by repository and tag
+func images(packages pack.PackageService, locator loc.Locator) (result []Image, err error) {
+	_, rc, err := packages.ReadPackage(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	decompressed, err := dockerarchive.DecompressStream(rc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		path := filepath.Clean(header.Name)
+		if !strings.HasPrefix(path, defaults.RegistryDir+"/") {
+			continue
+		}
+		repository, tag, ok := docker.ParseTagLink(strings.TrimPrefix(path, defaults.RegistryDir+"/"))
+		if !ok {
+			continue
+		}
+		digest, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result = append(result, Image{
+			Repository: repository,
+			Tag:        tag,
+			Digest:     strings.TrimSpace(string(digest)),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Repository != result[j].Repository {
+			return result[i].Repository < result[j].Repository
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
+
+func formatFlavorNodes(nodes []schema.FlavorNode) string {
+	formatted := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		formatted = append(formatted, fmt.Sprintf("%v x %v", node.Count, node.Profile))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// shorten returns the first 12 characters of the checksum
+func shorten(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
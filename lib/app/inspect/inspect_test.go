@@ -0,0 +1,144 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/service"
+	apptest "github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"gopkg.in/check.v1"
+)
+
+func TestInspect(t *testing.T) { check.TestingT(t) }
+
+type InspectSuite struct {
+	dir      string
+	packages *localpack.PackageServer
+	apps     app.Applications
+}
+
+var _ = check.Suite(&InspectSuite{})
+
+func (s *InspectSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(s.dir, defaults.GravityDBFile)})
+	c.Assert(err, check.IsNil)
+	objects, err := fs.New(filepath.Join(s.dir, defaults.PackagesDir))
+	c.Assert(err, check.IsNil)
+	s.packages, err = localpack.New(localpack.Config{
+		Backend:     backend,
+		Objects:     objects,
+		UnpackedDir: filepath.Join(s.dir, defaults.PackagesDir, defaults.UnpackedDir),
+	})
+	c.Assert(err, check.IsNil)
+	s.apps, err = service.New(service.Config{
+		Backend:  backend,
+		Packages: s.packages,
+		StateDir: filepath.Join(s.dir, "import"),
+	})
+	c.Assert(err, check.IsNil)
+	apptest.CreateRuntimeApplication(s.apps, c)
+}
+
+func (s *InspectSuite) TestInspectsImage(c *check.C) {
+	locator := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	application := apptest.CreateApplication(s.apps, locator, []*archive.Item{
+		archive.DirItem("resources"),
+		archive.ItemFromString("resources/app.yaml", manifest),
+		archive.ItemFromString("registry/docker/registry/v2/repositories/nginx/_manifests/tags/1.17/current/link",
+			"sha256:1234\n"),
+	}, c)
+
+	report, err := Inspect(Config{
+		Manifest: application.Manifest,
+		Packages: s.packages,
+		Apps:     s.apps,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(report.Kind, check.Equals, schema.KindCluster)
+	c.Assert(report.Locator, check.Equals, locator.String())
+	c.Assert(report.Description, check.Equals, "Test cluster")
+	c.Assert(report.Runtime, check.Equals, "0.0.1")
+	c.Assert(report.Hooks, check.DeepEquals, []schema.HookType{schema.HookInstall})
+	c.Assert(report.NodeProfiles, check.DeepEquals, []NodeProfile{
+		{Name: "node", Description: "Worker node", CPU: 2, RAM: "2.0GB"},
+	})
+	c.Assert(report.Flavors, check.DeepEquals, []Flavor{
+		{Name: "small", Default: true, Nodes: []schema.FlavorNode{{Profile: "node", Count: 1}}},
+	})
+	c.Assert(report.Images, check.DeepEquals, []Image{
+		{Repository: "nginx", Tag: "1.17", Digest: "sha256:1234"},
+	})
+	c.Assert(report.Packages, check.HasLen, 2)
+	c.Assert(report.Verified(), check.Equals, true)
+
+	// Corrupt the application package
+	blobPath := filepath.Join(s.dir, defaults.PackagesDir, "blobs",
+		application.PackageEnvelope.SHA512[0:3], application.PackageEnvelope.SHA512)
+	c.Assert(ioutil.WriteFile(blobPath, []byte("corrupted"), defaults.SharedReadMask), check.IsNil)
+	packages, err := verifyPackages(s.packages)
+	c.Assert(err, check.IsNil)
+	c.Assert(packages[0].Locator, check.Equals, locator.String())
+	c.Assert(packages[0].Error, check.Matches, ".*size mismatch.*")
+	c.Assert(packages[1].Error, check.Equals, "")
+}
+
+const manifest = `apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: app
+  resourceVersion: 1.0.0
+  description: Test cluster
+installer:
+  flavors:
+    default: small
+    items:
+      - name: small
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+    description: Worker node
+    requirements:
+      cpu:
+        min: 2
+      ram:
+        min: "2GB"
+hooks:
+  install:
+    job: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: install
+systemOptions:
+  runtime:
+    version: 0.0.1
+`
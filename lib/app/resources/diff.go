@@ -0,0 +1,120 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gravitational/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ObjectKey identifies a Kubernetes object
+type ObjectKey struct {
+	// Kind is the object kind
+	Kind string `json:"kind"`
+	// Namespace is the object namespace
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the object name
+	Name string `json:"name"`
+}
+
+// String returns a textual representation of this key
+func (r ObjectKey) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%v/%v", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%v/%v/%v", r.Kind, r.Namespace, r.Name)
+}
+
+// GetObjectKey returns the key of the specified object
+func GetObjectKey(object runtime.Object) (*ObjectKey, error) {
+	unknown, err := ToUnknown(object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if unknown.Kind == "" || unknown.Metadata.Name == "" {
+		return nil, trace.BadParameter("object has no kind or name")
+	}
+	return &ObjectKey{
+		Kind:      unknown.Kind,
+		Namespace: unknown.Metadata.Namespace,
+		Name:      unknown.Metadata.Name,
+	}, nil
+}
+
+// DiffObjects returns keys of objects that are only present in new (added),
+// only present in old (removed) or are present in both but have different
+// specifications (changed). Objects without kind or name are ignored.
+// All lists are sorted
+func DiffObjects(old, new []runtime.Object) (added, removed, changed []ObjectKey, err error) {
+	oldObjects, err := objectsByKey(old)
+	if err != nil {
+		return nil, nil, nil, trace.Wrap(err)
+	}
+	newObjects, err := objectsByKey(new)
+	if err != nil {
+		return nil, nil, nil, trace.Wrap(err)
+	}
+	for key, newObject := range newObjects {
+		oldObject, ok := oldObjects[key]
+		if !ok {
+			added = append(added, key)
+			continue
+		}
+		if !reflect.DeepEqual(oldObject, newObject) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldObjects {
+		if _, ok := newObjects[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	for _, keys := range [][]ObjectKey{added, removed, changed} {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	return added, removed, changed, nil
+}
+
+// objectsByKey indexes the specified objects by key. Objects are converted
+// to generic representation so that they can be compared regardless of
+// formatting and field order of their source
+func objectsByKey(objects []runtime.Object) (map[ObjectKey]interface{}, error) {
+	result := make(map[ObjectKey]interface{}, len(objects))
+	for _, object := range objects {
+		key, err := GetObjectKey(object)
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(object)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result[*key] = generic
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (*ResourceCodecSuite) TestDiffsObjects(c *C) {
+	old, err := Decode(strings.NewReader(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: kube-system
+data:
+  key: value
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: removed
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: same
+spec:
+  ports:
+  - port: 80
+`))
+	c.Assert(err, IsNil)
+	new, err := Decode(strings.NewReader(`apiVersion: v1
+kind: Service
+metadata:
+  name: same
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: kube-system
+  name: config
+data:
+  key: updated
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: added
+spec:
+  ports:
+  - port: 443
+`))
+	c.Assert(err, IsNil)
+
+	added, removed, changed, err := DiffObjects(old.Objects, new.Objects)
+	c.Assert(err, IsNil)
+	c.Assert(added, DeepEquals, []ObjectKey{{Kind: "Service", Name: "added"}})
+	c.Assert(removed, DeepEquals, []ObjectKey{{Kind: "Service", Name: "removed"}})
+	c.Assert(changed, DeepEquals, []ObjectKey{{Kind: "ConfigMap", Namespace: "kube-system", Name: "config"}})
+}
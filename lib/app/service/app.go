@@ -553,8 +553,18 @@ func (r *applications) UpsertApp(locator loc.Locator, reader io.Reader, labels m
 // CreateAppWithManifest new application from the specified package bytes (reader)
 // and an optional set of package labels using locator as destination for the
 // resulting package, with supplied manifest
-func (r *applications) CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string) (*appservice.Application, error) {
-	return r.createApp(locator, reader, manifest, labels, "", false)
+//
+// The package signatures of the application are verified against
+// the trust policy, if there is one
+func (r *applications) CreateAppWithManifest(locator loc.Locator, manifest []byte, reader io.Reader, labels map[string]string, options ...pack.PackageOption) (*appservice.Application, error) {
+	application, err := r.createApp(locator, reader, manifest, labels, "", false, options...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := r.verifySignatures(*application); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return application, nil
 }
 
 // CreateApp creates a new application from the specified package bytes (reader)
@@ -584,7 +594,7 @@ func (r *applications) CreateApp(locator loc.Locator, reader io.Reader, labels m
 	return r.CreateAppWithManifest(locator, manifest, packageBytes, labels)
 }
 
-func (r *applications) createApp(locator loc.Locator, packageBytes io.Reader, manifestBytes []byte, labels map[string]string, email string, upsert bool) (*appservice.Application, error) {
+func (r *applications) createApp(locator loc.Locator, packageBytes io.Reader, manifestBytes []byte, labels map[string]string, email string, upsert bool, extraOptions ...pack.PackageOption) (*appservice.Application, error) {
 	manifest, err := r.resolveManifest(manifestBytes)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -606,6 +616,7 @@ func (r *applications) createApp(locator loc.Locator, packageBytes io.Reader, ma
 		pack.WithCreatedBy(email),
 		pack.WithHidden(manifest.Metadata.Hidden),
 	}
+	options = append(options, extraOptions...)
 
 	var envelope *pack.PackageEnvelope
 	if upsert {
@@ -54,6 +54,39 @@ func exportLayers(ctx context.Context, dir string, images []string, dockerClient
 	return nil
 }
 
+// copyImages copies the specified set of images into the registry directory
+// in exportDir directly from their registries or the configured image sources
+// without a Docker daemon
+func copyImages(ctx context.Context, exportDir string, images []string, req VendorRequest, log log.FieldLogger) error {
+	copier, err := docker.NewImageCopier(docker.ImageCopierConfig{
+		Dir:                filepath.Join(exportDir, defaults.RegistryDir),
+		Sources:            req.ImageSources,
+		InsecureRegistries: req.InsecureRegistries,
+		FieldLogger:        log,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer func() {
+		if errClose := copier.Close(); errClose != nil {
+			log.Warnf("Failed to clean up image copier: %v.", errClose)
+		}
+	}()
+
+	group, ctx := run.WithContext(ctx, run.WithParallel(req.Parallel))
+	for _, image := range images {
+		image := image
+		group.Go(ctx, func() error {
+			if err := copier.Copy(ctx, image); err != nil {
+				return trace.Wrap(err)
+			}
+			req.ProgressReporter.PrintSubStep("Vendored image %v", image)
+			return nil
+		})
+	}
+	return trace.Wrap(group.Wait())
+}
+
 // newLayerExporter creates an instance of layer exporter
 func newLayerExporter(exportDir string, client docker.DockerInterface, log log.FieldLogger, progress utils.Progress) (*layerExporter, error) {
 	outputDir := filepath.Join(exportDir, defaults.RegistryDir)
@@ -27,11 +27,11 @@ import (
 
 func (s *VendorSuite) TestGeneratesProperPackageName(c *C) {
 	var testCases = []struct {
-		image        string
-		result       loc.Locator
-		visited      map[string]loc.Locator
-		randomSuffix func(string) string
-		comment      string
+		image   string
+		result  loc.Locator
+		visited map[string]loc.Locator
+		suffix  func(string, int) string
+		comment string
 	}{
 		{
 			image:   "foo:5.1.0",
@@ -64,11 +64,17 @@ func (s *VendorSuite) TestGeneratesProperPackageName(c *C) {
 		{
 			image:  "planet-master:0.0.1",
 			result: loc.MustParseLocator("gravitational.io/planet-master-qux:0.0.1"),
-			randomSuffix: func(name string) string {
-				return fmt.Sprintf("%v-qux", name)
+			suffix: func(image string, attempt int) string {
+				return "qux"
 			},
 			comment: "avoids collision with legacy name",
 		},
+		{
+			image: "planet-master:0.0.1",
+			result: loc.MustParseLocator(fmt.Sprintf("gravitational.io/planet-master-%v:0.0.1",
+				imageSuffix("planet-master:0.0.1", 0))),
+			comment: "derives suffix from image reference",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -77,9 +83,35 @@ func (s *VendorSuite) TestGeneratesProperPackageName(c *C) {
 		if visited == nil {
 			visited = make(map[string]loc.Locator)
 		}
-		generate := newRuntimePackage(visited, testCase.randomSuffix)
+		generate := newRuntimePackage(visited, testCase.suffix)
 		runtimePackage, err := generate(testCase.image)
 		c.Assert(err, IsNil, comment)
 		c.Assert(*runtimePackage, compare.DeepEquals, testCase.result, comment)
 	}
 }
+
+func (s *VendorSuite) TestGeneratesReproduciblePackageNames(c *C) {
+	images := []string{"planet-master:0.0.1", "planet-master:0.0.2", "planet-node:0.0.1"}
+	generateAll := func() (result []loc.Locator) {
+		generate := newRuntimePackage(make(map[string]loc.Locator), nil)
+		for _, image := range images {
+			runtimePackage, err := generate(image)
+			c.Assert(err, IsNil)
+			result = append(result, *runtimePackage)
+		}
+		return result
+	}
+	first := generateAll()
+	c.Assert(generateAll(), compare.DeepEquals, first)
+	c.Assert(first[0].Name, Not(Equals), first[1].Name)
+
+	// Names colliding with previously generated ones are regenerated
+	generate := newRuntimePackage(make(map[string]loc.Locator), func(image string, attempt int) string {
+		return fmt.Sprint(attempt)
+	})
+	for i, expected := range []string{"planet-master-0", "planet-master-1"} {
+		runtimePackage, err := generate(images[i])
+		c.Assert(err, IsNil)
+		c.Assert(runtimePackage.Name, Equals, expected)
+	}
+}
@@ -21,12 +21,13 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/trace"
 
-	"github.com/docker/docker/pkg/archive"
+	dockerarchive "github.com/docker/docker/pkg/archive"
 )
 
 // importApp imports an application from the specified directory
@@ -57,16 +58,23 @@ func (r *applications) importAppWithContext(ctx *operationContext, request *app.
 		return trace.Wrap(err)
 	}
 
-	archiveOptions := &archive.TarOptions{
-		Compression:     archive.Gzip,
+	archiveOptions := &dockerarchive.TarOptions{
+		Compression:     dockerarchive.Gzip,
 		ExcludePatterns: request.ExcludePatterns,
 		IncludeFiles:    request.IncludePaths,
 	}
+	if !request.SourceDate.IsZero() {
+		// compress after normalizing the tarball
+		archiveOptions.Compression = dockerarchive.Uncompressed
+	}
 
-	packageBytes, err := archive.TarWithOptions(unpackedDir, archiveOptions)
+	packageBytes, err := dockerarchive.TarWithOptions(unpackedDir, archiveOptions)
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if !request.SourceDate.IsZero() {
+		packageBytes = archive.NormalizeStream(packageBytes, request.SourceDate, dockerarchive.Gzip)
+	}
 	defer packageBytes.Close()
 
 	if err = ctx.update(app.ImportStateCreatingPackage); err != nil {
@@ -81,8 +89,11 @@ func (r *applications) importAppWithContext(ctx *operationContext, request *app.
 	ctx.Infof("creating application package")
 
 	var labels map[string]string
-	_, err = r.createApp(*locator, packageBytes, manifestBytes, labels, request.Email, request.Force)
-	return trace.Wrap(err)
+	application, err := r.createApp(*locator, packageBytes, manifestBytes, labels, request.Email, request.Force)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.verifySignatures(*application))
 }
 
 // importOperation implements operation interface
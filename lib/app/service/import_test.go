@@ -0,0 +1,77 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"io/ioutil"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/archive"
+
+	. "gopkg.in/check.v1"
+)
+
+type ImportSuite struct{}
+
+var _ = Suite(&ImportSuite{})
+
+func (s *ImportSuite) TestImportsReproduciblePackage(c *C) {
+	sourceDate := time.Unix(1577836800, 0).UTC()
+	importApp := func(modTime time.Time, uid int) *app.Application {
+		_, _, apps := setupServices(c)
+		items := []*archive.Item{
+			archive.DirItem("resources"),
+			archive.ItemFromString("resources/Chart.yaml", "name: app\nversion: 0.0.1"),
+			archive.ItemFromString("resources/app.yaml", importManifest),
+		}
+		for _, item := range items {
+			item.ModTime = modTime
+			item.Uid = uid
+		}
+		var buf bytes.Buffer
+		c.Assert(archive.CompressDirectory(c.MkDir(), &buf, items...), IsNil)
+
+		progressC := make(chan *app.ProgressEntry)
+		errorC := make(chan error, 1)
+		op, err := apps.CreateImportOperation(&app.ImportRequest{
+			Source:     ioutil.NopCloser(&buf),
+			ProgressC:  progressC,
+			ErrorC:     errorC,
+			SourceDate: sourceDate,
+		})
+		c.Assert(err, IsNil)
+		for range progressC {
+		}
+		c.Assert(<-errorC, IsNil)
+		application, err := apps.GetImportedApplication(*op)
+		c.Assert(err, IsNil)
+		return application
+	}
+
+	first := importApp(time.Now().Add(-time.Hour), 1000)
+	second := importApp(time.Now(), 1001)
+	c.Assert(first.PackageEnvelope.SHA512, Equals, second.PackageEnvelope.SHA512)
+}
+
+const importManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Application
+metadata:
+  name: app
+  resourceVersion: 0.0.1
+  repository: gravitational.io`
@@ -34,6 +34,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/encryptedpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/sbom"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
@@ -42,6 +43,8 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/gravitational/license/authority"
 	"github.com/gravitational/trace"
+	"github.com/mailgun/timetools"
+	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -50,7 +53,7 @@ func (r *applications) getApplicationInstaller(
 	app *appservice.Application,
 	apps *applications,
 ) ([]*archive.Item, error) {
-	err := pullApplications([]loc.Locator{app.Package}, apps, r, r)
+	err := pullApplications([]loc.Locator{app.Package}, apps, r, req.Signer, r)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -62,7 +65,7 @@ func (r *applications) getClusterInstaller(
 	app *appservice.Application,
 	apps *applications,
 ) ([]*archive.Item, error) {
-	err := pullDependencies(app, apps, r, r)
+	err := pullDependencies(app, apps, r, req.Signer, r)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -123,11 +126,17 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
+	var clock timetools.TimeProvider
+	if !req.SourceDate.IsZero() {
+		clock = &timetools.FreezedTime{CurrentTime: req.SourceDate.UTC()}
+	}
+
 	var localPackages pack.PackageService
 	localPackages, err = localpack.New(localpack.Config{
 		Backend:     localBackend,
 		UnpackedDir: filepath.Join(tempDir, defaults.PackagesDir, defaults.UnpackedDir),
 		Objects:     objects,
+		Clock:       clock,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -146,7 +155,13 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
-	_, err = localBackend.CreateAccount(req.Account)
+	account := req.Account
+	if account.ID == "" && !req.SourceDate.IsZero() {
+		// Derive the account ID from the application instead of generating
+		// a random one
+		account.ID = uuid.NewSHA1(uuid.NameSpace_URL, []byte(req.Application.String())).String()
+	}
+	_, err = localBackend.CreateAccount(account)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -162,6 +177,11 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
+	bomItem, err := r.getSBOMItem(app.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	var items []*archive.Item
 	switch app.Manifest.Kind {
 	case schema.KindBundle, schema.KindCluster:
@@ -175,6 +195,11 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if bomItem != nil {
+		// Keep the bill of materials in front so it can be read
+		// without scanning the entire installer
+		items = append([]*archive.Item{bomItem}, items...)
+	}
 
 	reader, writer := io.Pipe()
 	go func() {
@@ -186,7 +211,13 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 			}
 			return
 		}
-		err = archive.CompressDirectory(tempDir, writer, append(items,
+		compress := archive.CompressDirectory
+		if !req.SourceDate.IsZero() {
+			compress = func(dir string, writer io.Writer, items ...*archive.Item) error {
+				return archive.CompressDirectoryReproducible(dir, writer, req.SourceDate, items...)
+			}
+		}
+		err = compress(tempDir, writer, append(items,
 			archive.ItemFromStringMode(
 				defaults.ManifestFileName, string(manifestBytes), defaults.SharedReadMask),
 			archive.ItemFromStringMode(
@@ -222,6 +253,30 @@ func renderUploadScript(app appservice.Application) (uploadScript []byte, err er
 	return buf.Bytes(), nil
 }
 
+// getSBOMItem returns the software bill of materials of the specified
+// application package as an installer item.
+// Returns nil if the application was built without one
+func (r *applications) getSBOMItem(locator loc.Locator) (*archive.Item, error) {
+	_, rc, err := r.Packages.ReadPackage(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	bom, err := sbom.FromTarball(rc)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			r.Debugf("Application %v has no bill of materials.", locator)
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	data, err := bom.Marshal()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return archive.ItemFromStringMode(defaults.SBOMFileName, string(data), defaults.SharedReadMask), nil
+}
+
 func (r *applications) getGravityBinaryForApp(app *appservice.Application) (*archive.Item, error) {
 	var gravityPackage *loc.Locator
 	gravityPackage, err := app.Manifest.Dependencies.ByName(constants.GravityPackage)
@@ -240,26 +295,27 @@ func (r *applications) getGravityBinaryForApp(app *appservice.Application) (*arc
 }
 
 // pullDependencies transitively pulls all dependent packages for app to localApps
-func pullDependencies(app *appservice.Application, localApps, remoteApps *applications, log log.FieldLogger) error {
+func pullDependencies(app *appservice.Application, localApps, remoteApps *applications, signer pack.Signer, log log.FieldLogger) error {
 	dependencies, err := appservice.GetDependencies(app, remoteApps)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	if err = pullPackages(dependencies.Packages, localApps.Packages, remoteApps.Packages, log); err != nil {
+	if err = pullPackages(dependencies.Packages, localApps.Packages, remoteApps.Packages, signer, log); err != nil {
 		return trace.Wrap(err)
 	}
 
 	apps := dependencies.Apps
 	apps = append(apps, app.Package)
-	if err = pullApplications(apps, localApps, remoteApps, log); err != nil {
+	if err = pullApplications(apps, localApps, remoteApps, signer, log); err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
 }
 
-// pullPackages pulls package locators from remotePackages to localPackages
-func pullPackages(locators []loc.Locator, localPackages pack.PackageService, remotePackages pack.PackageService, log log.FieldLogger) error {
+// pullPackages pulls package locators from remotePackages to localPackages.
+// If signer is set, the pulled packages are signed with it
+func pullPackages(locators []loc.Locator, localPackages pack.PackageService, remotePackages pack.PackageService, signer pack.Signer, log log.FieldLogger) error {
 	log.Infof("Pulling packages %v.", locators)
 
 	for _, locator := range locators {
@@ -273,7 +329,12 @@ func pullPackages(locators []loc.Locator, localPackages pack.PackageService, rem
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		_, err = localPackages.CreatePackage(envelope.Locator, reader, pack.WithLabels(envelope.RuntimeLabels))
+		signatures, err := signPackage(*envelope, signer)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = localPackages.CreatePackage(envelope.Locator, reader,
+			pack.WithLabels(envelope.RuntimeLabels), pack.WithSignatures(signatures))
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -281,8 +342,9 @@ func pullPackages(locators []loc.Locator, localPackages pack.PackageService, rem
 	return nil
 }
 
-// pullApplications pulls applications specified with locators from remoteApps to localApps
-func pullApplications(locators []loc.Locator, localApps *applications, remoteApps *applications, log log.FieldLogger) error {
+// pullApplications pulls applications specified with locators from remoteApps to localApps.
+// If signer is set, the pulled applications are signed with it
+func pullApplications(locators []loc.Locator, localApps *applications, remoteApps *applications, signer pack.Signer, log log.FieldLogger) error {
 	log.Infof("Pulling applications %v.", locators)
 
 	for _, locator := range locators {
@@ -292,8 +354,13 @@ func pullApplications(locators []loc.Locator, localApps *applications, remoteApp
 		}
 		defer reader.Close()
 
+		signatures, err := signPackage(*envelope, signer)
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		var labels map[string]string
-		_, err = localApps.CreateAppWithManifest(envelope.Locator, envelope.Manifest, reader, labels)
+		_, err = localApps.CreateAppWithManifest(envelope.Locator, envelope.Manifest, reader, labels,
+			pack.WithSignatures(signatures))
 		if err != nil && !trace.IsAlreadyExists(err) {
 			return trace.Wrap(err)
 		}
@@ -302,6 +369,19 @@ func pullApplications(locators []loc.Locator, localApps *applications, remoteApp
 	return nil
 }
 
+// signPackage returns the signatures of the package described with envelope
+// extended with the signature made by signer if it is set
+func signPackage(envelope pack.PackageEnvelope, signer pack.Signer) ([]storage.PackageSignature, error) {
+	if signer == nil {
+		return envelope.Signatures, nil
+	}
+	signature, err := signer.SignPackage(envelope)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return append(envelope.Signatures, *signature), nil
+}
+
 // addCertificateAuthority makes the certificate authority package from the provided CA and key
 // and puts it alongside other installer packages
 func addCertificateAuthority(req appservice.InstallerRequest, destPackages pack.PackageService) error {
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	apptest "github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+
+	. "gopkg.in/check.v1"
+)
+
+type InstallerSuite struct{}
+
+var _ = Suite(&InstallerSuite{})
+
+func (s *InstallerSuite) TestGeneratesReproducibleInstaller(c *C) {
+	_, _, apps := setupServices(c)
+	locator := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	apptest.CreateHelmChartApp(c, apps, locator)
+	sourceDate := time.Unix(1577836800, 0).UTC()
+
+	generate := func() []byte {
+		installer, err := apps.GetAppInstaller(app.InstallerRequest{
+			Application: locator,
+			SourceDate:  sourceDate,
+		})
+		c.Assert(err, IsNil)
+		defer installer.Close()
+		data, err := ioutil.ReadAll(installer)
+		c.Assert(err, IsNil)
+		return data
+	}
+
+	first := generate()
+	// Make sure the files of the second installer are created at a different time
+	time.Sleep(time.Second)
+	second := generate()
+	c.Assert(bytes.Equal(first, second), Equals, true)
+
+	tr := tar.NewReader(bytes.NewReader(first))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+		c.Assert(hdr.ModTime.Equal(sourceDate), Equals, true, Commentf(hdr.Name))
+		c.Assert(hdr.Uid, Equals, defaults.ArchiveUID, Commentf(hdr.Name))
+	}
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/run"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/transfer"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -90,6 +92,9 @@ type AppPullRequest struct {
 	// If < 0, the number of tasks is unrestricted.
 	// If in [0,1], the tasks are executed sequentially.
 	Parallel int
+	// Signer optionally signs the pulled application package.
+	// Dependencies keep the signatures they already have
+	Signer pack.Signer
 }
 
 // CheckAndSetDefaults checks the app pull request and sets some defaults
@@ -180,24 +185,11 @@ func pullPackage(req PackagePullRequest) (*pack.PackageEnvelope, error) {
 
 	req.Infof("Pulling package %v.", req.Package)
 
-	reader := ioutil.NopCloser(utils.NopReader())
-	if req.MetadataOnly {
-		env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
-	} else {
-		env, reader, err = req.SrcPack.ReadPackage(req.Package)
-	}
+	env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	if req.Progress != nil {
-		reader = utils.TeeReadCloser(reader, &pack.ProgressWriter{
-			Size: env.SizeBytes,
-			R:    req.Progress,
-		})
-	}
-	defer reader.Close()
-
 	err = req.DstPack.UpsertRepository(env.Locator.Repository, time.Time{})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -213,6 +205,22 @@ func pullPackage(req PackagePullRequest) (*pack.PackageEnvelope, error) {
 		}
 	}
 
+	if !req.MetadataOnly {
+		env, err = transfer.CopyPackage(transfer.CopyPackageRequest{
+			Src:      req.SrcPack,
+			Dst:      req.DstPack,
+			Package:  env.Locator,
+			Upsert:   req.Upsert,
+			Options:  []pack.PackageOption{pack.WithLabels(req.Labels)},
+			Progress: req.Progress,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return env, nil
+	}
+
+	reader := utils.NopReader()
 	if req.Upsert {
 		env, err = req.DstPack.UpsertPackage(
 			env.Locator, reader, pack.WithLabels(req.Labels))
@@ -307,7 +315,7 @@ func pullApp(req AppPullRequest, state *pullState) (*app.Application, error) {
 	if req.MetadataOnly {
 		env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
 	} else {
-		env, reader, err = req.SrcPack.ReadPackage(req.Package)
+		env, reader, err = transfer.ReadPackage(req.SrcPack, req.DstPack, req.Package)
 	}
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -324,8 +332,13 @@ func pullApp(req AppPullRequest, state *pullState) (*app.Application, error) {
 	if req.Upsert {
 		application, err = req.DstApp.UpsertApp(env.Locator, reader, req.Labels)
 	} else {
+		var signatures []storage.PackageSignature
+		signatures, err = signPackage(*env, req.Signer)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
 		application, err = req.DstApp.CreateAppWithManifest(
-			env.Locator, env.Manifest, reader, req.Labels)
+			env.Locator, env.Manifest, reader, req.Labels, pack.WithSignatures(signatures))
 	}
 	if err != nil {
 		return nil, trace.Wrap(err)
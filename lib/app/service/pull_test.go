@@ -30,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/signing"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 
@@ -157,6 +158,52 @@ dependencies:
 	c.Assert(trace.IsAlreadyExists(err), Equals, true)
 }
 
+func (s *PullerSuite) TestPullAppVerifiesSignatures(c *C) {
+	keyPEM, publicKeyPEM, err := signing.GenerateKey()
+	c.Assert(err, IsNil)
+	signer, err := signing.NewSigner(keyPEM, nil)
+	c.Assert(err, IsNil)
+	policy := storage.NewTrustPolicy(storage.TrustPolicySpecV2{
+		Keys: []storage.TrustedKey{{Name: "release", PublicKey: string(publicKeyPEM)}},
+	})
+	c.Assert(policy.CheckAndSetDefaults(), IsNil)
+	backend, dstPack, dstApp := setupServices(c)
+	c.Assert(backend.UpsertTrustPolicy(policy), IsNil)
+
+	runtimePackage := loc.MustParseLocator("gravitational.io/planet:0.0.1")
+	apptest.CreatePackage(s.srcPack, runtimePackage, nil, c)
+	apptest.CreateRuntimeApplication(s.srcApp, c)
+	locator := loc.MustParseLocator("example.com/app:0.0.1")
+	apptest.CreateDummyApplication(locator, c, s.srcApp)
+	req := AppPullRequest{
+		SrcPack: s.srcPack,
+		DstPack: dstPack,
+		SrcApp:  s.srcApp,
+		DstApp:  dstApp,
+		Package: locator,
+		Signer:  signer,
+	}
+
+	// the runtime dependencies are not signed
+	_, err = PullApp(req)
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("expected AccessDenied, got %v", err))
+	_, err = dstPack.ReadPackageEnvelope(locator)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("expected NotFound, got %v", err))
+
+	audit := storage.NewTrustPolicy(storage.TrustPolicySpecV2{
+		Mode: storage.TrustPolicyModeAudit,
+		Keys: policy.GetKeys(),
+	})
+	c.Assert(audit.CheckAndSetDefaults(), IsNil)
+	c.Assert(backend.UpsertTrustPolicy(audit), IsNil)
+	_, err = PullApp(req)
+	c.Assert(err, IsNil)
+
+	verifier, err := signing.NewVerifier(policy)
+	c.Assert(err, IsNil)
+	c.Assert(verifier.VerifyPackage(dstPack, locator), IsNil)
+}
+
 func setupServices(c *C) (storage.Backend, pack.PackageService, *applications) {
 	dir := c.MkDir()
 
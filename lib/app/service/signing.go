@@ -0,0 +1,56 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/signing"
+
+	"github.com/gravitational/trace"
+)
+
+// verifySignatures verifies the package signatures of the specified application
+// and its dependencies against the trust policy of this service.
+// If the verification fails, the application package is removed.
+// Verification is skipped if there is no trust policy or the application
+// is a metadata package
+func (r *applications) verifySignatures(application appservice.Application) error {
+	if IsMetadataPackage(application.PackageEnvelope) {
+		return nil
+	}
+	policy, err := r.Backend.GetTrustPolicy()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	err = signing.VerifyApplication(signing.VerifyApplicationRequest{
+		Policy:      policy,
+		Apps:        r,
+		Packages:    r.Packages,
+		Application: application.Package,
+		FieldLogger: r.FieldLogger,
+	})
+	if err == nil {
+		return nil
+	}
+	if errDelete := r.Packages.DeletePackage(application.Package); errDelete != nil {
+		r.WithError(errDelete).Warnf("Failed to delete package %v.", application.Package)
+	}
+	return trace.Wrap(err)
+}
@@ -18,6 +18,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -49,7 +51,6 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	utilrand "k8s.io/apimachinery/pkg/util/rand"
 )
 
 // VendorerConfig is configuration for vendorer
@@ -121,6 +122,21 @@ type VendorRequest struct {
 	ProgressReporter utils.Progress
 	// Helm contains parameters for rendering Helm charts.
 	Helm helm.RenderParameters
+	// Daemonless specifies whether to vendor images without a Docker daemon
+	// by fetching them directly from registries and image sources.
+	// Custom base images cannot be vendored in this mode since translating
+	// them into runtime packages requires exporting a container filesystem.
+	Daemonless bool
+	// ImageSources lists OCI image layout directories and 'docker save'
+	// tarballs to look up images in before pulling them from registries.
+	// Only used when vendoring without a Docker daemon.
+	ImageSources []string
+	// InsecureRegistries lists registries to pull images from over plain HTTP.
+	// Only used when vendoring without a Docker daemon.
+	InsecureRegistries []string
+	// SourceDate, if set, is recorded as the creation time of the application
+	// in place of the current time to make builds reproducible.
+	SourceDate time.Time
 }
 
 // vendorer is a helper struct that encapsulates all services needed to vendor/rewrite images in
@@ -217,7 +233,7 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	manifestRewrites := []resources.ManifestRewriteFunc{
 		makeRewriteDepsFunc(req.SetDeps),
 		makeRewritePackagesMetadataFunc(v.packages),
-		makeRewriteAppMetadataFunc(req.Repository, req.PackageName, req.PackageVersion),
+		makeRewriteAppMetadataFunc(req.Repository, req.PackageName, req.PackageVersion, req.SourceDate),
 	}
 	if req.VendorRuntime {
 		manifestRewrites = append(manifestRewrites, fetchRuntimeImages(&runtimeImages))
@@ -232,6 +248,15 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	imagesToPull := append(images, defaults.ContainerImage)
 	imagesToPull = append(imagesToPull, runtimeImages...)
 
+	if req.Daemonless {
+		if len(runtimeImages) != 0 {
+			return trace.BadParameter("custom base images %v can only be vendored "+
+				"with a Docker daemon, build the image without --daemonless "+
+				"and --image-source", runtimeImages)
+		}
+		return trace.Wrap(v.vendorDirDaemonless(ctx, unpackedDir, resourceFiles, imagesToPull, chartImages, req))
+	}
+
 	group, groupCtx := run.WithContext(ctx, run.WithParallel(req.Parallel))
 	for _, image := range imagesToPull {
 		log := log.WithField("image", image)
@@ -299,6 +324,45 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	return nil
 }
 
+// vendorDirDaemonless writes the rewritten resource files and copies the
+// referenced images into the registry directory without a Docker daemon.
+//
+// Since the resource files reference images without their original
+// registries at this point, the images are resolved back to the references
+// in originalImages before copying.
+func (v *vendorer) vendorDirDaemonless(ctx context.Context, unpackedDir string, resourceFiles resources.ResourceFiles, originalImages, chartImages []string, req VendorRequest) error {
+	if err := resourceFiles.Write(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if ok, _ := utils.IsDirectory(filepath.Join(unpackedDir, defaults.RegistryDir)); ok {
+		log.Debug("Registry layers are present.")
+		return nil
+	}
+
+	originals := make(map[string]string)
+	for _, image := range originalImages {
+		originals[v.imageService.Unwrap(v.imageService.Wrap(image))] = image
+	}
+
+	images, err := resourceFiles.Images()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	images = append(images, hooks.InitContainerImage)
+	for i, image := range images {
+		images[i] = v.imageService.Unwrap(image)
+		if original, ok := originals[images[i]]; ok {
+			images[i] = original
+		}
+	}
+	images = teleutils.Deduplicate(append(images, chartImages...))
+
+	log.Infof("Will copy images %q.", images)
+	return trace.Wrap(copyImages(ctx, unpackedDir, images, req,
+		log.WithField("export-directory", unpackedDir)))
+}
+
 // analyzeResources looks at the parsed Kubernetes/Helm resource files and
 // prints some helpful information about them to the user.
 func analyzeResources(resourceFiles, chartFiles resources.ResourceFiles, req VendorRequest) error {
@@ -464,16 +528,16 @@ func (v *vendorer) translateRuntimeImages(m *schema.Manifest) error {
 // newRuntimePackage returns a generator to generate package names.
 // Generated package names are guaranteed to not collide with legacy runtime
 // package names and be unique within a single generator.
-func newRuntimePackage(imageToPackage map[string]loc.Locator, randomSuffix randomSuffix) packageNameGeneratorFunc {
+// The same image always yields the same package name so that builds
+// are reproducible.
+func newRuntimePackage(imageToPackage map[string]loc.Locator, suffix packageSuffix) packageNameGeneratorFunc {
 	generatedNames := make(map[string]struct{})
 	nonUnique := func(name string) bool {
 		_, exists := generatedNames[name]
 		return exists
 	}
-	if randomSuffix == nil {
-		randomSuffix = func(name string) string {
-			return fmt.Sprintf("%v-%v", name, utilrand.String(4))
-		}
+	if suffix == nil {
+		suffix = imageSuffix
 	}
 	var legacyPackages = []string{loc.LegacyPlanetMaster.Name, loc.LegacyPlanetNode.Name}
 	return func(image string) (runtimePackage *loc.Locator, err error) {
@@ -488,9 +552,9 @@ func newRuntimePackage(imageToPackage map[string]loc.Locator, randomSuffix rando
 
 		// Update the name if it matches any of the legacy package names to avoid collision
 		if utils.StringInSlice(legacyPackages, name) {
-			newName := randomSuffix(name)
-			for nonUnique(newName) {
-				newName = randomSuffix(name)
+			newName := fmt.Sprintf("%v-%v", name, suffix(image, 0))
+			for attempt := 1; nonUnique(newName); attempt++ {
+				newName = fmt.Sprintf("%v-%v", name, suffix(image, attempt))
 			}
 			name = newName
 		}
@@ -506,7 +570,16 @@ func newRuntimePackage(imageToPackage map[string]loc.Locator, randomSuffix rando
 	}
 }
 
-type randomSuffix func(string) string
+// packageSuffix returns the suffix of the package name generated for the
+// specified image. attempt is incremented every time the resulting name
+// collides with a previously generated one
+type packageSuffix func(image string, attempt int) string
+
+// imageSuffix derives the package name suffix from the image reference
+func imageSuffix(image string, attempt int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v/%v", image, attempt)))
+	return hex.EncodeToString(hash[:])[:4]
+}
 
 type packageNameGeneratorFunc func(image string) (runtimePackage *loc.Locator, err error)
 
@@ -546,8 +619,9 @@ func makeRewriteSetImagesFunc(setImages []loc.DockerImage) rewriteFunc {
 	}
 }
 
-// makeRewriteAppMetadataFunc returns a function to rewrite application metadata: repository, name or version
-func makeRewriteAppMetadataFunc(setRepository, setName, setVersion string) resources.ManifestRewriteFunc {
+// makeRewriteAppMetadataFunc returns a function to rewrite application metadata: repository, name or version.
+// The creation timestamp is set to created or the current time if created is not set
+func makeRewriteAppMetadataFunc(setRepository, setName, setVersion string, created time.Time) resources.ManifestRewriteFunc {
 	return func(m *schema.Manifest) error {
 		if setRepository != "" {
 			m.Metadata.Repository = setRepository
@@ -558,7 +632,10 @@ func makeRewriteAppMetadataFunc(setRepository, setName, setVersion string) resou
 		if setVersion != "" {
 			m.Metadata.ResourceVersion = setVersion
 		}
-		m.Metadata.CreatedTimestamp = time.Now().UTC()
+		m.Metadata.CreatedTimestamp = created.UTC()
+		if created.IsZero() {
+			m.Metadata.CreatedTimestamp = time.Now().UTC()
+		}
 		return nil
 	}
 }
@@ -43,7 +43,17 @@ func DefaultOptions() *dockerarchive.TarOptions {
 // CompressDirectory compresses the directory given with dir, using writer as a sink
 // for the archive
 func CompressDirectory(dir string, writer io.Writer, items ...*Item) error {
-	archive := NewTarAppender(writer)
+	return compressDirectory(NewTarAppender(writer), dir, items...)
+}
+
+// CompressDirectoryReproducible is like CompressDirectory but normalizes
+// the headers of all archive entries with NormalizeHeader so that the same
+// directory contents and items always produce the same archive
+func CompressDirectoryReproducible(dir string, writer io.Writer, modTime time.Time, items ...*Item) error {
+	return compressDirectory(NewReproducibleTarAppender(writer, modTime), dir, items...)
+}
+
+func compressDirectory(archive *TarAppender, dir string, items ...*Item) error {
 	defer archive.Close()
 
 	if err := archive.Add(items...); err != nil {
@@ -238,11 +248,20 @@ type TarGlobHandler func(match *tar.Header, r *tar.Reader) error
 // TarAppender wraps a tar writer and can append items to it
 type TarAppender struct {
 	tw *tar.Writer
+	// modTime is the modification time to set on all items.
+	// If set, item headers are normalized
+	modTime time.Time
 }
 
 // NewTarAppender creates a new tar appender writing to w
 func NewTarAppender(w io.Writer) *TarAppender {
-	return &TarAppender{tar.NewWriter(w)}
+	return &TarAppender{tw: tar.NewWriter(w)}
+}
+
+// NewReproducibleTarAppender creates a new tar appender writing to w
+// that normalizes the headers of all items with NormalizeHeader
+func NewReproducibleTarAppender(w io.Writer, modTime time.Time) *TarAppender {
+	return &TarAppender{tw: tar.NewWriter(w), modTime: modTime}
 }
 
 // Add adds the specified items to the underlined archive
@@ -255,7 +274,9 @@ func (r *TarAppender) Add(items ...*Item) (err error) {
 		}
 	}()
 	for _, item := range items {
-		if item.ModTime.IsZero() {
+		if !r.modTime.IsZero() {
+			NormalizeHeader(&item.Header, r.modTime)
+		} else if item.ModTime.IsZero() {
 			item.ModTime = time.Now()
 		}
 		if err = r.tw.WriteHeader(&item.Header); err != nil {
@@ -276,6 +297,64 @@ func (r *TarAppender) Close() error {
 	return r.tw.Close()
 }
 
+// NormalizeHeader resets the attributes of the header that differ between
+// otherwise identical files: the modification time is set to modTime,
+// access and change times are cleared and ownership is set to
+// ArchiveUID/ArchiveGID without user and group names
+func NormalizeHeader(header *tar.Header, modTime time.Time) {
+	header.ModTime = modTime
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid = defaults.ArchiveUID
+	header.Gid = defaults.ArchiveGID
+	header.Uname = ""
+	header.Gname = ""
+}
+
+// NormalizeStream returns a stream with the tarball read from rc with
+// the headers of all entries normalized with NormalizeHeader.
+// The resulting tarball is compressed with the specified compression.
+// The entries are kept in their original order, so the source
+// is expected to list them in a stable order
+func NormalizeStream(rc io.ReadCloser, modTime time.Time, compression dockerarchive.Compression) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		defer rc.Close()
+		// always returns nil
+		writer.CloseWithError(normalizeStream(rc, writer, modTime, compression)) //nolint:errcheck
+	}()
+	return reader
+}
+
+func normalizeStream(r io.Reader, w io.Writer, modTime time.Time, compression dockerarchive.Compression) error {
+	compressed, err := dockerarchive.CompressStream(w, compression)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(compressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		NormalizeHeader(header, modTime)
+		if err := tw.WriteHeader(header); err != nil {
+			return trace.Wrap(err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(compressed.Close())
+}
+
 // ItemFromString creates an Item from given string
 func ItemFromString(path, value string) *Item {
 	return ItemFromStringMode(path, value, defaults.SharedExecutableMask)
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -121,6 +122,81 @@ func (_ *S) TestCompressesDirectory(c *C) {
 	AssertArchiveHasItems(c, ioutil.NopCloser(&buf), nil, testCases[0], testCases[1], testCases[2])
 }
 
+func (_ *S) TestCompressesDirectoryReproducibly(c *C) {
+	files := []file{
+		{name: "dir", isDir: true},
+		{name: "dir/file1", data: []byte("brown")},
+		{name: "dir/file2", data: []byte("fox")},
+	}
+	modTime := time.Unix(1577836800, 0).UTC()
+	compress := func(fileModTime time.Time) []byte {
+		dir := c.MkDir()
+		write(c, dir, files)
+		for _, file := range files {
+			path := filepath.Join(dir, file.name)
+			c.Assert(os.Chtimes(path, fileModTime, fileModTime), IsNil)
+		}
+		var buf bytes.Buffer
+		err := CompressDirectoryReproducible(dir, &buf, modTime, ItemFromString("item", "quick"))
+		c.Assert(err, IsNil)
+		return buf.Bytes()
+	}
+
+	first := compress(time.Now().Add(-time.Hour))
+	second := compress(time.Now())
+	c.Assert(first, DeepEquals, second)
+
+	tr := tar.NewReader(bytes.NewReader(first))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+		c.Assert(hdr.ModTime.Equal(modTime), Equals, true, Commentf(hdr.Name))
+		c.Assert(hdr.Uid, Equals, defaults.ArchiveUID, Commentf(hdr.Name))
+		c.Assert(hdr.Gid, Equals, defaults.ArchiveGID, Commentf(hdr.Name))
+		c.Assert(hdr.Uname, Equals, "", Commentf(hdr.Name))
+	}
+}
+
+func (_ *S) TestNormalizesStream(c *C) {
+	modTime := time.Unix(1577836800, 0).UTC()
+	input, err := CreateMemArchive([]*Item{
+		{
+			Header: tar.Header{
+				Name:    "file",
+				Mode:    defaults.SharedReadMask,
+				Size:    3,
+				Uid:     1234,
+				Gid:     1234,
+				Uname:   "bob",
+				Gname:   "bob",
+				ModTime: time.Now(),
+			},
+			Data: ioutil.NopCloser(bytes.NewReader([]byte("fox"))),
+		},
+	})
+	c.Assert(err, IsNil)
+
+	stream := NormalizeStream(ioutil.NopCloser(input), modTime, archive.Gzip)
+	defer stream.Close()
+	decompressed, err := archive.DecompressStream(stream)
+	c.Assert(err, IsNil)
+	tr := tar.NewReader(decompressed)
+	hdr, err := tr.Next()
+	c.Assert(err, IsNil)
+	c.Assert(hdr.Name, Equals, "file")
+	c.Assert(hdr.ModTime.Equal(modTime), Equals, true)
+	c.Assert([]interface{}{hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname}, DeepEquals,
+		[]interface{}{defaults.ArchiveUID, defaults.ArchiveGID, "", ""})
+	data, err := ioutil.ReadAll(tr)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "fox")
+	_, err = tr.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
 func (_ *S) TestExtractsWithoutPermissions(c *C) {
 	var data = []byte("root")
 	rc := ioutil.NopCloser(bytes.NewReader(data))
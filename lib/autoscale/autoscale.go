@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package autoscale implements provider-neutral cluster autoscaling.
+
+Masters publish the cluster join token and service URL using the configured
+provider so new instances can discover and join the cluster, and remove
+nodes from the cluster as the provider reports instances being terminated.
+
+Providers integrate with specific infrastructure, see packages aws and webhook.
+*/
+package autoscale
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// EventInstanceLaunching is the event sent when a new instance is launching
+	EventInstanceLaunching = "launching"
+	// EventInstanceTerminating is the event sent when an instance is terminating
+	EventInstanceTerminating = "terminating"
+)
+
+// Provider integrates cluster autoscaling with the underlying infrastructure
+type Provider interface {
+	// Publisher publishes the cluster discovery information
+	Publisher
+	// Discovery retrieves the cluster discovery information
+	Discovery
+	// EventSource delivers autoscaling events
+	EventSource
+	// Instances manages the provider's instances
+	Instances
+	// Name returns the name of this provider
+	Name() string
+}
+
+// Publisher publishes cluster discovery information for joining instances
+type Publisher interface {
+	// PublishJoinToken publishes the cluster join token
+	PublishJoinToken(ctx context.Context, token string) error
+	// PublishServiceURL publishes the URL of the cluster service
+	PublishServiceURL(ctx context.Context, serviceURL string) error
+}
+
+// Discovery retrieves cluster discovery information on joining instances
+type Discovery interface {
+	// GetJoinToken returns the published cluster join token
+	GetJoinToken(context.Context) (string, error)
+	// GetServiceURL returns the published URL of the cluster service
+	GetServiceURL(context.Context) (string, error)
+	// LocalInstance returns the identity of the local instance
+	LocalInstance(context.Context) (*Instance, error)
+}
+
+// EventSource delivers autoscaling events
+type EventSource interface {
+	// Receive blocks until new events are available or the context is done.
+	// Receive may return an empty list of events if it timed out waiting
+	Receive(context.Context) ([]Event, error)
+	// Ack acknowledges that the specified event has been processed
+	Ack(context.Context, Event) error
+}
+
+// Instances manages instances on the provider's infrastructure
+type Instances interface {
+	// PrepareInstance prepares the launching instance with the specified ID
+	// to become a cluster node
+	PrepareInstance(ctx context.Context, instanceID string) error
+	// WaitTerminated blocks until the instance with the specified ID
+	// has been terminated
+	WaitTerminated(ctx context.Context, instanceID string) error
+}
+
+// Event is an autoscaling event
+type Event struct {
+	// Type is the event type, e.g. EventInstanceTerminating
+	Type string `json:"type"`
+	// InstanceID identifies the instance the event is about.
+	// It is matched against the cloud instance ID, hostname and
+	// advertise address of cluster nodes
+	InstanceID string `json:"instance_id"`
+	// Handle is the provider-specific handle used to acknowledge the event
+	Handle string `json:"-"`
+}
+
+// String returns a textual representation of this event
+func (r Event) String() string {
+	return fmt.Sprintf("event(type=%v, instance=%v)", r.Type, r.InstanceID)
+}
+
+// Instance describes the identity of an instance
+type Instance struct {
+	// ID is the provider-specific instance ID
+	ID string
+	// PrivateIP is the private IP address of the instance
+	PrivateIP string
+}
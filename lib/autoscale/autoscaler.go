@@ -0,0 +1,177 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Autoscaler runs on master nodes and uses the configured provider
+// to publish cluster discovery information and to remove nodes
+// from the cluster as their instances are terminated
+type Autoscaler struct {
+	// Config is the autoscaler configuration
+	Config
+	logrus.FieldLogger
+
+	// publishedToken is the token that has been published last
+	publishedToken string
+	// publishedServiceURL is the service URL that has been published last
+	publishedServiceURL string
+}
+
+// Config is the autoscaler configuration
+type Config struct {
+	// Provider is the autoscaling provider
+	Provider Provider
+	// Operator is the cluster operator service
+	Operator Operator
+	// Client is the Kubernetes client used to discover the cluster service URL
+	Client kubernetes.Interface
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Provider == nil {
+		return trace.BadParameter("missing parameter Provider")
+	}
+	if r.Operator == nil {
+		return trace.BadParameter("missing parameter Operator")
+	}
+	return nil
+}
+
+// Operator is a subset of the cluster operator service used by the autoscaler
+type Operator interface {
+	// GetLocalSite returns the local cluster
+	GetLocalSite() (*ops.Site, error)
+	// GetExpandToken returns the cluster's expand token
+	GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error)
+	// CreateSiteShrinkOperation starts the operation to remove nodes from the cluster
+	CreateSiteShrinkOperation(context.Context, ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
+}
+
+// New returns a new autoscaler
+func New(config Config) (*Autoscaler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Autoscaler{
+		Config: config,
+		FieldLogger: logrus.WithFields(logrus.Fields{
+			trace.Component: "autoscale",
+			"provider":      config.Provider.Name(),
+		}),
+	}, nil
+}
+
+// ProcessEvents receives and processes autoscaling events from the provider
+// until the context is done
+func (a *Autoscaler) ProcessEvents(ctx context.Context) {
+	a.Info("Start processing events.")
+	for {
+		events, err := a.Provider.Receive(ctx)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				a.Info("Stop processing events.")
+				return
+			default:
+			}
+			a.WithError(err).Error("Failed to receive events.")
+			continue
+		}
+		for _, event := range events {
+			if err := a.processEvent(ctx, event); err != nil {
+				a.WithError(err).Errorf("Failed to process %v.", event)
+			}
+		}
+	}
+}
+
+func (a *Autoscaler) processEvent(ctx context.Context, event Event) error {
+	a.WithField("event", event).Info("Received autoscale event.")
+	switch event.Type {
+	case EventInstanceLaunching:
+		if err := a.Provider.PrepareInstance(ctx, event.InstanceID); err != nil {
+			return trace.Wrap(err)
+		}
+	case EventInstanceTerminating:
+		if err := a.Provider.WaitTerminated(ctx, event.InstanceID); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := a.removeInstance(ctx, event); err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	default:
+		a.Debugf("Discarding unsupported %v.", event)
+		if err := a.Provider.Ack(ctx, event); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.BadParameter("unsupported event: %v", event.Type)
+	}
+	return trace.Wrap(a.Provider.Ack(ctx, event))
+}
+
+func (a *Autoscaler) removeInstance(ctx context.Context, event Event) error {
+	cluster, err := a.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	server, err := FindServer(cluster, event.InstanceID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = a.Operator.CreateSiteShrinkOperation(ctx,
+		ops.CreateSiteShrinkOperationRequest{
+			AccountID:   cluster.AccountID,
+			SiteDomain:  cluster.Domain,
+			Servers:     []string{server.Hostname},
+			Force:       true,
+			NodeRemoved: true,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	a.Debugf("Initiated shrink operation for node %v.", server.Hostname)
+	return nil
+}
+
+// FindServer returns the cluster server that matches the specified instance
+// by either cloud instance ID, hostname or advertise address
+func FindServer(cluster *ops.Site, instanceID string) (*storage.Server, error) {
+	if instanceID == "" {
+		return nil, trace.BadParameter("missing instance ID")
+	}
+	server, err := ops.FindServerByInstanceID(cluster, instanceID)
+	if err == nil {
+		return server, nil
+	}
+	for _, server := range cluster.ClusterState.Servers {
+		if server.Hostname == instanceID || server.AdvertiseIP == instanceID {
+			return &server, nil
+		}
+	}
+	return nil, trace.NotFound("no server matching instance %q found", instanceID)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/autoscale/fake"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"gopkg.in/check.v1"
+)
+
+func TestAutoscale(t *testing.T) { check.TestingT(t) }
+
+type AutoscalerSuite struct {
+	provider *fake.Provider
+	operator *fake.Operator
+	cancel   context.CancelFunc
+}
+
+var _ = check.Suite(&AutoscalerSuite{})
+
+func (s *AutoscalerSuite) SetUpTest(c *check.C) {
+	s.provider = fake.NewProvider()
+	s.operator = fake.NewOperator(ops.Site{
+		AccountID: "1",
+		Domain:    "example.com",
+		ClusterState: storage.ClusterState{
+			Servers: []storage.Server{
+				{InstanceID: "i-1", Hostname: "node-1", AdvertiseIP: "10.0.0.1"},
+				{Hostname: "node-2", AdvertiseIP: "10.0.0.2"},
+			},
+		},
+	})
+	a, err := autoscale.New(autoscale.Config{
+		Provider: s.provider,
+		Operator: s.operator,
+	})
+	c.Assert(err, check.IsNil)
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.TODO())
+	go a.ProcessEvents(ctx)
+}
+
+func (s *AutoscalerSuite) TearDownTest(c *check.C) {
+	s.cancel()
+}
+
+func (s *AutoscalerSuite) TestRemovesTerminatedInstances(c *check.C) {
+	var testCases = []struct {
+		instanceID string
+		hostname   string
+		comment    string
+	}{
+		{instanceID: "i-1", hostname: "node-1", comment: "matched by instance ID"},
+		{instanceID: "node-2", hostname: "node-2", comment: "matched by hostname"},
+		{instanceID: "10.0.0.2", hostname: "node-2", comment: "matched by advertise address"},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		event := autoscale.Event{
+			Type:       autoscale.EventInstanceTerminating,
+			InstanceID: tc.instanceID,
+		}
+		s.provider.EventsC <- event
+		c.Assert(receiveString(c, s.provider.TerminatedC), check.Equals, tc.instanceID, comment)
+		select {
+		case req := <-s.operator.ShrinksC:
+			c.Assert(req.Servers, check.DeepEquals, []string{tc.hostname}, comment)
+			c.Assert(req.Force, check.Equals, true, comment)
+		case <-time.After(time.Second):
+			c.Fatalf("timeout waiting for shrink operation: %v", tc.comment)
+		}
+		c.Assert(receiveEvent(c, s.provider.AckedC), check.DeepEquals, event, comment)
+	}
+}
+
+func (s *AutoscalerSuite) TestAcksEventsForUnknownInstances(c *check.C) {
+	event := autoscale.Event{
+		Type:       autoscale.EventInstanceTerminating,
+		InstanceID: "i-unknown",
+	}
+	s.provider.EventsC <- event
+	c.Assert(receiveEvent(c, s.provider.AckedC), check.DeepEquals, event)
+	c.Assert(s.operator.ShrinksC, check.HasLen, 0)
+}
+
+func (s *AutoscalerSuite) TestPreparesLaunchingInstances(c *check.C) {
+	event := autoscale.Event{
+		Type:       autoscale.EventInstanceLaunching,
+		InstanceID: "i-2",
+	}
+	s.provider.EventsC <- event
+	c.Assert(receiveString(c, s.provider.PreparedC), check.Equals, "i-2")
+	c.Assert(receiveEvent(c, s.provider.AckedC), check.DeepEquals, event)
+}
+
+func (s *AutoscalerSuite) TestDiscardsUnsupportedEvents(c *check.C) {
+	event := autoscale.Event{
+		Type:       "autoscaling:TEST_NOTIFICATION",
+		InstanceID: "i-1",
+	}
+	s.provider.EventsC <- event
+	c.Assert(receiveEvent(c, s.provider.AckedC), check.DeepEquals, event)
+	c.Assert(s.provider.PreparedC, check.HasLen, 0)
+	c.Assert(s.provider.TerminatedC, check.HasLen, 0)
+}
+
+func receiveEvent(c *check.C, eventsC chan autoscale.Event) autoscale.Event {
+	select {
+	case event := <-eventsC:
+		return event
+	case <-time.After(time.Second):
+		c.Fatal("timeout waiting for event")
+	}
+	return autoscale.Event{}
+}
+
+func receiveString(c *check.C, valuesC chan string) string {
+	select {
+	case value := <-valuesC:
+		return value
+	case <-time.After(time.Second):
+		c.Fatal("timeout waiting for value")
+	}
+	return ""
+}
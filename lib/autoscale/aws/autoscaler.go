@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
@@ -33,40 +34,35 @@ import (
 
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
-	"k8s.io/client-go/kubernetes"
 )
 
 const (
+	// ProviderName is the name of the AWS autoscaling provider
+	ProviderName = "aws"
 	// InstanceLaunching is AWS instance launching lifecycle autoscaling event
 	InstanceLaunching = "autoscaling:EC2_INSTANCE_LAUNCHING"
 	// InstanceTermination is AWS instance terminating lifecycle autoscaling event
 	InstanceTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
 )
 
-// Autoscaler is AWS autoscaler server, it enables nodes
-// to discover cluster information via AWS Systems Manager (SSM) Parameter Store
-// and Masters to add/remove nodes from the cluster as they join
-// via discovery group
-type Autoscaler struct {
-	// Config is Autoscaler config
+// Provider is the AWS autoscaling provider. It publishes cluster discovery
+// information to AWS Systems Manager (SSM) Parameter Store and receives
+// auto scaling group lifecycle hook notifications via SQS
+type Provider struct {
+	// Config is the provider config
 	Config
 	// QueueURL is SQS queue name with notifications
 	QueueURL string
 	*log.Entry
-
-	// publishedToken is the token that has been published to SSM
-	publishedToken string
-	// publishedserviceURL is the service url that has been published to SSM
-	publishedServiceURL string
 }
 
-// Config is autoscaler config
+var _ autoscale.Provider = (*Provider)(nil)
+
+// Config is the AWS provider config
 type Config struct {
 	// ClusterName is a Telekube cluster name,
 	// used to discover configuration in the cluster
 	ClusterName string
-	// Client is an optional kubernetes client
-	Client *kubernetes.Clientset
 	// SSM is AWS systems manager parameter store,
 	// metadata store used to store configuration
 	SystemsManager SSM
@@ -89,8 +85,8 @@ func (cfg *Config) CheckAndSetDefaults() error {
 	return nil
 }
 
-// New returns new instance of AWS autoscaler
-func New(cfg Config) (*Autoscaler, error) {
+// New returns a new AWS autoscaling provider
+func New(cfg Config) (*Provider, error) {
 	if err := cfg.CheckAndSetDefaults(); err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -114,26 +110,34 @@ func New(cfg Config) (*Autoscaler, error) {
 	if cfg.Cloud == nil {
 		cfg.Cloud = ec2.New(sess)
 	}
-	a := &Autoscaler{
+	a := &Provider{
 		Config: cfg,
-		Entry:  log.WithFields(log.Fields{trace.Component: "autoscale"}),
+		Entry:  log.WithFields(log.Fields{trace.Component: "autoscale:aws"}),
 	}
 	return a, nil
 }
 
-// DeleteEvent deletes SQS message associated with event
-func (a *Autoscaler) DeleteEvent(ctx context.Context, event HookEvent) error {
-	a.Debugf("DeleteEvent(%v)", event.Type)
-	_, err := a.Queue.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(event.QueueURL),
-		ReceiptHandle: aws.String(event.ReceiptHandle),
-	})
-	return trace.Wrap(err)
+// Name returns the name of this provider
+func (a *Provider) Name() string {
+	return ProviderName
+}
+
+// LocalInstance returns the identity of the local instance
+// from the EC2 instance metadata
+func (a *Provider) LocalInstance(context.Context) (*autoscale.Instance, error) {
+	instance, err := a.NewLocalInstance()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &autoscale.Instance{
+		ID:        instance.ID,
+		PrivateIP: instance.PrivateIP,
+	}, nil
 }
 
 // TurnOffSourceDestination check turns off source destination check on the instance
 // that is necessary for K8s to function properly
-func (a *Autoscaler) TurnOffSourceDestinationCheck(ctx context.Context, instanceID string) error {
+func (a *Provider) TurnOffSourceDestinationCheck(ctx context.Context, instanceID string) error {
 	a.Debugf("TurnOffSourceDestinationCheck(%v)", instanceID)
 	_, err := a.Cloud.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
 		InstanceId:      aws.String(instanceID),
@@ -143,7 +147,7 @@ func (a *Autoscaler) TurnOffSourceDestinationCheck(ctx context.Context, instance
 }
 
 // DescribeInstance returns information about instance with the specified ID.
-func (a *Autoscaler) DescribeInstance(ctx context.Context, instanceID string) (*ec2.Instance, error) {
+func (a *Provider) DescribeInstance(ctx context.Context, instanceID string) (*ec2.Instance, error) {
 	a.Debugf("DescribeInstance(%v)", instanceID)
 	resp, err := a.Cloud.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: aws.StringSlice([]string{instanceID}),
@@ -166,7 +170,7 @@ func (a *Autoscaler) DescribeInstance(ctx context.Context, instanceID string) (*
 // Note: If an incorrect or non-existent ID is provided, the method will block
 // indefinitely (or until timeout has been reached) so it is advised to query
 // the instance using DescribeInstance method prior to calling it.
-func (a *Autoscaler) WaitUntilInstanceTerminated(ctx context.Context, instanceID string) error {
+func (a *Provider) WaitUntilInstanceTerminated(ctx context.Context, instanceID string) error {
 	a.Debugf("WaitUntilInstanceTerminated(%v)", instanceID)
 	localCtx, cancel := context.WithTimeout(ctx, defaults.InstanceTerminationTimeout)
 	defer cancel()
@@ -177,7 +181,7 @@ func (a *Autoscaler) WaitUntilInstanceTerminated(ctx context.Context, instanceID
 }
 
 // GetJoinToken fetches and decrypts cluster join token from SSM parameter
-func (a *Autoscaler) GetJoinToken(ctx context.Context) (string, error) {
+func (a *Provider) GetJoinToken(ctx context.Context) (string, error) {
 	name := a.tokenParam()
 	a.Debugf("GetJoinToken(%v)", name)
 	resp, err := a.SystemsManager.GetParameterWithContext(ctx, &ssm.GetParameterInput{
@@ -191,7 +195,7 @@ func (a *Autoscaler) GetJoinToken(ctx context.Context) (string, error) {
 }
 
 // GetServiceURL returns service URL
-func (a *Autoscaler) GetServiceURL(ctx context.Context) (string, error) {
+func (a *Provider) GetServiceURL(ctx context.Context) (string, error) {
 	name := a.serviceURLParam()
 	a.Debugf("GetServiceURL(%v)", name)
 	resp, err := a.SystemsManager.GetParameterWithContext(ctx, &ssm.GetParameterInput{
@@ -204,32 +208,21 @@ func (a *Autoscaler) GetServiceURL(ctx context.Context) (string, error) {
 	return aws.StringValue(resp.Parameter.Value), nil
 }
 
-func (a *Autoscaler) publishServiceURL(ctx context.Context, serviceURL string, force bool) error {
-	// only publish if there is a change
-	if serviceURL == a.publishedServiceURL && !force {
-		return nil
-	}
-
+// PublishServiceURL publishes the cluster service URL as an SSM parameter
+func (a *Provider) PublishServiceURL(ctx context.Context, serviceURL string) error {
 	name := a.serviceURLParam()
+	a.Debugf("PublishServiceURL(%v)", name)
 	_, err := a.SystemsManager.PutParameterWithContext(ctx, &ssm.PutParameterInput{
 		Type:      aws.String("String"),
 		Name:      aws.String(name),
 		Value:     aws.String(serviceURL),
 		Overwrite: aws.Bool(true),
 	})
-	if err != nil {
-		return ConvertError(err)
-	}
-	a.publishedServiceURL = serviceURL
-	return nil
+	return ConvertError(err)
 }
 
-func (a *Autoscaler) publishJoinToken(ctx context.Context, token string, force bool) error {
-	// only publish if there is a change
-	if token == a.publishedToken && !force {
-		return nil
-	}
-
+// PublishJoinToken publishes the cluster join token as an encrypted SSM parameter
+func (a *Provider) PublishJoinToken(ctx context.Context, token string) error {
 	name := a.tokenParam()
 	a.Debugf("PublishJoinToken(%v)", name)
 	_, err := a.SystemsManager.PutParameterWithContext(ctx, &ssm.PutParameterInput{
@@ -238,19 +231,14 @@ func (a *Autoscaler) publishJoinToken(ctx context.Context, token string, force b
 		Value:     aws.String(token),
 		Overwrite: aws.Bool(true),
 	})
-	if err != nil {
-		return ConvertError(err)
-	}
-
-	a.publishedToken = token
-	return nil
+	return ConvertError(err)
 }
 
-func (a *Autoscaler) tokenParam() string {
+func (a *Provider) tokenParam() string {
 	return fmt.Sprintf("/telekube/%v/token", a.ClusterName)
 }
 
-func (a *Autoscaler) serviceURLParam() string {
+func (a *Provider) serviceURLParam() string {
 	return fmt.Sprintf("/telekube/%v/service", a.ClusterName)
 }
 
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -50,7 +51,7 @@ func (s *AutoscalerSuite) TestInstanceTerminate(c *check.C) {
 		InstanceId: aws.String("instance-1"),
 	})
 	queue := newMockQueue("queue-1")
-	a, err := New(Config{
+	provider, err := New(Config{
 		ClusterName: clusterName,
 		NewLocalInstance: func() (*gaws.Instance, error) {
 			return instance, nil
@@ -59,7 +60,7 @@ func (s *AutoscalerSuite) TestInstanceTerminate(c *check.C) {
 		Cloud: ec,
 	})
 	c.Assert(err, check.IsNil)
-	c.Assert(a, check.NotNil)
+	provider.QueueURL = queue.url
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -74,7 +75,12 @@ func (s *AutoscalerSuite) TestInstanceTerminate(c *check.C) {
 			Servers: []storage.Server{server},
 		},
 	})
-	go a.ProcessEvents(ctx, queue.url, op)
+	a, err := autoscale.New(autoscale.Config{
+		Provider: provider,
+		Operator: op,
+	})
+	c.Assert(err, check.IsNil)
+	go a.ProcessEvents(ctx)
 
 	// send terminated event
 	msg := &message{
@@ -117,7 +123,7 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 	ec := newMockEC2(&ec2.Instance{
 		InstanceId: aws.String("instance-1"),
 	})
-	a, err := New(Config{
+	provider, err := New(Config{
 		ClusterName: clusterName,
 		NewLocalInstance: func() (*gaws.Instance, error) {
 			return instance, nil
@@ -126,7 +132,7 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 		Cloud: ec,
 	})
 	c.Assert(err, check.IsNil)
-	c.Assert(a, check.NotNil)
+	provider.QueueURL = queue.url
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -135,7 +141,12 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 		Domain:       "example.com",
 		ClusterState: storage.ClusterState{},
 	})
-	go a.ProcessEvents(ctx, queue.url, op)
+	a, err := autoscale.New(autoscale.Config{
+		Provider: provider,
+		Operator: op,
+	})
+	c.Assert(err, check.IsNil)
+	go a.ProcessEvents(ctx)
 
 	// send launched event
 	instanceID := "instance-1"
@@ -276,6 +287,10 @@ func (o *mockOperator) GetLocalSite() (*ops.Site, error) {
 	return &o.site, nil
 }
 
+func (o *mockOperator) GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error) {
+	return nil, trace.NotImplemented("not implemented")
+}
+
 func (o *mockOperator) CreateSiteShrinkOperation(ctx context.Context, req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
 	select {
 	case o.shrinksC <- &req:
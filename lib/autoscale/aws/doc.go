@@ -14,7 +14,8 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-/* package aws implements autoscaling integration for AWS cloud provider
+/* package aws implements autoscaling integration for AWS cloud provider.
+The provider is driven by the provider-neutral autoscaler in package autoscale.
 
 Design
 ------
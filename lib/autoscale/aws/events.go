@@ -21,22 +21,17 @@ import (
 	"encoding/json"
 	"regexp"
 
-	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/autoscale"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/sqs"
 
 	"github.com/gravitational/trace"
-	log "github.com/sirupsen/logrus"
 )
 
 // HookEvent is a lifecycle hook event posted by autoscaling group
 type HookEvent struct {
-	// QueueURL is a queue this event belongs to
-	QueueURL string `json:"-"`
-	// ReceiptHandle is SQS receipt handle
-	ReceiptHandle string `json:"-"`
 	// InstanceID is AWS instance ID
 	InstanceID string `json:"EC2InstanceId"`
 	// Type is event type
@@ -44,7 +39,7 @@ type HookEvent struct {
 }
 
 // GetQueueURL returns queue URL associated with this cluster
-func (a *Autoscaler) GetQueueURL(ctx context.Context) (string, error) {
+func (a *Provider) GetQueueURL(ctx context.Context) (string, error) {
 	expr, err := regexp.Compile(`[^a-zA-Z0-9\-]`)
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -60,75 +55,56 @@ func (a *Autoscaler) GetQueueURL(ctx context.Context) (string, error) {
 	return aws.StringValue(out.QueueUrl), nil
 }
 
-// ProcessEvents listens for events on SQS queue that are sent by the auto scaling
-// group lifecycle hooks.
-func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operator Operator) {
-	a.WithField("queue", queueURL).Info("Start processing events.")
-	for {
-		out, err := a.Queue.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(queueURL),
-			MaxNumberOfMessages: aws.Int64(1),
-			VisibilityTimeout:   aws.Int64(30),
-			WaitTimeSeconds:     aws.Int64(5),
-		})
+// Receive receives the next batch of events from the SQS queue that are sent
+// by the auto scaling group lifecycle hooks
+func (a *Provider) Receive(ctx context.Context) ([]autoscale.Event, error) {
+	out, err := a.Queue.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(a.QueueURL),
+		MaxNumberOfMessages: aws.Int64(1),
+		VisibilityTimeout:   aws.Int64(30),
+		WaitTimeSeconds:     aws.Int64(5),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var events []autoscale.Event
+	for _, m := range out.Messages {
+		a.Debugf("got message body: %q", aws.StringValue(m.Body))
+		hook, err := unmarshalHook(aws.StringValue(m.Body))
 		if err != nil {
-			select {
-			case <-ctx.Done():
-				a.WithField("queue", queueURL).Info("Stop processing events.")
-				return
-			default:
-			}
-			a.Errorf("receive message error: %v", trace.DebugReport(err))
+			a.Errorf("failed to unmarshal hook: %v", trace.DebugReport(err))
 			continue
 		}
-		for _, m := range out.Messages {
-			a.Debugf("got message body: %q", aws.StringValue(m.Body))
-			hook, err := unmarshalHook(aws.StringValue(m.Body))
-			if err != nil {
-				a.Errorf("failed to unmarshal hook: %v", trace.DebugReport(err))
-			}
-			hook.ReceiptHandle = aws.StringValue(m.ReceiptHandle)
-			hook.QueueURL = queueURL
-			if err := a.processEvent(ctx, operator, *hook); err != nil {
-				a.Errorf("failed to process hook: %v", trace.DebugReport(err))
-			}
-		}
+		events = append(events, autoscale.Event{
+			Type:       eventType(hook.Type),
+			InstanceID: hook.InstanceID,
+			Handle:     aws.StringValue(m.ReceiptHandle),
+		})
 	}
+	return events, nil
 }
 
-func (a *Autoscaler) processEvent(ctx context.Context, operator Operator, event HookEvent) error {
-	a.WithField("event", event).Info("Received autoscale event.")
-	switch event.Type {
-	case InstanceLaunching:
-		if err := a.TurnOffSourceDestinationCheck(ctx, event.InstanceID); err != nil {
-			return trace.Wrap(err)
-		}
-		if err := a.DeleteEvent(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
-	case InstanceTerminating:
-		if err := a.ensureInstanceTerminated(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
-		if err := a.removeInstance(ctx, operator, event); err != nil && !trace.IsNotFound(err) {
-			return trace.Wrap(err)
-		}
-		if err := a.DeleteEvent(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
-	default:
-		log.Debugf("Discarding unsupported event %#v.", event)
-		if err := a.DeleteEvent(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
-		return trace.BadParameter("unsupported event: %v", event.Type)
-	}
-	return nil
+// Ack deletes the SQS message associated with the event
+func (a *Provider) Ack(ctx context.Context, event autoscale.Event) error {
+	a.Debugf("Ack(%v)", event)
+	_, err := a.Queue.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(a.QueueURL),
+		ReceiptHandle: aws.String(event.Handle),
+	})
+	return trace.Wrap(err)
 }
 
-func (a *Autoscaler) ensureInstanceTerminated(ctx context.Context, event HookEvent) error {
-	log := a.WithField("instance", event.InstanceID)
-	instance, err := a.DescribeInstance(ctx, event.InstanceID)
+// PrepareInstance turns off the source/destination check on the launching
+// instance which is necessary for Kubernetes networking to function
+func (a *Provider) PrepareInstance(ctx context.Context, instanceID string) error {
+	return trace.Wrap(a.TurnOffSourceDestinationCheck(ctx, instanceID))
+}
+
+// WaitTerminated blocks until the instance with the specified ID is terminated.
+// Returns immediately if the instance does not exist
+func (a *Provider) WaitTerminated(ctx context.Context, instanceID string) error {
+	log := a.WithField("instance", instanceID)
+	instance, err := a.DescribeInstance(ctx, instanceID)
 	if err != nil && !trace.IsNotFound(err) {
 		return trace.Wrap(err)
 	}
@@ -141,38 +117,23 @@ func (a *Autoscaler) ensureInstanceTerminated(ctx context.Context, event HookEve
 		return nil
 	}
 	log.Info("Waiting for instance to terminate.")
-	if err = a.WaitUntilInstanceTerminated(ctx, event.InstanceID); err != nil {
+	if err = a.WaitUntilInstanceTerminated(ctx, instanceID); err != nil {
 		return trace.Wrap(err)
 	}
 	log.Info("Instance has been terminated.")
 	return nil
 }
 
-func (a *Autoscaler) removeInstance(ctx context.Context, operator Operator, event HookEvent) error {
-	cluster, err := operator.GetLocalSite()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	server, err := ops.FindServerByInstanceID(cluster, event.InstanceID)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	_, err = operator.CreateSiteShrinkOperation(ctx,
-		ops.CreateSiteShrinkOperationRequest{
-			AccountID:   cluster.AccountID,
-			SiteDomain:  cluster.Domain,
-			Servers:     []string{server.Hostname},
-			Force:       true,
-			NodeRemoved: true,
-		})
-	if err != nil {
-		return trace.Wrap(err)
+// eventType converts the lifecycle transition to autoscaling event type.
+// Unsupported transitions are returned as-is
+func eventType(transition string) string {
+	switch transition {
+	case InstanceLaunching:
+		return autoscale.EventInstanceLaunching
+	case InstanceTerminating:
+		return autoscale.EventInstanceTerminating
 	}
-
-	a.Debugf("initiated shrink operation for node %v", server.Hostname)
-	return nil
+	return transition
 }
 
 func mustMarshalHook(e HookEvent) string {
@@ -17,10 +17,7 @@ limitations under the License.
 package aws
 
 import (
-	"context"
-
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
-	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -49,10 +46,4 @@ type EC2 interface {
 	WaitUntilInstanceTerminatedWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.WaiterOption) error
 }
 
-// Operator is a simplified operator interface to mock in tests
-type Operator interface {
-	GetLocalSite() (*ops.Site, error)
-	CreateSiteShrinkOperation(context.Context, ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
-}
-
 type NewLocalInstance func() (*gaws.Instance, error)
@@ -1,5 +1,5 @@
 /*
-Copyright 2018 Gravitational, Inc.
+Copyright 2019 Gravitational, Inc.
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -14,7 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package aws
+package autoscale
 
 import (
 	"context"
@@ -23,33 +23,35 @@ import (
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
-	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/gravitational/trace"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// PublishDiscovery periodically updates discovery information
-func (a *Autoscaler) PublishDiscovery(ctx context.Context, operator ops.Operator) {
+// PublishDiscovery periodically publishes cluster discovery information
+// using the provider until the context is done
+func (a *Autoscaler) PublishDiscovery(ctx context.Context) {
 	a.Info("Start publishing discovery info.")
-	err := a.syncDiscovery(ctx, operator, true)
+	err := a.syncDiscovery(ctx, true)
 	if err != nil {
 		a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
 	}
 	publishTicker := time.NewTicker(defaults.DiscoveryPublishInterval)
+	defer publishTicker.Stop()
 	resyncTicker := time.NewTicker(defaults.DiscoveryResyncInterval)
+	defer resyncTicker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			a.Info("Stop publishing discovery info.")
 			return
 		case <-publishTicker.C:
-			err = a.syncDiscovery(ctx, operator, false)
+			err = a.syncDiscovery(ctx, false)
 			if err != nil {
 				a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
 			}
 		case <-resyncTicker.C:
-			err = a.syncDiscovery(ctx, operator, true)
+			err = a.syncDiscovery(ctx, true)
 			if err != nil {
 				a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
 			}
@@ -57,35 +59,42 @@ func (a *Autoscaler) PublishDiscovery(ctx context.Context, operator ops.Operator
 	}
 }
 
-// syncDiscovery syncs cluster discovery information in the SSM
-func (a *Autoscaler) syncDiscovery(ctx context.Context, operator ops.Operator, force bool) error {
-	cluster, err := operator.GetLocalSite()
+// syncDiscovery publishes the cluster join token and service URL.
+// Unless force is set, only the values that have changed since
+// the last successful publish are published
+func (a *Autoscaler) syncDiscovery(ctx context.Context, force bool) error {
+	cluster, err := a.Operator.GetLocalSite()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-
-	if err := a.syncToken(ctx, operator, cluster, force); err != nil {
+	joinToken, err := a.Operator.GetExpandToken(cluster.Key())
+	if err != nil {
 		return trace.Wrap(err)
 	}
-
-	if err := a.syncMasterService(ctx, force); err != nil {
-		return trace.Wrap(err)
+	if joinToken.Token != a.publishedToken || force {
+		if err := a.Provider.PublishJoinToken(ctx, joinToken.Token); err != nil {
+			return trace.Wrap(err)
+		}
+		a.publishedToken = joinToken.Token
 	}
-	return nil
-}
-
-func (a *Autoscaler) syncToken(ctx context.Context, operator ops.Operator, cluster *ops.Site, force bool) error {
-	joinToken, err := operator.GetExpandToken(cluster.Key())
+	serviceURL, err := a.getServiceURL()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	if err := a.publishJoinToken(ctx, joinToken.Token, force); err != nil {
-		return trace.Wrap(err)
+	if serviceURL != a.publishedServiceURL || force {
+		if err := a.Provider.PublishServiceURL(ctx, serviceURL); err != nil {
+			return trace.Wrap(err)
+		}
+		a.publishedServiceURL = serviceURL
 	}
 	return nil
 }
 
+// getServiceURL returns the URL of the cluster load balancer service
 func (a *Autoscaler) getServiceURL() (string, error) {
+	if a.Client == nil {
+		return "", trace.BadParameter("no Kubernetes client to discover cluster service")
+	}
 	service, err := a.Client.CoreV1().Services(constants.KubeSystemNamespace).Get(constants.GravityServiceName, v1.GetOptions{})
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -104,14 +113,9 @@ func (a *Autoscaler) getServiceURL() (string, error) {
 		if ingress.Hostname != "" {
 			return fmt.Sprintf("https://%v:%v", ingress.Hostname, port), nil
 		}
+		if ingress.IP != "" {
+			return fmt.Sprintf("https://%v:%v", ingress.IP, port), nil
+		}
 	}
 	return "", trace.NotFound("ingress load balancer not found for %v", constants.GravityServiceName)
 }
-
-func (a *Autoscaler) syncMasterService(ctx context.Context, force bool) error {
-	serviceURL, err := a.getServiceURL()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	return a.publishServiceURL(ctx, serviceURL, force)
-}
@@ -0,0 +1,220 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake implements in-memory autoscaling provider and operator
+// for testing the autoscaler without access to a real infrastructure
+package fake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// NewProvider returns a new fake provider
+func NewProvider() *Provider {
+	return &Provider{
+		EventsC:     make(chan autoscale.Event, capacity),
+		AckedC:      make(chan autoscale.Event, capacity),
+		PreparedC:   make(chan string, capacity),
+		TerminatedC: make(chan string, capacity),
+		Instance: autoscale.Instance{
+			ID:        "instance-1",
+			PrivateIP: "127.0.0.1",
+		},
+	}
+}
+
+// Provider is a fake autoscaling provider.
+//
+// Events sent to EventsC are delivered to the autoscaler, and
+// acknowledged events, prepared and terminated instances are
+// reported on the respective channels
+type Provider struct {
+	// EventsC delivers events to the autoscaler
+	EventsC chan autoscale.Event
+	// AckedC receives acknowledged events
+	AckedC chan autoscale.Event
+	// PreparedC receives IDs of prepared instances
+	PreparedC chan string
+	// TerminatedC receives IDs of instances waited on to terminate
+	TerminatedC chan string
+	// Instance is returned as the local instance
+	Instance autoscale.Instance
+
+	mu         sync.Mutex
+	token      string
+	serviceURL string
+	published  int
+}
+
+var _ autoscale.Provider = (*Provider)(nil)
+
+// Name returns the name of this provider
+func (p *Provider) Name() string {
+	return "fake"
+}
+
+// PublishJoinToken stores the join token
+func (p *Provider) PublishJoinToken(ctx context.Context, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = token
+	p.published++
+	return nil
+}
+
+// PublishServiceURL stores the service URL
+func (p *Provider) PublishServiceURL(ctx context.Context, serviceURL string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.serviceURL = serviceURL
+	p.published++
+	return nil
+}
+
+// GetJoinToken returns the stored join token
+func (p *Provider) GetJoinToken(context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == "" {
+		return "", trace.NotFound("join token has not been published")
+	}
+	return p.token, nil
+}
+
+// GetServiceURL returns the stored service URL
+func (p *Provider) GetServiceURL(context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.serviceURL == "" {
+		return "", trace.NotFound("service URL has not been published")
+	}
+	return p.serviceURL, nil
+}
+
+// Published returns the total number of publish calls
+func (p *Provider) Published() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.published
+}
+
+// LocalInstance returns the configured local instance
+func (p *Provider) LocalInstance(context.Context) (*autoscale.Instance, error) {
+	instance := p.Instance
+	return &instance, nil
+}
+
+// Receive returns the next event sent to EventsC
+func (p *Provider) Receive(ctx context.Context) ([]autoscale.Event, error) {
+	select {
+	case event := <-p.EventsC:
+		return []autoscale.Event{event}, nil
+	case <-time.After(receiveTimeout):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, trace.Wrap(ctx.Err())
+	}
+}
+
+// Ack reports the event on AckedC
+func (p *Provider) Ack(ctx context.Context, event autoscale.Event) error {
+	select {
+	case p.AckedC <- event:
+		return nil
+	default:
+		return trace.LimitExceeded("blocked on channel send")
+	}
+}
+
+// PrepareInstance reports the instance on PreparedC
+func (p *Provider) PrepareInstance(ctx context.Context, instanceID string) error {
+	select {
+	case p.PreparedC <- instanceID:
+		return nil
+	default:
+		return trace.LimitExceeded("blocked on channel send")
+	}
+}
+
+// WaitTerminated reports the instance on TerminatedC
+func (p *Provider) WaitTerminated(ctx context.Context, instanceID string) error {
+	select {
+	case p.TerminatedC <- instanceID:
+		return nil
+	default:
+		return trace.LimitExceeded("blocked on channel send")
+	}
+}
+
+// NewOperator returns a new fake operator for the specified cluster
+func NewOperator(cluster ops.Site) *Operator {
+	return &Operator{
+		Cluster:  cluster,
+		ShrinksC: make(chan ops.CreateSiteShrinkOperationRequest, capacity),
+	}
+}
+
+// Operator is a fake cluster operator
+type Operator struct {
+	// Cluster is the local cluster
+	Cluster ops.Site
+	// Token is the cluster expand token
+	Token string
+	// ShrinksC receives requests to create shrink operations
+	ShrinksC chan ops.CreateSiteShrinkOperationRequest
+}
+
+// GetLocalSite returns the local cluster
+func (o *Operator) GetLocalSite() (*ops.Site, error) {
+	return &o.Cluster, nil
+}
+
+// GetExpandToken returns the cluster expand token
+func (o *Operator) GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error) {
+	if o.Token == "" {
+		return nil, trace.NotFound("expand token not found")
+	}
+	return &storage.ProvisioningToken{Token: o.Token}, nil
+}
+
+// CreateSiteShrinkOperation reports the request on ShrinksC
+func (o *Operator) CreateSiteShrinkOperation(ctx context.Context, req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
+	select {
+	case o.ShrinksC <- req:
+	default:
+		return nil, trace.LimitExceeded("blocked on channel send")
+	}
+	return &ops.SiteOperationKey{
+		AccountID:   o.Cluster.AccountID,
+		SiteDomain:  o.Cluster.Domain,
+		OperationID: "op-1",
+	}, nil
+}
+
+const (
+	// capacity is the capacity of the fake channels
+	capacity = 10
+	// receiveTimeout is how long Receive waits for new events
+	receiveTimeout = 100 * time.Millisecond
+)
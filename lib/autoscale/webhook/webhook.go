@@ -0,0 +1,383 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package webhook implements a generic autoscaling provider for clusters
+running on infrastructure without a dedicated provider.
+
+  - Cluster discovery information is published to and retrieved from an
+    external HTTP key/value endpoint: the join token and the service URL are
+    stored with PUT and read with GET requests to <discovery-url>/<cluster>/token
+    and <discovery-url>/<cluster>/service respectively.
+  - The infrastructure notifies the cluster about launching and terminating
+    instances by posting autoscale.Event JSON documents to the /events endpoint
+    served over TLS by the provider on the active master node. The request
+    completes once the event has been processed and should be retried
+    if it fails.
+
+All requests are authenticated with the shared secret passed as a bearer token.
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ProviderName is the name of the webhook autoscaling provider
+const ProviderName = "webhook"
+
+// Config is the webhook provider configuration
+type Config struct {
+	// ClusterName is the name of the cluster used to scope discovery information
+	ClusterName string
+	// DiscoveryURL is the base URL of the endpoint storing discovery information
+	DiscoveryURL string
+	// Secret is the shared secret used to authenticate requests
+	Secret string
+	// Client is an optional HTTP client for the discovery endpoint
+	Client *http.Client
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.ClusterName == "" {
+		return trace.BadParameter("missing parameter ClusterName")
+	}
+	if r.DiscoveryURL == "" {
+		return trace.BadParameter("missing parameter DiscoveryURL")
+	}
+	if r.Secret == "" {
+		return trace.BadParameter("missing parameter Secret")
+	}
+	if r.Client == nil {
+		r.Client = httplib.GetClient(false, httplib.WithTimeout(defaults.DialTimeout))
+	}
+	r.DiscoveryURL = strings.TrimSuffix(r.DiscoveryURL, "/")
+	return nil
+}
+
+// Provider is the webhook autoscaling provider
+type Provider struct {
+	// Config is the provider configuration
+	Config
+	logrus.FieldLogger
+	// Router serves the events endpoint
+	*httprouter.Router
+	eventsC chan autoscale.Event
+	// mu guards pending
+	mu sync.Mutex
+	// pending maps handles of the events whose senders are waiting for them
+	// to be processed to the channels closed once the events are acknowledged
+	pending map[string]chan struct{}
+}
+
+var _ autoscale.Provider = (*Provider)(nil)
+
+// New returns a new webhook autoscaling provider
+func New(config Config) (*Provider, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	p := &Provider{
+		Config:      config,
+		FieldLogger: logrus.WithField(trace.Component, "autoscale:webhook"),
+		Router:      httprouter.New(),
+		eventsC:     make(chan autoscale.Event, eventsCapacity),
+		pending:     make(map[string]chan struct{}),
+	}
+	p.POST("/events", p.postEvent)
+	return p, nil
+}
+
+// Name returns the name of this provider
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// ListenAndServe serves the events endpoint over TLS on the specified address
+// until the context is done
+func (p *Provider) ListenAndServe(ctx context.Context, addr string, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		return trace.BadParameter("missing parameter tlsConfig")
+	}
+	server := &http.Server{Addr: addr, Handler: p, TLSConfig: tlsConfig}
+	errC := make(chan error, 1)
+	go func() {
+		// the certificates are provided by the TLS config
+		errC <- server.ListenAndServeTLS("", "")
+	}()
+	p.WithField("addr", addr).Info("Serving autoscale events.")
+	select {
+	case err := <-errC:
+		return trace.Wrap(err)
+	case <-ctx.Done():
+		return trace.Wrap(server.Close())
+	}
+}
+
+// Receive returns the events posted to the events endpoint.
+// Events whose senders have stopped waiting for them are skipped
+func (p *Provider) Receive(ctx context.Context) ([]autoscale.Event, error) {
+	select {
+	case event := <-p.eventsC:
+		events := p.filterPending(nil, event)
+		for {
+			select {
+			case event := <-p.eventsC:
+				events = p.filterPending(events, event)
+			default:
+				return events, nil
+			}
+		}
+	case <-time.After(receiveTimeout):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, trace.Wrap(ctx.Err())
+	}
+}
+
+// Ack acknowledges the event to its sender
+func (p *Provider) Ack(_ context.Context, event autoscale.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	doneC, ok := p.pending[event.Handle]
+	if !ok {
+		p.WithField("event", event).Debug("Sender is no longer waiting for event.")
+		return nil
+	}
+	close(doneC)
+	delete(p.pending, event.Handle)
+	return nil
+}
+
+// PrepareInstance is a no-op for this provider
+func (p *Provider) PrepareInstance(context.Context, string) error {
+	return nil
+}
+
+// WaitTerminated is a no-op for this provider as the terminating
+// event is expected to be sent once the instance is gone
+func (p *Provider) WaitTerminated(context.Context, string) error {
+	return nil
+}
+
+// PublishJoinToken publishes the cluster join token to the discovery endpoint
+func (p *Provider) PublishJoinToken(ctx context.Context, token string) error {
+	return trace.Wrap(p.put(ctx, tokenKey, token))
+}
+
+// PublishServiceURL publishes the cluster service URL to the discovery endpoint
+func (p *Provider) PublishServiceURL(ctx context.Context, serviceURL string) error {
+	return trace.Wrap(p.put(ctx, serviceKey, serviceURL))
+}
+
+// GetJoinToken returns the cluster join token from the discovery endpoint
+func (p *Provider) GetJoinToken(ctx context.Context) (string, error) {
+	token, err := p.get(ctx, tokenKey)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return token, nil
+}
+
+// GetServiceURL returns the cluster service URL from the discovery endpoint
+func (p *Provider) GetServiceURL(ctx context.Context) (string, error) {
+	serviceURL, err := p.get(ctx, serviceKey)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return serviceURL, nil
+}
+
+// LocalInstance returns the identity of the local instance.
+// The instance is identified by its hostname
+func (p *Provider) LocalInstance(context.Context) (*autoscale.Instance, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	addr, err := utils.PickAdvertiseIP()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &autoscale.Instance{
+		ID:        hostname,
+		PrivateIP: addr,
+	}, nil
+}
+
+func (p *Provider) postEvent(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := p.authenticate(r); err != nil {
+		trace.WriteError(w, err)
+		return
+	}
+	var event autoscale.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		trace.WriteError(w, trace.BadParameter("failed to decode event: %v", err))
+		return
+	}
+	if event.Type == "" || event.InstanceID == "" {
+		trace.WriteError(w, trace.BadParameter("event should specify type and instance_id"))
+		return
+	}
+	event.Handle = uuid.New()
+	doneC := p.addPending(event.Handle)
+	defer p.removePending(event.Handle)
+	select {
+	case p.eventsC <- event:
+		p.WithField("event", event).Debug("Accepted event.")
+	default:
+		trace.WriteError(w, trace.LimitExceeded("too many pending events"))
+		return
+	}
+	select {
+	case <-doneC:
+		w.WriteHeader(http.StatusOK)
+	case <-time.After(ackTimeout):
+		trace.WriteError(w, trace.ConnectionProblem(nil,
+			"event has not been processed in %v, retry later", ackTimeout))
+	case <-r.Context().Done():
+		p.WithField("event", event).Debug("Sender has stopped waiting for event.")
+	}
+}
+
+// addPending registers the event with the specified handle as waiting
+// to be processed and returns the channel closed once it is acknowledged
+func (p *Provider) addPending(handle string) <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	doneC := make(chan struct{})
+	p.pending[handle] = doneC
+	return doneC
+}
+
+// removePending removes the event with the specified handle
+// from the events waiting to be processed
+func (p *Provider) removePending(handle string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, handle)
+}
+
+// filterPending appends the event to the list if its sender
+// is still waiting for it to be processed
+func (p *Provider) filterPending(events []autoscale.Event, event autoscale.Event) []autoscale.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pending[event.Handle]; !ok {
+		p.WithField("event", event).Debug("Skip event abandoned by its sender.")
+		return events
+	}
+	return append(events, event)
+}
+
+func (p *Provider) authenticate(r *http.Request) error {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.Secret)) != 1 {
+		return trace.AccessDenied("invalid credentials")
+	}
+	return nil
+}
+
+func (p *Provider) put(ctx context.Context, key, value string) error {
+	p.Debugf("Publish %v.", p.keyURL(key))
+	req, err := p.newRequest(ctx, http.MethodPut, key, bytes.NewBufferString(value))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = p.do(req)
+	return trace.Wrap(err)
+}
+
+func (p *Provider) get(ctx context.Context, key string) (string, error) {
+	req, err := p.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	value, err := p.do(req)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func (p *Provider) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, p.keyURL(key), body)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", p.Secret))
+	return req.WithContext(ctx), nil
+}
+
+func (p *Provider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, trace.NotFound("%v not found", req.URL)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest:
+		return nil, trace.BadParameter("%v %v: unexpected status: %v", req.Method, req.URL, resp.Status)
+	}
+	return body, nil
+}
+
+func (p *Provider) keyURL(key string) string {
+	return fmt.Sprintf("%v/%v/%v", p.DiscoveryURL, p.ClusterName, key)
+}
+
+const (
+	// tokenKey is the discovery key of the cluster join token
+	tokenKey = "token"
+	// serviceKey is the discovery key of the cluster service URL
+	serviceKey = "service"
+	// eventsCapacity is the maximum number of pending events
+	eventsCapacity = 100
+	// receiveTimeout is how long Receive waits for new events
+	receiveTimeout = 5 * time.Second
+	// ackTimeout is how long the sender of an event waits for
+	// the event to be processed
+	ackTimeout = time.Minute
+)
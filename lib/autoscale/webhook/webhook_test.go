@@ -0,0 +1,185 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestWebhook(t *testing.T) { check.TestingT(t) }
+
+type WebhookSuite struct {
+	discovery *discoveryServer
+	server    *httptest.Server
+	provider  *Provider
+}
+
+var _ = check.Suite(&WebhookSuite{})
+
+func (s *WebhookSuite) SetUpTest(c *check.C) {
+	s.discovery = &discoveryServer{values: make(map[string]string)}
+	s.server = httptest.NewServer(s.discovery)
+	var err error
+	s.provider, err = New(Config{
+		ClusterName:  "example.com",
+		DiscoveryURL: s.server.URL + "/",
+		Secret:       "secret",
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *WebhookSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+}
+
+func (s *WebhookSuite) TestPublishesDiscovery(c *check.C) {
+	ctx := context.TODO()
+	_, err := s.provider.GetJoinToken(ctx)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+
+	c.Assert(s.provider.PublishJoinToken(ctx, "token"), check.IsNil)
+	c.Assert(s.provider.PublishServiceURL(ctx, "https://example.com:3009"), check.IsNil)
+	c.Assert(s.discovery.values, check.DeepEquals, map[string]string{
+		"/example.com/token":   "token",
+		"/example.com/service": "https://example.com:3009",
+	})
+
+	token, err := s.provider.GetJoinToken(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "token")
+	serviceURL, err := s.provider.GetServiceURL(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(serviceURL, check.Equals, "https://example.com:3009")
+
+	s.provider.Secret = "invalid"
+	_, err = s.provider.GetJoinToken(ctx)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *WebhookSuite) TestReceivesEvents(c *check.C) {
+	var testCases = []struct {
+		secret  string
+		body    string
+		code    int
+		comment string
+	}{
+		{
+			secret:  "invalid",
+			body:    `{"type": "terminating", "instance_id": "node-2"}`,
+			code:    http.StatusForbidden,
+			comment: "invalid secret",
+		},
+		{
+			secret:  "secret",
+			body:    `{"type": "terminating"}`,
+			code:    http.StatusBadRequest,
+			comment: "missing instance",
+		},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer "+tc.secret)
+		w := httptest.NewRecorder()
+		s.provider.ServeHTTP(w, req)
+		c.Assert(w.Code, check.Equals, tc.code, check.Commentf(tc.comment))
+	}
+
+	// the sender waits for a valid event to be processed
+	codeC := make(chan int, 1)
+	go func() {
+		codeC <- s.postEvent(context.TODO(), `{"type": "terminating", "instance_id": "node-1"}`)
+	}()
+	events, err := s.provider.Receive(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 1)
+	c.Assert(events[0].Handle, check.Not(check.Equals), "")
+	c.Assert(events[0].Type, check.Equals, autoscale.EventInstanceTerminating)
+	c.Assert(events[0].InstanceID, check.Equals, "node-1")
+	select {
+	case code := <-codeC:
+		c.Fatalf("Sender completed with %v before the event has been processed.", code)
+	default:
+	}
+	c.Assert(s.provider.Ack(context.TODO(), events[0]), check.IsNil)
+	c.Assert(<-codeC, check.Equals, http.StatusOK)
+}
+
+func (s *WebhookSuite) TestSkipsAbandonedEvents(c *check.C) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	codeC := make(chan int, 1)
+	go func() {
+		codeC <- s.postEvent(ctx, `{"type": "terminating", "instance_id": "node-1"}`)
+	}()
+	// wait for the event to be queued and abandon it
+	for len(s.provider.eventsC) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-codeC
+
+	events, err := s.provider.Receive(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 0)
+}
+
+// postEvent posts the event to the provider and returns the response code
+func (s *WebhookSuite) postEvent(ctx context.Context, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.provider.ServeHTTP(w, req.WithContext(ctx))
+	return w.Code
+}
+
+// discoveryServer is an in-memory key/value discovery endpoint
+type discoveryServer struct {
+	sync.Mutex
+	values map[string]string
+}
+
+func (s *discoveryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		value, _ := ioutil.ReadAll(r.Body)
+		s.values[r.URL.Path] = string(value)
+	case http.MethodGet:
+		value, ok := s.values[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(value)) //nolint:errcheck
+	}
+}
@@ -91,6 +91,65 @@ func (a *ObjectsACL) GetBLOBEnvelope(hash string) (*Envelope, error) {
 	return a.objects.GetBLOBEnvelope(hash)
 }
 
+// GetBLOBManifest returns the chunk manifest of the BLOB
+func (a *ObjectsACL) GetBLOBManifest(hash string) (*Manifest, error) {
+	objects, err := a.chunked(teleservices.VerbRead)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects.GetBLOBManifest(hash)
+}
+
+// GetMissingChunks returns the subset of the specified chunk hashes
+// that are not present in the storage
+func (a *ObjectsACL) GetMissingChunks(hashes []string) ([]string, error) {
+	objects, err := a.chunked(teleservices.VerbRead)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects.GetMissingChunks(hashes)
+}
+
+// OpenChunk opens the chunk by hash and returns reader object
+func (a *ObjectsACL) OpenChunk(hash string) (io.ReadCloser, error) {
+	objects, err := a.chunked(teleservices.VerbRead)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects.OpenChunk(hash)
+}
+
+// WriteChunk writes the chunk with the specified hash to storage
+func (a *ObjectsACL) WriteChunk(hash string, data io.Reader) error {
+	objects, err := a.chunked(teleservices.VerbCreate)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return objects.WriteChunk(hash, data)
+}
+
+// WriteBLOBManifest creates the BLOB from the chunks already present in the storage
+func (a *ObjectsACL) WriteBLOBManifest(manifest Manifest) (*Envelope, error) {
+	objects, err := a.chunked(teleservices.VerbCreate)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects.WriteBLOBManifest(manifest)
+}
+
+// chunked checks whether the user has the requested permissions and
+// returns the wrapped storage if it supports chunked BLOB operations
+func (a *ObjectsACL) chunked(action string) (ChunkedObjects, error) {
+	if err := a.check(action); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	objects, ok := a.objects.(ChunkedObjects)
+	if !ok {
+		return nil, trace.NotImplemented("storage does not support chunked BLOBs")
+	}
+	return objects, nil
+}
+
 // check checks whether the user has the requested permissions
 func (a *ObjectsACL) check(action string) error {
 	// first check the access to all repositories
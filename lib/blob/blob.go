@@ -54,3 +54,38 @@ type Objects interface {
 	// GetBLOBEnvelope returns BLOB envelope
 	GetBLOBEnvelope(hash string) (*Envelope, error)
 }
+
+// Manifest describes a BLOB stored as a sequence of content-defined chunks
+type Manifest struct {
+	// Envelope is the envelope of the whole BLOB
+	Envelope
+	// Chunks lists the BLOB chunks in the order they appear in the BLOB
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk describes a single chunk of a BLOB
+type Chunk struct {
+	// SHA512 is the half SHA512 hash of the chunk
+	SHA512 string `json:"sha512"`
+	// SizeBytes is the chunk size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// ChunkedObjects is a BLOB storage that splits BLOBs into chunks and
+// stores every unique chunk once. It allows peers to exchange only
+// the chunks that are missing on the receiving side
+type ChunkedObjects interface {
+	Objects
+	// GetBLOBManifest returns the chunk manifest of the BLOB
+	GetBLOBManifest(hash string) (*Manifest, error)
+	// GetMissingChunks returns the subset of the specified chunk hashes
+	// that are not present in the storage
+	GetMissingChunks(hashes []string) ([]string, error)
+	// OpenChunk opens the chunk by hash and returns reader object
+	OpenChunk(hash string) (io.ReadCloser, error)
+	// WriteChunk writes the chunk with the specified hash to storage
+	WriteChunk(hash string, data io.Reader) error
+	// WriteBLOBManifest creates the BLOB from the chunks already present
+	// in the storage, on success returns the envelope of the BLOB
+	WriteBLOBManifest(manifest Manifest) (*Envelope, error)
+}
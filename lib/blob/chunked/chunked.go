@@ -0,0 +1,688 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chunked implements BLOB storage that splits BLOBs into
+// content-defined chunks and stores every unique chunk once, so BLOBs
+// that share most of their content (e.g. consecutive versions of the same
+// application package) only consume disk space for the differences.
+//
+// The storage directory is organized as follows:
+//
+//	chunks/<hash[0:3]>/<hash>     - chunk data
+//	manifests/<hash[0:3]>/<hash>  - BLOB manifests listing the BLOB chunks
+//	tmp/                          - temporary files
+package chunked
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config is the chunked BLOB storage configuration
+type Config struct {
+	// Path is the storage directory
+	Path string
+	// MinChunkSize is the minimum chunk size
+	MinChunkSize int
+	// AvgChunkSize is the target average chunk size, must be a power of 2
+	AvgChunkSize int
+	// MaxChunkSize is the maximum chunk size
+	MaxChunkSize int
+	// Clock is clock interface, used in tests
+	Clock clockwork.Clock
+}
+
+// CheckAndSetDefaults validates the config and sets default values
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Path == "" {
+		return trace.BadParameter("missing Path parameter")
+	}
+	if c.MinChunkSize == 0 {
+		c.MinChunkSize = defaults.MinChunkSize
+	}
+	if c.AvgChunkSize == 0 {
+		c.AvgChunkSize = defaults.AvgChunkSize
+	}
+	if c.MaxChunkSize == 0 {
+		c.MaxChunkSize = defaults.MaxChunkSize
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.AvgChunkSize&(c.AvgChunkSize-1) != 0 {
+		return trace.BadParameter("AvgChunkSize should be a power of 2, got %v", c.AvgChunkSize)
+	}
+	if c.MinChunkSize >= c.AvgChunkSize || c.AvgChunkSize >= c.MaxChunkSize {
+		return trace.BadParameter("expected MinChunkSize < AvgChunkSize < MaxChunkSize, got %v, %v, %v",
+			c.MinChunkSize, c.AvgChunkSize, c.MaxChunkSize)
+	}
+	return nil
+}
+
+// New returns a new instance of the chunked BLOB storage
+func New(config Config) (*Objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o := &Objects{
+		Config:   config,
+		pins:     make(map[string]*chunkPin),
+		released: make(map[string]struct{}),
+	}
+	for _, d := range []string{o.tempDir(), o.chunkDir(), o.manifestDir()} {
+		if err := os.MkdirAll(d, defaults.SharedDirMask); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return o, nil
+}
+
+// Objects is the chunked BLOB storage.
+//
+// Chunks are shared between BLOBs, so deleting a BLOB only removes the
+// chunks no other BLOB refers to. Writes hold a shared lock and deletes
+// hold an exclusive lock to prevent removing a chunk that is being
+// referenced by a BLOB being written.
+//
+// BLOBs replicated chunk by chunk only reference their chunks once the
+// manifest has been written, so the chunks uploaded or found present in the
+// meantime are pinned to keep deletes from removing them.
+// Open BLOB readers pin the chunks of their BLOB until they are closed.
+//
+// The chunk references are indexed in memory. The index is built from the
+// BLOB manifests on the first delete and updated as manifests are written,
+// so deleting a BLOB only has to look at the chunks of the deleted BLOB
+type Objects struct {
+	Config
+	sync.RWMutex
+	// mu guards pins, refs and released between concurrent writers
+	mu sync.Mutex
+	// pins maps pinned chunks to their pins.
+	// Pins are only updated under the shared lock and are read by deletes
+	// under the exclusive lock
+	pins map[string]*chunkPin
+	// refs maps chunks to the set of BLOBs referencing them.
+	// The index is nil until it has been loaded
+	refs map[string]map[string]struct{}
+	// released lists the chunks unpinned since the last delete
+	// that might need to be removed
+	released map[string]struct{}
+}
+
+// chunkPin counts the pending BLOB uploads and open readers referencing a chunk
+type chunkPin struct {
+	// count is the number of pending uploads referencing the chunk
+	count int
+	// readers is the number of open readers referencing the chunk.
+	// Unlike the pins of pending uploads, the pins of readers do not expire
+	readers int
+	// updated is the time the chunk was last pinned by an upload
+	updated time.Time
+}
+
+func (o *Objects) tempDir() string {
+	return filepath.Join(o.Path, "tmp")
+}
+
+func (o *Objects) chunkDir() string {
+	return filepath.Join(o.Path, "chunks")
+}
+
+func (o *Objects) manifestDir() string {
+	return filepath.Join(o.Path, "manifests")
+}
+
+func (o *Objects) chunkPath(hash string) string {
+	return filepath.Join(o.chunkDir(), hash[0:3], hash)
+}
+
+func (o *Objects) manifestPath(hash string) string {
+	return filepath.Join(o.manifestDir(), hash[0:3], hash)
+}
+
+// Close closes the storage
+func (o *Objects) Close() error {
+	return nil
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *Objects) GetBLOBs() ([]string, error) {
+	out, err := listFiles(o.manifestDir())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// WriteBLOB splits the data into chunks and writes the chunks
+// missing in the storage, returns object envelope
+func (o *Objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	o.RLock()
+	defer o.RUnlock()
+
+	hasher := sha512.New()
+	chunker := newChunker(io.TeeReader(data, hasher),
+		o.MinChunkSize, o.AvgChunkSize, o.MaxChunkSize)
+	var manifest blob.Manifest
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		hash := halfHash(chunk)
+		if err := o.writeChunk(hash, bytes.NewReader(chunk)); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		manifest.Chunks = append(manifest.Chunks, blob.Chunk{
+			SHA512:    hash,
+			SizeBytes: int64(len(chunk)),
+		})
+		manifest.SizeBytes += int64(len(chunk))
+	}
+	manifest.SHA512 = fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	return o.writeManifest(manifest)
+}
+
+// GetBLOBEnvelope returns file information identified by hash
+func (o *Objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	manifest, err := o.GetBLOBManifest(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &manifest.Envelope, nil
+}
+
+// GetBLOBManifest returns the chunk manifest of the BLOB
+func (o *Objects) GetBLOBManifest(hash string) (*blob.Manifest, error) {
+	if err := checkHash(hash); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.readManifest(o.manifestPath(hash))
+}
+
+// OpenBLOB opens file identified by hash and returns reader.
+// The BLOB chunks are pinned until the reader is closed
+func (o *Objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	o.RLock()
+	defer o.RUnlock()
+	manifest, err := o.GetBLOBManifest(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hashes := chunkHashes(manifest.Chunks)
+	o.pinForRead(hashes...)
+	return newReader(manifest.Chunks, o.chunkPath, func() {
+		o.RLock()
+		defer o.RUnlock()
+		o.unpinForRead(hashes...)
+	}), nil
+}
+
+// DeleteBLOB deletes BLOB from the storage along with the
+// chunks not referenced by any other BLOB
+func (o *Objects) DeleteBLOB(hash string) error {
+	if err := checkHash(hash); err != nil {
+		return trace.Wrap(err)
+	}
+	o.Lock()
+	defer o.Unlock()
+	if o.refs == nil {
+		if err := o.loadRefs(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	manifest, err := o.readManifest(o.manifestPath(hash))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Remove(o.manifestPath(hash)); err != nil {
+		return trace.Wrap(err)
+	}
+	candidates := make(map[string]struct{}, len(manifest.Chunks))
+	for _, chunk := range manifest.Chunks {
+		delete(o.refs[chunk.SHA512], hash)
+		if len(o.refs[chunk.SHA512]) == 0 {
+			delete(o.refs, chunk.SHA512)
+		}
+		candidates[chunk.SHA512] = struct{}{}
+	}
+	o.removeUnreferencedChunks(candidates)
+	return nil
+}
+
+// GetMissingChunks returns the subset of the specified chunk hashes
+// that are not present in the storage.
+// The chunks that are present are pinned until the BLOB manifest
+// referencing them is written
+func (o *Objects) GetMissingChunks(hashes []string) (missing []string, err error) {
+	o.RLock()
+	defer o.RUnlock()
+	missing, err = o.getMissingChunks(hashes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	present := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		present[hash] = struct{}{}
+	}
+	for _, hash := range missing {
+		delete(present, hash)
+	}
+	for hash := range present {
+		o.pin(hash)
+	}
+	return missing, nil
+}
+
+func (o *Objects) getMissingChunks(hashes []string) (missing []string, err error) {
+	for _, hash := range hashes {
+		if err := checkHash(hash); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		_, err := os.Stat(o.chunkPath(hash))
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		missing = append(missing, hash)
+	}
+	return missing, nil
+}
+
+// OpenChunk opens the chunk identified by hash
+func (o *Objects) OpenChunk(hash string) (io.ReadCloser, error) {
+	if err := checkHash(hash); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	f, err := os.Open(o.chunkPath(hash))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return f, nil
+}
+
+// WriteChunk writes the chunk to the storage verifying its hash.
+// The chunk is pinned until the BLOB manifest referencing it is written
+func (o *Objects) WriteChunk(hash string, data io.Reader) error {
+	if err := checkHash(hash); err != nil {
+		return trace.Wrap(err)
+	}
+	o.RLock()
+	defer o.RUnlock()
+	if err := o.writeChunk(hash, data); err != nil {
+		return trace.Wrap(err)
+	}
+	o.pin(hash)
+	return nil
+}
+
+// WriteBLOBManifest creates the BLOB from the chunks present in the storage.
+// The BLOB hash and size are verified against the chunk data
+func (o *Objects) WriteBLOBManifest(manifest blob.Manifest) (*blob.Envelope, error) {
+	if err := checkHash(manifest.SHA512); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o.RLock()
+	defer o.RUnlock()
+	hashes := chunkHashes(manifest.Chunks)
+	missing, err := o.getMissingChunks(hashes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(missing) != 0 {
+		return nil, trace.NotFound("missing %v chunks of %v", len(missing), manifest.SHA512)
+	}
+	reader := newReader(manifest.Chunks, o.chunkPath, nil)
+	defer reader.Close()
+	hasher := sha512.New()
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	if hash != manifest.SHA512 || size != manifest.SizeBytes {
+		return nil, trace.BadParameter("manifest %v(%v bytes) does not match chunk data %v(%v bytes)",
+			manifest.SHA512, manifest.SizeBytes, hash, size)
+	}
+	envelope, err := o.writeManifest(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the manifest references the chunks now
+	o.unpin(hashes...)
+	return envelope, nil
+}
+
+// pin keeps the chunk from being removed until it is unpinned as many
+// times as it has been pinned or the pin expires.
+// Must be called under the shared lock
+func (o *Objects) pin(hash string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p := o.getPin(hash)
+	p.count++
+	p.updated = o.Clock.Now()
+}
+
+// unpin releases a pin on each of the specified chunks.
+// Must be called under the shared lock
+func (o *Objects) unpin(hashes ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, hash := range hashes {
+		p, ok := o.pins[hash]
+		if !ok || p.count == 0 {
+			continue
+		}
+		p.count--
+		o.releasePin(hash, p)
+	}
+}
+
+// pinForRead keeps the specified chunks from being removed
+// until they are unpinned with unpinForRead.
+// Must be called under the shared lock
+func (o *Objects) pinForRead(hashes ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, hash := range hashes {
+		o.getPin(hash).readers++
+	}
+}
+
+// unpinForRead releases a reader pin on each of the specified chunks.
+// Must be called under the shared lock
+func (o *Objects) unpinForRead(hashes ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, hash := range hashes {
+		p, ok := o.pins[hash]
+		if !ok || p.readers == 0 {
+			continue
+		}
+		p.readers--
+		o.releasePin(hash, p)
+	}
+}
+
+// getPin returns the pin of the specified chunk, creating it if necessary.
+// Must be called with mu held
+func (o *Objects) getPin(hash string) *chunkPin {
+	p, ok := o.pins[hash]
+	if !ok {
+		p = &chunkPin{}
+		o.pins[hash] = p
+	}
+	return p
+}
+
+// releasePin removes the pin once it is no longer held and records
+// the chunk to be looked at by the next delete.
+// Must be called with mu held
+func (o *Objects) releasePin(hash string, p *chunkPin) {
+	if p.count > 0 || p.readers > 0 {
+		return
+	}
+	delete(o.pins, hash)
+	if o.refs != nil {
+		o.released[hash] = struct{}{}
+	}
+}
+
+// addRefs records the chunks of the specified BLOB in the chunk index
+// if it has been loaded.
+// Must be called under the shared lock
+func (o *Objects) addRefs(manifest blob.Manifest) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.refs == nil {
+		return
+	}
+	for _, chunk := range manifest.Chunks {
+		blobs, ok := o.refs[chunk.SHA512]
+		if !ok {
+			blobs = make(map[string]struct{})
+			o.refs[chunk.SHA512] = blobs
+		}
+		blobs[manifest.SHA512] = struct{}{}
+	}
+}
+
+// writeChunk writes the chunk unless it is already present in the storage
+func (o *Objects) writeChunk(hash string, data io.Reader) error {
+	targetPath := o.chunkPath(hash)
+	if _, err := os.Stat(targetPath); err == nil {
+		return nil
+	}
+	return trace.Wrap(o.writeFile(targetPath, func(w io.Writer) error {
+		hasher := sha512.New()
+		if _, err := io.Copy(io.MultiWriter(w, hasher), data); err != nil {
+			return trace.Wrap(err)
+		}
+		if actual := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]); actual != hash {
+			return trace.BadParameter("chunk hash mismatch: expected %v, got %v", hash, actual)
+		}
+		return nil
+	}))
+}
+
+func (o *Objects) writeManifest(manifest blob.Manifest) (*blob.Envelope, error) {
+	targetPath := o.manifestPath(manifest.SHA512)
+	err := o.writeFile(targetPath, func(w io.Writer) error {
+		return trace.Wrap(json.NewEncoder(w).Encode(manifest))
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o.addRefs(manifest)
+	fileInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &blob.Envelope{
+		SizeBytes: manifest.SizeBytes,
+		SHA512:    manifest.SHA512,
+		Modified:  fileInfo.ModTime().UTC(),
+	}, nil
+}
+
+func (o *Objects) readManifest(path string) (*blob.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer f.Close()
+	var manifest blob.Manifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return nil, trace.Wrap(err, "failed to read manifest %v", path)
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest.Modified = fileInfo.ModTime().UTC()
+	return &manifest, nil
+}
+
+// writeFile writes the file contents into a temporary file first
+// and then atomically moves it to the target path
+func (o *Objects) writeFile(targetPath string, write func(io.Writer) error) error {
+	f, err := ioutil.TempFile(o.tempDir(), "blob")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		defer os.Remove(f.Name())
+		return trace.Wrap(err)
+	}
+	if err := f.Close(); err != nil {
+		defer os.Remove(f.Name())
+		return trace.Wrap(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), defaults.SharedDirMask); err != nil {
+		defer os.Remove(f.Name())
+		return trace.Wrap(err)
+	}
+	if err := os.Rename(f.Name(), targetPath); err != nil {
+		defer os.Remove(f.Name())
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// loadRefs builds the chunk index from the BLOB manifests and removes
+// the chunks that are neither referenced by any BLOB nor pinned, e.g. the
+// chunks left over after a restart.
+// This reads every manifest in the storage, so it is only done once:
+// afterwards the index is updated as BLOBs are written and deleted.
+// Must be called under the write lock
+func (o *Objects) loadRefs() error {
+	hashes, err := o.GetBLOBs()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	refs := make(map[string]map[string]struct{})
+	for _, hash := range hashes {
+		manifest, err := o.readManifest(o.manifestPath(hash))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, chunk := range manifest.Chunks {
+			blobs, ok := refs[chunk.SHA512]
+			if !ok {
+				blobs = make(map[string]struct{})
+				refs[chunk.SHA512] = blobs
+			}
+			blobs[hash] = struct{}{}
+		}
+	}
+	o.refs = refs
+	chunks, err := listFiles(o.chunkDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	candidates := make(map[string]struct{}, len(chunks))
+	for _, hash := range chunks {
+		candidates[hash] = struct{}{}
+	}
+	o.removeUnreferencedChunks(candidates)
+	return nil
+}
+
+// removeUnreferencedChunks removes the candidate chunks, as well as
+// the chunks released since the last delete, that are neither referenced
+// by any BLOB nor pinned. Expired pins of pending uploads are removed.
+// Must be called under the write lock
+func (o *Objects) removeUnreferencedChunks(candidates map[string]struct{}) {
+	now := o.Clock.Now()
+	for hash, pin := range o.pins {
+		if pin.count > 0 && now.Sub(pin.updated) > defaults.ChunkPinTTL {
+			pin.count = 0
+			o.releasePin(hash, pin)
+		}
+	}
+	for hash := range o.released {
+		candidates[hash] = struct{}{}
+	}
+	o.released = make(map[string]struct{})
+	for hash := range candidates {
+		if _, ok := o.refs[hash]; ok {
+			continue
+		}
+		if _, ok := o.pins[hash]; ok {
+			continue
+		}
+		if err := os.Remove(o.chunkPath(hash)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove chunk %v: %v.", hash, err)
+		}
+	}
+}
+
+// listFiles returns names of all files in the directory tree
+func listFiles(dir string) ([]string, error) {
+	var out []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warningf("error while traversing %v: %v", dir, err)
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		out = append(out, info.Name())
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+// chunkHashes returns the unique hashes of the specified chunks
+func chunkHashes(chunks []blob.Chunk) []string {
+	seen := make(map[string]struct{}, len(chunks))
+	hashes := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		if _, ok := seen[chunk.SHA512]; ok {
+			continue
+		}
+		seen[chunk.SHA512] = struct{}{}
+		hashes = append(hashes, chunk.SHA512)
+	}
+	return hashes
+}
+
+// checkHash makes sure the hash is a valid half SHA512 hash
+// as hashes are used to build paths in the storage directory
+func checkHash(hash string) error {
+	if len(hash) != sha512.Size {
+		return trace.BadParameter("invalid hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return trace.BadParameter("invalid hash %q", hash)
+	}
+	return nil
+}
+
+// halfHash returns the half SHA512 hash of the data
+func halfHash(data []byte) string {
+	sum := sha512.Sum512(data)
+	return hex.EncodeToString(sum[:sha512.Size/2])
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/suite"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+func TestChunked(t *testing.T) { TestingT(t) }
+
+type ChunkedSuite struct {
+	suite   suite.BLOBSuite
+	objects *Objects
+}
+
+var _ = Suite(&ChunkedSuite{})
+
+func (s *ChunkedSuite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+
+	obj, err := New(Config{
+		Path:         c.MkDir(),
+		MinChunkSize: 1024,
+		AvgChunkSize: 4096,
+		MaxChunkSize: 16384,
+	})
+	c.Assert(err, IsNil)
+
+	s.objects = obj
+	s.suite.Objects = obj
+}
+
+func (s *ChunkedSuite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *ChunkedSuite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *ChunkedSuite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *ChunkedSuite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *ChunkedSuite) TestDeduplicatesSharedContent(c *C) {
+	data := randomData(256 * 1024)
+	// insert a few bytes in the middle of the data
+	modified := append(append(append([]byte{}, data[:100000]...), []byte("update")...), data[100000:]...)
+
+	e1, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	chunks1, err := listFiles(s.objects.chunkDir())
+	c.Assert(err, IsNil)
+
+	e2, err := s.objects.WriteBLOB(bytes.NewReader(modified))
+	c.Assert(err, IsNil)
+	chunks2, err := listFiles(s.objects.chunkDir())
+	c.Assert(err, IsNil)
+
+	// only the chunks around the change should have been added
+	added := len(chunks2) - len(chunks1)
+	c.Assert(added > 0, Equals, true)
+	c.Assert(added <= 3, Equals, true, Commentf("added %v chunks out of %v", added, len(chunks1)))
+
+	s.assertContents(c, e1.SHA512, data)
+	s.assertContents(c, e2.SHA512, modified)
+
+	// deleting a BLOB keeps the chunks shared with other BLOBs
+	c.Assert(s.objects.DeleteBLOB(e1.SHA512), IsNil)
+	s.assertContents(c, e2.SHA512, modified)
+	chunks, err := listFiles(s.objects.chunkDir())
+	c.Assert(err, IsNil)
+	manifest, err := s.objects.GetBLOBManifest(e2.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(len(chunks), Equals, uniqueChunks(manifest.Chunks))
+
+	c.Assert(s.objects.DeleteBLOB(e2.SHA512), IsNil)
+	chunks, err = listFiles(s.objects.chunkDir())
+	c.Assert(err, IsNil)
+	c.Assert(chunks, HasLen, 0)
+}
+
+func (s *ChunkedSuite) TestSeekAcrossChunks(c *C) {
+	data := randomData(64 * 1024)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	manifest, err := s.objects.GetBLOBManifest(e.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(len(manifest.Chunks) > 1, Equals, true)
+
+	r, err := s.objects.OpenBLOB(e.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	for _, offset := range []int64{0, 1023, 5000, 30000, int64(len(data)) - 10} {
+		_, err = r.Seek(offset, io.SeekStart)
+		c.Assert(err, IsNil)
+		out := make([]byte, 3000)
+		n, err := io.ReadFull(r, out)
+		if err != io.ErrUnexpectedEOF {
+			c.Assert(err, IsNil)
+		}
+		c.Assert(out[:n], DeepEquals, data[offset:offset+int64(n)])
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(len(data)))
+}
+
+func (s *ChunkedSuite) TestWriteBLOBManifest(c *C) {
+	data := randomData(64 * 1024)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	manifest, err := s.objects.GetBLOBManifest(e.SHA512)
+	c.Assert(err, IsNil)
+
+	target, err := New(Config{
+		Path:         c.MkDir(),
+		MinChunkSize: 1024,
+		AvgChunkSize: 4096,
+		MaxChunkSize: 16384,
+	})
+	c.Assert(err, IsNil)
+
+	_, err = target.WriteBLOBManifest(*manifest)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	var hashes []string
+	for _, chunk := range manifest.Chunks {
+		hashes = append(hashes, chunk.SHA512)
+	}
+	missing, err := target.GetMissingChunks(hashes)
+	c.Assert(err, IsNil)
+	for _, hash := range missing {
+		r, err := s.objects.OpenChunk(hash)
+		c.Assert(err, IsNil)
+		c.Assert(target.WriteChunk(hash, r), IsNil)
+		r.Close()
+	}
+
+	// chunk data is verified against the hash
+	c.Assert(target.WriteChunk(utils.MustSHA512Half([]byte("a")), bytes.NewReader([]byte("b"))), NotNil)
+
+	envelope, err := target.WriteBLOBManifest(*manifest)
+	c.Assert(err, IsNil)
+	c.Assert(envelope.SHA512, Equals, e.SHA512)
+	c.Assert(envelope.SizeBytes, Equals, e.SizeBytes)
+
+	r, err := target.OpenBLOB(e.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(out, DeepEquals, data)
+}
+
+func (s *ChunkedSuite) TestKeepsChunksOfPendingBLOBs(c *C) {
+	data := randomData(64 * 1024)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	manifest, err := s.objects.GetBLOBManifest(e.SHA512)
+	c.Assert(err, IsNil)
+	var hashes []string
+	for _, chunk := range manifest.Chunks {
+		hashes = append(hashes, chunk.SHA512)
+	}
+
+	clock := clockwork.NewFakeClock()
+	target, err := New(Config{
+		Path:         c.MkDir(),
+		MinChunkSize: 1024,
+		AvgChunkSize: 4096,
+		MaxChunkSize: 16384,
+		Clock:        clock,
+	})
+	c.Assert(err, IsNil)
+	// the first half of the chunks is shared with another BLOB
+	// that is deleted while the chunks are being uploaded
+	other, err := target.WriteBLOB(bytes.NewReader(data[:32*1024]))
+	c.Assert(err, IsNil)
+	missing, err := target.GetMissingChunks(hashes)
+	c.Assert(err, IsNil)
+	c.Assert(len(missing) < len(hashes), Equals, true)
+	for _, hash := range missing {
+		r, err := s.objects.OpenChunk(hash)
+		c.Assert(err, IsNil)
+		c.Assert(target.WriteChunk(hash, r), IsNil)
+		r.Close()
+	}
+	c.Assert(target.DeleteBLOB(other.SHA512), IsNil)
+
+	missing, err = target.GetMissingChunks(hashes)
+	c.Assert(err, IsNil)
+	c.Assert(missing, HasLen, 0)
+	_, err = target.WriteBLOBManifest(*manifest)
+	c.Assert(err, IsNil)
+
+	// chunks of abandoned uploads are removed once their pins expire
+	orphan := randomData(1024)
+	orphanHash := utils.MustSHA512Half(orphan)
+	c.Assert(target.WriteChunk(orphanHash, bytes.NewReader(orphan)), IsNil)
+	other, err = target.WriteBLOB(bytes.NewReader([]byte("other")))
+	c.Assert(err, IsNil)
+	clock.Advance(defaults.ChunkPinTTL + time.Second)
+	c.Assert(target.DeleteBLOB(other.SHA512), IsNil)
+	missing, err = target.GetMissingChunks([]string{orphanHash})
+	c.Assert(err, IsNil)
+	c.Assert(missing, DeepEquals, []string{orphanHash})
+	// chunks referenced by manifests are not affected
+	missing, err = target.GetMissingChunks(hashes)
+	c.Assert(err, IsNil)
+	c.Assert(missing, HasLen, 0)
+}
+
+func (s *ChunkedSuite) TestDeleteWhileReading(c *C) {
+	data := randomData(256 * 1024)
+	envelope, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	r, err := s.objects.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	head := make([]byte, 1024)
+	_, err = io.ReadFull(r, head)
+	c.Assert(err, IsNil)
+
+	// the open reader keeps the chunks of the deleted BLOB
+	c.Assert(s.objects.DeleteBLOB(envelope.SHA512), IsNil)
+	_, err = s.objects.GetBLOBManifest(envelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	tail, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(append(head, tail...), data), Equals, true)
+	c.Assert(r.Close(), IsNil)
+
+	// the chunks are removed by the next delete once the reader is closed
+	other, err := s.objects.WriteBLOB(bytes.NewReader([]byte("other")))
+	c.Assert(err, IsNil)
+	c.Assert(s.objects.DeleteBLOB(other.SHA512), IsNil)
+	chunks, err := listFiles(s.objects.chunkDir())
+	c.Assert(err, IsNil)
+	c.Assert(chunks, HasLen, 0)
+}
+
+func (s *ChunkedSuite) TestRejectsInvalidHashes(c *C) {
+	_, err := s.objects.OpenBLOB("../../etc/passwd")
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+	_, err = s.objects.OpenChunk("abc")
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *ChunkedSuite) TestChunkBoundariesAreStable(c *C) {
+	data := randomData(128 * 1024)
+	split := func(data []byte) (sizes []int) {
+		chunker := newChunker(bytes.NewReader(data), 1024, 4096, 16384)
+		for {
+			chunk, err := chunker.Next()
+			if err == io.EOF {
+				return sizes
+			}
+			c.Assert(err, IsNil)
+			c.Assert(len(chunk) <= 16384, Equals, true)
+			sizes = append(sizes, len(chunk))
+		}
+	}
+	sizes := split(data)
+	c.Assert(split(data), DeepEquals, sizes)
+	var total int
+	for _, size := range sizes {
+		total += size
+	}
+	c.Assert(total, Equals, len(data))
+}
+
+func (s *ChunkedSuite) assertContents(c *C, hash string, data []byte) {
+	r, err := s.objects.OpenBLOB(hash)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(out, data), Equals, true)
+}
+
+func uniqueChunks(chunks []blob.Chunk) int {
+	unique := make(map[string]struct{})
+	for _, chunk := range chunks {
+		unique[chunk.SHA512] = struct{}{}
+	}
+	return len(unique)
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
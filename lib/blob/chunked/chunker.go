@@ -0,0 +1,142 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"io"
+	"math/bits"
+
+	"github.com/gravitational/trace"
+)
+
+// chunker splits the data stream into content-defined chunks.
+//
+// Chunk boundaries are determined by a rolling gear hash over the data,
+// so that an insertion or removal of bytes only affects the chunks
+// around the change and the rest of the stream is split identically.
+// To keep chunk sizes close to the average, a stricter mask is used
+// before the average size is reached and a looser one after it
+// (normalized chunking)
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	// start and end delimit the pending data in buf
+	start, end int
+	eof        bool
+	min, avg   int
+	max        int
+	maskS      uint64
+	maskL      uint64
+}
+
+func newChunker(r io.Reader, min, avg, max int) *chunker {
+	avgBits := bits.Len(uint(avg)) - 1
+	return &chunker{
+		r:     r,
+		buf:   make([]byte, max),
+		min:   min,
+		avg:   avg,
+		max:   max,
+		maskS: mask(avgBits + 2),
+		maskL: mask(avgBits - 2),
+	}
+}
+
+// Next returns the next chunk of data or io.EOF if the stream has
+// been consumed. The returned slice is only valid until the next call
+func (c *chunker) Next() ([]byte, error) {
+	if err := c.fill(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+	data := c.buf[c.start:c.end]
+	n := cutPoint(data, c.min, c.avg, c.maskS, c.maskL)
+	c.start += n
+	return data[:n], nil
+}
+
+// fill reads data from the underlying reader until the buffer
+// holds at least max bytes of pending data or the stream is exhausted
+func (c *chunker) fill() error {
+	if c.eof || c.end-c.start >= c.max {
+		return nil
+	}
+	c.end = copy(c.buf, c.buf[c.start:c.end])
+	c.start = 0
+	n, err := io.ReadFull(c.r, c.buf[c.end:])
+	c.end += n
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.eof = true
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
+// cutPoint returns the length of the next chunk in data
+func cutPoint(data []byte, min, avg int, maskS, maskL uint64) int {
+	n := len(data)
+	if n <= min {
+		return n
+	}
+	if n < avg {
+		avg = n
+	}
+	var fp uint64
+	i := min
+	for ; i < avg; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// mask returns the mask with the specified number of the most
+// significant bits set. The most significant bits of the gear hash
+// depend on the widest window of the input
+func mask(bits int) uint64 {
+	if bits <= 0 {
+		return 0
+	}
+	return ^uint64(0) << uint(64-bits)
+}
+
+// gear is the table of random values used by the rolling hash.
+// The table must not change between versions, otherwise the same
+// data would be split into different chunks
+var gear [256]uint64
+
+func init() {
+	// splitmix64 with a fixed seed
+	seed := uint64(0x6772617669747921)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
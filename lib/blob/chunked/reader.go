@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/blob"
+
+	"github.com/gravitational/trace"
+)
+
+// newReader returns a reader that reads the BLOB assembled
+// from the specified chunks. Chunk paths are resolved with getPath.
+// The optional release is called once the reader is closed
+func newReader(chunks []blob.Chunk, getPath func(hash string) string, release func()) *reader {
+	offsets := make([]int64, len(chunks))
+	var size int64
+	for i, chunk := range chunks {
+		offsets[i] = size
+		size += chunk.SizeBytes
+	}
+	return &reader{
+		chunks:  chunks,
+		offsets: offsets,
+		size:    size,
+		getPath: getPath,
+		release: release,
+		current: -1,
+	}
+}
+
+// reader implements blob.ReadSeekCloser on top of the chunk files,
+// keeping at most one chunk file open at a time
+type reader struct {
+	chunks []blob.Chunk
+	// offsets lists the offsets of every chunk in the BLOB
+	offsets []int64
+	size    int64
+	getPath func(hash string) string
+	// release is called once the reader is closed
+	release func()
+	// pos is the current read position
+	pos int64
+	// current is the index of the open chunk file or -1
+	current int
+	file    *os.File
+}
+
+// Read reads up to len(p) bytes from the current position
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	index := sort.Search(len(r.offsets), func(i int) bool {
+		return r.offsets[i] > r.pos
+	}) - 1
+	if err := r.open(index); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if _, err := r.file.Seek(r.pos-r.offsets[index], io.SeekStart); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	remaining := r.chunks[index].SizeBytes - (r.pos - r.offsets[index])
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.file.Read(p)
+	r.pos += int64(n)
+	if err == io.EOF {
+		if n == 0 {
+			return 0, trace.NotFound("chunk %v is truncated", r.chunks[index].SHA512)
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the position for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, trace.BadParameter("unsupported whence: %v", whence)
+	}
+	if pos < 0 {
+		return 0, trace.BadParameter("negative position: %v", pos)
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+// Close closes the currently open chunk file and releases the BLOB chunks
+func (r *reader) Close() error {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	return trace.Wrap(r.closeFile())
+}
+
+func (r *reader) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.current = -1
+	return trace.Wrap(err)
+}
+
+func (r *reader) open(index int) error {
+	if r.current == index {
+		return nil
+	}
+	if err := r.closeFile(); err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.Open(r.getPath(r.chunks[index].SHA512))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.file = f
+	r.current = index
+	return nil
+}
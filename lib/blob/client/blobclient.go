@@ -21,6 +21,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -187,6 +188,73 @@ func (c *Client) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
 	return c.OpenFile(context.TODO(), endpoint, url.Values{})
 }
 
+func (c *Client) GetBLOBManifest(hash string) (*blob.Manifest, error) {
+	out, err := c.Get(c.Endpoint("blobs", hash, "manifest"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var manifest blob.Manifest
+	if err := json.Unmarshal(out.Bytes(), &manifest); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &manifest, nil
+}
+
+func (c *Client) GetMissingChunks(hashes []string) ([]string, error) {
+	out, err := c.PostJSON(c.Endpoint("chunks", "missing"), hashes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var missing []string
+	if err := json.Unmarshal(out.Bytes(), &missing); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return missing, nil
+}
+
+func (c *Client) OpenChunk(hash string) (io.ReadCloser, error) {
+	re, err := c.Client.GetFile(context.TODO(), c.Endpoint("chunks", hash), url.Values{})
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if re.Code() < 200 || re.Code() > 299 {
+		defer re.Body().Close()
+		bytes, err := ioutil.ReadAll(re.Body())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return nil, trace.ReadError(re.Code(), bytes)
+	}
+	return re.Body(), nil
+}
+
+func (c *Client) WriteChunk(hash string, data io.Reader) error {
+	file := roundtrip.File{
+		Name:     "file",
+		Filename: hash,
+		Reader:   data,
+	}
+	_, err := c.PostForm(c.Endpoint("chunks"), url.Values{"hash": []string{hash}}, file)
+	return trace.Wrap(err)
+}
+
+func (c *Client) WriteBLOBManifest(manifest blob.Manifest) (*blob.Envelope, error) {
+	out, err := c.PostJSON(c.Endpoint("manifests"), manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var envelope *blob.Envelope
+	if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// PostJSON issues HTTP POST request with JSON body to the server
+func (c *Client) PostJSON(endpoint string, data interface{}) (*roundtrip.Response, error) {
+	return telehttplib.ConvertResponse(c.Client.PostJSON(context.TODO(), endpoint, data))
+}
+
 // PostForm is a generic method that issues http POST request to the server
 func (c *Client) PostForm(
 	endpoint string,
@@ -254,18 +254,12 @@ func (c *cluster) fetchObject(hash string) error {
 			errors = append(errors, err)
 			continue
 		}
-		f, err := objects.OpenBLOB(hash)
+		envelope, err := c.copyBLOB(objects, c.Local, hash)
 		if err != nil {
 			c.Errorf("Failure to fetch %v from %v: %v.", hash, p, trace.DebugReport(err))
 			errors = append(errors, err)
 			continue
 		}
-		defer f.Close()
-		envelope, err := c.Local.WriteBLOB(f)
-		if err != nil {
-			errors = append(errors, err)
-			continue
-		}
 		c.Infof("Successfully fetched %v from %v.", envelope, p)
 		err = c.Backend.UpsertObjectPeers(hash, []string{c.ID}, 0)
 		if err != nil {
@@ -303,28 +297,18 @@ func (c *cluster) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
 		}
 		return envelope, nil
 	}
-	f, err := c.Local.OpenBLOB(envelope.SHA512)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	defer f.Close()
-
 	var errors []error
 	for _, p := range peers {
 		if p.ID == c.ID {
 			continue
 		}
-		_, err := f.Seek(0, 0)
-		if err != nil {
-			return nil, trace.Wrap(err)
-		}
 		peerClient, err := c.GetPeer(p)
 		if err != nil {
 			c.Infof("%v returned error: %v", p, err)
 			errors = append(errors, err)
 			continue
 		}
-		_, err = peerClient.WriteBLOB(f)
+		_, err = c.copyBLOB(c.Local, peerClient, envelope.SHA512)
 		if err != nil {
 			c.Infof("%v returned error: %v", p, err)
 			errors = append(errors, err)
@@ -344,6 +328,78 @@ func (c *cluster) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
 	return nil, trace.Wrap(trace.NewAggregate(errors...), "not enough successfull writes")
 }
 
+// copyBLOB copies the BLOB identified by hash from src to dst.
+// If both storages support chunked BLOBs, only the chunks
+// missing in dst are transferred
+func (c *cluster) copyBLOB(src, dst blob.Objects, hash string) (*blob.Envelope, error) {
+	srcChunked, ok := src.(blob.ChunkedObjects)
+	dstChunked, ok2 := dst.(blob.ChunkedObjects)
+	if ok && ok2 {
+		envelope, err := c.copyChunks(srcChunked, dstChunked, hash)
+		if err == nil {
+			return envelope, nil
+		}
+		// peers running older versions or backed by storage without
+		// chunks support fall back to transferring the whole BLOB
+		if !trace.IsNotImplemented(err) && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		c.Debugf("Chunked copy of %v is not available: %v.", hash, err)
+	}
+	f, err := src.OpenBLOB(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer f.Close()
+	envelope, err := dst.WriteBLOB(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// copyChunks copies the BLOB identified by hash from src to dst
+// by transferring only the chunks dst does not have
+func (c *cluster) copyChunks(src, dst blob.ChunkedObjects, hash string) (*blob.Envelope, error) {
+	manifest, err := src.GetBLOBManifest(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	seen := make(map[string]struct{}, len(manifest.Chunks))
+	hashes := make([]string, 0, len(manifest.Chunks))
+	for _, chunk := range manifest.Chunks {
+		if _, ok := seen[chunk.SHA512]; ok {
+			continue
+		}
+		seen[chunk.SHA512] = struct{}{}
+		hashes = append(hashes, chunk.SHA512)
+	}
+	missing, err := dst.GetMissingChunks(hashes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c.Debugf("Copying %v of %v chunks of %v.", len(missing), len(hashes), hash)
+	for _, chunkHash := range missing {
+		if err := copyChunk(src, dst, chunkHash); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	envelope, err := dst.WriteBLOBManifest(*manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+func copyChunk(src, dst blob.ChunkedObjects, hash string) error {
+	r, err := src.OpenChunk(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer r.Close()
+	return trace.Wrap(dst.WriteChunk(hash, r))
+}
+
 func (c *cluster) getObjects(p storage.Peer) (blob.Objects, error) {
 	if p.ID == c.ID {
 		return c.Local, nil
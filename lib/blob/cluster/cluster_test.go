@@ -20,7 +20,9 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -30,6 +32,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	"github.com/gravitational/gravity/lib/blob/client"
 	"github.com/gravitational/gravity/lib/blob/fs"
 	"github.com/gravitational/gravity/lib/blob/handler"
@@ -55,6 +58,7 @@ type ClusterSinglePeer struct {
 var _ = Suite(&ClusterSinglePeer{})
 var _ = Suite(&ClusterMultiPeers{})
 var _ = Suite(&RPCSuite{})
+var _ = Suite(&ChunkedRPCSuite{})
 
 const (
 	heartbeatPeriod  = 100 * time.Millisecond
@@ -212,9 +216,16 @@ func (s *ClusterMultiPeers) TestCleanup(c *C) {
 type RPCSuite struct {
 	suite        suite.BLOBSuite
 	clusterSuite clusterSuite
+	localClients []blob.Objects
 }
 
 func (s *RPCSuite) SetUpTest(c *C) {
+	s.setUp(c, func(dir string) (blob.Objects, error) {
+		return fs.New(dir)
+	})
+}
+
+func (s *RPCSuite) setUp(c *C, newLocal func(dir string) (blob.Objects, error)) {
 	log.SetOutput(os.Stderr)
 	log.SetLevel(log.DebugLevel)
 
@@ -250,7 +261,7 @@ func (s *RPCSuite) SetUpTest(c *C) {
 	fakeClock := clockwork.NewFakeClockAt(time.Now().UTC())
 
 	for i := 0; i < 3; i++ {
-		local, err := fs.New(c.MkDir())
+		local, err := newLocal(c.MkDir())
 		c.Assert(err, IsNil)
 		peers[i] = local
 
@@ -302,13 +313,14 @@ func (s *RPCSuite) SetUpTest(c *C) {
 		objects[i] = obj.(*cluster)
 		c.Assert(objects[i].heartbeat(), IsNil)
 		clients[i] = clusterClient
-		localClients[i] = localClient
+		localClients[i] = &countingObjects{ChunkedObjects: localClient}
 	}
 
 	s.suite.Objects = objects[0]
 	s.clusterSuite.objects = objects
 	s.clusterSuite.clients = clients
 	s.clusterSuite.clock = fakeClock
+	s.localClients = localClients
 
 	c.Assert(err, IsNil)
 
@@ -338,6 +350,60 @@ func (s *RPCSuite) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+// ChunkedRPCSuite runs the RPC tests with peers backed by chunked storage
+type ChunkedRPCSuite struct {
+	RPCSuite
+}
+
+func (s *ChunkedRPCSuite) SetUpTest(c *C) {
+	s.setUp(c, func(dir string) (blob.Objects, error) {
+		return chunked.New(chunked.Config{
+			Path:         dir,
+			MinChunkSize: 1024,
+			AvgChunkSize: 4096,
+			MaxChunkSize: 16384,
+		})
+	})
+}
+
+func (s *ChunkedRPCSuite) TestReplicatesMissingChunks(c *C) {
+	peer2 := s.clusterSuite.objects[2]
+	source := s.localClients[0].(*countingObjects)
+
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	_, err := s.clusterSuite.clients[0].WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(peer2.fetchNewObjects(), IsNil)
+	initialChunks := source.openedChunks
+	c.Assert(initialChunks > 10, Equals, true, Commentf("%v", initialChunks))
+
+	modified := append(append(append([]byte{}, data[:100000]...), []byte("update")...), data[100000:]...)
+	envelope, err := s.clusterSuite.clients[0].WriteBLOB(bytes.NewReader(modified))
+	c.Assert(err, IsNil)
+	c.Assert(peer2.fetchNewObjects(), IsNil)
+	transferred := source.openedChunks - initialChunks
+	c.Assert(transferred > 0 && transferred <= 3, Equals, true, Commentf("%v", transferred))
+
+	r, err := peer2.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(out, modified), Equals, true)
+}
+
+// countingObjects counts chunks read from the wrapped peer
+type countingObjects struct {
+	blob.ChunkedObjects
+	openedChunks int
+}
+
+func (o *countingObjects) OpenChunk(hash string) (io.ReadCloser, error) {
+	o.openedChunks++
+	return o.ChunkedObjects.OpenChunk(hash)
+}
+
 type clusterSuite struct {
 	objects []*cluster
 	clients []blob.Objects
@@ -28,6 +28,7 @@ import (
 
 	"github.com/gravitational/form"
 	"github.com/gravitational/roundtrip"
+	telehttplib "github.com/gravitational/teleport/lib/httplib"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
 	log "github.com/sirupsen/logrus"
@@ -79,6 +80,11 @@ func New(cfg Config) (*Server, error) {
 		h.GET(handler.prefix+"/blobs/:hash/envelope", h.needsAuth(h.getBLOBEnvelope, handler.objects))
 		h.HEAD(handler.prefix+"/blobs/:hash", h.needsAuth(h.getBLOB, handler.objects))
 		h.POST(handler.prefix+"/blobs", h.needsAuth(h.createBLOB, handler.objects))
+		h.GET(handler.prefix+"/blobs/:hash/manifest", h.needsAuth(h.getBLOBManifest, handler.objects))
+		h.POST(handler.prefix+"/manifests", h.needsAuth(h.createBLOBFromManifest, handler.objects))
+		h.GET(handler.prefix+"/chunks/:hash", h.needsAuth(h.getChunk, handler.objects))
+		h.POST(handler.prefix+"/chunks", h.needsAuth(h.createChunk, handler.objects))
+		h.POST(handler.prefix+"/chunks/missing", h.needsAuth(h.getMissingChunks, handler.objects))
 	}
 
 	h.NotFound = h.notFound
@@ -164,6 +170,98 @@ func (s *Server) createBLOB(w http.ResponseWriter, r *http.Request, p httprouter
 	return nil
 }
 
+func (s *Server) getBLOBManifest(w http.ResponseWriter, r *http.Request, p httprouter.Params, objects blob.Objects) error {
+	chunked, err := chunkedObjects(objects)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifest, err := chunked.GetBLOBManifest(p.ByName("hash"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, manifest)
+	return nil
+}
+
+func (s *Server) createBLOBFromManifest(w http.ResponseWriter, r *http.Request, p httprouter.Params, objects blob.Objects) error {
+	chunked, err := chunkedObjects(objects)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var manifest blob.Manifest
+	if err := telehttplib.ReadJSON(r, &manifest); err != nil {
+		return trace.Wrap(err)
+	}
+	envelope, err := chunked.WriteBLOBManifest(manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, envelope)
+	return nil
+}
+
+func (s *Server) getChunk(w http.ResponseWriter, r *http.Request, p httprouter.Params, objects blob.Objects) error {
+	chunked, err := chunkedObjects(objects)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reader, err := chunked.OpenChunk(p.ByName("hash"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, reader)
+	return trace.Wrap(err)
+}
+
+func (s *Server) createChunk(w http.ResponseWriter, r *http.Request, p httprouter.Params, objects blob.Objects) error {
+	chunked, err := chunkedObjects(objects)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var hash string
+	var files form.Files
+	err = form.Parse(r,
+		form.String("hash", &hash, form.Required()),
+		form.FileSlice("file", &files),
+	)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(files) != 1 {
+		return trace.BadParameter("expected a single file parameter but got %d", len(files))
+	}
+	defer func() {
+		if err := files.Close(); err != nil {
+			log.Errorf("failed to close files: %v", trace.DebugReport(err))
+		}
+	}()
+	if err := chunked.WriteChunk(hash, files[0]); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("ok"))
+	return nil
+}
+
+func (s *Server) getMissingChunks(w http.ResponseWriter, r *http.Request, p httprouter.Params, objects blob.Objects) error {
+	chunked, err := chunkedObjects(objects)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var hashes []string
+	if err := telehttplib.ReadJSON(r, &hashes); err != nil {
+		return trace.Wrap(err)
+	}
+	missing, err := chunked.GetMissingChunks(hashes)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, missing)
+	return nil
+}
+
 func (s *Server) needsAuth(fn authHandle, objects blob.Objects) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		log.WithFields(log.Fields{
@@ -195,5 +293,19 @@ func (s *Server) needsAuth(fn authHandle, objects blob.Objects) httprouter.Handl
 	}
 }
 
+// chunkedObjects returns the storage as chunked BLOB storage
+// or a not implemented error if chunks are not supported
+func chunkedObjects(objects blob.Objects) (blob.ChunkedObjects, error) {
+	chunked, ok := objects.(blob.ChunkedObjects)
+	if !ok {
+		return nil, trace.NotImplemented("storage does not support chunked BLOBs")
+	}
+	return chunked, nil
+}
+
+func message(msg string, args ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"message": fmt.Sprintf(msg, args...)}
+}
+
 type authHandle func(
 	http.ResponseWriter, *http.Request, httprouter.Params, blob.Objects) error
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gravitational/trace"
+)
+
+// reader implements blob.ReadSeekCloser on top of ranged GET requests.
+// The response body is kept open for sequential reads and is reopened
+// at the new offset after a seek
+type reader struct {
+	objects *Objects
+	key     string
+	size    int64
+	pos     int64
+	body    io.ReadCloser
+}
+
+// Read reads up to len(p) bytes from the current position
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		out, err := r.objects.S3.GetObject(&awss3.GetObjectInput{
+			Bucket: aws.String(r.objects.Bucket),
+			Key:    aws.String(r.key),
+			Range:  aws.String(fmt.Sprintf("bytes=%v-", r.pos)),
+		})
+		if err != nil {
+			return 0, trace.Wrap(utils.ConvertS3Error(err))
+		}
+		r.body = out.Body
+	}
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+	if err == io.EOF {
+		r.closeBody()
+		if r.pos < r.size {
+			if n == 0 {
+				return 0, trace.ConnectionProblem(nil, "unexpected end of %v at %v", r.key, r.pos)
+			}
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// Seek sets the position for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, trace.BadParameter("unsupported whence: %v", whence)
+	}
+	if pos < 0 {
+		return 0, trace.BadParameter("negative position: %v", pos)
+	}
+	if pos != r.pos {
+		r.closeBody()
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+// Close closes the open response body
+func (r *reader) Close() error {
+	r.closeBody()
+	return nil
+}
+
+func (r *reader) closeBody() {
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+}
@@ -0,0 +1,528 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package s3 implements BLOB storage backed by an S3-compatible object store.
+//
+// BLOBs are stored under the following keys in the bucket:
+//
+//	<prefix>/blobs/<hash>  - BLOB data, the half SHA512 hash is also kept in the object metadata
+//	<prefix>/tmp/<uuid>    - uploads in progress
+//
+// Since the hash of the BLOB is only known once all the data has been read,
+// the data is first uploaded to a temporary key and then copied to its
+// final location on the server side. The copy is read back and verified
+// against the hash before the BLOB is reported as written.
+//
+// Temporary objects are deleted once the upload finishes or fails.
+// Temporary objects left over by interrupted uploads are removed when
+// the storage is created. It is still recommended to configure a bucket
+// lifecycle rule for the <prefix>/tmp/ prefix that expires objects and
+// aborts incomplete multipart uploads after a day.
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the S3 BLOB storage configuration
+type Config struct {
+	// Bucket is the name of the bucket to store BLOBs in
+	Bucket string
+	// Prefix is the optional key prefix for all objects
+	Prefix string
+	// Region is the bucket region
+	Region string
+	// Endpoint is the optional endpoint of the S3-compatible service
+	Endpoint string
+	// ForcePathStyle turns on path-style addressing of objects,
+	// which is usually required by S3-compatible services
+	ForcePathStyle bool
+	// DisableSSL turns off TLS for connections to the endpoint
+	DisableSSL bool
+	// AccessKeyID is the optional access key ID. If unspecified,
+	// credentials are looked up in the default locations
+	AccessKeyID string
+	// SecretAccessKey is the secret access key
+	SecretAccessKey string
+	// PartSize is the size of a part in multipart uploads,
+	// between 5MiB and 5GiB
+	PartSize int64
+	// CopyPartSize is the size of a part in multipart copies,
+	// between 5MiB and 5GiB
+	CopyPartSize int64
+	// MaxCopySize is the maximum size of an object copied in a single request,
+	// at most 5GiB
+	MaxCopySize int64
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// S3 is optional S3 API client
+	S3 s3iface.S3API
+	// Clock is clock interface, used in tests
+	Clock clockwork.Clock
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Bucket == "" {
+		return trace.BadParameter("missing Bucket parameter")
+	}
+	if c.Region == "" {
+		c.Region = defaults.AWSRegion
+	}
+	if c.Prefix != "" && !strings.HasSuffix(c.Prefix, "/") {
+		c.Prefix += "/"
+	}
+	if c.PartSize == 0 {
+		c.PartSize = defaults.S3PartSize
+	}
+	if c.CopyPartSize == 0 {
+		c.CopyPartSize = defaults.S3CopyPartSize
+	}
+	if c.MaxCopySize == 0 {
+		c.MaxCopySize = defaults.S3MaxCopySize
+	}
+	if c.PartSize < defaults.S3MinPartSize || c.PartSize > defaults.S3MaxPartSize {
+		return trace.BadParameter("PartSize should be between %v and %v bytes, got %v",
+			defaults.S3MinPartSize, int64(defaults.S3MaxPartSize), c.PartSize)
+	}
+	if c.CopyPartSize < defaults.S3MinPartSize || c.CopyPartSize > defaults.S3MaxPartSize {
+		return trace.BadParameter("CopyPartSize should be between %v and %v bytes, got %v",
+			defaults.S3MinPartSize, int64(defaults.S3MaxPartSize), c.CopyPartSize)
+	}
+	if c.MaxCopySize < 0 || c.MaxCopySize > defaults.S3MaxCopySize {
+		return trace.BadParameter("MaxCopySize should be at most %v bytes, got %v",
+			int64(defaults.S3MaxCopySize), c.MaxCopySize)
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "s3blob")
+	}
+	if c.S3 == nil {
+		config := &aws.Config{
+			Region:           aws.String(c.Region),
+			S3ForcePathStyle: aws.Bool(c.ForcePathStyle),
+			DisableSSL:       aws.Bool(c.DisableSSL),
+		}
+		if c.Endpoint != "" {
+			config.Endpoint = aws.String(c.Endpoint)
+		}
+		if c.AccessKeyID != "" {
+			config.Credentials = credentials.NewStaticCredentials(
+				c.AccessKeyID, c.SecretAccessKey, "")
+		}
+		session, err := session.NewSession(config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		c.S3 = awss3.New(session)
+	}
+	return nil
+}
+
+// New returns a new instance of the S3 BLOB storage
+func New(config Config) (*Objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o := &Objects{Config: config}
+	if err := o.removeStaleUploads(); err != nil {
+		o.Warnf("Failed to remove stale uploads: %v.", err)
+	}
+	return o, nil
+}
+
+// Objects is the S3 BLOB storage
+type Objects struct {
+	Config
+}
+
+// Close closes the storage
+func (o *Objects) Close() error {
+	return nil
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *Objects) GetBLOBs() ([]string, error) {
+	prefix := o.blobKey("")
+	var out []string
+	err := o.S3.ListObjectsV2Pages(&awss3.ListObjectsV2Input{
+		Bucket: aws.String(o.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			out = append(out, strings.TrimPrefix(aws.StringValue(object.Key), prefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// WriteBLOB uploads the data to the bucket, returns object envelope
+func (o *Objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	tempKey := o.tempKey(uuid.New())
+	// the object might have been stored even if the upload failed
+	defer o.deleteObject(tempKey)
+	hasher := sha512.New()
+	size, err := o.upload(tempKey, io.TeeReader(data, hasher))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	envelope, err := o.GetBLOBEnvelope(hash)
+	if err == nil {
+		o.Debugf("BLOB %v already exists.", hash)
+		return envelope, nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.copy(tempKey, o.blobKey(hash), hash, size); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	envelope, err = o.GetBLOBEnvelope(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if envelope.SizeBytes != size {
+		o.deleteObject(o.blobKey(hash))
+		return nil, trace.BadParameter("stored BLOB %v has size %v, expected %v",
+			hash, envelope.SizeBytes, size)
+	}
+	if err := o.verify(hash, size); err != nil {
+		o.deleteObject(o.blobKey(hash))
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// GetBLOBEnvelope returns BLOB information identified by hash.
+// The hash recorded in the object metadata is verified to match
+func (o *Objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	if err := checkHash(hash); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out, err := o.S3.HeadObject(&awss3.HeadObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.blobKey(hash)),
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	if stored := metadataHash(out.Metadata); stored != hash {
+		return nil, trace.BadParameter("BLOB %v has mismatching hash %q in metadata", hash, stored)
+	}
+	return &blob.Envelope{
+		SizeBytes: aws.Int64Value(out.ContentLength),
+		SHA512:    hash,
+		Modified:  aws.TimeValue(out.LastModified).UTC(),
+	}, nil
+}
+
+// OpenBLOB opens BLOB identified by hash and returns reader
+func (o *Objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	envelope, err := o.GetBLOBEnvelope(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &reader{
+		objects: o,
+		key:     o.blobKey(hash),
+		size:    envelope.SizeBytes,
+	}, nil
+}
+
+// DeleteBLOB deletes BLOB from the storage
+func (o *Objects) DeleteBLOB(hash string) error {
+	if _, err := o.GetBLOBEnvelope(hash); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err := o.S3.DeleteObject(&awss3.DeleteObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.blobKey(hash)),
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// upload uploads the data to the specified key. Data larger than
+// a single part is uploaded using multipart upload. Every request
+// carries Content-MD5 of the payload so the server verifies its integrity
+func (o *Objects) upload(key string, data io.Reader) (size int64, err error) {
+	buf := make([]byte, o.PartSize)
+	n, err := io.ReadFull(data, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = o.S3.PutObject(&awss3.PutObjectInput{
+			Bucket:     aws.String(o.Bucket),
+			Key:        aws.String(key),
+			Body:       bytes.NewReader(buf[:n]),
+			ContentMD5: aws.String(contentMD5(buf[:n])),
+		})
+		if err != nil {
+			return 0, trace.Wrap(utils.ConvertS3Error(err))
+		}
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	upload, err := o.S3.CreateMultipartUpload(&awss3.CreateMultipartUploadInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	defer func() {
+		if err != nil {
+			o.abortUpload(key, upload.UploadId)
+		}
+	}()
+	var parts []*awss3.CompletedPart
+	for partNumber := int64(1); n > 0; partNumber++ {
+		out, err := o.S3.UploadPart(&awss3.UploadPartInput{
+			Bucket:     aws.String(o.Bucket),
+			Key:        aws.String(key),
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int64(partNumber),
+			Body:       bytes.NewReader(buf[:n]),
+			ContentMD5: aws.String(contentMD5(buf[:n])),
+		})
+		if err != nil {
+			return 0, trace.Wrap(utils.ConvertS3Error(err))
+		}
+		parts = append(parts, &awss3.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int64(partNumber),
+		})
+		size += int64(n)
+		n, err = io.ReadFull(data, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, trace.Wrap(err)
+		}
+	}
+	_, err = o.S3.CompleteMultipartUpload(&awss3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(o.Bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &awss3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return 0, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return size, nil
+}
+
+// copy copies the object on the server side and records
+// the BLOB hash in the metadata of the target object
+func (o *Objects) copy(sourceKey, targetKey, hash string, size int64) (err error) {
+	source := copySource(o.Bucket, sourceKey)
+	metadata := map[string]*string{metadataHashKey: aws.String(hash)}
+	if size <= o.MaxCopySize {
+		_, err := o.S3.CopyObject(&awss3.CopyObjectInput{
+			Bucket:            aws.String(o.Bucket),
+			Key:               aws.String(targetKey),
+			CopySource:        aws.String(source),
+			Metadata:          metadata,
+			MetadataDirective: aws.String(awss3.MetadataDirectiveReplace),
+		})
+		return trace.Wrap(utils.ConvertS3Error(err))
+	}
+	upload, err := o.S3.CreateMultipartUpload(&awss3.CreateMultipartUploadInput{
+		Bucket:   aws.String(o.Bucket),
+		Key:      aws.String(targetKey),
+		Metadata: metadata,
+	})
+	if err != nil {
+		return trace.Wrap(utils.ConvertS3Error(err))
+	}
+	defer func() {
+		if err != nil {
+			o.abortUpload(targetKey, upload.UploadId)
+		}
+	}()
+	var parts []*awss3.CompletedPart
+	for offset, partNumber := int64(0), int64(1); offset < size; offset, partNumber = offset+o.CopyPartSize, partNumber+1 {
+		last := offset + o.CopyPartSize - 1
+		if last >= size {
+			last = size - 1
+		}
+		out, err := o.S3.UploadPartCopy(&awss3.UploadPartCopyInput{
+			Bucket:          aws.String(o.Bucket),
+			Key:             aws.String(targetKey),
+			UploadId:        upload.UploadId,
+			PartNumber:      aws.Int64(partNumber),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%v-%v", offset, last)),
+		})
+		if err != nil {
+			return trace.Wrap(utils.ConvertS3Error(err))
+		}
+		parts = append(parts, &awss3.CompletedPart{
+			ETag:       out.CopyPartResult.ETag,
+			PartNumber: aws.Int64(partNumber),
+		})
+	}
+	_, err = o.S3.CompleteMultipartUpload(&awss3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(o.Bucket),
+		Key:             aws.String(targetKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &awss3.CompletedMultipartUpload{Parts: parts},
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// verify reads the stored BLOB back and verifies its contents
+// against the hash
+func (o *Objects) verify(hash string, size int64) error {
+	r := &reader{
+		objects: o,
+		key:     o.blobKey(hash),
+		size:    size,
+	}
+	defer r.Close()
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return trace.Wrap(err)
+	}
+	if actual := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]); actual != hash {
+		return trace.BadParameter("stored BLOB %v has mismatching hash %v", hash, actual)
+	}
+	return nil
+}
+
+// removeStaleUploads deletes the temporary objects left over by uploads
+// that have been interrupted. As the bucket can be shared, only objects
+// older than defaults.S3StaleUploadTimeout are deleted
+func (o *Objects) removeStaleUploads() error {
+	var stale []string
+	now := o.Clock.Now()
+	err := o.S3.ListObjectsV2Pages(&awss3.ListObjectsV2Input{
+		Bucket: aws.String(o.Bucket),
+		Prefix: aws.String(o.tempKey("")),
+	}, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if now.Sub(aws.TimeValue(object.LastModified)) > defaults.S3StaleUploadTimeout {
+				stale = append(stale, aws.StringValue(object.Key))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return trace.Wrap(utils.ConvertS3Error(err))
+	}
+	for _, key := range stale {
+		o.Infof("Removing stale upload %v.", key)
+		o.deleteObject(key)
+	}
+	return nil
+}
+
+func (o *Objects) abortUpload(key string, uploadID *string) {
+	_, err := o.S3.AbortMultipartUpload(&awss3.AbortMultipartUploadInput{
+		Bucket:   aws.String(o.Bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		o.Warnf("Failed to abort multipart upload of %v: %v.", key, err)
+	}
+}
+
+func (o *Objects) deleteObject(key string) {
+	_, err := o.S3.DeleteObject(&awss3.DeleteObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		o.Warnf("Failed to delete %v: %v.", key, err)
+	}
+}
+
+func (o *Objects) blobKey(hash string) string {
+	return o.Prefix + "blobs/" + hash
+}
+
+func (o *Objects) tempKey(id string) string {
+	return o.Prefix + "tmp/" + id
+}
+
+// copySource returns the URL-encoded copy source for the specified key
+func copySource(bucket, key string) string {
+	segments := strings.Split(path.Join(bucket, key), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// metadataHash returns the BLOB hash recorded in the object metadata
+func metadataHash(metadata map[string]*string) string {
+	for key, value := range metadata {
+		if strings.EqualFold(key, metadataHashKey) {
+			return aws.StringValue(value)
+		}
+	}
+	return ""
+}
+
+// contentMD5 returns the value of Content-MD5 header for the data
+func contentMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// checkHash makes sure the hash is a valid half SHA512 hash
+func checkHash(hash string) error {
+	if len(hash) != sha512.Size {
+		return trace.BadParameter("invalid hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return trace.BadParameter("invalid hash %q", hash)
+	}
+	return nil
+}
+
+// metadataHashKey is the object metadata key with the BLOB hash
+const metadataHashKey = "Sha512"
@@ -0,0 +1,253 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob/suite"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/testutils"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+func TestS3(t *testing.T) { TestingT(t) }
+
+type S3Suite struct {
+	suite   suite.BLOBSuite
+	server  *testutils.S3Server
+	objects *Objects
+}
+
+var _ = Suite(&S3Suite{})
+
+func (s *S3Suite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+	s.server = testutils.NewS3Server()
+
+	obj, err := New(Config{
+		Bucket:          "packages",
+		Prefix:          "cluster",
+		Endpoint:        s.server.URL,
+		ForcePathStyle:  true,
+		DisableSSL:      true,
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		PartSize:        5 * mib,
+		CopyPartSize:    5 * mib,
+		MaxCopySize:     16 * mib,
+	})
+	c.Assert(err, IsNil)
+
+	s.objects = obj
+	s.suite.Objects = obj
+}
+
+func (s *S3Suite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *S3Suite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *S3Suite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *S3Suite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *S3Suite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *S3Suite) TestListsAllPages(c *C) {
+	var hashes []string
+	for i := 0; i < 5; i++ {
+		e, err := s.objects.WriteBLOB(bytes.NewReader(randomData(int64(i), 100)))
+		c.Assert(err, IsNil)
+		hashes = append(hashes, e.SHA512)
+	}
+	out, err := s.objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(out, HasLen, len(hashes))
+}
+
+func (s *S3Suite) TestMultipartUpload(c *C) {
+	// spans several upload parts, but is copied in a single request
+	data := randomData(1, 12*mib)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(e.SHA512, Equals, utils.MustSHA512Half(data))
+	c.Assert(e.SizeBytes, Equals, int64(len(data)))
+	c.Assert(s.server.Requests["UploadPart"], Equals, 3)
+	c.Assert(s.server.Requests["CopyObject"], Equals, 1)
+	s.assertContents(c, e.SHA512, data)
+	s.assertNoTemporaryObjects(c)
+}
+
+func (s *S3Suite) TestMultipartCopy(c *C) {
+	// exceeds the size of a single copy request
+	data := randomData(2, 20*mib)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(s.server.Requests["UploadPartCopy"], Equals, 4)
+	s.assertContents(c, e.SHA512, data)
+	s.assertNoTemporaryObjects(c)
+
+	r, err := s.objects.OpenBLOB(e.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	_, err = r.Seek(10000, io.SeekStart)
+	c.Assert(err, IsNil)
+	out := make([]byte, 100)
+	_, err = io.ReadFull(r, out)
+	c.Assert(err, IsNil)
+	c.Assert(out, DeepEquals, data[10000:10100])
+}
+
+func (s *S3Suite) TestEscapesCopySource(c *C) {
+	obj, err := New(Config{
+		Bucket:          "packages",
+		Prefix:          "site 100%+a",
+		Endpoint:        s.server.URL,
+		ForcePathStyle:  true,
+		DisableSSL:      true,
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		PartSize:        5 * mib,
+		CopyPartSize:    5 * mib,
+		MaxCopySize:     16 * mib,
+	})
+	c.Assert(err, IsNil)
+	for i, size := range []int{1024, 20 * mib} {
+		data := randomData(int64(i), size)
+		e, err := obj.WriteBLOB(bytes.NewReader(data))
+		c.Assert(err, IsNil)
+		c.Assert(e.SHA512, Equals, utils.MustSHA512Half(data))
+	}
+	c.Assert(s.server.Requests["CopyObject"], Equals, 1)
+	c.Assert(s.server.Requests["UploadPartCopy"], Equals, 4)
+	c.Assert(copySource("packages", "site 100%+a/tmp/id"), Equals, "packages/site%20100%25+a/tmp/id")
+}
+
+func (s *S3Suite) TestVerifiesHashInMetadata(c *C) {
+	data := []byte("hello, blob")
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	object := s.server.Objects["packages/cluster/blobs/"+e.SHA512]
+	c.Assert(object, NotNil)
+	object.Metadata["sha512"] = utils.MustSHA512Half([]byte("other"))
+
+	_, err = s.objects.GetBLOBEnvelope(e.SHA512)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+	_, err = s.objects.OpenBLOB(e.SHA512)
+	c.Assert(err, NotNil)
+}
+
+func (s *S3Suite) TestVerifiesStoredData(c *C) {
+	s.server.OnWrite = func(path string, object *testutils.S3ServerObject) {
+		if strings.HasPrefix(path, "packages/cluster/blobs/") {
+			object.Data[0] ^= 0xff
+		}
+	}
+	for i, size := range []int{1024, 20 * mib} {
+		_, err := s.objects.WriteBLOB(bytes.NewReader(randomData(int64(i), size)))
+		c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+	}
+	out, err := s.objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(out, HasLen, 0)
+	s.assertNoTemporaryObjects(c)
+}
+
+func (s *S3Suite) TestRemovesStaleUploads(c *C) {
+	now := time.Date(2019, time.June, 5, 12, 0, 0, 0, time.UTC)
+	for key, modified := range map[string]time.Time{
+		"packages/cluster/tmp/stale":   now.Add(-defaults.S3StaleUploadTimeout - time.Minute),
+		"packages/cluster/tmp/pending": now.Add(-time.Minute),
+	} {
+		s.server.Objects[key] = &testutils.S3ServerObject{
+			Data:     []byte("data"),
+			Metadata: map[string]string{},
+			Modified: modified,
+		}
+	}
+	_, err := New(Config{
+		Bucket:          "packages",
+		Prefix:          "cluster",
+		Endpoint:        s.server.URL,
+		ForcePathStyle:  true,
+		DisableSSL:      true,
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		Clock:           clockwork.NewFakeClockAt(now),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.server.Objects["packages/cluster/tmp/stale"], IsNil)
+	c.Assert(s.server.Objects["packages/cluster/tmp/pending"], NotNil)
+}
+
+func (s *S3Suite) TestValidatesPartSizes(c *C) {
+	for _, config := range []Config{
+		{Bucket: "packages", PartSize: 5 * 1024},
+		{Bucket: "packages", PartSize: 6 * 1024 * mib},
+		{Bucket: "packages", CopyPartSize: 4 * mib},
+		{Bucket: "packages", MaxCopySize: 6 * 1024 * mib},
+	} {
+		err := config.CheckAndSetDefaults()
+		c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+	}
+}
+
+func (s *S3Suite) assertContents(c *C, hash string, data []byte) {
+	r, err := s.objects.OpenBLOB(hash)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(out, data), Equals, true)
+}
+
+func (s *S3Suite) assertNoTemporaryObjects(c *C) {
+	for key := range s.server.Objects {
+		c.Assert(key, Not(Matches), "packages/cluster/tmp/.*")
+	}
+}
+
+const mib = 1024 * 1024
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tarball implements read-only BLOB storage served directly from
+// an uncompressed image tarball.
+//
+// The tarball is expected to contain BLOBs in the layout of the filesystem
+// BLOB storage:
+//
+//	<dir>/blobs/<hash[0:3]>/<hash>
+//
+// The tarball is indexed once when opened and BLOBs are then read from
+// their offsets in the tarball without extracting them.
+package tarball
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+
+	"github.com/gravitational/trace"
+)
+
+// New opens the tarball at the specified path and returns BLOB storage
+// for BLOBs stored under dir in the tarball
+func New(tarballPath, dir string) (*Objects, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	files, err := index(file)
+	if err != nil {
+		file.Close()
+		return nil, trace.Wrap(err, "failed to read %v", tarballPath)
+	}
+	return &Objects{
+		file:  file,
+		files: files,
+		dir:   path.Join(path.Clean(dir), "blobs"),
+	}, nil
+}
+
+// Objects is a read-only BLOB storage backed by a tarball
+type Objects struct {
+	file *os.File
+	// files maps the path of every regular file in the tarball
+	// to its location
+	files map[string]entry
+	// dir is the BLOB directory in the tarball
+	dir string
+}
+
+// Open opens the file with the specified path in the tarball
+func (o *Objects) Open(name string) (blob.ReadSeekCloser, error) {
+	entry, ok := o.files[path.Clean(name)]
+	if !ok {
+		return nil, trace.NotFound("file %v not found in tarball", name)
+	}
+	return o.open(entry), nil
+}
+
+// Close closes the tarball
+func (o *Objects) Close() error {
+	return trace.ConvertSystemError(o.file.Close())
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *Objects) GetBLOBs() (out []string, err error) {
+	for name := range o.files {
+		if hash, ok := o.hash(name); ok {
+			out = append(out, hash)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// GetBLOBEnvelope returns BLOB information identified by hash
+func (o *Objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	entry, err := o.get(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &blob.Envelope{
+		SizeBytes: entry.size,
+		SHA512:    hash,
+		Modified:  entry.modified,
+	}, nil
+}
+
+// OpenBLOB opens BLOB identified by hash and returns reader
+func (o *Objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	entry, err := o.get(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.open(*entry), nil
+}
+
+// WriteBLOB is not supported as the storage is read-only
+func (o *Objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	return nil, trace.BadParameter("tarball BLOB storage is read-only")
+}
+
+// DeleteBLOB is not supported as the storage is read-only
+func (o *Objects) DeleteBLOB(hash string) error {
+	return trace.BadParameter("tarball BLOB storage is read-only")
+}
+
+func (o *Objects) get(hash string) (*entry, error) {
+	if len(hash) < 3 {
+		return nil, trace.BadParameter("invalid BLOB hash %q", hash)
+	}
+	entry, ok := o.files[path.Join(o.dir, hash[0:3], hash)]
+	if !ok {
+		return nil, trace.NotFound("BLOB %v not found", hash)
+	}
+	return &entry, nil
+}
+
+// hash returns the BLOB hash if the specified file is a BLOB
+func (o *Objects) hash(name string) (hash string, ok bool) {
+	dir, hash := path.Split(name)
+	if path.Dir(path.Clean(dir)) != o.dir || len(hash) < 3 || path.Base(dir) != hash[0:3] {
+		return "", false
+	}
+	return hash, true
+}
+
+func (o *Objects) open(entry entry) blob.ReadSeekCloser {
+	return &reader{SectionReader: io.NewSectionReader(o.file, entry.offset, entry.size)}
+}
+
+// index returns the locations of all regular files in the tarball read from file.
+// Headers are read sequentially and file contents are skipped over so the
+// offset of the file after reading the header is where the file contents start
+func index(file *os.File) (map[string]entry, error) {
+	files := make(map[string]entry)
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "/"))] = entry{
+			offset:   offset,
+			size:     header.Size,
+			modified: header.ModTime.UTC(),
+		}
+	}
+}
+
+// entry describes the location of a file in the tarball
+type entry struct {
+	offset   int64
+	size     int64
+	modified time.Time
+}
+
+// reader reads a single file from the tarball.
+// Closing the reader does not close the tarball
+type reader struct {
+	*io.SectionReader
+}
+
+// Close is a no-op
+func (r *reader) Close() error {
+	return nil
+}
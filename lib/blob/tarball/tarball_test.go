@@ -0,0 +1,95 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tarball
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob/fs"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestTarball(t *testing.T) { TestingT(t) }
+
+type TarballSuite struct{}
+
+var _ = Suite(&TarballSuite{})
+
+func (s *TarballSuite) TestReadsBLOBsFromTarball(c *C) {
+	dir := c.MkDir()
+	objects, err := fs.New(filepath.Join(dir, "packages"))
+	c.Assert(err, IsNil)
+	first, err := objects.WriteBLOB(strings.NewReader("first"))
+	c.Assert(err, IsNil)
+	// Make the second BLOB large enough to span several tar blocks
+	data := bytes.Repeat([]byte("second"), 1000)
+	second, err := objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(c.MkDir(), "image.tar")
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	err = archive.CompressDirectory(dir, f, archive.ItemFromString("app.yaml", "kind: Cluster"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	tarball, err := New(path, "packages")
+	c.Assert(err, IsNil)
+	defer tarball.Close()
+
+	hashes, err := tarball.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(len(hashes), Equals, 2)
+
+	envelope, err := tarball.GetBLOBEnvelope(second.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(envelope.SizeBytes, Equals, int64(len(data)))
+
+	rc, err := tarball.OpenBLOB(second.SHA512)
+	c.Assert(err, IsNil)
+	_, err = rc.Seek(6, io.SeekStart)
+	c.Assert(err, IsNil)
+	read, err := ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, data[6:])
+
+	rc, err = tarball.OpenBLOB(first.SHA512)
+	c.Assert(err, IsNil)
+	read, err = ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Assert(string(read), Equals, "first")
+
+	rc, err = tarball.Open("app.yaml")
+	c.Assert(err, IsNil)
+	read, err = ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Assert(string(read), Equals, "kind: Cluster")
+
+	_, err = tarball.OpenBLOB(strings.Repeat("0", len(first.SHA512)))
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	_, err = tarball.WriteBLOB(strings.NewReader("data"))
+	c.Assert(err, NotNil)
+}
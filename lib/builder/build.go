@@ -32,7 +32,7 @@ import (
 
 // Build builds the standalone application installer using the provided builder
 func Build(ctx context.Context, builder *Builder) error {
-	err := checkBuildEnv()
+	err := checkBuildEnv(builder.VendorReq.Daemonless)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -113,12 +113,16 @@ func Build(ctx context.Context, builder *Builder) error {
 }
 
 // checkBuildEnv makes sure that the environment "tele build" is invoked in is
-// suitable, for example, OS is supported and Docker is running
-func checkBuildEnv() error {
+// suitable, for example, OS is supported and Docker is running unless
+// images are vendored without a Docker daemon
+func checkBuildEnv(daemonless bool) error {
 	if runtime.GOOS != "linux" {
 		return trace.BadParameter("tele build is not supported on %v, only "+
 			"Linux is supported", runtime.GOOS)
 	}
+	if daemonless {
+		return nil
+	}
 	client, err := docker.NewDefaultClient()
 	if err != nil {
 		return trace.Wrap(err)
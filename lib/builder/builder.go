@@ -29,6 +29,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/archive"
 	blobfs "github.com/gravitational/gravity/lib/blob/fs"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -39,6 +40,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/layerpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/sbom"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
@@ -46,10 +48,11 @@ import (
 	"k8s.io/helm/pkg/chartutil"
 
 	"github.com/coreos/go-semver/semver"
-	"github.com/docker/docker/pkg/archive"
+	dockerarchive "github.com/docker/docker/pkg/archive"
 	"github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
 	"github.com/gravitational/version"
+	"github.com/jonboulle/clockwork"
 	"github.com/sirupsen/logrus"
 )
 
@@ -89,6 +92,12 @@ type Config struct {
 	Credentials *credentials.Credentials
 	// Level is the level at which the progress should be reported
 	Level utils.ProgressLevel
+	// Signer optionally signs the installer packages
+	Signer pack.Signer
+	// SourceDate, if set, enables reproducible builds: it is used in place
+	// of the current time for all timestamps recorded in the image and
+	// the entries of the image tarballs are normalized
+	SourceDate time.Time
 	// FieldLogger is used for logging
 	logrus.FieldLogger
 	// Progress allows builder to report build progress
@@ -338,11 +347,73 @@ func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error)
 	vendorReq := b.VendorReq
 	vendorReq.ManifestPath = manifestPath
 	vendorReq.ProgressReporter = b.Progress
+	vendorReq.SourceDate = b.SourceDate
 	err = vendorer.VendorDir(ctx, dir, vendorReq)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return archive.Tar(dir, archive.Uncompressed)
+	err = b.writeSBOM(ctx, dir, manifestPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	stream, err := dockerarchive.Tar(dir, dockerarchive.Uncompressed)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !b.SourceDate.IsZero() {
+		return archive.NormalizeStream(stream, b.SourceDate, dockerarchive.Uncompressed), nil
+	}
+	return stream, nil
+}
+
+// writeSBOM generates the software bill of materials for the contents of
+// the vendor directory dir and saves it at the root of the directory
+func (b *Builder) writeSBOM(ctx context.Context, dir, manifestPath string) error {
+	b.NextStep("Generating software bill of materials")
+	manifest, err := schema.ParseManifest(manifestPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locator := b.Locator()
+	dependencies, err := app.GetDependencies(&app.Application{
+		Package:  locator,
+		Manifest: *manifest,
+	}, b.Apps)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if dependencies == nil {
+		// Application images do not pull their dependencies so
+		// only the direct ones are known
+		dependencies = &app.Dependencies{
+			Packages: manifest.Dependencies.GetPackages(),
+			Apps:     manifest.Dependencies.GetApps(),
+		}
+	}
+	var clock clockwork.Clock
+	if !b.SourceDate.IsZero() {
+		clock = clockwork.NewFakeClockAt(b.SourceDate)
+	}
+	bom, err := sbom.Generate(ctx, sbom.Config{
+		Application:  locator,
+		Dir:          dir,
+		Dependencies: *dependencies,
+		Packages:     b.Packages,
+		Clock:        clock,
+		FieldLogger:  b.FieldLogger,
+	})
+	if err != nil {
+		return trace.Wrap(err, "failed to generate software bill of materials")
+	}
+	data, err := bom.Marshal()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, defaults.SBOMFileName), data, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
 }
 
 // CreateApplication creates a Gravity application from the provided
@@ -355,9 +426,10 @@ func (b *Builder) CreateApplication(data io.ReadCloser) (*app.Application, error
 		return nil, trace.Wrap(err)
 	}
 	op, err := b.Apps.CreateImportOperation(&app.ImportRequest{
-		Source:    data,
-		ProgressC: progressC,
-		ErrorC:    errorC,
+		Source:     data,
+		ProgressC:  progressC,
+		ErrorC:     errorC,
+		SourceDate: b.SourceDate,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
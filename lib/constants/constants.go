@@ -106,6 +106,18 @@ const (
 	// ETCDBackend defines storage backend as Etcd
 	ETCDBackend = "etcd"
 
+	// SQLBackend defines storage backend as a relational database
+	SQLBackend = "sql"
+
+	// BLOBStorageFS defines BLOB storage as files in a local directory
+	BLOBStorageFS = "fs"
+
+	// BLOBStorageChunked defines BLOB storage as deduplicated chunks in a local directory
+	BLOBStorageChunked = "chunked"
+
+	// BLOBStorageS3 defines BLOB storage as objects in S3-compatible bucket
+	BLOBStorageS3 = "s3"
+
 	// WebAssetsPackage names the web assets package
 	WebAssetsPackage = "web-assets"
 
@@ -206,6 +218,10 @@ const (
 	// ServiceGroupEnvVar names the environment variable that specifies the service group ID
 	ServiceGroupEnvVar = "GRAVITY_SERVICE_GROUP"
 
+	// DiscoverySecretEnvVar names the environment variable that specifies the secret
+	// used to authenticate with the cluster discovery endpoint
+	DiscoverySecretEnvVar = "GRAVITY_DISCOVERY_SECRET"
+
 	// PreflightChecksOffEnvVar is the name of environment variable that can be used to turn off preflight
 	// checks during install or update.
 	// If not empty, turns the preflight checks off
@@ -352,6 +368,10 @@ const (
 	// EnvKubeConfig is environment variable for kubeconfig
 	EnvKubeConfig = "KUBECONFIG"
 
+	// EnvSourceDateEpoch is environment variable with the Unix timestamp
+	// to use in place of the current time for reproducible builds
+	EnvSourceDateEpoch = "SOURCE_DATE_EPOCH"
+
 	// EnvPodIP is environment variable that contains pod IP address
 	EnvPodIP = "POD_IP"
 	// EnvPodName is environment variable with the pod name
@@ -719,6 +739,11 @@ const (
 	//
 	// Used in audit events.
 	ServiceStatusChecker = "@statuschecker"
+	// ServiceOperationQueue is the name of the service that starts
+	// operations from the cluster operation queue.
+	//
+	// Used in audit events.
+	ServiceOperationQueue = "@operationqueue"
 	// ServiceSystem is the identifier used as a "user" field for events
 	// that are triggered not by a human user but by a system process.
 	//
@@ -737,6 +762,8 @@ var (
 	EncodingShort Format = "short"
 	// EncodingYAML is for the YAML encoding format
 	EncodingYAML Format = "yaml"
+	// EncodingCSV is for the CSV encoding format
+	EncodingCSV Format = "csv"
 	// OutputFormats is a list of recognized output formats for gravity CLI commands
 	OutputFormats = []Format{
 		EncodingText,
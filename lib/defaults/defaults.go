@@ -87,6 +87,13 @@ const (
 	// EtcdRetryInterval is the retry interval for some etcd commands
 	EtcdRetryInterval = 3 * time.Second
 
+	// EtcdMemberStartTimeout is how long to wait for a newly added etcd member to start
+	EtcdMemberStartTimeout = 10 * time.Minute
+
+	// EtcdMembershipLockTTL is how long the etcd membership lock is held
+	// if its owner fails to release it
+	EtcdMembershipLockTTL = 15 * time.Minute
+
 	// InstallApplicationTimeout is the max allowed time for k8s application to install
 	InstallApplicationTimeout = 90 * time.Minute // 1.5 hours
 
@@ -96,6 +103,14 @@ const (
 	// UpdateTimeout is the max allowed time for system update
 	UpdateTimeout = 30 * time.Minute
 
+	// UpdateHealthTimeout is the max allowed time for the cluster to become
+	// healthy after a batch of nodes has been updated
+	UpdateHealthTimeout = 10 * time.Minute
+
+	// PhaseHealthTimeout is the max allowed time for the health checks
+	// declared by an operation phase to pass
+	PhaseHealthTimeout = 5 * time.Minute
+
 	// InstallSystemServiceTimeout specifies the maximum time to wait for system install service to complete
 	InstallSystemServiceTimeout = 5 * time.Minute
 
@@ -138,6 +153,18 @@ const (
 	// MaxExpandConcurrency is the number of servers that can be joining the cluster concurrently
 	MaxExpandConcurrency = 5
 
+	// OperationQueueTTL is how long an operation stays in the cluster
+	// operation queue if it has not been started
+	OperationQueueTTL = time.Hour
+
+	// OperationQueuePollInterval is how often a queued operation is checked
+	// for whether it can be started in addition to watching for cluster changes
+	OperationQueuePollInterval = 10 * time.Second
+
+	// OperationQueueLeaseTimeout is how long a queued operation stays in the
+	// cluster operation queue after its client has stopped renewing it
+	OperationQueueLeaseTimeout = time.Minute
+
 	// DownloadRetryPeriod is the period between failed retry attempts
 	DownloadRetryPeriod = 5 * time.Second
 
@@ -528,6 +555,37 @@ const (
 	// to prevent accidental deletion
 	GracePeriod = 24 * time.Hour
 
+	// MinChunkSize is the minimum size of a chunk in chunked BLOB storage
+	MinChunkSize = 256 * 1024
+	// AvgChunkSize is the target average size of a chunk in chunked BLOB storage
+	AvgChunkSize = 1024 * 1024
+	// MaxChunkSize is the maximum size of a chunk in chunked BLOB storage
+	MaxChunkSize = 4 * 1024 * 1024
+
+	// ChunkPinTTL is how long chunks uploaded for a BLOB are kept in chunked
+	// BLOB storage before the BLOB manifest referencing them is written
+	ChunkPinTTL = time.Hour
+
+	// S3PartSize is the size of a part in multipart uploads to S3 BLOB storage
+	S3PartSize = 16 * 1024 * 1024
+	// S3CopyPartSize is the size of a part in multipart copies in S3 BLOB storage
+	S3CopyPartSize = 1024 * 1024 * 1024
+	// S3MaxCopySize is the maximum object size S3 can copy in a single request
+	S3MaxCopySize = 5 * 1024 * 1024 * 1024
+	// S3MinPartSize is the minimum size of a part, other than the last one,
+	// in S3 multipart uploads and copies
+	S3MinPartSize = 5 * 1024 * 1024
+	// S3MaxPartSize is the maximum size of a part in S3 multipart uploads and copies
+	S3MaxPartSize = 5 * 1024 * 1024 * 1024
+	// S3StaleUploadTimeout is the age of a temporary object in S3 BLOB storage
+	// after which it is considered left over by an interrupted upload
+	S3StaleUploadTimeout = 24 * time.Hour
+
+	// PackageDeltaBlockSize is the size of a block in package delta signatures
+	PackageDeltaBlockSize = 16 * 1024
+	// MaxPackageSignatureSize is the maximum size of an encoded package delta signature
+	MaxPackageSignatureSize = 64 * 1024 * 1024
+
 	// APIPrefix defines the URL prefix for kubernetes-related queries tunneled from a master node
 	APIPrefix = "/k8s"
 	// APIServerPort defines the port of the kubernetes API server
@@ -590,6 +648,10 @@ const (
 	// RegistryDir is the name of the layers directory inside an application tarball
 	RegistryDir = "registry"
 
+	// SBOMFileName is the name of the software bill of materials inside
+	// application packages and installer tarballs
+	SBOMFileName = "bom.json"
+
 	// CheckForUpdatesInterval is how often local gravity site will attempt to check
 	// for new app versions with OpsCenter
 	CheckForUpdatesInterval = 10 * time.Second
@@ -805,6 +867,18 @@ const (
 	// DecoderBufferSize is the size of the buffer used when decoding YAML resources
 	DecoderBufferSize = 1024 * 1024
 
+	// WatchBufferSize is the number of storage watch events buffered for a single watcher
+	WatchBufferSize = 1024
+
+	// WatchPollInterval is the interval between checks for changes made
+	// to a shared bolt database by other processes, and between attempts
+	// to restart a stopped watch
+	WatchPollInterval = 500 * time.Millisecond
+
+	// WatchCoalesceInterval is the interval during which subsequent changes
+	// are combined into a single update when following cluster changes
+	WatchCoalesceInterval = 200 * time.Millisecond
+
 	// DiskCapacity is the minimum required free disk space for some default directories
 	DiskCapacity = "5GB"
 	// DiskTransferRate is the minimum required disk speed for some default locations
@@ -894,6 +968,9 @@ const (
 
 	// EtcdGravityPrefix is etcd prefix under which gravity keeps its data
 	EtcdGravityPrefix = "/gravity"
+	// EtcdMembershipLockKey is the etcd key used to serialize etcd membership
+	// changes made by concurrently joining master nodes
+	EtcdMembershipLockKey = "/gravity/locks/etcd-membership"
 	// EtcdPlanetPrefix is etcd prefix under which planet keeps its data
 	EtcdPlanetPrefix = "/planet"
 
@@ -1148,6 +1225,9 @@ var (
 	DiscoveryPublishInterval = 5 * time.Second
 	// DiscoveryResyncInterval specifies the frequency to force publish cluster discovery details
 	DiscoveryResyncInterval = 10 * time.Minute
+	// AutoscaleWebhookListenAddr is the default address the webhook autoscaling
+	// provider accepts autoscaling events on
+	AutoscaleWebhookListenAddr = "0.0.0.0:3015"
 
 	// CACertificateExpiry is the validity period of self-signed CA generated
 	// for clusters during installation
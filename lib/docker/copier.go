@@ -0,0 +1,745 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+// ImageCopierConfig defines the configuration of the image copier
+type ImageCopierConfig struct {
+	// Dir is the directory in docker registry 2.x format to copy images into
+	Dir string
+	// Sources lists OCI image layout directories and 'docker save' tarballs
+	// or directories to look up images in before pulling them from registries
+	Sources []string
+	// InsecureRegistries lists registries to access over plain HTTP
+	InsecureRegistries []string
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *ImageCopierConfig) CheckAndSetDefaults() error {
+	if r.Dir == "" {
+		return trace.BadParameter("missing parameter Dir")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "image-copier")
+	}
+	return nil
+}
+
+// NewImageCopier returns a new image copier for the specified configuration
+func NewImageCopier(config ImageCopierConfig) (*ImageCopier, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.MkdirAll(config.Dir, defaults.SharedDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	local, err := openLocal(config.Dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	copier := &ImageCopier{
+		ImageCopierConfig: config,
+		local:             local,
+		images:            make(map[string]imageSource),
+		archived:          make(map[string]bool),
+	}
+	for _, source := range config.Sources {
+		if err := copier.addSource(source); err != nil {
+			copier.Close()
+			return nil, trace.Wrap(err, "failed to read image source %v", source)
+		}
+	}
+	return copier, nil
+}
+
+// ImageCopier copies container images into a local registry directory
+// without a Docker daemon.
+//
+// Images are looked up in the configured image sources first and are
+// pulled directly from their registries otherwise.
+type ImageCopier struct {
+	// ImageCopierConfig is the copier configuration
+	ImageCopierConfig
+	local *localStore
+	// images maps normalized references to images found in image sources
+	images map[string]imageSource
+	// archived lists normalized repositories of images found in
+	// 'docker save' archives
+	archived map[string]bool
+	// tempDirs lists directories image source tarballs were unpacked into
+	tempDirs []string
+}
+
+// Copy copies the specified image into the registry directory.
+//
+// The image is stored in the repository named after the image with
+// the registry part removed.
+// Images referenced by digest are only copied if their manifest is
+// preserved as-is by the image source
+func (r *ImageCopier) Copy(ctx context.Context, image string) error {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dst, err := r.local.Repository(ctx, parsed.Repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var manifest distribution.Manifest
+	if source, ok := r.images[normalizeImage(*parsed)]; ok {
+		r.WithField("image", image).Info("Copy image from local source.")
+		manifest, err = source.copyTo(ctx, dst)
+	} else if isDigest(parsed.Tag) && r.archived[normalizeRepository(*parsed)] {
+		return trace.BadParameter("image %v is referenced by digest but 'docker save' "+
+			"archives do not preserve image manifests, reference the image by tag or "+
+			"provide it as an OCI image layout", image)
+	} else {
+		r.WithField("image", image).Info("Pull image from registry.")
+		manifest, err = r.pull(ctx, *parsed, dst)
+	}
+	if err != nil {
+		return trace.Wrap(err, "failed to copy image %v", image)
+	}
+	if isDigest(parsed.Tag) {
+		if err := checkManifestDigest(manifest, digest.Digest(parsed.Tag)); err != nil {
+			return trace.Wrap(err, "failed to copy image %v", image)
+		}
+	}
+	manifests, err := dst.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dgst, err := manifests.Put(ctx, manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if isDigest(parsed.Tag) {
+		return nil
+	}
+	// Storage backed repositories ignore the tag option of Put
+	// so the manifest is tagged explicitly
+	err = dst.Tags(ctx).Tag(ctx, imageTag(*parsed), distribution.Descriptor{Digest: dgst})
+	return trace.Wrap(err)
+}
+
+// Close removes temporary directories used by the copier
+func (r *ImageCopier) Close() error {
+	var errors []error
+	for _, dir := range r.tempDirs {
+		errors = append(errors, os.RemoveAll(dir))
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// pull copies the specified image from its registry into dst and
+// returns the image manifest
+func (r *ImageCopier) pull(ctx context.Context, image loc.DockerImage, dst distribution.Repository) (distribution.Manifest, error) {
+	registry, path := remoteRepository(image)
+	req := RegistryConnectionRequest{
+		RegistryAddress: registry,
+		Repository:      path,
+	}
+	if utils.StringInSlice(r.InsecureRegistries, registry) {
+		req.RegistryAddress = "http://" + registry
+		req.CertName = registry
+	}
+	req.Username, req.Password = registryCredentials(registry)
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	remote, err := ConnectRegistry(ctx, req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repository, err := remote.Repository(ctx, path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifests, err := repository.Manifests(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var manifest distribution.Manifest
+	if isDigest(image.Tag) {
+		manifest, err = manifests.Get(ctx, digest.Digest(image.Tag))
+	} else {
+		manifest, err = manifests.Get(ctx, "", distribution.WithTag(imageTag(image)))
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if list, ok := manifest.(*manifestlist.DeserializedManifestList); ok {
+		if isDigest(image.Tag) {
+			return nil, trace.BadParameter("image %v references a manifest list by digest, "+
+				"reference the %v/%v image digest instead", image.String(), platformOS, platformArch)
+		}
+		desc, err := selectPlatform(list)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		manifest, err = manifests.Get(ctx, desc.Digest)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	blobs := repository.Blobs(ctx)
+	err = copyBlobs(ctx, dst, manifest, func(ctx context.Context, dgst digest.Digest) (io.ReadCloser, error) {
+		return blobs.Open(ctx, dgst)
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// addSource indexes images found in the specified image source.
+// Tarballs are unpacked into a temporary directory first
+func (r *ImageCopier) addSource(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	dir := path
+	if !fi.IsDir() {
+		dir, err = ioutil.TempDir("", "image-source")
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		r.tempDirs = append(r.tempDirs, dir)
+		if err := untar(path, dir); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ociIndexFile)); err == nil {
+		return trace.Wrap(r.addOCILayout(dir))
+	}
+	if _, err := os.Stat(filepath.Join(dir, dockerArchiveManifestFile)); err == nil {
+		return trace.Wrap(r.addDockerArchive(dir))
+	}
+	return trace.BadParameter("%v is neither an OCI image layout nor a 'docker save' archive", path)
+}
+
+// addOCILayout indexes images in the OCI image layout in dir.
+// Images are matched by the full image reference in either containerd image
+// name or reference name annotations, and by the manifest digest
+func (r *ImageCopier) addOCILayout(dir string) error {
+	var index ocispec.Index
+	if err := readJSON(filepath.Join(dir, ociIndexFile), &index); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, desc := range index.Manifests {
+		name := desc.Annotations[containerdImageNameAnnotation]
+		if name == "" {
+			name = desc.Annotations[ocispec.AnnotationRefName]
+		}
+		// Reference name annotation may only contain the tag
+		// which is not enough to match the image
+		if !strings.ContainsAny(name, ":/") {
+			r.WithField("digest", desc.Digest).Debug("Skip image without full reference.")
+			continue
+		}
+		source := &ociImage{dir: dir, desc: desc}
+		if err := r.addImage(name, source); err != nil {
+			return trace.Wrap(err)
+		}
+		parsed, err := loc.ParseDockerImage(name)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		parsed.Tag = desc.Digest.String()
+		if err := r.addImage(parsed.String(), source); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// addDockerArchive indexes images in the unpacked 'docker save' archive in dir.
+// Images are matched by tag only: the archive does not preserve the image
+// manifests so digest references cannot be matched
+func (r *ImageCopier) addDockerArchive(dir string) error {
+	var manifests []dockerArchiveManifest
+	if err := readJSON(filepath.Join(dir, dockerArchiveManifestFile), &manifests); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, manifest := range manifests {
+		for _, tag := range manifest.RepoTags {
+			if err := r.addImage(tag, &dockerArchiveImage{dir: dir, manifest: manifest}); err != nil {
+				return trace.Wrap(err)
+			}
+			parsed, err := loc.ParseDockerImage(tag)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			r.archived[normalizeRepository(*parsed)] = true
+		}
+	}
+	return nil
+}
+
+func (r *ImageCopier) addImage(name string, source imageSource) error {
+	parsed, err := loc.ParseDockerImage(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.WithField("image", name).Debug("Found image in local source.")
+	r.images[normalizeImage(*parsed)] = source
+	return nil
+}
+
+// imageSource is a container image in a local image source
+type imageSource interface {
+	// copyTo copies the image blobs into dst and returns the image manifest
+	copyTo(ctx context.Context, dst distribution.Repository) (distribution.Manifest, error)
+}
+
+// ociImage is an image in an OCI image layout directory
+type ociImage struct {
+	dir  string
+	desc ocispec.Descriptor
+}
+
+func (r *ociImage) copyTo(ctx context.Context, dst distribution.Repository) (distribution.Manifest, error) {
+	desc := r.desc
+	if desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == manifestlist.MediaTypeManifestList {
+		var index ocispec.Index
+		if err := readJSON(r.blobPath(desc.Digest), &index); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var err error
+		desc, err = selectOCIPlatform(index)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	payload, err := ioutil.ReadFile(r.blobPath(desc.Digest))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	manifest, err := readOCIManifest(desc.MediaType, payload)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = copyBlobs(ctx, dst, manifest, func(_ context.Context, dgst digest.Digest) (io.ReadCloser, error) {
+		f, err := os.Open(r.blobPath(dgst))
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+func (r *ociImage) blobPath(dgst digest.Digest) string {
+	return filepath.Join(r.dir, ociBlobsDir, dgst.Algorithm().String(), dgst.Hex())
+}
+
+// dockerArchiveImage is an image in an unpacked 'docker save' archive
+type dockerArchiveImage struct {
+	dir      string
+	manifest dockerArchiveManifest
+}
+
+// dockerArchiveManifest describes an image in 'docker save' archive manifest
+type dockerArchiveManifest struct {
+	// Config is the path to the image configuration
+	Config string
+	// RepoTags lists the image references
+	RepoTags []string
+	// Layers lists paths to uncompressed image layers
+	Layers []string
+}
+
+// copyTo writes the image configuration and compressed layers into dst
+// and returns a generated schema 2 manifest for the image
+func (r *dockerArchiveImage) copyTo(ctx context.Context, dst distribution.Repository) (distribution.Manifest, error) {
+	blobs := dst.Blobs(ctx)
+	config, err := ioutil.ReadFile(filepath.Join(r.dir, r.manifest.Config))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	configDesc, err := blobs.Put(ctx, schema2.MediaTypeImageConfig, config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	configDesc.MediaType = schema2.MediaTypeImageConfig
+	var layers []distribution.Descriptor
+	for _, layer := range r.manifest.Layers {
+		desc, err := writeCompressedLayer(ctx, blobs, filepath.Join(r.dir, layer))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		layers = append(layers, *desc)
+	}
+	manifest, err := schema2.FromStruct(schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    configDesc,
+		Layers:    layers,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// writeCompressedLayer writes the uncompressed layer at path into blobs
+// compressing it on the fly and returns the descriptor of the written layer
+func writeCompressedLayer(ctx context.Context, blobs distribution.BlobIngester, path string) (*distribution.Descriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	writer, err := blobs.Create(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer writer.Close()
+	digester := digest.Canonical.Digester()
+	compressor := gzip.NewWriter(io.MultiWriter(writer, digester.Hash()))
+	if _, err := io.Copy(compressor, f); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	desc := distribution.Descriptor{
+		MediaType: schema2.MediaTypeLayer,
+		Digest:    digester.Digest(),
+		Size:      writer.Size(),
+	}
+	if _, err := writer.Commit(ctx, desc); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &desc, nil
+}
+
+// blobOpenerFunc opens the blob with the specified digest for reading
+type blobOpenerFunc func(ctx context.Context, dgst digest.Digest) (io.ReadCloser, error)
+
+// copyBlobs copies the blobs referenced by the manifest that are
+// not yet present in dst using the specified opener
+func copyBlobs(ctx context.Context, dst distribution.Repository, manifest distribution.Manifest, open blobOpenerFunc) error {
+	blobs := dst.Blobs(ctx)
+	for _, desc := range manifest.References() {
+		if desc.MediaType == schema2.MediaTypeForeignLayer {
+			continue
+		}
+		if _, err := blobs.Stat(ctx, desc.Digest); err == nil {
+			continue
+		}
+		if err := copyBlob(ctx, blobs, desc, open); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func copyBlob(ctx context.Context, blobs distribution.BlobStore, desc distribution.Descriptor, open blobOpenerFunc) error {
+	reader, err := open(ctx, desc.Digest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	writer, err := blobs.Create(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer writer.Close()
+	if _, err := io.Copy(writer, reader); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = writer.Commit(ctx, distribution.Descriptor{Digest: desc.Digest})
+	return trace.Wrap(err)
+}
+
+// readOCIManifest returns the image manifest from the OCI image layout.
+// Schema 2 manifests are kept as-is which preserves the image digest.
+// OCI manifests are converted to schema 2 manifests as the registry does not
+// support them, which changes the image digest
+func readOCIManifest(mediaType string, payload []byte) (distribution.Manifest, error) {
+	if mediaType == schema2.MediaTypeManifest {
+		manifest, _, err := distribution.UnmarshalManifest(mediaType, payload)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return manifest, nil
+	}
+	var image ocispec.Manifest
+	if err := json.Unmarshal(payload, &image); err != nil {
+		return nil, trace.Wrap(err, "failed to decode image manifest")
+	}
+	manifest, err := convertOCIManifest(image)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// checkManifestDigest verifies that the manifest has the specified digest.
+// Returns trace.BadParameter if the image source has not preserved the
+// original image manifest
+func checkManifestDigest(manifest distribution.Manifest, expected digest.Digest) error {
+	_, payload, err := manifest.Payload()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if actual := digest.FromBytes(payload); actual != expected {
+		return trace.BadParameter("image is referenced by digest but its manifest has "+
+			"digest %v: the image source does not preserve the original manifest, for "+
+			"example, because it is an OCI manifest or a manifest list. Reference the "+
+			"image by tag or by the digest of its %v/%v schema 2 manifest instead",
+			actual, platformOS, platformArch)
+	}
+	return nil
+}
+
+// convertOCIManifest converts the OCI image manifest into
+// a schema 2 manifest referencing the same blobs
+func convertOCIManifest(image ocispec.Manifest) (distribution.Manifest, error) {
+	config, err := convertOCIDescriptor(image.Config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var layers []distribution.Descriptor
+	for _, layer := range image.Layers {
+		desc, err := convertOCIDescriptor(layer)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		layers = append(layers, *desc)
+	}
+	manifest, err := schema2.FromStruct(schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    *config,
+		Layers:    layers,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+func convertOCIDescriptor(desc ocispec.Descriptor) (*distribution.Descriptor, error) {
+	mediaType, ok := ociMediaTypes[desc.MediaType]
+	if !ok {
+		return nil, trace.BadParameter("unsupported media type %q", desc.MediaType)
+	}
+	return &distribution.Descriptor{
+		MediaType: mediaType,
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+		URLs:      desc.URLs,
+	}, nil
+}
+
+// selectPlatform returns the descriptor of the image for
+// the cluster platform from the manifest list
+func selectPlatform(list *manifestlist.DeserializedManifestList) (*distribution.Descriptor, error) {
+	for _, desc := range list.Manifests {
+		if desc.Platform.OS == platformOS && desc.Platform.Architecture == platformArch {
+			return &desc.Descriptor, nil
+		}
+	}
+	return nil, trace.NotFound("no %v/%v image found in manifest list", platformOS, platformArch)
+}
+
+// selectOCIPlatform returns the descriptor of the image for
+// the cluster platform from the OCI image index
+func selectOCIPlatform(index ocispec.Index) (ocispec.Descriptor, error) {
+	for _, desc := range index.Manifests {
+		if desc.Platform == nil && len(index.Manifests) == 1 {
+			return desc, nil
+		}
+		if desc.Platform != nil && desc.Platform.OS == platformOS && desc.Platform.Architecture == platformArch {
+			return desc, nil
+		}
+	}
+	return ocispec.Descriptor{}, trace.NotFound("no %v/%v image found in image index", platformOS, platformArch)
+}
+
+// normalizeImage returns the image reference in the form used to match
+// images: with the default registry and the official repository prefix
+// removed and the tag defaulted to latest
+func normalizeImage(image loc.DockerImage) string {
+	if isDefaultRegistry(image.Registry) {
+		image.Registry = ""
+		image.Repository = strings.TrimPrefix(image.Repository, officialRepoName+"/")
+	}
+	image.Tag = imageTag(image)
+	return image.String()
+}
+
+// normalizeRepository returns the normalized image reference without the tag
+func normalizeRepository(image loc.DockerImage) string {
+	image.Tag = ""
+	return strings.TrimSuffix(normalizeImage(image), ":"+defaultTag)
+}
+
+// remoteRepository returns the registry address and the repository path
+// to pull the specified image from
+func remoteRepository(image loc.DockerImage) (registry, path string) {
+	if !isDefaultRegistry(image.Registry) {
+		return image.Registry, image.Repository
+	}
+	path = image.Repository
+	if !strings.Contains(path, "/") {
+		path = officialRepoName + "/" + path
+	}
+	return defaultRegistryAddress, path
+}
+
+func isDefaultRegistry(registry string) bool {
+	return registry == "" || registry == defaultDomain || registry == legacyDefaultDomain
+}
+
+func imageTag(image loc.DockerImage) string {
+	if image.Tag == "" {
+		return defaultTag
+	}
+	return image.Tag
+}
+
+func isDigest(tag string) bool {
+	return strings.HasPrefix(tag, string(digest.Canonical)+":")
+}
+
+// registryCredentials returns the credentials for the specified registry
+// saved in the Docker client configuration file, if any
+func registryCredentials(registry string) (username, password string) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := readJSON(filepath.Join(dir, "config.json"), &config); err != nil {
+		return "", ""
+	}
+	keys := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == defaultRegistryAddress {
+		keys = append(keys, legacyDefaultRegistryURL)
+	}
+	for _, key := range keys {
+		entry, ok := config.Auths[key]
+		if !ok {
+			continue
+		}
+		auth, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			continue
+		}
+		parts := strings.SplitN(string(auth), ":", 2)
+		if len(parts) == 2 {
+			return parts[0], parts[1]
+		}
+	}
+	return "", ""
+}
+
+func untar(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	return trace.Wrap(dockerarchive.Untar(f, dir, &dockerarchive.TarOptions{NoLchown: true}))
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return trace.Wrap(err, "failed to decode %v", path)
+	}
+	return nil
+}
+
+// ociMediaTypes maps OCI media types to their schema 2 equivalents
+var ociMediaTypes = map[string]string{
+	ocispec.MediaTypeImageConfig:                    schema2.MediaTypeImageConfig,
+	ocispec.MediaTypeImageLayerGzip:                 schema2.MediaTypeLayer,
+	ocispec.MediaTypeImageLayer:                     schema2.MediaTypeUncompressedLayer,
+	ocispec.MediaTypeImageLayerNonDistributableGzip: schema2.MediaTypeForeignLayer,
+	schema2.MediaTypeImageConfig:                    schema2.MediaTypeImageConfig,
+	schema2.MediaTypeLayer:                          schema2.MediaTypeLayer,
+	schema2.MediaTypeUncompressedLayer:              schema2.MediaTypeUncompressedLayer,
+	schema2.MediaTypeForeignLayer:                   schema2.MediaTypeForeignLayer,
+}
+
+const (
+	// platformOS is the operating system of the cluster images
+	platformOS = "linux"
+	// platformArch is the CPU architecture of the cluster images
+	platformArch = "amd64"
+	// defaultTag is the tag of the images referenced without one
+	defaultTag = "latest"
+	// legacyDefaultDomain is the alternative name of the default registry domain
+	legacyDefaultDomain = "index.docker.io"
+	// defaultRegistryAddress is the address of the default registry API
+	defaultRegistryAddress = "registry-1.docker.io"
+	// legacyDefaultRegistryURL is the key of the default registry
+	// credentials in the Docker client configuration
+	legacyDefaultRegistryURL = "https://index.docker.io/v1/"
+	// containerdImageNameAnnotation is the annotation with the full image
+	// reference set by containerd-based exporters
+	containerdImageNameAnnotation = "io.containerd.image.name"
+	// dockerArchiveManifestFile is the name of the 'docker save' archive manifest
+	dockerArchiveManifestFile = "manifest.json"
+	// ociIndexFile is the name of the OCI image layout index
+	ociIndexFile = "index.json"
+	// ociBlobsDir is the name of the OCI image layout blobs directory
+	ociBlobsDir = "blobs"
+)
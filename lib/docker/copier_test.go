@@ -0,0 +1,370 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	. "gopkg.in/check.v1"
+)
+
+type CopierSuite struct{}
+
+var _ = Suite(&CopierSuite{})
+
+func (s *CopierSuite) TestCopiesImageFromDockerArchive(c *C) {
+	source := c.MkDir()
+	layer := newLayer(c)
+	config := newImageConfig(c, layer)
+	writeFile(c, filepath.Join(source, "config.json"), config)
+	writeFile(c, filepath.Join(source, "layer", "layer.tar"), layer)
+	writeJSON(c, filepath.Join(source, dockerArchiveManifestFile), []dockerArchiveManifest{{
+		Config:   "config.json",
+		RepoTags: []string{"example.com/app:1.0"},
+		Layers:   []string{"layer/layer.tar"},
+	}})
+
+	dir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: dir, Sources: []string{source}})
+	c.Assert(err, IsNil)
+	defer copier.Close()
+	c.Assert(copier.Copy(context.Background(), "example.com/app:1.0"), IsNil)
+
+	manifest := getManifest(c, dir, "app", "1.0")
+	c.Assert(manifest.Config.Digest, Equals, digest.FromBytes(config))
+	c.Assert(manifest.Layers, HasLen, 1)
+	c.Assert(manifest.Layers[0].MediaType, Equals, schema2.MediaTypeLayer)
+}
+
+func (s *CopierSuite) TestCopiesImageFromOCILayout(c *C) {
+	source := c.MkDir()
+	layer := compress(c, newLayer(c))
+	config := newImageConfig(c, layer)
+	image := ocispec.Manifest{
+		Config: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []ocispec.Descriptor{{
+			MediaType: ocispec.MediaTypeImageLayerGzip,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		}},
+	}
+	image.SchemaVersion = 2
+	imageBytes, err := json.Marshal(image)
+	c.Assert(err, IsNil)
+	for _, blob := range [][]byte{config, layer, imageBytes} {
+		writeFile(c, filepath.Join(source, ociBlobsDir, "sha256", digest.FromBytes(blob).Hex()), blob)
+	}
+	index := ocispec.Index{
+		Manifests: []ocispec.Descriptor{{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(imageBytes),
+			Size:      int64(len(imageBytes)),
+			Annotations: map[string]string{
+				containerdImageNameAnnotation: "docker.io/library/app:2.0",
+				ocispec.AnnotationRefName:     "2.0",
+			},
+		}},
+	}
+	index.SchemaVersion = 2
+	writeJSON(c, filepath.Join(source, ociIndexFile), index)
+
+	dir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: dir, Sources: []string{source}})
+	c.Assert(err, IsNil)
+	defer copier.Close()
+	c.Assert(copier.Copy(context.Background(), "app:2.0"), IsNil)
+
+	manifest := getManifest(c, dir, "app", "2.0")
+	c.Assert(manifest.Config.MediaType, Equals, schema2.MediaTypeImageConfig)
+	c.Assert(manifest.Layers, DeepEquals, []distribution.Descriptor{{
+		MediaType: schema2.MediaTypeLayer,
+		Digest:    digest.FromBytes(layer),
+		Size:      int64(len(layer)),
+	}})
+}
+
+func (s *CopierSuite) TestCopiesImageFromOCILayoutByDigest(c *C) {
+	source := c.MkDir()
+	layer := compress(c, newLayer(c))
+	config := newImageConfig(c, layer)
+	image, err := schema2.FromStruct(schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config: distribution.Descriptor{
+			MediaType: schema2.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []distribution.Descriptor{{
+			MediaType: schema2.MediaTypeLayer,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		}},
+	})
+	c.Assert(err, IsNil)
+	_, imageBytes, err := image.Payload()
+	c.Assert(err, IsNil)
+	for _, blob := range [][]byte{config, layer, imageBytes} {
+		writeFile(c, filepath.Join(source, ociBlobsDir, "sha256", digest.FromBytes(blob).Hex()), blob)
+	}
+	index := ocispec.Index{
+		Manifests: []ocispec.Descriptor{{
+			MediaType: schema2.MediaTypeManifest,
+			Digest:    digest.FromBytes(imageBytes),
+			Size:      int64(len(imageBytes)),
+			Annotations: map[string]string{
+				containerdImageNameAnnotation: "example.com/app:2.0",
+			},
+		}},
+	}
+	index.SchemaVersion = 2
+	writeJSON(c, filepath.Join(source, ociIndexFile), index)
+
+	dir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: dir, Sources: []string{source}})
+	c.Assert(err, IsNil)
+	defer copier.Close()
+	dgst := digest.FromBytes(imageBytes)
+	c.Assert(copier.Copy(context.Background(), "example.com/app@"+dgst.String()), IsNil)
+
+	// the manifest is stored as-is
+	ctx := context.Background()
+	store, err := openLocal(dir)
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, "app")
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	exists, err := manifests.Exists(ctx, dgst)
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, true)
+}
+
+func (s *CopierSuite) TestRejectsDigestReferencesToConvertedImages(c *C) {
+	source := c.MkDir()
+	layer := compress(c, newLayer(c))
+	config := newImageConfig(c, layer)
+	image := ocispec.Manifest{
+		Config: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []ocispec.Descriptor{{
+			MediaType: ocispec.MediaTypeImageLayerGzip,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		}},
+	}
+	image.SchemaVersion = 2
+	imageBytes, err := json.Marshal(image)
+	c.Assert(err, IsNil)
+	for _, blob := range [][]byte{config, layer, imageBytes} {
+		writeFile(c, filepath.Join(source, ociBlobsDir, "sha256", digest.FromBytes(blob).Hex()), blob)
+	}
+	index := ocispec.Index{
+		Manifests: []ocispec.Descriptor{{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(imageBytes),
+			Size:      int64(len(imageBytes)),
+			Annotations: map[string]string{
+				containerdImageNameAnnotation: "example.com/app:2.0",
+			},
+		}},
+	}
+	index.SchemaVersion = 2
+	writeJSON(c, filepath.Join(source, ociIndexFile), index)
+	archive := c.MkDir()
+	writeFile(c, filepath.Join(archive, "config.json"), config)
+	writeFile(c, filepath.Join(archive, "layer.tar"), newLayer(c))
+	writeJSON(c, filepath.Join(archive, dockerArchiveManifestFile), []dockerArchiveManifest{{
+		Config:   "config.json",
+		RepoTags: []string{"example.com/other:1.0"},
+		Layers:   []string{"layer.tar"},
+	}})
+
+	dir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: dir, Sources: []string{source, archive}})
+	c.Assert(err, IsNil)
+	defer copier.Close()
+	// OCI manifests are converted which changes their digest
+	err = copier.Copy(context.Background(), "example.com/app@"+digest.FromBytes(imageBytes).String())
+	c.Assert(err, ErrorMatches, "(?s).*does not preserve the original manifest.*")
+	// 'docker save' archives do not preserve manifests at all
+	err = copier.Copy(context.Background(), "example.com/other@"+digest.FromBytes(config).String())
+	c.Assert(err, ErrorMatches, "(?s).*archives do not preserve image manifests.*")
+}
+
+func (s *CopierSuite) TestPullsImageFromRegistry(c *C) {
+	source := c.MkDir()
+	layer := newLayer(c)
+	writeFile(c, filepath.Join(source, "config.json"), newImageConfig(c, layer))
+	writeFile(c, filepath.Join(source, "layer.tar"), layer)
+	writeJSON(c, filepath.Join(source, dockerArchiveManifestFile), []dockerArchiveManifest{{
+		Config:   "config.json",
+		RepoTags: []string{"app:1.0"},
+		Layers:   []string{"layer.tar"},
+	}})
+	registryDir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: registryDir, Sources: []string{source}})
+	c.Assert(err, IsNil)
+	c.Assert(copier.Copy(context.Background(), "app:1.0"), IsNil)
+	expected := getManifest(c, registryDir, "app", "1.0")
+
+	registry, err := NewRegistry(BasicConfiguration("127.0.0.1:0", registryDir))
+	c.Assert(err, IsNil)
+	c.Assert(registry.Start(), IsNil)
+	defer registry.Close()
+
+	dir := c.MkDir()
+	copier, err = NewImageCopier(ImageCopierConfig{
+		Dir:                dir,
+		InsecureRegistries: []string{registry.Addr()},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(copier.Copy(context.Background(), registry.Addr()+"/app:1.0"), IsNil)
+	c.Assert(getManifest(c, dir, "app", "1.0"), DeepEquals, expected)
+}
+
+func (s *CopierSuite) TestListsLocalImages(c *C) {
+	source := c.MkDir()
+	layer := newLayer(c)
+	writeFile(c, filepath.Join(source, "config.json"), newImageConfig(c, layer))
+	writeFile(c, filepath.Join(source, "layer.tar"), layer)
+	writeJSON(c, filepath.Join(source, dockerArchiveManifestFile), []dockerArchiveManifest{{
+		Config:   "config.json",
+		RepoTags: []string{"app:1.0"},
+		Layers:   []string{"layer.tar"},
+	}})
+	dir := c.MkDir()
+	copier, err := NewImageCopier(ImageCopierConfig{Dir: dir, Sources: []string{source}})
+	c.Assert(err, IsNil)
+	defer copier.Close()
+	c.Assert(copier.Copy(context.Background(), "app:1.0"), IsNil)
+	manifest := getManifest(c, dir, "app", "1.0")
+
+	registry, err := OpenLocalRegistry(dir)
+	c.Assert(err, IsNil)
+	images, err := registry.Images(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(images, HasLen, 1)
+	c.Assert(images[0].Repository, Equals, "app")
+	c.Assert(images[0].Tag, Equals, "1.0")
+	c.Assert(images[0].Layers, DeepEquals, manifest.Layers)
+
+	rc, err := registry.OpenLayer(context.Background(), "app", images[0].Layers[0])
+	c.Assert(err, IsNil)
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, layer)
+}
+
+func (s *CopierSuite) TestNormalizesImages(c *C) {
+	var testCases = []struct {
+		image      string
+		normalized string
+	}{
+		{image: "nginx", normalized: "nginx:latest"},
+		{image: "docker.io/library/nginx:1.17", normalized: "nginx:1.17"},
+		{image: "index.docker.io/gravitational/debian-tall:0.0.1", normalized: "gravitational/debian-tall:0.0.1"},
+		{image: "quay.io/library/app:1.0", normalized: "quay.io/library/app:1.0"},
+	}
+	config := ImageCopierConfig{Dir: c.MkDir()}
+	c.Assert(config.CheckAndSetDefaults(), IsNil)
+	for _, tc := range testCases {
+		copier := &ImageCopier{ImageCopierConfig: config, images: make(map[string]imageSource)}
+		c.Assert(copier.addImage(tc.image, nil), IsNil)
+		_, ok := copier.images[tc.normalized]
+		c.Assert(ok, Equals, true, Commentf(tc.image))
+	}
+}
+
+func getManifest(c *C, dir, repository, tag string) *schema2.DeserializedManifest {
+	ctx := context.Background()
+	store, err := openLocal(dir)
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, repository)
+	c.Assert(err, IsNil)
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	manifest, err := manifests.Get(ctx, desc.Digest)
+	c.Assert(err, IsNil)
+	deserialized, ok := manifest.(*schema2.DeserializedManifest)
+	c.Assert(ok, Equals, true)
+	return deserialized
+}
+
+func newLayer(c *C) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	data := []byte("hello")
+	c.Assert(w.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0644, Size: int64(len(data))}), IsNil)
+	_, err := w.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func newImageConfig(c *C, layer []byte) []byte {
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": platformArch,
+		"os":           platformOS,
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": []string{digest.FromBytes(layer).String()},
+		},
+	})
+	c.Assert(err, IsNil)
+	return config
+}
+
+func compress(c *C, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func writeJSON(c *C, path string, v interface{}) {
+	data, err := json.Marshal(v)
+	c.Assert(err, IsNil)
+	writeFile(c, path, data)
+}
+
+func writeFile(c *C, path string, data []byte) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, data, 0644), IsNil)
+}
@@ -74,6 +74,9 @@ type RegistryConnectionRequest struct {
 	Prefix string
 	// Insecure indicates a plain http registry
 	Insecure bool
+	// Repository optionally specifies the repository to request
+	// pull access to when authenticating with a token
+	Repository string
 }
 
 // HasBasicAuth returns true if the request contains basic auth credentials.
@@ -406,22 +409,32 @@ func initTransport(req RegistryConnectionRequest) (http.RoundTripper, string, er
 		return nil, "", trace.Wrap(err, "failed to ping Docker registry: %s", err).AddField("req", req)
 	}
 
-	if !req.HasBasicAuth() {
+	if !req.HasBasicAuth() && req.Repository == "" {
 		return transport, registryAddress, nil
 	}
 
-	// If basic auth credentials were provided, set up the authorizer
-	// middleware for the transport.
+	// If basic auth credentials or the repository were provided, set up
+	// the authorizer middleware for the transport. Registries like
+	// Docker Hub require a token scoped to the repository even for
+	// anonymous pulls.
 	credentials := &credentials{
 		username: req.Username,
 		password: req.Password,
 		tokens:   make(map[string]string),
 	}
+	var scopes []registryauth.Scope
+	if req.Repository != "" {
+		scopes = append(scopes, registryauth.RepositoryScope{
+			Repository: req.Repository,
+			Actions:    []string{"pull"},
+		})
+	}
 	basicHandler := registryauth.NewBasicHandler(credentials)
 	tokenHandler := registryauth.NewTokenHandlerWithOptions(
 		registryauth.TokenHandlerOptions{
 			Transport:   transport,
 			Credentials: credentials,
+			Scopes:      scopes,
 		})
 	authorizer := registryauth.NewAuthorizer(challengeManager, tokenHandler, basicHandler)
 	return registrytransport.NewTransport(transport, authorizer), registryAddress, nil
@@ -0,0 +1,164 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/schema2"
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+)
+
+// LocalImage describes a tagged image stored in a local registry directory
+type LocalImage struct {
+	// Repository is the image repository, e.g. gravitational/debian-tall
+	Repository string
+	// Tag is the image tag
+	Tag string
+	// Digest is the digest of the image manifest
+	Digest digest.Digest
+	// Layers lists the image layers from the base layer up
+	Layers []distribution.Descriptor
+}
+
+// LocalRegistry provides read access to the images stored in a local
+// directory in docker registry 2.x format
+type LocalRegistry struct {
+	store *localStore
+}
+
+// OpenLocalRegistry opens the registry directory specified with dir
+func OpenLocalRegistry(dir string) (*LocalRegistry, error) {
+	store, err := openLocal(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &LocalRegistry{store: store}, nil
+}
+
+// Images returns all tagged images in the registry sorted by repository and tag
+func (r *LocalRegistry) Images(ctx context.Context) (images []LocalImage, err error) {
+	repos, err := ListRepos(ctx, r.store)
+	if err != nil && err != io.EOF {
+		return nil, trace.Wrap(err)
+	}
+	for _, name := range repos {
+		repo, err := r.store.Repository(ctx, name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tags, err := repo.Tags(ctx).All(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		manifests, err := repo.Manifests(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, tag := range tags {
+			desc, err := repo.Tags(ctx).Get(ctx, tag)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			manifest, err := manifests.Get(ctx, desc.Digest)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			image := LocalImage{
+				Repository: name,
+				Tag:        tag,
+				Digest:     desc.Digest,
+			}
+			// Only schema2 manifests are produced when vendoring so
+			// layers of other manifest kinds are not reported
+			if m, ok := manifest.(*schema2.DeserializedManifest); ok {
+				image.Layers = m.Layers
+			}
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		return images[i].Tag < images[j].Tag
+	})
+	return images, nil
+}
+
+// OpenLayer returns the uncompressed contents of the specified layer of
+// the image from the given repository.
+// Caller is responsible for closing the returned reader
+func (r *LocalRegistry) OpenLayer(ctx context.Context, repository string, layer distribution.Descriptor) (io.ReadCloser, error) {
+	repo, err := r.store.Repository(ctx, repository)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	blob, err := repo.Blobs(ctx).Open(ctx, layer.Digest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rc, err := dockerarchive.DecompressStream(blob)
+	if err != nil {
+		blob.Close()
+		return nil, trace.Wrap(err)
+	}
+	return &layerReader{ReadCloser: rc, blob: blob}, nil
+}
+
+// layerReader closes both the decompressed stream and the underlying blob
+type layerReader struct {
+	io.ReadCloser
+	blob io.Closer
+}
+
+// Close closes the decompressed stream and the underlying blob
+func (r *layerReader) Close() error {
+	return trace.NewAggregate(r.ReadCloser.Close(), r.blob.Close())
+}
+
+// ParseTagLink returns the image repository and tag if the specified path
+// relative to the registry directory is a tag link, e.g.
+// docker/registry/v2/repositories/<repository>/_manifests/tags/<tag>/current/link.
+// The contents of the link is the digest of the tagged image manifest
+func ParseTagLink(path string) (repository, tag string, ok bool) {
+	if !strings.HasPrefix(path, repositoriesDir) || !strings.HasSuffix(path, tagLinkSuffix) {
+		return "", "", false
+	}
+	path = strings.TrimSuffix(strings.TrimPrefix(path, repositoriesDir), tagLinkSuffix)
+	parts := strings.Split(path, tagsDir)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+const (
+	// repositoriesDir is the directory with image repositories
+	// relative to the registry directory
+	repositoriesDir = "docker/registry/v2/repositories/"
+	// tagsDir separates image repository from the tag in the tag link path
+	tagsDir = "/_manifests/tags/"
+	// tagLinkSuffix is the suffix of the tag link path
+	tagLinkSuffix = "/current/link"
+)
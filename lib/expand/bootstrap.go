@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/install"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/pack"
@@ -28,6 +29,7 @@ import (
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/system/environ"
+	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/gravitational/trace"
@@ -146,6 +148,53 @@ func (p *Peer) syncOperationPlan(ctx operationContext) error {
 	return nil
 }
 
+// syncOperationPlanState updates the state of the phases in the local copy of
+// the operation plan with the phases that have changed their state in the
+// cluster since, e.g. because they have been executed on another node.
+// Synchronizes the whole plan if there is no local copy yet
+func (p *Peer) syncOperationPlanState(ctx operationContext) error {
+	p.planMu.Lock()
+	defer p.planMu.Unlock()
+	plan, err := ctx.Operator.GetOperationPlan(ctx.Operation.Key())
+	if err != nil {
+		// the cluster might be temporarily unavailable while the joining
+		// node becomes an etcd member, carry on with the local state
+		p.WithError(err).Warn("Failed to query operation plan.")
+		return nil
+	}
+	localPlan, err := fsm.GetOperationPlan(p.JoinBackend, ctx.Operation.Key())
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if localPlan == nil {
+		return trace.Wrap(p.syncOperationPlan(ctx))
+	}
+	localPhases := make(map[string]*storage.OperationPhase)
+	for _, phase := range fsm.FlattenPlan(localPlan) {
+		localPhases[phase.ID] = phase
+	}
+	for _, phase := range fsm.FlattenPlan(plan) {
+		localPhase, ok := localPhases[phase.ID]
+		if !ok || phase.HasSubphases() || phase.State == localPhase.State ||
+			!phase.Updated.After(localPhase.Updated) {
+			continue
+		}
+		_, err := p.JoinBackend.CreateOperationPlanChange(storage.PlanChange{
+			ID:          uuid.New(),
+			ClusterName: ctx.Operation.SiteDomain,
+			OperationID: ctx.Operation.ID,
+			PhaseID:     phase.ID,
+			NewState:    phase.State,
+			Error:       phase.Error,
+			Created:     phase.Updated,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
 // syncOperation synchronizes operation-related data to the local join backend
 func (p *Peer) syncOperation(operator ops.Operator, cluster ops.Site, operationKey ops.SiteOperationKey) error {
 	// sync cluster
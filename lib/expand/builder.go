@@ -18,6 +18,9 @@ package expand
 
 import (
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
@@ -40,10 +43,9 @@ type planBuilder struct {
 	Runtime app.Application
 	// TeleportPackage is the teleport package to install
 	TeleportPackage loc.Locator
-	// PlanetPackage is the planet package to install
-	PlanetPackage loc.Locator
-	// JoiningNode is the node that's joining to the cluster
-	JoiningNode storage.Server
+	// JoiningNodes is the list of nodes that are joining to the cluster.
+	// The first node executes the operation plan
+	JoiningNodes storage.Servers
 	// ClusterNodes is the list of existing cluster nodes
 	ClusterNodes storage.Servers
 	// Peer is the IP:port of the cluster node this peer is joining to
@@ -62,121 +64,175 @@ type planBuilder struct {
 
 // AddInitPhase appends initialization phase to the plan.
 func (b *planBuilder) AddInitPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          installphases.InitPhase,
-		Description: "Initialize operation on the joining node",
-		Data: &storage.OperationPhaseData{
-			Server:  &b.JoiningNode,
-			Master:  &b.Master,
-			Package: &b.Application.Package,
-		},
+		Description: "Initialize operation on the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          installphases.InitPhase,
+			Description: "Initialize operation on the joining node",
+			Data: &storage.OperationPhaseData{
+				Server:     node,
+				ExecServer: node,
+				Master:     &b.Master,
+				Package:    &b.Application.Package,
+			},
+		}
 	})
 }
 
 // AddChecksPhase appends preflight checks phase to the plan.
 func (b *planBuilder) AddChecksPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          ChecksPhase,
-		Description: "Execute preflight checks on the joining node",
-		Data: &storage.OperationPhaseData{
-			Server: &b.JoiningNode,
-			Master: &b.Master,
-		},
-		Requires: []string{installphases.InitPhase, StartAgentPhase},
+		Description: "Execute preflight checks on the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          ChecksPhase,
+			Description: "Execute preflight checks on the joining node",
+			Data: &storage.OperationPhaseData{
+				Server: node,
+				Master: &b.Master,
+			},
+			Requires: []string{installphases.InitPhase, StartAgentPhase},
+		}
 	})
 }
 
 // AddConfigurePhase appends package configuration phase to the plan
 func (b *planBuilder) AddConfigurePhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          installphases.ConfigurePhase,
-		Description: "Configure packages for the joining node",
-		Data: &storage.OperationPhaseData{
-			ExecServer: &b.JoiningNode,
-		},
-		Requires: []string{ChecksPhase},
+		Description: "Configure packages for the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          installphases.ConfigurePhase,
+			Description: "Configure packages for the joining node",
+			Data: &storage.OperationPhaseData{
+				ExecServer: node,
+			},
+			Requires: []string{ChecksPhase},
+		}
 	})
 }
 
 // AddBootstrapPhase appends local node bootstrap phase to the plan
 func (b *planBuilder) AddBootstrapPhase(plan *storage.OperationPlan) {
-	agent := &b.AdminAgent
-	if !b.JoiningNode.IsMaster() {
-		agent = &b.RegularAgent
-	}
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          installphases.BootstrapPhase,
-		Description: "Bootstrap the joining node",
-		Data: &storage.OperationPhaseData{
-			Server:      &b.JoiningNode,
-			ExecServer:  &b.JoiningNode,
-			Package:     &b.Application.Package,
-			Agent:       agent,
-			ServiceUser: &b.ServiceUser,
-		},
+		Description: "Bootstrap the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		agent := &b.AdminAgent
+		if !node.IsMaster() {
+			agent = &b.RegularAgent
+		}
+		phase := storage.OperationPhase{
+			ID:          installphases.BootstrapPhase,
+			Description: "Bootstrap the joining node",
+			Data: &storage.OperationPhaseData{
+				Server:      node,
+				ExecServer:  node,
+				Package:     &b.Application.Package,
+				Agent:       agent,
+				ServiceUser: &b.ServiceUser,
+			},
+		}
+		if len(b.JoiningNodes) > 1 {
+			// with a single joining node, bootstrap follows the configure phase
+			phase.Requires = []string{ChecksPhase}
+		}
+		return phase
 	})
 }
 
 // AddPullPhase appends package pull phase to the plan
 func (b *planBuilder) AddPullPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          installphases.PullPhase,
-		Description: "Pull packages on the joining node",
-		Data: &storage.OperationPhaseData{
-			Server:      &b.JoiningNode,
-			ExecServer:  &b.JoiningNode,
-			Package:     &b.Application.Package,
-			ServiceUser: &b.ServiceUser,
-		},
-		Requires: []string{installphases.ConfigurePhase, installphases.BootstrapPhase},
+		Description: "Pull packages on the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          installphases.PullPhase,
+			Description: "Pull packages on the joining node",
+			Data: &storage.OperationPhaseData{
+				Server:      node,
+				ExecServer:  node,
+				Package:     &b.Application.Package,
+				ServiceUser: &b.ServiceUser,
+			},
+			Requires: []string{installphases.ConfigurePhase, installphases.BootstrapPhase},
+		}
 	})
 }
 
 // AddPreHookPhase appends pre-expand hook phase to the plan
 func (b *planBuilder) AddPreHookPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          PreHookPhase,
-		Description: fmt.Sprintf("Execute the application's %v hook", schema.HookNodeAdding),
-		Data: &storage.OperationPhaseData{
-			ExecServer:  &b.JoiningNode,
-			Package:     &b.Application.Package,
-			ServiceUser: &b.ServiceUser,
-		},
-		Requires: []string{installphases.PullPhase},
+		Description: fmt.Sprintf("Execute the application's %v hook for the joining nodes", schema.HookNodeAdding),
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          PreHookPhase,
+			Description: fmt.Sprintf("Execute the application's %v hook", schema.HookNodeAdding),
+			Data: &storage.OperationPhaseData{
+				ExecServer:  node,
+				Package:     &b.Application.Package,
+				ServiceUser: &b.ServiceUser,
+			},
+			Requires: []string{installphases.PullPhase},
+		}
 	})
 }
 
-// AddSystemPhase appends teleport/planet installation phase to the plan
-func (b *planBuilder) AddSystemPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+// AddSystemPhase appends teleport/planet installation phase to the plan.
+// Teleport and planet packages do not depend on each other and can be
+// installed concurrently
+func (b *planBuilder) AddSystemPhase(plan *storage.OperationPlan) error {
+	planetPackages := make(map[string]loc.Locator)
+	for _, node := range b.JoiningNodes {
+		planetPackage, err := b.Application.Manifest.RuntimePackageForProfile(node.Role)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		planetPackages[node.Role] = *planetPackage
+	}
+	requires := fsm.RequireIfPresent(plan, installphases.PullPhase, PreHookPhase)
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          SystemPhase,
-		Description: "Install system software on the joining node",
-		Phases: []storage.OperationPhase{
-			{
-				ID: fmt.Sprintf("%v/teleport", SystemPhase),
-				Description: fmt.Sprintf("Install system package %v:%v",
-					b.TeleportPackage.Name, b.TeleportPackage.Version),
-				Data: &storage.OperationPhaseData{
-					Server:     &b.JoiningNode,
-					ExecServer: &b.JoiningNode,
-					Package:    &b.TeleportPackage,
+		Description: "Install system software on the joining nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		planetPackage := planetPackages[node.Role]
+		return storage.OperationPhase{
+			ID:          SystemPhase,
+			Description: "Install system software on the joining node",
+			Phases: []storage.OperationPhase{
+				{
+					ID: fmt.Sprintf("%v/teleport", SystemPhase),
+					Description: fmt.Sprintf("Install system package %v:%v",
+						b.TeleportPackage.Name, b.TeleportPackage.Version),
+					Data: &storage.OperationPhaseData{
+						Server:     node,
+						ExecServer: node,
+						Package:    &b.TeleportPackage,
+					},
+					Requires: requires,
 				},
-				Requires: []string{installphases.PullPhase},
-			},
-			{
-				ID: fmt.Sprintf("%v/planet", SystemPhase),
-				Description: fmt.Sprintf("Install system package %v:%v",
-					b.PlanetPackage.Name, b.PlanetPackage.Version),
-				Data: &storage.OperationPhaseData{
-					Server:     &b.JoiningNode,
-					ExecServer: &b.JoiningNode,
-					Package:    &b.PlanetPackage,
-					Labels:     pack.RuntimePackageLabels,
+				{
+					ID: fmt.Sprintf("%v/planet", SystemPhase),
+					Description: fmt.Sprintf("Install system package %v:%v",
+						planetPackage.Name, planetPackage.Version),
+					Data: &storage.OperationPhaseData{
+						Server:     node,
+						ExecServer: node,
+						Package:    &planetPackage,
+						Labels:     pack.RuntimePackageLabels,
+					},
+					Requires: requires,
 				},
-				Requires: []string{installphases.PullPhase},
 			},
-		},
+		}
 	})
+	return nil
 }
 
 // AddStartAgentPhase appends phase that starts agent on a master node
@@ -186,7 +242,7 @@ func (b *planBuilder) AddStartAgentPhase(plan *storage.OperationPlan) {
 		Description: fmt.Sprintf("Start RPC agent on the master node %v",
 			b.Master.AdvertiseIP),
 		Data: &storage.OperationPhaseData{
-			ExecServer: &b.JoiningNode,
+			ExecServer: &b.JoiningNodes[0],
 			Server:     &b.Master,
 			Agent: &storage.LoginEntry{
 				Email:        b.AdminAgent.Email,
@@ -205,51 +261,93 @@ func (b *planBuilder) AddEtcdBackupPhase(plan *storage.OperationPlan) {
 			b.Master.AdvertiseIP),
 		Data: &storage.OperationPhaseData{
 			Server:     &b.Master,
-			ExecServer: &b.JoiningNode,
+			ExecServer: &b.JoiningNodes[0],
 		},
 		Requires: []string{StartAgentPhase},
 	})
 }
 
-// AddEtcdPhase appends etcd member addition phase to the plan
+// AddEtcdPhase appends etcd member addition phase to the plan.
+//
+// Joining master nodes are added to the etcd cluster one at a time: the etcd
+// phase of each master also depends on the etcd phase of the preceding master
 func (b *planBuilder) AddEtcdPhase(plan *storage.OperationPlan) {
+	if len(b.JoiningNodes) == 1 {
+		plan.Phases = append(plan.Phases, storage.OperationPhase{
+			ID:          EtcdPhase,
+			Description: "Add the joining node to the etcd cluster",
+			Data: &storage.OperationPhaseData{
+				Server:     &b.JoiningNodes[0],
+				ExecServer: &b.JoiningNodes[0],
+				Master:     &b.Master,
+			},
+			Requires: fsm.RequireIfPresent(plan, SystemPhase, EtcdBackupPhase),
+		})
+		return
+	}
+	masters := b.JoiningNodes.Masters()
+	var phases []storage.OperationPhase
+	for i, master := range masters {
+		requires := append([]string{nodePhaseID(SystemPhase, master.Hostname)},
+			fsm.RequireIfPresent(plan, EtcdBackupPhase)...)
+		if i > 0 {
+			requires = append(requires, phases[i-1].ID)
+		}
+		phases = append(phases, storage.OperationPhase{
+			ID: nodePhaseID(EtcdPhase, master.Hostname),
+			Description: fmt.Sprintf("Add the joining node %v to the etcd cluster",
+				master.Hostname),
+			Data: &storage.OperationPhaseData{
+				Server:     &masters[i],
+				ExecServer: &masters[i],
+				Master:     &b.Master,
+			},
+			Requires: requires,
+		})
+	}
 	plan.Phases = append(plan.Phases, storage.OperationPhase{
 		ID:          EtcdPhase,
-		Description: "Add the joining node to the etcd cluster",
-		Data: &storage.OperationPhaseData{
-			Server:     &b.JoiningNode,
-			ExecServer: &b.JoiningNode,
-			Master:     &b.Master,
-		},
-		Requires: fsm.RequireIfPresent(plan, SystemPhase, EtcdBackupPhase),
+		Description: "Add the joining master nodes to the etcd cluster",
+		Phases:      phases,
+		Requires:    fsm.RequireIfPresent(plan, StartAgentPhase),
 	})
 }
 
 // AddWaitPhase appends planet startup wait phase to the plan
 func (b *planBuilder) AddWaitPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          installphases.WaitPhase,
-		Description: "Wait for the node to join the cluster",
-		Phases: []storage.OperationPhase{
-			{
-				ID:          WaitPlanetPhase,
-				Description: "Wait for the planet to start",
-				Data: &storage.OperationPhaseData{
-					Server:     &b.JoiningNode,
-					ExecServer: &b.JoiningNode,
+		Description: "Wait for the nodes to join the cluster",
+	}, func(node *storage.Server) storage.OperationPhase {
+		// only the joining masters need to become etcd members first
+		requires := fsm.RequireIfPresent(plan, SystemPhase)
+		if node.IsMaster() {
+			requires = fsm.RequireIfPresent(plan, SystemPhase, EtcdPhase)
+		}
+		return storage.OperationPhase{
+			ID:          installphases.WaitPhase,
+			Description: "Wait for the node to join the cluster",
+			Phases: []storage.OperationPhase{
+				{
+					ID:          WaitPlanetPhase,
+					Description: "Wait for the planet to start",
+					Data: &storage.OperationPhaseData{
+						Server:     node,
+						ExecServer: node,
+					},
+					Requires: requires,
 				},
-				Requires: fsm.RequireIfPresent(plan, SystemPhase, EtcdPhase),
-			},
-			{
-				ID:          WaitK8sPhase,
-				Description: "Wait for the node to join Kubernetes cluster",
-				Data: &storage.OperationPhaseData{
-					Server:     &b.JoiningNode,
-					ExecServer: &b.JoiningNode,
+				{
+					ID:          WaitK8sPhase,
+					Description: "Wait for the node to join Kubernetes cluster",
+					Data: &storage.OperationPhaseData{
+						Server:     node,
+						ExecServer: node,
+					},
+					Requires: []string{WaitPlanetPhase},
 				},
-				Requires: []string{WaitPlanetPhase},
 			},
-		},
+		}
 	})
 }
 
@@ -260,7 +358,7 @@ func (b *planBuilder) AddStopAgentPhase(plan *storage.OperationPlan) {
 		Description: fmt.Sprintf("Stop RPC agent on the master node %v",
 			b.Master.AdvertiseIP),
 		Data: &storage.OperationPhaseData{
-			ExecServer: &b.JoiningNode,
+			ExecServer: &b.JoiningNodes[0],
 			Server:     &b.Master,
 		},
 		Requires: []string{installphases.WaitPhase},
@@ -269,33 +367,113 @@ func (b *planBuilder) AddStopAgentPhase(plan *storage.OperationPlan) {
 
 // AddPostHookPhase appends post-expand hook phase to the plan
 func (b *planBuilder) AddPostHookPhase(plan *storage.OperationPlan) {
-	plan.Phases = append(plan.Phases, storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          PostHookPhase,
-		Description: fmt.Sprintf("Execute the application's %v hook", schema.HookNodeAdded),
-		Data: &storage.OperationPhaseData{
-			ExecServer:  &b.JoiningNode,
-			Package:     &b.Application.Package,
-			ServiceUser: &b.ServiceUser,
-		},
-		Requires: []string{installphases.WaitPhase},
+		Description: fmt.Sprintf("Execute the application's %v hook for the joined nodes", schema.HookNodeAdded),
+	}, func(node *storage.Server) storage.OperationPhase {
+		return storage.OperationPhase{
+			ID:          PostHookPhase,
+			Description: fmt.Sprintf("Execute the application's %v hook", schema.HookNodeAdded),
+			Data: &storage.OperationPhaseData{
+				ExecServer:  node,
+				Package:     &b.Application.Package,
+				ServiceUser: &b.ServiceUser,
+			},
+			Requires: []string{installphases.WaitPhase},
+		}
 	})
 }
 
 // AddElectPhase appends phase that enables leader election to the plan
 func (b *planBuilder) AddElectPhase(plan *storage.OperationPlan) {
-	phase := storage.OperationPhase{
+	b.addNodePhases(plan, storage.OperationPhase{
 		ID:          ElectPhase,
-		Description: "Enable leader election on the joined node",
-		Data: &storage.OperationPhaseData{
-			Server:     &b.JoiningNode,
-			ExecServer: &b.JoiningNode,
-		},
-		Requires: []string{installphases.WaitPhase},
+		Description: "Enable or disable leader election on the joined nodes",
+	}, func(node *storage.Server) storage.OperationPhase {
+		phase := storage.OperationPhase{
+			ID:          ElectPhase,
+			Description: "Enable leader election on the joined node",
+			Data: &storage.OperationPhaseData{
+				Server:     node,
+				ExecServer: node,
+			},
+			Requires: []string{installphases.WaitPhase},
+		}
+		if !node.IsMaster() {
+			phase.Description = "Disable leader election on the joined node"
+		}
+		return phase
+	})
+}
+
+// addNodePhases appends the phase returned by newPhase for each joining node
+// to the plan.
+//
+// The phase of a single joining node is added as is. The phases of several
+// joining nodes are executed in parallel as sub-phases of the specified parent
+// phase: the phase of each node is named after the node and depends on the
+// phases of the same node, so the nodes do not wait for each other.
+// Instead of the preceding phase, the parent phase depends on the RPC agent
+// started on the master node
+func (b *planBuilder) addNodePhases(plan *storage.OperationPlan, parent storage.OperationPhase, newPhase func(*storage.Server) storage.OperationPhase) {
+	if len(b.JoiningNodes) == 1 {
+		plan.Phases = append(plan.Phases, newPhase(&b.JoiningNodes[0]))
+		return
 	}
-	if !b.JoiningNode.IsMaster() {
-		phase.Description = "Disable leader election on the joined node"
+	for i := range b.JoiningNodes {
+		node := &b.JoiningNodes[i]
+		parent.Phases = append(parent.Phases, nodePhase(plan, newPhase(node), node.Hostname))
 	}
-	plan.Phases = append(plan.Phases, phase)
+	parent.Requires = fsm.RequireIfPresent(plan, StartAgentPhase)
+	parent.Parallel = true
+	plan.Phases = append(plan.Phases, parent)
+}
+
+// nodePhase returns the provided phase of the joining node with the specified
+// hostname renamed after the node.
+// Requirements that refer to the phases of the same node are renamed as well
+// while requirements on phases that are not part of the plan are dropped
+func nodePhase(plan *storage.OperationPlan, phase storage.OperationPhase, hostname string) storage.OperationPhase {
+	ids := make(map[string]bool)
+	for _, phase := range fsm.FlattenPlan(plan) {
+		ids[phase.ID] = true
+	}
+	var collectIDs func(storage.OperationPhase)
+	collectIDs = func(phase storage.OperationPhase) {
+		ids[nodePhaseID(phase.ID, hostname)] = true
+		for _, subphase := range phase.Phases {
+			collectIDs(subphase)
+		}
+	}
+	collectIDs(phase)
+	return renameNodePhase(phase, hostname, ids)
+}
+
+func renameNodePhase(phase storage.OperationPhase, hostname string, ids map[string]bool) storage.OperationPhase {
+	phase.ID = nodePhaseID(phase.ID, hostname)
+	phase.Description = fmt.Sprintf("%v (%v)", phase.Description, hostname)
+	var requires []string
+	for _, id := range phase.Requires {
+		if ids[nodePhaseID(id, hostname)] {
+			requires = append(requires, nodePhaseID(id, hostname))
+		} else if ids[id] {
+			requires = append(requires, id)
+		}
+	}
+	phase.Requires = requires
+	var phases []storage.OperationPhase
+	for _, subphase := range phase.Phases {
+		phases = append(phases, renameNodePhase(subphase, hostname, ids))
+	}
+	phase.Phases = phases
+	return phase
+}
+
+// nodePhaseID returns the ID of the specified phase of the joining node with
+// the given hostname, e.g. /system/node-1/planet for /system/planet
+func nodePhaseID(id, hostname string) string {
+	parts := strings.SplitN(strings.TrimPrefix(id, "/"), "/", 2)
+	return path.Join(append([]string{"/", parts[0], hostname}, parts[1:]...)...)
 }
 
 func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
@@ -317,10 +495,6 @@ func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	planetPackage, err := application.Manifest.RuntimePackageForProfile(p.Role)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
 	adminAgent, err := ctx.Operator.GetClusterAgent(ops.ClusterAgentRequest{
 		AccountID:   ctx.Operation.AccountID,
 		ClusterName: ctx.Operation.SiteDomain,
@@ -348,8 +522,7 @@ func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
 		Application:     *application,
 		Runtime:         *runtime,
 		TeleportPackage: *teleportPackage,
-		PlanetPackage:   *planetPackage,
-		JoiningNode:     operation.Servers[0],
+		JoiningNodes:    joiningNodes(operation.Servers),
 		ClusterNodes:    storage.Servers(ctx.Cluster.ClusterState.Servers),
 		Peer:            ctx.Peer,
 		Master:          storage.Servers(ctx.Cluster.ClusterState.Servers).Masters()[0],
@@ -360,6 +533,17 @@ func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
 	}, nil
 }
 
+// joiningNodes returns the provided servers joining with an expand operation
+// ordered by advertise address, so the node that executes the operation plan
+// comes first
+func joiningNodes(servers storage.Servers) storage.Servers {
+	nodes := append(storage.Servers{}, servers...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].AdvertiseIP < nodes[j].AdvertiseIP
+	})
+	return nodes
+}
+
 // fillSteps sets each phase's step number to its index number in the plan
 func fillSteps(plan *storage.OperationPlan) {
 	for i, phase := range fsm.FlattenPlan(plan) {
@@ -64,6 +64,9 @@ type FSMConfig struct {
 	DebugMode bool
 	// Insecure turns on FSM insecure mode
 	Insecure bool
+	// Parallel is the maximum number of phases to execute concurrently.
+	// If unspecified, phases are executed sequentially in plan order
+	Parallel int
 }
 
 // CheckAndSetDefaults validates expand FSM configuration and sets defaults
@@ -117,9 +120,10 @@ func NewFSM(config FSMConfig) (*fsm.FSM, error) {
 		FieldLogger: logger,
 	}
 	fsm, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
-		Logger: logger,
+		Engine:   engine,
+		Runner:   config.Runner,
+		Logger:   logger,
+		Parallel: config.Parallel,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -17,6 +17,7 @@ limitations under the License.
 package expand
 
 import (
+	"path"
 	"strings"
 
 	"github.com/gravitational/gravity/lib/expand/phases"
@@ -92,11 +93,11 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 				config.Operator,
 				remote)
 
-		case strings.HasPrefix(p.Phase.ID, WaitPlanetPhase):
+		case isWaitPhase(p.Phase.ID, WaitPlanetPhase):
 			return phases.NewWaitPlanet(p,
 				config.Operator)
 
-		case strings.HasPrefix(p.Phase.ID, WaitK8sPhase):
+		case isWaitPhase(p.Phase.ID, WaitK8sPhase):
 			return phases.NewWaitK8s(p,
 				config.Operator)
 
@@ -116,6 +117,14 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 	}
 }
 
+// isWaitPhase returns true if the phase with the specified ID is the given
+// wait phase, either of a single joining node (e.g. /wait/planet) or
+// of one of several joining nodes (e.g. /wait/node-1/planet)
+func isWaitPhase(id, waitPhase string) bool {
+	return strings.HasPrefix(id, installphases.WaitPhase+"/") &&
+		path.Base(id) == path.Base(waitPhase)
+}
+
 const (
 	// ChecksPhase runs preflight checks on the joining node
 	ChecksPhase = "/checks"
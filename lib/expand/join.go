@@ -115,6 +115,8 @@ type Peer struct {
 	execDoneC chan install.ExecResult
 	// wg is a wait group used to ensure completion of internal processes
 	wg sync.WaitGroup
+	// planMu serializes updates to the local copy of the operation plan
+	planMu sync.Mutex
 }
 
 // Run runs the peer operation
@@ -209,6 +211,21 @@ func (p *Peer) SetPhase(req *installpb.SetStateRequest) error {
 	})
 }
 
+// DryRun describes the changes the phase given with req would make if executed.
+// Implements server.DryRunner
+func (p *Peer) DryRun(req *installpb.DryRunRequest) ([]storage.PhaseDryRun, error) {
+	p.WithField("req", req).Info("Dry-run.")
+	ctx, err := p.tryConnect(req.OperationKey().OperationID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	machine, err := p.getFSM(*ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return machine.DryRunPhase(p.ctx, req.Phase.ID)
+}
+
 // Complete manually completes the operation given with opKey.
 // Implements server.Executor
 func (p *Peer) Complete(opKey ops.SiteOperationKey) error {
@@ -273,6 +290,8 @@ type PeerConfig struct {
 	DebugMode bool
 	// Insecure turns on FSM insecure mode
 	Insecure bool
+	// Parallel is the maximum number of join plan phases to execute concurrently
+	Parallel int
 	// LocalBackend is local backend of the joining node
 	LocalBackend storage.Backend
 	// LocalApps is local apps service of the joining node
@@ -284,6 +303,7 @@ type PeerConfig struct {
 	// JoinBackend is the local backend where join-specific operation data is stored
 	JoinBackend storage.Backend
 	// OperationID is the ID of existing join operation created via UI
+	// or with gravity expand
 	OperationID string
 	// StateDir defines where peer will store operation-specific data
 	StateDir string
@@ -528,7 +548,7 @@ func (p *Peer) resumeExpandOperation(ctx context.Context, opCtx operationContext
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = p.ensureExpandOperationState(opCtx)
+	_, err = p.ensureExpandOperationState(opCtx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -536,6 +556,15 @@ func (p *Peer) resumeExpandOperation(ctx context.Context, opCtx operationContext
 }
 
 func (p *Peer) executeSinglePhase(ctx context.Context, opCtx operationContext, phase installpb.Phase, disp dispatcher.EventDispatcher) error {
+	if opCtx.isExpand() {
+		// phases of an expand operation that adds several nodes are executed
+		// on multiple nodes, so pick up the state of the phases this phase
+		// might depend on
+		err := p.syncOperationPlanState(opCtx)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	machine, err := p.getFSM(opCtx)
 	if err != nil {
 		return trace.Wrap(err)
@@ -844,7 +873,16 @@ func (p *Peer) run(ctx operationContext) (dispatcher.Status, error) {
 	if ctx.Operation.Type == ops.OperationInstall {
 		return p.agentLoop(ctx)
 	}
-	err := p.executeExpandOperation(ctx)
+	driver, err := p.ensureExpandOperationState(ctx)
+	if err != nil {
+		return dispatcher.StatusUnknown, trace.Wrap(err)
+	}
+	if !driver {
+		// the plan is executed by another joining node, this node only
+		// executes the phases it is requested to
+		return p.agentLoop(ctx)
+	}
+	err = p.executeExpandOperation(ctx)
 	if err != nil {
 		return dispatcher.StatusUnknown, trace.Wrap(err)
 	}
@@ -858,11 +896,7 @@ func (p *Peer) agentLoop(ctx operationContext) (dispatcher.Status, error) {
 }
 
 func (p *Peer) executeExpandOperation(ctx operationContext) error {
-	err := p.ensureExpandOperationState(ctx)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	err = p.emitAuditEvent(ctx)
+	err := p.emitAuditEvent(ctx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -883,24 +917,60 @@ func (p *Peer) executeExpandOperation(ctx operationContext) error {
 	return trace.Wrap(fsmErr)
 }
 
-func (p *Peer) ensureExpandOperationState(ctx operationContext) error {
-	err := p.waitForOperation(ctx.Operator, ctx.Operation)
+// ensureExpandOperationState waits for all nodes to join the expand operation
+// and makes sure the operation plan has been created.
+// Returns true if this node is the one that executes the operation plan
+func (p *Peer) ensureExpandOperationState(ctx operationContext) (driver bool, err error) {
+	err = p.waitForOperation(ctx.Operator, ctx.Operation)
 	if err != nil {
-		return trace.Wrap(err)
+		return false, trace.Wrap(err)
 	}
-	err = p.waitForAgents(ctx.Operator, ctx.Operation)
+	driver, err = p.waitForAgents(ctx.Operator, ctx.Operation)
 	if err != nil {
-		return trace.Wrap(err)
+		return false, trace.Wrap(err)
 	}
-	err = p.initOperationPlan(ctx)
-	if err != nil && !trace.IsAlreadyExists(err) {
-		return trace.Wrap(err)
+	if driver {
+		err = p.initOperationPlan(ctx)
+		if err != nil && !trace.IsAlreadyExists(err) {
+			return false, trace.Wrap(err)
+		}
+	} else {
+		err = p.waitForOperationPlan(ctx.Operator, ctx.Operation)
+		if err != nil {
+			return false, trace.Wrap(err)
+		}
 	}
+	p.planMu.Lock()
+	defer p.planMu.Unlock()
 	err = p.syncOperationPlan(ctx)
 	if err != nil {
-		return trace.Wrap(err)
+		return false, trace.Wrap(err)
+	}
+	return driver, nil
+}
+
+// waitForOperationPlan blocks until the plan of the expand operation
+// has been created by the node that executes it
+func (p *Peer) waitForOperationPlan(operator ops.Operator, operation ops.SiteOperation) error {
+	ticker := backoff.NewTicker(backoff.NewConstantBackOff(1 * time.Second))
+	defer ticker.Stop()
+	log := p.WithField(constants.FieldOperationID, operation.ID)
+	log.Debug("Waiting for the operation plan.")
+	for {
+		select {
+		case <-ticker.C:
+			_, err := operator.GetOperationPlan(operation.Key())
+			if err == nil {
+				return nil
+			}
+			if !trace.IsNotFound(err) {
+				return trace.Wrap(err)
+			}
+			log.Debug("Operation plan has not been created yet.")
+		case <-p.ctx.Done():
+			return trace.Wrap(p.ctx.Err())
+		}
 	}
-	return nil
 }
 
 // createExpandOperation creates a new expand operation
@@ -928,6 +998,7 @@ func (p *Peer) createExpandOperation(operator ops.Operator, cluster ops.Site) (*
 }
 
 // getExpandOperation returns existing expand operation created via UI
+// or with gravity expand
 func (p *Peer) getExpandOperation(operator ops.Operator, cluster ops.Site, operationID string) (*ops.SiteOperation, error) {
 	operation, err := operator.GetSiteOperation(ops.SiteOperationKey{
 		AccountID:   cluster.AccountID,
@@ -984,11 +1055,16 @@ func (p *Peer) createShrinkOperation(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	server := storage.Servers(operation.Servers).FindByIP(p.AdvertiseAddr)
+	if server == nil {
+		return trace.NotFound("node %v is not part of the operation %v",
+			p.AdvertiseAddr, operation.ID)
+	}
 	_, err = opCtx.Operator.CreateSiteShrinkOperation(ctx,
 		ops.CreateSiteShrinkOperationRequest{
 			AccountID:  opCtx.Cluster.AccountID,
 			SiteDomain: opCtx.Cluster.Domain,
-			Servers:    []string{operation.Servers[0].Hostname},
+			Servers:    []string{server.Hostname},
 			Force:      true,
 			// Have cluster avoid triggering remote node cleanup
 			NodeRemoved: true,
@@ -997,7 +1073,11 @@ func (p *Peer) createShrinkOperation(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
-func (p *Peer) waitForAgents(operator ops.Operator, operation ops.SiteOperation) error {
+// waitForAgents blocks until the agents of all nodes the expand operation
+// adds have joined. The node with the lowest advertise address executes
+// the operation plan: it updates the operation with the joined servers.
+// Returns true if this node executes the operation plan
+func (p *Peer) waitForAgents(operator ops.Operator, operation ops.SiteOperation) (driver bool, err error) {
 	ticker := backoff.NewTicker(&backoff.ExponentialBackOff{
 		InitialInterval: time.Second,
 		Multiplier:      1.5,
@@ -1007,28 +1087,30 @@ func (p *Peer) waitForAgents(operator ops.Operator, operation ops.SiteOperation)
 	})
 	defer ticker.Stop()
 	log := p.WithField(constants.FieldOperationID, operation.ID)
-	log.Debug("Waiting for agent to join.")
+	expected := expectedServers(operation)
+	log.WithField("servers", expected).Debug("Waiting for agents to join.")
 	for {
 		select {
 		case tm := <-ticker.C:
 			if tm.IsZero() {
-				return trace.ConnectionProblem(nil, "timed out waiting for agents to join")
+				return false, trace.ConnectionProblem(nil, "timed out waiting for agents to join")
 			}
 			report, err := operator.GetSiteExpandOperationAgentReport(operation.Key())
 			if err != nil {
 				log.WithError(err).Warn("Failed to query agent report.")
 				continue
 			}
-			if len(report.Servers) == 0 {
-				log.Debug("Agent hasn't joined yet.")
+			if len(report.Servers) < expected {
+				log.WithField("joined", len(report.Servers)).Debug("Not all agents have joined yet.")
 				continue
 			}
+			driver := isDriver(p.AdvertiseAddr, report.Servers)
 			op, err := operator.GetSiteOperation(operation.Key())
 			if err != nil {
 				log.WithError(err).Warn("Failed to query operation.")
 				continue
 			}
-			if shouldUpdateExpandOperationState(op.State) {
+			if driver && shouldUpdateExpandOperationState(op.State) {
 				req, err := install.GetServerUpdateRequest(*op, report.Servers)
 				if err != nil {
 					log.WithError(err).Warn("Failed to create server update request.")
@@ -1036,15 +1118,43 @@ func (p *Peer) waitForAgents(operator ops.Operator, operation ops.SiteOperation)
 				}
 				err = operator.UpdateExpandOperationState(operation.Key(), *req)
 				if err != nil {
-					return trace.Wrap(err)
+					return false, trace.Wrap(err)
 				}
 			}
 			log.WithField("report", report).Debug("Installation can proceed.")
-			return nil
+			return driver, nil
 		case <-p.ctx.Done():
-			return trace.Wrap(p.ctx.Err())
+			return false, trace.Wrap(p.ctx.Err())
+		}
+	}
+}
+
+// expectedServers returns the number of nodes the specified expand
+// operation adds to the cluster
+func expectedServers(operation ops.SiteOperation) (count int) {
+	if operation.InstallExpand != nil {
+		for _, profile := range operation.InstallExpand.Profiles {
+			count += profile.Request.Count
+		}
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// isDriver returns true if the node with the specified advertise address
+// is the one that executes the plan of the expand operation joined by
+// the given agents
+func isDriver(addr string, agents []checks.ServerInfo) bool {
+	ip, _ := utils.SplitHostPort(addr, "")
+	for _, agent := range agents {
+		agentIP, _ := utils.SplitHostPort(agent.AdvertiseAddr, "")
+		if agentIP < ip {
+			return false
 		}
 	}
+	return true
 }
 
 // emitAuditEvent sends expand operation start event to the cluster audit log.
@@ -1105,6 +1215,7 @@ func (p *Peer) newJoinFSM(ctx operationContext) (*fsm.FSM, error) {
 		Credentials:   ctx.Creds.Client,
 		DebugMode:     p.DebugMode,
 		Insecure:      p.Insecure,
+		Parallel:      p.Parallel,
 	})
 }
 
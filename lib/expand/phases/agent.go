@@ -92,6 +92,12 @@ func (p *agentStartExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the RPC agent deployed on the master node
+func (p *agentStartExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("deploy RPC agent on master node %v", p.Master.AdvertiseIP), nil
+}
+
 func (p *agentStartExecutor) getProxyClient(ctx context.Context) (*client.ProxyClient, error) {
 	operator, err := opsclient.NewBearerClient(p.Phase.Data.Agent.OpsCenterURL,
 		p.Phase.Data.Agent.Password, opsclient.HTTPClient(httplib.GetClient(true)))
@@ -166,6 +172,12 @@ func (p *agentStopExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the RPC agent stopped on the master node
+func (p *agentStopExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("stop RPC agent on master node %v", p.Master.AdvertiseIP), nil
+}
+
 // Rollback is no-op for this phase
 func (*agentStopExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -97,6 +97,11 @@ func (p *checksExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only runs the preflight checks.
+func (p *checksExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 // Rollback is no-op for this phase.
 func (*checksExecutor) Rollback(context.Context) error { return nil }
 
@@ -67,6 +67,15 @@ func (p *electExecutor) Execute(ctx context.Context) (err error) {
 	return nil
 }
 
+// DryRun describes the command that resets leader election on the joined node
+func (p *electExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	if p.Phase.Data.Server.IsMaster() {
+		return report.AddCommand("%v", ops.EnableLeaderElectionCommand(p.Plan.ClusterName, *p.Phase.Data.Server)), nil
+	}
+	return report.AddCommand("%v", ops.PauseLeaderElectionCommand(p.Plan.ClusterName, *p.Phase.Data.Server)), nil
+}
+
 // Rollback is no-op for this phase
 func (*electExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/clients"
 	"github.com/gravitational/gravity/lib/constants"
@@ -57,7 +58,7 @@ func NewEtcd(p fsm.ExecutorParams, operator ops.Operator, runner rpc.AgentReposi
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	etcdClient, err := clients.EtcdMembers(&clients.EtcdConfig{
+	etcdClient, err := clients.Etcd(&clients.EtcdConfig{
 		Endpoints:  endpoints,
 		SecretsDir: state.SecretDir(stateDir),
 	})
@@ -74,7 +75,8 @@ func NewEtcd(p fsm.ExecutorParams, operator ops.Operator, runner rpc.AgentReposi
 	}
 	return &etcdExecutor{
 		FieldLogger:    logger,
-		Etcd:           etcdClient,
+		Etcd:           etcd.NewMembersAPI(etcdClient),
+		Keys:           etcd.NewKeysAPI(etcdClient),
 		Runner:         runner,
 		Master:         *p.Phase.Data.Master,
 		ExecutorParams: p,
@@ -86,6 +88,8 @@ type etcdExecutor struct {
 	logrus.FieldLogger
 	// Etcd is client to the cluster's etcd members API
 	Etcd etcd.MembersAPI
+	// Keys is client to the cluster's etcd keys API
+	Keys etcd.KeysAPI
 	// Runner is used to run remote commands
 	Runner rpc.AgentRepository
 	// Master is one of the master nodes
@@ -94,17 +98,123 @@ type etcdExecutor struct {
 	fsm.ExecutorParams
 }
 
-// Execute adds the joining node to the cluster's etcd cluster
+// Execute adds the joining node to the cluster's etcd cluster.
+//
+// Master nodes can be joining the cluster concurrently so the membership
+// change is serialized with other expand operations: the cluster-wide lock
+// is held until the new member has started so the etcd cluster never has
+// more than one unstarted member.
 func (p *etcdExecutor) Execute(ctx context.Context) error {
-	p.Progress.NextStep("Adding etcd member")
-	member, err := p.Etcd.Add(ctx, p.Phase.Data.Server.EtcdPeerURL())
+	p.Progress.NextStep("Waiting for other etcd members to join")
+	err := p.acquireLock(ctx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	p.Infof("Added etcd member: %v.", member)
+	defer p.releaseLock(ctx)
+	peerURL := p.Phase.Data.Server.EtcdPeerURL()
+	member, err := p.findMember(ctx, peerURL)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if member == nil {
+		p.Progress.NextStep("Adding etcd member")
+		member, err = p.Etcd.Add(ctx, peerURL)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		p.Infof("Added etcd member: %v.", member)
+	}
+	p.Progress.NextStep("Waiting for etcd member to start")
+	err = utils.RetryFor(ctx, defaults.EtcdMemberStartTimeout, func() error {
+		started, err := p.findMember(ctx, peerURL)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		// members that haven't started yet do not have a name
+		if started.Name == "" {
+			return trace.NotFound("etcd member %v has not started yet", peerURL)
+		}
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Infof("Etcd member %v has started.", peerURL)
 	return nil
 }
 
+// DryRun describes the etcd member added to the cluster
+func (p *etcdExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("%v member add %v", defaults.EtcdCtlBin, p.Phase.Data.Server.EtcdPeerURL()), nil
+}
+
+// acquireLock blocks until this operation holds the etcd membership lock
+func (p *etcdExecutor) acquireLock(ctx context.Context) error {
+	for {
+		_, err := p.Keys.Set(ctx, defaults.EtcdMembershipLockKey, p.Plan.OperationID,
+			&etcd.SetOptions{
+				PrevExist: etcd.PrevNoExist,
+				TTL:       defaults.EtcdMembershipLockTTL,
+			})
+		if err == nil {
+			return nil
+		}
+		if !isNodeExist(err) {
+			return trace.Wrap(err)
+		}
+		resp, err := p.Keys.Get(ctx, defaults.EtcdMembershipLockKey, nil)
+		if err != nil && !etcd.IsKeyNotFound(err) {
+			return trace.Wrap(err)
+		}
+		// the lock may already be held by this operation if the phase
+		// is being resumed
+		if err == nil && resp.Node.Value == p.Plan.OperationID {
+			return nil
+		}
+		if err == nil {
+			p.Infof("Etcd membership is being changed by operation %v.", resp.Node.Value)
+		}
+		select {
+		case <-time.After(defaults.EtcdRetryInterval):
+		case <-ctx.Done():
+			return trace.ConnectionProblem(ctx.Err(), "timed out waiting for etcd membership lock")
+		}
+	}
+}
+
+// releaseLock releases the etcd membership lock held by this operation.
+// If the lock cannot be released, it expires after its TTL
+func (p *etcdExecutor) releaseLock(ctx context.Context) {
+	_, err := p.Keys.Delete(ctx, defaults.EtcdMembershipLockKey,
+		&etcd.DeleteOptions{PrevValue: p.Plan.OperationID})
+	if err != nil {
+		p.WithError(err).Warnf("Failed to release etcd membership lock, it will expire in %v.",
+			defaults.EtcdMembershipLockTTL)
+	}
+}
+
+// findMember returns the etcd member with the specified peer URL
+func (p *etcdExecutor) findMember(ctx context.Context, peerURL string) (*etcd.Member, error) {
+	members, err := p.Etcd.List(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, member := range members {
+		if utils.StringInSlice(member.PeerURLs, peerURL) {
+			return &member, nil
+		}
+	}
+	return nil, trace.NotFound("etcd member %v not found", peerURL)
+}
+
+func isNodeExist(err error) bool {
+	if etcdErr, ok := err.(etcd.Error); ok {
+		return etcdErr.Code == etcd.ErrorCodeNodeExist
+	}
+	return false
+}
+
 // Rollback removes the joined node from the cluster's etcd cluster
 func (p *etcdExecutor) Rollback(ctx context.Context) error {
 	p.Progress.NextStep("Restoring etcd data")
@@ -244,6 +354,14 @@ func (p *etcdBackupExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the etcd backup taken on the master node
+func (p *etcdBackupExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	backupPath := getBackupPath(p.Plan.OperationID)
+	var report fsm.DryRunReport
+	report.AddCommand("%v etcd backup %v on %v", defaults.PlanetBin, backupPath, p.Master.AdvertiseIP)
+	return report.AddFile(backupPath), nil
+}
+
 func (p *etcdBackupExecutor) backupEtcd(ctx context.Context, agent rpcclient.Client, backupPath string) error {
 	var out bytes.Buffer
 	err := agent.Command(ctx, p.FieldLogger, &out, utils.PlanetEnterCommand(
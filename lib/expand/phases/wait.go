@@ -26,6 +26,7 @@ import (
 	kubeutils "github.com/gravitational/gravity/lib/kubernetes"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -87,6 +88,22 @@ func (p *waitPlanetExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only waits for planet to start on the node
+func (p *waitPlanetExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
+// HealthChecks verifies that the cluster is running with the joined node
+// and, if the node has joined as a master, that etcd is healthy
+func (p *waitPlanetExecutor) HealthChecks() fsm.HealthChecks {
+	server := *p.Phase.Data.Server
+	checks := []fsm.HealthCheck{fsm.PlanetRunning([]storage.Server{server})}
+	if server.IsMaster() {
+		checks = append(checks, fsm.EtcdHealthy(p.FieldLogger))
+	}
+	return fsm.HealthChecks{Checks: checks}
+}
+
 // Rollback is no-op for this phase
 func (*waitPlanetExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -148,6 +165,20 @@ func (p *waitK8sExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only waits for the Kubernetes node to register
+func (p *waitK8sExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
+// HealthChecks verifies that the joined node is ready
+func (p *waitK8sExecutor) HealthChecks() fsm.HealthChecks {
+	return fsm.HealthChecks{
+		Checks: []fsm.HealthCheck{
+			fsm.NodeReady(p.Client.CoreV1(), p.Phase.Data.Server.KubeNodeID()),
+		},
+	}
+}
+
 // Rollback is no-op for this phase
 func (*waitK8sExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -81,11 +81,15 @@ func (p *Peer) getOperationPlan(ctx operationContext) (*storage.OperationPlan, e
 		builder.AddPreHookPhase(plan)
 	}
 
-	// install teleport and planet services on the joining node
-	builder.AddSystemPhase(plan)
+	// install teleport and planet services on the joining nodes
+	err = builder.AddSystemPhase(plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 
-	// when adding a master node, add it to the existing etcd cluster as a full member
-	if builder.JoiningNode.IsMaster() {
+	// when adding master nodes, add them to the existing etcd cluster as full
+	// members one at a time
+	if len(builder.JoiningNodes.Masters()) != 0 {
 		// when adding a second master node, etcd cluster becomes unavailable
 		// from the moment the second member is added to the moment the planet
 		// on the joining node comes up
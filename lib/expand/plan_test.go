@@ -19,11 +19,13 @@ package expand
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	installphases "github.com/gravitational/gravity/lib/install/phases"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
@@ -33,6 +35,7 @@ import (
 	"github.com/gravitational/gravity/lib/rpc/proto"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
@@ -226,15 +229,122 @@ func (s *PlanSuite) TestPlan(c *check.C) {
 			"expected phase number %v to be %v but got %v", i, expected[i].phaseID, phase.ID))
 		expected[i].phaseVerifier(c, phase)
 	}
+
+	// system packages are installed concurrently after the pre-join hook
+	graph, err := fsm.NewPlanGraph(*plan)
+	c.Assert(err, check.IsNil)
+	teleport, planet := fmt.Sprintf("%v/teleport", SystemPhase), fmt.Sprintf("%v/planet", SystemPhase)
+	c.Assert(graph.Requires(teleport), check.DeepEquals, graph.Requires(planet))
+	c.Assert(utils.StringInSlice(graph.Requires(planet), PreHookPhase), check.Equals, true)
+}
+
+func (s *PlanSuite) TestBatchPlan(c *check.C) {
+	servers := storage.Servers{
+		{AdvertiseIP: "10.10.0.5", Hostname: "node-5", Role: "node"},
+		{AdvertiseIP: "10.10.0.3", Hostname: "node-3", Role: "node"},
+		{AdvertiseIP: "10.10.0.4", Hostname: "node-4", Role: "node"},
+	}
+	opKey, err := s.services.Operator.CreateSiteExpandOperation(context.TODO(),
+		ops.CreateSiteExpandOperationRequest{
+			AccountID:   s.cluster.AccountID,
+			SiteDomain:  s.cluster.Domain,
+			Servers:     map[string]int{"node": len(servers)},
+			Provisioner: schema.ProvisionerAWSTerraform,
+		})
+	c.Assert(err, check.IsNil)
+	err = s.services.Operator.UpdateExpandOperationState(
+		*opKey, ops.OperationUpdateRequest{
+			Profiles: map[string]storage.ServerProfileRequest{
+				"node": {Count: len(servers)}},
+			Servers: servers,
+		})
+	c.Assert(err, check.IsNil)
+	op, err := s.services.Operator.GetSiteOperation(*opKey)
+	c.Assert(err, check.IsNil)
+
+	err = s.peer.initOperationPlan(operationContext{
+		Operator:  s.services.Operator,
+		Packages:  s.services.Packages,
+		Apps:      s.services.Apps,
+		Peer:      fmt.Sprintf("%v:%v", s.masterNode.AdvertiseIP, defaults.GravitySiteNodePort),
+		Operation: *op,
+		Cluster:   *s.cluster,
+	})
+	c.Assert(err, check.IsNil)
+	plan, err := s.services.Operator.GetOperationPlan(*opKey)
+	c.Assert(err, check.IsNil)
+
+	var phaseIDs []string
+	for _, phase := range plan.Phases {
+		phaseIDs = append(phaseIDs, phase.ID)
+	}
+	c.Assert(phaseIDs, check.DeepEquals, []string{
+		installphases.InitPhase,
+		StartAgentPhase,
+		ChecksPhase,
+		installphases.ConfigurePhase,
+		installphases.BootstrapPhase,
+		installphases.PullPhase,
+		PreHookPhase,
+		SystemPhase,
+		EtcdBackupPhase,
+		EtcdPhase,
+		installphases.WaitPhase,
+		StopAgentPhase,
+		PostHookPhase,
+		ElectPhase,
+	})
+
+	// the plan is executed by the joining node with the lowest address
+	startAgent := plan.Phases[1]
+	c.Assert(startAgent.Data.ExecServer.AdvertiseIP, check.Equals, "10.10.0.3")
+
+	// per-node phases run in parallel
+	pull := plan.Phases[5]
+	c.Assert(pull.Parallel, check.Equals, true)
+	c.Assert(pull.Phases, check.HasLen, len(servers))
+	c.Assert(pull.Phases[0].ID, check.Equals, "/pull/node-3")
+	c.Assert(pull.Phases[0].Requires, check.DeepEquals,
+		[]string{"/configure/node-3", "/bootstrap/node-3"})
+
+	// joining masters are added to etcd one at a time
+	masters := joiningNodes(op.Servers).Masters()
+	c.Assert(len(masters) > 1, check.Equals, true)
+	etcd := plan.Phases[9]
+	c.Assert(etcd.Parallel, check.Equals, false)
+	c.Assert(etcd.Phases, check.HasLen, len(masters))
+	for i, master := range masters {
+		requires := []string{nodePhaseID(SystemPhase, master.Hostname), EtcdBackupPhase}
+		if i > 0 {
+			requires = append(requires, etcd.Phases[i-1].ID)
+		}
+		c.Assert(etcd.Phases[i].ID, check.Equals, nodePhaseID(EtcdPhase, master.Hostname))
+		c.Assert(etcd.Phases[i].Requires, check.DeepEquals, requires)
+	}
+
+	// phases of a node do not wait for phases of other nodes, the joining
+	// regular node does not wait for etcd either
+	graph, err := fsm.NewPlanGraph(*plan)
+	c.Assert(err, check.IsNil)
+	for _, phaseID := range []string{"/system/node-4/planet", "/wait/node-4/k8s", "/elect/node-4"} {
+		for _, required := range graph.Requires(phaseID) {
+			c.Assert(strings.Contains(required, "node-3") || strings.Contains(required, "node-5") ||
+				strings.HasPrefix(required, EtcdPhase), check.Equals, false,
+				check.Commentf("%v should not depend on %v", phaseID, required))
+		}
+	}
+	c.Assert(utils.StringInSlice(graph.Requires("/system/node-4/planet"), "/preHook/node-4"), check.Equals, true)
+	c.Assert(utils.StringInSlice(graph.Requires("/wait/node-5/planet"), "/etcd/node-5"), check.Equals, true)
 }
 
 func (s *PlanSuite) verifyInitPhase(c *check.C, phase storage.OperationPhase) {
 	storage.DeepComparePhases(c, storage.OperationPhase{
 		ID: installphases.InitPhase,
 		Data: &storage.OperationPhaseData{
-			Server:  &s.joiningNode,
-			Master:  &s.masterNode,
-			Package: &s.appPackage,
+			Server:     &s.joiningNode,
+			ExecServer: &s.joiningNode,
+			Master:     &s.masterNode,
+			Package:    &s.appPackage,
 		},
 	}, phase)
 }
@@ -309,7 +419,7 @@ func (s *PlanSuite) verifySystemPhase(c *check.C, phase storage.OperationPhase)
 					ExecServer: &s.joiningNode,
 					Package:    s.teleportPackage,
 				},
-				Requires: []string{installphases.PullPhase},
+				Requires: []string{installphases.PullPhase, PreHookPhase},
 			},
 			{
 				ID: fmt.Sprintf("%v/planet", SystemPhase),
@@ -319,7 +429,7 @@ func (s *PlanSuite) verifySystemPhase(c *check.C, phase storage.OperationPhase)
 					Package:    s.planetPackage,
 					Labels:     pack.RuntimePackageLabels,
 				},
-				Requires: []string{installphases.PullPhase},
+				Requires: []string{installphases.PullPhase, PreHookPhase},
 			},
 		},
 	}, phase)
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// DryRunner is implemented by phase executors that can describe
+// the changes they would make without applying them
+type DryRunner interface {
+	// DryRun returns the report of changes the phase would make if executed
+	DryRun(context.Context) (*DryRunReport, error)
+}
+
+// DryRunReport describes the changes a phase would make if executed
+type DryRunReport = storage.DryRunReport
+
+// PhaseDryRun is the result of a dry-run of a single phase
+type PhaseDryRun = storage.PhaseDryRun
+
+// DryRunPlan describes the changes each phase of the plan that has not
+// been completed yet would make if executed.
+//
+// Phases which executors cannot be created on this node or which fail
+// to produce a report are recorded with an error and do not abort the dry-run
+func (f *FSM) DryRunPlan(ctx context.Context) ([]PhaseDryRun, error) {
+	return f.DryRunPhase(ctx, RootPhase)
+}
+
+// DryRunPhase describes the changes the specified phase would make if executed.
+//
+// If the phase has subphases, the report covers its subphases that have not
+// been completed yet. The root phase describes the whole plan
+func (f *FSM) DryRunPhase(ctx context.Context, phaseID string) ([]PhaseDryRun, error) {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	phases := FlattenPlan(plan)
+	if phaseID != RootPhase {
+		phase, err := FindPhase(plan, phaseID)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if !phase.HasSubphases() {
+			return []PhaseDryRun{f.dryRunPhase(ctx, *plan, *phase)}, nil
+		}
+		phases = nil
+		addPhases(phase, &phases)
+	}
+	var result []PhaseDryRun
+	for _, phase := range phases {
+		if phase.HasSubphases() || phase.IsCompleted() {
+			continue
+		}
+		result = append(result, f.dryRunPhase(ctx, *plan, *phase))
+	}
+	return result, nil
+}
+
+func (f *FSM) dryRunPhase(ctx context.Context, plan storage.OperationPlan, phase storage.OperationPhase) PhaseDryRun {
+	result := PhaseDryRun{
+		PhaseID:     phase.ID,
+		Description: phase.Description,
+	}
+	if phase.Data != nil && phase.Data.ExecServer != nil {
+		result.Node = phase.Data.ExecServer.AdvertiseIP
+	}
+	executor, err := f.GetExecutor(ExecutorParams{
+		Plan:     plan,
+		Phase:    phase,
+		Progress: utils.DiscardProgress,
+	}, f)
+	if err != nil {
+		f.WithError(err).Debugf("Failed to create executor for phase %q.", phase.ID)
+		result.Error = trace.UserMessage(err)
+		return result
+	}
+	dryRunner, ok := executor.(DryRunner)
+	if !ok {
+		return result
+	}
+	report, err := dryRunner.DryRun(ctx)
+	if err != nil {
+		f.WithError(err).Debugf("Failed to dry-run phase %q.", phase.ID)
+		result.Error = trace.UserMessage(err)
+		return result
+	}
+	result.Report = report
+	return result
+}
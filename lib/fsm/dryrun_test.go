@@ -0,0 +1,147 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type DryRunSuite struct{}
+
+var _ = Suite(&DryRunSuite{})
+
+func (s *DryRunSuite) TestDryRunsIncompletePhases(c *C) {
+	engine := &dryRunEngine{
+		testEngine: newTestEngine(storage.OperationPlan{
+			Phases: []storage.OperationPhase{
+				{ID: "/init", State: storage.OperationPhaseStateCompleted},
+				{
+					ID: "/masters",
+					Phases: []storage.OperationPhase{
+						{ID: "/masters/drain", Description: "Drain node"},
+						{ID: "/masters/system"},
+					},
+				},
+				{ID: "/app"},
+			},
+		}),
+		reports: map[string]*DryRunReport{
+			"/masters/drain": (&DryRunReport{}).
+				AddCommand("kubectl drain %v", "node-1").
+				AddObject("node", "node-1"),
+		},
+		failures: map[string]error{
+			"/app": trace.BadParameter("requires kubernetes client"),
+		},
+	}
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	phases, err := machine.DryRunPlan(context.TODO())
+	c.Assert(err, IsNil)
+	c.Assert(phases, DeepEquals, []PhaseDryRun{
+		{
+			PhaseID:     "/masters/drain",
+			Description: "Drain node",
+			Report: &DryRunReport{
+				Commands: []string{"kubectl drain node-1"},
+				Objects:  []string{"node/node-1"},
+			},
+		},
+		{PhaseID: "/masters/system"},
+		{PhaseID: "/app", Error: "requires kubernetes client"},
+	})
+	c.Assert(engine.executed, HasLen, 0)
+}
+
+func (s *DryRunSuite) TestDryRunsSinglePhase(c *C) {
+	engine := &dryRunEngine{
+		testEngine: newTestEngine(storage.OperationPlan{
+			Phases: []storage.OperationPhase{
+				{
+					ID: "/masters",
+					Phases: []storage.OperationPhase{
+						{ID: "/masters/node-1", State: storage.OperationPhaseStateCompleted},
+						{ID: "/masters/node-2"},
+					},
+				},
+				{ID: "/app", State: storage.OperationPhaseStateCompleted},
+			},
+		}),
+		reports: map[string]*DryRunReport{
+			"/app": (&DryRunReport{}).AddCommand("helm install"),
+		},
+	}
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	phases, err := machine.DryRunPhase(context.TODO(), "/masters")
+	c.Assert(err, IsNil)
+	c.Assert(phases, DeepEquals, []PhaseDryRun{{PhaseID: "/masters/node-2"}})
+
+	phases, err = machine.DryRunPhase(context.TODO(), "/app")
+	c.Assert(err, IsNil)
+	c.Assert(phases, DeepEquals, []PhaseDryRun{
+		{
+			PhaseID: "/app",
+			Report:  &DryRunReport{Commands: []string{"helm install"}},
+		},
+	})
+
+	_, err = machine.DryRunPhase(context.TODO(), "/unknown")
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	c.Assert(engine.executed, HasLen, 0)
+}
+
+// dryRunEngine returns executors that support dry-run for phases with reports
+type dryRunEngine struct {
+	*testEngine
+	reports  map[string]*DryRunReport
+	failures map[string]error
+}
+
+func (e *dryRunEngine) GetExecutor(p ExecutorParams, remote Remote) (PhaseExecutor, error) {
+	if err := e.failures[p.Phase.ID]; err != nil {
+		return nil, trace.Wrap(err)
+	}
+	executor, err := e.testEngine.GetExecutor(p, remote)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report, ok := e.reports[p.Phase.ID]
+	if !ok {
+		return executor, nil
+	}
+	return &dryRunExecutor{
+		PhaseExecutor: executor,
+		report:        report,
+	}, nil
+}
+
+type dryRunExecutor struct {
+	PhaseExecutor
+	report *DryRunReport
+}
+
+func (r *dryRunExecutor) DryRun(context.Context) (*DryRunReport, error) {
+	return r.report, nil
+}
@@ -29,6 +29,12 @@ import (
 //
 // An executor can optionally validate its requirements and outcomes by
 // implementing non-trivial PreCheck/PostCheck APIs.
+//
+// An executor can optionally describe the changes it would make without
+// applying them by implementing DryRunner.
+//
+// An executor can optionally declare the health checks to verify before
+// the phase is marked completed by implementing HealthGate.
 type PhaseExecutor interface {
 	// PreCheck is called before phase execution
 	PreCheck(context.Context) error
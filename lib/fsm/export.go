@@ -0,0 +1,399 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// PlanExportFormatDot exports the plan as a Graphviz graph
+	PlanExportFormatDot = "dot"
+	// PlanExportFormatMermaid exports the plan as a Mermaid flowchart
+	PlanExportFormatMermaid = "mermaid"
+	// PlanExportFormatJSON exports the plan as JSON using the PlanExport schema
+	PlanExportFormatJSON = "json"
+
+	// PlanExportSchemaVersion is the version of the PlanExport schema.
+	// It is incremented every time a backwards-incompatible change is made
+	// to the schema. Fields can be added without changing the version
+	PlanExportSchemaVersion = "plan.gravitational.io/v1"
+)
+
+// PlanExportFormats lists all supported plan export formats
+var PlanExportFormats = []string{
+	PlanExportFormatDot,
+	PlanExportFormatMermaid,
+	PlanExportFormatJSON,
+}
+
+// PlanExport is the versioned representation of an operation plan
+// suitable for consumption by external tools.
+//
+// The schema is shared by all operation types (install, expand, update,
+// environ and config updates, etc.)
+type PlanExport struct {
+	// SchemaVersion is the version of this schema, see PlanExportSchemaVersion
+	SchemaVersion string `json:"schema_version"`
+	// OperationID is the ID of the operation the plan belongs to
+	OperationID string `json:"operation_id"`
+	// OperationType is the type of the operation, e.g. "operation_update"
+	OperationType string `json:"operation_type"`
+	// ClusterName is the name of the cluster
+	ClusterName string `json:"cluster_name"`
+	// State is the aggregate plan state, one of the phase states
+	State string `json:"state"`
+	// Created is the plan creation time
+	Created time.Time `json:"created"`
+	// Phases is the tree of plan phases in execution order
+	Phases []PhaseExport `json:"phases"`
+}
+
+// PhaseExport describes a single phase of the exported plan
+type PhaseExport struct {
+	// ID is the absolute phase ID, e.g. "/masters/node-1/drain"
+	ID string `json:"id"`
+	// Description is the human-readable phase description
+	Description string `json:"description,omitempty"`
+	// State is the phase state: unstarted, in_progress, completed,
+	// failed or rolled_back
+	State string `json:"state"`
+	// Requires lists IDs of the phases that have to complete first.
+	// Besides the declared requirements, it includes the preceding
+	// sibling of a phase whose parent executes subphases sequentially
+	Requires []string `json:"requires,omitempty"`
+	// Parallel is whether the subphases can execute concurrently
+	Parallel bool `json:"parallel,omitempty"`
+	// Server is the server the phase operates on
+	Server *ServerExport `json:"server,omitempty"`
+	// ExecServer is the server the phase executes on if different from Server
+	ExecServer *ServerExport `json:"exec_server,omitempty"`
+	// Started is the time the phase started executing
+	Started *time.Time `json:"started,omitempty"`
+	// Updated is the time of the last phase state change
+	Updated *time.Time `json:"updated,omitempty"`
+	// DurationSeconds is the phase execution time.
+	// It is only set for phases that have finished executing
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	// Error is the phase error message if the phase has failed
+	Error string `json:"error,omitempty"`
+	// HealthChecks lists the failed health checks if the phase
+	// has failed its health checks
+	HealthChecks []HealthCheckFailure `json:"health_checks,omitempty"`
+	// Phases lists the subphases
+	Phases []PhaseExport `json:"phases,omitempty"`
+}
+
+// ServerExport identifies a server in the exported plan
+type ServerExport struct {
+	// Hostname is the server hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the server IP address
+	AdvertiseIP string `json:"advertise_ip"`
+	// Role is the server cluster role: master or node
+	Role string `json:"role,omitempty"`
+}
+
+// ExportPlan converts the provided plan to its versioned export representation
+func ExportPlan(plan storage.OperationPlan) PlanExport {
+	export := PlanExport{
+		SchemaVersion: PlanExportSchemaVersion,
+		OperationID:   plan.OperationID,
+		OperationType: plan.OperationType,
+		ClusterName:   plan.ClusterName,
+		State:         planState(plan),
+		Created:       plan.CreatedAt,
+	}
+	graph := newPlanGraph(plan)
+	for _, phase := range plan.Phases {
+		export.Phases = append(export.Phases, exportPhase(phase, graph))
+	}
+	return export
+}
+
+// FormatOperationPlanExport formats the provided plan as JSON
+// using the versioned PlanExport schema
+func FormatOperationPlanExport(w io.Writer, plan storage.OperationPlan) error {
+	bytes, err := json.MarshalIndent(ExportPlan(plan), "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := fmt.Fprintln(w, string(bytes)); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// FormatOperationPlanDot formats the provided plan as a Graphviz graph.
+//
+// Phases with subphases are rendered as clusters and dependencies
+// between phases (see PlanGraph) as edges
+func FormatOperationPlanDot(w io.Writer, plan storage.OperationPlan) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", plan.OperationID)
+	b.WriteString("  compound=true;\n  rankdir=TB;\n  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+	for _, phase := range plan.Phases {
+		writeDotPhase(&b, phase, "  ")
+	}
+	for _, edge := range newPlanGraph(plan).Edges {
+		from, err := FindPhase(&plan, edge.From)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		to, err := FindPhase(&plan, edge.To)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		var attrs []string
+		if from.HasSubphases() {
+			attrs = append(attrs, fmt.Sprintf("ltail=%q", dotClusterID(from.ID)))
+		}
+		if to.HasSubphases() {
+			attrs = append(attrs, fmt.Sprintf("lhead=%q", dotClusterID(to.ID)))
+		}
+		fmt.Fprintf(&b, "  %q -> %q", dotAnchor(*from), dotAnchor(*to))
+		if len(attrs) != 0 {
+			fmt.Fprintf(&b, " [%v]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return trace.Wrap(err)
+}
+
+// FormatOperationPlanMermaid formats the provided plan as a Mermaid flowchart.
+//
+// Phases with subphases are rendered as subgraphs and dependencies
+// between phases (see PlanGraph) as links
+func FormatOperationPlanMermaid(w io.Writer, plan storage.OperationPlan) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, phase := range plan.Phases {
+		writeMermaidPhase(&b, phase, "  ")
+	}
+	for _, edge := range newPlanGraph(plan).Edges {
+		fmt.Fprintf(&b, "  %v --> %v\n", mermaidID(edge.From), mermaidID(edge.To))
+	}
+	for _, state := range storage.OperationPhaseStates {
+		fmt.Fprintf(&b, "  classDef %v %v\n", mermaidClass(state), mermaidStyles[state])
+	}
+	_, err := io.WriteString(w, b.String())
+	return trace.Wrap(err)
+}
+
+func exportPhase(phase storage.OperationPhase, graph *PlanGraph) PhaseExport {
+	export := PhaseExport{
+		ID:          phase.ID,
+		Description: phase.Description,
+		State:       phase.GetState(),
+		Requires:    graph.PhaseRequires(phase.ID),
+		Parallel:    phase.Parallel,
+	}
+	if phase.Data != nil {
+		export.Server = exportServer(phase.Data.Server)
+		export.ExecServer = exportServer(phase.Data.ExecServer)
+	}
+	started, updated := phase.GetStartTime(), phase.GetLastUpdateTime()
+	if !started.IsZero() {
+		export.Started = &started
+	}
+	if !updated.IsZero() {
+		export.Updated = &updated
+	}
+	if duration := phaseDuration(phase); duration != 0 {
+		export.DurationSeconds = duration.Seconds()
+	}
+	if phase.Error != nil {
+		export.Error = phaseErrorMessage(phase)
+		export.HealthChecks = HealthCheckFailures(phase)
+	}
+	for _, subphase := range phase.Phases {
+		export.Phases = append(export.Phases, exportPhase(subphase, graph))
+	}
+	return export
+}
+
+func exportServer(server *storage.Server) *ServerExport {
+	if server == nil {
+		return nil
+	}
+	return &ServerExport{
+		Hostname:    server.Hostname,
+		AdvertiseIP: server.AdvertiseIP,
+		Role:        server.ClusterRole,
+	}
+}
+
+// phaseDuration returns the execution time of the specified phase
+// if it has finished executing
+func phaseDuration(phase storage.OperationPhase) time.Duration {
+	switch phase.GetState() {
+	case storage.OperationPhaseStateCompleted,
+		storage.OperationPhaseStateFailed,
+		storage.OperationPhaseStateRolledBack:
+	default:
+		return 0
+	}
+	started, updated := phase.GetStartTime(), phase.GetLastUpdateTime()
+	if started.IsZero() || updated.Before(started) {
+		return 0
+	}
+	return updated.Sub(started)
+}
+
+func phaseErrorMessage(phase storage.OperationPhase) string {
+	var phaseErr trace.TraceErr
+	if err := utils.UnmarshalError(phase.Error.Err, &phaseErr); err != nil || phaseErr.Err == nil {
+		return phase.Error.Message
+	}
+	return phaseErr.Err.Error()
+}
+
+func planState(plan storage.OperationPlan) string {
+	if IsCompleted(&plan) {
+		return storage.OperationPhaseStateCompleted
+	}
+	if HasFailed(&plan) {
+		return storage.OperationPhaseStateFailed
+	}
+	state := storage.OperationPhaseStateUnstarted
+	for _, phase := range FlattenPlan(&plan) {
+		switch {
+		case phase.IsRolledBack():
+			state = storage.OperationPhaseStateRolledBack
+		case !phase.IsUnstarted():
+			return storage.OperationPhaseStateInProgress
+		}
+	}
+	return state
+}
+
+func writeDotPhase(b *strings.Builder, phase storage.OperationPhase, indent string) {
+	if !phase.HasSubphases() {
+		fmt.Fprintf(b, "%v%q [label=%q, fillcolor=%q];\n", indent, phase.ID,
+			phaseLabel(phase, "\n"), dotColors[phase.GetState()])
+		return
+	}
+	fmt.Fprintf(b, "%vsubgraph %q {\n", indent, dotClusterID(phase.ID))
+	fmt.Fprintf(b, "%v  label=%q;\n", indent, phaseLabel(phase, "\n"))
+	fmt.Fprintf(b, "%v  style=filled;\n%v  fillcolor=%q;\n", indent, indent,
+		dotColors[phase.GetState()])
+	for _, subphase := range phase.Phases {
+		writeDotPhase(b, subphase, indent+"  ")
+	}
+	fmt.Fprintf(b, "%v}\n", indent)
+}
+
+func writeMermaidPhase(b *strings.Builder, phase storage.OperationPhase, indent string) {
+	label := strings.Replace(phaseLabel(phase, "<br/>"), `"`, "#quot;", -1)
+	if !phase.HasSubphases() {
+		fmt.Fprintf(b, "%v%v[\"%v\"]:::%v\n", indent, mermaidID(phase.ID), label,
+			mermaidClass(phase.GetState()))
+		return
+	}
+	fmt.Fprintf(b, "%vsubgraph %v[\"%v\"]\n", indent, mermaidID(phase.ID), label)
+	for _, subphase := range phase.Phases {
+		writeMermaidPhase(b, subphase, indent+"  ")
+	}
+	fmt.Fprintf(b, "%vend\n", indent)
+	fmt.Fprintf(b, "%vclass %v %v\n", indent, mermaidID(phase.ID), mermaidClass(phase.GetState()))
+}
+
+// phaseLabel returns the label for the specified phase with lines
+// separated by the given separator
+func phaseLabel(phase storage.OperationPhase, separator string) string {
+	lines := []string{formatName(phase.ID)}
+	if phase.Description != "" {
+		lines = append(lines, phase.Description)
+	}
+	state := formatState(phase.GetState())
+	if duration := phaseDuration(phase); duration != 0 {
+		state = fmt.Sprintf("%v in %v", state, duration.Round(time.Second))
+	}
+	lines = append(lines, state)
+	if phase.Data != nil && phase.Data.Server != nil {
+		lines = append(lines, fmt.Sprintf("%v (%v)",
+			phase.Data.Server.Hostname, phase.Data.Server.AdvertiseIP))
+	}
+	return strings.Join(lines, separator)
+}
+
+// dotAnchor returns the ID of the graph node edges to/from the specified
+// phase are attached to.
+// Phases with subphases are rendered as clusters, so edges are attached
+// to their first leaf phase and clipped at the cluster boundary
+func dotAnchor(phase storage.OperationPhase) string {
+	for phase.HasSubphases() {
+		phase = phase.Phases[0]
+	}
+	return phase.ID
+}
+
+func dotClusterID(phaseID string) string {
+	return "cluster_" + phaseID
+}
+
+// mermaidID returns the ID of the graph node for the specified phase.
+// Characters other than letters and digits are escaped as an underscore
+// followed by the character's hex code, including the underscore itself,
+// so distinct phase IDs always map to distinct node IDs
+func mermaidID(phaseID string) string {
+	var b strings.Builder
+	b.WriteString("phase")
+	for i := 0; i < len(phaseID); i++ {
+		c := phaseID[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "_%02X", c)
+	}
+	return b.String()
+}
+
+func mermaidClass(state string) string {
+	return mermaidInvalidChars.ReplaceAllString(state, "_")
+}
+
+var mermaidInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+var dotColors = map[string]string{
+	storage.OperationPhaseStateUnstarted:  "white",
+	storage.OperationPhaseStateInProgress: "lightyellow",
+	storage.OperationPhaseStateCompleted:  "palegreen",
+	storage.OperationPhaseStateFailed:     "lightpink",
+	storage.OperationPhaseStateRolledBack: "lightgrey",
+}
+
+var mermaidStyles = map[string]string{
+	storage.OperationPhaseStateUnstarted:  "fill:#ffffff,stroke:#999999",
+	storage.OperationPhaseStateInProgress: "fill:#ffffe0,stroke:#c9a800",
+	storage.OperationPhaseStateCompleted:  "fill:#98fb98,stroke:#2e8b57",
+	storage.OperationPhaseStateFailed:     "fill:#ffb6c1,stroke:#b22222",
+	storage.OperationPhaseStateRolledBack: "fill:#d3d3d3,stroke:#696969",
+}
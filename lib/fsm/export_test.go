@@ -0,0 +1,181 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+type ExportSuite struct{}
+
+var _ = Suite(&ExportSuite{})
+
+func (s *ExportSuite) TestExportsPlan(c *C) {
+	plan := newExportPlan()
+	export := ExportPlan(*plan)
+
+	started := exportTime
+	updated := exportTime.Add(90 * time.Second)
+	c.Assert(export, DeepEquals, PlanExport{
+		SchemaVersion: PlanExportSchemaVersion,
+		OperationID:   "operation-1",
+		OperationType: "operation_update",
+		ClusterName:   "example.com",
+		State:         storage.OperationPhaseStateInProgress,
+		Created:       exportTime,
+		Phases: []PhaseExport{
+			{
+				ID:              "/init",
+				Description:     "Initialize operation",
+				State:           storage.OperationPhaseStateCompleted,
+				Started:         &started,
+				Updated:         &updated,
+				DurationSeconds: 90,
+			},
+			{
+				ID:          "/masters",
+				Description: "Update masters",
+				State:       storage.OperationPhaseStateUnstarted,
+				Requires:    []string{"/init"},
+				Phases: []PhaseExport{
+					{
+						ID:          "/masters/node-1",
+						Description: "Drain node-1",
+						State:       storage.OperationPhaseStateUnstarted,
+						Server: &ServerExport{
+							Hostname:    "node-1",
+							AdvertiseIP: "10.0.0.1",
+							Role:        "master",
+						},
+					},
+				},
+			},
+			{
+				ID:          "/app",
+				Description: "Update application",
+				State:       storage.OperationPhaseStateUnstarted,
+				// implicitly depends on the preceding phase
+				Requires: []string{"/masters"},
+			},
+		},
+	})
+}
+
+func (s *ExportSuite) TestFormatsDot(c *C) {
+	var buf bytes.Buffer
+	err := FormatOperationPlanDot(&buf, *newExportPlan())
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Equals, `digraph "operation-1" {
+  compound=true;
+  rankdir=TB;
+  node [shape=box, style="rounded,filled", fillcolor=white];
+  "/init" [label="init\nInitialize operation\nCompleted in 1m30s", fillcolor="palegreen"];
+  subgraph "cluster_/masters" {
+    label="masters\nUpdate masters\nUnstarted";
+    style=filled;
+    fillcolor="white";
+    "/masters/node-1" [label="node-1\nDrain node-1\nUnstarted\nnode-1 (10.0.0.1)", fillcolor="white"];
+  }
+  "/app" [label="app\nUpdate application\nUnstarted", fillcolor="white"];
+  "/init" -> "/masters/node-1" [lhead="cluster_/masters"];
+  "/masters/node-1" -> "/app" [ltail="cluster_/masters"];
+}
+`)
+}
+
+func (s *ExportSuite) TestFormatsMermaid(c *C) {
+	var buf bytes.Buffer
+	err := FormatOperationPlanMermaid(&buf, *newExportPlan())
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Equals, `flowchart TD
+  phase_2Finit["init<br/>Initialize operation<br/>Completed in 1m30s"]:::completed
+  subgraph phase_2Fmasters["masters<br/>Update masters<br/>Unstarted"]
+    phase_2Fmasters_2Fnode_2D1["node-1<br/>Drain node-1<br/>Unstarted<br/>node-1 (10.0.0.1)"]:::unstarted
+  end
+  class phase_2Fmasters unstarted
+  phase_2Fapp["app<br/>Update application<br/>Unstarted"]:::unstarted
+  phase_2Finit --> phase_2Fmasters
+  phase_2Fmasters --> phase_2Fapp
+  classDef unstarted fill:#ffffff,stroke:#999999
+  classDef in_progress fill:#ffffe0,stroke:#c9a800
+  classDef completed fill:#98fb98,stroke:#2e8b57
+  classDef failed fill:#ffb6c1,stroke:#b22222
+  classDef rolled_back fill:#d3d3d3,stroke:#696969
+`)
+}
+
+func (s *ExportSuite) TestMermaidIDsAreUnique(c *C) {
+	ids := make(map[string]string)
+	for _, phaseID := range []string{"/a-b", "/a_b", "/a/b", "/a_2Fb", "/a__b", "/a.b"} {
+		id := mermaidID(phaseID)
+		c.Assert(id, Matches, "[a-zA-Z0-9_]+")
+		other, exists := ids[id]
+		c.Assert(exists, Equals, false, Commentf("%v and %v map to %v", phaseID, other, id))
+		ids[id] = phaseID
+	}
+}
+
+// newExportPlan returns a plan with its state resolved from a changelog
+func newExportPlan() *storage.OperationPlan {
+	plan := storage.OperationPlan{
+		OperationID:   "operation-1",
+		OperationType: "operation_update",
+		ClusterName:   "example.com",
+		CreatedAt:     exportTime,
+		Phases: []storage.OperationPhase{
+			{ID: "/init", Description: "Initialize operation"},
+			{
+				ID:          "/masters",
+				Description: "Update masters",
+				Requires:    []string{"/init"},
+				Phases: []storage.OperationPhase{
+					{
+						ID:          "/masters/node-1",
+						Description: "Drain node-1",
+						Data: &storage.OperationPhaseData{
+							Server: &storage.Server{
+								Hostname:    "node-1",
+								AdvertiseIP: "10.0.0.1",
+								ClusterRole: "master",
+							},
+						},
+					},
+				},
+			},
+			{ID: "/app", Description: "Update application"},
+		},
+	}
+	return ResolvePlan(plan, storage.PlanChangelog{
+		{
+			PhaseID:  "/init",
+			NewState: storage.OperationPhaseStateInProgress,
+			Created:  exportTime,
+		},
+		{
+			PhaseID:  "/init",
+			NewState: storage.OperationPhaseStateCompleted,
+			Created:  exportTime.Add(90 * time.Second),
+		},
+	})
+}
+
+var exportTime = time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC)
@@ -163,3 +163,37 @@ func formatState(state string) string {
 		return "Unknown"
 	}
 }
+
+// FormatDryRunText formats the results of the plan dry-run as text
+func FormatDryRunText(w io.Writer, phases []PhaseDryRun) {
+	for _, phase := range phases {
+		node := phase.Node
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(w, "* %v\t%v\t(node: %v)\n", phase.PhaseID, phase.Description, node)
+		switch {
+		case phase.Error != "":
+			fmt.Fprintf(w, "    Unable to preview: %v\n", phase.Error)
+		case phase.Report == nil:
+			fmt.Fprint(w, "    Preview is not available for this phase\n")
+		case phase.Report.IsEmpty():
+			fmt.Fprint(w, "    No changes\n")
+		default:
+			printDryRunItems(w, "Commands", phase.Report.Commands)
+			printDryRunItems(w, "Packages", phase.Report.Packages)
+			printDryRunItems(w, "Kubernetes objects", phase.Report.Objects)
+			printDryRunItems(w, "Files", phase.Report.Files)
+		}
+	}
+}
+
+func printDryRunItems(w io.Writer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "    %v:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(w, "      %v\n", item)
+	}
+}
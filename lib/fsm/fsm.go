@@ -128,6 +128,10 @@ type Config struct {
 	Insecure bool
 	// Logger allows to override default logger
 	Logger logrus.FieldLogger
+	// Parallel is the maximum number of phases ExecutePlan runs concurrently.
+	// If set, phases are scheduled based on their dependencies (see PlanGraph),
+	// otherwise they are executed in plan order
+	Parallel int
 }
 
 // CheckAndSetDefaults makes sure the config is valid and sets some defaults
@@ -138,6 +142,9 @@ func (c *Config) CheckAndSetDefaults() error {
 	if c.Logger == nil {
 		c.Logger = logrus.WithField(trace.Component, "fsm")
 	}
+	if c.Parallel < 0 {
+		return trace.BadParameter("Parallel cannot be negative")
+	}
 	return nil
 }
 
@@ -153,8 +160,12 @@ func New(config Config) (*FSM, error) {
 	}, nil
 }
 
-// ExecutePlan iterates over all phases of the plan and executes them in order
+// ExecutePlan iterates over all phases of the plan and executes them in order,
+// or as their dependencies complete if the FSM is configured to run phases in parallel
 func (f *FSM) ExecutePlan(ctx context.Context, progress utils.Progress) error {
+	if f.Parallel != 0 {
+		return trace.Wrap(f.executePlanGraph(ctx, progress))
+	}
 	plan, err := f.GetPlan()
 	if err != nil {
 		return trace.Wrap(err)
@@ -471,6 +482,20 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 		return trace.Wrap(err)
 	}
 
+	err = verifyHealth(ctx, executor)
+	if err != nil {
+		executor.Errorf("Phase health checks failed: %v.", err)
+		if err := f.ChangePhaseState(ctx,
+			StateChange{
+				Phase: phase.ID,
+				State: storage.OperationPhaseStateFailed,
+				Error: trace.Wrap(err),
+			}); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(err)
+	}
+
 	err = f.ChangePhaseState(ctx,
 		StateChange{
 			Phase: phase.ID,
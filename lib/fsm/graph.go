@@ -0,0 +1,253 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// PlanGraph is the dependency graph of the executable phases of an operation plan.
+//
+// Only the phases without subphases are executable: a composite phase is
+// completed once all of its subphases are. A phase depends on:
+//
+//   - all phases listed in the Requires field of the phase itself or any of
+//     its parents, with a composite phase standing for all of its subphases
+//   - all phases of its preceding sibling, unless the phase declares its own
+//     requirements or its parent executes subphases in parallel
+type PlanGraph struct {
+	// Phases lists IDs of the executable phases in plan order
+	Phases []string
+	// Edges lists the dependencies between phases (composite or not)
+	// the executable phase dependencies are computed from, in plan order
+	Edges []PlanEdge
+	// requires maps a phase ID to IDs of the phases it depends on
+	requires map[string][]string
+}
+
+// PlanEdge is a dependency between two phases of a plan
+type PlanEdge struct {
+	// From is the ID of the phase that has to complete first
+	From string
+	// To is the ID of the dependent phase
+	To string
+	// Implicit is true if the dependency is not declared by the phase
+	// but follows from the order of phases in a sequential parent
+	Implicit bool
+}
+
+// NewPlanGraph returns the dependency graph for the specified plan.
+// Returns an error if the plan contains circular dependencies
+func NewPlanGraph(plan storage.OperationPlan) (*PlanGraph, error) {
+	graph := newPlanGraph(plan)
+	if err := graph.checkCycles(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return graph, nil
+}
+
+func newPlanGraph(plan storage.OperationPlan) *PlanGraph {
+	leaves := make(map[string][]string)
+	for _, phase := range plan.Phases {
+		collectLeaves(phase, leaves)
+	}
+	graph := &PlanGraph{
+		requires: make(map[string][]string),
+	}
+	graph.addPhases(plan.Phases, false, nil, leaves)
+	return graph
+}
+
+// Requires returns IDs of the executable phases the specified phase depends on
+func (r *PlanGraph) Requires(phaseID string) []string {
+	return r.requires[phaseID]
+}
+
+// PhaseRequires returns IDs of the phases the specified phase directly
+// depends on, including its preceding sibling in a sequential parent
+func (r *PlanGraph) PhaseRequires(phaseID string) (result []string) {
+	for _, edge := range r.Edges {
+		if edge.To == phaseID {
+			result = append(result, edge.From)
+		}
+	}
+	return result
+}
+
+func (r *PlanGraph) addPhases(phases []storage.OperationPhase, parallel bool, inherited []string, leaves map[string][]string) {
+	for i, phase := range phases {
+		requires := append([]string{}, inherited...)
+		declared := make(map[string]bool, len(phase.Requires))
+		for _, required := range phase.Requires {
+			// Requirements that are not part of the plan are ignored
+			// similar to the sequential executor
+			if _, ok := leaves[required]; !ok || declared[required] {
+				continue
+			}
+			declared[required] = true
+			requires = append(requires, leaves[required]...)
+			r.Edges = append(r.Edges, PlanEdge{From: required, To: phase.ID})
+		}
+		if i > 0 && !parallel && len(phase.Requires) == 0 {
+			previous := phases[i-1].ID
+			requires = append(requires, leaves[previous]...)
+			r.Edges = append(r.Edges, PlanEdge{From: previous, To: phase.ID, Implicit: true})
+		}
+		if phase.HasSubphases() {
+			r.addPhases(phase.Phases, phase.Parallel, requires, leaves)
+			continue
+		}
+		r.Phases = append(r.Phases, phase.ID)
+		r.requires[phase.ID] = teleutils.Deduplicate(requires)
+	}
+}
+
+// checkCycles makes sure that all phases can be scheduled
+func (r *PlanGraph) checkCycles() error {
+	done := make(map[string]bool, len(r.Phases))
+	for len(done) < len(r.Phases) {
+		var progress bool
+		for _, phaseID := range r.Phases {
+			if !done[phaseID] && r.isReady(phaseID, done) {
+				done[phaseID] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var blocked []string
+			for _, phaseID := range r.Phases {
+				if !done[phaseID] {
+					blocked = append(blocked, phaseID)
+				}
+			}
+			return trace.BadParameter("plan has circular dependencies between phases %v", blocked)
+		}
+	}
+	return nil
+}
+
+// isReady returns true if all phases the specified phase depends on are done
+func (r *PlanGraph) isReady(phaseID string, done map[string]bool) bool {
+	for _, required := range r.requires[phaseID] {
+		if !done[required] {
+			return false
+		}
+	}
+	return true
+}
+
+// executePlanGraph executes the plan phases as soon as their dependencies
+// have completed, running up to the configured number of phases concurrently.
+//
+// Phases that have already completed are not executed again so an interrupted
+// operation can be resumed. After a phase fails, no new phases are started but
+// the phases that are already running are allowed to finish
+func (f *FSM) executePlanGraph(ctx context.Context, progress utils.Progress) error {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	graph, err := NewPlanGraph(*plan)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	done := make(map[string]bool, len(graph.Phases))
+	var pending []string
+	for _, phaseID := range graph.Phases {
+		phase, err := FindPhase(plan, phaseID)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if phase.IsCompleted() {
+			done[phaseID] = true
+			continue
+		}
+		pending = append(pending, phaseID)
+	}
+	type result struct {
+		phaseID string
+		err     error
+	}
+	resultsC := make(chan result, len(pending))
+	var running int
+	var errors []error
+	for {
+		if len(errors) == 0 && ctx.Err() == nil {
+			var blocked []string
+			for _, phaseID := range pending {
+				if running >= f.Parallel || !graph.isReady(phaseID, done) {
+					blocked = append(blocked, phaseID)
+					continue
+				}
+				running++
+				go func(phaseID string) {
+					f.Debugf("Executing phase %q.", phaseID)
+					err := f.ExecutePhase(ctx, Params{
+						PhaseID:  phaseID,
+						Progress: progress,
+						Resume:   true,
+					})
+					resultsC <- result{phaseID: phaseID, err: err}
+				}(phaseID)
+			}
+			pending = blocked
+		}
+		if running == 0 {
+			break
+		}
+		result := <-resultsC
+		running--
+		if result.err != nil {
+			f.WithFields(logrus.Fields{
+				logrus.ErrorKey: result.err,
+				"phase":         result.phaseID,
+			}).Warn("Failed to execute phase.")
+			errors = append(errors, trace.Wrap(result.err, "failed to execute phase %q", result.phaseID))
+			continue
+		}
+		done[result.phaseID] = true
+	}
+	if len(errors) != 0 {
+		return trace.NewAggregate(errors...)
+	}
+	if err := ctx.Err(); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// collectLeaves records the executable phases of the specified phase
+// and each of its subphases in leaves
+func collectLeaves(phase storage.OperationPhase, leaves map[string][]string) []string {
+	if !phase.HasSubphases() {
+		leaves[phase.ID] = []string{phase.ID}
+		return leaves[phase.ID]
+	}
+	var result []string
+	for _, subphase := range phase.Phases {
+		result = append(result, collectLeaves(subphase, leaves)...)
+	}
+	leaves[phase.ID] = result
+	return result
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/rpc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+func TestFSM(t *testing.T) { TestingT(t) }
+
+type GraphSuite struct{}
+
+var _ = Suite(&GraphSuite{})
+
+func (s *GraphSuite) TestComputesDependencies(c *C) {
+	graph, err := NewPlanGraph(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{
+				ID:       "/nodes",
+				Parallel: true,
+				Requires: []string{"/init"},
+				Phases: []storage.OperationPhase{
+					{ID: "/nodes/node-1"},
+					{ID: "/nodes/node-2"},
+				},
+			},
+			{
+				ID:       "/masters",
+				Requires: []string{"/init"},
+				Phases: []storage.OperationPhase{
+					{ID: "/masters/master-1"},
+					{ID: "/masters/master-2"},
+				},
+			},
+			{ID: "/app"},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(graph.Phases, DeepEquals, []string{
+		"/init", "/nodes/node-1", "/nodes/node-2",
+		"/masters/master-1", "/masters/master-2", "/app",
+	})
+	c.Assert(graph.Requires("/init"), HasLen, 0)
+	c.Assert(graph.Requires("/nodes/node-1"), DeepEquals, []string{"/init"})
+	c.Assert(graph.Requires("/nodes/node-2"), DeepEquals, []string{"/init"})
+	c.Assert(graph.Requires("/masters/master-1"), DeepEquals, []string{"/init"})
+	c.Assert(graph.Requires("/masters/master-2"), DeepEquals, []string{"/init", "/masters/master-1"})
+	c.Assert(graph.Requires("/app"), DeepEquals, []string{"/masters/master-1", "/masters/master-2"})
+	c.Assert(graph.Edges, DeepEquals, []PlanEdge{
+		{From: "/init", To: "/nodes"},
+		{From: "/init", To: "/masters"},
+		{From: "/masters/master-1", To: "/masters/master-2", Implicit: true},
+		{From: "/masters", To: "/app", Implicit: true},
+	})
+	c.Assert(graph.PhaseRequires("/masters"), DeepEquals, []string{"/init"})
+}
+
+func (s *GraphSuite) TestDetectsCycles(c *C) {
+	_, err := NewPlanGraph(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/a", Requires: []string{"/b"}},
+			{ID: "/b", Requires: []string{"/a"}},
+		},
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("expected BadParameter, got %v", err))
+}
+
+func (s *GraphSuite) TestExecutesIndependentPhasesInParallel(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/node-1", Requires: []string{"/init"}},
+			{ID: "/node-2", Requires: []string{"/init"}},
+			{ID: "/node-3", Requires: []string{"/init"}},
+			{ID: "/app", Requires: []string{"/node-1", "/node-2", "/node-3"}},
+		},
+	})
+	// node phases only complete once all of them have started
+	engine.barrier("/node-1", "/node-2", "/node-3")
+	machine, err := New(Config{Engine: engine, Parallel: 3})
+	c.Assert(err, IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), utils.DiscardProgress)
+	c.Assert(err, IsNil)
+	plan, err := engine.GetPlan()
+	c.Assert(err, IsNil)
+	c.Assert(IsCompleted(plan), Equals, true)
+	c.Assert(engine.executed[0], Equals, "/init")
+	c.Assert(engine.executed[4], Equals, "/app")
+}
+
+func (s *GraphSuite) TestResumesFailedPlan(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/node-1", Requires: []string{"/init"}},
+			{ID: "/node-2", Requires: []string{"/node-1"}},
+		},
+	})
+	engine.failures["/node-1"] = trace.ConnectionProblem(nil, "node is unavailable")
+	machine, err := New(Config{Engine: engine, Parallel: 2})
+	c.Assert(err, IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), utils.DiscardProgress)
+	c.Assert(err, NotNil)
+	c.Assert(engine.executed, DeepEquals, []string{"/init", "/node-1"})
+
+	delete(engine.failures, "/node-1")
+	err = machine.ExecutePlan(context.TODO(), utils.DiscardProgress)
+	c.Assert(err, IsNil)
+	c.Assert(engine.executed, DeepEquals, []string{"/init", "/node-1", "/node-1", "/node-2"})
+}
+
+func newTestEngine(plan storage.OperationPlan) *testEngine {
+	return &testEngine{
+		plan:     plan,
+		failures: make(map[string]error),
+		barriers: make(map[string]*sync.WaitGroup),
+	}
+}
+
+// testEngine is an in-memory FSM engine that records executed phases
+type testEngine struct {
+	sync.Mutex
+	plan     storage.OperationPlan
+	executed []string
+	failures map[string]error
+	barriers map[string]*sync.WaitGroup
+}
+
+// barrier makes each of the specified phases wait for all others to start
+func (e *testEngine) barrier(phaseIDs ...string) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(phaseIDs))
+	for _, phaseID := range phaseIDs {
+		e.barriers[phaseID] = wg
+	}
+}
+
+func (e *testEngine) GetExecutor(p ExecutorParams, remote Remote) (PhaseExecutor, error) {
+	return &testExecutor{
+		FieldLogger: logrus.WithField("phase", p.Phase.ID),
+		engine:      e,
+		phaseID:     p.Phase.ID,
+	}, nil
+}
+
+func (e *testEngine) ChangePhaseState(ctx context.Context, change StateChange) error {
+	e.Lock()
+	defer e.Unlock()
+	phase, err := FindPhase(&e.plan, change.Phase)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	phase.State = change.State
+	return nil
+}
+
+func (e *testEngine) GetPlan() (*storage.OperationPlan, error) {
+	e.Lock()
+	defer e.Unlock()
+	plan := e.plan
+	plan.Phases = copyPhases(e.plan.Phases)
+	return &plan, nil
+}
+
+func (e *testEngine) RunCommand(context.Context, rpc.RemoteRunner, storage.Server, Params) error {
+	return trace.NotImplemented("not implemented")
+}
+
+func (e *testEngine) Complete(error) error {
+	return nil
+}
+
+func (e *testEngine) execute(phaseID string) error {
+	e.Lock()
+	e.executed = append(e.executed, phaseID)
+	wg := e.barriers[phaseID]
+	err := e.failures[phaseID]
+	e.Unlock()
+	if wg != nil {
+		wg.Done()
+		wg.Wait()
+	}
+	return err
+}
+
+type testExecutor struct {
+	logrus.FieldLogger
+	engine  *testEngine
+	phaseID string
+}
+
+func (r *testExecutor) PreCheck(context.Context) error  { return nil }
+func (r *testExecutor) PostCheck(context.Context) error { return nil }
+func (r *testExecutor) Rollback(context.Context) error  { return nil }
+func (r *testExecutor) Execute(context.Context) error {
+	return r.engine.execute(r.phaseID)
+}
+
+func copyPhases(phases []storage.OperationPhase) []storage.OperationPhase {
+	result := make([]storage.OperationPhase, len(phases))
+	for i, phase := range phases {
+		result[i] = phase
+		result[i].Phases = copyPhases(phase.Phases)
+	}
+	return result
+}
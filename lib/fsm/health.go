@@ -0,0 +1,208 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/gravitational/trace"
+)
+
+// HealthGate is implemented by phase executors that declare the health
+// checks to verify after the phase has been executed.
+//
+// The FSM evaluates the checks with retries after the phase post-check
+// and only marks the phase completed once all of them pass.
+// If the checks do not pass within the timeout, the phase is marked failed
+// and the failed checks are recorded in the phase error
+type HealthGate interface {
+	// HealthChecks returns the health checks to verify after the phase
+	HealthChecks() HealthChecks
+}
+
+// HealthCheck is a single condition verified after a phase has been executed
+type HealthCheck interface {
+	// Name describes the condition this check verifies
+	Name() string
+	// Check returns an error if the condition is not satisfied
+	Check(context.Context) error
+}
+
+// HealthChecks is a set of health checks evaluated together
+type HealthChecks struct {
+	// Checks lists the health checks to verify
+	Checks []HealthCheck
+	// Timeout is the maximum amount of time to wait for the checks to pass.
+	// Defaults to defaults.PhaseHealthTimeout
+	Timeout time.Duration
+	// Interval is the interval between the attempts.
+	// Defaults to defaults.RetryInterval
+	Interval time.Duration
+}
+
+// Verify evaluates the health checks until all of them pass or the timeout expires.
+// Returns an error with the failed checks attached if the checks did not pass in time
+func (r HealthChecks) Verify(ctx context.Context) error {
+	if len(r.Checks) == 0 {
+		return nil
+	}
+	timeout, interval := r.Timeout, r.Interval
+	if timeout == 0 {
+		timeout = defaults.PhaseHealthTimeout
+	}
+	if interval == 0 {
+		interval = defaults.RetryInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var failures []HealthCheckFailure
+	err := utils.RetryWithInterval(ctx, backoff.NewConstantBackOff(interval), func() error {
+		failures = r.check(ctx)
+		if len(failures) != 0 {
+			return &HealthCheckError{Failures: failures}
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	if len(failures) == 0 {
+		return trace.Wrap(err)
+	}
+	return newHealthCheckError(failures)
+}
+
+func (r HealthChecks) check(ctx context.Context) (failures []HealthCheckFailure) {
+	for _, check := range r.Checks {
+		if err := check.Check(ctx); err != nil {
+			failures = append(failures, HealthCheckFailure{
+				Check:  check.Name(),
+				Reason: trace.UserMessage(err),
+			})
+		}
+	}
+	return failures
+}
+
+// HealthCheckFailure describes a failed health check
+type HealthCheckFailure struct {
+	// Check is the name of the failed check
+	Check string `json:"check"`
+	// Reason describes why the check failed
+	Reason string `json:"reason"`
+}
+
+// String returns a textual representation of this failure
+func (r HealthCheckFailure) String() string {
+	return fmt.Sprintf("%v: %v", r.Check, r.Reason)
+}
+
+// HealthCheckError is returned when the phase health checks fail
+type HealthCheckError struct {
+	// Failures lists the failed health checks
+	Failures []HealthCheckFailure
+}
+
+// Error returns the textual representation of this error
+func (r *HealthCheckError) Error() string {
+	var reasons []string
+	for _, failure := range r.Failures {
+		reasons = append(reasons, failure.String())
+	}
+	return fmt.Sprintf("health checks failed: %v", strings.Join(reasons, "; "))
+}
+
+// HealthCheckFailures returns the health checks the specified phase has failed with.
+// Returns nil if the phase has not failed due to health checks
+func HealthCheckFailures(phase storage.OperationPhase) []HealthCheckFailure {
+	if phase.Error == nil || phase.Error.Fields == nil {
+		return nil
+	}
+	value, ok := phase.Error.Fields[healthChecksField]
+	if !ok {
+		return nil
+	}
+	// the error fields lose their type once the phase has been stored
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var failures []HealthCheckFailure
+	if err := json.Unmarshal(bytes, &failures); err != nil {
+		return nil
+	}
+	return failures
+}
+
+// HealthCheckFunc returns a health check with the specified name
+// that verifies the condition using the provided function
+func HealthCheckFunc(name string, fn func(context.Context) error) HealthCheck {
+	return healthCheckFunc{name: name, fn: fn}
+}
+
+// Name describes the condition this check verifies
+func (r healthCheckFunc) Name() string {
+	return r.name
+}
+
+// Check returns an error if the condition is not satisfied
+func (r healthCheckFunc) Check(ctx context.Context) error {
+	return r.fn(ctx)
+}
+
+type healthCheckFunc struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// newHealthCheckError returns a new error for the specified failed checks.
+// The failures are attached to the error as a field so they are preserved
+// in the phase error and can be displayed by 'gravity plan'
+func newHealthCheckError(failures []HealthCheckFailure) trace.Error {
+	err := trace.Wrap(&HealthCheckError{Failures: failures})
+	if traceErr, ok := err.(*trace.TraceErr); ok {
+		return traceErr.AddField(healthChecksField, failures)
+	}
+	return err
+}
+
+// verifyHealth evaluates the health checks declared by the executor, if any
+func verifyHealth(ctx context.Context, executor PhaseExecutor) error {
+	gate, ok := executor.(HealthGate)
+	if !ok {
+		return nil
+	}
+	checks := gate.HealthChecks()
+	if len(checks.Checks) == 0 {
+		return nil
+	}
+	executor.Infof("Verify %v health checks.", len(checks.Checks))
+	return trace.Wrap(checks.Verify(ctx))
+}
+
+// healthChecksField is the name of the phase error field
+// with the failed health checks
+const healthChecksField = "health_checks"
@@ -0,0 +1,195 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type HealthSuite struct{}
+
+var _ = Suite(&HealthSuite{})
+
+func (s *HealthSuite) TestRetriesUntilChecksPass(c *C) {
+	var attempts int
+	checks := HealthChecks{
+		Checks: []HealthCheck{
+			HealthCheckFunc("node is ready", func(context.Context) error {
+				attempts++
+				if attempts < 3 {
+					return trace.CompareFailed("not yet")
+				}
+				return nil
+			}),
+		},
+		Timeout:  time.Second,
+		Interval: time.Millisecond,
+	}
+	c.Assert(checks.Verify(context.TODO()), IsNil)
+	c.Assert(attempts, Equals, 3)
+}
+
+func (s *HealthSuite) TestPreservesFailedChecksInPhaseError(c *C) {
+	checks := HealthChecks{
+		Checks: []HealthCheck{
+			HealthCheckFunc("etcd cluster is healthy", func(context.Context) error {
+				return nil
+			}),
+			HealthCheckFunc("node node-1 is ready", func(context.Context) error {
+				return trace.CompareFailed("node is not ready: kubelet stopped posting status")
+			}),
+		},
+		Timeout:  10 * time.Millisecond,
+		Interval: time.Millisecond,
+	}
+	err := checks.Verify(context.TODO())
+	c.Assert(err, ErrorMatches, ".*node node-1 is ready: node is not ready.*")
+
+	// emulate storing the phase error in the backend
+	bytes, err := json.Marshal(utils.ToRawTrace(trace.Wrap(err)))
+	c.Assert(err, IsNil)
+	var phase storage.OperationPhase
+	c.Assert(json.Unmarshal(bytes, &phase.Error), IsNil)
+
+	c.Assert(HealthCheckFailures(phase), DeepEquals, []HealthCheckFailure{
+		{
+			Check:  "node node-1 is ready",
+			Reason: "node is not ready: kubelet stopped posting status",
+		},
+	})
+}
+
+func (s *HealthSuite) TestFailsPhaseOnHealthChecks(c *C) {
+	engine := &healthGatedEngine{
+		testEngine: newTestEngine(storage.OperationPlan{
+			Phases: []storage.OperationPhase{{ID: "/uncordon"}},
+		}),
+	}
+	fsm, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	err = fsm.ExecutePhase(context.TODO(), Params{PhaseID: "/uncordon"})
+	c.Assert(err, NotNil)
+
+	plan, err := engine.GetPlan()
+	c.Assert(err, IsNil)
+	phase := plan.Phases[0]
+	c.Assert(phase.State, Equals, storage.OperationPhaseStateFailed)
+	c.Assert(HealthCheckFailures(phase), DeepEquals, []HealthCheckFailure{
+		{Check: "node node-1 is ready", Reason: "node is not ready: NotReady"},
+	})
+}
+
+func (s *HealthSuite) TestChecksNodeReady(c *C) {
+	node := v1.Node{
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionFalse, Message: "NotReady"},
+			},
+		},
+	}
+	c.Assert(checkNodeReady(node), ErrorMatches, "node is not ready: NotReady")
+	node.Status.Conditions[0].Status = v1.ConditionTrue
+	c.Assert(checkNodeReady(node), IsNil)
+	c.Assert(checkNodeReady(v1.Node{}), ErrorMatches, "node has not reported its status")
+}
+
+func (s *HealthSuite) TestChecksPodsHealthy(c *C) {
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns"},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{Name: "coredns", Ready: true}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "hook"},
+			Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	}
+	c.Assert(checkPodsHealthy(pods), IsNil)
+
+	pods = append(pods,
+		v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{Name: "nginx", Ready: false}},
+			},
+		},
+		v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db"},
+			Status:     v1.PodStatus{Phase: v1.PodPending},
+		})
+	c.Assert(checkPodsHealthy(pods), ErrorMatches,
+		"web is not ready \\(container nginx\\), db is pending")
+}
+
+// healthGatedEngine returns executors that declare a failing health check
+type healthGatedEngine struct {
+	*testEngine
+}
+
+func (e *healthGatedEngine) GetExecutor(p ExecutorParams, remote Remote) (PhaseExecutor, error) {
+	return &healthGatedExecutor{
+		testExecutor: testExecutor{
+			FieldLogger: logrus.WithField("phase", p.Phase.ID),
+			engine:      e.testEngine,
+			phaseID:     p.Phase.ID,
+		},
+	}, nil
+}
+
+func (e *healthGatedEngine) ChangePhaseState(ctx context.Context, change StateChange) error {
+	e.Lock()
+	defer e.Unlock()
+	phase, err := FindPhase(&e.plan, change.Phase)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	phase.State = change.State
+	phase.Error = utils.ToRawTrace(change.Error)
+	return nil
+}
+
+type healthGatedExecutor struct {
+	testExecutor
+}
+
+func (r *healthGatedExecutor) HealthChecks() HealthChecks {
+	return HealthChecks{
+		Checks: []HealthCheck{
+			HealthCheckFunc("node node-1 is ready", func(context.Context) error {
+				return trace.CompareFailed("node is not ready: NotReady")
+			}),
+		},
+		Timeout:  10 * time.Millisecond,
+		Interval: time.Millisecond,
+	}
+}
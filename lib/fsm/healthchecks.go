@@ -0,0 +1,176 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// NodeReady returns a health check that verifies that the specified
+// Kubernetes node is Ready
+func NodeReady(client corev1.NodesGetter, name string) HealthCheck {
+	return HealthCheckFunc(fmt.Sprintf("node %v is ready", name), func(context.Context) error {
+		node, err := client.Nodes().Get(name, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		return trace.Wrap(checkNodeReady(*node))
+	})
+}
+
+// PodsHealthy returns a health check that verifies that all pods
+// in the specified namespace are either running with all containers
+// ready or have completed successfully
+func PodsHealthy(client corev1.PodsGetter, namespace string) HealthCheck {
+	return HealthCheckFunc(fmt.Sprintf("pods in namespace %v are healthy", namespace), func(context.Context) error {
+		pods, err := client.Pods(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		return trace.Wrap(checkPodsHealthy(pods.Items))
+	})
+}
+
+// EtcdHealthy returns a health check that verifies the etcd cluster health
+// using etcdctl inside the local planet container
+func EtcdHealthy(logger logrus.FieldLogger) HealthCheck {
+	return HealthCheckFunc("etcd cluster is healthy", func(ctx context.Context) error {
+		out, err := utils.RunCommand(ctx, logger,
+			utils.PlanetCommandArgs(defaults.EtcdCtlBin, "cluster-health")...)
+		if err != nil {
+			return trace.Wrap(err, "etcd cluster is unhealthy: %s", out)
+		}
+		return nil
+	})
+}
+
+// PlanetRunning returns a health check that verifies that the planet
+// agents report the cluster running and all specified nodes healthy
+func PlanetRunning(servers []storage.Server) HealthCheck {
+	return HealthCheckFunc("planet agents report cluster running", func(ctx context.Context) error {
+		agent, err := status.FromPlanetAgent(ctx, servers)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(CheckPlanetStatus(*agent))
+	})
+}
+
+// HTTPProbe returns a health check that verifies that the specified URL
+// responds with a successful status code.
+// If client is nil, the default HTTP client is used
+func HTTPProbe(client *http.Client, url string) HealthCheck {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return HealthCheckFunc(fmt.Sprintf("%v responds", url), func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+			return trace.BadParameter("unexpected status: %v", resp.Status)
+		}
+		return nil
+	})
+}
+
+func checkNodeReady(node v1.Node) error {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != v1.NodeReady {
+			continue
+		}
+		if condition.Status == v1.ConditionTrue {
+			return nil
+		}
+		return trace.CompareFailed("node is not ready: %v", condition.Message)
+	}
+	return trace.CompareFailed("node has not reported its status")
+}
+
+func checkPodsHealthy(pods []v1.Pod) error {
+	var unhealthy []string
+	for _, pod := range pods {
+		if reason := podUnhealthyReason(pod); reason != "" {
+			unhealthy = append(unhealthy, fmt.Sprintf("%v is %v", pod.Name, reason))
+		}
+	}
+	if len(unhealthy) != 0 {
+		return trace.CompareFailed("%v", strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+func podUnhealthyReason(pod v1.Pod) string {
+	switch pod.Status.Phase {
+	case v1.PodSucceeded:
+		return ""
+	case v1.PodRunning:
+	default:
+		return strings.ToLower(string(pod.Status.Phase))
+	}
+	for _, container := range pod.Status.ContainerStatuses {
+		if !container.Ready {
+			return fmt.Sprintf("not ready (container %v)", container.Name)
+		}
+	}
+	return ""
+}
+
+// CheckPlanetStatus returns an error if the specified planet agent status
+// reports any degraded nodes or the cluster not running
+func CheckPlanetStatus(agent status.Agent) error {
+	var degraded []string
+	for _, node := range agent.Nodes {
+		if node.Status == status.NodeHealthy {
+			continue
+		}
+		detail := fmt.Sprintf("%v is %v", node.Hostname, node.Status)
+		if len(node.FailedProbes) != 0 {
+			detail = fmt.Sprintf("%v: %v", detail, strings.Join(node.FailedProbes, ", "))
+		}
+		degraded = append(degraded, detail)
+	}
+	if len(degraded) != 0 {
+		return trace.CompareFailed("cluster is degraded: %v", strings.Join(degraded, "; "))
+	}
+	if agent.GetSystemStatus() != agentpb.SystemStatus_Running {
+		return trace.CompareFailed("cluster is %v", agent.SystemStatus)
+	}
+	return nil
+}
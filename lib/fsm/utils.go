@@ -99,6 +99,20 @@ func FlattenPlan(plan *storage.OperationPlan) []*storage.OperationPhase {
 	return result
 }
 
+// RollbackPhases returns the leaf phases of the plan that have been started
+// and have not been rolled back yet, in the reverse order of their execution
+func RollbackPhases(plan *storage.OperationPlan) (result []storage.OperationPhase) {
+	phases := FlattenPlan(plan)
+	for i := len(phases) - 1; i >= 0; i-- {
+		phase := phases[i]
+		if phase.HasSubphases() || phase.IsUnstarted() || phase.IsRolledBack() {
+			continue
+		}
+		result = append(result, *phase)
+	}
+	return result
+}
+
 // SplitServers splits the specified server list into servers with master cluster role
 // and regular nodes.
 func SplitServers(servers []storage.Server) (masters, nodes []storage.Server) {
@@ -140,6 +154,10 @@ func ResolvePlan(plan storage.OperationPlan, changelog storage.PlanChangelog) *s
 			allPhases[i].Updated = latest.Created
 			allPhases[i].Error = latest.Error
 		}
+		started := changelog.LatestWithState(phase.ID, storage.OperationPhaseStateInProgress)
+		if started != nil {
+			allPhases[i].Started = started.Created
+		}
 	}
 	return &plan
 }
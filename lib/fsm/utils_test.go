@@ -0,0 +1,61 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+type UtilsSuite struct{}
+
+var _ = Suite(&UtilsSuite{})
+
+func (s *UtilsSuite) TestRollbackPhasesInReverseOrder(c *C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init", State: storage.OperationPhaseStateCompleted},
+			{
+				ID: "/masters",
+				Phases: []storage.OperationPhase{
+					{ID: "/masters/node-1", State: storage.OperationPhaseStateCompleted},
+					{ID: "/masters/node-2", State: storage.OperationPhaseStateRolledBack},
+				},
+			},
+			{
+				ID: "/nodes",
+				Phases: []storage.OperationPhase{
+					{ID: "/nodes/node-3", State: storage.OperationPhaseStateCompleted},
+					{ID: "/nodes/node-4", State: storage.OperationPhaseStateFailed},
+					{ID: "/nodes/node-5", State: storage.OperationPhaseStateUnstarted},
+				},
+			},
+			{ID: "/app", State: storage.OperationPhaseStateUnstarted},
+		},
+	}
+	var ids []string
+	for _, phase := range RollbackPhases(&plan) {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, DeepEquals, []string{
+		"/nodes/node-4",
+		"/nodes/node-3",
+		"/masters/node-1",
+		"/init",
+	})
+}
@@ -17,6 +17,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 
@@ -25,6 +26,7 @@ import (
 	installpb "github.com/gravitational/gravity/lib/install/proto"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/system/signals"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -84,6 +86,25 @@ func (r *Client) SetPhase(ctx context.Context, phase Phase, state string) error
 	return trace.Wrap(err)
 }
 
+// DryRunPhase describes the changes the specified phase would make if executed
+func (r *Client) DryRunPhase(ctx context.Context, phase Phase) ([]storage.PhaseDryRun, error) {
+	r.WithField("phase", phase).Info("Dry-run.")
+	resp, err := r.client.DryRun(ctx, &installpb.DryRunRequest{
+		Phase: &installpb.Phase{
+			Key: installpb.KeyToProto(phase.Key),
+			ID:  phase.ID,
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var phases []storage.PhaseDryRun
+	if err := json.Unmarshal(resp.Phases, &phases); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return phases, nil
+}
+
 // RollbackPhase rolls back the specified phase
 func (r *Client) RollbackPhase(ctx context.Context, phase Phase) error {
 	r.WithField("phase", phase).Info("Rollback.")
@@ -157,7 +157,7 @@ func (r *executor) upsertClusterAndOperation() (*ops.SiteOperation, error) {
 	}
 	var operation *ops.SiteOperation
 	if len(operations) == 0 {
-		operation, err = r.createOperation()
+		operation, err = r.createOperation(cluster.Key())
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -167,7 +167,11 @@ func (r *executor) upsertClusterAndOperation() (*ops.SiteOperation, error) {
 	return operation, nil
 }
 
-func (r *executor) createOperation() (*ops.SiteOperation, error) {
+func (r *executor) createOperation(clusterKey ops.SiteKey) (*ops.SiteOperation, error) {
+	err := libinstall.UpsertTrustPolicy(r.ctx, r.Operator, clusterKey, r.config.ClusterResources)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	key, err := r.Operator.CreateSiteInstallOperation(r.ctx, ops.CreateSiteInstallOperationRequest{
 		SiteDomain: r.config.SiteDomain,
 		AccountID:  defaults.SystemAccountID,
@@ -32,6 +32,7 @@ import (
 	"github.com/gravitational/gravity/lib/install/server"
 	"github.com/gravitational/gravity/lib/ops"
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/system/signals"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -137,6 +138,17 @@ func (i *Installer) SetPhase(req *installpb.SetStateRequest) error {
 	})
 }
 
+// DryRun describes the changes the phase given with req would make if executed.
+// Implements server.DryRunner
+func (i *Installer) DryRun(req *installpb.DryRunRequest) ([]storage.PhaseDryRun, error) {
+	i.WithField("req", req).Info("Dry-run.")
+	machine, err := i.config.FSMFactory.NewFSM(i.config.Operator, req.OperationKey())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return machine.DryRunPhase(i.ctx, req.Phase.ID)
+}
+
 // Complete manually completes the operation given with key.
 // Implements server.Executor
 func (i *Installer) Complete(key ops.SiteOperationKey) error {
@@ -92,6 +92,15 @@ func (p *hookExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the application hooks that would run
+func (p *hookExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, hook := range p.Hooks {
+		report.AddCommand("run %v hook for %v", hook, *p.Phase.Data.Package)
+	}
+	return &report, nil
+}
+
 // runHooks runs specified app hooks
 func (p *hookExecutor) runHooks(ctx context.Context, hooks ...schema.HookType) error {
 	for _, hook := range hooks {
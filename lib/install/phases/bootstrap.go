@@ -145,6 +145,36 @@ func (p *bootstrapExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the system directories and application volumes
+// created on the node and the local state changes made by this phase
+func (p *bootstrapExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	dockerConfig, err := p.getDockerConfig()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	node := p.Phase.Data.Server
+	if dockerConfig.StorageDriver == constants.DockerStorageDriverDevicemapper && node.Docker.Device.Path() != "" {
+		report.AddCommand("configure %v for Docker devicemapper storage driver", node.Docker.Device.Path())
+	}
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, dir := range systemDirectories(stateDir) {
+		report.AddFile(dir)
+	}
+	mounts, err := opsservice.GetMounts(p.Application.Manifest, *node)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, mount := range mounts {
+		report.AddFile(mount.Source)
+	}
+	report.AddCommand("create login entry for agent user %v", p.Phase.Data.Agent.Email)
+	return report.AddCommand("set cluster DNS configuration to %v", p.dnsConfig), nil
+}
+
 // getDockerConfig returns Docker configuration merged from the application
 // manifest and operation variables
 func (p *bootstrapExecutor) getDockerConfig() (*schema.Docker, error) {
@@ -187,27 +217,7 @@ func (p *bootstrapExecutor) configureSystemDirectories() error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	mkdirList := []string{
-		filepath.Join(stateDir, "local", "packages", "blobs"),
-		filepath.Join(stateDir, "local", "packages", "unpacked"),
-		filepath.Join(stateDir, "local", "packages", "tmp"),
-		filepath.Join(stateDir, "teleport", "auth"),
-		filepath.Join(stateDir, "teleport", "node"),
-		filepath.Join(stateDir, "planet", "state"),
-		filepath.Join(stateDir, "planet", "etcd"),
-		filepath.Join(stateDir, "planet", "registry"),
-		filepath.Join(stateDir, "planet", "docker"),
-		filepath.Join(stateDir, "planet", "kubelet"),
-		filepath.Join(stateDir, "planet", "share", "hooks"),
-		filepath.Join(stateDir, "planet", "log", "journal"),
-		filepath.Join(stateDir, "site", "teleport", "log"),
-		filepath.Join(stateDir, "site", "packages", "unpacked"),
-		filepath.Join(stateDir, "site", "packages", "blobs"),
-		filepath.Join(stateDir, "site", "packages", "tmp"),
-		filepath.Join(stateDir, "secrets"),
-		filepath.Join(stateDir, "backup"),
-	}
-	for _, dir := range mkdirList {
+	for _, dir := range systemDirectories(stateDir) {
 		p.Infof("Creating system directory %v.", dir)
 		err := os.MkdirAll(dir, defaults.SharedDirMask)
 		if err != nil {
@@ -352,6 +362,31 @@ func (*bootstrapExecutor) PostCheck(ctx context.Context) error {
 	return nil
 }
 
+// systemDirectories returns the system directories created
+// in the specified state directory
+func systemDirectories(stateDir string) []string {
+	return []string{
+		filepath.Join(stateDir, "local", "packages", "blobs"),
+		filepath.Join(stateDir, "local", "packages", "unpacked"),
+		filepath.Join(stateDir, "local", "packages", "tmp"),
+		filepath.Join(stateDir, "teleport", "auth"),
+		filepath.Join(stateDir, "teleport", "node"),
+		filepath.Join(stateDir, "planet", "state"),
+		filepath.Join(stateDir, "planet", "etcd"),
+		filepath.Join(stateDir, "planet", "registry"),
+		filepath.Join(stateDir, "planet", "docker"),
+		filepath.Join(stateDir, "planet", "kubelet"),
+		filepath.Join(stateDir, "planet", "share", "hooks"),
+		filepath.Join(stateDir, "planet", "log", "journal"),
+		filepath.Join(stateDir, "site", "teleport", "log"),
+		filepath.Join(stateDir, "site", "packages", "unpacked"),
+		filepath.Join(stateDir, "site", "packages", "blobs"),
+		filepath.Join(stateDir, "site", "packages", "tmp"),
+		filepath.Join(stateDir, "secrets"),
+		filepath.Join(stateDir, "backup"),
+	}
+}
+
 func opKey(plan storage.OperationPlan) ops.SiteOperationKey {
 	return ops.SiteOperationKey{
 		AccountID:   plan.AccountID,
@@ -71,6 +71,11 @@ func (r *checksExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only runs the preflight checks
+func (r *checksExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 // Rollback is a no-op for this phase
 func (r *checksExecutor) Rollback(context.Context) error {
 	return nil
@@ -77,6 +77,12 @@ func (p *configureExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the cluster configuration packages generated by this phase
+func (p *configureExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("generate configuration packages for cluster %v", p.Plan.ClusterName), nil
+}
+
 // Rollback is no-op for this phase
 func (p *configureExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -148,6 +148,22 @@ func (p *connectExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the certificate authorities exchanged by this phase
+func (p *connectExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	trustedCluster, err := storage.UnmarshalTrustedCluster(p.Phase.Data.TrustedCluster)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	for _, caType := range []services.CertAuthType{services.HostCA, services.UserCA} {
+		report.AddCommand("upsert %v certificate authority of %v in %v",
+			caType, p.Plan.ClusterName, trustedCluster.GetName())
+		report.AddCommand("upsert %v certificate authority of %v in %v",
+			caType, trustedCluster.GetName(), p.Plan.ClusterName)
+	}
+	return &report, nil
+}
+
 func (p *connectExecutor) getAuthClient(ctx context.Context, operator ops.Operator, proxyHost, clusterName string) (client *clients.AuthClient, err error) {
 	// Retry a few times to account for possible network errors.
 	err = utils.RetryOnNetworkError(defaults.RetryInterval, defaults.RetryLessAttempts, func() error {
@@ -126,6 +126,12 @@ func (r *corednsExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the CoreDNS configuration created by this phase
+func (r *corednsExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddObject("configmap", "coredns"), nil
+}
+
 func mergeUpstreamResolvers(configs ...*storage.ResolvConf) []string {
 	var upstreams []string
 	dedup := make(map[string]bool)
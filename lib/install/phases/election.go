@@ -73,6 +73,17 @@ func (p *enableElectionExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the commands that resume leader election on master nodes
+func (p *enableElectionExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, server := range p.Plan.Servers {
+		if server.ClusterRole == string(schema.ServiceRoleMaster) {
+			report.AddCommand("%v", ops.EnableLeaderElectionCommand(p.Plan.ClusterName, server))
+		}
+	}
+	return &report, nil
+}
+
 // Rollback is no-op for this phase
 func (*enableElectionExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -120,6 +120,22 @@ func (p *exportExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the application packages unpacked on the node and
+// exported to the local registry
+func (p *exportExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	app, err := p.Apps.GetApp(*p.Phase.Data.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	locators := append(app.Manifest.Dependencies.GetApps(), app.Package)
+	for _, locator := range locators {
+		report.AddFile(p.packagePath(locator))
+		report.AddCommand("export %v to %v", locator, constants.LocalRegistryAddr)
+	}
+	return &report, nil
+}
+
 // Rollback is no-op for this phase
 func (*exportExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -74,6 +74,21 @@ func (p *initExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the fio binary exported to the node.
+func (p *initExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	locator, err := p.Application.Manifest.Dependencies.ByName(constants.FioPackage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	path, err := state.InStateDir(constants.FioBin)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	report.AddPackage("%v", *locator)
+	return report.AddFile(path), nil
+}
+
 // downloadFio downloads fio tool from the configured package service and
 // places it in a temporary directory on the node.
 func (p *initExecutor) downloadFio() error {
@@ -81,6 +81,13 @@ func (r *openebs) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the OpenEBS namespace and configuration created by this phase.
+func (r *openebs) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddObject("namespace", defaults.OpenEBSNamespace)
+	return report.AddObject("configmap", constants.OpenEBSNDMConfigMap), nil
+}
+
 // createNamespace creates OpenEBS namespace if it doesn't exist.
 func (r *openebs) createNamespace() error {
 	_, err := r.Client.CoreV1().Namespaces().Create(&v1.Namespace{
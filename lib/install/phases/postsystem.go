@@ -20,6 +20,7 @@ import (
 	"archive/tar"
 	"context"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
@@ -36,7 +37,9 @@ import (
 	dockerarchive "github.com/docker/docker/pkg/archive"
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -83,6 +86,11 @@ func (p *waitExecutor) Execute(ctx context.Context) error {
 	}
 }
 
+// DryRun reports no changes as this phase only waits for the Kubernetes API
+func (p *waitExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 // waitForAPI tries to query the kubernetes API in a loop until it gets a successful result
 func (p *waitExecutor) waitForAPI(ctx context.Context, done chan bool) {
 	timer := time.NewTicker(1 * time.Second)
@@ -189,6 +197,11 @@ func (p *healthExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only waits for planet to start
+func (p *healthExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 // Rollback is no-op for this phase
 func (*healthExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -240,6 +253,34 @@ type rbacExecutor struct {
 // Execute executes the rbac phase
 func (p *rbacExecutor) Execute(ctx context.Context) error {
 	p.Progress.NextStep("Creating Kubernetes RBAC resources")
+	err := p.forEachResource(fsm.GetUpsertBootstrapResourceFunc(p.Client))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Info("Created Kubernetes RBAC resources.")
+	return nil
+}
+
+// DryRun describes the Kubernetes RBAC resources created by this phase
+func (p *rbacExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	err := p.forEachResource(func(object runtime.Object) error {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		kind := object.GetObjectKind().GroupVersionKind().Kind
+		report.AddObject(strings.ToLower(kind), accessor.GetName())
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &report, nil
+}
+
+// forEachResource invokes fn for each Kubernetes resource of the application
+func (p *rbacExecutor) forEachResource(fn resources.ResourceFunc) error {
 	reader, err := p.Apps.GetAppResources(*p.Phase.Data.Package)
 	if err != nil {
 		return trace.Wrap(err)
@@ -255,15 +296,9 @@ func (p *rbacExecutor) Execute(ctx context.Context) error {
 		defaults.ResourcesDir,
 		[]string{defaults.ResourcesFile},
 		func(_ string, reader io.Reader) error {
-			return resources.ForEachObject(
-				reader,
-				fsm.GetUpsertBootstrapResourceFunc(p.Client))
+			return resources.ForEachObject(reader, fn)
 		})
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	p.Info("Created Kubernetes RBAC resources.")
-	return nil
+	return trace.Wrap(err)
 }
 
 // Rollback is no-op for this phase
@@ -139,6 +139,32 @@ func (p *pullExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the packages pulled to the node and unpacked
+func (p *pullExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddPackage("%v", *p.Phase.Data.Package)
+	envelopes, err := p.collectConfiguredPackages()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, e := range envelopes {
+		report.AddPackage("%v", e.Locator)
+	}
+	report.AddPackage("%v", p.runtimePackage)
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report.AddFile(filepath.Join(stateDir, defaults.LocalDir))
+	for _, e := range envelopes {
+		if isSecret(e) {
+			report.AddFile(filepath.Join(stateDir, defaults.SecretsDir))
+			break
+		}
+	}
+	return &report, nil
+}
+
 func (p *pullExecutor) pullUserApplication() error {
 	p.Progress.NextStep("Pulling user application")
 	p.Info("Pulling user application.")
@@ -204,15 +230,10 @@ func (p *pullExecutor) applyPackageLabels() error {
 	return nil
 }
 
-func (p *pullExecutor) pullConfiguredPackages() (err error) {
+func (p *pullExecutor) pullConfiguredPackages() error {
 	p.Progress.NextStep("Pulling configured packages")
 	p.Info("Pulling configured packages.")
-	var envelopes []pack.PackageEnvelope
-	if p.Phase.Data.Server.ClusterRole == string(schema.ServiceRoleMaster) {
-		envelopes, err = p.collectMasterPackages()
-	} else {
-		envelopes, err = p.collectNodePackages()
-	}
+	envelopes, err := p.collectConfiguredPackages()
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -238,6 +259,14 @@ func (p *pullExecutor) pullConfiguredPackages() (err error) {
 	return nil
 }
 
+// collectConfiguredPackages returns the configured packages to pull to the node
+func (p *pullExecutor) collectConfiguredPackages() ([]pack.PackageEnvelope, error) {
+	if p.Phase.Data.Server.ClusterRole == string(schema.ServiceRoleMaster) {
+		return p.collectMasterPackages()
+	}
+	return p.collectNodePackages()
+}
+
 func (p *pullExecutor) unpackSecrets(e pack.PackageEnvelope) error {
 	stateDir, err := state.GetStateDir()
 	if err != nil {
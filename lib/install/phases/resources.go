@@ -82,6 +82,12 @@ func (r *systemResources) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the system Kubernetes resources created by this phase.
+func (r *systemResources) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddObject("configmap", constants.ClusterInfoMap), nil
+}
+
 // createClusterInfoMap creates a config map with cluster information to be
 // made available to every cluster hook.
 func (r *systemResources) createClusterInfoMap() error {
@@ -140,13 +146,12 @@ type userResourcesExecutor struct {
 
 // Execute executes the resources phase
 func (p *userResourcesExecutor) Execute(ctx context.Context) error {
-	const filename = "resources.yaml"
 	p.Progress.NextStep("Creating user-supplied Kubernetes resources")
 	stateDir, err := state.GetStateDir()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = ioutil.WriteFile(filepath.Join(state.ShareDir(stateDir), filename),
+	err = ioutil.WriteFile(filepath.Join(state.ShareDir(stateDir), userResourcesFile),
 		p.resources, defaults.SharedReadMask)
 	if err != nil {
 		return trace.Wrap(err, "failed to write user resources on disk")
@@ -159,7 +164,7 @@ func (p *userResourcesExecutor) Execute(ctx context.Context) error {
 		constants.PrivilegedKubeconfig,
 		"apply",
 		"-f",
-		filepath.Join(defaults.PlanetShareDir, filename),
+		filepath.Join(defaults.PlanetShareDir, userResourcesFile),
 	)
 	if err != nil {
 		return trace.Wrap(err, "failed to create user resources: %s", out)
@@ -168,6 +173,19 @@ func (p *userResourcesExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the user-supplied resources file and the command
+// that creates the resources
+func (p *userResourcesExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	report.AddFile(filepath.Join(state.ShareDir(stateDir), userResourcesFile))
+	return report.AddCommand("%v --kubeconfig %v apply -f %v", defaults.KubectlBin,
+		constants.PrivilegedKubeconfig, filepath.Join(defaults.PlanetShareDir, userResourcesFile)), nil
+}
+
 // Rollback is no-op for this phase
 func (*userResourcesExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -232,6 +250,15 @@ func (r *gravityExecutor) Execute(ctx context.Context) (err error) {
 	return nil
 }
 
+// DryRun describes the Gravity resources created by this phase
+func (r *gravityExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, resource := range r.resources {
+		report.AddCommand("create %v %v", resource.Kind, resource.Metadata.Name)
+	}
+	return &report, nil
+}
+
 // Rollback is no-op for this phase
 func (*gravityExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -255,3 +282,6 @@ type gravityExecutor struct {
 	operator  ops.Operator
 	resources []storage.UnknownResource
 }
+
+// userResourcesFile is the name of the file with user-supplied Kubernetes resources
+const userResourcesFile = "resources.yaml"
@@ -19,6 +19,7 @@ package phases
 import (
 	"bytes"
 	"context"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/fsm"
@@ -68,13 +69,7 @@ func (p *systemExecutor) Execute(ctx context.Context) error {
 	//               service which now lives in lib/update/system/system.go but
 	//               should be moved to some common location where both install
 	//               and upgrade can use it from.
-	args := []string{"--debug", "system", "reinstall", locator.String(),
-		"--cluster-role", p.Phase.Data.Server.ClusterRole}
-	if len(p.Phase.Data.Labels) != 0 {
-		labels := configure.KeyVal(p.Phase.Data.Labels)
-		args = append(args, "--labels", labels.String())
-	}
-	out, err := utils.RunGravityCommand(ctx, p.FieldLogger, args...)
+	out, err := utils.RunGravityCommand(ctx, p.FieldLogger, p.reinstallArgs()...)
 	output := string(bytes.TrimSpace(out))
 	if len(output) == 0 {
 		return trace.Wrap(err, "failed to install system service")
@@ -82,6 +77,24 @@ func (p *systemExecutor) Execute(ctx context.Context) error {
 	return trace.Wrap(err, "failed to install system service: %v", output)
 }
 
+// DryRun describes the command that installs the system service
+func (p *systemExecutor) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddPackage("%v", *p.Phase.Data.Package)
+	return report.AddCommand("gravity %v", strings.Join(p.reinstallArgs(), " ")), nil
+}
+
+// reinstallArgs returns the gravity command line that installs the system service
+func (p *systemExecutor) reinstallArgs() []string {
+	args := []string{"--debug", "system", "reinstall", p.Phase.Data.Package.String(),
+		"--cluster-role", p.Phase.Data.Server.ClusterRole}
+	if len(p.Phase.Data.Labels) != 0 {
+		labels := configure.KeyVal(p.Phase.Data.Labels)
+		args = append(args, "--labels", labels.String())
+	}
+	return args
+}
+
 // Rollback is no-op for this phase
 func (*systemExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -539,6 +539,87 @@ func (m *OperationKey) GetID() string {
 	return ""
 }
 
+// DryRunRequest describes a request to dry-run an operation plan phase
+type DryRunRequest struct {
+	// Phase describes the phase to dry-run.
+	// The root phase describes the whole plan
+	Phase                *Phase   `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DryRunRequest) Reset()         { *m = DryRunRequest{} }
+func (m *DryRunRequest) String() string { return proto.CompactTextString(m) }
+func (*DryRunRequest) ProtoMessage()    {}
+func (*DryRunRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_675879a591bd3155, []int{10}
+}
+func (m *DryRunRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DryRunRequest.Unmarshal(m, b)
+}
+func (m *DryRunRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DryRunRequest.Marshal(b, m, deterministic)
+}
+func (m *DryRunRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DryRunRequest.Merge(m, src)
+}
+func (m *DryRunRequest) XXX_Size() int {
+	return xxx_messageInfo_DryRunRequest.Size(m)
+}
+func (m *DryRunRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DryRunRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DryRunRequest proto.InternalMessageInfo
+
+func (m *DryRunRequest) GetPhase() *Phase {
+	if m != nil {
+		return m.Phase
+	}
+	return nil
+}
+
+// DryRunResponse describes the changes the phase would make if executed
+type DryRunResponse struct {
+	// Phases is the JSON-encoded list of phase dry-run results
+	Phases               []byte   `protobuf:"bytes,1,opt,name=phases,proto3" json:"phases,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DryRunResponse) Reset()         { *m = DryRunResponse{} }
+func (m *DryRunResponse) String() string { return proto.CompactTextString(m) }
+func (*DryRunResponse) ProtoMessage()    {}
+func (*DryRunResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_675879a591bd3155, []int{11}
+}
+func (m *DryRunResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DryRunResponse.Unmarshal(m, b)
+}
+func (m *DryRunResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DryRunResponse.Marshal(b, m, deterministic)
+}
+func (m *DryRunResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DryRunResponse.Merge(m, src)
+}
+func (m *DryRunResponse) XXX_Size() int {
+	return xxx_messageInfo_DryRunResponse.Size(m)
+}
+func (m *DryRunResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DryRunResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DryRunResponse proto.InternalMessageInfo
+
+func (m *DryRunResponse) GetPhases() []byte {
+	if m != nil {
+		return m.Phases
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("installer.ProgressResponse_Status", ProgressResponse_Status_name, ProgressResponse_Status_value)
 	proto.RegisterType((*Phase)(nil), "installer.Phase")
@@ -551,56 +632,60 @@ func init() {
 	proto.RegisterType((*ProgressResponse)(nil), "installer.ProgressResponse")
 	proto.RegisterType((*Error)(nil), "installer.Error")
 	proto.RegisterType((*OperationKey)(nil), "installer.OperationKey")
+	proto.RegisterType((*DryRunRequest)(nil), "installer.DryRunRequest")
+	proto.RegisterType((*DryRunResponse)(nil), "installer.DryRunResponse")
 }
 
 func init() { proto.RegisterFile("installer.proto", fileDescriptor_675879a591bd3155) }
 
 var fileDescriptor_675879a591bd3155 = []byte{
-	// 697 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcd, 0x4e, 0xdb, 0x4a,
-	0x14, 0xc6, 0x09, 0x0e, 0xf1, 0x09, 0x90, 0x30, 0x5c, 0x71, 0x73, 0xcd, 0x6d, 0x48, 0xbd, 0xa8,
-	0x52, 0xa9, 0x0a, 0x6d, 0xba, 0x41, 0x55, 0x55, 0x29, 0x24, 0x11, 0x8a, 0xa0, 0x49, 0x34, 0x29,
-	0xea, 0x12, 0x39, 0xf6, 0xc1, 0x44, 0x38, 0x1e, 0xd7, 0x1e, 0x0b, 0x90, 0xfa, 0x00, 0x88, 0x75,
-	0xb7, 0xac, 0xca, 0xa2, 0xfb, 0xee, 0xfa, 0x00, 0x7d, 0x0c, 0x16, 0x3c, 0x49, 0x65, 0x8f, 0x93,
-	0x38, 0xa9, 0x42, 0xd5, 0x9d, 0xcf, 0xcf, 0x77, 0xbe, 0xf3, 0xe3, 0x6f, 0x20, 0x3f, 0x74, 0x7c,
-	0xae, 0xdb, 0x36, 0x7a, 0x55, 0xd7, 0x63, 0x9c, 0x11, 0x65, 0xe2, 0x50, 0xb7, 0x2d, 0xc6, 0x2c,
-	0x1b, 0x77, 0xa3, 0xc0, 0x20, 0x38, 0xdd, 0xc5, 0x91, 0xcb, 0xaf, 0x44, 0x9e, 0x0a, 0x16, 0xb3,
-	0x98, 0xf8, 0xd6, 0x3e, 0x83, 0xdc, 0x3b, 0xd3, 0x7d, 0x24, 0x5b, 0x90, 0x1a, 0x9a, 0x45, 0xa9,
-	0x2c, 0x55, 0x94, 0xfd, 0xcc, 0xc3, 0xfd, 0x4e, 0xaa, 0xdd, 0xa4, 0xa9, 0xa1, 0x49, 0x9e, 0x43,
-	0xfa, 0x1c, 0xaf, 0x8a, 0xa9, 0xb2, 0x54, 0xc9, 0xd5, 0xfe, 0xad, 0x4e, 0x39, 0xbb, 0x2e, 0x7a,
-	0x3a, 0x1f, 0x32, 0xe7, 0x10, 0xaf, 0x68, 0x98, 0x43, 0x54, 0xc8, 0x7a, 0xcc, 0xb6, 0x07, 0xba,
-	0x71, 0x5e, 0x4c, 0x97, 0xa5, 0x4a, 0x96, 0x4e, 0x6c, 0xf2, 0x0f, 0xc8, 0xa7, 0xcc, 0x33, 0xb0,
-	0xb8, 0x1c, 0x05, 0x84, 0xa1, 0xed, 0xc1, 0x7a, 0xeb, 0x12, 0x8d, 0x80, 0x23, 0xc5, 0x4f, 0x01,
-	0xfa, 0x9c, 0x3c, 0x03, 0xd9, 0x0d, 0xfb, 0x89, 0x3a, 0xc9, 0xd5, 0x0a, 0x09, 0xc2, 0xa8, 0x4f,
-	0x2a, 0xc2, 0x5a, 0x17, 0xf2, 0x7d, 0xe4, 0x7d, 0xae, 0xff, 0x35, 0x34, 0x6c, 0xc5, 0x0f, 0x71,
-	0xd1, 0x4c, 0x0a, 0x15, 0x86, 0xf6, 0x16, 0xf2, 0x0d, 0x36, 0x72, 0x6d, 0x9c, 0x16, 0x8c, 0x47,
-	0x97, 0xfe, 0x3c, 0xba, 0xb6, 0x0e, 0xab, 0xf5, 0x01, 0xf3, 0x78, 0x0c, 0xd5, 0x0e, 0x21, 0xdf,
-	0x3f, 0x0b, 0xb8, 0xc9, 0x2e, 0x9c, 0x71, 0xb5, 0xff, 0x41, 0x31, 0x62, 0x02, 0xb1, 0xe7, 0x2c,
-	0x9d, 0x3a, 0xc2, 0xdd, 0xe1, 0xe5, 0x90, 0x37, 0x98, 0x29, 0xfa, 0x92, 0xe9, 0xc4, 0xd6, 0x2a,
-	0x40, 0x9a, 0x38, 0x08, 0x2c, 0x8a, 0xee, 0x94, 0x82, 0x10, 0x58, 0x76, 0x75, 0x7e, 0x26, 0x4e,
-	0x46, 0xa3, 0x6f, 0xed, 0x67, 0x0a, 0x0a, 0x3d, 0x8f, 0x59, 0x1e, 0xfa, 0x3e, 0x45, 0xdf, 0x65,
-	0x8e, 0x8f, 0xa4, 0x08, 0x2b, 0x23, 0xf4, 0x7d, 0xdd, 0xc2, 0x38, 0x77, 0x6c, 0x92, 0x37, 0x90,
-	0x09, 0x87, 0x0f, 0xfc, 0x88, 0x72, 0xbd, 0xa6, 0x25, 0x57, 0x36, 0x57, 0xa6, 0xda, 0x8f, 0x32,
-	0x69, 0x8c, 0x08, 0xb7, 0x8d, 0x9e, 0xc7, 0xbc, 0xe8, 0xd2, 0xb3, 0xdb, 0x6e, 0x85, 0x7e, 0x2a,
-	0xc2, 0xda, 0x77, 0x09, 0x32, 0x02, 0x4a, 0x4a, 0xb0, 0x72, 0xdc, 0x39, 0xec, 0x74, 0x3f, 0x76,
-	0x0a, 0x4b, 0xea, 0xc6, 0xcd, 0x6d, 0x79, 0x4d, 0x04, 0x8e, 0x9d, 0x73, 0x87, 0x5d, 0x38, 0x44,
-	0x03, 0xa5, 0xd1, 0x7d, 0xdf, 0x3b, 0x6a, 0x7d, 0x68, 0x35, 0x0b, 0x92, 0xba, 0x79, 0x73, 0x5b,
-	0xce, 0x8b, 0x8c, 0xc6, 0x64, 0x4f, 0xaf, 0x60, 0x63, 0x92, 0x73, 0xd2, 0x6b, 0x75, 0x9a, 0xed,
-	0xce, 0x41, 0x21, 0xa5, 0xaa, 0x37, 0xb7, 0xe5, 0xad, 0xb9, 0xdc, 0x1e, 0x3a, 0xe6, 0xd0, 0xb1,
-	0x42, 0xda, 0xfa, 0x7e, 0x97, 0x86, 0x45, 0xd3, 0x49, 0xda, 0xe8, 0x60, 0x68, 0xaa, 0xe4, 0xfa,
-	0x6b, 0x69, 0xe9, 0xdb, 0x5d, 0x69, 0xe9, 0xc7, 0x5d, 0x29, 0x6e, 0x55, 0x7b, 0x0a, 0x72, 0x34,
-	0xc5, 0xe2, 0xe5, 0x69, 0xd7, 0x12, 0xac, 0x26, 0x7f, 0x04, 0xf2, 0x02, 0x40, 0x37, 0x0c, 0x16,
-	0x38, 0xfc, 0x64, 0xa2, 0xa4, 0xb5, 0x87, 0xfb, 0x1d, 0xa5, 0x2e, 0xbc, 0xed, 0x26, 0x55, 0xe2,
-	0x84, 0xb6, 0x49, 0x6a, 0xb0, 0x6a, 0xd8, 0x81, 0xcf, 0xd1, 0x3b, 0x71, 0xf4, 0x51, 0xfc, 0x33,
-	0xee, 0xe7, 0x1f, 0xee, 0x77, 0x72, 0x0d, 0xe1, 0xef, 0xe8, 0x23, 0xa4, 0x39, 0x63, 0x6a, 0xc4,
-	0x1a, 0x4d, 0xcf, 0x6b, 0xb4, 0xf6, 0x25, 0x0d, 0x72, 0xdd, 0x42, 0x87, 0x93, 0x06, 0xac, 0xc4,
-	0x82, 0x22, 0xff, 0x25, 0x2f, 0x32, 0x23, 0x32, 0x75, 0xfb, 0x91, 0x3b, 0xbf, 0x94, 0xc8, 0x3b,
-	0xc8, 0x8e, 0x97, 0x48, 0xd4, 0x44, 0xea, 0x9c, 0x3e, 0xd4, 0xad, 0xaa, 0x78, 0x65, 0xaa, 0xe3,
-	0x57, 0xa6, 0xda, 0x0a, 0x5f, 0x99, 0x10, 0x3f, 0xd6, 0xe6, 0x0c, 0x7e, 0x4e, 0xb0, 0x0b, 0xf1,
-	0x7b, 0x20, 0x47, 0xb7, 0x21, 0x49, 0xcd, 0x25, 0xe5, 0xf5, 0x28, 0x73, 0x2c, 0xbb, 0x59, 0xe6,
-	0x59, 0x2d, 0x2e, 0xc4, 0x1f, 0xc1, 0xe6, 0x01, 0x3a, 0xe1, 0x4d, 0x31, 0xa1, 0x38, 0xf2, 0x24,
-	0x51, 0xea, 0x77, 0x25, 0x2e, 0xaa, 0x36, 0xc8, 0x44, 0xf6, 0xeb, 0x5f, 0x01, 0x00, 0x00, 0xff,
-	0xff, 0x3d, 0x71, 0x20, 0x38, 0xa9, 0x05, 0x00, 0x00,
+	// 735 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcd, 0x4e, 0x1a, 0x51,
+	0x14, 0x76, 0x40, 0x10, 0x0e, 0x08, 0x78, 0x6d, 0x28, 0x8e, 0x2d, 0xd2, 0x59, 0x34, 0x34, 0x69,
+	0xb0, 0xa5, 0x8b, 0x9a, 0xa6, 0x6d, 0x82, 0x40, 0x0c, 0xd1, 0x02, 0xb9, 0xd4, 0x74, 0x69, 0x86,
+	0xe1, 0x38, 0x12, 0x61, 0xee, 0x74, 0xe6, 0x4e, 0x94, 0xa4, 0x0f, 0x60, 0x7c, 0x07, 0x57, 0x75,
+	0xd1, 0x7d, 0x77, 0x7d, 0x80, 0xbe, 0x40, 0xf7, 0x2e, 0x7c, 0x92, 0x66, 0xe6, 0x0e, 0x30, 0x60,
+	0xb1, 0x71, 0x77, 0xcf, 0xcf, 0x77, 0xfe, 0xbf, 0x0b, 0xe9, 0xbe, 0x61, 0x73, 0x75, 0x30, 0x40,
+	0xab, 0x64, 0x5a, 0x8c, 0x33, 0x12, 0x9f, 0x28, 0xe4, 0x4d, 0x9d, 0x31, 0x7d, 0x80, 0xdb, 0x9e,
+	0xa1, 0xeb, 0x1c, 0x6f, 0xe3, 0xd0, 0xe4, 0x23, 0xe1, 0x27, 0x83, 0xce, 0x74, 0x26, 0xde, 0xca,
+	0x37, 0x88, 0xb4, 0x4f, 0x54, 0x1b, 0x49, 0x16, 0x42, 0xfd, 0x5e, 0x4e, 0x2a, 0x48, 0xc5, 0xf8,
+	0x6e, 0xf4, 0xf6, 0x66, 0x2b, 0xd4, 0xa8, 0xd1, 0x50, 0xbf, 0x47, 0x5e, 0x40, 0xf8, 0x14, 0x47,
+	0xb9, 0x50, 0x41, 0x2a, 0x26, 0xca, 0x8f, 0x4b, 0xd3, 0x9c, 0x2d, 0x13, 0x2d, 0x95, 0xf7, 0x99,
+	0xb1, 0x8f, 0x23, 0xea, 0xfa, 0x10, 0x19, 0x62, 0x16, 0x1b, 0x0c, 0xba, 0xaa, 0x76, 0x9a, 0x0b,
+	0x17, 0xa4, 0x62, 0x8c, 0x4e, 0x64, 0xf2, 0x08, 0x22, 0xc7, 0xcc, 0xd2, 0x30, 0xb7, 0xec, 0x19,
+	0x84, 0xa0, 0xec, 0x40, 0xaa, 0x7e, 0x8e, 0x9a, 0xc3, 0x91, 0xe2, 0x57, 0x07, 0x6d, 0x4e, 0x9e,
+	0x43, 0xc4, 0x74, 0xeb, 0xf1, 0x2a, 0x49, 0x94, 0x33, 0x81, 0x84, 0x5e, 0x9d, 0x54, 0x98, 0x95,
+	0x16, 0xa4, 0x3b, 0xc8, 0x3b, 0x5c, 0x7d, 0x30, 0xd4, 0x2d, 0xc5, 0x76, 0x71, 0x5e, 0x4f, 0x71,
+	0x2a, 0x04, 0xe5, 0x3d, 0xa4, 0xab, 0x6c, 0x68, 0x0e, 0x70, 0x1a, 0xd0, 0x6f, 0x5d, 0xfa, 0x7f,
+	0xeb, 0x4a, 0x0a, 0x92, 0x95, 0x2e, 0xb3, 0xb8, 0x0f, 0x55, 0xf6, 0x21, 0xdd, 0x39, 0x71, 0x78,
+	0x8f, 0x9d, 0x19, 0xe3, 0x68, 0x4f, 0x20, 0xae, 0xf9, 0x09, 0xc4, 0x9c, 0x63, 0x74, 0xaa, 0x70,
+	0x67, 0x87, 0xe7, 0x7d, 0x5e, 0x65, 0x3d, 0x51, 0x57, 0x84, 0x4e, 0x64, 0xa5, 0x08, 0xa4, 0x86,
+	0x5d, 0x47, 0xa7, 0x68, 0x4e, 0x53, 0x10, 0x02, 0xcb, 0xa6, 0xca, 0x4f, 0xc4, 0xca, 0xa8, 0xf7,
+	0x56, 0x7e, 0x87, 0x20, 0xd3, 0xb6, 0x98, 0x6e, 0xa1, 0x6d, 0x53, 0xb4, 0x4d, 0x66, 0xd8, 0x48,
+	0x72, 0xb0, 0x32, 0x44, 0xdb, 0x56, 0x75, 0xf4, 0x7d, 0xc7, 0x22, 0x79, 0x07, 0x51, 0xb7, 0x79,
+	0xc7, 0xf6, 0x52, 0xa6, 0xca, 0x4a, 0x70, 0x64, 0x73, 0x61, 0x4a, 0x1d, 0xcf, 0x93, 0xfa, 0x08,
+	0x77, 0xda, 0x68, 0x59, 0xcc, 0xca, 0x85, 0xef, 0x4c, 0xbb, 0xee, 0xea, 0xa9, 0x30, 0x2b, 0x3f,
+	0x25, 0x88, 0x0a, 0x28, 0xc9, 0xc3, 0xca, 0x61, 0x73, 0xbf, 0xd9, 0xfa, 0xd2, 0xcc, 0x2c, 0xc9,
+	0x6b, 0x97, 0x57, 0x85, 0x55, 0x61, 0x38, 0x34, 0x4e, 0x0d, 0x76, 0x66, 0x10, 0x05, 0xe2, 0xd5,
+	0xd6, 0xa7, 0xf6, 0x41, 0xfd, 0x73, 0xbd, 0x96, 0x91, 0xe4, 0xf5, 0xcb, 0xab, 0x42, 0x5a, 0x78,
+	0x54, 0x27, 0x73, 0x7a, 0x0d, 0x6b, 0x13, 0x9f, 0xa3, 0x76, 0xbd, 0x59, 0x6b, 0x34, 0xf7, 0x32,
+	0x21, 0x59, 0xbe, 0xbc, 0x2a, 0x64, 0xe7, 0x7c, 0xdb, 0x68, 0xf4, 0xfa, 0x86, 0xee, 0xa6, 0xad,
+	0xec, 0xb6, 0xa8, 0x1b, 0x34, 0x1c, 0x4c, 0xeb, 0x2d, 0x0c, 0x7b, 0x32, 0xb9, 0xf8, 0x9e, 0x5f,
+	0xfa, 0x71, 0x9d, 0x5f, 0xfa, 0x75, 0x9d, 0xf7, 0x4b, 0x55, 0x9e, 0x41, 0xc4, 0xeb, 0x62, 0xf1,
+	0xf0, 0x94, 0x0b, 0x09, 0x92, 0xc1, 0x43, 0x20, 0x2f, 0x01, 0x54, 0x4d, 0x63, 0x8e, 0xc1, 0x8f,
+	0x26, 0x4c, 0x5a, 0xbd, 0xbd, 0xd9, 0x8a, 0x57, 0x84, 0xb6, 0x51, 0xa3, 0x71, 0xdf, 0xa1, 0xd1,
+	0x23, 0x65, 0x48, 0x6a, 0x03, 0xc7, 0xe6, 0x68, 0x1d, 0x19, 0xea, 0xd0, 0x3f, 0xc6, 0xdd, 0xf4,
+	0xed, 0xcd, 0x56, 0xa2, 0x2a, 0xf4, 0x4d, 0x75, 0x88, 0x34, 0xa1, 0x4d, 0x05, 0x9f, 0xa3, 0xe1,
+	0x79, 0x8e, 0x2a, 0x6f, 0x61, 0xb5, 0x66, 0x8d, 0xa8, 0x63, 0x3c, 0x94, 0x45, 0x45, 0x48, 0x8d,
+	0x81, 0xfe, 0xb1, 0x64, 0x21, 0xea, 0x99, 0x6c, 0x0f, 0x9a, 0xa4, 0xbe, 0x54, 0xfe, 0x13, 0x86,
+	0x48, 0x45, 0x47, 0x83, 0x93, 0x2a, 0xac, 0xf8, 0x9c, 0x25, 0x1b, 0xc1, 0xa5, 0xcf, 0xf0, 0x58,
+	0xde, 0xbc, 0xe7, 0x94, 0x5e, 0x49, 0xe4, 0x23, 0xc4, 0xc6, 0x7b, 0x22, 0x72, 0xc0, 0x75, 0x8e,
+	0x82, 0x72, 0xb6, 0x24, 0x3e, 0xb2, 0xd2, 0xf8, 0x23, 0x2b, 0xd5, 0xdd, 0x8f, 0xcc, 0xc5, 0x8f,
+	0xe9, 0x3f, 0x83, 0x9f, 0xfb, 0x13, 0x16, 0xe2, 0x77, 0x20, 0xe2, 0xad, 0x9f, 0x04, 0x69, 0x1d,
+	0x64, 0xf0, 0xbd, 0x99, 0x7d, 0x66, 0xcf, 0x66, 0x9e, 0xa5, 0xfb, 0x42, 0xfc, 0x01, 0xac, 0xef,
+	0xa1, 0xe1, 0x9e, 0x0d, 0x06, 0x48, 0x4d, 0x9e, 0x06, 0x42, 0xdd, 0x25, 0xfb, 0xc2, 0x68, 0x1f,
+	0x20, 0x2a, 0x16, 0x48, 0x72, 0xc1, 0x00, 0xc1, 0x63, 0x90, 0x37, 0xfe, 0x61, 0x11, 0x8b, 0xe8,
+	0x46, 0xbd, 0x70, 0x6f, 0xfe, 0x0e, 0x00, 0x64, 0x9f, 0xe9, 0x27, 0x4b, 0x06, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -630,6 +715,8 @@ type AgentClient interface {
 	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*types.Empty, error)
 	// GenerateDebugReport requests the installer to generate a debug report
 	GenerateDebugReport(ctx context.Context, in *DebugReportRequest, opts ...grpc.CallOption) (*types.Empty, error)
+	// DryRun describes the changes the specified phase would make if executed
+	DryRun(ctx context.Context, in *DryRunRequest, opts ...grpc.CallOption) (*DryRunResponse, error)
 }
 
 type agentClient struct {
@@ -717,6 +804,15 @@ func (c *agentClient) GenerateDebugReport(ctx context.Context, in *DebugReportRe
 	return out, nil
 }
 
+func (c *agentClient) DryRun(ctx context.Context, in *DryRunRequest, opts ...grpc.CallOption) (*DryRunResponse, error) {
+	out := new(DryRunResponse)
+	err := c.cc.Invoke(ctx, "/installer.Agent/DryRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 type AgentServer interface {
 	// Execute runs the operation specified with request.
@@ -734,6 +830,8 @@ type AgentServer interface {
 	Shutdown(context.Context, *ShutdownRequest) (*types.Empty, error)
 	// GenerateDebugReport requests the installer to generate a debug report
 	GenerateDebugReport(context.Context, *DebugReportRequest) (*types.Empty, error)
+	// DryRun describes the changes the specified phase would make if executed
+	DryRun(context.Context, *DryRunRequest) (*DryRunResponse, error)
 }
 
 // UnimplementedAgentServer can be embedded to have forward compatible implementations.
@@ -758,6 +856,9 @@ func (*UnimplementedAgentServer) Shutdown(ctx context.Context, req *ShutdownRequ
 func (*UnimplementedAgentServer) GenerateDebugReport(ctx context.Context, req *DebugReportRequest) (*types.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GenerateDebugReport not implemented")
 }
+func (*UnimplementedAgentServer) DryRun(ctx context.Context, req *DryRunRequest) (*DryRunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DryRun not implemented")
+}
 
 func RegisterAgentServer(s *grpc.Server, srv AgentServer) {
 	s.RegisterService(&_Agent_serviceDesc, srv)
@@ -874,6 +975,24 @@ func _Agent_GenerateDebugReport_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_DryRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DryRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).DryRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/installer.Agent/DryRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).DryRun(ctx, req.(*DryRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Agent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "installer.Agent",
 	HandlerType: (*AgentServer)(nil),
@@ -898,6 +1017,10 @@ var _Agent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GenerateDebugReport",
 			Handler:    _Agent_GenerateDebugReport_Handler,
 		},
+		{
+			MethodName: "DryRun",
+			Handler:    _Agent_DryRun_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
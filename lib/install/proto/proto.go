@@ -82,6 +82,11 @@ func (r *SetStateRequest) OperationID() string {
 	return r.Phase.ID
 }
 
+// OperationKey returns operation key from request.
+func (r *DryRunRequest) OperationKey() ops.SiteOperationKey {
+	return KeyFromProto(r.Phase.Key)
+}
+
 // IsResume determines if this phase describes a resume operation
 func (r *Phase) IsResume() bool {
 	return r.ID == fsm.RootPhase
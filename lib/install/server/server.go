@@ -17,10 +17,12 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 
 	installpb "github.com/gravitational/gravity/lib/install/proto"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gogo/protobuf/types"
@@ -155,6 +157,26 @@ func (r *Server) GenerateDebugReport(ctx context.Context, req *installpb.DebugRe
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
 
+// DryRun describes the changes the phase given with req would make if executed.
+// Implements installpb.AgentServer
+func (r *Server) DryRun(ctx context.Context, req *installpb.DryRunRequest) (*installpb.DryRunResponse, error) {
+	r.WithField("req", req).Info("Dry-run.")
+	dryRunner, ok := r.executor.(DryRunner)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "not implemented")
+	}
+	phases, err := dryRunner.DryRun(req)
+	if err != nil {
+		// Not wrapping err as it passes the gRPC boundary
+		return nil, err
+	}
+	bytes, err := json.Marshal(phases)
+	if err != nil {
+		return nil, err
+	}
+	return &installpb.DryRunResponse{Phases: bytes}, nil
+}
+
 // Executor wraps a potentially failing operation
 type Executor interface {
 	Completer
@@ -172,6 +194,13 @@ type DebugReporter interface {
 	GenerateDebugReport(path string) error
 }
 
+// DryRunner allows to describe the changes of an operation phase
+// without executing it
+type DryRunner interface {
+	// DryRun describes the changes the phase given with req would make if executed
+	DryRun(req *installpb.DryRunRequest) ([]storage.PhaseDryRun, error)
+}
+
 // Completer describes completion outcomes
 type Completer interface {
 	// HandleAborted indicates that the operation has been aborted and completion steps
@@ -237,6 +237,24 @@ func UpdateOperationState(operator ops.Operator, operation ops.SiteOperation, re
 	return trace.Wrap(err)
 }
 
+// UpsertTrustPolicy creates the trust policy found among the specified cluster
+// resources with the given operator, so that application package signatures
+// are verified against it when the install operation is created.
+// Does nothing if the resources have no trust policy
+func UpsertTrustPolicy(ctx context.Context, operator ops.Operator, key ops.SiteKey, resources []storage.UnknownResource) error {
+	for _, res := range resources {
+		if res.Kind != storage.KindTrustPolicy {
+			continue
+		}
+		policy, err := storage.UnmarshalTrustPolicy(res.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(operator.UpsertTrustPolicy(ctx, key, policy))
+	}
+	return nil
+}
+
 // GetServerUpdateRequest returns a request to update servers in given operation's state
 // based on specified list of servers
 func GetServerUpdateRequest(op ops.SiteOperation, servers []checks.ServerInfo) (*ops.OperationUpdateRequest, error) {
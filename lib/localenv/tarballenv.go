@@ -18,11 +18,20 @@ package localenv
 
 import (
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	libapp "github.com/gravitational/gravity/lib/app"
+	appservice "github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/blob/tarball"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/encryptedpack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/license"
@@ -30,11 +39,15 @@ import (
 )
 
 // NewTarballEnvironment creates new environment for the cluster image
-// unpacked at the configured location
+// unpacked at the configured location or, if Tarball is specified,
+// for the cluster image tarball itself
 func NewTarballEnvironment(config TarballEnvironmentArgs) (*TarballEnvironment, error) {
 	if err := config.checkAndSetDefaults(); err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if config.Tarball != "" {
+		return newTarballFileEnvironment(config)
+	}
 	env, err := NewLocalEnvironment(LocalEnvironmentArgs{
 		StateDir: config.StateDir,
 	})
@@ -46,16 +59,9 @@ func NewTarballEnvironment(config TarballEnvironmentArgs) (*TarballEnvironment,
 			env.Close()
 		}
 	}()
-	var packages pack.PackageService = env.Packages
-	if config.License != "" {
-		parsed, err := license.ParseLicense(config.License)
-		if err != nil {
-			return nil, trace.Wrap(err)
-		}
-		encryptionKey := parsed.GetPayload().EncryptionKey
-		if len(encryptionKey) != 0 {
-			packages = encryptedpack.New(packages, string(encryptionKey))
-		}
+	packages, err := config.packages(env.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
 	apps, err := env.AppServiceLocal(AppConfig{
 		Packages: packages,
@@ -67,6 +73,73 @@ func NewTarballEnvironment(config TarballEnvironmentArgs) (*TarballEnvironment,
 		Closer:   env,
 		Packages: packages,
 		Apps:     apps,
+		openFile: func(name string) (io.ReadCloser, error) {
+			f, err := os.Open(filepath.Join(config.StateDir, name))
+			if err != nil {
+				return nil, trace.ConvertSystemError(err)
+			}
+			return f, nil
+		},
+	}, nil
+}
+
+// newTarballFileEnvironment creates new environment that reads packages
+// directly from the cluster image tarball.
+// Only the package database is extracted from the tarball
+func newTarballFileEnvironment(config TarballEnvironmentArgs) (env *TarballEnvironment, err error) {
+	objects, err := tarball.New(config.Tarball, defaults.PackagesDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	closer := &tarballFileCloser{objects: objects}
+	defer func() {
+		if err != nil {
+			closer.Close()
+		}
+	}()
+	closer.dir, err = ioutil.TempDir("", "tarball")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if err := extractFile(objects, defaults.GravityDBFile, closer.dir); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	closer.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Path:     filepath.Join(closer.dir, defaults.GravityDBFile),
+		Readonly: true,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	unpackedDir := filepath.Join(closer.dir, defaults.PackagesDir, defaults.UnpackedDir)
+	localPackages, err := localpack.New(localpack.Config{
+		Backend:     closer.backend,
+		Objects:     objects,
+		UnpackedDir: unpackedDir,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	packages, err := config.packages(localPackages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	apps, err := appservice.New(appservice.Config{
+		Backend:     closer.backend,
+		Packages:    packages,
+		StateDir:    filepath.Join(closer.dir, "import"),
+		UnpackedDir: unpackedDir,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &TarballEnvironment{
+		Closer:   closer,
+		Packages: packages,
+		Apps:     apps,
+		openFile: func(name string) (io.ReadCloser, error) {
+			return objects.Open(name)
+		},
 	}, nil
 }
 
@@ -75,6 +148,10 @@ type TarballEnvironmentArgs struct {
 	// StateDir specifies optional state directory.
 	// If unspecified, current process's working directory is used
 	StateDir string
+	// Tarball specifies optional path to the cluster image tarball.
+	// If specified, packages are read directly from the tarball
+	// and StateDir is not used
+	Tarball string
 	// License specifies optional license payload to decode packages
 	License string
 }
@@ -86,6 +163,23 @@ func (r *TarballEnvironmentArgs) checkAndSetDefaults() error {
 	return nil
 }
 
+// packages returns the package service for the environment that
+// decrypts packages with the key from the license, if any
+func (r TarballEnvironmentArgs) packages(packages pack.PackageService) (pack.PackageService, error) {
+	if r.License == "" {
+		return packages, nil
+	}
+	parsed, err := license.ParseLicense(r.License)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	encryptionKey := parsed.GetPayload().EncryptionKey
+	if len(encryptionKey) != 0 {
+		return encryptedpack.New(packages, string(encryptionKey)), nil
+	}
+	return packages, nil
+}
+
 // TarballEnvironment describes application environment in the directory
 // with unpacked installer
 type TarballEnvironment struct {
@@ -94,4 +188,64 @@ type TarballEnvironment struct {
 	Packages pack.PackageService
 	// Apps specifies the local application service
 	Apps libapp.Applications
+	// openFile opens the file with the specified name from the image
+	openFile func(name string) (io.ReadCloser, error)
+}
+
+// Manifest returns the manifest of the cluster image
+func (r *TarballEnvironment) Manifest() (*schema.Manifest, error) {
+	rc, err := r.openFile(defaults.ManifestFileName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// tarballFileCloser releases resources of the environment
+// created for the cluster image tarball
+type tarballFileCloser struct {
+	objects *tarball.Objects
+	backend storage.Backend
+	// dir is the temporary directory with the package database
+	dir string
+}
+
+// Close closes the package database and the tarball and removes
+// the temporary directory
+func (r *tarballFileCloser) Close() error {
+	var errors []error
+	if r.backend != nil {
+		errors = append(errors, r.backend.Close())
+	}
+	errors = append(errors, r.objects.Close())
+	if r.dir != "" {
+		errors = append(errors, os.RemoveAll(r.dir))
+	}
+	return trace.NewAggregate(errors...)
+}
+
+func extractFile(objects *tarball.Objects, name, dir string) error {
+	rc, err := objects.Open(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer rc.Close()
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, rc); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(f.Close())
 }
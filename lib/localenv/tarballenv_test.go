@@ -0,0 +1,61 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localenv
+
+import (
+	"os"
+	"path/filepath"
+
+	apptest "github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *LocalEnvSuite) TestTarballEnvironment(c *check.C) {
+	stateDir := c.MkDir()
+	env, err := NewLocalEnvironment(LocalEnvironmentArgs{StateDir: stateDir})
+	c.Assert(err, check.IsNil)
+	apptest.CreateRuntimeApplication(env.Apps, c)
+	locator := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	application := apptest.CreateDummyApplication(locator, c, env.Apps)
+	c.Assert(env.Close(), check.IsNil)
+
+	path := filepath.Join(c.MkDir(), "app.tar")
+	f, err := os.Create(path)
+	c.Assert(err, check.IsNil)
+	err = archive.CompressDirectory(stateDir, f,
+		archive.ItemFromString(defaults.ManifestFileName, "apiVersion: bundle.gravitational.io/v2\nkind: Bundle\nmetadata:\n  name: app\n  resourceVersion: 0.0.1\n"))
+	c.Assert(err, check.IsNil)
+	c.Assert(f.Close(), check.IsNil)
+
+	tarballEnv, err := NewTarballEnvironment(TarballEnvironmentArgs{Tarball: path})
+	c.Assert(err, check.IsNil)
+	defer tarballEnv.Close()
+
+	manifest, err := tarballEnv.Manifest()
+	c.Assert(err, check.IsNil)
+	c.Assert(manifest.Locator().Name, check.Equals, "app")
+
+	read, err := tarballEnv.Apps.GetApp(locator)
+	c.Assert(err, check.IsNil)
+	c.Assert(read.PackageEnvelope.SHA512, check.Equals, application.PackageEnvelope.SHA512)
+	c.Assert(pack.VerifyPackage(tarballEnv.Packages, locator), check.IsNil)
+}
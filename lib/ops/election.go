@@ -19,6 +19,7 @@ package ops
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/storage"
@@ -47,13 +48,34 @@ func runLeaderCommandRetry(ctx context.Context, command string, clusterName stri
 	})
 }
 
-func runLeaderCommand(ctx context.Context, command string, clusterName string, node storage.Server, log logrus.FieldLogger) error {
-	out, err := utils.RunPlanetCommand(ctx, log, "leader", command,
+// EnableLeaderElectionCommand returns the planet command that turns on
+// leader election for the specified node.
+func EnableLeaderElectionCommand(clusterName string, node storage.Server) string {
+	return formatLeaderCommand("resume", clusterName, node)
+}
+
+// PauseLeaderElectionCommand returns the planet command that pauses
+// leader election for the specified node.
+func PauseLeaderElectionCommand(clusterName string, node storage.Server) string {
+	return formatLeaderCommand("pause", clusterName, node)
+}
+
+func formatLeaderCommand(command string, clusterName string, node storage.Server) string {
+	args := append([]string{defaults.PlanetBin}, leaderCommandArgs(command, clusterName, node)...)
+	return strings.Join(args, " ")
+}
+
+func leaderCommandArgs(command string, clusterName string, node storage.Server) []string {
+	return []string{"leader", command,
 		fmt.Sprintf("--public-ip=%v", node.AdvertiseIP),
 		fmt.Sprintf("--election-key=/planet/cluster/%v/election", clusterName),
 		"--etcd-cafile=/var/state/root.cert",
 		"--etcd-certfile=/var/state/etcd.cert",
-		"--etcd-keyfile=/var/state/etcd.key")
+		"--etcd-keyfile=/var/state/etcd.key"}
+}
+
+func runLeaderCommand(ctx context.Context, command string, clusterName string, node storage.Server, log logrus.FieldLogger) error {
+	out, err := utils.RunPlanetCommand(ctx, log, leaderCommandArgs(command, clusterName, node)...)
 	if err != nil {
 		return trace.Wrap(err, "failed to enable election for %v: %s",
 			node.AdvertiseIP, string(out))
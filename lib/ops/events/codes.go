@@ -254,6 +254,26 @@ var (
 		Name: PersistentStorageUpdatedEvent,
 		Code: PersistentStorageUpdatedCode,
 	}
+	// TrustPolicyUpdated is emitted when the package trust policy is created/updated.
+	TrustPolicyUpdated = events.Event{
+		Name: TrustPolicyUpdatedEvent,
+		Code: TrustPolicyUpdatedCode,
+	}
+	// TrustPolicyDeleted is emitted when the package trust policy is deleted.
+	TrustPolicyDeleted = events.Event{
+		Name: TrustPolicyDeletedEvent,
+		Code: TrustPolicyDeletedCode,
+	}
+	// MaintenanceWindowUpdated is emitted when the maintenance window is created/updated.
+	MaintenanceWindowUpdated = events.Event{
+		Name: MaintenanceWindowUpdatedEvent,
+		Code: MaintenanceWindowUpdatedCode,
+	}
+	// MaintenanceWindowDeleted is emitted when the maintenance window is deleted.
+	MaintenanceWindowDeleted = events.Event{
+		Name: MaintenanceWindowDeletedEvent,
+		Code: MaintenanceWindowDeletedCode,
+	}
 	// ClusterUnhealthy is emitted when cluster becomes unhealthy.
 	ClusterUnhealthy = events.Event{
 		Name: ClusterDegradedEvent,
@@ -264,6 +284,12 @@ var (
 		Name: ClusterActivatedEvent,
 		Code: ClusterHealthyCode,
 	}
+	// MaintenanceWindowOverridden is emitted when an operation is forced
+	// outside of the maintenance window.
+	MaintenanceWindowOverridden = events.Event{
+		Name: MaintenanceWindowOverriddenEvent,
+		Code: MaintenanceWindowOverriddenCode,
+	}
 	// ApplicationInstall is emitted when a new application image is installed.
 	ApplicationInstall = events.Event{
 		Name: AppInstalledEvent,
@@ -396,10 +422,20 @@ const (
 	UserInviteCreatedCode = "G1010I"
 	// PersistentStorageUpdatedCode is the persistent storage updated event code.
 	PersistentStorageUpdatedCode = "G1011I"
+	// TrustPolicyUpdatedCode is the package trust policy updated event code.
+	TrustPolicyUpdatedCode = "G1012I"
+	// TrustPolicyDeletedCode is the package trust policy deleted event code.
+	TrustPolicyDeletedCode = "G2012I"
+	// MaintenanceWindowUpdatedCode is the maintenance window updated event code.
+	MaintenanceWindowUpdatedCode = "G1013I"
+	// MaintenanceWindowDeletedCode is the maintenance window deleted event code.
+	MaintenanceWindowDeletedCode = "G2013I"
 	// ClusterUnhealthyCode is the cluster goes unhealthy event code.
 	ClusterUnhealthyCode = "G3000W"
 	// ClusterHealthyCode is the cluster goes healthy event code.
 	ClusterHealthyCode = "G3001I"
+	// MaintenanceWindowOverriddenCode is the maintenance window overridden event code.
+	MaintenanceWindowOverriddenCode = "G3002W"
 	// ApplicationInstallCode is the application release install event code.
 	ApplicationInstallCode = "G4000I"
 	// ApplicationUpgradeCode is the application release upgrade event code.
@@ -467,9 +503,20 @@ const (
 	InviteCreatedEvent = "invite.created"
 	// PersistentStorageUpdatedEvent fires when persistent storage configuration is updated.
 	PersistentStorageUpdatedEvent = "persistentstorage.updated"
+	// TrustPolicyUpdatedEvent fires when the package trust policy is created/updated.
+	TrustPolicyUpdatedEvent = "trustpolicy.updated"
+	// TrustPolicyDeletedEvent fires when the package trust policy is deleted.
+	TrustPolicyDeletedEvent = "trustpolicy.deleted"
+	// MaintenanceWindowUpdatedEvent fires when the maintenance window is created/updated.
+	MaintenanceWindowUpdatedEvent = "maintenancewindow.updated"
+	// MaintenanceWindowDeletedEvent fires when the maintenance window is deleted.
+	MaintenanceWindowDeletedEvent = "maintenancewindow.deleted"
 
 	// ClusterDegradedEvent fires when cluster health check fails.
 	ClusterDegradedEvent = "cluster.degraded"
 	// ClusterActivatedEvent fires when cluster becomes healthy again.
 	ClusterActivatedEvent = "cluster.activated"
+	// MaintenanceWindowOverriddenEvent fires when an operation is forced
+	// outside of the maintenance window.
+	MaintenanceWindowOverriddenEvent = "maintenancewindow.overridden"
 )
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"text/template"
 	"time"
 
@@ -49,6 +50,30 @@ type Metrics interface {
 	GetMaxCPURate(ctx context.Context, interval time.Duration) (int, error)
 	// GetMaxMemoryRate returns highest RAM usage rate on the specified interval.
 	GetMaxMemoryRate(ctx context.Context, interval time.Duration) (int, error)
+	// GetNodeCPURates returns CPU usage rates of individual nodes
+	// for the specified time range.
+	GetNodeCPURates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetNodeMemoryRates returns RAM usage rates of individual nodes
+	// for the specified time range.
+	GetNodeMemoryRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetNamespaceCPUUsage returns CPU usage of individual namespaces
+	// in millicores for the specified time range.
+	GetNamespaceCPUUsage(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetNamespaceMemoryUsage returns RAM usage of individual namespaces
+	// in bytes for the specified time range.
+	GetNamespaceMemoryUsage(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetDiskReadRates returns disk read throughput of individual nodes
+	// in bytes per second for the specified time range.
+	GetDiskReadRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetDiskWriteRates returns disk write throughput of individual nodes
+	// in bytes per second for the specified time range.
+	GetDiskWriteRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetNetworkReceiveRates returns network receive throughput of individual
+	// nodes in bytes per second for the specified time range.
+	GetNetworkReceiveRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
+	// GetNetworkTransmitRates returns network transmit throughput of individual
+	// nodes in bytes per second for the specified time range.
+	GetNetworkTransmitRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error)
 }
 
 // Series represents a time series, collection of data points.
@@ -62,6 +87,23 @@ type Point struct {
 	Value int `json:"value"`
 }
 
+// LabeledSeries is a time series of a single node or namespace.
+type LabeledSeries struct {
+	// Label is the name of the node or namespace the series belongs to.
+	Label string `json:"label"`
+	// Series is the time series.
+	Series Series `json:"series"`
+}
+
+// Last returns the value of the latest data point in the series
+// or 0 if the series is empty.
+func (r Series) Last() int {
+	if len(r) == 0 {
+		return 0
+	}
+	return r[len(r)-1].Value
+}
+
 // prometheus retrieves cluster metrics by querying in-cluster Prometheus.
 //
 // Implements Metrics interface.
@@ -182,6 +224,88 @@ func (p *prometheus) GetMaxMemoryRate(ctx context.Context, interval time.Duratio
 	return int(value), nil
 }
 
+// GetNodeCPURates returns CPU usage rates of individual nodes
+// for the specified time range.
+func (p *prometheus) GetNodeCPURates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNodeCPURate, labelNode, timeRange)
+}
+
+// GetNodeMemoryRates returns RAM usage rates of individual nodes
+// for the specified time range.
+func (p *prometheus) GetNodeMemoryRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNodeMemoryRate, labelNode, timeRange)
+}
+
+// GetNamespaceCPUUsage returns CPU usage of individual namespaces
+// in millicores for the specified time range.
+func (p *prometheus) GetNamespaceCPUUsage(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNamespaceCPUUsage, labelNamespace, timeRange)
+}
+
+// GetNamespaceMemoryUsage returns RAM usage of individual namespaces
+// in bytes for the specified time range.
+func (p *prometheus) GetNamespaceMemoryUsage(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNamespaceMemoryUsage, labelNamespace, timeRange)
+}
+
+// GetDiskReadRates returns disk read throughput of individual nodes
+// in bytes per second for the specified time range.
+func (p *prometheus) GetDiskReadRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryDiskReadRate, labelNode, timeRange)
+}
+
+// GetDiskWriteRates returns disk write throughput of individual nodes
+// in bytes per second for the specified time range.
+func (p *prometheus) GetDiskWriteRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryDiskWriteRate, labelNode, timeRange)
+}
+
+// GetNetworkReceiveRates returns network receive throughput of individual
+// nodes in bytes per second for the specified time range.
+func (p *prometheus) GetNetworkReceiveRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNetworkReceiveRate, labelNode, timeRange)
+}
+
+// GetNetworkTransmitRates returns network transmit throughput of individual
+// nodes in bytes per second for the specified time range.
+func (p *prometheus) GetNetworkTransmitRates(ctx context.Context, timeRange v1.Range) ([]LabeledSeries, error) {
+	return p.getLabeledSeries(ctx, queryNetworkTransmitRate, labelNode, timeRange)
+}
+
+// getLabeledSeries issues the provided Prometheus ranged query and returns
+// a time series for each element of the resulting matrix identified
+// by the value of the specified label.
+func (p *prometheus) getLabeledSeries(ctx context.Context, query string, label model.LabelName, timeRange v1.Range) ([]LabeledSeries, error) {
+	matrix, err := p.getMatrix(ctx, query, timeRange)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return toLabeledSeries(matrix, label), nil
+}
+
+// toLabeledSeries converts the provided range vector to a list of time series
+// sorted by the value of the specified label.
+func toLabeledSeries(matrix model.Matrix, label model.LabelName) []LabeledSeries {
+	result := make([]LabeledSeries, 0, len(matrix))
+	for _, stream := range matrix {
+		var series Series
+		for _, v := range stream.Values {
+			series = append(series, Point{
+				Value: int(v.Value),
+				Time:  v.Timestamp.Time(),
+			})
+		}
+		result = append(result, LabeledSeries{
+			Label:  string(stream.Metric[label]),
+			Series: series,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
 // getVector executes the provided Prometheus query and returns the resulting
 // instant vector:
 //
@@ -265,4 +389,46 @@ var (
 	// memory usage rate percent value on a certain interval.
 	queryMaxMemory = template.Must(template.New("").Parse(
 		"max_over_time(cluster:memory_usage_rate[{{.interval}}])"))
+	// queryNodeCPURate is the Prometheus query that returns CPU usage rate
+	// of each node in percent values.
+	queryNodeCPURate = byNodeName(`100 * (1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m])))`)
+	// queryNodeMemoryRate is the Prometheus query that returns memory usage
+	// rate of each node in percent values.
+	queryNodeMemoryRate = byNodeName(`100 * (1 - sum by (instance) (node_memory_MemAvailable_bytes) / sum by (instance) (node_memory_MemTotal_bytes))`)
+	// queryNamespaceCPUUsage is the Prometheus query that returns CPU usage
+	// of each namespace in millicores.
+	queryNamespaceCPUUsage = `1000 * sum by (namespace) (rate(container_cpu_usage_seconds_total{image!=""}[5m]))`
+	// queryNamespaceMemoryUsage is the Prometheus query that returns memory
+	// usage of each namespace in bytes.
+	queryNamespaceMemoryUsage = `sum by (namespace) (container_memory_working_set_bytes{image!=""})`
+	// queryDiskReadRate is the Prometheus query that returns disk read
+	// throughput of each node in bytes per second.
+	queryDiskReadRate = byNodeName(`sum by (instance) (rate(node_disk_read_bytes_total[5m]))`)
+	// queryDiskWriteRate is the Prometheus query that returns disk write
+	// throughput of each node in bytes per second.
+	queryDiskWriteRate = byNodeName(`sum by (instance) (rate(node_disk_written_bytes_total[5m]))`)
+	// queryNetworkReceiveRate is the Prometheus query that returns network
+	// receive throughput of each node in bytes per second.
+	queryNetworkReceiveRate = byNodeName(`sum by (instance) (rate(node_network_receive_bytes_total{device!="lo"}[5m]))`)
+	// queryNetworkTransmitRate is the Prometheus query that returns network
+	// transmit throughput of each node in bytes per second.
+	queryNetworkTransmitRate = byNodeName(`sum by (instance) (rate(node_network_transmit_bytes_total{device!="lo"}[5m]))`)
+)
+
+// byNodeName returns the query that labels the results of the specified
+// per-instance query with the names of the nodes they belong to.
+//
+// Node exporter targets are scraped by address, so the instance label
+// only carries the IP:port of the node. The node name is taken from the
+// nodename label of the node_uname_info metric of the same instance
+func byNodeName(query string) string {
+	return fmt.Sprintf("(%v) * on (instance) group_left (%v) max by (instance, %v) (node_uname_info)",
+		query, labelNode, labelNode)
+}
+
+const (
+	// labelNode is the label that identifies the node a node-level metric belongs to.
+	labelNode model.LabelName = "nodename"
+	// labelNamespace is the label that identifies the namespace a metric belongs to.
+	labelNamespace model.LabelName = "namespace"
 )
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"gopkg.in/check.v1"
+)
+
+func TestMonitoring(t *testing.T) { check.TestingT(t) }
+
+type MetricsSuite struct{}
+
+var _ = check.Suite(&MetricsSuite{})
+
+func (s *MetricsSuite) TestConvertsLabeledSeries(c *check.C) {
+	now := time.Unix(1500000000, 0)
+	matrix := model.Matrix{
+		{
+			Metric: model.Metric{labelNode: "node-2"},
+			Values: []model.SamplePair{
+				{Timestamp: model.TimeFromUnix(now.Unix()), Value: 10},
+			},
+		},
+		{
+			Metric: model.Metric{labelNode: "node-1"},
+			Values: []model.SamplePair{
+				{Timestamp: model.TimeFromUnix(now.Unix()), Value: 20.7},
+				{Timestamp: model.TimeFromUnix(now.Add(time.Minute).Unix()), Value: 30},
+			},
+		},
+	}
+	series := toLabeledSeries(matrix, labelNode)
+	c.Assert(series, check.DeepEquals, []LabeledSeries{
+		{
+			Label: "node-1",
+			Series: Series{
+				{Time: model.TimeFromUnix(now.Unix()).Time(), Value: 20},
+				{Time: model.TimeFromUnix(now.Add(time.Minute).Unix()).Time(), Value: 30},
+			},
+		},
+		{
+			Label: "node-2",
+			Series: Series{
+				{Time: model.TimeFromUnix(now.Unix()).Time(), Value: 10},
+			},
+		},
+	})
+	c.Assert(series[0].Series.Last(), check.Equals, 30)
+	c.Assert(Series(nil).Last(), check.Equals, 0)
+}
+
+func (s *MetricsSuite) TestLabelsNodeSeriesWithNodeNames(c *check.C) {
+	api := &fakeAPI{
+		matrix: model.Matrix{
+			{
+				Metric: model.Metric{"instance": "10.0.0.1:9100", labelNode: "node-1"},
+				Values: []model.SamplePair{{Timestamp: model.TimeFromUnix(1500000000), Value: 10}},
+			},
+		},
+	}
+	metrics := &prometheus{API: api}
+	series, err := metrics.GetDiskReadRates(context.TODO(), v1.Range{})
+	c.Assert(err, check.IsNil)
+	c.Assert(series, check.HasLen, 1)
+	c.Assert(series[0].Label, check.Equals, "node-1")
+	c.Assert(api.query, check.Matches, `.* on \(instance\) group_left \(nodename\) .*\(node_uname_info\)`)
+}
+
+// fakeAPI is a Prometheus API client that returns the configured
+// range query results
+type fakeAPI struct {
+	v1.API
+	matrix model.Matrix
+	// query is the last issued range query
+	query string
+}
+
+func (a *fakeAPI) QueryRange(ctx context.Context, query string, r v1.Range) (model.Value, error) {
+	a.query = query
+	return a.matrix, nil
+}
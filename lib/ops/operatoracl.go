@@ -452,6 +452,27 @@ func (o *OperatorACL) ResumeShrink(key SiteKey) (*SiteOperationKey, error) {
 	return o.operator.ResumeShrink(key)
 }
 
+func (o *OperatorACL) RunShrinkPhase(ctx context.Context, req RunPhaseRequest) error {
+	if err := o.ClusterAction(req.Key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.RunShrinkPhase(ctx, req)
+}
+
+func (o *OperatorACL) RunUninstallPhase(ctx context.Context, req RunPhaseRequest) error {
+	if err := o.ClusterAction(req.Key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.RunUninstallPhase(ctx, req)
+}
+
+func (o *OperatorACL) DryRunOperationPhase(ctx context.Context, req RunPhaseRequest) ([]storage.PhaseDryRun, error) {
+	if err := o.ClusterAction(req.Key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.DryRunOperationPhase(ctx, req)
+}
+
 func (o *OperatorACL) CreateSiteExpandOperation(ctx context.Context, req CreateSiteExpandOperationRequest) (*SiteOperationKey, error) {
 	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
 		return nil, trace.Wrap(err)
@@ -752,6 +773,83 @@ func (o *OperatorACL) DeleteSMTPConfig(ctx context.Context, key SiteKey) error {
 	return o.operator.DeleteSMTPConfig(ctx, key)
 }
 
+func (o *OperatorACL) GetTrustPolicy(key SiteKey) (storage.TrustPolicy, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustPolicy, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetTrustPolicy(key)
+}
+
+func (o *OperatorACL) UpsertTrustPolicy(ctx context.Context, key SiteKey, policy storage.TrustPolicy) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustPolicy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertTrustPolicy(ctx, key, policy)
+}
+
+func (o *OperatorACL) DeleteTrustPolicy(ctx context.Context, key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindTrustPolicy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteTrustPolicy(ctx, key)
+}
+
+func (o *OperatorACL) GetMaintenanceWindow(key SiteKey) (storage.MaintenanceWindow, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindMaintenanceWindow, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetMaintenanceWindow(key)
+}
+
+func (o *OperatorACL) UpsertMaintenanceWindow(ctx context.Context, key SiteKey, window storage.MaintenanceWindow) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindMaintenanceWindow, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertMaintenanceWindow(ctx, key, window)
+}
+
+func (o *OperatorACL) DeleteMaintenanceWindow(ctx context.Context, key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindMaintenanceWindow, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteMaintenanceWindow(ctx, key)
+}
+
+func (o *OperatorACL) QueueOperation(ctx context.Context, req QueueOperationRequest) (*storage.QueuedOperation, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.QueueOperation(ctx, req)
+}
+
+func (o *OperatorACL) GetQueuedOperations(key SiteKey) ([]storage.QueuedOperation, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetQueuedOperations(key)
+}
+
+func (o *OperatorACL) RenewQueuedOperation(ctx context.Context, key QueuedOperationKey) (*storage.QueuedOperation, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.RenewQueuedOperation(ctx, key)
+}
+
+func (o *OperatorACL) CancelQueuedOperation(ctx context.Context, key QueuedOperationKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.CancelQueuedOperation(ctx, key)
+}
+
+func (o *OperatorACL) WatchCluster(ctx context.Context, req WatchClusterRequest) (<-chan storage.ClusterChange, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.WatchCluster(ctx, req)
+}
+
 func (o *OperatorACL) GetAlerts(key SiteKey) ([]storage.Alert, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlert, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
@@ -132,6 +132,10 @@ type Operator interface {
 	RuntimeEnvironment
 	ClusterConfiguration
 	PersistentStorage
+	TrustPolicies
+	MaintenanceWindows
+	OperationQueue
+	Watches
 	Audit
 }
 
@@ -817,6 +821,18 @@ type Operations interface {
 	// its leadership
 	ResumeShrink(key SiteKey) (*SiteOperationKey, error)
 
+	// RunShrinkPhase executes or rolls back the specified phase
+	// of the shrink operation plan
+	RunShrinkPhase(context.Context, RunPhaseRequest) error
+
+	// RunUninstallPhase executes or rolls back the specified phase
+	// of the uninstall operation plan
+	RunUninstallPhase(context.Context, RunPhaseRequest) error
+
+	// DryRunOperationPhase describes the changes the specified phase
+	// of the shrink or uninstall operation plan would make if executed
+	DryRunOperationPhase(context.Context, RunPhaseRequest) ([]storage.PhaseDryRun, error)
+
 	// UpdateInstallOperationState updates the state of an install operation
 	UpdateInstallOperationState(key SiteOperationKey, req OperationUpdateRequest) error
 
@@ -1343,8 +1359,37 @@ func (r *CreateSiteShrinkOperationRequest) CheckAndSetDefaults() error {
 	if len(r.Servers) == 0 {
 		return trace.BadParameter("expected a server to remove")
 	}
-	if len(r.Servers) != 1 {
-		return trace.BadParameter("can delete only one server at a time, got: %v", r.Servers)
+	seen := make(map[string]bool, len(r.Servers))
+	for _, server := range r.Servers {
+		if seen[server] {
+			return trace.BadParameter("server %v is specified more than once", server)
+		}
+		seen[server] = true
+	}
+	return nil
+}
+
+// RunPhaseRequest is a request to execute or roll back a phase
+// of an operation plan executed by the operator
+type RunPhaseRequest struct {
+	// Key identifies the operation
+	Key SiteOperationKey `json:"key"`
+	// PhaseID is the ID of the phase to run.
+	// The root phase ID ("/") resumes the operation
+	PhaseID string `json:"phase_id"`
+	// Force forces execution or rollback of the phase regardless of its state
+	Force bool `json:"force"`
+	// Rollback specifies whether the phase should be rolled back instead of executed
+	Rollback bool `json:"rollback"`
+}
+
+// Check makes sure the request is correct
+func (r RunPhaseRequest) Check() error {
+	if err := r.Key.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.PhaseID == "" {
+		return trace.BadParameter("missing PhaseID")
 	}
 	return nil
 }
@@ -1362,6 +1407,17 @@ type CreateSiteAppUpdateOperationRequest struct {
 	StartAgents bool `json:"start_agents"`
 	// Vars are variables specific to this operation
 	Vars storage.OperationVariables `json:"vars"`
+	// Strategy optionally defines the order in which the regular nodes are updated
+	Strategy *storage.UpdateStrategy `json:"strategy,omitempty"`
+	// RollbackOnFailure specifies whether the operation is automatically
+	// rolled back if it fails
+	RollbackOnFailure bool `json:"rollback_on_failure,omitempty"`
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window. The override is audited
+	IgnoreMaintenanceWindow bool `json:"ignore_maintenance_window,omitempty"`
+	// QueueID is the ID of the operation queue entry to start
+	// the operation from
+	QueueID string `json:"queue_id,omitempty"`
 }
 
 // Check validates this request
@@ -1382,6 +1438,12 @@ type CreateClusterGarbageCollectOperationRequest struct {
 	AccountID string `json:"account_id"`
 	// ClusterName is the name of the cluster
 	ClusterName string `json:"cluster_name"`
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window. The override is audited
+	IgnoreMaintenanceWindow bool `json:"ignore_maintenance_window,omitempty"`
+	// QueueID is the ID of the operation queue entry to start
+	// the operation from
+	QueueID string `json:"queue_id,omitempty"`
 }
 
 // CreateClusterReconfigureOperationRequest is a request to initialize
@@ -1421,6 +1483,12 @@ type CreateUpdateEnvarsOperationRequest struct {
 	ClusterKey SiteKey `json:"cluster_key"`
 	// Env specifies the new cluster environment variables
 	Env map[string]string `json:"env"`
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window. The override is audited
+	IgnoreMaintenanceWindow bool `json:"ignore_maintenance_window,omitempty"`
+	// QueueID is the ID of the operation queue entry to start
+	// the operation from
+	QueueID string `json:"queue_id,omitempty"`
 }
 
 // CreateUpdateConfigOperationRequest is a request
@@ -1430,6 +1498,12 @@ type CreateUpdateConfigOperationRequest struct {
 	ClusterKey SiteKey `json:"cluster_key"`
 	// Config specifies the new configuration as JSON-encoded payload
 	Config []byte `json:"config"`
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window. The override is audited
+	IgnoreMaintenanceWindow bool `json:"ignore_maintenance_window,omitempty"`
+	// QueueID is the ID of the operation queue entry to start
+	// the operation from
+	QueueID string `json:"queue_id,omitempty"`
 }
 
 // UpdateClusterEnvironRequest is a request
@@ -1967,6 +2041,196 @@ type SMTP interface {
 	DeleteSMTPConfig(context.Context, SiteKey) error
 }
 
+// TrustPolicies defines the interface to manage the cluster trust policy
+// for package signatures
+type TrustPolicies interface {
+	// GetTrustPolicy returns the cluster trust policy
+	GetTrustPolicy(SiteKey) (storage.TrustPolicy, error)
+	// UpsertTrustPolicy creates or updates the cluster trust policy
+	UpsertTrustPolicy(context.Context, SiteKey, storage.TrustPolicy) error
+	// DeleteTrustPolicy deletes the cluster trust policy
+	DeleteTrustPolicy(context.Context, SiteKey) error
+}
+
+// MaintenanceWindows defines the interface to manage the cluster maintenance window
+// that restricts when disruptive operations can be started
+type MaintenanceWindows interface {
+	// GetMaintenanceWindow returns the cluster maintenance window
+	GetMaintenanceWindow(SiteKey) (storage.MaintenanceWindow, error)
+	// UpsertMaintenanceWindow creates or updates the cluster maintenance window
+	UpsertMaintenanceWindow(context.Context, SiteKey, storage.MaintenanceWindow) error
+	// DeleteMaintenanceWindow deletes the cluster maintenance window
+	DeleteMaintenanceWindow(context.Context, SiteKey) error
+}
+
+// OperationQueue manages operations waiting for the cluster to become idle.
+//
+// Queued operations are started by the cluster operator, in the order they
+// have been queued in, once the cluster becomes idle. The client that has
+// queued an operation renews its entry while it waits for the operation to
+// start. Entries that have not been started before their TTL elapses, or whose
+// client has stopped renewing them, are removed
+type OperationQueue interface {
+	// QueueOperation adds an operation to the cluster operation queue
+	QueueOperation(context.Context, QueueOperationRequest) (*storage.QueuedOperation, error)
+	// GetQueuedOperations returns the cluster operation queue in the order
+	// the operations will be started in
+	GetQueuedOperations(SiteKey) ([]storage.QueuedOperation, error)
+	// RenewQueuedOperation extends the lease on the specified queued operation
+	// and returns the updated queue entry
+	RenewQueuedOperation(context.Context, QueuedOperationKey) (*storage.QueuedOperation, error)
+	// CancelQueuedOperation removes the specified operation from the queue
+	CancelQueuedOperation(context.Context, QueuedOperationKey) error
+}
+
+// QueueOperationRequest is a request to add an operation to the cluster operation queue
+type QueueOperationRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey `json:"site_key"`
+	// Request is the request to create the operation with
+	Request QueuedOperationRequest `json:"request"`
+	// Description is an optional human-readable operation description
+	Description string `json:"description,omitempty"`
+	// TTL is how long the operation stays in the queue
+	// if it has not been started
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *QueueOperationRequest) CheckAndSetDefaults() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.Request.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.TTL < 0 {
+		return trace.BadParameter("TTL cannot be negative")
+	}
+	if r.TTL == 0 {
+		r.TTL = defaults.OperationQueueTTL
+	}
+	return nil
+}
+
+// QueuedOperationRequest is the request a queued operation is created from.
+// Exactly one of the requests is set
+type QueuedOperationRequest struct {
+	// Update is the request to create an update operation
+	Update *CreateSiteAppUpdateOperationRequest `json:"update,omitempty"`
+	// Environ is the request to create a runtime environment update operation
+	Environ *CreateUpdateEnvarsOperationRequest `json:"environ,omitempty"`
+	// Config is the request to create a configuration update operation
+	Config *CreateUpdateConfigOperationRequest `json:"config,omitempty"`
+	// GarbageCollect is the request to create a garbage collection operation
+	GarbageCollect *CreateClusterGarbageCollectOperationRequest `json:"garbage_collect,omitempty"`
+}
+
+// Check validates this request
+func (r QueuedOperationRequest) Check() error {
+	var count int
+	for _, set := range []bool{r.Update != nil, r.Environ != nil, r.Config != nil, r.GarbageCollect != nil} {
+		if set {
+			count++
+		}
+	}
+	if count != 1 {
+		return trace.BadParameter("exactly one of %v operation requests should be set",
+			strings.Join(QueueableOperations, ", "))
+	}
+	return nil
+}
+
+// Type returns the type of the operation this request creates
+func (r QueuedOperationRequest) Type() string {
+	switch {
+	case r.Update != nil:
+		return OperationUpdate
+	case r.Environ != nil:
+		return OperationUpdateRuntimeEnviron
+	case r.Config != nil:
+		return OperationUpdateConfig
+	case r.GarbageCollect != nil:
+		return OperationGarbageCollect
+	}
+	return ""
+}
+
+// Create creates the operation in the specified cluster on behalf
+// of the queue entry with the given ID
+func (r QueuedOperationRequest) Create(ctx context.Context, operator Operator, key SiteKey, queueID string) (*SiteOperationKey, error) {
+	switch {
+	case r.Update != nil:
+		req := *r.Update
+		req.AccountID, req.SiteDomain, req.QueueID = key.AccountID, key.SiteDomain, queueID
+		return operator.CreateSiteAppUpdateOperation(ctx, req)
+	case r.Environ != nil:
+		req := *r.Environ
+		req.ClusterKey, req.QueueID = key, queueID
+		return operator.CreateUpdateEnvarsOperation(ctx, req)
+	case r.Config != nil:
+		req := *r.Config
+		req.ClusterKey, req.QueueID = key, queueID
+		return operator.CreateUpdateConfigOperation(ctx, req)
+	case r.GarbageCollect != nil:
+		req := *r.GarbageCollect
+		req.AccountID, req.ClusterName, req.QueueID = key.AccountID, key.SiteDomain, queueID
+		return operator.CreateClusterGarbageCollectOperation(ctx, req)
+	}
+	return nil, trace.BadParameter("missing operation request")
+}
+
+// QueuedOperationKey identifies an entry in the cluster operation queue
+type QueuedOperationKey struct {
+	// SiteKey identifies the cluster
+	SiteKey `json:"site_key"`
+	// ID is the queue entry ID
+	ID string `json:"id"`
+}
+
+// Check validates this key
+func (r QueuedOperationKey) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.ID == "" {
+		return trace.BadParameter("missing queued operation ID")
+	}
+	return nil
+}
+
+// QueueableOperations lists the types of operations that can be queued
+var QueueableOperations = []string{
+	OperationUpdate,
+	OperationUpdateRuntimeEnviron,
+	OperationUpdateConfig,
+	OperationGarbageCollect,
+}
+
+// Watches defines the interface to watch for changes to clusters and operations
+type Watches interface {
+	// WatchCluster returns a channel that receives notifications about changes
+	// to the cluster and its operations until the context is cancelled.
+	// The channel is closed when the watch stops
+	WatchCluster(context.Context, WatchClusterRequest) (<-chan storage.ClusterChange, error)
+}
+
+// WatchClusterRequest is a request to watch for cluster changes
+type WatchClusterRequest struct {
+	// SiteKey identifies the cluster to watch
+	SiteKey `json:"site_key"`
+	// OperationID optionally limits the watch to the specified operation
+	OperationID string `json:"operation_id,omitempty"`
+}
+
+// Prefix returns the storage watch prefix for this request
+func (r WatchClusterRequest) Prefix() []string {
+	if r.OperationID != "" {
+		return storage.OperationWatchPrefix(r.SiteDomain, r.OperationID)
+	}
+	return storage.ClusterWatchPrefix(r.SiteDomain)
+}
+
 // Monitoring defines the interface to manage monitoring and metrics
 type Monitoring interface {
 	// GetAlerts returns the list of configured monitoring alerts
@@ -1997,6 +2261,9 @@ type ClusterMetricsRequest struct {
 	//
 	// If left unspecified, defaults to 15 seconds.
 	Step time.Duration `json:"step"`
+	// Breakdown requests per-node and per-namespace usage breakdown
+	// and disk/network I/O metrics in addition to cluster totals.
+	Breakdown bool `json:"breakdown,omitempty"`
 }
 
 // CheckAndSetDefaults validates the request and fills in defaults.
@@ -2023,6 +2290,28 @@ type ClusterMetricsResponse struct {
 	CPURates ClusterMetricsRates `json:"cpu_rates"`
 	// MemoryRates contains current/max/historic memory usage rates.
 	MemoryRates ClusterMetricsRates `json:"memory_rates"`
+	// Breakdown contains per-node and per-namespace metrics if requested.
+	Breakdown *ClusterMetricsBreakdown `json:"breakdown,omitempty"`
+}
+
+// ClusterMetricsBreakdown contains per-node and per-namespace historic metrics.
+type ClusterMetricsBreakdown struct {
+	// NodeCPURates contains CPU usage rates of individual nodes.
+	NodeCPURates []monitoring.LabeledSeries `json:"node_cpu_rates"`
+	// NodeMemoryRates contains memory usage rates of individual nodes.
+	NodeMemoryRates []monitoring.LabeledSeries `json:"node_memory_rates"`
+	// NamespaceCPUUsage contains CPU usage of individual namespaces in millicores.
+	NamespaceCPUUsage []monitoring.LabeledSeries `json:"namespace_cpu_usage"`
+	// NamespaceMemoryUsage contains memory usage of individual namespaces in bytes.
+	NamespaceMemoryUsage []monitoring.LabeledSeries `json:"namespace_memory_usage"`
+	// DiskReadRates contains disk read throughput of individual nodes in bytes per second.
+	DiskReadRates []monitoring.LabeledSeries `json:"disk_read_rates"`
+	// DiskWriteRates contains disk write throughput of individual nodes in bytes per second.
+	DiskWriteRates []monitoring.LabeledSeries `json:"disk_write_rates"`
+	// NetworkReceiveRates contains network receive throughput of individual nodes in bytes per second.
+	NetworkReceiveRates []monitoring.LabeledSeries `json:"network_receive_rates"`
+	// NetworkTransmitRates contains network transmit throughput of individual nodes in bytes per second.
+	NetworkTransmitRates []monitoring.LabeledSeries `json:"network_transmit_rates"`
 }
 
 // ClusterMetricsRates encapsulates usage rates.
@@ -41,6 +41,7 @@ import (
 	telehttplib "github.com/gravitational/teleport/lib/httplib"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
 )
 
 const CurrentVersion = "portal/v1"
@@ -491,6 +492,34 @@ func (c *Client) ResumeShrink(key ops.SiteKey) (*ops.SiteOperationKey, error) {
 	return &opKey, trace.Wrap(err)
 }
 
+func (c *Client) RunShrinkPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	_, err := c.PostJSONWithContext(ctx, c.Endpoint(
+		"accounts", req.Key.AccountID, "sites", req.Key.SiteDomain, "operations", "common",
+		req.Key.OperationID, "shrink", "phase"), req)
+	return trace.Wrap(err)
+}
+
+func (c *Client) RunUninstallPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	_, err := c.PostJSONWithContext(ctx, c.Endpoint(
+		"accounts", req.Key.AccountID, "sites", req.Key.SiteDomain, "operations", "common",
+		req.Key.OperationID, "uninstall", "phase"), req)
+	return trace.Wrap(err)
+}
+
+func (c *Client) DryRunOperationPhase(ctx context.Context, req ops.RunPhaseRequest) ([]storage.PhaseDryRun, error) {
+	out, err := c.PostJSONWithContext(ctx, c.Endpoint(
+		"accounts", req.Key.AccountID, "sites", req.Key.SiteDomain, "operations", "common",
+		req.Key.OperationID, "phase", "dry-run"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var phases []storage.PhaseDryRun
+	if err := json.Unmarshal(out.Bytes(), &phases); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return phases, nil
+}
+
 func (c *Client) GetSiteInstallOperationAgentReport(key ops.SiteOperationKey) (*ops.AgentReport, error) {
 	out, err := c.Get(context.TODO(), c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "operations", "install",
 		key.OperationID, "agent-report"), url.Values{})
@@ -1016,8 +1045,9 @@ func (c *Client) DeleteLogForwarder(ctx context.Context, key ops.SiteKey, forwar
 func (c *Client) GetClusterMetrics(ctx context.Context, req ops.ClusterMetricsRequest) (*ops.ClusterMetricsResponse, error) {
 	response, err := c.Get(context.TODO(), c.Endpoint("accounts", req.AccountID, "sites",
 		req.SiteDomain, "monitoring", "metrics"), url.Values{
-		"interval": []string{req.Interval.String()},
-		"step":     []string{req.Step.String()},
+		"interval":  []string{req.Interval.String()},
+		"step":      []string{req.Step.String()},
+		"breakdown": []string{strconv.FormatBool(req.Breakdown)},
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -1068,6 +1098,166 @@ func (c *Client) DeleteSMTPConfig(ctx context.Context, key ops.SiteKey) error {
 	return trace.Wrap(err)
 }
 
+// GetTrustPolicy returns the cluster trust policy
+func (c *Client) GetTrustPolicy(key ops.SiteKey) (storage.TrustPolicy, error) {
+	response, err := c.Get(context.TODO(), c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "trustpolicy"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var raw json.RawMessage
+	if err := json.Unmarshal(response.Bytes(), &raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	policy, err := storage.UnmarshalTrustPolicy(raw)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return policy, nil
+}
+
+// UpsertTrustPolicy creates or updates the cluster trust policy
+func (c *Client) UpsertTrustPolicy(ctx context.Context, key ops.SiteKey, policy storage.TrustPolicy) error {
+	bytes, err := storage.MarshalTrustPolicy(policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustpolicy"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteTrustPolicy deletes the cluster trust policy
+func (c *Client) DeleteTrustPolicy(ctx context.Context, key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustpolicy"))
+	return trace.Wrap(err)
+}
+
+// GetMaintenanceWindow returns the cluster maintenance window
+func (c *Client) GetMaintenanceWindow(key ops.SiteKey) (storage.MaintenanceWindow, error) {
+	response, err := c.Get(context.TODO(), c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "maintenancewindow"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var raw json.RawMessage
+	if err := json.Unmarshal(response.Bytes(), &raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	window, err := storage.UnmarshalMaintenanceWindow(raw)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return window, nil
+}
+
+// UpsertMaintenanceWindow creates or updates the cluster maintenance window
+func (c *Client) UpsertMaintenanceWindow(ctx context.Context, key ops.SiteKey, window storage.MaintenanceWindow) error {
+	bytes, err := storage.MarshalMaintenanceWindow(window)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "maintenancewindow"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteMaintenanceWindow deletes the cluster maintenance window
+func (c *Client) DeleteMaintenanceWindow(ctx context.Context, key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "maintenancewindow"))
+	return trace.Wrap(err)
+}
+
+// QueueOperation adds an operation to the cluster operation queue
+func (c *Client) QueueOperation(ctx context.Context, req ops.QueueOperationRequest) (*storage.QueuedOperation, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "queue"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var op storage.QueuedOperation
+	if err := json.Unmarshal(out.Bytes(), &op); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// GetQueuedOperations returns the cluster operation queue in the order
+// the operations will be started in
+func (c *Client) GetQueuedOperations(key ops.SiteKey) ([]storage.QueuedOperation, error) {
+	out, err := c.Get(context.TODO(), c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "queue"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var queue []storage.QueuedOperation
+	if err := json.Unmarshal(out.Bytes(), &queue); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return queue, nil
+}
+
+// RenewQueuedOperation extends the lease on the specified queued operation
+// and returns the updated queue entry
+func (c *Client) RenewQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) (*storage.QueuedOperation, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "queue", key.ID, "renew"), key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var op storage.QueuedOperation
+	if err := json.Unmarshal(out.Bytes(), &op); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// CancelQueuedOperation removes the specified operation from the queue
+func (c *Client) CancelQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "queue", key.ID))
+	return trace.Wrap(err)
+}
+
+// WatchCluster returns a channel that receives notifications about changes
+// to the cluster and its operations until the context is cancelled
+func (c *Client) WatchCluster(ctx context.Context, req ops.WatchClusterRequest) (<-chan storage.ClusterChange, error) {
+	endpoint := c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "watch")
+	if req.OperationID != "" {
+		endpoint = fmt.Sprintf("%v?%v", endpoint, url.Values{"operation_id": []string{req.OperationID}}.Encode())
+	}
+	stream, err := httplib.SetupWebsocketClient(ctx, &c.Client, endpoint, c.dialer)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	doneC := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-doneC:
+		}
+		stream.Close()
+	}()
+	changesC := make(chan storage.ClusterChange)
+	go func() {
+		defer close(changesC)
+		defer close(doneC)
+		decoder := json.NewDecoder(stream)
+		for {
+			var change storage.ClusterChange
+			if err := decoder.Decode(&change); err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					logrus.WithError(err).Warn("Failed to decode cluster change.")
+				}
+				return
+			}
+			select {
+			case changesC <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changesC, nil
+}
+
 // GetAlerts returns a list of monitoring alerts for the cluster
 func (c *Client) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	response, err := c.Get(context.TODO(), c.Endpoint(
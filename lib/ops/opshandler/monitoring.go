@@ -18,6 +18,7 @@ package opshandler
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gravitational/gravity/lib/ops"
@@ -33,7 +34,7 @@ import (
 
 /* getClusterMetrics returns basic CPU/RAM metrics for the cluster.
 
-     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/metrics
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/metrics?interval=<interval>&step=<step>&breakdown=<true|false>
 
    Success Response:
 
@@ -55,11 +56,18 @@ func (h *WebHandler) getClusterMetrics(w http.ResponseWriter, r *http.Request, p
 			return trace.Wrap(err)
 		}
 	}
+	var breakdown bool
+	if b := r.Form.Get("breakdown"); b != "" {
+		if breakdown, err = strconv.ParseBool(b); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	metrics, err := context.Operator.GetClusterMetrics(r.Context(),
 		ops.ClusterMetricsRequest{
-			SiteKey:  siteKey(p),
-			Interval: interval,
-			Step:     step,
+			SiteKey:   siteKey(p),
+			Interval:  interval,
+			Step:      step,
+			Breakdown: breakdown,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -244,6 +244,9 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/crash-report", h.needsAuth(h.getSiteOperationCrashReport))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/complete", h.needsAuth(h.completeSiteOperation))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan", h.needsAuth(h.createOperationPlan))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/shrink/phase", h.needsAuth(h.runShrinkPhase))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/uninstall/phase", h.needsAuth(h.runUninstallPhase))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/phase/dry-run", h.needsAuth(h.dryRunOperationPhase))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan/changelog", h.needsAuth(h.createOperationPlanChange))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan", h.needsAuth(h.getOperationPlan))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan/configure", h.needsAuth(h.configurePackages))
@@ -260,6 +263,25 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.updateSMTPConfig))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.deleteSMTPConfig))
 
+	// package trust policy
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy", h.needsAuth(h.getTrustPolicy))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy", h.needsAuth(h.upsertTrustPolicy))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy", h.needsAuth(h.deleteTrustPolicy))
+
+	// maintenance window
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow", h.needsAuth(h.getMaintenanceWindow))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow", h.needsAuth(h.upsertMaintenanceWindow))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow", h.needsAuth(h.deleteMaintenanceWindow))
+
+	// operation queue
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/queue", h.needsAuth(h.queueOperation))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/queue", h.needsAuth(h.getQueuedOperations))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/queue/:queue_id/renew", h.needsAuth(h.renewQueuedOperation))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/queue/:queue_id", h.needsAuth(h.cancelQueuedOperation))
+
+	// cluster changes
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/watch", h.needsAuth(h.watchCluster))
+
 	// monitoring
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alerts", h.needsAuth(h.getAlerts))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alerts/:name", h.needsAuth(h.updateAlert))
@@ -1412,6 +1434,91 @@ func (h *WebHandler) resumeShrink(w http.ResponseWriter, r *http.Request, p http
 	return nil
 }
 
+/* runShrinkPhase executes or rolls back a phase of the shrink operation plan
+
+   POST	/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/shrink/phase
+
+   {
+      "phase_id": "/etcd",
+      "force": false,
+      "rollback": false
+   }
+
+Success response:
+
+   {
+      "status": "ok"
+   }
+*/
+func (h *WebHandler) runShrinkPhase(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.RunPhaseRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.Key = siteOperationKey(p)
+	if err := context.Operator.RunShrinkPhase(r.Context(), req); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("phase completed"))
+	return nil
+}
+
+/* runUninstallPhase executes or rolls back a phase of the uninstall operation plan
+
+   POST	/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/uninstall/phase
+
+   {
+      "phase_id": "/teardown/node-1",
+      "force": false,
+      "rollback": false
+   }
+
+Success response:
+
+   {
+      "status": "ok"
+   }
+*/
+func (h *WebHandler) runUninstallPhase(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.RunPhaseRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.Key = siteOperationKey(p)
+	if err := context.Operator.RunUninstallPhase(r.Context(), req); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("phase completed"))
+	return nil
+}
+
+/* dryRunOperationPhase describes the changes a phase of the shrink or uninstall
+   operation plan would make if executed
+
+   POST	/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/phase/dry-run
+
+   {
+      "phase_id": "/etcd"
+   }
+
+Success response:
+
+   [{"phase": "/etcd/node-2", "description": "...", "report": {...}}, ...]
+*/
+func (h *WebHandler) dryRunOperationPhase(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.RunPhaseRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.Key = siteOperationKey(p)
+	phases, err := context.Operator.DryRunOperationPhase(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, phases)
+	return nil
+}
+
 /* createSiteInstallOperation creates site install operation. Note that
 it does not starts actuall uninstall, but rather creates a record to configure
 and track uninstall
@@ -2224,6 +2331,257 @@ func (h *WebHandler) deleteSMTPConfig(w http.ResponseWriter, r *http.Request, p
 	return nil
 }
 
+/* getTrustPolicy returns the cluster trust policy
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy
+
+   Success Response:
+
+     storage.TrustPolicy
+*/
+func (h *WebHandler) getTrustPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	policy, err := context.Operator.GetTrustPolicy(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, policy)
+	return nil
+}
+
+/* upsertTrustPolicy creates or updates the cluster trust policy
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy
+
+   Success Response:
+
+     {
+       "message": "trust policy updated"
+     }
+*/
+func (h *WebHandler) upsertTrustPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	policy, err := storage.UnmarshalTrustPolicy(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = context.Operator.UpsertTrustPolicy(r.Context(), siteKey(p), policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("trust policy updated"))
+	return nil
+}
+
+/* deleteTrustPolicy deletes the cluster trust policy
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/trustpolicy
+
+   Success Response:
+
+     {
+       "message": "trust policy deleted"
+     }
+*/
+func (h *WebHandler) deleteTrustPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteTrustPolicy(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("trust policy deleted"))
+	return nil
+}
+
+/* getMaintenanceWindow returns the cluster maintenance window
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow
+
+   Success Response:
+
+     storage.MaintenanceWindow
+*/
+func (h *WebHandler) getMaintenanceWindow(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	window, err := context.Operator.GetMaintenanceWindow(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, window)
+	return nil
+}
+
+/* upsertMaintenanceWindow creates or updates the cluster maintenance window
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow
+
+   Success Response:
+
+     {
+       "message": "maintenance window updated"
+     }
+*/
+func (h *WebHandler) upsertMaintenanceWindow(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	window, err := storage.UnmarshalMaintenanceWindow(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = context.Operator.UpsertMaintenanceWindow(r.Context(), siteKey(p), window)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("maintenance window updated"))
+	return nil
+}
+
+/* deleteMaintenanceWindow deletes the cluster maintenance window
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/maintenancewindow
+
+   Success Response:
+
+     {
+       "message": "maintenance window deleted"
+     }
+*/
+func (h *WebHandler) deleteMaintenanceWindow(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteMaintenanceWindow(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("maintenance window deleted"))
+	return nil
+}
+
+/* queueOperation adds an operation to the cluster operation queue
+
+     POST /portal/v1/accounts/:account_id/sites/:site_domain/queue
+
+   Input: ops.QueueOperationRequest
+
+   Success Response:
+
+     storage.QueuedOperation
+*/
+func (h *WebHandler) queueOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.QueueOperationRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.SiteKey = siteKey(p)
+	op, err := context.Operator.QueueOperation(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, op)
+	return nil
+}
+
+/* getQueuedOperations returns the cluster operation queue
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/queue
+
+   Success Response:
+
+     []storage.QueuedOperation
+*/
+func (h *WebHandler) getQueuedOperations(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	queue, err := context.Operator.GetQueuedOperations(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, queue)
+	return nil
+}
+
+/* renewQueuedOperation extends the lease on the specified queued operation
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/queue/:queue_id/renew
+
+   Success Response:
+
+     storage.QueuedOperation
+*/
+func (h *WebHandler) renewQueuedOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	op, err := context.Operator.RenewQueuedOperation(r.Context(), ops.QueuedOperationKey{
+		SiteKey: siteKey(p),
+		ID:      p.ByName("queue_id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, op)
+	return nil
+}
+
+/* cancelQueuedOperation removes the specified operation from the queue
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/queue/:queue_id
+
+   Success Response:
+
+     {
+       "message": "queued operation cancelled"
+     }
+*/
+func (h *WebHandler) cancelQueuedOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.CancelQueuedOperation(r.Context(), ops.QueuedOperationKey{
+		SiteKey: siteKey(p),
+		ID:      p.ByName("queue_id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("queued operation cancelled"))
+	return nil
+}
+
+/* watchCluster is a web socket method that returns a stream of changes
+   to the cluster and its operations
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/watch?operation_id=<operation-id>
+
+   Each message is a JSON-encoded change:
+
+     {
+       "type": "updated",
+       "kind": "plan",
+       "cluster_name": "example.com",
+       "operation_id": "operation id"
+     }
+*/
+func (h *WebHandler) watchCluster(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	// the request context is cancelled once the connection has been served
+	changesC, err := context.Operator.WatchCluster(r.Context(), ops.WatchClusterRequest{
+		SiteKey:     siteKey(p),
+		OperationID: r.URL.Query().Get("operation_id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		for change := range changesC {
+			if err := encoder.Encode(change); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.Close()
+	}()
+	ws := &httplib.WebSocketReader{
+		Reader: reader,
+	}
+	defer ws.Close()
+	ws.Handler().ServeHTTP(w, r)
+	return nil
+}
+
 /* getApplicationEndpoints returns application endpoints for a deployed cluster
 
      GET /portal/v1/accounts/:account_id/sites/:site_domain/endpoints
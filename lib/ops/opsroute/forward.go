@@ -304,6 +304,26 @@ func (r *Router) ResumeShrink(key ops.SiteKey) (*ops.SiteOperationKey, error) {
 	return r.Local.ResumeShrink(key)
 }
 
+func (r *Router) RunShrinkPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	client, err := r.PickOperationClient(req.Key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.RunShrinkPhase(ctx, req)
+}
+
+func (r *Router) RunUninstallPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	return r.Local.RunUninstallPhase(ctx, req)
+}
+
+func (r *Router) DryRunOperationPhase(ctx context.Context, req ops.RunPhaseRequest) ([]storage.PhaseDryRun, error) {
+	client, err := r.PickOperationClient(req.Key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.DryRunOperationPhase(ctx, req)
+}
+
 func (r *Router) CreateSiteExpandOperation(ctx context.Context, req ops.CreateSiteExpandOperationRequest) (*ops.SiteOperationKey, error) {
 	client, err := r.PickOperationClient(req.SiteDomain)
 	if err != nil {
@@ -609,6 +629,106 @@ func (r *Router) DeleteSMTPConfig(ctx context.Context, key ops.SiteKey) error {
 	return client.DeleteSMTPConfig(ctx, key)
 }
 
+// GetTrustPolicy returns the cluster trust policy
+func (r *Router) GetTrustPolicy(key ops.SiteKey) (storage.TrustPolicy, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetTrustPolicy(key)
+}
+
+// UpsertTrustPolicy creates or updates the cluster trust policy
+func (r *Router) UpsertTrustPolicy(ctx context.Context, key ops.SiteKey, policy storage.TrustPolicy) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertTrustPolicy(ctx, key, policy)
+}
+
+// DeleteTrustPolicy deletes the cluster trust policy
+func (r *Router) DeleteTrustPolicy(ctx context.Context, key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteTrustPolicy(ctx, key)
+}
+
+// GetMaintenanceWindow returns the cluster maintenance window
+func (r *Router) GetMaintenanceWindow(key ops.SiteKey) (storage.MaintenanceWindow, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetMaintenanceWindow(key)
+}
+
+// UpsertMaintenanceWindow creates or updates the cluster maintenance window
+func (r *Router) UpsertMaintenanceWindow(ctx context.Context, key ops.SiteKey, window storage.MaintenanceWindow) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertMaintenanceWindow(ctx, key, window)
+}
+
+// DeleteMaintenanceWindow deletes the cluster maintenance window
+func (r *Router) DeleteMaintenanceWindow(ctx context.Context, key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteMaintenanceWindow(ctx, key)
+}
+
+// QueueOperation adds an operation to the cluster operation queue
+func (r *Router) QueueOperation(ctx context.Context, req ops.QueueOperationRequest) (*storage.QueuedOperation, error) {
+	client, err := r.RemoteClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.QueueOperation(ctx, req)
+}
+
+// GetQueuedOperations returns the cluster operation queue
+func (r *Router) GetQueuedOperations(key ops.SiteKey) ([]storage.QueuedOperation, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetQueuedOperations(key)
+}
+
+// RenewQueuedOperation extends the lease on the specified queued operation
+func (r *Router) RenewQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) (*storage.QueuedOperation, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.RenewQueuedOperation(ctx, key)
+}
+
+// CancelQueuedOperation removes the specified operation from the queue
+func (r *Router) CancelQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.CancelQueuedOperation(ctx, key)
+}
+
+// WatchCluster returns a channel that receives notifications about changes
+// to the cluster and its operations
+func (r *Router) WatchCluster(ctx context.Context, req ops.WatchClusterRequest) (<-chan storage.ClusterChange, error) {
+	client, err := r.PickOperationClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.WatchCluster(ctx, req)
+}
+
 // GetAlerts returns a list of monitoring alerts
 func (r *Router) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
@@ -154,6 +154,8 @@ func (s *site) createUpdateConfigOperation(ctx context.Context, req ops.CreateUp
 			PrevConfig: prevConfig,
 			Config:     req.Config,
 		},
+		IgnoreMaintenanceWindow: req.IgnoreMaintenanceWindow,
+		QueueID:                 req.QueueID,
 	}
 	key, err := s.getOperationGroup().createSiteOperation(op)
 	if err != nil {
@@ -151,6 +151,8 @@ func (s *site) createUpdateEnvarsOperation(ctx context.Context, req ops.CreateUp
 			PrevEnv: prevEnv,
 			Env:     req.Env,
 		},
+		IgnoreMaintenanceWindow: req.IgnoreMaintenanceWindow,
+		QueueID:                 req.QueueID,
 	}
 	key, err := s.getOperationGroup().createSiteOperation(op)
 	if err != nil {
@@ -19,6 +19,7 @@ package opsservice
 import (
 	"context"
 
+	libapp "github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
@@ -125,12 +126,14 @@ func (s *site) expandOperationStart(ctx *operationContext) error {
 
 func (s *site) validateExpand(op *ops.SiteOperation, req *ops.OperationUpdateRequest) error {
 	if op.Provisioner == schema.ProvisionerOnPrem {
-		if len(req.Servers) > 1 {
+		if len(req.Servers) == 0 {
 			return trace.BadParameter(
-				"can only add one node at a time, stop agents on %v extra node(-s)", len(req.Servers)-1)
-		} else if len(req.Servers) == 0 {
+				"no servers provided, run agent command on the nodes you want to join")
+		}
+		if requested := requestedServers(*op.InstallExpand); requested != 0 && len(req.Servers) > requested {
 			return trace.BadParameter(
-				"no servers provided, run agent command on the node you want to join")
+				"the operation adds %v node(-s), stop agents on %v extra node(-s)",
+				requested, len(req.Servers)-requested)
 		}
 	}
 	for role := range req.Profiles {
@@ -143,15 +146,43 @@ func (s *site) validateExpand(op *ops.SiteOperation, req *ops.OperationUpdateReq
 				"server profile %q does not allow expansion", role)
 		}
 	}
+	err := setExpandClusterRoles(req.Servers, *s.app, op.InstallExpand.Masters)
+	return trace.Wrap(err)
+}
 
-	labels := map[string]string{
-		schema.ServiceLabelRole: string(schema.ServiceRoleMaster),
-	}
-	masters, err := s.teleport().GetServers(context.TODO(), s.domainName, labels)
-	if err != nil {
-		return trace.Wrap(err)
+// requestedServers returns the number of servers requested by the provided
+// expand operation state
+func requestedServers(state storage.InstallExpandOperationState) (count int) {
+	for _, profile := range state.Profiles {
+		count += profile.Request.Count
 	}
+	return count
+}
 
-	err = setClusterRoles(req.Servers, *s.app, len(masters))
-	return trace.Wrap(err)
+// setExpandClusterRoles assigns cluster roles to the servers joining the cluster.
+// Servers whose node profile does not specify the cluster role become masters
+// until the number of masters reserved by the expand operation is exhausted
+func setExpandClusterRoles(servers []storage.Server, app libapp.Application, masters int) error {
+	for i, server := range servers {
+		profile, err := app.Manifest.NodeProfiles.ByName(server.Role)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		switch profile.ServiceRole {
+		case "":
+			if masters > 0 {
+				servers[i].ClusterRole = string(schema.ServiceRoleMaster)
+				masters--
+			} else {
+				servers[i].ClusterRole = string(schema.ServiceRoleNode)
+			}
+		case schema.ServiceRoleMaster, schema.ServiceRoleNode:
+			servers[i].ClusterRole = string(profile.ServiceRole)
+		default:
+			return trace.BadParameter(
+				"unknown cluster role %q for node profile %q",
+				profile.ServiceRole, server.Role)
+		}
+	}
+	return nil
 }
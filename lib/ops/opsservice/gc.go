@@ -34,14 +34,16 @@ func (s *site) createGarbageCollectOperation(ctx context.Context, req ops.Create
 	}
 
 	op := ops.SiteOperation{
-		ID:         uuid.New(),
-		AccountID:  s.key.AccountID,
-		SiteDomain: s.key.SiteDomain,
-		Type:       ops.OperationGarbageCollect,
-		Created:    s.clock().UtcNow(),
-		CreatedBy:  storage.UserFromContext(ctx),
-		Updated:    s.clock().UtcNow(),
-		State:      ops.OperationGarbageCollectInProgress,
+		ID:                      uuid.New(),
+		AccountID:               s.key.AccountID,
+		SiteDomain:              s.key.SiteDomain,
+		Type:                    ops.OperationGarbageCollect,
+		Created:                 s.clock().UtcNow(),
+		CreatedBy:               storage.UserFromContext(ctx),
+		Updated:                 s.clock().UtcNow(),
+		State:                   ops.OperationGarbageCollectInProgress,
+		IgnoreMaintenanceWindow: req.IgnoreMaintenanceWindow,
+		QueueID:                 req.QueueID,
 	}
 
 	key, err := s.getOperationGroup().createSiteOperation(op)
@@ -27,6 +27,7 @@ import (
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -219,13 +220,8 @@ func (s *site) runClusterDeprovisionHook(ctx *operationContext) error {
 	return s.runIntegrationHook(ctx, job, request, secretParams)
 }
 
-// runNodesDeprovisionHook runs hook to deprovison nodes
-func (s *site) runNodesDeprovisionHook(ctx *operationContext) error {
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 50,
-		Message:    "Running nodes deprovisioning hook",
-	})
+// runNodesDeprovisionHook runs hook to deprovison the specified node
+func (s *site) runNodesDeprovisionHook(ctx *operationContext, server storage.Server) error {
 	site, err := s.service.GetSite(s.key)
 	if err != nil {
 		return trace.Wrap(err)
@@ -255,7 +251,6 @@ func (s *site) runNodesDeprovisionHook(ctx *operationContext) error {
 		env[fmt.Sprintf(constants.EnvTelekubeNodeProfileCountTemplate, profileName)] = fmt.Sprintf("%v", len(servers))
 	}
 	env[constants.EnvTelekubeNodeProfiles] = strings.Join(profiles, ",")
-	server := ctx.serversToRemove[0]
 	switch provider := s.cloudProvider().(type) {
 	case *aws:
 		env[constants.EnvAWSInstancePrivateIP] = server.AdvertiseIP
@@ -67,6 +67,9 @@ func (o *Operator) StreamOperationLogs(key ops.SiteOperationKey, reader io.Reade
 // createInstallOperation initiates install operation for a given site
 // it makes sure that install operation is the first operation too
 func (s *site) createInstallOperation(ctx context.Context, req ops.CreateSiteInstallOperationRequest) (*ops.SiteOperationKey, error) {
+	if err := s.verifySignatures(s.app.Package); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	profiles := make(map[string]storage.ServerProfile)
 	for _, profile := range s.app.Manifest.NodeProfiles {
 		profiles[profile.Name] = storage.ServerProfile{
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// GetMaintenanceWindow returns the cluster maintenance window
+func (o *Operator) GetMaintenanceWindow(key ops.SiteKey) (storage.MaintenanceWindow, error) {
+	window, err := o.backend().GetMaintenanceWindow()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return window, nil
+}
+
+// UpsertMaintenanceWindow creates or updates the cluster maintenance window
+func (o *Operator) UpsertMaintenanceWindow(ctx context.Context, key ops.SiteKey, window storage.MaintenanceWindow) error {
+	if err := window.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.backend().UpsertMaintenanceWindow(window); err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.MaintenanceWindowUpdated)
+	return nil
+}
+
+// DeleteMaintenanceWindow deletes the cluster maintenance window
+func (o *Operator) DeleteMaintenanceWindow(ctx context.Context, key ops.SiteKey) error {
+	if err := o.backend().DeleteMaintenanceWindow(); err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.MaintenanceWindowDeleted)
+	return nil
+}
+
+// checkMaintenanceWindow verifies that the specified operation is allowed
+// to start at this time with regard to the cluster maintenance window.
+//
+// Returns true if the window is closed but the operation has been explicitly
+// allowed to ignore it.
+// In case the operation is not allowed, returns trace.CompareFailed error
+func (o *Operator) checkMaintenanceWindow(operation ops.SiteOperation) (overridden bool, err error) {
+	if !utils.StringInSlice(maintenanceOperations, operation.Type) {
+		return false, nil
+	}
+	window, err := o.backend().GetMaintenanceWindow()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	now := o.cfg.Clock.UtcNow()
+	if window.IsOpen(now) {
+		return false, nil
+	}
+	if operation.IgnoreMaintenanceWindow {
+		return true, nil
+	}
+	return false, trace.CompareFailed("%v operation can only be started during the maintenance window, "+
+		"next window opens at %v", operation.Type, window.NextOpen(now).Format(time.RFC1123))
+}
+
+// maintenanceOperations lists the types of operations
+// restricted by the cluster maintenance window
+var maintenanceOperations = []string{
+	ops.OperationUpdate,
+	ops.OperationUpdateRuntimeEnviron,
+	ops.OperationUpdateConfig,
+	ops.OperationGarbageCollect,
+}
@@ -87,6 +87,17 @@ func GetClusterMetrics(ctx context.Context, metrics monitoring.Metrics, req ops.
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	var breakdown *ops.ClusterMetricsBreakdown
+	if req.Breakdown {
+		breakdown, err = getClusterMetricsBreakdown(ctx, metrics, monitoringv1.Range{
+			Start: time.Now().Add(-req.Interval),
+			End:   time.Now(),
+			Step:  req.Step,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 	return &ops.ClusterMetricsResponse{
 		TotalCPUCores:    totalCPUCores,
 		TotalMemoryBytes: totalRAMBytes,
@@ -100,9 +111,37 @@ func GetClusterMetrics(ctx context.Context, metrics monitoring.Metrics, req ops.
 			Max:      maxRAMRate,
 			Historic: historicRAMRate,
 		},
+		Breakdown: breakdown,
 	}, nil
 }
 
+// getClusterMetricsBreakdown retrieves per-node and per-namespace metrics
+// for the specified time range from the provided client.
+func getClusterMetricsBreakdown(ctx context.Context, metrics monitoring.Metrics, timeRange monitoringv1.Range) (*ops.ClusterMetricsBreakdown, error) {
+	var breakdown ops.ClusterMetricsBreakdown
+	queries := []struct {
+		query  func(context.Context, monitoringv1.Range) ([]monitoring.LabeledSeries, error)
+		result *[]monitoring.LabeledSeries
+	}{
+		{metrics.GetNodeCPURates, &breakdown.NodeCPURates},
+		{metrics.GetNodeMemoryRates, &breakdown.NodeMemoryRates},
+		{metrics.GetNamespaceCPUUsage, &breakdown.NamespaceCPUUsage},
+		{metrics.GetNamespaceMemoryUsage, &breakdown.NamespaceMemoryUsage},
+		{metrics.GetDiskReadRates, &breakdown.DiskReadRates},
+		{metrics.GetDiskWriteRates, &breakdown.DiskWriteRates},
+		{metrics.GetNetworkReceiveRates, &breakdown.NetworkReceiveRates},
+		{metrics.GetNetworkTransmitRates, &breakdown.NetworkTransmitRates},
+	}
+	for _, q := range queries {
+		series, err := q.query(ctx, timeRange)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		*q.result = series
+	}
+	return &breakdown, nil
+}
+
 // GetAlerts returns a list of configured monitoring alerts
 func (o *Operator) GetAlerts(key ops.SiteKey) (alerts []storage.Alert, err error) {
 	client, err := o.GetKubeClient()
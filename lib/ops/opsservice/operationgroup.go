@@ -73,6 +73,23 @@ func (g *operationGroup) createSiteOperation(operation ops.SiteOperation) (*ops.
 		return nil, trace.Wrap(err)
 	}
 
+	if operation.Type == ops.OperationExpand && operation.InstallExpand != nil {
+		operation.InstallExpand.Masters, err = g.reserveMasters(operation)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+
+	err = g.operator.checkOperationQueue(operation)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	overridden, err := g.operator.checkMaintenanceWindow(operation)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	site, err := g.operator.openSite(g.siteKey)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -93,11 +110,24 @@ func (g *operationGroup) createSiteOperation(operation ops.SiteOperation) (*ops.
 		return nil, trace.Wrap(err)
 	}
 
+	if op.QueueID != "" {
+		err = g.operator.backend().DeleteQueuedOperation(op.SiteDomain, op.QueueID)
+		if err != nil && !trace.IsNotFound(err) {
+			log.WithError(err).Warnf("Failed to remove %v from the operation queue.", op.QueueID)
+		}
+	}
+
 	err = g.emitAuditEvent(context.TODO(), *op)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
+	if overridden {
+		log.Warnf("%v operation %v started outside of the maintenance window.", op.Type, op.ID)
+		events.Emit(context.TODO(), g.operator, events.MaintenanceWindowOverridden,
+			events.FieldsForOperation(*op))
+	}
+
 	key := op.Key()
 	return &key, nil
 }
@@ -174,42 +204,68 @@ func (g *operationGroup) canCreateExpandOperation(site ops.Site, operation ops.S
 	}
 
 	if len(operations) >= defaults.MaxExpandConcurrency {
-		return trace.CompareFailed("at most %v nodes can be joining simultaneously",
+		return trace.CompareFailed("at most %v expand operations can be running simultaneously",
 			defaults.MaxExpandConcurrency)
 	}
 
-	// if an expand operation that's adding master node is currently running,
-	// it has to finish before another expand can be started
+	// master nodes can be joining concurrently with other nodes as the
+	// expand operations serialize etcd membership changes themselves
+	return nil
+}
+
+// reserveMasters returns the number of nodes joining with the provided expand
+// operation that become masters if their node profile does not specify the
+// cluster role.
+//
+// Existing masters, nodes joining with the master node profiles and masters
+// reserved by other active expand operations all count towards the maximum
+// number of master nodes. Since it runs under the group lock, concurrent
+// expand operations cannot reserve more masters than the cluster can have.
+func (g *operationGroup) reserveMasters(operation ops.SiteOperation) (int, error) {
+	cluster, err := g.operator.GetSite(g.siteKey)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+
+	operations, err := ops.GetActiveOperationsByType(g.siteKey, g.operator, ops.OperationExpand)
+	if err != nil && !trace.IsNotFound(err) {
+		return 0, trace.Wrap(err)
+	}
+
+	masters := len(storage.Servers(cluster.ClusterState.Servers).Masters())
 	for _, op := range operations {
-		for _, node := range op.Servers {
-			if node.ClusterRole == string(schema.ServiceRoleMaster) {
-				return trace.CompareFailed("can't launch another expand while master node %v is joining",
-					node.AdvertiseIP)
-			}
+		if op.ID == operation.ID || op.InstallExpand == nil {
+			continue
 		}
+		joining, _ := joiningMasters(*op.InstallExpand)
+		masters += joining + op.InstallExpand.Masters
 	}
 
-	// now check the opposite use-case: if we're about to add a master,
-	// it has to be the only operation running
-	for _, profile := range operation.InstallExpand.Profiles {
-		switch profile.ServiceRole {
-		case string(schema.ServiceRoleMaster):
-			// the joining node wants to be a master
-			return trace.CompareFailed("can't join master node while another node is joining")
+	joining, unassigned := joiningMasters(*operation.InstallExpand)
+	reserved := defaults.MaxMasterNodes - masters - joining
+	switch {
+	case reserved <= 0:
+		return 0, nil
+	case reserved > unassigned:
+		return unassigned, nil
+	default:
+		return reserved, nil
+	}
+}
+
+// joiningMasters returns the number of nodes requested by the provided
+// expand operation state with the master node profiles and the number of
+// nodes requested with node profiles that do not specify the cluster role
+func joiningMasters(state storage.InstallExpandOperationState) (masters, unassigned int) {
+	for _, profile := range state.Profiles {
+		switch schema.ServiceRole(profile.ServiceRole) {
+		case schema.ServiceRoleMaster:
+			masters += profile.Request.Count
 		case "":
-			// cluster role was not set explicitly on the joining node, so
-			// it will be auto-set to master if the max number of masters
-			// haven't been reached yet
-			if len(site.Masters()) < defaults.MaxMasterNodes {
-				return trace.CompareFailed("can't join master node while another node is joining")
-			}
+			unassigned += profile.Request.Count
 		}
 	}
-
-	// if we've reached here, we're about to join a regular node, there are
-	// other regular nodes joining right now too and the total number of join
-	// operations is under the maximum
-	return nil
+	return masters, unassigned
 }
 
 // compareAndSwapOperationState changes the operation state according to the provided spec
@@ -17,14 +17,20 @@ limitations under the License.
 package opsservice
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/suite"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	"github.com/mailgun/timetools"
 	"gopkg.in/check.v1"
 )
 
@@ -143,6 +149,110 @@ func (s *OperationGroupSuite) TestExpandMaxConcurrency(c *check.C) {
 	s.assertClusterState(c, ops.SiteStateExpanding)
 }
 
+// Makes sure master nodes can join concurrently with other nodes
+func (s *OperationGroupSuite) TestExpandConcurrentMasters(c *check.C) {
+	group := s.operator.getOperationGroup(s.cluster.Key())
+
+	// initiate and finalize the install operation
+	key, err := group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(key, check.NotNil)
+
+	_, err = group.compareAndSwapOperationState(swap{
+		key:            *key,
+		expectedStates: []string{ops.OperationStateInstallInitiated},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	c.Assert(err, check.IsNil)
+	s.assertClusterState(c, ops.SiteStateActive)
+
+	roles := []schema.ServiceRole{schema.ServiceRoleMaster, schema.ServiceRoleNode, schema.ServiceRoleMaster}
+	for i, role := range roles {
+		_, err = group.createSiteOperation(ops.SiteOperation{
+			AccountID:  s.cluster.AccountID,
+			SiteDomain: s.cluster.Domain,
+			Type:       ops.OperationExpand,
+			State:      ops.OperationStateExpandInitiated,
+			InstallExpand: &storage.InstallExpandOperationState{
+				Profiles: map[string]storage.ServerProfile{
+					string(role): storage.ServerProfile{
+						ServiceRole: string(role),
+					},
+				},
+			},
+			Servers: []storage.Server{{
+				Hostname:    fmt.Sprintf("node-%v", i),
+				Role:        string(role),
+				ClusterRole: string(role),
+			}},
+		})
+		c.Assert(err, check.IsNil)
+		s.assertClusterState(c, ops.SiteStateExpanding)
+	}
+}
+
+// Makes sure concurrent expand operations do not reserve more masters
+// than the cluster can have
+func (s *OperationGroupSuite) TestExpandReservesMasters(c *check.C) {
+	group := s.operator.getOperationGroup(s.cluster.Key())
+
+	// initiate and finalize the install operation
+	key, err := group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(key, check.NotNil)
+
+	_, err = group.compareAndSwapOperationState(swap{
+		key:            *key,
+		expectedStates: []string{ops.OperationStateInstallInitiated},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	c.Assert(err, check.IsNil)
+
+	err = group.addClusterStateServers([]storage.Server{{
+		Hostname:    "node-0",
+		ClusterRole: string(schema.ServiceRoleMaster),
+	}})
+	c.Assert(err, check.IsNil)
+
+	// the cluster has one master so the first operation joining a master
+	// and two nodes without cluster role can only promote one of them,
+	// and the following operations cannot promote any
+	expected := []int{1, 0, 0}
+	for _, masters := range expected {
+		key, err := group.createSiteOperation(ops.SiteOperation{
+			AccountID:  s.cluster.AccountID,
+			SiteDomain: s.cluster.Domain,
+			Type:       ops.OperationExpand,
+			State:      ops.OperationStateExpandInitiated,
+			InstallExpand: &storage.InstallExpandOperationState{
+				Profiles: map[string]storage.ServerProfile{
+					"master": storage.ServerProfile{
+						ServiceRole: string(schema.ServiceRoleMaster),
+						Request:     storage.ServerProfileRequest{Count: 1},
+					},
+					"any": storage.ServerProfile{
+						Request: storage.ServerProfileRequest{Count: 2},
+					},
+				},
+			},
+		})
+		c.Assert(err, check.IsNil)
+		operation, err := s.operator.GetSiteOperation(*key)
+		c.Assert(err, check.IsNil)
+		c.Assert(operation.InstallExpand.Masters, check.Equals, masters)
+	}
+}
+
 // Makes sure cannot expand shrinking cluster
 func (s *OperationGroupSuite) TestFailsToExpandShrinkingCluster(c *check.C) {
 	group := s.operator.getOperationGroup(s.cluster.Key())
@@ -287,6 +397,242 @@ func (s *OperationGroupSuite) TestClusterStateModifications(c *check.C) {
 	s.assertServerCount(c, 2)
 }
 
+// Makes sure operations restricted by the maintenance window can only be
+// created outside of the window if explicitly requested
+func (s *OperationGroupSuite) TestMaintenanceWindow(c *check.C) {
+	group := s.operator.getOperationGroup(s.cluster.Key())
+
+	// initiate and finalize the install operation
+	key, err := group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = group.compareAndSwapOperationState(swap{
+		key:            *key,
+		expectedStates: []string{ops.OperationStateInstallInitiated},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	c.Assert(err, check.IsNil)
+
+	err = s.operator.UpsertMaintenanceWindow(context.TODO(), s.cluster.Key(),
+		storage.NewMaintenanceWindow(storage.MaintenanceWindowSpecV2{
+			Windows: []storage.Window{{
+				Days:     []string{"sat"},
+				Start:    "01:00",
+				Duration: teleservices.NewDuration(4 * time.Hour),
+			}},
+		}))
+	c.Assert(err, check.IsNil)
+	// Wednesday, outside of the window
+	s.operator.cfg.Clock = &timetools.FreezedTime{
+		CurrentTime: time.Date(2019, time.June, 5, 12, 0, 0, 0, time.UTC),
+	}
+
+	operation := ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationGarbageCollect,
+		State:      ops.OperationGarbageCollectInProgress,
+	}
+	_, err = group.createSiteOperation(operation)
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, ".*next window opens at Sat, 08 Jun 2019 01:00:00 UTC")
+	s.assertClusterState(c, ops.SiteStateActive)
+
+	operation.IgnoreMaintenanceWindow = true
+	_, err = group.createSiteOperation(operation)
+	c.Assert(err, check.IsNil)
+	s.assertClusterState(c, ops.SiteStateGarbageCollecting)
+}
+
+func (s *OperationGroupSuite) TestOperationQueue(c *check.C) {
+	group := s.operator.getOperationGroup(s.cluster.Key())
+
+	// initiate and finalize the install operation
+	key, err := group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = group.compareAndSwapOperationState(swap{
+		key:            *key,
+		expectedStates: []string{ops.OperationStateInstallInitiated},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	c.Assert(err, check.IsNil)
+	err = s.operator.CreateProgressEntry(*key, ops.ProgressEntry{
+		SiteDomain:  key.SiteDomain,
+		OperationID: key.OperationID,
+		Completion:  constants.Completed,
+		State:       ops.ProgressStateCompleted,
+		Created:     time.Now().UTC(),
+	})
+	c.Assert(err, check.IsNil)
+
+	clock := &timetools.FreezedTime{
+		CurrentTime: time.Date(2019, time.June, 5, 12, 0, 0, 0, time.UTC),
+	}
+	s.operator.cfg.Clock = clock
+	var queue []storage.QueuedOperation
+	for _, req := range []ops.QueuedOperationRequest{
+		{GarbageCollect: &ops.CreateClusterGarbageCollectOperationRequest{}},
+		{Environ: &ops.CreateUpdateEnvarsOperationRequest{Env: map[string]string{"A": "B"}}},
+	} {
+		op, err := s.operator.QueueOperation(context.TODO(), ops.QueueOperationRequest{
+			SiteKey: s.cluster.Key(),
+			Request: req,
+			TTL:     time.Hour,
+		})
+		c.Assert(err, check.IsNil)
+		queue = append(queue, *op)
+		clock.CurrentTime = clock.CurrentTime.Add(time.Second)
+	}
+	c.Assert(queue[0].Type, check.Equals, ops.OperationGarbageCollect)
+	c.Assert(queue[1].Type, check.Equals, ops.OperationUpdateRuntimeEnviron)
+	_, err = s.operator.QueueOperation(context.TODO(), ops.QueueOperationRequest{
+		SiteKey: s.cluster.Key(),
+	})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+
+	environ := ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationUpdateRuntimeEnviron,
+		State:      ops.OperationUpdateRuntimeEnvironInProgress,
+		QueueID:    queue[1].ID,
+	}
+	// queued operations cannot jump the queue
+	_, err = group.createSiteOperation(environ)
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true)
+	// queued operations cannot change type
+	environ.QueueID = queue[0].ID
+	_, err = group.createSiteOperation(environ)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	s.assertClusterState(c, ops.SiteStateActive)
+
+	// the operator starts the operation at the head of the queue
+	c.Assert(s.operator.ProcessOperationQueue(context.TODO(), s.cluster.Key()), check.IsNil)
+	s.assertClusterState(c, ops.SiteStateGarbageCollecting)
+	operations, err := s.operator.GetSiteOperations(s.cluster.Key())
+	c.Assert(err, check.IsNil)
+	c.Assert(operations[0].QueueID, check.Equals, queue[0].ID)
+	remaining, err := s.operator.GetQueuedOperations(s.cluster.Key())
+	c.Assert(err, check.IsNil)
+	c.Assert(remaining, check.DeepEquals, queue[1:])
+
+	// the next operation waits for the cluster to become idle
+	c.Assert(s.operator.ProcessOperationQueue(context.TODO(), s.cluster.Key()), check.IsNil)
+	clock.CurrentTime = clock.CurrentTime.Add(defaults.OperationQueueLeaseTimeout / 2)
+	entry, err := s.operator.RenewQueuedOperation(context.TODO(), ops.QueuedOperationKey{
+		SiteKey: s.cluster.Key(),
+		ID:      queue[1].ID,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.Reason, check.Matches, ".*is in progress")
+	c.Assert(entry.LastSeen, check.Equals, clock.CurrentTime)
+
+	// operations that have not been queued cannot bypass the queue
+	direct := ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationGarbageCollect,
+	}
+	err = s.operator.checkOperationQueue(direct)
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true)
+
+	// the next operation is removed once its client stops renewing it
+	clock.CurrentTime = clock.CurrentTime.Add(defaults.OperationQueueLeaseTimeout + time.Second)
+	c.Assert(s.operator.ProcessOperationQueue(context.TODO(), s.cluster.Key()), check.IsNil)
+	_, err = s.operator.backend().GetQueuedOperation(s.cluster.Domain, queue[1].ID)
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+	environ.QueueID = queue[1].ID
+	err = s.operator.checkOperationQueue(environ)
+	c.Assert(err, check.ErrorMatches, ".*has either been cancelled or has expired")
+	// and operations that have not been queued are allowed once the queue is empty
+	c.Assert(s.operator.checkOperationQueue(direct), check.IsNil)
+}
+
+// Makes sure operations waiting in the queue for the maintenance window
+// do not hold up operations that cannot be queued
+func (s *OperationGroupSuite) TestExpandWhileOperationQueued(c *check.C) {
+	group := s.operator.getOperationGroup(s.cluster.Key())
+
+	// initiate and finalize the install operation
+	key, err := group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = group.compareAndSwapOperationState(swap{
+		key:            *key,
+		expectedStates: []string{ops.OperationStateInstallInitiated},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	c.Assert(err, check.IsNil)
+	err = s.operator.CreateProgressEntry(*key, ops.ProgressEntry{
+		SiteDomain:  key.SiteDomain,
+		OperationID: key.OperationID,
+		Completion:  constants.Completed,
+		State:       ops.ProgressStateCompleted,
+		Created:     time.Now().UTC(),
+	})
+	c.Assert(err, check.IsNil)
+	err = group.addClusterStateServers([]storage.Server{{Hostname: "node-0"}})
+	c.Assert(err, check.IsNil)
+
+	err = s.operator.UpsertMaintenanceWindow(context.TODO(), s.cluster.Key(),
+		storage.NewMaintenanceWindow(storage.MaintenanceWindowSpecV2{
+			Windows: []storage.Window{{
+				Days:     []string{"sat"},
+				Start:    "01:00",
+				Duration: teleservices.NewDuration(4 * time.Hour),
+			}},
+		}))
+	c.Assert(err, check.IsNil)
+	// Wednesday, outside of the window
+	s.operator.cfg.Clock = &timetools.FreezedTime{
+		CurrentTime: time.Date(2019, time.June, 5, 12, 0, 0, 0, time.UTC),
+	}
+
+	queued, err := s.operator.QueueOperation(context.TODO(), ops.QueueOperationRequest{
+		SiteKey: s.cluster.Key(),
+		Request: ops.QueuedOperationRequest{
+			GarbageCollect: &ops.CreateClusterGarbageCollectOperationRequest{},
+		},
+		TTL: time.Hour,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(s.operator.ProcessOperationQueue(context.TODO(), s.cluster.Key()), check.IsNil)
+	entry, err := s.operator.backend().GetQueuedOperation(s.cluster.Domain, queued.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.Reason, check.Matches, ".*can only be started during the maintenance window.*")
+	c.Assert(entry.Error, check.Equals, "")
+
+	_, err = group.createSiteOperation(ops.SiteOperation{
+		AccountID:  s.cluster.AccountID,
+		SiteDomain: s.cluster.Domain,
+		Type:       ops.OperationExpand,
+		State:      ops.OperationStateExpandInitiated,
+		InstallExpand: &storage.InstallExpandOperationState{
+			Profiles: map[string]storage.ServerProfile{
+				"node": storage.ServerProfile{
+					ServiceRole: string(schema.ServiceRoleNode),
+				},
+			},
+		},
+		Servers: []storage.Server{{Hostname: "node-1", Role: "node"}},
+	})
+	c.Assert(err, check.IsNil)
+	s.assertClusterState(c, ops.SiteStateExpanding)
+}
+
 func (s *OperationGroupSuite) assertClusterState(c *check.C, state string) {
 	cluster, err := s.operator.GetSite(s.cluster.Key())
 	c.Assert(err, check.IsNil)
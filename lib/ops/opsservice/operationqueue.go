@@ -0,0 +1,247 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// QueueOperation adds an operation to the cluster operation queue
+func (o *Operator) QueueOperation(ctx context.Context, req ops.QueueOperationRequest) (*storage.QueuedOperation, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	request, err := json.Marshal(req.Request)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	now := o.cfg.Clock.UtcNow()
+	op, err := o.backend().CreateQueuedOperation(storage.QueuedOperation{
+		ID:          uuid.New(),
+		AccountID:   req.AccountID,
+		SiteDomain:  req.SiteDomain,
+		Type:        req.Request.Type(),
+		Description: req.Description,
+		Created:     now,
+		CreatedBy:   storage.UserFromContext(ctx),
+		Expires:     now.Add(req.TTL),
+		LastSeen:    now,
+		Request:     request,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o.Infof("Added %v.", op)
+	return op, nil
+}
+
+// GetQueuedOperations returns the cluster operation queue in the order
+// the operations will be started in
+func (o *Operator) GetQueuedOperations(key ops.SiteKey) ([]storage.QueuedOperation, error) {
+	queue, err := o.backend().GetQueuedOperations(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// not all backends expire entries on their own
+	now := o.cfg.Clock.UtcNow()
+	var out []storage.QueuedOperation
+	for _, op := range queue {
+		if !isStaleQueuedOperation(op, now) {
+			out = append(out, op)
+		}
+	}
+	return out, nil
+}
+
+// RenewQueuedOperation extends the lease on the specified queued operation
+// and returns the updated queue entry
+func (o *Operator) RenewQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) (*storage.QueuedOperation, error) {
+	if err := key.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	op, err := o.backend().GetQueuedOperation(key.SiteDomain, key.ID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	now := o.cfg.Clock.UtcNow()
+	if isStaleQueuedOperation(*op, now) {
+		return nil, trace.NotFound("queued operation(%v) not found", key.ID)
+	}
+	op.LastSeen = now
+	op, err = o.backend().UpdateQueuedOperation(*op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return op, nil
+}
+
+// CancelQueuedOperation removes the specified operation from the queue
+func (o *Operator) CancelQueuedOperation(ctx context.Context, key ops.QueuedOperationKey) error {
+	if err := key.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.backend().DeleteQueuedOperation(key.SiteDomain, key.ID); err != nil {
+		return trace.Wrap(err)
+	}
+	o.Infof("Cancelled queued operation %v by %v.", key.ID, storage.UserFromContext(ctx))
+	return nil
+}
+
+// ProcessOperationQueue removes stale entries from the cluster operation queue
+// and starts the operation at the head of the queue if the cluster is idle.
+//
+// Entries whose operation has failed to start are kept until their client
+// collects the error, but do not hold up the rest of the queue
+func (o *Operator) ProcessOperationQueue(ctx context.Context, key ops.SiteKey) error {
+	queue, err := o.backend().GetQueuedOperations(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	now := o.cfg.Clock.UtcNow()
+	for _, op := range queue {
+		if isStaleQueuedOperation(op, now) {
+			o.Infof("Removing %v: it has either expired or its client has gone away.", op)
+			err := o.backend().DeleteQueuedOperation(op.SiteDomain, op.ID)
+			if err != nil && !trace.IsNotFound(err) {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		if op.Error != "" {
+			continue
+		}
+		return trace.Wrap(o.startQueuedOperation(ctx, op))
+	}
+	return nil
+}
+
+// startQueuedOperation creates the specified queued operation unless
+// there are active operations in the cluster.
+//
+// The reason the operation cannot start yet, or the reason it has failed
+// to start, is recorded in the queue entry for its client
+func (o *Operator) startQueuedOperation(ctx context.Context, op storage.QueuedOperation) error {
+	clusterKey := ops.SiteKey{AccountID: op.AccountID, SiteDomain: op.SiteDomain}
+	active, err := ops.GetActiveOperations(clusterKey, o)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if len(active) != 0 {
+		return trace.Wrap(o.updateQueuedOperation(op,
+			fmt.Sprintf("%v is in progress", active[0].TypeString()), ""))
+	}
+	var req ops.QueuedOperationRequest
+	if err := json.Unmarshal(op.Request, &req); err != nil {
+		return trace.Wrap(o.updateQueuedOperation(op, "",
+			fmt.Sprintf("invalid operation request: %v", err)))
+	}
+	user := op.CreatedBy
+	if user == "" {
+		user = constants.ServiceOperationQueue
+	}
+	key, err := req.Create(context.WithValue(ctx, constants.UserContext, user), o, clusterKey, op.ID)
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.Wrap(o.updateQueuedOperation(op, trace.UserMessage(err), ""))
+		}
+		o.WithError(err).Warnf("Failed to start %v.", op)
+		return trace.Wrap(o.updateQueuedOperation(op, "", trace.UserMessage(err)))
+	}
+	o.Infof("Started operation %v from the queue.", key.OperationID)
+	return nil
+}
+
+// updateQueuedOperation records the waiting reason and the start error
+// in the specified queue entry if they have changed
+func (o *Operator) updateQueuedOperation(op storage.QueuedOperation, reason, errorMessage string) error {
+	if op.Reason == reason && op.Error == errorMessage {
+		return nil
+	}
+	op.Reason = reason
+	op.Error = errorMessage
+	_, err := o.backend().UpdateQueuedOperation(op)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// isStaleQueuedOperation returns true if the specified queue entry has either
+// expired or its client has stopped renewing it
+func isStaleQueuedOperation(op storage.QueuedOperation, now time.Time) bool {
+	return !op.Expires.After(now) || now.Sub(op.LastSeen) > defaults.OperationQueueLeaseTimeout
+}
+
+// checkOperationQueue verifies that the specified operation is allowed
+// to start with regard to the cluster operation queue.
+//
+// An operation created from the queue has to be at the head of the queue.
+// Operations of the queueable types that have not been queued are only
+// allowed to start if no queued operations are waiting, so they cannot jump
+// the queue. Other operations, like expand or shrink, are not affected by the queue.
+// Returns trace.CompareFailed if the operation is not allowed to start
+func (o *Operator) checkOperationQueue(operation ops.SiteOperation) error {
+	if operation.QueueID == "" && !utils.StringInSlice(ops.QueueableOperations, operation.Type) {
+		return nil
+	}
+	queue, err := o.GetQueuedOperations(operation.ClusterKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var ahead []storage.QueuedOperation
+	if operation.QueueID == "" {
+		for _, op := range queue {
+			// entries that have failed to start do not hold up the queue
+			if op.Error == "" {
+				return trace.CompareFailed("%v is waiting in the cluster "+
+					"operation queue, queue the operation or try again later", op)
+			}
+		}
+		return nil
+	}
+	for _, op := range queue {
+		if op.ID != operation.QueueID {
+			// entries that have failed to start do not hold up the queue
+			if op.Error == "" {
+				ahead = append(ahead, op)
+			}
+			continue
+		}
+		if op.Type != operation.Type {
+			return trace.BadParameter("queue entry %v is for %v operation, not %v",
+				op.ID, op.Type, operation.Type)
+		}
+		if len(ahead) != 0 {
+			return trace.CompareFailed("%v is waiting behind %v", op, ahead[0])
+		}
+		return nil
+	}
+	return trace.CompareFailed("queued operation %v not found: it has either "+
+		"been cancelled or has expired", operation.QueueID)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/rpc"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// planEngine implements the parts of the FSM engine common to the operations
+// which plans are executed by the operator itself, such as shrink and uninstall.
+//
+// The plan is stored in the operator backend and all phases execute
+// inside the operator process
+type planEngine struct {
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	site *site
+	ctx  *operationContext
+	// force specifies whether the phase failures should be ignored
+	// where possible
+	force bool
+}
+
+// ChangePhaseState creates a new changelog entry for the operation plan
+func (e *planEngine) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
+	key := e.ctx.key()
+	_, err := e.site.backend().CreateOperationPlanChange(storage.PlanChange{
+		ID:          uuid.New(),
+		ClusterName: key.SiteDomain,
+		OperationID: key.OperationID,
+		PhaseID:     change.Phase,
+		NewState:    change.State,
+		Error:       utils.ToRawTrace(change.Error),
+		Created:     e.site.clock().UtcNow(),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	e.WithField("change", change).Debug("Applied.")
+	return nil
+}
+
+// GetPlan returns the up-to-date operation plan
+func (e *planEngine) GetPlan() (*storage.OperationPlan, error) {
+	return fsm.GetOperationPlan(e.site.backend(), e.ctx.key())
+}
+
+// RunCommand is not supported as all phases are executed by the operator
+func (e *planEngine) RunCommand(context.Context, rpc.RemoteRunner, storage.Server, fsm.Params) error {
+	return trace.NotImplemented("%v phases cannot be executed remotely", e.ctx.operation.Type)
+}
+
+// newExecutor returns a new executor for the specified phase
+func (e *planEngine) newExecutor(phase storage.OperationPhase) *phaseExecutor {
+	return &phaseExecutor{
+		FieldLogger: e.WithField(constants.FieldPhase, phase.ID),
+		force:       e.force,
+		forceable:   true,
+	}
+}
+
+// hookDryRun returns a phase dry-run function that reports the specified
+// hook of the cluster application
+func (e *planEngine) hookDryRun(hook schema.HookType) func(context.Context) (*fsm.DryRunReport, error) {
+	return func(context.Context) (*fsm.DryRunReport, error) {
+		var report fsm.DryRunReport
+		return report.AddCommand("run %v hook for %v", hook, e.site.app.Package), nil
+	}
+}
+
+// reportFailure reports the failure of the plan execution in the operation progress.
+// The operation is left in progress so it can be resumed
+func (e *planEngine) reportFailure(message string, fsmErr error) {
+	if fsmErr != nil {
+		message = fmt.Sprintf("%v: %v", message, trace.Unwrap(fsmErr))
+	}
+	e.site.reportProgress(e.ctx, ops.ProgressEntry{
+		State:      ops.ProgressStateFailed,
+		Completion: constants.Completed,
+		Message: fmt.Sprintf("%v, use 'gravity plan' to inspect the operation "+
+			"and 'gravity plan resume' to resume it", message),
+	})
+}
+
+// updateProgress reports the progress of the phase about to be executed
+func (e *planEngine) updateProgress(ctx context.Context, p fsm.Params) error {
+	plan, err := e.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	phase, err := fsm.FindPhase(plan, p.PhaseID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if phase.HasSubphases() {
+		return nil
+	}
+	var completed, total int
+	for _, phase := range fsm.FlattenPlan(plan) {
+		if phase.HasSubphases() {
+			continue
+		}
+		total++
+		if phase.IsCompleted() {
+			completed++
+		}
+	}
+	e.site.reportProgress(e.ctx, ops.ProgressEntry{
+		State:      ops.ProgressStateInProgress,
+		Completion: completed * constants.Completed / total,
+		Message:    phase.Description,
+	})
+	return nil
+}
+
+// phaseExecutor executes a single phase of an operation plan executed by the operator
+type phaseExecutor struct {
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// execute implements the phase
+	execute func(context.Context) error
+	// rollback reverts the phase changes.
+	// Phases which changes cannot be reverted do not set it
+	rollback func(context.Context) error
+	// dryRun describes the changes the phase would make.
+	// Phases which make no changes do not set it
+	dryRun func(context.Context) (*fsm.DryRunReport, error)
+	// force specifies whether the operation is forced
+	force bool
+	// forceable specifies whether the phase failure can be ignored
+	// when the operation is forced
+	forceable bool
+	// healthChecks lists the health checks to verify after the phase
+	healthChecks []fsm.HealthCheck
+}
+
+// PreCheck is no-op for operator phases
+func (*phaseExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is no-op for operator phases
+func (*phaseExecutor) PostCheck(context.Context) error {
+	return nil
+}
+
+// Execute executes the phase.
+// If the operation is forced, the phase failure is logged and ignored
+func (r *phaseExecutor) Execute(ctx context.Context) error {
+	err := r.execute(ctx)
+	if err == nil {
+		return nil
+	}
+	if !r.forceable || !r.force {
+		return trace.Wrap(err)
+	}
+	r.Warnf("Phase failed, force continue: %v.", trace.DebugReport(err))
+	return nil
+}
+
+// HealthChecks returns the health checks to verify after the phase.
+// The checks are skipped if the phase failure would be ignored anyway
+func (r *phaseExecutor) HealthChecks() fsm.HealthChecks {
+	if r.forceable && r.force {
+		return fsm.HealthChecks{}
+	}
+	return fsm.HealthChecks{Checks: r.healthChecks}
+}
+
+// DryRun describes the changes the phase would make if executed
+func (r *phaseExecutor) DryRun(ctx context.Context) (*fsm.DryRunReport, error) {
+	if r.dryRun == nil {
+		return &fsm.DryRunReport{}, nil
+	}
+	report, err := r.dryRun(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return report, nil
+}
+
+// Rollback reverts the phase changes if possible
+func (r *phaseExecutor) Rollback(ctx context.Context) error {
+	if r.rollback == nil {
+		r.Info("Phase cannot be rolled back, nothing to do.")
+		return nil
+	}
+	return trace.Wrap(r.rollback(ctx))
+}
+
+// DryRunOperationPhase describes the changes the specified phase of the shrink
+// or uninstall operation plan would make if executed
+func (o *Operator) DryRunOperationPhase(ctx context.Context, req ops.RunPhaseRequest) ([]storage.PhaseDryRun, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	site, err := o.openSite(req.Key.SiteKey())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return site.dryRunOperationPhase(ctx, req)
+}
+
+// dryRunOperationPhase describes the changes the specified phase of the shrink
+// or uninstall operation plan would make if executed.
+// The operation plan is expected to exist
+func (s *site) dryRunOperationPhase(ctx context.Context, req ops.RunPhaseRequest) ([]storage.PhaseDryRun, error) {
+	op, err := s.getSiteOperation(req.Key.OperationID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opCtx, err := s.newOperationContext(*op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer opCtx.Close()
+
+	var machine *fsm.FSM
+	switch op.Type {
+	case ops.OperationShrink:
+		// the engine is not closed as it would reset the cloud provider
+		// of the shrink operation that might be running at the same time
+		if s.service.getCloudProvider(s.key) == nil {
+			defer s.service.deleteCloudProvider(s.key)
+		}
+		machine, _, err = s.newShrinkFSM(opCtx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	case ops.OperationUninstall:
+		machine, _, err = s.newUninstallFSM(opCtx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	default:
+		return nil, trace.BadParameter("operation type %q is not executed by the cluster operator",
+			op.Type)
+	}
+	phases, err := machine.DryRunPhase(ctx, req.PhaseID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return phases, nil
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package opsservice
 
 import (
+	"context"
+
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/trace"
 )
@@ -37,6 +39,18 @@ func (o *Operator) ResumeShrink(key ops.SiteKey) (*ops.SiteOperationKey, error)
 	return opKey, nil
 }
 
+// RunShrinkPhase executes or rolls back the specified phase of the shrink operation plan
+func (o *Operator) RunShrinkPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	site, err := o.openSite(req.Key.SiteKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(site.runShrinkPhase(ctx, req))
+}
+
 func (s *site) resumeShrink() (*ops.SiteOperationKey, error) {
 	s.Debug("resume shrink operation")
 
@@ -17,12 +17,14 @@ limitations under the License.
 package opsservice
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/pack"
@@ -47,7 +49,7 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 		return nil, trace.Wrap(err)
 	}
 
-	server, err := s.validateShrinkRequest(req, *cluster)
+	servers, err := s.validateShrinkRequest(req, *cluster)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -61,7 +63,7 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 		CreatedBy:   storage.UserFromContext(context),
 		Updated:     s.clock().UtcNow(),
 		State:       ops.OperationStateShrinkInProgress,
-		Provisioner: server.Provisioner,
+		Provisioner: servers[0].Provisioner,
 	}
 
 	ctx, err := s.newOperationContext(*op)
@@ -81,7 +83,7 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 	}
 
 	op.Shrink = &storage.ShrinkOperationState{
-		Servers:     []storage.Server{*server},
+		Servers:     servers,
 		Force:       req.Force,
 		Vars:        req.Variables,
 		NodeRemoved: req.NodeRemoved,
@@ -102,11 +104,25 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 		}
 	}
 
+	plan, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: *op,
+		Manifest:  s.app.Manifest,
+		Online:    s.getOnlineShrinkNodes(*op),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	key, err := s.getOperationGroup().createSiteOperation(*op)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
+	_, err = s.backend().CreateOperationPlan(*plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	s.reportProgress(ctx, ops.ProgressEntry{
 		State:      ops.ProgressStateInProgress,
 		Completion: 0,
@@ -121,314 +137,240 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 	return key, nil
 }
 
-func (s *site) validateShrinkRequest(req ops.CreateSiteShrinkOperationRequest, cluster ops.Site) (*storage.Server, error) {
-	serverName := req.Servers[0]
+func (s *site) validateShrinkRequest(req ops.CreateSiteShrinkOperationRequest, cluster ops.Site) ([]storage.Server, error) {
 	if len(cluster.ClusterState.Servers) == 1 {
 		return nil, trace.BadParameter(
 			"cannot shrink 1-node cluster, use --force flag to uninstall")
 	}
-
-	server, err := cluster.ClusterState.FindServer(serverName)
-	if err != nil {
-		return nil, trace.Wrap(err)
+	if len(req.Servers) >= len(cluster.ClusterState.Servers) {
+		return nil, trace.BadParameter(
+			"cannot remove all nodes of the cluster, use --force flag to uninstall")
 	}
 
-	// check to make sure the server exists and can be found
-	servers, err := s.getAllTeleportServers()
+	// check to make sure the servers exist and can be found
+	teleservers, err := s.getAllTeleportServers()
 	if err != nil {
 		return nil, trace.Wrap(err, "failed to query teleport servers")
 	}
 
-	masters := servers.getWithLabels(labels{schema.ServiceLabelRole: string(schema.ServiceRoleMaster)})
+	masters := teleservers.getWithLabels(labels{schema.ServiceLabelRole: string(schema.ServiceRoleMaster)})
 	if len(masters) == 0 {
 		return nil, trace.NotFound("no master servers found")
 	}
-	if len(masters) == 1 && masters[0].GetLabels()[ops.Hostname] == server.Hostname {
-		return nil, trace.BadParameter("cannot remove the last master server")
-	}
 
-	teleserver := servers.getWithLabels(labels{ops.Hostname: server.Hostname})
-	if len(teleserver) == 0 {
-		if !req.Force {
+	var servers []storage.Server
+	removedMasters := make(map[string]bool)
+	for _, serverName := range req.Servers {
+		server, err := cluster.ClusterState.FindServer(serverName)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(servers) != 0 && server.Provisioner != servers[0].Provisioner {
 			return nil, trace.BadParameter(
-				"node %q is offline, add --force flag to force removal", serverName)
+				"nodes with different provisioners cannot be removed together: %v and %v",
+				servers[0].Hostname, server.Hostname)
 		}
-		log.Warnf("Node %q is offline, forcing removal.", serverName)
+		teleserver := teleservers.getWithLabels(labels{ops.Hostname: server.Hostname})
+		if len(teleserver) == 0 {
+			if !req.Force {
+				return nil, trace.BadParameter(
+					"node %q is offline, add --force flag to force removal", serverName)
+			}
+			log.Warnf("Node %q is offline, forcing removal.", serverName)
+		}
+		removedMasters[server.Hostname] = server.IsMaster()
+		servers = append(servers, *server)
 	}
 
-	return server, nil
+	var remainingMasters int
+	for _, master := range masters {
+		if !removedMasters[master.GetLabels()[ops.Hostname]] {
+			remainingMasters++
+		}
+	}
+	if remainingMasters == 0 {
+		return nil, trace.BadParameter("cannot remove the last master server")
+	}
+
+	return servers, nil
 }
 
-// shrinkOperationStart kicks off actuall uninstall process:
-// deprovisions servers, deletes packages
+// shrinkOperationStart kicks off actual node removal by executing
+// the shrink operation plan
 func (s *site) shrinkOperationStart(ctx *operationContext) (err error) {
 	state := ctx.operation.Shrink
-	ctx.serversToRemove = state.Servers
-	force := state.Force
-
-	site, err := s.service.GetSite(s.key)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	server, err := site.ClusterState.FindServer(state.Servers[0].Hostname)
-	if err != nil {
-		return trace.Wrap(err)
-	}
 
 	// if the node is the gravity site leader (i.e. the process that is executing this code)
 	// is running on is being removed, give up the leadership so another process will pick up
 	// and resume the operation
-	if server.AdvertiseIP == os.Getenv(constants.EnvPodIP) {
+	if isLocalServerRemoved(state.Servers) {
 		ctx.RecordInfo("this node is being removed, stepping down")
 		s.leader().StepDown()
 		return nil
 	}
 
-	// if the operation was resumed, cloud provider might not be set
-	if s.service.getCloudProvider(s.key) == nil {
-		err = s.service.setCloudProviderFromRequest(
-			s.key, ctx.operation.Provisioner, &ctx.operation.Shrink.Vars)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-	}
-
-	serverName := server.Hostname
-
-	if force {
-		ctx.RecordInfo("forcing %q removal", serverName)
-	} else {
-		ctx.RecordInfo("starting %q removal", serverName)
+	// operations started before shrink was plan-based do not have a plan
+	if err := s.ensureShrinkPlan(ctx.operation); err != nil {
+		return trace.Wrap(err)
 	}
 
-	// shrink uses a couple of runners for the following purposes:
-	//  * teleport master runner is used to execute system commands that remove
-	//    the node from k8s, database, etc.
-	//  * agent runner runs on the removed node and is used to perform system
-	//    uninstall on it (if the node is online)
-	var masterRunner, agentRunner *serverRunner
-
-	masterRunner, err = s.pickShrinkMasterRunner(ctx, *server)
+	machine, engine, err := s.newShrinkFSM(ctx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	ctx.Infof("Selected %v (%v) as master runner.",
-		masterRunner.server.HostName(),
-		masterRunner.server.Address())
-
-	// determine whether the node being removed is online and, if so, launch
-	// a shrink agent on it
-	online := false
-	if !state.NodeRemoved {
-		_, err := s.getTeleportServerNoRetry(ops.Hostname, serverName)
-		if err != nil {
-			ctx.Warningf("node %q is offline: %v", serverName, trace.DebugReport(err))
-		} else {
-			agentRunner, err = s.launchAgent(ctx, *server)
-			if err != nil {
-				if !force {
-					return trace.Wrap(err)
-				}
-				ctx.Warningf("failed to launch agent on %q: %v", serverName, trace.DebugReport(err))
-			} else {
-				online = true
-			}
-		}
-	}
-
-	opKey := ctx.key()
-
-	// schedule some clean up actions to run regardless of the outcome of the operation
-	defer func() {
-		// erase cloud provider info for this site which may contain sensitive information
-		// such as API keys
-		s.service.deleteCloudProvider(s.key)
-		// stop running shrink agent
-		err := s.agentService().StopAgents(context.TODO(), opKey)
-		if err != nil {
-			ctx.Warningf("failed to stop shrink agent: %v", trace.DebugReport(err))
-		}
-	}()
+	defer engine.Close()
 
-	if online {
-		ctx.RecordInfo("node %q is online", serverName)
+	hostnames := shrinkHostnames(state.Servers)
+	if state.Force {
+		ctx.RecordInfo("forcing %v removal", hostnames)
 	} else {
-		ctx.RecordInfo("node %q is offline", serverName)
+		ctx.RecordInfo("starting %v removal", hostnames)
 	}
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 10,
-		Message:    "unregistering the node",
-	})
-
-	if err = s.unlabelNode(*server, masterRunner); err != nil {
-		if !force {
-			return trace.Wrap(err, "failed to unregister the node")
-		}
-		ctx.Warningf("failed to unregister the node, force continue: %v", trace.DebugReport(err))
+	fsmErr := machine.ExecutePlan(context.TODO(), utils.DiscardProgress)
+	if fsmErr != nil {
+		ctx.Warnf("Failed to execute shrink plan: %v.", trace.DebugReport(fsmErr))
 	}
+	return trace.Wrap(engine.Complete(fsmErr))
+}
 
-	if s.app.Manifest.HasHook(schema.HookNodeRemoving) {
-		s.reportProgress(ctx, ops.ProgressEntry{
-			State:      ops.ProgressStateInProgress,
-			Completion: 20,
-			Message:    "running pre-removal hooks",
-		})
-
-		if err = s.runHook(ctx, schema.HookNodeRemoving); err != nil {
-			if !force {
-				return trace.Wrap(err, "failed to run %v hook", schema.HookNodeRemoving)
-			}
-			ctx.Warningf("failed to run %v hook, force continue: %v", schema.HookNodeRemoving, trace.DebugReport(err))
-		}
+// runShrinkPhase executes or rolls back the specified phase of the shrink operation plan
+func (s *site) runShrinkPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	op, err := s.getSiteOperation(req.Key.OperationID)
+	if err != nil {
+		return trace.Wrap(err)
 	}
-
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 30,
-		Message:    "removing the node from the cluster",
-	})
-
-	// if the node is online, it needs to leave the serf cluster to
-	// prevent joining back
-	if online {
-		err = s.serfNodeLeave(agentRunner)
-		if err != nil {
-			if !force {
-				return trace.Wrap(err, "failed to remove the node from the serf cluster")
-			}
-			ctx.Warnf("Failed to remove node %q from serf cluster: %v.", serverName, trace.DebugReport(err))
-		}
+	if op.Type != ops.OperationShrink {
+		return trace.BadParameter("operation %v is not a shrink operation", op.ID)
 	}
-
-	// delete the Kubernetes node and force-leave its serf member
-	if err = s.removeNodeFromCluster(*server, masterRunner); err != nil {
-		if !force {
-			return trace.Wrap(err, "failed to remove the node from the cluster")
-		}
-		ctx.Warningf("Failed to remove node %q from the cluster, force continue: %v.",
-			serverName, trace.DebugReport(err))
+	if op.State != ops.OperationStateShrinkInProgress {
+		return trace.BadParameter("shrink operation is not in progress: %v", op)
 	}
-
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 40,
-		Message:    "removing the node from the database",
-	})
-
-	// remove etcd member
-	err = s.removeFromEtcd(ctx, masterRunner, *server)
-	// the node may be an etcd proxy and not a full member of the etcd cluster
-	if err != nil && !trace.IsNotFound(err) {
-		if !force {
-			return trace.Wrap(err, "failed to remove the node from the database")
-		}
-		ctx.Warningf("failed to remove the node from the database, force continue: %v", trace.DebugReport(err))
+	if op.Shrink != nil && isLocalServerRemoved(op.Shrink.Servers) {
+		return trace.BadParameter("this node is being removed, " +
+			"the operation can only be run by the cluster controller on another master node")
 	}
-
-	if online {
-		s.reportProgress(ctx, ops.ProgressEntry{
-			State:      ops.ProgressStateInProgress,
-			Completion: 50,
-			Message:    "uninstalling the system software",
-		})
-
-		if err = s.uninstallSystem(ctx, agentRunner); err != nil {
-			ctx.Warningf("error uninstalling the system software: %v", trace.DebugReport(err))
-		}
+	if err := s.ensureShrinkPlan(*op); err != nil {
+		return trace.Wrap(err)
 	}
 
-	if isAWSProvisioner(ctx.operation.Provisioner) {
-		if !s.app.Manifest.HasHook(schema.HookNodesDeprovision) {
-			return trace.BadParameter("%v hook is not defined",
-				schema.HookNodesDeprovision)
-		}
-		ctx.Infof("using nodes deprovisioning hook")
-		err := s.runNodesDeprovisionHook(ctx)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		ctx.RecordInfo("nodes have been successfully deprovisioned")
+	opCtx, err := s.newOperationContext(*op)
+	if err != nil {
+		return trace.Wrap(err)
 	}
+	defer opCtx.Close()
 
-	if s.app.Manifest.HasHook(schema.HookNodeRemoved) {
-		s.reportProgress(ctx, ops.ProgressEntry{
-			State:      ops.ProgressStateInProgress,
-			Completion: 80,
-			Message:    "running post-removal hooks",
-		})
-
-		if err = s.runHook(ctx, schema.HookNodeRemoved); err != nil {
-			if !force {
-				return trace.Wrap(err, "failed to run %v hook", schema.HookNodeRemoved)
-			}
-			ctx.Warningf("failed to run %v hook, force continue: %v", schema.HookNodeRemoved, trace.DebugReport(err))
-		}
+	machine, engine, err := s.newShrinkFSM(opCtx)
+	if err != nil {
+		return trace.Wrap(err)
 	}
+	defer engine.Close()
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 85,
-		Message:    "cleaning up packages",
-	})
-
-	provisionedServer := &ProvisionedServer{Server: *server}
-	if err = s.deletePackages(provisionedServer); err != nil {
-		if !force {
-			return trace.Wrap(err, "failed to clean up packages")
+	params := fsm.Params{
+		PhaseID: req.PhaseID,
+		Force:   req.Force,
+	}
+	switch {
+	case req.Rollback:
+		return trace.Wrap(machine.RollbackPhase(ctx, params))
+	case req.PhaseID == fsm.RootPhase:
+		fsmErr := machine.ExecutePlan(ctx, utils.DiscardProgress)
+		if err := engine.Complete(fsmErr); err != nil {
+			return trace.Wrap(err)
 		}
-		ctx.Warningf("failed to clean up packages, force continue: %v", trace.DebugReport(err))
+		return trace.Wrap(fsmErr)
+	default:
+		return trace.Wrap(machine.ExecutePhase(ctx, params))
 	}
+}
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 90,
-		Message:    "waiting for operation to complete",
-	})
-
-	if err = s.waitForServerToDisappear(serverName); err != nil {
-		ctx.Warningf("failed to wait for server %v to disappear: %v", serverName, trace.DebugReport(err))
+// ensureShrinkPlan creates the plan for the specified shrink operation
+// unless it already exists
+func (s *site) ensureShrinkPlan(op ops.SiteOperation) error {
+	_, err := s.backend().GetOperationPlan(op.SiteDomain, op.ID)
+	if err == nil {
+		return nil
 	}
-
-	if err = s.removeClusterStateServers([]string{server.Hostname}); err != nil {
+	if !trace.IsNotFound(err) {
 		return trace.Wrap(err)
 	}
+	return trace.Wrap(s.createShrinkPlan(op))
+}
 
-	_, err = s.compareAndSwapOperationState(swap{
-		key:            opKey,
-		expectedStates: []string{ops.OperationStateShrinkInProgress},
-		newOpState:     ops.OperationStateCompleted,
+// createShrinkPlan generates and saves the plan for the specified shrink operation
+func (s *site) createShrinkPlan(op ops.SiteOperation) error {
+	plan, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: op,
+		Manifest:  s.app.Manifest,
+		Online:    s.getOnlineShrinkNodes(op),
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	_, err = s.backend().CreateOperationPlan(*plan)
+	return trace.Wrap(err)
+}
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateCompleted,
-		Completion: constants.Completed,
-		Message:    fmt.Sprintf("%v removed", serverName),
-	})
+// getOnlineShrinkNodes returns hostnames of the nodes removed by the specified
+// shrink operation that are online
+func (s *site) getOnlineShrinkNodes(op ops.SiteOperation) (online []string) {
+	if op.Shrink == nil || op.Shrink.NodeRemoved {
+		return nil
+	}
+	for _, server := range op.Shrink.Servers {
+		_, err := s.getTeleportServerNoRetry(ops.Hostname, server.Hostname)
+		if err != nil {
+			log.Warnf("Node %q is offline: %v.", server.Hostname, trace.DebugReport(err))
+			continue
+		}
+		online = append(online, server.Hostname)
+	}
+	return online
+}
 
-	return nil
+// isLocalServerRemoved returns true if the node this process
+// is running on is among the specified servers
+func isLocalServerRemoved(servers []storage.Server) bool {
+	podIP := os.Getenv(constants.EnvPodIP)
+	for _, server := range servers {
+		if server.AdvertiseIP == podIP {
+			return true
+		}
+	}
+	return false
+}
+
+func shrinkHostnames(servers []storage.Server) (hostnames []string) {
+	for _, server := range servers {
+		hostnames = append(hostnames, server.Hostname)
+	}
+	return hostnames
 }
 
-func (s *site) pickShrinkMasterRunner(ctx *operationContext, removedServer storage.Server) (*serverRunner, error) {
+func (s *site) pickShrinkMasterRunner(ctx *operationContext, removedServers []storage.Server) (*serverRunner, error) {
 	masters, err := s.getTeleportServers(schema.ServiceLabelRole, string(schema.ServiceRoleMaster))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	// Pick any master server except the one that's being removed.
+	// Pick any master server except the ones that are being removed.
 	for _, master := range masters {
-		if master.IP != removedServer.AdvertiseIP {
+		if !isRemovedServer(removedServers, master.IP) {
 			return &serverRunner{
 				&master, &teleportRunner{ctx, s.domainName, s.teleport()},
 			}, nil
 		}
 	}
-	return nil, trace.NotFound("%v is being removed and no more master nodes are available to execute the operation",
-		removedServer)
+	return nil, trace.NotFound("%v are being removed and no more master nodes are available to execute the operation",
+		shrinkHostnames(removedServers))
+}
+
+func isRemovedServer(removedServers []storage.Server, advertiseIP string) bool {
+	for _, server := range removedServers {
+		if server.AdvertiseIP == advertiseIP {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *site) waitForServerToDisappear(hostname string) error {
@@ -492,7 +434,10 @@ func (s *site) uninstallSystem(ctx *operationContext, runner *serverRunner) erro
 	return nil
 }
 
-func (s *site) launchAgent(ctx *operationContext, server storage.Server) (*serverRunner, error) {
+// launchAgent starts the shrink agent on the specified server.
+// numAgents is the total number of agents expected to have joined
+// the operation once the agent is up
+func (s *site) launchAgent(ctx *operationContext, server storage.Server, numAgents int) (*serverRunner, error) {
 	teleportServer, err := s.getTeleportServer(ops.Hostname, server.Hostname)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -526,19 +471,31 @@ func (s *site) launchAgent(ctx *operationContext, server storage.Server) (*serve
 		return nil, trace.Wrap(err, "failed to start shrink agent: %s", out)
 	}
 
-	agentReport, err := s.waitForAgents(context.TODO(), ctx)
+	localCtx, cancel := defaults.WithTimeout(context.TODO())
+	defer cancel()
+	err = s.agentService().Wait(localCtx, ctx.key(), numAgents)
 	if err != nil {
 		return nil, trace.Wrap(err, "failed to wait for shrink agent")
 	}
 
-	info := agentReport.Servers[0]
-	return &serverRunner{
-		server: agentServer{
-			AdvertiseIP: info.AdvertiseAddr,
-			Hostname:    info.GetHostname(),
-		},
-		runner: &agentRunner{ctx, s.agentService()},
-	}, nil
+	agentReport, err := s.agentReport(localCtx, ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	for _, info := range agentReport.Servers {
+		if info.AdvertiseAddr != server.AdvertiseIP {
+			continue
+		}
+		return &serverRunner{
+			server: agentServer{
+				AdvertiseIP: info.AdvertiseAddr,
+				Hostname:    info.GetHostname(),
+			},
+			runner: &agentRunner{ctx, s.agentService()},
+		}, nil
+	}
+	return nil, trace.NotFound("shrink agent on %v has not joined the operation", server.Hostname)
 }
 
 func (s *site) createShrinkAgentToken(operationID string) (tokenID string, err error) {
@@ -598,19 +555,19 @@ func (s *site) serverPackages(server *ProvisionedServer) ([]loc.Locator, error)
 	return packages, nil
 }
 
-// unlabelNode deletes server profile labels from k8s node
-func (s *site) unlabelNode(server storage.Server, runner *serverRunner) error {
+// labelNode restores server profile labels on k8s node
+func (s *site) labelNode(server storage.Server, runner *serverRunner) error {
 	profile, err := s.app.Manifest.NodeProfiles.ByName(server.Role)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
 	var labelFlags []string
-	for label := range profile.Labels {
-		labelFlags = append(labelFlags, fmt.Sprintf("%s-", label))
+	for label, value := range profile.Labels {
+		labelFlags = append(labelFlags, fmt.Sprintf("%s=%s", label, value))
 	}
 
-	command := s.planetEnterCommand(defaults.KubectlBin, "label", "nodes",
+	command := s.planetEnterCommand(defaults.KubectlBin, "label", "nodes", "--overwrite",
 		fmt.Sprintf("-l=%v=%v", v1.LabelHostname, server.KubeNodeID()))
 	command = append(command, labelFlags...)
 
@@ -622,17 +579,40 @@ func (s *site) unlabelNode(server storage.Server, runner *serverRunner) error {
 	return trace.Wrap(err)
 }
 
-func (s *site) removeNodeFromCluster(server storage.Server, runner *serverRunner) (err error) {
-	provisionedServer := ProvisionedServer{Server: server}
-	commands := [][]string{
-		s.planetEnterCommand(
-			defaults.KubectlBin, "delete", "nodes", "--ignore-not-found=true",
-			fmt.Sprintf("-l=%v=%v", v1.LabelHostname, server.KubeNodeID())),
-		// Issue `serf force-leave` from the master node to transition
-		// failed nodes to `left` state in case the node itself failed shutting down
-		s.planetEnterCommand(defaults.SerfBin, "force-leave", provisionedServer.AgentName(s.domainName)),
+// unlabelNode deletes server profile labels from k8s node
+func (s *site) unlabelNode(server storage.Server, runner *serverRunner) error {
+	command, err := s.unlabelNodeCommand(server)
+	if err != nil {
+		return trace.Wrap(err)
 	}
 
+	err = utils.Retry(defaults.RetryInterval, defaults.RetryAttempts, func() error {
+		_, err := runner.Run(command...)
+		return trace.Wrap(err)
+	})
+
+	return trace.Wrap(err)
+}
+
+// unlabelNodeCommand returns the command that deletes server profile labels from k8s node
+func (s *site) unlabelNodeCommand(server storage.Server) ([]string, error) {
+	profile, err := s.app.Manifest.NodeProfiles.ByName(server.Role)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var labelFlags []string
+	for label := range profile.Labels {
+		labelFlags = append(labelFlags, fmt.Sprintf("%s-", label))
+	}
+
+	command := s.planetEnterCommand(defaults.KubectlBin, "label", "nodes",
+		fmt.Sprintf("-l=%v=%v", v1.LabelHostname, server.KubeNodeID()))
+	return append(command, labelFlags...), nil
+}
+
+func (s *site) removeNodeFromCluster(server storage.Server, runner *serverRunner) (err error) {
+	commands := s.removeNodeCommands(server)
 	err = utils.Retry(defaults.RetryInterval, defaults.RetryAttempts, func() error {
 		for _, command := range commands {
 			out, err := runner.Run(command...)
@@ -646,6 +626,20 @@ func (s *site) removeNodeFromCluster(server storage.Server, runner *serverRunner
 	return trace.Wrap(err)
 }
 
+// removeNodeCommands returns the commands that remove the Kubernetes node
+// and the serf member of the specified server
+func (s *site) removeNodeCommands(server storage.Server) [][]string {
+	provisionedServer := ProvisionedServer{Server: server}
+	return [][]string{
+		s.planetEnterCommand(
+			defaults.KubectlBin, "delete", "nodes", "--ignore-not-found=true",
+			fmt.Sprintf("-l=%v=%v", v1.LabelHostname, server.KubeNodeID())),
+		// Issue `serf force-leave` from the master node to transition
+		// failed nodes to `left` state in case the node itself failed shutting down
+		s.planetEnterCommand(defaults.SerfBin, "force-leave", provisionedServer.AgentName(s.domainName)),
+	}
+}
+
 // serfNodeLeave removes the node specified with runner from the serf cluster
 // by issuing a `serf leave` from the node itself.
 func (s *site) serfNodeLeave(runner *serverRunner) error {
@@ -661,6 +655,37 @@ func (s *site) serfNodeLeave(runner *serverRunner) error {
 	return trace.Wrap(err)
 }
 
+// restartPlanetServices restarts the specified services inside planet on the node
+func (s *site) restartPlanetServices(runner *serverRunner, services ...string) error {
+	command := s.planetEnterCommand(defaults.SystemctlBin, "restart")
+	command = append(command, services...)
+	err := utils.Retry(defaults.RetryInterval, defaults.RetryLessAttempts, func() error {
+		out, err := runner.Run(command...)
+		if err != nil {
+			return trace.Wrap(err, "command %q failed: %s", command, out)
+		}
+		return nil
+	})
+	return trace.Wrap(err)
+}
+
+// waitForKubernetesNode waits until the Kubernetes node of the specified server is registered
+func (s *site) waitForKubernetesNode(server storage.Server, runner *serverRunner) error {
+	command := s.planetEnterCommand(defaults.KubectlBin, "get", "nodes", "--output=name",
+		fmt.Sprintf("-l=%v=%v", v1.LabelHostname, server.KubeNodeID()))
+	err := utils.Retry(defaults.RetryInterval, defaults.RetryAttempts, func() error {
+		out, err := runner.Run(command...)
+		if err != nil {
+			return trace.Wrap(err, "command %q failed: %s", command, out)
+		}
+		if len(bytes.TrimSpace(out)) == 0 {
+			return trace.NotFound("node %v is not registered", server.KubeNodeID())
+		}
+		return nil
+	})
+	return trace.Wrap(err)
+}
+
 func (s *site) isTeleportMasterConfigPackageFor(server *ProvisionedServer, loc loc.Locator) bool {
 	configPackage := s.teleportMasterConfigPackage(server)
 	return configPackage.Name == loc.Name && configPackage.Repository == loc.Repository
@@ -682,3 +707,10 @@ func (s *site) isPlanetSecretsPackageFor(server *ProvisionedServer, loc loc.Loca
 	configPackage := s.planetSecretsPackage(server, "")
 	return configPackage.Name == loc.Name && configPackage.Repository == loc.Repository
 }
+
+const (
+	// planetSerfService is the name of the serf agent service inside planet
+	planetSerfService = "serf"
+	// planetKubeletService is the name of the kubelet service inside planet
+	planetKubeletService = "kube-kubelet"
+)
@@ -0,0 +1,396 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// newShrinkFSM returns a new state machine for the shrink operation
+// specified with ctx.
+//
+// The returned engine must be closed after the state machine is done
+func (s *site) newShrinkFSM(ctx *operationContext) (*fsm.FSM, *shrinkEngine, error) {
+	state := ctx.operation.Shrink
+	if state == nil || len(state.Servers) == 0 {
+		return nil, nil, trace.BadParameter("operation %v does not specify servers to remove",
+			ctx.operation.ID)
+	}
+	ctx.serversToRemove = state.Servers
+	// if the operation was resumed, cloud provider might not be set
+	if s.service.getCloudProvider(s.key) == nil {
+		err := s.service.setCloudProviderFromRequest(
+			s.key, ctx.operation.Provisioner, &state.Vars)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+	}
+	engine := &shrinkEngine{
+		planEngine: &planEngine{
+			FieldLogger: ctx.WithField(trace.Component, "fsm:shrink"),
+			site:        s,
+			ctx:         ctx,
+			force:       state.Force,
+		},
+		servers:      state.Servers,
+		agentRunners: make(map[string]*serverRunner),
+	}
+	machine, err := fsm.New(fsm.Config{
+		Engine: engine,
+		Logger: engine.FieldLogger,
+	})
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	machine.SetPreExec(engine.updateProgress)
+	return machine, engine, nil
+}
+
+// shrinkEngine is the shrink operation FSM engine.
+//
+// All phases execute inside the cluster controller process:
+// the commands are run on the remaining master node via teleport and
+// on the nodes being removed via the shrink agents
+type shrinkEngine struct {
+	*planEngine
+	// servers lists the nodes being removed
+	servers []storage.Server
+
+	mu sync.Mutex
+	// masterRunner executes commands on one of the remaining masters
+	masterRunner *serverRunner
+	// agentRunners execute commands on the nodes being removed
+	// and are keyed by node hostname
+	agentRunners map[string]*serverRunner
+}
+
+// GetExecutor returns the executor for the specified shrink phase
+func (e *shrinkEngine) GetExecutor(p fsm.ExecutorParams, remote fsm.Remote) (fsm.PhaseExecutor, error) {
+	executor := e.newExecutor(p.Phase)
+	site := e.site
+	parentID, hostname := path.Split(p.Phase.ID)
+	parentID = path.Clean(parentID)
+	if parentID == fsm.RootPhase {
+		parentID, hostname = p.Phase.ID, ""
+	}
+	var server storage.Server
+	if hostname != "" {
+		planServer, err := findPlanServer(p.Plan, hostname)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		server = *planServer
+	}
+	var err error
+	switch parentID {
+	case shrinkPhaseUnregister:
+		executor.execute = e.withMasterRunner(func(runner *serverRunner) error {
+			return trace.Wrap(site.unlabelNode(server, runner))
+		})
+		executor.rollback = e.withMasterRunner(func(runner *serverRunner) error {
+			return trace.Wrap(site.labelNode(server, runner))
+		})
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			command, err := site.unlabelNodeCommand(server)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			var report fsm.DryRunReport
+			return report.AddCommand("%v on a master node", strings.Join(command, " ")), nil
+		}
+	case shrinkPhasePreHook:
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.runHook(e.ctx, schema.HookNodeRemoving))
+		}
+		executor.dryRun = e.hookDryRun(schema.HookNodeRemoving)
+	case shrinkPhaseLeave:
+		executor.execute = e.withAgentRunner(server, func(runner *serverRunner) error {
+			return trace.Wrap(site.serfNodeLeave(runner))
+		})
+		executor.rollback = e.withAgentRunner(server, func(runner *serverRunner) error {
+			return trace.Wrap(site.restartPlanetServices(runner, planetSerfService))
+		})
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			command := site.planetEnterCommand(defaults.SerfBin, "leave")
+			return report.AddCommand("%v on %v", strings.Join(command, " "), server.Hostname), nil
+		}
+	case shrinkPhaseRemoveNode:
+		executor.execute = e.withMasterRunner(func(runner *serverRunner) error {
+			return trace.Wrap(site.removeNodeFromCluster(server, runner))
+		})
+		executor.rollback = func(context.Context) error {
+			return trace.Wrap(e.restoreNode(server))
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			for _, command := range site.removeNodeCommands(server) {
+				report.AddCommand("%v on a master node", strings.Join(command, " "))
+			}
+			return &report, nil
+		}
+	case shrinkPhaseEtcd:
+		executor.execute = e.withMasterRunner(func(runner *serverRunner) error {
+			err := site.removeFromEtcd(e.ctx, runner, server)
+			// the node may be an etcd proxy and not a full member of the etcd cluster
+			if err != nil && !trace.IsNotFound(err) {
+				return trace.Wrap(err)
+			}
+			return nil
+		})
+		executor.healthChecks, err = e.remainingClusterChecks()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			provisionedServer := ProvisionedServer{Server: server}
+			command := site.etcdctlCommand("member", "remove")
+			return report.AddCommand("%v <ID of member %v> on a master node",
+				strings.Join(command, " "), provisionedServer.EtcdMemberName(site.domainName)), nil
+		}
+	case shrinkPhaseSystem:
+		// the node has already left the cluster at this point, so failing
+		// to clean it up does not fail the operation
+		uninstall := e.withAgentRunner(server, func(runner *serverRunner) error {
+			return trace.Wrap(site.uninstallSystem(e.ctx, runner))
+		})
+		executor.execute = func(ctx context.Context) error {
+			if err := uninstall(ctx); err != nil {
+				e.Warnf("Failed to uninstall system software on %v: %v.",
+					server.Hostname, trace.DebugReport(err))
+			}
+			return nil
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			command := site.gravityCommand("system", "uninstall", "--confirm")
+			return report.AddCommand("%v on %v", strings.Join(command, " "), server.Hostname), nil
+		}
+	case shrinkPhaseDeprovision:
+		executor.forceable = false
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.runNodesDeprovisionHook(e.ctx, server))
+		}
+		executor.dryRun = e.hookDryRun(schema.HookNodesDeprovision)
+	case shrinkPhasePostHook:
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.runHook(e.ctx, schema.HookNodeRemoved))
+		}
+		executor.dryRun = e.hookDryRun(schema.HookNodeRemoved)
+	case shrinkPhasePackages:
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.deletePackages(&ProvisionedServer{Server: server}))
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			packages, err := site.serverPackages(&ProvisionedServer{Server: server})
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			var report fsm.DryRunReport
+			for _, pkg := range packages {
+				report.AddCommand("delete package %v", pkg)
+			}
+			return &report, nil
+		}
+	case shrinkPhaseCleanup:
+		executor.forceable = false
+		executor.execute = func(context.Context) error {
+			hostnames := shrinkHostnames(p.Plan.Servers)
+			for _, hostname := range hostnames {
+				if err := site.waitForServerToDisappear(hostname); err != nil {
+					e.Warnf("Failed to wait for server %v to disappear: %v.",
+						hostname, trace.DebugReport(err))
+				}
+			}
+			return trace.Wrap(site.removeClusterStateServers(hostnames))
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			return report.AddCommand("remove %v from the cluster state",
+				strings.Join(shrinkHostnames(p.Plan.Servers), ", ")), nil
+		}
+		executor.healthChecks, err = e.remainingClusterChecks()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	default:
+		return nil, trace.BadParameter("unknown phase %q", p.Phase.ID)
+	}
+	return executor, nil
+}
+
+// Complete marks the operation completed if all phases of the plan have completed.
+//
+// Otherwise, the operation is left in progress so it can be resumed
+// and the failure is reported in the operation progress
+func (e *shrinkEngine) Complete(fsmErr error) error {
+	plan, err := e.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !fsm.IsCompleted(plan) {
+		e.reportFailure(fmt.Sprintf("failed to remove %v", shrinkHostnames(e.servers)), fsmErr)
+		return nil
+	}
+	_, err = e.site.compareAndSwapOperationState(swap{
+		key:            e.ctx.key(),
+		expectedStates: []string{ops.OperationStateShrinkInProgress},
+		newOpState:     ops.OperationStateCompleted,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	e.site.reportProgress(e.ctx, ops.ProgressEntry{
+		State:      ops.ProgressStateCompleted,
+		Completion: constants.Completed,
+		Message:    fmt.Sprintf("%v removed", strings.Join(shrinkHostnames(e.servers), ", ")),
+	})
+	return nil
+}
+
+// Close stops the shrink agents if they have been started and erases
+// the cloud provider information which may contain sensitive data such as API keys
+func (e *shrinkEngine) Close() {
+	e.site.service.deleteCloudProvider(e.site.key)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.agentRunners) == 0 {
+		return
+	}
+	err := e.site.agentService().StopAgents(context.TODO(), e.ctx.key())
+	if err != nil {
+		e.Warnf("Failed to stop shrink agents: %v.", trace.DebugReport(err))
+	}
+	e.agentRunners = make(map[string]*serverRunner)
+}
+
+// restoreNode brings back the Kubernetes node and the serf member
+// of the specified server removed in the remove-node phase.
+//
+// The deleted Kubernetes node is registered again by its kubelet after
+// a restart, so the server has to be online
+func (e *shrinkEngine) restoreNode(server storage.Server) error {
+	agentRunner, err := e.getAgentRunner(server)
+	if err != nil {
+		return trace.Wrap(err, "node %v has to be online to be restored", server.Hostname)
+	}
+	err = e.site.restartPlanetServices(agentRunner, planetKubeletService, planetSerfService)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	masterRunner, err := e.getMasterRunner()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := e.site.waitForKubernetesNode(server, masterRunner); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.site.labelNode(server, masterRunner))
+}
+
+// remainingClusterChecks returns the health checks that verify that
+// the cluster keeps running on the nodes that are not being removed
+func (e *shrinkEngine) remainingClusterChecks() ([]fsm.HealthCheck, error) {
+	cluster, err := e.site.backend().GetSite(e.site.key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var servers []storage.Server
+	for _, server := range cluster.ClusterState.Servers {
+		if !isRemovedServer(e.servers, server.AdvertiseIP) {
+			servers = append(servers, server)
+		}
+	}
+	return []fsm.HealthCheck{fsm.PlanetRunning(servers)}, nil
+}
+
+// withMasterRunner returns a phase function that executes fn with
+// the runner on one of the remaining master nodes
+func (e *shrinkEngine) withMasterRunner(fn func(*serverRunner) error) func(context.Context) error {
+	return func(context.Context) error {
+		runner, err := e.getMasterRunner()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(fn(runner))
+	}
+}
+
+// withAgentRunner returns a phase function that executes fn with
+// the runner on the specified node being removed
+func (e *shrinkEngine) withAgentRunner(server storage.Server, fn func(*serverRunner) error) func(context.Context) error {
+	return func(context.Context) error {
+		runner, err := e.getAgentRunner(server)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(fn(runner))
+	}
+}
+
+func (e *shrinkEngine) getMasterRunner() (*serverRunner, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.masterRunner != nil {
+		return e.masterRunner, nil
+	}
+	runner, err := e.site.pickShrinkMasterRunner(e.ctx, e.servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	e.Infof("Selected %v (%v) as master runner.",
+		runner.server.HostName(), runner.server.Address())
+	e.masterRunner = runner
+	return runner, nil
+}
+
+// getAgentRunner returns the runner for the specified node starting
+// the shrink agent on it if necessary.
+//
+// Agents are started one at a time so each waits for exactly one new agent
+// to join the operation
+func (e *shrinkEngine) getAgentRunner(server storage.Server) (*serverRunner, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if runner, ok := e.agentRunners[server.Hostname]; ok {
+		return runner, nil
+	}
+	_, err := e.site.getTeleportServerNoRetry(ops.Hostname, server.Hostname)
+	if err != nil {
+		return nil, trace.Wrap(err, "node %v is offline", server.Hostname)
+	}
+	runner, err := e.site.launchAgent(e.ctx, server, len(e.agentRunners)+1)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to launch agent on %v", server.Hostname)
+	}
+	e.agentRunners[server.Hostname] = runner
+	return runner, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// shrinkPhaseUnregister removes the node profile labels so
+	// no new workloads are scheduled on the node
+	shrinkPhaseUnregister = "/unregister"
+	// shrinkPhasePreHook runs the application's node removing hook
+	shrinkPhasePreHook = "/pre-hook"
+	// shrinkPhaseLeave removes the node from the serf cluster
+	// from the node itself
+	shrinkPhaseLeave = "/leave"
+	// shrinkPhaseRemoveNode deletes the Kubernetes node and forces
+	// its serf member to leave
+	shrinkPhaseRemoveNode = "/remove-node"
+	// shrinkPhaseEtcd removes the node from the etcd cluster
+	shrinkPhaseEtcd = "/etcd"
+	// shrinkPhaseSystem uninstalls the system software on the node
+	shrinkPhaseSystem = "/system-uninstall"
+	// shrinkPhaseDeprovision deprovisions the node from the cloud provider
+	shrinkPhaseDeprovision = "/deprovision"
+	// shrinkPhasePostHook runs the application's node removed hook
+	shrinkPhasePostHook = "/post-hook"
+	// shrinkPhasePackages deletes the node's packages from the cluster package service
+	shrinkPhasePackages = "/packages"
+	// shrinkPhaseCleanup removes the nodes from the cluster state
+	shrinkPhaseCleanup = "/cleanup"
+)
+
+// shrinkPlanConfig is the configuration for the shrink operation plan
+type shrinkPlanConfig struct {
+	// Operation is the shrink operation
+	Operation ops.SiteOperation
+	// Manifest is the cluster application manifest
+	Manifest schema.Manifest
+	// Online lists hostnames of the nodes being removed that are online.
+	// The phases that run on the node itself are only added for online nodes
+	Online []string
+}
+
+// newShrinkPlan returns a new plan for the shrink operation.
+//
+// The per-node steps are executed for all nodes being removed in parallel,
+// except for the etcd membership changes which are serialized
+func newShrinkPlan(config shrinkPlanConfig) (*storage.OperationPlan, error) {
+	op := config.Operation
+	if op.Shrink == nil || len(op.Shrink.Servers) == 0 {
+		return nil, trace.BadParameter("operation %v does not specify servers to remove", op.ID)
+	}
+	servers := op.Shrink.Servers
+	if isAWSProvisioner(op.Provisioner) && !config.Manifest.HasHook(schema.HookNodesDeprovision) {
+		return nil, trace.BadParameter("%v hook is not defined", schema.HookNodesDeprovision)
+	}
+	var online []storage.Server
+	if !op.Shrink.NodeRemoved {
+		for _, server := range servers {
+			if utils.StringInSlice(config.Online, server.Hostname) {
+				online = append(online, server)
+			}
+		}
+	}
+
+	phases := []storage.OperationPhase{
+		newShrinkNodesPhase(shrinkPhaseUnregister, "Unregister", servers, true),
+	}
+	if config.Manifest.HasHook(schema.HookNodeRemoving) {
+		phases = append(phases, storage.OperationPhase{
+			ID:          shrinkPhasePreHook,
+			Description: fmt.Sprintf("Run %v hook", schema.HookNodeRemoving),
+		})
+	}
+	if len(online) != 0 {
+		phases = append(phases, newShrinkNodesPhase(shrinkPhaseLeave,
+			"Remove from the serf cluster", online, true))
+	}
+	phases = append(phases,
+		newShrinkNodesPhase(shrinkPhaseRemoveNode, "Remove from Kubernetes", servers, true),
+		newShrinkNodesPhase(shrinkPhaseEtcd, "Remove from the etcd cluster", servers, false))
+	if len(online) != 0 {
+		phases = append(phases, newShrinkNodesPhase(shrinkPhaseSystem,
+			"Uninstall system software", online, true))
+	}
+	if isAWSProvisioner(op.Provisioner) {
+		phases = append(phases, newShrinkNodesPhase(shrinkPhaseDeprovision,
+			"Deprovision", servers, false))
+	}
+	if config.Manifest.HasHook(schema.HookNodeRemoved) {
+		phases = append(phases, storage.OperationPhase{
+			ID:          shrinkPhasePostHook,
+			Description: fmt.Sprintf("Run %v hook", schema.HookNodeRemoved),
+		})
+	}
+	phases = append(phases,
+		newShrinkNodesPhase(shrinkPhasePackages, "Delete packages", servers, true),
+		storage.OperationPhase{
+			ID:          shrinkPhaseCleanup,
+			Description: "Remove nodes from the cluster state",
+		})
+
+	return &storage.OperationPlan{
+		OperationID:   op.ID,
+		OperationType: op.Type,
+		AccountID:     op.AccountID,
+		ClusterName:   op.SiteDomain,
+		Phases:        phases,
+		Servers:       servers,
+		CreatedAt:     op.Created,
+	}, nil
+}
+
+// newShrinkNodesPhase returns a phase that executes the step described
+// with description for each of the specified servers
+func newShrinkNodesPhase(id, description string, servers []storage.Server, parallel bool) storage.OperationPhase {
+	phase := storage.OperationPhase{
+		ID:          id,
+		Description: fmt.Sprintf("%v nodes", description),
+		Parallel:    parallel && len(servers) > 1,
+	}
+	for _, server := range servers {
+		phase.Phases = append(phase.Phases, storage.OperationPhase{
+			ID:          path.Join(id, server.Hostname),
+			Description: fmt.Sprintf("%v node %v", description, server.Hostname),
+		})
+	}
+	return phase
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"gopkg.in/check.v1"
+)
+
+type ShrinkPlanSuite struct{}
+
+var _ = check.Suite(&ShrinkPlanSuite{})
+
+func (s *ShrinkPlanSuite) TestOfflineNode(c *check.C) {
+	plan, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: newShrinkOperation("", shrinkServer),
+	})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, plan.Servers, []storage.Server{shrinkServer})
+	compare.DeepCompare(c, phaseIDs(plan.Phases), []string{
+		shrinkPhaseUnregister,
+		shrinkPhaseRemoveNode,
+		shrinkPhaseEtcd,
+		shrinkPhasePackages,
+		shrinkPhaseCleanup,
+	})
+}
+
+func (s *ShrinkPlanSuite) TestOnlineNodeWithHooks(c *check.C) {
+	plan, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: newShrinkOperation(schema.ProvisionerAWSTerraform, shrinkServer),
+		Manifest: schema.Manifest{
+			Hooks: &schema.Hooks{
+				NodeRemoving:     &schema.Hook{Job: "job"},
+				NodeRemoved:      &schema.Hook{Job: "job"},
+				NodesDeprovision: &schema.Hook{Job: "job"},
+			},
+		},
+		Online: []string{shrinkServer.Hostname},
+	})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, phaseIDs(plan.Phases), []string{
+		shrinkPhaseUnregister,
+		shrinkPhasePreHook,
+		shrinkPhaseLeave,
+		shrinkPhaseRemoveNode,
+		shrinkPhaseEtcd,
+		shrinkPhaseSystem,
+		shrinkPhaseDeprovision,
+		shrinkPhasePostHook,
+		shrinkPhasePackages,
+		shrinkPhaseCleanup,
+	})
+}
+
+func (s *ShrinkPlanSuite) TestRequiresDeprovisionHookOnAWS(c *check.C) {
+	_, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: newShrinkOperation(schema.ProvisionerAWSTerraform, shrinkServer),
+	})
+	c.Assert(err, check.ErrorMatches, ".*nodesDeprovision hook is not defined.*")
+}
+
+func (s *ShrinkPlanSuite) TestRemovesMultipleNodes(c *check.C) {
+	master := storage.Server{
+		Hostname:    "node-3",
+		AdvertiseIP: "10.0.0.3",
+		Role:        "master",
+	}
+	plan, err := newShrinkPlan(shrinkPlanConfig{
+		Operation: newShrinkOperation("", shrinkServer, master),
+		Online:    []string{master.Hostname},
+	})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, plan.Servers, []storage.Server{shrinkServer, master})
+	compare.DeepCompare(c, phaseIDs(plan.Phases), []string{
+		shrinkPhaseUnregister,
+		shrinkPhaseLeave,
+		shrinkPhaseRemoveNode,
+		shrinkPhaseEtcd,
+		shrinkPhaseSystem,
+		shrinkPhasePackages,
+		shrinkPhaseCleanup,
+	})
+	phases := make(map[string]storage.OperationPhase)
+	for _, phase := range plan.Phases {
+		phases[phase.ID] = phase
+	}
+	compare.DeepCompare(c, phaseIDs(phases[shrinkPhaseRemoveNode].Phases), []string{
+		"/remove-node/node-2",
+		"/remove-node/node-3",
+	})
+	c.Assert(phases[shrinkPhaseRemoveNode].Parallel, check.Equals, true)
+	// etcd membership changes are serialized
+	compare.DeepCompare(c, phaseIDs(phases[shrinkPhaseEtcd].Phases), []string{
+		"/etcd/node-2",
+		"/etcd/node-3",
+	})
+	c.Assert(phases[shrinkPhaseEtcd].Parallel, check.Equals, false)
+	// only the online node leaves on its own
+	compare.DeepCompare(c, phaseIDs(phases[shrinkPhaseLeave].Phases), []string{
+		"/leave/node-3",
+	})
+}
+
+func newShrinkOperation(provisioner string, servers ...storage.Server) ops.SiteOperation {
+	return ops.SiteOperation{
+		ID:          "operation-1",
+		AccountID:   "account-1",
+		SiteDomain:  "example.com",
+		Type:        ops.OperationShrink,
+		Created:     time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC),
+		State:       ops.OperationStateShrinkInProgress,
+		Provisioner: provisioner,
+		Shrink: &storage.ShrinkOperationState{
+			Servers: servers,
+		},
+	}
+}
+
+func phaseIDs(phases []storage.OperationPhase) (ids []string) {
+	for _, phase := range phases {
+		ids = append(ids, phase.ID)
+	}
+	return ids
+}
+
+var shrinkServer = storage.Server{
+	Hostname:    "node-2",
+	AdvertiseIP: "10.0.0.2",
+	Role:        "node",
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/signing"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// GetTrustPolicy returns the cluster trust policy
+func (o *Operator) GetTrustPolicy(key ops.SiteKey) (storage.TrustPolicy, error) {
+	policy, err := o.backend().GetTrustPolicy()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return policy, nil
+}
+
+// UpsertTrustPolicy creates or updates the cluster trust policy
+func (o *Operator) UpsertTrustPolicy(ctx context.Context, key ops.SiteKey, policy storage.TrustPolicy) error {
+	if err := policy.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	// make sure the trusted keys and certificate authorities can be parsed
+	if _, err := signing.NewVerifier(policy); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.backend().UpsertTrustPolicy(policy); err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.TrustPolicyUpdated)
+	return nil
+}
+
+// DeleteTrustPolicy deletes the cluster trust policy
+func (o *Operator) DeleteTrustPolicy(ctx context.Context, key ops.SiteKey) error {
+	if err := o.backend().DeleteTrustPolicy(); err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.TrustPolicyDeleted)
+	return nil
+}
+
+// verifySignatures verifies the package signatures of the specified application
+// and its dependencies against the cluster trust policy.
+// Verification is skipped if the cluster has no trust policy
+func (s *site) verifySignatures(locator loc.Locator) error {
+	policy, err := s.backend().GetTrustPolicy()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			s.Debug("Cluster has no trust policy, skip signature verification.")
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(signing.VerifyApplication(signing.VerifyApplicationRequest{
+		Policy:      policy,
+		Apps:        s.apps(),
+		Packages:    s.packages(),
+		Application: locator,
+		FieldLogger: s.Entry,
+	}))
+}
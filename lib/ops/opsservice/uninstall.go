@@ -19,11 +19,12 @@ package opsservice
 import (
 	"context"
 
-	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
 	"github.com/pborman/uuid"
@@ -119,11 +120,21 @@ func (s *site) createUninstallOperation(context context.Context, req ops.CreateS
 		}
 	}
 
+	plan, err := s.newUninstallPlan(*op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	key, err := s.getOperationGroup().createSiteOperation(*op)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
+	_, err = s.backend().CreateOperationPlan(*plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	s.reportProgress(ctx, ops.ProgressEntry{
 		State:      ops.ProgressStateInProgress,
 		Completion: 0,
@@ -138,57 +149,122 @@ func (s *site) createUninstallOperation(context context.Context, req ops.CreateS
 	return key, nil
 }
 
-// uninstallOperationStart kicks off actuall uninstall process:
-// deprovisions servers, deletes packages
+// uninstallOperationStart kicks off actual uninstall process by executing
+// the uninstall operation plan
 func (s *site) uninstallOperationStart(ctx *operationContext) error {
-	state := ctx.operation.Uninstall
+	// operations started before uninstall was plan-based do not have a plan
+	if err := s.ensureUninstallPlan(ctx.operation); err != nil {
+		return trace.Wrap(err)
+	}
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 10,
-		Message:    "uninstalling applications",
-	})
+	machine, engine, err := s.newUninstallFSM(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 
-	// execute commands attempting to run uninstall hooks
-	err := s.uninstallUserApp(ctx)
+	fsmErr := machine.ExecutePlan(context.TODO(), utils.DiscardProgress)
+	if fsmErr != nil {
+		ctx.Warnf("Failed to execute uninstall plan: %v.", trace.DebugReport(fsmErr))
+	}
+	return trace.Wrap(engine.Complete(fsmErr))
+}
+
+// RunUninstallPhase executes or rolls back the specified phase of the uninstall operation plan
+func (o *Operator) RunUninstallPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	site, err := o.openSite(req.Key.SiteKey())
 	if err != nil {
-		if !state.Force {
-			return trace.Wrap(err, "failed to uninstall user app")
-		}
-		log.Warningf("forced uninstall: failed to uninstall user app: %v",
-			trace.DebugReport(err))
+		return trace.Wrap(err)
 	}
+	return trace.Wrap(site.runUninstallPhase(ctx, req))
+}
 
-	// if user has supplied custom hooks, call them
-	if isAWSProvisioner(ctx.operation.Provisioner) {
-		if !s.app.Manifest.HasHook(schema.HookClusterDeprovision) {
-			return trace.BadParameter("%v hook is not defined",
-				schema.HookClusterDeprovision)
-		}
-		err := s.runClusterDeprovisionHook(ctx)
-		if err != nil {
+// runUninstallPhase executes or rolls back the specified phase of the uninstall operation plan
+func (s *site) runUninstallPhase(ctx context.Context, req ops.RunPhaseRequest) error {
+	op, err := s.getSiteOperation(req.Key.OperationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if op.Type != ops.OperationUninstall {
+		return trace.BadParameter("operation %v is not an uninstall operation", op.ID)
+	}
+	if op.State != ops.OperationStateUninstallInProgress {
+		return trace.BadParameter("uninstall operation is not in progress: %v", op)
+	}
+	if err := s.ensureUninstallPlan(*op); err != nil {
+		return trace.Wrap(err)
+	}
+
+	opCtx, err := s.newOperationContext(*op)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer opCtx.Close()
+
+	machine, engine, err := s.newUninstallFSM(opCtx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	params := fsm.Params{
+		PhaseID: req.PhaseID,
+		Force:   req.Force,
+	}
+	switch {
+	case req.Rollback:
+		return trace.Wrap(machine.RollbackPhase(ctx, params))
+	case req.PhaseID == fsm.RootPhase:
+		fsmErr := machine.ExecutePlan(ctx, utils.DiscardProgress)
+		if err := engine.Complete(fsmErr); err != nil {
 			return trace.Wrap(err)
 		}
+		return trace.Wrap(fsmErr)
+	default:
+		return trace.Wrap(machine.ExecutePhase(ctx, params))
 	}
+}
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateCompleted,
-		Completion: constants.Completed,
-		Message:    "uninstall completed",
-	})
+// ensureUninstallPlan creates the plan for the specified uninstall operation
+// unless it already exists
+func (s *site) ensureUninstallPlan(op ops.SiteOperation) error {
+	_, err := s.backend().GetOperationPlan(op.SiteDomain, op.ID)
+	if err == nil {
+		return nil
+	}
+	if !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	plan, err := s.newUninstallPlan(op)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = s.backend().CreateOperationPlan(*plan)
+	return trace.Wrap(err)
+}
 
-	return trace.Wrap(s.deleteSite())
+// newUninstallPlan returns the plan for the specified uninstall operation
+func (s *site) newUninstallPlan(op ops.SiteOperation) (*storage.OperationPlan, error) {
+	cluster, err := s.service.GetSite(s.key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := s.appPackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return newUninstallPlan(uninstallPlanConfig{
+		Operation: op,
+		Manifest:  s.app.Manifest,
+		App:       app.String(),
+		Servers:   cluster.ClusterState.Servers,
+	})
 }
 
 func (s *site) uninstallUserApp(ctx *operationContext) error {
 	log.Infof("uninstallUserApp")
 
-	s.reportProgress(ctx, ops.ProgressEntry{
-		State:      ops.ProgressStateInProgress,
-		Completion: 20,
-		Message:    "uninstalling user application",
-	})
-
 	runner := &teleportRunner{ctx, s.domainName, s.teleport()}
 
 	master, err := s.getTeleportServerNoRetry(schema.ServiceLabelRole,
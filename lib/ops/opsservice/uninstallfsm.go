@@ -0,0 +1,186 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// newUninstallFSM returns a new state machine for the uninstall operation
+// specified with ctx
+func (s *site) newUninstallFSM(ctx *operationContext) (*fsm.FSM, *uninstallEngine, error) {
+	state := ctx.operation.Uninstall
+	if state == nil {
+		return nil, nil, trace.BadParameter("operation %v is not an uninstall operation",
+			ctx.operation.ID)
+	}
+	// if the operation was resumed, cloud provider might not be set
+	if s.service.getCloudProvider(s.key) == nil {
+		err := s.service.setCloudProviderFromRequest(
+			s.key, ctx.operation.Provisioner, &state.Vars)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+	}
+	engine := &uninstallEngine{
+		planEngine: &planEngine{
+			FieldLogger: ctx.WithField(trace.Component, "fsm:uninstall"),
+			site:        s,
+			ctx:         ctx,
+			force:       state.Force,
+		},
+	}
+	machine, err := fsm.New(fsm.Config{
+		Engine: engine,
+		Logger: engine.FieldLogger,
+	})
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	machine.SetPreExec(engine.updateProgress)
+	return machine, engine, nil
+}
+
+// uninstallEngine is the uninstall operation FSM engine.
+//
+// All phases execute inside the operator process which reaches
+// the cluster nodes via teleport
+type uninstallEngine struct {
+	*planEngine
+}
+
+// GetExecutor returns the executor for the specified uninstall phase
+func (e *uninstallEngine) GetExecutor(p fsm.ExecutorParams, remote fsm.Remote) (fsm.PhaseExecutor, error) {
+	executor := e.newExecutor(p.Phase)
+	site := e.site
+	switch {
+	case p.Phase.ID == uninstallPhaseApp:
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.uninstallUserApp(e.ctx))
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			userAppPackage, err := site.appPackage()
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			var report fsm.DryRunReport
+			command := site.planetGravityCommand("app", "package-uninstall", userAppPackage.String())
+			return report.AddCommand("%v on a master node", strings.Join(command, " ")), nil
+		}
+	case isTeardownNodePhase(p.Phase.ID):
+		server, err := findPlanServer(p.Plan, path.Base(p.Phase.ID))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.teardownNode(e.ctx, *server))
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			command := site.gravityCommand("system", "uninstall", "--confirm")
+			return report.AddCommand("%v on %v", strings.Join(command, " "), server.Hostname), nil
+		}
+	case p.Phase.ID == uninstallPhaseDeprovision:
+		executor.forceable = false
+		executor.execute = func(context.Context) error {
+			return trace.Wrap(site.runClusterDeprovisionHook(e.ctx))
+		}
+		executor.dryRun = e.hookDryRun(schema.HookClusterDeprovision)
+	case p.Phase.ID == uninstallPhasePackages:
+		executor.forceable = false
+		executor.execute = func(context.Context) error {
+			err := site.packages().DeleteRepository(site.domainName)
+			if err != nil && !trace.IsNotFound(err) {
+				return trace.Wrap(err)
+			}
+			return nil
+		}
+		executor.dryRun = func(context.Context) (*fsm.DryRunReport, error) {
+			var report fsm.DryRunReport
+			return report.AddCommand("delete package repository %v", site.domainName), nil
+		}
+	default:
+		return nil, trace.BadParameter("unknown phase %q", p.Phase.ID)
+	}
+	return executor, nil
+}
+
+// Complete removes the cluster records once all phases of the plan have completed.
+//
+// Otherwise, the operation is left in progress so it can be resumed
+// and the failure is reported in the operation progress
+func (e *uninstallEngine) Complete(fsmErr error) error {
+	plan, err := e.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !fsm.IsCompleted(plan) {
+		e.reportFailure("failed to uninstall the cluster", fsmErr)
+		return nil
+	}
+	e.site.reportProgress(e.ctx, ops.ProgressEntry{
+		State:      ops.ProgressStateCompleted,
+		Completion: constants.Completed,
+		Message:    "uninstall completed",
+	})
+	return trace.Wrap(e.site.deleteSite())
+}
+
+// teardownNode uninstalls the system software on the specified node
+func (s *site) teardownNode(ctx *operationContext, server storage.Server) error {
+	teleportServer, err := s.getTeleportServerNoRetry(ops.Hostname, server.Hostname)
+	if err != nil {
+		return trace.Wrap(err, "node %v is offline", server.Hostname)
+	}
+	runner := &teleportRunner{ctx, s.domainName, s.teleport()}
+	out, err := runner.Run(teleportServer, s.gravityCommand("system", "uninstall", "--confirm")...)
+	if err == nil {
+		return nil
+	}
+	// the command stops the teleport node service on the node and the
+	// session is likely to be interrupted before the command completes
+	if errWait := s.waitForServerToDisappear(server.Hostname); errWait != nil {
+		return trace.Wrap(err, "failed to uninstall system software: %s", out)
+	}
+	ctx.Debugf("Node %v has left after system uninstall: %v.", server.Hostname, err)
+	return nil
+}
+
+func isTeardownNodePhase(phaseID string) bool {
+	return strings.HasPrefix(phaseID, uninstallPhaseTeardownNodes+"/") ||
+		strings.HasPrefix(phaseID, uninstallPhaseTeardownMasters+"/")
+}
+
+// findPlanServer returns the server with the specified hostname from the plan
+func findPlanServer(plan storage.OperationPlan, hostname string) (*storage.Server, error) {
+	for _, server := range plan.Servers {
+		if server.Hostname == hostname {
+			return &server, nil
+		}
+	}
+	return nil, trace.NotFound("no server %q in operation plan", hostname)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// uninstallPhaseApp runs the application uninstall hooks
+	uninstallPhaseApp = "/app"
+	// uninstallPhaseTeardown removes the system software from the cluster nodes
+	uninstallPhaseTeardown = "/teardown"
+	// uninstallPhaseTeardownNodes removes the system software from regular nodes
+	uninstallPhaseTeardownNodes = "/teardown/nodes"
+	// uninstallPhaseTeardownMasters removes the system software from master nodes
+	uninstallPhaseTeardownMasters = "/teardown/masters"
+	// uninstallPhaseDeprovision runs the cluster deprovision hook
+	uninstallPhaseDeprovision = "/deprovision"
+	// uninstallPhasePackages deletes the cluster packages
+	uninstallPhasePackages = "/packages"
+)
+
+// uninstallPlanConfig is the configuration for the uninstall operation plan
+type uninstallPlanConfig struct {
+	// Operation is the uninstall operation
+	Operation ops.SiteOperation
+	// Manifest is the cluster application manifest
+	Manifest schema.Manifest
+	// App is the cluster application package
+	App string
+	// Servers lists the cluster nodes
+	Servers []storage.Server
+}
+
+// newUninstallPlan returns a new plan for the uninstall operation.
+//
+// Clusters provisioned on AWS are torn down by the cluster deprovision hook,
+// otherwise the system software is removed from each node: regular nodes
+// are torn down in parallel before the masters which are torn down one by one
+// as they are used to access the cluster
+func newUninstallPlan(config uninstallPlanConfig) (*storage.OperationPlan, error) {
+	op := config.Operation
+	phases := []storage.OperationPhase{{
+		ID:          uninstallPhaseApp,
+		Description: fmt.Sprintf("Uninstall application %v", config.App),
+	}}
+	if isAWSProvisioner(op.Provisioner) {
+		if !config.Manifest.HasHook(schema.HookClusterDeprovision) {
+			return nil, trace.BadParameter("%v hook is not defined",
+				schema.HookClusterDeprovision)
+		}
+		phases = append(phases, storage.OperationPhase{
+			ID:          uninstallPhaseDeprovision,
+			Description: "Deprovision the cluster",
+		})
+	} else if teardown := newTeardownPhase(config.Servers); teardown != nil {
+		phases = append(phases, *teardown)
+	}
+	phases = append(phases, storage.OperationPhase{
+		ID:          uninstallPhasePackages,
+		Description: "Delete cluster packages",
+	})
+	return &storage.OperationPlan{
+		OperationID:   op.ID,
+		OperationType: op.Type,
+		AccountID:     op.AccountID,
+		ClusterName:   op.SiteDomain,
+		Phases:        phases,
+		Servers:       config.Servers,
+		CreatedAt:     op.Created,
+	}, nil
+}
+
+func newTeardownPhase(servers []storage.Server) *storage.OperationPhase {
+	masters, nodes := fsm.SplitServers(servers)
+	var phases []storage.OperationPhase
+	if len(nodes) != 0 {
+		phases = append(phases, storage.OperationPhase{
+			ID:          uninstallPhaseTeardownNodes,
+			Description: "Tear down regular nodes",
+			Phases:      newTeardownNodePhases(uninstallPhaseTeardownNodes, nodes),
+			Parallel:    true,
+		})
+	}
+	if len(masters) != 0 {
+		phases = append(phases, storage.OperationPhase{
+			ID:          uninstallPhaseTeardownMasters,
+			Description: "Tear down master nodes",
+			Phases:      newTeardownNodePhases(uninstallPhaseTeardownMasters, masters),
+		})
+	}
+	if len(phases) == 0 {
+		return nil
+	}
+	return &storage.OperationPhase{
+		ID:          uninstallPhaseTeardown,
+		Description: "Uninstall system software on cluster nodes",
+		Phases:      phases,
+	}
+}
+
+func newTeardownNodePhases(parentID string, servers []storage.Server) (phases []storage.OperationPhase) {
+	for _, server := range servers {
+		phases = append(phases, storage.OperationPhase{
+			ID:          path.Join(parentID, server.Hostname),
+			Description: fmt.Sprintf("Uninstall system software on node %v", server.Hostname),
+		})
+	}
+	return phases
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"gopkg.in/check.v1"
+)
+
+type UninstallPlanSuite struct{}
+
+var _ = check.Suite(&UninstallPlanSuite{})
+
+func (s *UninstallPlanSuite) TestTearsDownNodes(c *check.C) {
+	servers := []storage.Server{
+		{Hostname: "node-1", ClusterRole: string(schema.ServiceRoleMaster)},
+		{Hostname: "node-2", ClusterRole: string(schema.ServiceRoleNode)},
+		{Hostname: "node-3", ClusterRole: string(schema.ServiceRoleNode)},
+	}
+	plan, err := newUninstallPlan(uninstallPlanConfig{
+		Operation: newUninstallOperation(""),
+		App:       "gravitational.io/app:0.0.1",
+		Servers:   servers,
+	})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, plan.Phases, []storage.OperationPhase{
+		{
+			ID:          uninstallPhaseApp,
+			Description: "Uninstall application gravitational.io/app:0.0.1",
+		},
+		{
+			ID:          uninstallPhaseTeardown,
+			Description: "Uninstall system software on cluster nodes",
+			Phases: []storage.OperationPhase{
+				{
+					ID:          uninstallPhaseTeardownNodes,
+					Description: "Tear down regular nodes",
+					Parallel:    true,
+					Phases: []storage.OperationPhase{
+						{
+							ID:          "/teardown/nodes/node-2",
+							Description: "Uninstall system software on node node-2",
+						},
+						{
+							ID:          "/teardown/nodes/node-3",
+							Description: "Uninstall system software on node node-3",
+						},
+					},
+				},
+				{
+					ID:          uninstallPhaseTeardownMasters,
+					Description: "Tear down master nodes",
+					Phases: []storage.OperationPhase{
+						{
+							ID:          "/teardown/masters/node-1",
+							Description: "Uninstall system software on node node-1",
+						},
+					},
+				},
+			},
+		},
+		{
+			ID:          uninstallPhasePackages,
+			Description: "Delete cluster packages",
+		},
+	})
+	compare.DeepCompare(c, plan.Servers, servers)
+}
+
+func (s *UninstallPlanSuite) TestDeprovisionsOnAWS(c *check.C) {
+	plan, err := newUninstallPlan(uninstallPlanConfig{
+		Operation: newUninstallOperation(schema.ProvisionerAWSTerraform),
+		Manifest: schema.Manifest{
+			Hooks: &schema.Hooks{
+				ClusterDeprovision: &schema.Hook{Job: "job"},
+			},
+		},
+		Servers: []storage.Server{
+			{Hostname: "node-1", ClusterRole: string(schema.ServiceRoleMaster)},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, phaseIDs(plan.Phases), []string{
+		uninstallPhaseApp,
+		uninstallPhaseDeprovision,
+		uninstallPhasePackages,
+	})
+}
+
+func (s *UninstallPlanSuite) TestRequiresDeprovisionHookOnAWS(c *check.C) {
+	_, err := newUninstallPlan(uninstallPlanConfig{
+		Operation: newUninstallOperation(schema.ProvisionerAWSTerraform),
+	})
+	c.Assert(err, check.ErrorMatches, ".*clusterDeprovision hook is not defined.*")
+}
+
+func newUninstallOperation(provisioner string) ops.SiteOperation {
+	return ops.SiteOperation{
+		ID:          "operation-1",
+		AccountID:   "account-1",
+		SiteDomain:  "example.com",
+		Type:        ops.OperationUninstall,
+		State:       ops.OperationStateUninstallInProgress,
+		Provisioner: provisioner,
+		Uninstall:   &storage.UninstallOperationState{},
+	}
+}
@@ -352,6 +352,14 @@ func (s *site) createUpdateOperation(context context.Context, req ops.CreateSite
 		return nil, trace.Wrap(err)
 	}
 
+	updatePackage, err := loc.ParseLocator(req.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := s.verifySignatures(*updatePackage); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	op := ops.SiteOperation{
 		ID:          uuid.New(),
 		AccountID:   s.key.AccountID,
@@ -363,9 +371,13 @@ func (s *site) createUpdateOperation(context context.Context, req ops.CreateSite
 		State:       ops.OperationStateUpdateInProgress,
 		Provisioner: installOperation.Provisioner,
 		Update: &storage.UpdateOperationState{
-			UpdatePackage: req.App,
-			Vars:          req.Vars,
+			UpdatePackage:     req.App,
+			Vars:              req.Vars,
+			Strategy:          req.Strategy,
+			RollbackOnFailure: req.RollbackOnFailure,
 		},
+		IgnoreMaintenanceWindow: req.IgnoreMaintenanceWindow,
+		QueueID:                 req.QueueID,
 	}
 
 	ctx, err := s.newOperationContext(op)
@@ -406,10 +418,6 @@ func (s *site) createUpdateOperation(context context.Context, req ops.CreateSite
 		return key, nil
 	}
 
-	updatePackage, err := loc.ParseLocator(req.App)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
 	updateApp, err := s.apps().GetApp(*updatePackage)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -424,6 +432,11 @@ func (s *site) createUpdateOperation(context context.Context, req ops.CreateSite
 }
 
 func (s *site) validateUpdateOperationRequest(req ops.CreateSiteAppUpdateOperationRequest, provisioner string) error {
+	if req.Strategy != nil {
+		if err := req.Strategy.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	currentPackage, err := s.appPackage()
 	if err != nil {
 		return trace.Wrap(err)
@@ -0,0 +1,39 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// WatchCluster returns a channel that receives notifications about changes
+// to the cluster and its operations until the context is cancelled
+func (o *Operator) WatchCluster(ctx context.Context, req ops.WatchClusterRequest) (<-chan storage.ClusterChange, error) {
+	if req.SiteDomain == "" {
+		return nil, trace.BadParameter("missing cluster name")
+	}
+	changesC, err := storage.WatchClusterChanges(ctx, o.backend(), req.Prefix())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return changesC, nil
+}
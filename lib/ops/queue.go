@@ -0,0 +1,160 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// StartQueuedOperationRequest is a request to wait for a queued operation to start
+type StartQueuedOperationRequest struct {
+	// Operator is the cluster operator service
+	Operator Operator
+	// Key identifies the queued operation
+	Key QueuedOperationKey
+	// Printer optionally outputs the reason the operation is waiting
+	Printer utils.Printer
+}
+
+// StartQueuedOperation waits for the cluster operator to start the queued
+// operation and returns the key of the started operation.
+//
+// The operator starts the operation once it reaches the head of the cluster
+// operation queue and the cluster becomes idle. While waiting, the queue entry
+// is renewed so the operator knows the client is still around.
+// Returns trace.NotFound if the queued operation has been cancelled or has expired
+func StartQueuedOperation(ctx context.Context, req StartQueuedOperationRequest) (*SiteOperationKey, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changesC, err := req.Operator.WatchCluster(ctx, WatchClusterRequest{SiteKey: req.Key.SiteKey})
+	if err != nil {
+		log.WithError(err).Warn("Failed to watch cluster changes, will poll for them.")
+	}
+	ticker := time.NewTicker(defaults.OperationQueuePollInterval)
+	defer ticker.Stop()
+	var reason string
+	for {
+		key, err := checkQueuedOperation(ctx, req)
+		if err == nil {
+			return key, nil
+		}
+		if !trace.IsCompareFailed(err) {
+			return nil, trace.Wrap(err)
+		}
+		if err.Error() != reason {
+			reason = err.Error()
+			log.Infof("Queued operation %v is waiting: %v.", req.Key.ID, reason)
+			if req.Printer != nil {
+				req.Printer.PrintStep("Waiting for the operation to start: %v", reason)
+			}
+		}
+		if err := waitForQueueChange(ctx, &changesC, ticker.C); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+}
+
+// checkQueuedOperation renews the queued operation and returns the key
+// of the operation once it has been started.
+// Returns trace.CompareFailed with the reason if the operation is still waiting
+func checkQueuedOperation(ctx context.Context, req StartQueuedOperationRequest) (*SiteOperationKey, error) {
+	entry, err := req.Operator.RenewQueuedOperation(ctx, req.Key)
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		return findQueuedOperation(req)
+	}
+	if entry.Error != "" {
+		if err := req.Operator.CancelQueuedOperation(ctx, req.Key); err != nil && !trace.IsNotFound(err) {
+			log.WithError(err).Warn("Failed to remove operation from the queue.")
+		}
+		return nil, trace.BadParameter("operation failed to start: %v", entry.Error)
+	}
+	queue, err := req.Operator.GetQueuedOperations(req.Key.SiteKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var ahead int
+	for _, op := range queue {
+		if op.ID == req.Key.ID {
+			break
+		}
+		if op.Error == "" {
+			ahead++
+		}
+	}
+	if ahead > 0 {
+		return nil, trace.CompareFailed("%v operation(s) are queued ahead", ahead)
+	}
+	if entry.Reason != "" {
+		return nil, trace.CompareFailed("%v", entry.Reason)
+	}
+	return nil, trace.CompareFailed("operation is about to start")
+}
+
+// findQueuedOperation returns the key of the operation that has been started
+// from the queued operation.
+// Returns trace.NotFound if the queued operation has been cancelled or has expired
+func findQueuedOperation(req StartQueuedOperationRequest) (*SiteOperationKey, error) {
+	operations, err := req.Operator.GetSiteOperations(req.Key.SiteKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, op := range operations {
+		if op.QueueID == req.Key.ID {
+			return &SiteOperationKey{
+				AccountID:   op.AccountID,
+				SiteDomain:  op.SiteDomain,
+				OperationID: op.ID,
+			}, nil
+		}
+	}
+	return nil, trace.NotFound("queued operation %v has either been cancelled or has expired",
+		req.Key.ID)
+}
+
+// waitForQueueChange blocks until a change that might allow a queued operation
+// to start happens, or the poll interval elapses.
+// If the watch stops, it falls back to polling
+func waitForQueueChange(ctx context.Context, changesC *<-chan storage.ClusterChange, tickC <-chan time.Time) error {
+	for {
+		select {
+		case change, ok := <-*changesC:
+			if !ok {
+				*changesC = nil
+				continue
+			}
+			switch change.Kind {
+			case storage.ClusterChangeCluster, storage.ClusterChangeOperation, storage.ClusterChangeQueue:
+				return nil
+			}
+		case <-tickC:
+			return nil
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+}
@@ -430,6 +430,104 @@ func (r smtpConfigCollection) ToMarshal() interface{} {
 
 type smtpConfigCollection []storage.SMTPConfig
 
+// Resources returns the resources collection in the generic format
+func (r trustPolicyCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (r trustPolicyCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Mode", "Keys", "Certificate Authorities"})
+	for _, policy := range r {
+		var keys []string
+		for _, key := range policy.GetKeys() {
+			keys = append(keys, key.Name)
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", policy.GetMode(), strings.Join(keys, ", "),
+			len(policy.GetCertificateAuthorities()))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r trustPolicyCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r trustPolicyCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r trustPolicyCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+type trustPolicyCollection []storage.TrustPolicy
+
+// Resources returns the resources collection in the generic format
+func (r maintenanceWindowCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (r maintenanceWindowCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Timezone", "Windows", "Next Window"})
+	now := time.Now()
+	for _, window := range r {
+		var windows []string
+		for _, item := range window.GetWindows() {
+			windows = append(windows, item.String())
+		}
+		next := "open"
+		if !window.IsOpen(now) {
+			next = window.NextOpen(now).Format(constants.ShortDateFormat)
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", window.GetTimezone(), strings.Join(windows, "; "), next)
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r maintenanceWindowCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r maintenanceWindowCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r maintenanceWindowCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+type maintenanceWindowCollection []storage.MaintenanceWindow
+
 // WriteText serializes collection in human-friendly text format
 func (r alertCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
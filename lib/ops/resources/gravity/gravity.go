@@ -255,6 +255,26 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		r.Println("Updated persistent storage configuration")
+	case storage.KindTrustPolicy:
+		policy, err := storage.UnmarshalTrustPolicy(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertTrustPolicy(ctx, req.SiteKey, policy)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated package trust policy in %v mode\n", policy.GetMode())
+	case storage.KindMaintenanceWindow:
+		window, err := storage.UnmarshalMaintenanceWindow(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertMaintenanceWindow(ctx, req.SiteKey, window)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster maintenance window")
 	case "":
 		return trace.BadParameter("missing resource kind")
 	default:
@@ -414,6 +434,18 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 			return nil, trace.Wrap(err)
 		}
 		return storageCollection{PersistentStorage: ps}, nil
+	case storage.KindTrustPolicy:
+		policy, err := r.Operator.GetTrustPolicy(req.SiteKey)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return trustPolicyCollection{policy}, nil
+	case storage.KindMaintenanceWindow:
+		window, err := r.Operator.GetMaintenanceWindow(req.SiteKey)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return maintenanceWindowCollection{window}, nil
 	case "":
 		return nil, trace.BadParameter("missing resource kind")
 	}
@@ -514,6 +546,22 @@ func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) err
 	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
 		err := r.ClusterOperationHandler.RemoveResource(req)
 		return trace.Wrap(err)
+	case storage.KindTrustPolicy:
+		if err := r.Operator.DeleteTrustPolicy(ctx, req.SiteKey); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Println("Package trust policy has been deleted")
+	case storage.KindMaintenanceWindow:
+		if err := r.Operator.DeleteMaintenanceWindow(ctx, req.SiteKey); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Println("Cluster maintenance window has been deleted")
 	case "":
 		return trace.BadParameter("missing resource kind")
 	default:
@@ -561,6 +609,10 @@ func Validate(resource storage.UnknownResource) (err error) {
 		_, err = clusterconfig.Unmarshal(resource.Raw)
 	case storage.KindPersistentStorage:
 		_, err = storage.UnmarshalPersistentStorage(resource.Raw)
+	case storage.KindTrustPolicy:
+		_, err = storage.UnmarshalTrustPolicy(resource.Raw)
+	case storage.KindMaintenanceWindow:
+		_, err = storage.UnmarshalMaintenanceWindow(resource.Raw)
 	default:
 		return trace.NotImplemented("unsupported resource %q, supported are: %v",
 			resource.Kind, modules.GetResources().SupportedResources())
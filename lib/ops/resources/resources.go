@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app/resources"
 	"github.com/gravitational/gravity/lib/constants"
@@ -92,6 +93,17 @@ type CreateRequest struct {
 	// Confirmed defines whether the operation has been explicitly approved.
 	// This attribute is operation-specific
 	Confirmed bool
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window.
+	// This attribute is operation-specific
+	IgnoreMaintenanceWindow bool
+	// Queue defines whether the operation is queued to start once
+	// the cluster becomes idle.
+	// This attribute is operation-specific
+	Queue bool
+	// QueueTTL is how long the operation waits in the queue.
+	// This attribute is operation-specific
+	QueueTTL time.Duration
 }
 
 // String returns the request string representation.
@@ -169,6 +181,17 @@ type RemoveRequest struct {
 	// Confirmed defines whether the operation has been explicitly approved.
 	// This attribute is operation-specific
 	Confirmed bool
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window.
+	// This attribute is operation-specific
+	IgnoreMaintenanceWindow bool
+	// Queue defines whether the operation is queued to start once
+	// the cluster becomes idle.
+	// This attribute is operation-specific
+	Queue bool
+	// QueueTTL is how long the operation waits in the queue.
+	// This attribute is operation-specific
+	QueueTTL time.Duration
 }
 
 // String returns the request string representation.
@@ -197,6 +220,8 @@ func (r *RemoveRequest) Check() error {
 	case storage.KindRuntimeEnvironment:
 	case storage.KindClusterConfiguration:
 	case storage.KindPersistentStorage:
+	case storage.KindTrustPolicy:
+	case storage.KindMaintenanceWindow:
 	default:
 		if r.Name == "" {
 			return trace.BadParameter("resource name is mandatory")
@@ -0,0 +1,194 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pack
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/delta"
+
+	"github.com/gravitational/trace"
+)
+
+// DeltaService is implemented by remote package services that can exchange
+// package contents as binary deltas against an older version of the same
+// package to avoid transferring the data both sides already have
+type DeltaService interface {
+	// ReadPackageSignature returns the delta signature of the specified package
+	ReadPackageSignature(loc loc.Locator) (*delta.Signature, error)
+	// ReadPackageDelta returns the contents of the specified package encoded
+	// as a delta against the base package described with signature
+	ReadPackageDelta(loc loc.Locator, signature delta.Signature) (*PackageEnvelope, io.ReadCloser, error)
+	// WritePackageDelta creates or updates the package by applying the delta
+	// to the base package
+	WritePackageDelta(req PackageDeltaRequest) (*PackageEnvelope, error)
+}
+
+// PackageDeltaRequest describes a package to create from a delta
+type PackageDeltaRequest struct {
+	// Locator references the package to create
+	Locator loc.Locator
+	// Base references the package the delta has been computed against
+	Base loc.Locator
+	// SHA512 is the checksum of the reconstructed package
+	SHA512 string
+	// Delta is the delta stream
+	Delta io.Reader
+	// Upsert is whether to create or upsert the package
+	Upsert bool
+	// Options specifies the attributes of the created package
+	Options []PackageOption
+}
+
+// Check validates this request
+func (r PackageDeltaRequest) Check() error {
+	if r.Locator.Repository != r.Base.Repository || r.Locator.Name != r.Base.Name {
+		return trace.BadParameter("base package %v is not a version of %v", r.Base, r.Locator)
+	}
+	if r.SHA512 == "" {
+		return trace.BadParameter("missing parameter SHA512")
+	}
+	if r.Delta == nil {
+		return trace.BadParameter("missing parameter Delta")
+	}
+	return nil
+}
+
+// FindDeltaBase returns the latest version of the package specified with locator
+// that is older than locator and can serve as a base for a package delta
+func FindDeltaBase(packages PackageService, locator loc.Locator) (*loc.Locator, error) {
+	version, err := locator.SemVer()
+	if err != nil {
+		return nil, trace.NotFound("package %v has no semantic version", locator)
+	}
+	return FindLatestPackageCustom(FindLatestPackageRequest{
+		Packages:   packages,
+		Repository: locator.Repository,
+		Match: func(env PackageEnvelope) bool {
+			if env.Locator.Name != locator.Name || env.Encrypted {
+				return false
+			}
+			other, err := env.Locator.SemVer()
+			return err == nil && other.LessThan(*version)
+		},
+	})
+}
+
+// ReadPackageSignature computes the delta signature of the specified package
+func ReadPackageSignature(packages PackageService, locator loc.Locator) (*delta.Signature, error) {
+	_, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	signature, err := delta.NewFileSignature(reader, defaults.PackageDeltaBlockSize)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signature, nil
+}
+
+// ApplyPackageDelta returns the contents of the package reconstructed from
+// the delta read from r and the base package.
+// The contents are verified against the specified checksum and the last read
+// from the returned reader fails if the reconstructed package does not match it
+func ApplyPackageDelta(packages PackageService, base loc.Locator, sha512sum string, r io.Reader) (io.ReadCloser, error) {
+	baseReader, err := openDeltaBase(packages, base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		defer baseReader.Close()
+		hasher := sha512.New()
+		err := delta.Apply(baseReader, r, io.MultiWriter(writer, hasher))
+		if err == nil {
+			hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+			if hash != sha512sum {
+				err = trace.BadParameter("reconstructed package has checksum %v, expected %v",
+					hash, sha512sum)
+			}
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// openDeltaBase returns the contents of the base package the package delta
+// signature is computed over (see delta.NewFileSignature).
+// Compressed packages are decompressed into a temporary file which is
+// removed when the returned reader is closed
+func openDeltaBase(packages PackageService, base loc.Locator) (readSeekCloser, error) {
+	_, reader, err := packages.ReadPackage(base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	seeker, ok := reader.(readSeekCloser)
+	if !ok {
+		reader.Close()
+		return nil, trace.BadParameter("base package %v is not seekable", base)
+	}
+	contents, compressed, err := delta.Decompress(seeker)
+	if err != nil {
+		reader.Close()
+		return nil, trace.Wrap(err)
+	}
+	if !compressed {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			reader.Close()
+			return nil, trace.ConvertSystemError(err)
+		}
+		return seeker, nil
+	}
+	defer reader.Close()
+	file, err := ioutil.TempFile("", "delta-base")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	decompressed := &tempFile{File: file}
+	if _, err := io.Copy(file, contents); err != nil {
+		decompressed.Close()
+		return nil, trace.Wrap(err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		decompressed.Close()
+		return nil, trace.ConvertSystemError(err)
+	}
+	return decompressed, nil
+}
+
+type readSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// tempFile is a temporary file that is removed when closed
+type tempFile struct {
+	*os.File
+}
+
+// Close closes and removes the file
+func (r *tempFile) Close() error {
+	r.File.Close()
+	return trace.ConvertSystemError(os.Remove(r.Name()))
+}
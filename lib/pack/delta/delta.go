@@ -0,0 +1,512 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// package delta implements rsync-style binary deltas between two versions
+// of a file.
+//
+// The side that has the old version (the base) computes a Signature of it
+// and sends it to the side that has the new version (the target). The target
+// side encodes the target as a sequence of references to base blocks and
+// literal data and sends the delta back, where it is applied to the base
+// to reconstruct the target.
+//
+// A small change to the contents of a compressed file usually changes most
+// of the compressed stream, so for gzip-compressed files the signature and
+// the delta are computed over the decompressed contents (see NewFileSignature)
+// and the target is compressed again when the delta is applied.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/gravitational/trace"
+)
+
+// Signature describes the contents of a base file as a list of block checksums
+type Signature struct {
+	// BlockSize is the size of a block in bytes
+	BlockSize int
+	// Blocks lists checksums of all complete blocks of the base file
+	Blocks []Block
+	// Decompressed is true if the signature describes the decompressed
+	// contents of a gzip-compressed base file
+	Decompressed bool
+}
+
+// Block describes a single block of a base file
+type Block struct {
+	// Weak is the rolling checksum of the block
+	Weak uint32
+	// Strong is the truncated SHA-256 hash of the block
+	Strong [strongSize]byte
+}
+
+// NewSignature computes the signature of the base file read from r
+// using blocks of the specified size
+func NewSignature(r io.Reader, blockSize int) (*Signature, error) {
+	if blockSize <= 0 {
+		return nil, trace.BadParameter("block size should be positive, got %v", blockSize)
+	}
+	signature := &Signature{BlockSize: blockSize}
+	block := make([]byte, blockSize)
+	for {
+		_, err := io.ReadFull(r, block)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the trailing partial block is always sent as data
+			return signature, nil
+		}
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		signature.Blocks = append(signature.Blocks, Block{
+			Weak:   newChecksum(block).digest(),
+			Strong: strongHash(block),
+		})
+	}
+}
+
+// NewFileSignature computes the signature of the base file read from r
+// using blocks of the specified size.
+// If the file is gzip-compressed, the signature describes its decompressed
+// contents and the delta has to be applied to the decompressed base
+func NewFileSignature(r io.Reader, blockSize int) (*Signature, error) {
+	contents, compressed, err := Decompress(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signature, err := NewSignature(contents, blockSize)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signature.Decompressed = compressed
+	return signature, nil
+}
+
+// Decompress returns the decompressed contents of the file read from r
+// if it is gzip-compressed or the file contents as-is otherwise.
+// The second return value is true if the file has been decompressed
+func Decompress(r io.Reader) (io.Reader, bool, error) {
+	reader := bufio.NewReader(r)
+	if _, ok := readGzipHeader(reader); !ok {
+		return reader, false, nil
+	}
+	decompressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, false, trace.BadParameter("failed to read gzip header: %v", err)
+	}
+	return decompressed, true, nil
+}
+
+// WriteTo writes the signature in binary form to w
+func (s Signature) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString(signatureMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(s.BlockSize))
+	binary.Write(&buf, binary.BigEndian, uint32(len(s.Blocks)))
+	var flags uint32
+	if s.Decompressed {
+		flags |= signatureDecompressed
+	}
+	binary.Write(&buf, binary.BigEndian, flags)
+	for _, block := range s.Blocks {
+		binary.Write(&buf, binary.BigEndian, block.Weak)
+		buf.Write(block.Strong[:])
+	}
+	n, err := buf.WriteTo(w)
+	return n, trace.Wrap(err)
+}
+
+// ReadSignature reads a signature in binary form from r.
+// maxSize limits the size of the encoded signature
+func ReadSignature(r io.Reader, maxSize int64) (*Signature, error) {
+	r = io.LimitReader(r, maxSize)
+	var header struct {
+		Magic     [len(signatureMagic)]byte
+		BlockSize uint32
+		Count     uint32
+		Flags     uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, trace.BadParameter("failed to read signature header: %v", err)
+	}
+	if string(header.Magic[:]) != signatureMagic {
+		return nil, trace.BadParameter("not a delta signature")
+	}
+	if header.BlockSize == 0 {
+		return nil, trace.BadParameter("invalid signature block size")
+	}
+	if int64(header.Count)*blockRecordSize > maxSize {
+		return nil, trace.BadParameter("signature with %v blocks exceeds maximum size of %v bytes",
+			header.Count, maxSize)
+	}
+	signature := &Signature{
+		BlockSize:    int(header.BlockSize),
+		Blocks:       make([]Block, header.Count),
+		Decompressed: header.Flags&signatureDecompressed != 0,
+	}
+	if err := binary.Read(r, binary.BigEndian, signature.Blocks); err != nil {
+		return nil, trace.BadParameter("failed to read signature blocks: %v", err)
+	}
+	return signature, nil
+}
+
+// Compute reads the target file from r and writes the delta
+// against the base described with signature to w.
+//
+// If the signature describes a decompressed base and the target has been
+// compressed with compress/gzip, the delta is computed over the decompressed
+// target and the target is compressed again with the same parameters when
+// the delta is applied. The result can only be verified after the delta has
+// been applied, e.g. with the checksum of the target
+func Compute(signature Signature, r io.Reader, w io.Writer) error {
+	if signature.BlockSize <= 0 {
+		return trace.BadParameter("invalid signature block size %v", signature.BlockSize)
+	}
+	e := &encoder{w: bufio.NewWriter(w)}
+	if _, err := e.w.WriteString(deltaMagic); err != nil {
+		return trace.Wrap(err)
+	}
+	target := bufio.NewReader(r)
+	if header, ok := readGzipHeader(target); ok && signature.Decompressed && header.reproducible() {
+		decompressed, err := gzip.NewReader(target)
+		if err != nil {
+			return trace.BadParameter("failed to read gzip header: %v", err)
+		}
+		defer decompressed.Close()
+		e.w.WriteByte(formatGzip)
+		e.writeGzipHeader(header.level(), decompressed.Header)
+		return trace.Wrap(compute(signature, decompressed, e))
+	}
+	e.w.WriteByte(formatRaw)
+	return trace.Wrap(compute(signature, target, e))
+}
+
+func compute(signature Signature, r io.Reader, e *encoder) error {
+	blocks := make(map[uint32][]int, len(signature.Blocks))
+	for i, block := range signature.Blocks {
+		blocks[block.Weak] = append(blocks[block.Weak], i)
+	}
+
+	blockSize := signature.BlockSize
+	match := func(weak uint32, window []byte) (int, bool) {
+		indexes, ok := blocks[weak]
+		if !ok {
+			return 0, false
+		}
+		strong := strongHash(window)
+		for _, i := range indexes {
+			if signature.Blocks[i].Strong == strong {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	// data holds the pending literal data in data[start:pos]
+	// followed by the current window at data[pos:pos+blockSize]
+	data := make([]byte, 0, 4*blockSize)
+	var start, pos int
+	var eof bool
+	fill := func() error {
+		copy(data, data[start:])
+		data = data[:len(data)-start]
+		pos -= start
+		start = 0
+		n, err := io.ReadFull(r, data[len(data):cap(data)])
+		data = data[:len(data)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			eof = true
+			return nil
+		}
+		return trace.ConvertSystemError(err)
+	}
+
+	var sum checksum
+	var rolling bool
+	for {
+		// make sure the window and the byte following it are available
+		if len(data)-pos <= blockSize && !eof {
+			if err := fill(); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		if len(data)-pos < blockSize {
+			break
+		}
+		window := data[pos : pos+blockSize]
+		if !rolling {
+			sum = newChecksum(window)
+			rolling = true
+		}
+		if i, ok := match(sum.digest(), window); ok {
+			if err := e.data(data[start:pos]); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := e.copy(int64(i)*int64(blockSize), int64(blockSize)); err != nil {
+				return trace.Wrap(err)
+			}
+			pos += blockSize
+			start = pos
+			rolling = false
+			continue
+		}
+		if pos-start >= blockSize {
+			if err := e.data(data[start:pos]); err != nil {
+				return trace.Wrap(err)
+			}
+			start = pos
+		}
+		if pos+blockSize == len(data) {
+			// end of the target
+			break
+		}
+		sum.roll(data[pos], data[pos+blockSize])
+		pos++
+	}
+	if err := e.data(data[start:]); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.close())
+}
+
+// NewReader returns a reader with the delta of the target file read from r
+// against the base described with signature.
+// Closing the returned reader does not close r
+func NewReader(signature Signature, r io.Reader) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(Compute(signature, r, writer))
+	}()
+	return reader
+}
+
+// Apply reconstructs the target file from the base file and the delta
+// read from r and writes it to w.
+// The base file is the file the signature has been computed over, i.e.
+// the decompressed base if the signature describes a decompressed base
+func Apply(base io.ReadSeeker, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	magic := make([]byte, len(deltaMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return trace.BadParameter("failed to read delta header: %v", err)
+	}
+	if string(magic) != deltaMagic {
+		return trace.BadParameter("not a delta")
+	}
+	format, err := reader.ReadByte()
+	if err != nil {
+		return trace.BadParameter("failed to read delta format: %v", err)
+	}
+	switch format {
+	case formatRaw:
+		return trace.Wrap(apply(base, reader, w))
+	case formatGzip:
+		level, header, err := readGzipParams(reader)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		compressed, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return trace.BadParameter("invalid compression level %v", level)
+		}
+		compressed.Header = *header
+		if err := apply(base, reader, compressed); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(compressed.Close())
+	default:
+		return trace.BadParameter("unknown delta format %v", format)
+	}
+}
+
+func apply(base io.ReadSeeker, reader *bufio.Reader, w io.Writer) error {
+	var written int64
+	for {
+		op, err := reader.ReadByte()
+		if err != nil {
+			return trace.BadParameter("unexpected end of delta: %v", err)
+		}
+		switch op {
+		case opCopy:
+			offset, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return trace.BadParameter("failed to read copy offset: %v", err)
+			}
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return trace.BadParameter("failed to read copy length: %v", err)
+			}
+			if _, err := base.Seek(int64(offset), io.SeekStart); err != nil {
+				return trace.ConvertSystemError(err)
+			}
+			n, err := io.CopyN(w, base, int64(length))
+			written += n
+			if err == io.EOF {
+				return trace.BadParameter("delta references data past the end of the base")
+			}
+			if err != nil {
+				return trace.ConvertSystemError(err)
+			}
+		case opData:
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return trace.BadParameter("failed to read data length: %v", err)
+			}
+			n, err := io.CopyN(w, reader, int64(length))
+			written += n
+			if err == io.EOF {
+				return trace.BadParameter("unexpected end of delta")
+			}
+			if err != nil {
+				return trace.ConvertSystemError(err)
+			}
+		case opEnd:
+			size, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return trace.BadParameter("failed to read target size: %v", err)
+			}
+			if int64(size) != written {
+				return trace.BadParameter("delta produced %v bytes, expected %v", written, size)
+			}
+			return nil
+		default:
+			return trace.BadParameter("unknown delta operation %v", op)
+		}
+	}
+}
+
+// encoder writes delta operations, merging copies of adjacent base blocks
+type encoder struct {
+	w *bufio.Writer
+	// offset and length describe the pending copy operation
+	offset, length int64
+	// written is the total size of the target
+	written int64
+}
+
+func (e *encoder) copy(offset, length int64) error {
+	if e.length != 0 && e.offset+e.length == offset {
+		e.length += length
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return trace.Wrap(err)
+	}
+	e.offset, e.length = offset, length
+	return nil
+}
+
+func (e *encoder) data(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return trace.Wrap(err)
+	}
+	e.writeOp(opData, uint64(len(data)))
+	e.written += int64(len(data))
+	_, err := e.w.Write(data)
+	return trace.Wrap(err)
+}
+
+func (e *encoder) flush() error {
+	if e.length == 0 {
+		return nil
+	}
+	e.writeOp(opCopy, uint64(e.offset), uint64(e.length))
+	e.written += e.length
+	e.offset, e.length = 0, 0
+	return nil
+}
+
+func (e *encoder) close() error {
+	if err := e.flush(); err != nil {
+		return trace.Wrap(err)
+	}
+	e.writeOp(opEnd, uint64(e.written))
+	return trace.Wrap(e.w.Flush())
+}
+
+// writeOp writes the operation with its arguments. Write errors are
+// sticky in bufio.Writer and are reported by the final flush
+func (e *encoder) writeOp(op byte, args ...uint64) {
+	e.w.WriteByte(op)
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, arg := range args {
+		n := binary.PutUvarint(buf, arg)
+		e.w.Write(buf[:n])
+	}
+}
+
+// checksum is the rsync rolling checksum of a block
+type checksum struct {
+	a, b uint32
+	size uint32
+}
+
+func newChecksum(block []byte) checksum {
+	sum := checksum{size: uint32(len(block))}
+	for i, c := range block {
+		sum.a += uint32(c)
+		sum.b += uint32(len(block)-i) * uint32(c)
+	}
+	return sum
+}
+
+// roll moves the block one byte forward
+func (s *checksum) roll(out, in byte) {
+	s.a = s.a - uint32(out) + uint32(in)
+	s.b = s.b - s.size*uint32(out) + s.a
+}
+
+func (s checksum) digest() uint32 {
+	return s.a&0xffff | s.b<<16
+}
+
+func strongHash(block []byte) (hash [strongSize]byte) {
+	sum := sha256.Sum256(block)
+	copy(hash[:], sum[:])
+	return hash
+}
+
+const (
+	signatureMagic = "GSIG"
+	deltaMagic     = "GDLT"
+
+	// signatureDecompressed is the signature flag set for signatures
+	// of decompressed base files
+	signatureDecompressed uint32 = 1
+
+	// formatRaw is the format of deltas of the target file as-is
+	formatRaw byte = 0
+	// formatGzip is the format of deltas of the decompressed target file
+	// which is compressed again after the delta has been applied
+	formatGzip byte = 1
+
+	// strongSize is the size of a truncated strong block hash
+	strongSize = 16
+	// blockRecordSize is the size of an encoded block in a signature
+	blockRecordSize = 4 + strongSize
+
+	opEnd  byte = 0
+	opCopy byte = 1
+	opData byte = 2
+)
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestDelta(t *testing.T) { TestingT(t) }
+
+type DeltaSuite struct{}
+
+var _ = Suite(&DeltaSuite{})
+
+func (s *DeltaSuite) TestRoundtrip(c *C) {
+	base := randomData(1, 100*1024)
+	tcs := []struct {
+		comment string
+		target  []byte
+		// maxSize is the upper bound on the delta size
+		maxSize int
+	}{
+		{
+			comment: "identical",
+			target:  base,
+			maxSize: 64,
+		},
+		{
+			comment: "insertion in the middle",
+			target:  concat(base[:50000], []byte("inserted"), base[50000:]),
+			maxSize: 2*1024 + 64,
+		},
+		{
+			comment: "deletion in the middle",
+			target:  concat(base[:30000], base[31000:]),
+			maxSize: 2*1024 + 64,
+		},
+		{
+			comment: "appended data",
+			target:  concat(base, []byte("appended")),
+			maxSize: 64,
+		},
+		{
+			comment: "unrelated data",
+			target:  randomData(2, 10*1024),
+			maxSize: 11 * 1024,
+		},
+		{
+			comment: "empty target",
+			target:  nil,
+			maxSize: 16,
+		},
+	}
+	for _, tc := range tcs {
+		comment := Commentf(tc.comment)
+		signature, err := NewSignature(bytes.NewReader(base), 1024)
+		c.Assert(err, IsNil, comment)
+		c.Assert(signature.Blocks, HasLen, 100, comment)
+
+		var delta bytes.Buffer
+		c.Assert(Compute(*signature, bytes.NewReader(tc.target), &delta), IsNil, comment)
+		c.Assert(delta.Len() <= tc.maxSize, Equals, true,
+			Commentf("%v: delta size %v", tc.comment, delta.Len()))
+
+		var out bytes.Buffer
+		c.Assert(Apply(bytes.NewReader(base), &delta, &out), IsNil, comment)
+		c.Assert(bytes.Equal(out.Bytes(), tc.target), Equals, true, comment)
+	}
+}
+
+func (s *DeltaSuite) TestSignatureEncoding(c *C) {
+	signature, err := NewSignature(bytes.NewReader(randomData(1, 10*1024+100)), 1024)
+	c.Assert(err, IsNil)
+	c.Assert(signature.Blocks, HasLen, 10)
+	signature.Decompressed = true
+
+	var buf bytes.Buffer
+	_, err = signature.WriteTo(&buf)
+	c.Assert(err, IsNil)
+	encoded := buf.Bytes()
+
+	out, err := ReadSignature(bytes.NewReader(encoded), int64(len(encoded)))
+	c.Assert(err, IsNil)
+	c.Assert(out, DeepEquals, signature)
+
+	_, err = ReadSignature(bytes.NewReader(encoded), 100)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+	_, err = ReadSignature(bytes.NewReader([]byte("garbage")), 100)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *DeltaSuite) TestCompressedFiles(c *C) {
+	base := compressible(1, 256*1024)
+	target := concat(base[:100000], []byte("inserted"), base[100000:])
+	modTime := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	compressedBase := compress(c, base, gzip.DefaultCompression, gzip.Header{Name: "base"})
+	tcs := []struct {
+		comment string
+		target  []byte
+		// maxSize is the upper bound on the delta size
+		maxSize int
+	}{
+		{
+			comment: "default compression",
+			target:  compress(c, target, gzip.DefaultCompression, gzip.Header{Name: "app.tar", ModTime: modTime}),
+			maxSize: 4 * 1024,
+		},
+		{
+			comment: "best compression",
+			target:  compress(c, target, gzip.BestCompression, gzip.Header{Comment: "comment", Extra: []byte("extra")}),
+			maxSize: 4 * 1024,
+		},
+		{
+			comment: "not written by compress/gzip",
+			target:  compress(c, target, gzip.DefaultCompression, gzip.Header{OS: 3}),
+			maxSize: 256 * 1024,
+		},
+		{
+			comment: "uncompressed",
+			target:  target,
+			maxSize: 4 * 1024,
+		},
+	}
+	for _, tc := range tcs {
+		comment := Commentf(tc.comment)
+		signature, err := NewFileSignature(bytes.NewReader(compressedBase), 1024)
+		c.Assert(err, IsNil, comment)
+		c.Assert(signature.Decompressed, Equals, true, comment)
+
+		var delta bytes.Buffer
+		c.Assert(Compute(*signature, bytes.NewReader(tc.target), &delta), IsNil, comment)
+		c.Assert(delta.Len() <= tc.maxSize, Equals, true,
+			Commentf("%v: delta size %v", tc.comment, delta.Len()))
+
+		// the delta is applied to the decompressed base
+		var out bytes.Buffer
+		c.Assert(Apply(bytes.NewReader(base), &delta, &out), IsNil, comment)
+		c.Assert(bytes.Equal(out.Bytes(), tc.target), Equals, true, comment)
+	}
+}
+
+func (s *DeltaSuite) TestReader(c *C) {
+	base := randomData(1, 20*1024)
+	target := concat([]byte("prefix"), base)
+	signature, err := NewSignature(bytes.NewReader(base), 1024)
+	c.Assert(err, IsNil)
+
+	reader := NewReader(*signature, bytes.NewReader(target))
+	defer reader.Close()
+	var out bytes.Buffer
+	c.Assert(Apply(bytes.NewReader(base), reader, &out), IsNil)
+	c.Assert(bytes.Equal(out.Bytes(), target), Equals, true)
+}
+
+func (s *DeltaSuite) TestRejectsCorruptDelta(c *C) {
+	base := randomData(1, 8*1024)
+	signature, err := NewSignature(bytes.NewReader(base), 1024)
+	c.Assert(err, IsNil)
+	var delta bytes.Buffer
+	c.Assert(Compute(*signature, bytes.NewReader(concat(base, []byte("tail"))), &delta), IsNil)
+
+	// truncated delta
+	truncated := delta.Bytes()[:delta.Len()-2]
+	err = Apply(bytes.NewReader(base), bytes.NewReader(truncated), ioutil.Discard)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	// base that is shorter than expected
+	err = Apply(bytes.NewReader(base[:4096]), bytes.NewReader(delta.Bytes()), ioutil.Discard)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// compressible returns data that resembles package contents:
+// random words separated by spaces
+func compressible(seed int64, size int) []byte {
+	words := []string{"gravity", "planet", "teleport", "cluster", "package", "node", "etcd", "\n"}
+	rnd := rand.New(rand.NewSource(seed))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[rnd.Intn(len(words))])
+		buf.WriteByte(' ')
+	}
+	return buf.Bytes()[:size]
+}
+
+func compress(c *C, data []byte, level int, header gzip.Header) []byte {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	c.Assert(err, IsNil)
+	w.Header = header
+	if header.OS == 0 {
+		w.Header.OS = 255
+	}
+	_, err = w.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// gzipHeader is the fixed-size part of a gzip member header (RFC 1952)
+type gzipHeader [10]byte
+
+// readGzipHeader returns the fixed-size gzip header of the stream
+// without consuming it. Returns false if the stream is not gzip-compressed
+func readGzipHeader(r *bufio.Reader) (header gzipHeader, ok bool) {
+	data, err := r.Peek(len(header))
+	if err != nil || data[0] != 0x1f || data[1] != 0x8b || data[2] != gzipDeflate {
+		return header, false
+	}
+	copy(header[:], data)
+	return header, true
+}
+
+// reproducible returns true if the stream has been written by compress/gzip
+// which always sets the operating system to unknown. Such a stream is
+// reproduced exactly from its decompressed contents by compressing them
+// with the same level and header
+func (r gzipHeader) reproducible() bool {
+	return r[9] == gzipOSUnknown
+}
+
+// level returns the compression level of the stream written by compress/gzip
+// based on the extra flags of the header. compress/gzip only marks the best
+// and the fastest compression, so other levels are reported as the default
+func (r gzipHeader) level() int {
+	switch r[8] {
+	case 2:
+		return gzip.BestCompression
+	case 4:
+		return gzip.BestSpeed
+	default:
+		return gzip.DefaultCompression
+	}
+}
+
+// writeGzipHeader writes the parameters of the compressed target file
+func (e *encoder) writeGzipHeader(level int, header gzip.Header) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, int64(level))
+	e.w.Write(buf[:n])
+	var modTime uint64
+	if header.ModTime.After(time.Unix(0, 0)) {
+		modTime = uint64(header.ModTime.Unix())
+	}
+	e.w.WriteByte(header.OS)
+	n = binary.PutUvarint(buf, modTime)
+	e.w.Write(buf[:n])
+	for _, field := range [][]byte{[]byte(header.Name), []byte(header.Comment), header.Extra} {
+		n := binary.PutUvarint(buf, uint64(len(field)))
+		e.w.Write(buf[:n])
+		e.w.Write(field)
+	}
+}
+
+// readGzipParams reads the compression level and the header
+// of the target file written with writeGzipHeader
+func readGzipParams(r *bufio.Reader) (level int, header *gzip.Header, err error) {
+	value, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, nil, trace.BadParameter("failed to read compression level: %v", err)
+	}
+	header = &gzip.Header{}
+	header.OS, err = r.ReadByte()
+	if err != nil {
+		return 0, nil, trace.BadParameter("failed to read gzip header: %v", err)
+	}
+	modTime, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, trace.BadParameter("failed to read gzip header: %v", err)
+	}
+	if modTime != 0 {
+		header.ModTime = time.Unix(int64(modTime), 0)
+	}
+	var fields [3][]byte
+	for i := range fields {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, nil, trace.BadParameter("failed to read gzip header: %v", err)
+		}
+		if length > maxGzipHeaderField {
+			return 0, nil, trace.BadParameter("gzip header field exceeds %v bytes", maxGzipHeaderField)
+		}
+		fields[i] = make([]byte, length)
+		if _, err := io.ReadFull(r, fields[i]); err != nil {
+			return 0, nil, trace.BadParameter("failed to read gzip header: %v", err)
+		}
+	}
+	header.Name, header.Comment = string(fields[0]), string(fields[1])
+	if len(fields[2]) != 0 {
+		header.Extra = fields[2]
+	}
+	return int(value), header, nil
+}
+
+const (
+	// gzipDeflate is the gzip compression method
+	gzipDeflate = 8
+	// gzipOSUnknown is the operating system compress/gzip writes
+	gzipOSUnknown = 255
+	// maxGzipHeaderField limits the size of the gzip header fields in a delta
+	maxGzipHeaderField = 64 * 1024
+)
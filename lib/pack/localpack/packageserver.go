@@ -136,6 +136,7 @@ func (p *PackageServer) GetPackages(repository string) ([]pack.PackageEnvelope,
 			Manifest:      p.Manifest,
 			Created:       p.Created,
 			CreatedBy:     p.CreatedBy,
+			Signatures:    p.Signatures,
 		})
 	}
 
@@ -177,6 +178,7 @@ func (p *PackageServer) CreatePackage(loc loc.Locator, data io.Reader, options .
 		Manifest:      pkg.Manifest,
 		Created:       pkg.Created,
 		CreatedBy:     pkg.CreatedBy,
+		Signatures:    pkg.Signatures,
 	}
 
 	// check that the repository exists
@@ -224,6 +226,7 @@ func (p *PackageServer) UpsertPackage(loc loc.Locator, data io.Reader, options .
 		Manifest:      pkg.Manifest,
 		Created:       pkg.Created,
 		CreatedBy:     pkg.CreatedBy,
+		Signatures:    pkg.Signatures,
 	}
 
 	_, err = p.backend.CreateRepository(storage.NewRepository(loc.Repository))
@@ -473,5 +476,6 @@ func newEnvelope(loc loc.Locator, p *storage.Package) *pack.PackageEnvelope {
 		Manifest:      p.Manifest,
 		Created:       p.Created,
 		CreatedBy:     p.CreatedBy,
+		Signatures:    p.Signatures,
 	}
 }
@@ -53,3 +53,10 @@ func WithCreatedBy(createdBy string) PackageOption {
 		pkg.CreatedBy = createdBy
 	}
 }
+
+// WithSignatures configures the signatures of a package
+func WithSignatures(signatures []storage.PackageSignature) PackageOption {
+	return func(pkg *storage.Package) {
+		pkg.Signatures = signatures
+	}
+}
@@ -61,6 +61,8 @@ type PackageEnvelope struct {
 	Created time.Time `json:"created"`
 	// CreatedBy specifies the package creator
 	CreatedBy string `json:"created_by"`
+	// Signatures lists signatures of the package producers
+	Signatures []storage.PackageSignature `json:"signatures,omitempty"`
 }
 
 // HasLabel returns true if envelope has the requested label
@@ -117,6 +119,9 @@ func (p PackageEnvelope) Options() []PackageOption {
 	if p.CreatedBy != "" {
 		options = append(options, WithCreatedBy(p.CreatedBy))
 	}
+	if len(p.Signatures) != 0 {
+		options = append(options, WithSignatures(p.Signatures))
+	}
 	return options
 }
 
@@ -140,6 +145,7 @@ func (p PackageEnvelope) ToPackagePtr() *storage.Package {
 		Hidden:        p.Hidden,
 		Encrypted:     p.Encrypted,
 		Manifest:      p.Manifest,
+		Signatures:    p.Signatures,
 	}
 }
 
@@ -206,6 +212,12 @@ func (s PackageSorter) Less(i, j int) bool {
 // PackageOption is a function that can make attribute modifications to the specified package
 type PackageOption func(pkg *storage.Package)
 
+// Signer signs packages with the key of the package producer
+type Signer interface {
+	// SignPackage returns the signature of the package described with envelope
+	SignPackage(PackageEnvelope) (*storage.PackageSignature, error)
+}
+
 type ProgressWriter struct {
 	Size    int64
 	current int64
@@ -218,6 +230,13 @@ func (w *ProgressWriter) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// Reset restarts the progress from zero, for example when the transfer
+// is retried
+func (w *ProgressWriter) Reset() {
+	w.current = 0
+	w.R.Report(w.current, w.Size)
+}
+
 type ProgressReporter interface {
 	Report(current, target int64)
 }
@@ -19,6 +19,7 @@ package pack
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha512"
 	"fmt"
 	"io"
 	"os"
@@ -300,6 +301,31 @@ func ForeachPackageInRepo(packages PackageService, repo string, fn func(e Packag
 	return nil
 }
 
+// VerifyPackage reads the contents of the package specified with locator
+// and checks them against the size and checksum recorded in its envelope
+func VerifyPackage(packages PackageService, locator loc.Locator) error {
+	envelope, rc, err := packages.ReadPackage(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer rc.Close()
+	hasher := sha512.New()
+	size, err := io.Copy(hasher, rc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if size != envelope.SizeBytes {
+		return trace.BadParameter("package %v size mismatch: expected %v bytes, got %v",
+			locator, envelope.SizeBytes, size)
+	}
+	checksum := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	if checksum != envelope.SHA512 {
+		return trace.BadParameter("package %v checksum mismatch: expected %v, got %v",
+			locator, envelope.SHA512, checksum)
+	}
+	return nil
+}
+
 // FindInstalledPackage finds package currently installed on the host
 func FindInstalledPackage(packages PackageService, filter loc.Locator) (*loc.Locator, error) {
 	env, err := findInstalledPackage(packages, filter)
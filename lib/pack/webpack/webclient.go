@@ -17,16 +17,20 @@ limitations under the License.
 package webpack
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/roundtrip"
@@ -145,30 +149,87 @@ func (c *Client) createOrUpsertPackage(loc loc.Locator, data io.Reader, upsert b
 		Filename: loc.String(),
 		Reader:   data,
 	}
-	pkg := storage.Package{
-		Repository: loc.Repository,
-		Name:       loc.Name,
-		Version:    loc.Version,
+	values, err := packageValues(loc, upsert, options...)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-	for _, option := range options {
-		option(&pkg)
+	out, err := c.PostForm(c.Endpoint("repositories", loc.Repository, "packages"), values, file)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-	labelsJSON, err := json.Marshal(pkg.RuntimeLabels)
+	var envelope *pack.PackageEnvelope
+	if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// ReadPackageSignature returns the delta signature of the specified package
+func (c *Client) ReadPackageSignature(loc loc.Locator) (*delta.Signature, error) {
+	re, err := c.Client.GetFile(context.TODO(), c.Endpoint("repositories", loc.Repository,
+		"packages", loc.Name, loc.Version, "signature"), url.Values{})
 	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer re.Close()
+	if err := checkResponse(re.Code(), re.Body()); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	values := url.Values{
-		"labels": []string{string(labelsJSON)},
-		"hidden": []string{fmt.Sprintf("%t", pkg.Hidden)},
-		"upsert": []string{fmt.Sprintf("%t", upsert)},
+	signature, err := delta.ReadSignature(re.Body(), defaults.MaxPackageSignatureSize)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-	if pkg.Type != "" {
-		values["type"] = []string{pkg.Type}
+	return signature, nil
+}
+
+// ReadPackageDelta returns the contents of the specified package encoded
+// as a delta against the base package described with signature
+func (c *Client) ReadPackageDelta(loc loc.Locator, signature delta.Signature) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	envelope, err := c.ReadPackageEnvelope(loc)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
 	}
-	if len(pkg.Manifest) > 0 {
-		values["manifest"] = []string{string(pkg.Manifest)}
+	var buf bytes.Buffer
+	if _, err := signature.WriteTo(&buf); err != nil {
+		return nil, nil, trace.Wrap(err)
 	}
-	out, err := c.PostForm(c.Endpoint("repositories", loc.Repository, "packages"), values, file)
+	req, err := http.NewRequest(http.MethodPost, c.Endpoint("repositories", loc.Repository,
+		"packages", loc.Name, loc.Version, "delta"), &buf)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.SetAuthHeader(req.Header)
+	re, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	if err := checkResponse(re.StatusCode, re.Body); err != nil {
+		re.Body.Close()
+		return nil, nil, trace.Wrap(err)
+	}
+	return envelope, re.Body, nil
+}
+
+// WritePackageDelta creates or updates the package by uploading the delta
+// against the base package
+func (c *Client) WritePackageDelta(req pack.PackageDeltaRequest) (*pack.PackageEnvelope, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	values, err := packageValues(req.Locator, req.Upsert, req.Options...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	values.Set("locator", req.Locator.String())
+	values.Set("base", req.Base.String())
+	values.Set("sha512", req.SHA512)
+	file := roundtrip.File{
+		Name:     "package",
+		Filename: req.Locator.String(),
+		Reader:   req.Delta,
+	}
+	out, err := c.PostForm(c.Endpoint("repositories", req.Locator.Repository, "deltas"), values, file)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -239,6 +300,54 @@ func (c *Client) ReadPackageEnvelope(loc loc.Locator) (*pack.PackageEnvelope, er
 	return envelope, nil
 }
 
+// packageValues returns the form values describing the package attributes
+func packageValues(loc loc.Locator, upsert bool, options ...pack.PackageOption) (url.Values, error) {
+	pkg := storage.Package{
+		Repository: loc.Repository,
+		Name:       loc.Name,
+		Version:    loc.Version,
+	}
+	for _, option := range options {
+		option(&pkg)
+	}
+	labelsJSON, err := json.Marshal(pkg.RuntimeLabels)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	values := url.Values{
+		"labels": []string{string(labelsJSON)},
+		"hidden": []string{fmt.Sprintf("%t", pkg.Hidden)},
+		"upsert": []string{fmt.Sprintf("%t", upsert)},
+	}
+	if pkg.Type != "" {
+		values["type"] = []string{pkg.Type}
+	}
+	if len(pkg.Manifest) > 0 {
+		values["manifest"] = []string{string(pkg.Manifest)}
+	}
+	if len(pkg.Signatures) > 0 {
+		signaturesJSON, err := json.Marshal(pkg.Signatures)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		values["signatures"] = []string{string(signaturesJSON)}
+	}
+	return values, nil
+}
+
+// checkResponse returns an error if the streamed response
+// with the specified status code failed
+func checkResponse(code int, body io.Reader) error {
+	if code >= 200 && code <= 299 {
+		return nil
+	}
+	bytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.ReadError(code, bytes)
+}
+
 // PostForm is a generic method that issues http POST request to the server
 func (c *Client) PostForm(
 	endpoint string,
@@ -25,8 +25,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
 	"github.com/gravitational/gravity/lib/utils/fields"
@@ -97,6 +99,9 @@ func NewHandler(cfg Config) (*Server, error) {
 	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/envelope", h.needsAuth(h.getPackageEnvelope))
 	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.updatePackageLabels))
 	h.DELETE("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.deletePackage))
+	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/signature", h.needsAuth(h.getPackageSignature))
+	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version/delta", h.needsAuth(h.getPackageDelta))
+	h.POST("/pack/v1/repositories/:repository/deltas", h.needsAuth(h.createPackageFromDelta))
 
 	return h, nil
 }
@@ -221,6 +226,7 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 	var hiddenS string
 	var packageType string
 	var manifest string
+	var signatures string
 
 	err := form.Parse(r,
 		form.FileSlice("package", &files),
@@ -229,6 +235,7 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 		form.String("hidden", &hiddenS),
 		form.String("type", &packageType),
 		form.String("manifest", &manifest),
+		form.String("signatures", &signatures),
 	)
 	if err != nil {
 		return trace.Wrap(err)
@@ -238,25 +245,14 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 		return trace.BadParameter("expected a single file parameter but got %d", len(files))
 	}
 
-	var labels map[string]string
-	if err := json.Unmarshal([]byte(labelsMap), &labels); err != nil {
+	upsert, err := parseBool("upsert", upsertS)
+	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	var upsert bool
-	if upsertS != "" {
-		upsert, err = strconv.ParseBool(upsertS)
-		if err != nil {
-			return trace.BadParameter("upsert should be either 'true' or 'false', got %v", upsertS)
-		}
-	}
-
-	var hidden bool
-	if hiddenS != "" {
-		hidden, err = strconv.ParseBool(hiddenS)
-		if err != nil {
-			return trace.BadParameter("hidden should be either 'true' or 'false', got %v", hiddenS)
-		}
+	opts, err := packageOptions(labelsMap, hiddenS, packageType, manifest, signatures)
+	if err != nil {
+		return trace.Wrap(err)
 	}
 
 	defer func() {
@@ -271,12 +267,6 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 		return trace.BadParameter(err.Error())
 	}
 
-	// configure package attributes
-	opts := []pack.PackageOption{pack.WithLabels(labels), pack.WithHidden(hidden)}
-	if manifest != "" {
-		opts = append(opts, pack.WithManifest(packageType, []byte(manifest)))
-	}
-
 	var envelope *pack.PackageEnvelope
 	if upsert {
 		envelope, err = service.UpsertPackage(*loc, files[0], opts...)
@@ -291,6 +281,126 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 	return nil
 }
 
+// getPackageSignature returns the delta signature of the package
+func (s *Server) getPackageSignature(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
+	if err != nil {
+		return trace.BadParameter(err.Error())
+	}
+	signature, err := pack.ReadPackageSignature(service, *loc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, err = signature.WriteTo(w)
+	return trace.Wrap(err)
+}
+
+// getPackageDelta returns the package contents encoded as a delta against the
+// base package with the signature sent in the request body
+func (s *Server) getPackageDelta(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
+	if err != nil {
+		return trace.BadParameter(err.Error())
+	}
+	signature, err := delta.ReadSignature(r.Body, defaults.MaxPackageSignatureSize)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	envelope, reader, err := service.ReadPackage(*loc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	if envelope.Encrypted {
+		return trace.BadParameter("package %v is encrypted", loc)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	err = delta.Compute(*signature, reader, w)
+	if err != nil {
+		// the response has already been started so the client
+		// will detect the truncated delta
+		log.Warnf("Failed to send delta for %v: %v.", loc, trace.DebugReport(err))
+	}
+	return nil
+}
+
+// createPackageFromDelta creates or updates a package by applying the uploaded
+// delta to the base package
+func (s *Server) createPackageFromDelta(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	var files form.Files
+	var locatorS, baseS, sha512sum, labelsMap, upsertS, hiddenS, packageType, manifest, signatures string
+	err := form.Parse(r,
+		form.FileSlice("package", &files),
+		form.String("locator", &locatorS, form.Required()),
+		form.String("base", &baseS, form.Required()),
+		form.String("sha512", &sha512sum, form.Required()),
+		form.String("labels", &labelsMap),
+		form.String("upsert", &upsertS),
+		form.String("hidden", &hiddenS),
+		form.String("type", &packageType),
+		form.String("manifest", &manifest),
+		form.String("signatures", &signatures),
+	)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer func() {
+		if err := files.Close(); err != nil {
+			log.Errorf("failed to close files: %v", err)
+		}
+	}()
+	if len(files) != 1 {
+		return trace.BadParameter("expected a single file parameter but got %d", len(files))
+	}
+	locator, err := loc.ParseLocator(locatorS)
+	if err != nil {
+		return trace.BadParameter(err.Error())
+	}
+	if locator.Repository != p.ByName("repository") {
+		return trace.BadParameter("package %v does not belong to repository %q",
+			locator, p.ByName("repository"))
+	}
+	base, err := loc.ParseLocator(baseS)
+	if err != nil {
+		return trace.BadParameter(err.Error())
+	}
+	upsert, err := parseBool("upsert", upsertS)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	opts, err := packageOptions(labelsMap, hiddenS, packageType, manifest, signatures)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req := pack.PackageDeltaRequest{
+		Locator: *locator,
+		Base:    *base,
+		SHA512:  sha512sum,
+		Delta:   files[0],
+	}
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	reader, err := pack.ApplyPackageDelta(service, req.Base, req.SHA512, req.Delta)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	var envelope *pack.PackageEnvelope
+	if upsert {
+		envelope, err = service.UpsertPackage(req.Locator, reader, opts...)
+	} else {
+		envelope, err = service.CreatePackage(req.Locator, reader, opts...)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, envelope)
+	return nil
+}
+
 func (s *Server) updatePackageLabels(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
 	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
 	if err != nil {
@@ -339,6 +449,42 @@ func (s *Server) needsAuth(fn authHandle) httprouter.Handle {
 	}
 }
 
+// packageOptions returns package attributes from the package upload form values
+func packageOptions(labelsMap, hiddenS, packageType, manifest, signaturesJSON string) ([]pack.PackageOption, error) {
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(labelsMap), &labels); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hidden, err := parseBool("hidden", hiddenS)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opts := []pack.PackageOption{pack.WithLabels(labels), pack.WithHidden(hidden)}
+	if manifest != "" {
+		opts = append(opts, pack.WithManifest(packageType, []byte(manifest)))
+	}
+	if signaturesJSON != "" {
+		var signatures []storage.PackageSignature
+		if err := json.Unmarshal([]byte(signaturesJSON), &signatures); err != nil {
+			return nil, trace.BadParameter("invalid package signatures: %v", err)
+		}
+		opts = append(opts, pack.WithSignatures(signatures))
+	}
+	return opts, nil
+}
+
+// parseBool parses the optional boolean form value
+func parseBool(name, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, trace.BadParameter("%v should be either 'true' or 'false', got %v", name, value)
+	}
+	return result, nil
+}
+
 type authHandle func(
 	http.ResponseWriter, *http.Request, httprouter.Params, pack.PackageService) error
 
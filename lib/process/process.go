@@ -35,7 +35,9 @@ import (
 	"github.com/gravitational/gravity/lib/app"
 	apphandler "github.com/gravitational/gravity/lib/app/handler"
 	appservice "github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/autoscale"
 	"github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/webhook"
 	"github.com/gravitational/gravity/lib/blob"
 	blobclient "github.com/gravitational/gravity/lib/blob/client"
 	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
@@ -221,7 +223,7 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	objects, err := blobfs.New(filepath.Join(cfg.DataDir, defaults.PackagesDir))
+	objects, err := cfg.CreateObjects()
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -244,16 +246,21 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	clusterObjects, err := blobcluster.New(blobcluster.Config{
-		Local:         objects,
-		Backend:       backend,
-		GetPeer:       peerPool.GetPeer,
-		ID:            processID,
-		AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
-		// TODO: set WriteFactor to the number of controller instances
-	})
-	if err != nil {
-		return nil, trace.Wrap(err)
+	// shared storage is accessible to every peer directly
+	// so BLOBs do not need to be replicated
+	clusterObjects := objects
+	if !cfg.Pack.Storage.IsShared() {
+		clusterObjects, err = blobcluster.New(blobcluster.Config{
+			Local:         objects,
+			Backend:       backend,
+			GetPeer:       peerPool.GetPeer,
+			ID:            processID,
+			AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
+			// TODO: set WriteFactor to the number of controller instances
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
 	}
 
 	packages, err := localpack.New(localpack.Config{
@@ -519,48 +526,100 @@ func (p *Process) leaderStatus() (string, bool) {
 }
 
 func (p *Process) startAutoscale(ctx context.Context) error {
-	_, err := cloudaws.NewLocalInstance()
-	if err != nil {
-		p.Info("Not on AWS, skip autoscaler start.")
-		return nil
-	}
-	p.Info("Starting AWS autoscaler.")
 	site, err := p.operator.GetLocalSite()
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	provider, err := p.newAutoscaleProvider(ctx, site.Domain)
+	if err != nil {
+		p.Warningf("Failed to initialize autoscaling provider: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
+		return nil
+	}
+	if provider == nil {
+		return nil
+	}
 	client, err := tryGetPrivilegedKubeClient()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	autoscaler, err := aws.New(aws.Config{
-		ClusterName: site.Domain,
-		Client:      client,
+	autoscaler, err := autoscale.New(autoscale.Config{
+		Provider: provider,
+		Operator: p.operator,
+		Client:   client,
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	queueURL, err := autoscaler.GetQueueURL(ctx)
-	if err != nil {
-		p.Warningf("Failed to get Autoscale Queue URL: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
-		return nil
-	}
+	p.Infof("Starting %v autoscaler.", provider.Name())
 
-	// receive and process events from SQS notification service
+	// receive and process autoscaling events from the provider
 	p.RegisterClusterService(func(ctx context.Context) {
 		localCtx := context.WithValue(ctx, constants.UserContext,
 			constants.ServiceAutoscaler)
-		autoscaler.ProcessEvents(localCtx, queueURL, p.operator)
+		autoscaler.ProcessEvents(localCtx)
 	})
 	// publish discovery information about this cluster
 	p.RegisterClusterService(func(ctx context.Context) {
 		localCtx := context.WithValue(ctx, constants.UserContext,
 			constants.ServiceAutoscaler)
-		autoscaler.PublishDiscovery(localCtx, p.operator)
+		autoscaler.PublishDiscovery(localCtx)
 	})
 	return nil
 }
 
+// newAutoscaleProvider returns the autoscaling provider configured for this cluster.
+// Returns nil if autoscaling is not available
+func (p *Process) newAutoscaleProvider(ctx context.Context, clusterName string) (autoscale.Provider, error) {
+	if p.cfg.Autoscale.Provider == webhook.ProviderName {
+		return p.newWebhookAutoscaleProvider(clusterName)
+	}
+	_, err := cloudaws.NewLocalInstance()
+	if err != nil {
+		p.Info("Not on AWS, skip autoscaler start.")
+		return nil, nil
+	}
+	provider, err := aws.New(aws.Config{
+		ClusterName: clusterName,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	provider.QueueURL, err = provider.GetQueueURL(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to get autoscale queue URL")
+	}
+	return provider, nil
+}
+
+func (p *Process) newWebhookAutoscaleProvider(clusterName string) (autoscale.Provider, error) {
+	config := p.cfg.Autoscale.Webhook
+	secret, err := ioutil.ReadFile(config.SecretFile)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	provider, err := webhook.New(webhook.Config{
+		ClusterName:  clusterName,
+		DiscoveryURL: config.DiscoveryURL,
+		Secret:       strings.TrimSpace(string(secret)),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// accept autoscaling events from the infrastructure
+	p.RegisterClusterService(func(ctx context.Context) {
+		tlsConfig, err := p.getTLSConfig()
+		if err != nil {
+			p.WithError(err).Warn("Failed to get TLS config for autoscale events.")
+			return
+		}
+		err = provider.ListenAndServe(ctx, config.ListenAddr, tlsConfig)
+		if err != nil {
+			p.WithError(err).Warn("Failed to serve autoscale events.")
+		}
+	})
+	return provider, nil
+}
+
 // runApplicationsSynchronizer runs a service that periodically exports
 // Docker images of the cluster's application images to the local Docker
 // registry.
@@ -807,6 +866,34 @@ func (p *Process) runSiteStatusChecker(ctx context.Context) {
 	}
 }
 
+// runOperationQueue returns a service that periodically starts operations
+// from the cluster operation queue
+func (p *Process) runOperationQueue(operator *opsservice.Operator) func(context.Context) {
+	return func(ctx context.Context) {
+		p.Info("Starting operation queue processor.")
+		ticker := time.NewTicker(defaults.OperationQueuePollInterval)
+		defer ticker.Stop()
+		localCtx := context.WithValue(ctx, constants.UserContext,
+			constants.ServiceOperationQueue)
+		for {
+			select {
+			case <-ticker.C:
+				cluster, err := operator.GetLocalSite()
+				if err != nil {
+					p.WithError(err).Warn("Failed to get local cluster.")
+					continue
+				}
+				if err := operator.ProcessOperationQueue(localCtx, cluster.Key()); err != nil {
+					p.WithError(err).Warn("Failed to process operation queue.")
+				}
+			case <-ctx.Done():
+				p.Info("Stopping operation queue processor.")
+				return
+			}
+		}
+	}
+}
+
 // startElection starts leader election process and watches the changes
 func (p *Process) startElection() error {
 	// elect gravity site leader - all other sites will remain
@@ -1469,6 +1556,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 
 	// site status checker executes status hook periodically
 	p.RegisterClusterService(p.runSiteStatusChecker)
+	// operation queue processor starts queued operations once the cluster is idle
+	p.RegisterClusterService(p.runOperationQueue(operator))
 
 	// a few services that are running only when gravity is started in
 	// local site mode
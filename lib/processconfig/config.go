@@ -24,6 +24,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	autoscaleaws "github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/webhook"
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/chunked"
+	blobfs "github.com/gravitational/gravity/lib/blob/fs"
+	blobs3 "github.com/gravitational/gravity/lib/blob/s3"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/helm"
@@ -141,6 +147,9 @@ type Config struct {
 	// ETCD provides etcd config options
 	ETCD keyval.ETCDConfig `yaml:"etcd"`
 
+	// SQL provides relational database config options
+	SQL keyval.SQLConfig `yaml:"sql"`
+
 	// OpsCenter provides settings for OpsCenter
 	OpsCenter OpsCenterConfig `yaml:"ops"`
 
@@ -169,6 +178,9 @@ type Config struct {
 
 	// InstallToken specifies the authentication token for the install operation
 	InstallToken string `yaml:"-"`
+
+	// Autoscale provides cluster autoscaling settings
+	Autoscale AutoscaleConfig `yaml:"autoscale"`
 }
 
 func (cfg *Config) CheckAndSetDefaults() error {
@@ -195,6 +207,10 @@ func (cfg *Config) CheckAndSetDefaults() error {
 		return trace.BadParameter("missing pack service advertise address")
 	}
 
+	if err := cfg.Pack.Storage.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	if cfg.HealthAddr.IsEmpty() {
 		cfg.HealthAddr = teleutils.NetAddr{
 			AddrNetwork: "tcp",
@@ -210,6 +226,10 @@ func (cfg *Config) CheckAndSetDefaults() error {
 			log.Errorf("error reading config: %#v", cfg.ETCD)
 			return trace.Wrap(err)
 		}
+	case constants.SQLBackend:
+		if err := cfg.SQL.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
 	default:
 		return trace.BadParameter("unsupported backend type: %v", cfg.BackendType)
 	}
@@ -223,6 +243,10 @@ func (cfg *Config) CheckAndSetDefaults() error {
 		return trace.Wrap(err)
 	}
 
+	if err := cfg.Autoscale.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	return nil
 }
 
@@ -237,10 +261,46 @@ func (cfg Config) CreateBackend() (backend storage.Backend, err error) {
 	case constants.ETCDBackend:
 		log.Debug("using ETCD backend")
 		backend, err = keyval.NewETCD(cfg.ETCD)
+	case constants.SQLBackend:
+		log.Debugf("using %v SQL backend", cfg.SQL.Driver)
+		backend, err = keyval.NewSQL(cfg.SQL)
 	}
 	return backend, trace.Wrap(err)
 }
 
+// CreateObjects creates the BLOB storage for package data
+func (cfg Config) CreateObjects() (objects blob.Objects, err error) {
+	dir := filepath.Join(cfg.DataDir, defaults.PackagesDir)
+	storage := cfg.Pack.Storage
+	switch storage.Type {
+	case constants.BLOBStorageFS:
+		log.Debug("using filesystem package storage")
+		objects, err = blobfs.New(dir)
+	case constants.BLOBStorageChunked:
+		log.Debug("using chunked package storage")
+		objects, err = chunked.New(chunked.Config{Path: dir})
+	case constants.BLOBStorageS3:
+		log.Debugf("using S3 package storage in bucket %v", storage.S3.Bucket)
+		objects, err = blobs3.New(blobs3.Config{
+			Bucket:          storage.S3.Bucket,
+			Prefix:          storage.S3.Prefix,
+			Region:          storage.S3.Region,
+			Endpoint:        storage.S3.Endpoint,
+			ForcePathStyle:  storage.S3.ForcePathStyle,
+			DisableSSL:      storage.S3.DisableSSL,
+			AccessKeyID:     storage.S3.AccessKeyID,
+			SecretAccessKey: storage.S3.SecretAccessKey,
+			PartSize:        storage.S3.PartSize,
+		})
+	default:
+		return nil, trace.BadParameter("unsupported package storage type %q", storage.Type)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects, nil
+}
+
 func (cfg Config) ProcessID() string {
 	id := os.Getenv(constants.EnvPodIP)
 	if id == "" {
@@ -317,6 +377,67 @@ type PackageServiceConfig struct {
 
 	// ReadDir is an optional directory with extra packages
 	ReadDir string `yaml:"read_dir"`
+
+	// Storage configures the BLOB storage for package data
+	Storage BLOBStorageConfig `yaml:"storage"`
+}
+
+// BLOBStorageConfig defines the BLOB storage for package data.
+//
+// The storage type should be selected when the cluster is created as
+// BLOBs written by one storage type are not visible to another
+type BLOBStorageConfig struct {
+	// Type is the storage type, one of fs (default), chunked or s3
+	Type string `yaml:"type"`
+	// S3 configures the S3-compatible storage
+	S3 S3StorageConfig `yaml:"s3"`
+}
+
+// S3StorageConfig configures BLOB storage in an S3-compatible bucket
+type S3StorageConfig struct {
+	// Bucket is the name of the bucket
+	Bucket string `yaml:"bucket"`
+	// Prefix is the optional key prefix for all objects
+	Prefix string `yaml:"prefix"`
+	// Region is the bucket region
+	Region string `yaml:"region"`
+	// Endpoint is the endpoint of the S3-compatible service, e.g. MinIO
+	Endpoint string `yaml:"endpoint"`
+	// ForcePathStyle turns on path-style addressing of objects
+	ForcePathStyle bool `yaml:"force_path_style"`
+	// DisableSSL turns off TLS for connections to the endpoint
+	DisableSSL bool `yaml:"disable_ssl"`
+	// AccessKeyID is the optional access key ID. If unspecified,
+	// credentials are looked up in the default locations
+	AccessKeyID string `yaml:"access_key_id"`
+	// SecretAccessKey is the secret access key
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// PartSize is the size of a part in multipart uploads,
+	// between 5MiB and 5GiB
+	PartSize int64 `yaml:"part_size"`
+}
+
+// CheckAndSetDefaults validates BLOB storage configuration
+func (c *BLOBStorageConfig) CheckAndSetDefaults() error {
+	switch c.Type {
+	case "":
+		c.Type = constants.BLOBStorageFS
+	case constants.BLOBStorageFS, constants.BLOBStorageChunked:
+	case constants.BLOBStorageS3:
+		if c.S3.Bucket == "" {
+			return trace.BadParameter("missing S3 bucket for package storage")
+		}
+	default:
+		return trace.BadParameter("unsupported package storage type %q, supported are: %v",
+			c.Type, []string{constants.BLOBStorageFS, constants.BLOBStorageChunked, constants.BLOBStorageS3})
+	}
+	return nil
+}
+
+// IsShared returns true if the storage is shared by all
+// package service instances and needs no replication
+func (c BLOBStorageConfig) IsShared() bool {
+	return c.Type == constants.BLOBStorageS3
 }
 
 // PeerAddr returns peer address of the package service instance
@@ -351,6 +472,48 @@ func (p *PackageServiceConfig) GetPublicAddr() teleutils.NetAddr {
 	return p.PublicAdvertiseAddr
 }
 
+// AutoscaleConfig defines cluster autoscaling configuration
+type AutoscaleConfig struct {
+	// Provider is the name of the autoscaling provider, either aws or webhook.
+	// If unspecified, the AWS provider is used when running on AWS
+	Provider string `yaml:"provider"`
+	// Webhook provides the webhook provider settings
+	Webhook WebhookAutoscaleConfig `yaml:"webhook"`
+}
+
+// WebhookAutoscaleConfig defines the webhook autoscaling provider configuration
+type WebhookAutoscaleConfig struct {
+	// ListenAddr is the address to accept autoscaling events on
+	ListenAddr string `yaml:"listen_addr"`
+	// DiscoveryURL is the base URL of the endpoint to publish
+	// cluster discovery information to
+	DiscoveryURL string `yaml:"discovery_url"`
+	// SecretFile is the path to the file with the shared secret
+	// used to authenticate requests
+	SecretFile string `yaml:"secret_file"`
+}
+
+// CheckAndSetDefaults validates autoscaling configuration and sets defaults
+func (c *AutoscaleConfig) CheckAndSetDefaults() error {
+	switch c.Provider {
+	case "", autoscaleaws.ProviderName:
+	case webhook.ProviderName:
+		if c.Webhook.DiscoveryURL == "" {
+			return trace.BadParameter("webhook autoscaling provider requires discovery_url")
+		}
+		if c.Webhook.SecretFile == "" {
+			return trace.BadParameter("webhook autoscaling provider requires secret_file")
+		}
+		if c.Webhook.ListenAddr == "" {
+			c.Webhook.ListenAddr = defaults.AutoscaleWebhookListenAddr
+		}
+	default:
+		return trace.BadParameter("unsupported autoscaling provider %q, supported are: %v",
+			c.Provider, []string{autoscaleaws.ProviderName, webhook.ProviderName})
+	}
+	return nil
+}
+
 // Charts defines Helm charts repository configuration.
 type ChartsConfig struct {
 	// Backend is the chart repository backend.
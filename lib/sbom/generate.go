@@ -0,0 +1,244 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/docker"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/docker/distribution"
+	"github.com/gravitational/trace"
+	"github.com/gravitational/version"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// Config defines the contents of the generated bill of materials
+type Config struct {
+	// Application is the locator of the image the bill of materials is for
+	Application loc.Locator
+	// Dir is the vendor directory with image resources and
+	// the registry with vendored container images
+	Dir string
+	// Dependencies lists gravity packages and applications the image depends on
+	Dependencies app.Dependencies
+	// Packages is the package service with the dependencies
+	Packages pack.PackageService
+	// Clock is used to timestamp the bill of materials
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets default values
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Dir == "" {
+		return trace.BadParameter("missing Dir")
+	}
+	if c.Packages == nil {
+		return trace.BadParameter("missing Packages")
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "sbom")
+	}
+	return nil
+}
+
+// Generate generates the bill of materials for the image with the specified
+// configuration
+func Generate(ctx context.Context, config Config) (*BOM, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	images, err := imageComponents(ctx, filepath.Join(config.Dir, defaults.RegistryDir), config.FieldLogger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	charts, err := chartComponents(filepath.Join(config.Dir, defaults.ResourcesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	packages, err := packageComponents(config.Dependencies.Packages, KindPackage, config.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	apps, err := packageComponents(config.Dependencies.Apps, KindApplication, config.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bom := &BOM{
+		BOMFormat:   FormatCycloneDX,
+		SpecVersion: SpecVersion,
+		Version:     1,
+		Metadata: Metadata{
+			Timestamp: config.Clock.Now().UTC(),
+			Tools: []Tool{{
+				Vendor:  "Gravitational",
+				Name:    "tele",
+				Version: version.Get().Version,
+			}},
+			Component: &Component{
+				Type:       "application",
+				Name:       config.Application.Name,
+				Version:    config.Application.Version,
+				Properties: []Property{{Name: PropertyLocator, Value: config.Application.String()}},
+			},
+		},
+	}
+	for _, components := range [][]Component{images, charts, packages, apps} {
+		bom.Components = append(bom.Components, components...)
+	}
+	return bom, nil
+}
+
+// imageComponents lists container images from the registry directory along
+// with OS packages installed in them
+func imageComponents(ctx context.Context, dir string, logger logrus.FieldLogger) (result []Component, err error) {
+	if ok, _ := utils.IsDirectory(dir); !ok {
+		return nil, nil
+	}
+	registry, err := docker.OpenLocalRegistry(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	images, err := registry.Images(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, image := range images {
+		logger.WithField("image", fmt.Sprintf("%v:%v", image.Repository, image.Tag)).
+			Info("Scanning image for OS packages.")
+		scanner := newOSPackageScanner()
+		for _, layer := range image.Layers {
+			err := scanLayer(ctx, registry, image.Repository, layer, scanner)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+		result = append(result, Component{
+			Type:    "container",
+			Name:    image.Repository,
+			Version: image.Tag,
+			PackageURL: fmt.Sprintf("pkg:docker/%v@%v?tag=%v", image.Repository,
+				url.QueryEscape(image.Digest.String()), url.QueryEscape(image.Tag)),
+			Hashes: []Hash{{
+				Algorithm: "SHA-256",
+				Content:   image.Digest.Hex(),
+			}},
+			Properties: []Property{{Name: PropertyKind, Value: KindImage}},
+			Components: scanner.components(),
+		})
+	}
+	return result, nil
+}
+
+func scanLayer(ctx context.Context, registry *docker.LocalRegistry, repository string, layer distribution.Descriptor, scanner *osPackageScanner) error {
+	rc, err := registry.OpenLayer(ctx, repository, layer)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer rc.Close()
+	return trace.Wrap(scanner.scanLayer(rc))
+}
+
+// chartComponents lists Helm charts found in the resources directory
+func chartComponents(dir string) (result []Component, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() || fi.Name() != chartutil.ChartfileName {
+			return nil
+		}
+		chart, err := chartutil.LoadChartfile(path)
+		if err != nil {
+			return trace.Wrap(err, "failed to load %v", path)
+		}
+		relpath, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		result = append(result, Component{
+			Type:    "application",
+			Name:    chart.Name,
+			Version: chart.Version,
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindChart},
+				{Name: PropertyPath, Value: relpath},
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Property(PropertyPath) < result[j].Property(PropertyPath)
+	})
+	return result, nil
+}
+
+// packageComponents lists the specified gravity packages with their checksums
+func packageComponents(locators []loc.Locator, kind string, packages pack.PackageService) (result []Component, err error) {
+	for _, locator := range locators {
+		componentType := "file"
+		if kind == KindApplication {
+			componentType = "application"
+		}
+		component := Component{
+			Type:    componentType,
+			Name:    fmt.Sprintf("%v/%v", locator.Repository, locator.Name),
+			Version: locator.Version,
+			Properties: []Property{
+				{Name: PropertyKind, Value: kind},
+				{Name: PropertyLocator, Value: locator.String()},
+			},
+		}
+		envelope, err := packages.ReadPackageEnvelope(locator)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		// Dependencies of application images are not pulled during
+		// the build so they are listed without checksums
+		if envelope != nil {
+			component.Hashes = []Hash{{
+				Algorithm: "SHA-512",
+				Content:   envelope.SHA512,
+			}}
+		}
+		result = append(result, component)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
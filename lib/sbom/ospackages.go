@@ -0,0 +1,261 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// osPackageScanner collects OS packages installed in an image by
+// inspecting package manager databases in the image layers
+type osPackageScanner struct {
+	// releases maps os-release file path to its contents
+	releases map[string]osRelease
+	// dpkg lists packages from the dpkg status database
+	dpkg []osPackage
+	// dpkgDistroless maps status.d file path to the package it describes
+	dpkgDistroless map[string][]osPackage
+	// apk lists packages from the apk database
+	apk []osPackage
+}
+
+func newOSPackageScanner() *osPackageScanner {
+	return &osPackageScanner{
+		releases:       make(map[string]osRelease),
+		dpkgDistroless: make(map[string][]osPackage),
+	}
+}
+
+// scanLayer inspects the uncompressed layer tarball read from r.
+// Layers are expected to be scanned from the base layer up so files in the
+// upper layers replace the ones from the lower layers
+func (s *osPackageScanner) scanLayer(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		path := strings.TrimPrefix(filepath.Clean("/"+header.Name), "/")
+		switch {
+		case path == dpkgStatusFile:
+			s.dpkg, err = parseDpkgStatus(tr)
+		case filepath.Dir(path) == dpkgStatusDir:
+			s.dpkgDistroless[path], err = parseDpkgStatus(tr)
+		case path == apkInstalledFile:
+			s.apk, err = parseAPKInstalled(tr)
+		case path == osReleaseFile || path == osReleaseFallbackFile:
+			s.releases[path], err = parseOSRelease(tr)
+		}
+		if err != nil {
+			return trace.Wrap(err, "failed to parse %v", path)
+		}
+	}
+}
+
+// components returns the collected OS packages sorted by name
+func (s *osPackageScanner) components() (result []Component) {
+	release, ok := s.releases[osReleaseFile]
+	if !ok {
+		release = s.releases[osReleaseFallbackFile]
+	}
+	packages := append([]osPackage(nil), s.dpkg...)
+	for _, distroless := range s.dpkgDistroless {
+		packages = append(packages, distroless...)
+	}
+	packages = append(packages, s.apk...)
+	for _, pkg := range packages {
+		result = append(result, Component{
+			Type:       "library",
+			Name:       pkg.name,
+			Version:    pkg.version,
+			PackageURL: pkg.packageURL(release),
+			Properties: []Property{{Name: PropertyKind, Value: KindOSPackage}},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// osPackage describes a single OS package
+type osPackage struct {
+	// packageType is the package URL type, e.g. deb or apk
+	packageType string
+	name        string
+	version     string
+	arch        string
+}
+
+// packageURL formats the package URL of this package for the specified
+// distribution, e.g. pkg:deb/debian/curl@7.64.0-4?arch=amd64&distro=debian-10
+func (p osPackage) packageURL(release osRelease) string {
+	namespace := release.id
+	if namespace == "" {
+		namespace = defaultNamespaces[p.packageType]
+	}
+	qualifiers := url.Values{}
+	if p.arch != "" {
+		qualifiers.Set("arch", p.arch)
+	}
+	if release.id != "" && release.versionID != "" {
+		qualifiers.Set("distro", fmt.Sprintf("%v-%v", release.id, release.versionID))
+	}
+	purl := fmt.Sprintf("pkg:%v/%v/%v@%v", p.packageType, namespace,
+		url.PathEscape(p.name), url.QueryEscape(p.version))
+	if len(qualifiers) != 0 {
+		purl = fmt.Sprintf("%v?%v", purl, qualifiers.Encode())
+	}
+	return purl
+}
+
+// osRelease describes the distribution of an image
+type osRelease struct {
+	// id is the distribution identifier, e.g. debian
+	id string
+	// versionID is the distribution version, e.g. 10
+	versionID string
+}
+
+// parseDpkgStatus returns installed packages from the dpkg status database.
+// The same format is used by the per-package files in status.d directory
+// of distroless images which have no Status field
+func parseDpkgStatus(r io.Reader) (packages []osPackage, err error) {
+	err = parseStanzas(r, ":", func(fields map[string]string) {
+		status := fields["Status"]
+		if fields["Package"] == "" || (status != "" && !strings.HasSuffix(status, " installed")) {
+			return
+		}
+		packages = append(packages, osPackage{
+			packageType: "deb",
+			name:        fields["Package"],
+			version:     fields["Version"],
+			arch:        fields["Architecture"],
+		})
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return packages, nil
+}
+
+// parseAPKInstalled returns installed packages from the apk database
+func parseAPKInstalled(r io.Reader) (packages []osPackage, err error) {
+	err = parseStanzas(r, ":", func(fields map[string]string) {
+		if fields["P"] == "" {
+			return
+		}
+		packages = append(packages, osPackage{
+			packageType: "apk",
+			name:        fields["P"],
+			version:     fields["V"],
+			arch:        fields["A"],
+		})
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return packages, nil
+}
+
+// parseOSRelease parses the os-release file
+func parseOSRelease(r io.Reader) (release osRelease, err error) {
+	err = parseStanzas(r, "=", func(fields map[string]string) {
+		release.id = strings.Trim(fields["ID"], `"'`)
+		release.versionID = strings.Trim(fields["VERSION_ID"], `"'`)
+	})
+	if err != nil {
+		return release, trace.Wrap(err)
+	}
+	return release, nil
+}
+
+// parseStanzas parses blocks of key/value pairs separated with empty lines
+// and invokes fn for each block. Continuation lines are ignored
+func parseStanzas(r io.Reader, separator string, fn func(map[string]string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	fields := make(map[string]string)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(fields) != 0 {
+				fn(fields)
+				fields = make(map[string]string)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		parts := strings.SplitN(line, separator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return trace.Wrap(err)
+	}
+	if len(fields) != 0 {
+		fn(fields)
+	}
+	return nil
+}
+
+const (
+	// dpkgStatusFile is the dpkg database of installed packages
+	dpkgStatusFile = "var/lib/dpkg/status"
+	// dpkgStatusDir is the directory with per-package dpkg status files
+	// used by distroless images
+	dpkgStatusDir = "var/lib/dpkg/status.d"
+	// apkInstalledFile is the apk database of installed packages
+	apkInstalledFile = "lib/apk/db/installed"
+	// osReleaseFile is the distribution information file
+	osReleaseFile = "etc/os-release"
+	// osReleaseFallbackFile is the fallback location of the distribution
+	// information file
+	osReleaseFallbackFile = "usr/lib/os-release"
+	// maxLineSize is the maximum size of a line in package databases
+	maxLineSize = 1024 * 1024
+)
+
+// defaultNamespaces maps package URL type to the namespace used when the
+// image distribution is unknown
+var defaultNamespaces = map[string]string{
+	"deb": "debian",
+	"apk": "alpine",
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package sbom implements the software bill of materials of cluster and
+application images.
+
+The bill of materials is a CycloneDX JSON document that lists the container
+images vendored into the image along with the OS packages found in their
+layers, the Helm charts, and the gravity packages and applications the image
+depends on.
+*/
+package sbom
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+)
+
+// BOM is the software bill of materials in CycloneDX format
+type BOM struct {
+	// BOMFormat is always "CycloneDX"
+	BOMFormat string `json:"bomFormat"`
+	// SpecVersion is the version of the CycloneDX specification
+	SpecVersion string `json:"specVersion"`
+	// Version is the version of this bill of materials
+	Version int `json:"version"`
+	// Metadata describes the image the bill of materials belongs to
+	Metadata Metadata `json:"metadata"`
+	// Components lists the image contents
+	Components []Component `json:"components,omitempty"`
+}
+
+// Metadata describes the bill of materials
+type Metadata struct {
+	// Timestamp is the time the bill of materials was generated
+	Timestamp time.Time `json:"timestamp"`
+	// Tools lists the tools used to generate the bill of materials
+	Tools []Tool `json:"tools,omitempty"`
+	// Component describes the image the bill of materials belongs to
+	Component *Component `json:"component,omitempty"`
+}
+
+// Tool describes a tool used to generate the bill of materials
+type Tool struct {
+	// Vendor is the tool vendor
+	Vendor string `json:"vendor,omitempty"`
+	// Name is the tool name
+	Name string `json:"name"`
+	// Version is the tool version
+	Version string `json:"version,omitempty"`
+}
+
+// Component describes a single item in the bill of materials
+type Component struct {
+	// Type is the CycloneDX component type
+	Type string `json:"type"`
+	// Name is the component name
+	Name string `json:"name"`
+	// Version is the component version
+	Version string `json:"version,omitempty"`
+	// PackageURL is the package URL of the component
+	PackageURL string `json:"purl,omitempty"`
+	// Hashes lists the component checksums
+	Hashes []Hash `json:"hashes,omitempty"`
+	// Properties lists gravity-specific component attributes
+	Properties []Property `json:"properties,omitempty"`
+	// Components lists nested components, e.g. OS packages of an image
+	Components []Component `json:"components,omitempty"`
+}
+
+// Kind returns the kind of the gravity artifact this component describes
+func (c Component) Kind() string {
+	return c.Property(PropertyKind)
+}
+
+// Property returns the value of the property with the specified name
+func (c Component) Property(name string) string {
+	for _, property := range c.Properties {
+		if property.Name == name {
+			return property.Value
+		}
+	}
+	return ""
+}
+
+// Hash is a component checksum
+type Hash struct {
+	// Algorithm is the hash algorithm, e.g. SHA-256
+	Algorithm string `json:"alg"`
+	// Content is the hex-encoded checksum
+	Content string `json:"content"`
+}
+
+// Property is a name/value component attribute
+type Property struct {
+	// Name is the property name
+	Name string `json:"name"`
+	// Value is the property value
+	Value string `json:"value"`
+}
+
+// Read decodes the bill of materials from r
+func Read(r io.Reader) (*BOM, error) {
+	var bom BOM
+	if err := json.NewDecoder(r).Decode(&bom); err != nil {
+		return nil, trace.Wrap(err, "failed to decode bill of materials")
+	}
+	if bom.BOMFormat != FormatCycloneDX {
+		return nil, trace.BadParameter("unsupported bill of materials format %q", bom.BOMFormat)
+	}
+	return &bom, nil
+}
+
+// FromTarball reads the bill of materials from the specified (optionally
+// compressed) tarball which can be either an installer tarball or
+// an application package.
+//
+// tele build places the bill of materials at the root of the application
+// package and in front of all other installer items so it is usually found
+// without reading the entire tarball, but all entries are scanned for
+// tarballs that have been repacked by other tools
+func FromTarball(r io.Reader) (*BOM, error) {
+	decompressed, err := dockerarchive.DecompressStream(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if header.FileInfo().IsDir() || filepath.Clean(header.Name) != defaults.SBOMFileName {
+			continue
+		}
+		return Read(tr)
+	}
+	return nil, trace.NotFound("no bill of materials found, make sure " +
+		"the image was built with a tele version that supports it")
+}
+
+// Marshal returns the JSON representation of the bill of materials
+func (b BOM) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// Write outputs the bill of materials to w in the specified format.
+// Text format is a human-readable summary while JSON format is the complete
+// CycloneDX document
+func (b BOM) Write(w io.Writer, format constants.Format) error {
+	switch format {
+	case constants.EncodingText:
+		return trace.Wrap(b.writeText(w))
+	case constants.EncodingJSON:
+		data, err := b.Marshal()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return trace.Wrap(err)
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v, %v",
+			format, constants.EncodingText, constants.EncodingJSON)
+	}
+}
+
+func (b BOM) writeText(w io.Writer) error {
+	if b.Metadata.Component != nil {
+		fmt.Fprintf(w, "Image: %v:%v\n", b.Metadata.Component.Name, b.Metadata.Component.Version)
+	}
+	fmt.Fprintf(w, "Generated (UTC): %v\n\n", b.Metadata.Timestamp.UTC().Format(constants.ShortDateFormat))
+	t := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(t, "Kind\tName\tVersion\tChecksum\n")
+	fmt.Fprintf(t, "----\t----\t-------\t--------\n")
+	for _, c := range b.Components {
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", c.Kind(), c.Name, c.Version, formatHash(c.Hashes))
+		for _, p := range c.Components {
+			fmt.Fprintf(t, "%v\t  %v\t%v\t\n", p.Kind(), p.Name, p.Version)
+		}
+	}
+	return trace.Wrap(t.Flush())
+}
+
+func formatHash(hashes []Hash) string {
+	if len(hashes) == 0 {
+		return ""
+	}
+	content := hashes[0].Content
+	if len(content) > 12 {
+		content = content[:12]
+	}
+	return fmt.Sprintf("%v:%v", hashes[0].Algorithm, content)
+}
+
+const (
+	// FormatCycloneDX is the format of the bill of materials
+	FormatCycloneDX = "CycloneDX"
+	// SpecVersion is the implemented version of CycloneDX specification
+	SpecVersion = "1.4"
+
+	// PropertyKind names the property with the kind of gravity artifact
+	// a component describes
+	PropertyKind = "gravity:kind"
+	// PropertyLocator names the property with the gravity package locator
+	PropertyLocator = "gravity:locator"
+	// PropertyPath names the property with the path of the component
+	// inside the image resources
+	PropertyPath = "gravity:path"
+
+	// KindImage is a vendored container image
+	KindImage = "image"
+	// KindOSPackage is an OS package installed in a container image
+	KindOSPackage = "os-package"
+	// KindChart is a Helm chart
+	KindChart = "chart"
+	// KindPackage is a gravity package dependency
+	KindPackage = "package"
+	// KindApplication is a gravity application dependency
+	KindApplication = "application"
+)
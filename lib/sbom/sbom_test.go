@@ -0,0 +1,207 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"gopkg.in/check.v1"
+)
+
+func TestSBOM(t *testing.T) { check.TestingT(t) }
+
+type SBOMSuite struct{}
+
+var _ = check.Suite(&SBOMSuite{})
+
+func (s *SBOMSuite) TestScansOSPackages(c *check.C) {
+	scanner := newOSPackageScanner()
+	base := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString("etc/os-release", "ID=debian\nVERSION_ID=\"9\"\n"),
+		archive.ItemFromString("var/lib/dpkg/status", `Package: libc6
+Status: install ok installed
+Version: 2.24-11
+Architecture: amd64
+Description: GNU C Library
+ continuation line
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+`),
+	})
+	c.Assert(scanner.scanLayer(base), check.IsNil)
+	upper := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString("./etc/os-release", "ID=debian\nVERSION_ID=\"10\"\n"),
+		archive.ItemFromString("var/lib/dpkg/status", `Package: libc6
+Status: install ok installed
+Version: 1:2.28-10
+Architecture: amd64
+`),
+		archive.ItemFromString("var/lib/dpkg/status.d/tzdata", "Package: tzdata\nVersion: 2020a-0\nArchitecture: all\n"),
+	})
+	c.Assert(scanner.scanLayer(upper), check.IsNil)
+	c.Assert(scanner.components(), check.DeepEquals, []Component{
+		{
+			Type:       "library",
+			Name:       "libc6",
+			Version:    "1:2.28-10",
+			PackageURL: "pkg:deb/debian/libc6@1%3A2.28-10?arch=amd64&distro=debian-10",
+			Properties: []Property{{Name: PropertyKind, Value: KindOSPackage}},
+		},
+		{
+			Type:       "library",
+			Name:       "tzdata",
+			Version:    "2020a-0",
+			PackageURL: "pkg:deb/debian/tzdata@2020a-0?arch=all&distro=debian-10",
+			Properties: []Property{{Name: PropertyKind, Value: KindOSPackage}},
+		},
+	})
+}
+
+func (s *SBOMSuite) TestScansAlpinePackages(c *check.C) {
+	scanner := newOSPackageScanner()
+	layer := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString("lib/apk/db/installed", `C:Q1abc=
+P:musl
+V:1.1.24-r2
+A:x86_64
+
+P:busybox
+V:1.31.1-r9
+A:x86_64
+`),
+	})
+	c.Assert(scanner.scanLayer(layer), check.IsNil)
+	components := scanner.components()
+	c.Assert(components, check.HasLen, 2)
+	c.Assert(components[0].PackageURL, check.Equals, "pkg:apk/alpine/busybox@1.31.1-r9?arch=x86_64")
+	c.Assert(components[1].Name, check.Equals, "musl")
+}
+
+func (s *SBOMSuite) TestGeneratesBOM(c *check.C) {
+	dir := c.MkDir()
+	chartDir := filepath.Join(dir, defaults.ResourcesDir, "charts", "app")
+	c.Assert(os.MkdirAll(chartDir, defaults.SharedDirMask), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(chartDir, "Chart.yaml"),
+		[]byte("name: app\nversion: 0.1.0\n"), defaults.SharedReadMask), check.IsNil)
+
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, check.IsNil)
+	defer backend.Close()
+	packagesDir := c.MkDir()
+	objects, err := fs.New(packagesDir)
+	c.Assert(err, check.IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		Objects:     objects,
+		UnpackedDir: filepath.Join(packagesDir, defaults.UnpackedDir),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(packages.UpsertRepository(defaults.SystemAccountOrg, time.Time{}), check.IsNil)
+	planet := loc.MustParseLocator("gravitational.io/planet:0.0.1")
+	envelope, err := packages.CreatePackage(planet, bytes.NewBufferString("planet"))
+	c.Assert(err, check.IsNil)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	bom, err := Generate(context.TODO(), Config{
+		Application: loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		Dir:         dir,
+		Dependencies: app.Dependencies{
+			Packages: []loc.Locator{planet},
+			Apps:     []loc.Locator{loc.MustParseLocator("gravitational.io/dns-app:0.0.1")},
+		},
+		Packages: packages,
+		Clock:    clock,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(bom.Metadata.Timestamp, check.Equals, clock.Now())
+	c.Assert(bom.Components, check.DeepEquals, []Component{
+		{
+			Type:    "application",
+			Name:    "app",
+			Version: "0.1.0",
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindChart},
+				{Name: PropertyPath, Value: "charts/app"},
+			},
+		},
+		{
+			Type:    "file",
+			Name:    "gravitational.io/planet",
+			Version: "0.0.1",
+			Hashes:  []Hash{{Algorithm: "SHA-512", Content: envelope.SHA512}},
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindPackage},
+				{Name: PropertyLocator, Value: planet.String()},
+			},
+		},
+		{
+			Type:    "application",
+			Name:    "gravitational.io/dns-app",
+			Version: "0.0.1",
+			Properties: []Property{
+				{Name: PropertyKind, Value: KindApplication},
+				{Name: PropertyLocator, Value: "gravitational.io/dns-app:0.0.1"},
+			},
+		},
+	})
+}
+
+func (s *SBOMSuite) TestReadsBOMFromTarball(c *check.C) {
+	bom := BOM{BOMFormat: FormatCycloneDX, SpecVersion: SpecVersion, Version: 1}
+	data, err := bom.Marshal()
+	c.Assert(err, check.IsNil)
+
+	tarball := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString(defaults.SBOMFileName, string(data)),
+		archive.ItemFromString(defaults.ManifestFileName, "kind: Cluster"),
+	})
+	read, err := FromTarball(tarball)
+	c.Assert(err, check.IsNil)
+	c.Assert(*read, check.DeepEquals, bom)
+
+	tarball = archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString(defaults.ManifestFileName, "kind: Cluster"),
+		archive.ItemFromString(defaults.SBOMFileName, string(data)),
+	})
+	read, err = FromTarball(tarball)
+	c.Assert(err, check.IsNil)
+	c.Assert(*read, check.DeepEquals, bom)
+
+	tarball = archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString(defaults.ManifestFileName, "kind: Cluster"),
+	})
+	_, err = FromTarball(tarball)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
@@ -81,3 +81,38 @@ type volumesByPath []Volume
 func (r volumesByPath) Len() int           { return len(r) }
 func (r volumesByPath) Less(i, j int) bool { return r[i].Path < r[j].Path }
 func (r volumesByPath) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// DiffHooks returns hooks that are only defined in new (added), only
+// defined in old (removed) or are defined in both but have different
+// job specifications (changed).
+func DiffHooks(old, new Manifest) (added, removed, changed []HookType) {
+	for _, hookType := range AllHooks() {
+		oldHook, _ := HookFromString(hookType, old)
+		newHook, _ := HookFromString(hookType, new)
+		switch {
+		case oldHook == nil && newHook != nil:
+			added = append(added, hookType)
+		case oldHook != nil && newHook == nil:
+			removed = append(removed, hookType)
+		case oldHook != nil && newHook != nil && oldHook.Job != newHook.Job:
+			changed = append(changed, hookType)
+		}
+	}
+	return added, removed, changed
+}
+
+// DiffNodeProfiles returns names of node profiles that are only present
+// in new (added) or only present in old (removed).
+func DiffNodeProfiles(old, new Manifest) (added, removed []string) {
+	for _, profile := range new.NodeProfiles {
+		if _, err := old.NodeProfiles.ByName(profile.Name); err != nil {
+			added = append(added, profile.Name)
+		}
+	}
+	for _, profile := range old.NodeProfiles {
+		if _, err := new.NodeProfiles.ByName(profile.Name); err != nil {
+			removed = append(removed, profile.Name)
+		}
+	}
+	return added, removed
+}
@@ -136,3 +136,28 @@ func newArray(from, to int) (result []int) {
 	}
 	return result
 }
+
+func (_ *DiffSuite) TestDiffsHooks(c *C) {
+	old := Manifest{Hooks: &Hooks{
+		Install:  &Hook{Type: HookInstall, Job: "install-v1"},
+		Updating: &Hook{Type: HookUpdate, Job: "update"},
+		Status:   &Hook{Type: HookStatus, Job: "status"},
+	}}
+	new := Manifest{Hooks: &Hooks{
+		Install:  &Hook{Type: HookInstall, Job: "install-v2"},
+		Updating: &Hook{Type: HookUpdate, Job: "update"},
+		Backup:   &Hook{Type: HookBackup, Job: "backup"},
+	}}
+	added, removed, changed := DiffHooks(old, new)
+	c.Assert(added, DeepEquals, []HookType{HookBackup})
+	c.Assert(removed, DeepEquals, []HookType{HookStatus})
+	c.Assert(changed, DeepEquals, []HookType{HookInstall})
+}
+
+func (_ *DiffSuite) TestDiffsNodeProfiles(c *C) {
+	old := Manifest{NodeProfiles: NodeProfiles{{Name: "master"}, {Name: "db"}}}
+	new := Manifest{NodeProfiles: NodeProfiles{{Name: "master"}, {Name: "worker"}}}
+	added, removed := DiffNodeProfiles(old, new)
+	c.Assert(added, DeepEquals, []string{"worker"})
+	c.Assert(removed, DeepEquals, []string{"db"})
+}
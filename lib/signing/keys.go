@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ed25519"
+)
+
+// GenerateKey generates a new Ed25519 signing key.
+// Returns the PEM-encoded private and public keys
+func GenerateKey() (keyPEM, publicKeyPEM []byte, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	keyPEM, err = marshalPrivateKey(private)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	publicKeyPEM, err = MarshalPublicKey(public)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return keyPEM, publicKeyPEM, nil
+}
+
+// MarshalPublicKey returns the PEM-encoded Ed25519 public key
+func MarshalPublicKey(key ed25519.PublicKey) ([]byte, error) {
+	der, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+		PublicKey: asn1.BitString{Bytes: key, BitLength: 8 * len(key)},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: der}), nil
+}
+
+// ParsePublicKey parses the PEM-encoded Ed25519 public key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != publicKeyType {
+		return nil, trace.BadParameter("expected PEM-encoded public key")
+	}
+	var info publicKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, trace.BadParameter("failed to parse public key: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidEd25519) {
+		return nil, trace.BadParameter("only Ed25519 public keys are supported")
+	}
+	if len(info.PublicKey.Bytes) != ed25519.PublicKeySize {
+		return nil, trace.BadParameter("invalid Ed25519 public key size %v", len(info.PublicKey.Bytes))
+	}
+	return ed25519.PublicKey(info.PublicKey.Bytes), nil
+}
+
+// parsePrivateKey parses the PEM-encoded Ed25519, RSA or ECDSA private key
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, trace.BadParameter("expected PEM-encoded private key")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, trace.BadParameter("failed to parse private key: %v", err)
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, trace.BadParameter("failed to parse private key: %v", err)
+		}
+		return key, nil
+	case privateKeyType:
+		var info privateKeyInfo
+		if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+			return nil, trace.BadParameter("failed to parse private key: %v", err)
+		}
+		if !info.Algorithm.Algorithm.Equal(oidEd25519) {
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, trace.BadParameter("failed to parse private key: %v", err)
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, trace.BadParameter("unsupported private key type %T", key)
+			}
+			return signer, nil
+		}
+		var seed []byte
+		if _, err := asn1.Unmarshal(info.PrivateKey, &seed); err != nil {
+			return nil, trace.BadParameter("failed to parse Ed25519 private key: %v", err)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, trace.BadParameter("invalid Ed25519 private key size %v", len(seed))
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	return nil, trace.BadParameter("unsupported private key type %q", block.Type)
+}
+
+func marshalPrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	seed, err := asn1.Marshal(key.Seed())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	der, err := asn1.Marshal(privateKeyInfo{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+		PrivateKey: seed,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Bytes: der}), nil
+}
+
+// keyID returns the identifier of the specified Ed25519 public key
+func keyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// certificateID returns the identifier of the specified certificate
+func certificateID(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// privateKeyInfo is the PKCS #8 private key structure
+type privateKeyInfo struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// publicKeyInfo is the PKIX public key structure
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// oidEd25519 is the Ed25519 algorithm identifier as defined in RFC 8410
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+const (
+	privateKeyType = "PRIVATE KEY"
+	publicKeyType  = "PUBLIC KEY"
+)
@@ -0,0 +1,317 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// package signing implements signing of packages by their producers
+// and verification of package signatures against a cluster trust policy.
+//
+// A signature covers the package locator, the checksum of the package
+// contents and, for application packages, the checksum of the application
+// manifest. Packages are signed either with an Ed25519 key or with an RSA or
+// ECDSA key accompanied by the x509 certificate chain of the signer.
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ed25519"
+)
+
+// NewSigner returns a new package signer for the PEM-encoded private key.
+// certPEM is the PEM-encoded certificate chain of the signer starting with the
+// certificate of the key and is required for RSA and ECDSA keys
+func NewSigner(keyPEM, certPEM []byte) (*Signer, error) {
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if private, ok := key.(ed25519.PrivateKey); ok {
+		if len(certPEM) != 0 {
+			return nil, trace.BadParameter("certificates are not supported for Ed25519 keys")
+		}
+		return &Signer{
+			key:     key,
+			sigType: SignatureEd25519,
+			keyID:   keyID(private.Public().(ed25519.PublicKey)),
+		}, nil
+	}
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(certs) == 0 {
+		return nil, trace.BadParameter("certificate is required for %T keys", key)
+	}
+	if !publicKeysEqual(certs[0].PublicKey, key.Public()) {
+		return nil, trace.BadParameter("certificate does not match the signing key")
+	}
+	return &Signer{
+		key:          key,
+		sigType:      SignatureX509,
+		keyID:        certificateID(certs[0]),
+		certificates: certPEM,
+	}, nil
+}
+
+// NewSignerFromFiles returns a new package signer for the private key
+// and the optional certificate chain read from the specified files
+func NewSignerFromFiles(keyPath, certPath string) (*Signer, error) {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var certPEM []byte
+	if certPath != "" {
+		certPEM, err = ioutil.ReadFile(certPath)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	return NewSigner(keyPEM, certPEM)
+}
+
+// Signer signs packages with the key of the package producer
+type Signer struct {
+	key          crypto.Signer
+	sigType      string
+	keyID        string
+	certificates []byte
+}
+
+// SignPackage returns the signature of the package described with envelope.
+// The envelope checksum is expected to be the checksum of unencrypted package contents
+func (s *Signer) SignPackage(envelope pack.PackageEnvelope) (*storage.PackageSignature, error) {
+	message := payload(envelope.Locator, envelope.SHA512, envelope.Manifest)
+	var value []byte
+	var err error
+	switch s.sigType {
+	case SignatureEd25519:
+		value, err = s.key.Sign(rand.Reader, message, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(message)
+		value, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &storage.PackageSignature{
+		Type:         s.sigType,
+		KeyID:        s.keyID,
+		Certificates: s.certificates,
+		Value:        value,
+	}, nil
+}
+
+// KeyID returns the identifier of the signing key
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// NewVerifier returns a new verifier for package signatures
+// trusted by the specified policy
+func NewVerifier(policy storage.TrustPolicy) (*Verifier, error) {
+	verifier := &Verifier{
+		keys:  make(map[string]trustedKey),
+		roots: x509.NewCertPool(),
+	}
+	for _, key := range policy.GetKeys() {
+		publicKey, err := ParsePublicKey([]byte(key.PublicKey))
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid trusted key %v", key.Name)
+		}
+		verifier.keys[keyID(publicKey)] = trustedKey{name: key.Name, key: publicKey}
+	}
+	for _, ca := range policy.GetCertificateAuthorities() {
+		if !verifier.roots.AppendCertsFromPEM([]byte(ca)) {
+			return nil, trace.BadParameter("failed to parse certificate authority")
+		}
+	}
+	verifier.hasRoots = len(policy.GetCertificateAuthorities()) != 0
+	return verifier, nil
+}
+
+// Verifier verifies package signatures against the trusted keys
+// and certificate authorities
+type Verifier struct {
+	keys     map[string]trustedKey
+	roots    *x509.CertPool
+	hasRoots bool
+}
+
+// VerifyPackage verifies that the specified package has been signed with
+// a trusted key. The checksum is computed from the package contents
+// so the package envelope is not trusted.
+// Returns AccessDenied if the package has no trusted signature
+func (v *Verifier) VerifyPackage(packages pack.PackageService, locator loc.Locator) error {
+	envelope, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	if len(envelope.Signatures) == 0 {
+		return trace.AccessDenied("package %v is not signed", locator)
+	}
+	sha512sum, err := checksum(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	message := payload(envelope.Locator, sha512sum, envelope.Manifest)
+	var errors []error
+	for _, signature := range envelope.Signatures {
+		err := v.verify(signature, message)
+		if err == nil {
+			return nil
+		}
+		errors = append(errors, err)
+	}
+	return trace.AccessDenied("package %v is not signed by a trusted key: %v",
+		locator, trace.NewAggregate(errors...))
+}
+
+func (v *Verifier) verify(signature storage.PackageSignature, message []byte) error {
+	switch signature.Type {
+	case SignatureEd25519:
+		key, ok := v.keys[signature.KeyID]
+		if !ok {
+			return trace.NotFound("key %v is not trusted", signature.KeyID)
+		}
+		if !ed25519.Verify(key.key, message, signature.Value) {
+			return trace.BadParameter("invalid signature by key %v", key.name)
+		}
+		return nil
+	case SignatureX509:
+		if !v.hasRoots {
+			return trace.NotFound("no trusted certificate authorities")
+		}
+		certs, err := parseCertificates(signature.Certificates)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if len(certs) == 0 {
+			return trace.BadParameter("signature has no certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		leaf := certs[0]
+		_, err = leaf.Verify(x509.VerifyOptions{
+			Roots:         v.roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return trace.BadParameter("certificate of %v is not trusted: %v",
+				leaf.Subject.CommonName, err)
+		}
+		var algorithm x509.SignatureAlgorithm
+		switch leaf.PublicKeyAlgorithm {
+		case x509.RSA:
+			algorithm = x509.SHA256WithRSA
+		case x509.ECDSA:
+			algorithm = x509.ECDSAWithSHA256
+		default:
+			return trace.BadParameter("unsupported certificate key algorithm %v", leaf.PublicKeyAlgorithm)
+		}
+		if err := leaf.CheckSignature(algorithm, message, signature.Value); err != nil {
+			return trace.BadParameter("invalid signature by %v: %v", leaf.Subject.CommonName, err)
+		}
+		return nil
+	}
+	return trace.BadParameter("unsupported signature type %q", signature.Type)
+}
+
+// payload returns the message signed for the package
+func payload(locator loc.Locator, sha512sum string, manifest []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v\n%v\n%v\n", payloadVersion, locator, sha512sum)
+	if len(manifest) != 0 {
+		manifestSum := utils.MustSHA512Half(manifest)
+		fmt.Fprintf(&buf, "%v\n", manifestSum)
+	}
+	return buf.Bytes()
+}
+
+// checksum returns the checksum of the package contents read from r
+// in the format of the package envelope
+func checksum(r io.Reader) (string, error) {
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]), nil
+}
+
+func parseCertificates(data []byte) (certs []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, trace.BadParameter("failed to parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	switch key := a.(type) {
+	case *rsa.PublicKey:
+		other, ok := b.(*rsa.PublicKey)
+		return ok && key.N.Cmp(other.N) == 0 && key.E == other.E
+	case *ecdsa.PublicKey:
+		other, ok := b.(*ecdsa.PublicKey)
+		return ok && key.X.Cmp(other.X) == 0 && key.Y.Cmp(other.Y) == 0
+	}
+	return false
+}
+
+type trustedKey struct {
+	name string
+	key  ed25519.PublicKey
+}
+
+const (
+	// SignatureEd25519 is the type of signatures made with Ed25519 keys
+	SignatureEd25519 = "ed25519"
+	// SignatureX509 is the type of signatures made with keys certified
+	// with x509 certificates
+	SignatureX509 = "x509"
+
+	payloadVersion = "gravity-package-signature-v1"
+)
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestSigning(t *testing.T) { TestingT(t) }
+
+type SigningSuite struct {
+	packages pack.PackageService
+}
+
+var _ = Suite(&SigningSuite{})
+
+func (s *SigningSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, "bolt.db"),
+	})
+	c.Assert(err, IsNil)
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	s.packages, err = localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	err = s.packages.UpsertRepository("example.com", time.Time{})
+	c.Assert(err, IsNil)
+}
+
+func (s *SigningSuite) TestEd25519Signatures(c *C) {
+	keyPEM, publicKeyPEM, err := GenerateKey()
+	c.Assert(err, IsNil)
+	signer, err := NewSigner(keyPEM, nil)
+	c.Assert(err, IsNil)
+
+	locator := loc.MustParseLocator("example.com/package:0.0.1")
+	s.createSignedPackage(c, signer, locator, "data")
+
+	verifier := newVerifier(c, storage.TrustPolicySpecV2{
+		Keys: []storage.TrustedKey{{Name: "release", PublicKey: string(publicKeyPEM)}},
+	})
+	c.Assert(verifier.VerifyPackage(s.packages, locator), IsNil)
+
+	_, otherPublicKeyPEM, err := GenerateKey()
+	c.Assert(err, IsNil)
+	verifier = newVerifier(c, storage.TrustPolicySpecV2{
+		Keys: []storage.TrustedKey{{Name: "other", PublicKey: string(otherPublicKeyPEM)}},
+	})
+	err = verifier.VerifyPackage(s.packages, locator)
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("expected AccessDenied, got %v", err))
+}
+
+func (s *SigningSuite) TestRejectsUnsignedAndTamperedPackages(c *C) {
+	keyPEM, publicKeyPEM, err := GenerateKey()
+	c.Assert(err, IsNil)
+	signer, err := NewSigner(keyPEM, nil)
+	c.Assert(err, IsNil)
+	verifier := newVerifier(c, storage.TrustPolicySpecV2{
+		Keys: []storage.TrustedKey{{Name: "release", PublicKey: string(publicKeyPEM)}},
+	})
+
+	unsigned := loc.MustParseLocator("example.com/unsigned:0.0.1")
+	_, err = s.packages.CreatePackage(unsigned, bytes.NewBufferString("data"))
+	c.Assert(err, IsNil)
+	err = verifier.VerifyPackage(s.packages, unsigned)
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("expected AccessDenied, got %v", err))
+
+	// reuse the signatures of a package with different contents
+	original := loc.MustParseLocator("example.com/original:0.0.1")
+	envelope := s.createSignedPackage(c, signer, original, "data")
+	tampered := loc.MustParseLocator("example.com/tampered:0.0.1")
+	_, err = s.packages.CreatePackage(tampered, bytes.NewBufferString("tampered"),
+		pack.WithSignatures(envelope.Signatures))
+	c.Assert(err, IsNil)
+	err = verifier.VerifyPackage(s.packages, tampered)
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("expected AccessDenied, got %v", err))
+}
+
+func (s *SigningSuite) TestX509Signatures(c *C) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	ca, caPEM := newCertificate(c, "ca", caKey, nil, nil)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	ecdsaKeyDER, err := x509.MarshalECPrivateKey(ecdsaKey)
+	c.Assert(err, IsNil)
+
+	verifier := newVerifier(c, storage.TrustPolicySpecV2{
+		CertificateAuthorities: []string{string(caPEM)},
+	})
+	for i, tc := range []struct {
+		key    crypto.Signer
+		keyPEM []byte
+	}{
+		{
+			key: rsaKey,
+			keyPEM: pem.EncodeToMemory(&pem.Block{
+				Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		},
+		{
+			key:    ecdsaKey,
+			keyPEM: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecdsaKeyDER}),
+		},
+	} {
+		comment := Commentf("test case %v", i)
+		_, certPEM := newCertificate(c, "release", tc.key, ca, caKey)
+
+		_, err := NewSigner(tc.keyPEM, nil)
+		c.Assert(err, NotNil, comment)
+		signer, err := NewSigner(tc.keyPEM, certPEM)
+		c.Assert(err, IsNil, comment)
+
+		locator := loc.MustParseLocator(fmt.Sprintf("example.com/package:%v.0.0", i+1))
+		s.createSignedPackage(c, signer, locator, "data")
+		c.Assert(verifier.VerifyPackage(s.packages, locator), IsNil, comment)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	_, otherPEM := newCertificate(c, "other", otherKey, nil, nil)
+	verifier = newVerifier(c, storage.TrustPolicySpecV2{
+		CertificateAuthorities: []string{string(otherPEM)},
+	})
+	err = verifier.VerifyPackage(s.packages, loc.MustParseLocator("example.com/package:1.0.0"))
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("expected AccessDenied, got %v", err))
+}
+
+func (s *SigningSuite) createSignedPackage(c *C, signer pack.Signer, locator loc.Locator, data string) *pack.PackageEnvelope {
+	envelope, err := s.packages.CreatePackage(locator, bytes.NewBufferString(data))
+	c.Assert(err, IsNil)
+	signature, err := signer.SignPackage(*envelope)
+	c.Assert(err, IsNil)
+	err = s.packages.DeletePackage(locator)
+	c.Assert(err, IsNil)
+	envelope, err = s.packages.CreatePackage(locator, bytes.NewBufferString(data),
+		pack.WithSignatures([]storage.PackageSignature{*signature}))
+	c.Assert(err, IsNil)
+	return envelope
+}
+
+func newVerifier(c *C, spec storage.TrustPolicySpecV2) *Verifier {
+	policy := storage.NewTrustPolicy(spec)
+	c.Assert(policy.CheckAndSetDefaults(), IsNil)
+	verifier, err := NewVerifier(policy)
+	c.Assert(err, IsNil)
+	return verifier
+}
+
+// newCertificate returns a new certificate for the specified key.
+// The certificate is self-signed if parent is nil
+func newCertificate(c *C, name string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, []byte) {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	c.Assert(err, IsNil)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
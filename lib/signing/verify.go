@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// VerifyApplicationRequest describes an application to verify
+type VerifyApplicationRequest struct {
+	// Policy is the trust policy to verify against
+	Policy storage.TrustPolicy
+	// Apps is the application service with the application
+	Apps app.Applications
+	// Packages is the package service with the application packages
+	Packages pack.PackageService
+	// Application references the application to verify
+	Application loc.Locator
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates this request and sets defaults
+func (r *VerifyApplicationRequest) CheckAndSetDefaults() error {
+	if r.Policy == nil {
+		return trace.BadParameter("missing parameter Policy")
+	}
+	if r.Apps == nil {
+		return trace.BadParameter("missing parameter Apps")
+	}
+	if r.Packages == nil {
+		return trace.BadParameter("missing parameter Packages")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "signing")
+	}
+	return nil
+}
+
+// VerifyApplication verifies the signatures of the application package and
+// all its dependencies against the trust policy.
+// If the policy is not enforced, verification failures are only logged
+func VerifyApplication(req VerifyApplicationRequest) error {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	verifier, err := NewVerifier(req.Policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	application, err := req.Apps.GetApp(req.Application)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dependencies, err := app.GetDependencies(application, req.Apps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locators := append(dependencies.Packages, dependencies.Apps...)
+	locators = append(locators, req.Application)
+	var errors []error
+	for _, locator := range locators {
+		err := verifier.VerifyPackage(req.Packages, locator)
+		if err == nil {
+			continue
+		}
+		if !trace.IsAccessDenied(err) {
+			return trace.Wrap(err)
+		}
+		if !req.Policy.IsEnforced() {
+			req.Warnf("Package signature verification failed: %v.", err)
+			continue
+		}
+		errors = append(errors, err)
+	}
+	if len(errors) != 0 {
+		return trace.AccessDenied("application %v failed signature verification: %v",
+			req.Application, trace.NewAggregate(errors...))
+	}
+	req.Infof("Verified signatures of %v and its dependencies.", req.Application)
+	return nil
+}
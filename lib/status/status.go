@@ -115,6 +115,11 @@ func FromCluster(ctx context.Context, operator ops.Operator, cluster ops.Site, o
 			fromOperationAndProgress(op, *progress))
 	}
 
+	status.QueuedOperations, err = operator.GetQueuedOperations(cluster.Key())
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to query the operation queue.")
+	}
+
 	var operation *ops.SiteOperation
 	var progress *ops.ProgressEntry
 	// if operation ID is provided, get info for that operation, otherwise
@@ -211,6 +216,8 @@ type Cluster struct {
 	Operation *ClusterOperation `json:"operation,omitempty"`
 	// ActiveOperations is a list of operations currently active in the cluster
 	ActiveOperations []*ClusterOperation `json:"active_operations,omitempty"`
+	// QueuedOperations lists operations waiting in the cluster operation queue
+	QueuedOperations []storage.QueuedOperation `json:"queued_operations,omitempty"`
 	// Endpoints contains cluster and application endpoints.
 	Endpoints Endpoints `json:"endpoints"`
 	// Extension is a cluster status extension
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import "fmt"
+
+// DryRunReport describes the changes a phase would make if executed
+type DryRunReport struct {
+	// Commands lists the commands the phase would run
+	Commands []string `json:"commands,omitempty"`
+	// Packages lists the packages the phase would install or update
+	Packages []string `json:"packages,omitempty"`
+	// Objects lists the Kubernetes objects the phase would modify
+	Objects []string `json:"objects,omitempty"`
+	// Files lists the files on the host the phase would modify
+	Files []string `json:"files,omitempty"`
+}
+
+// AddCommand records a command in this report
+func (r *DryRunReport) AddCommand(format string, args ...interface{}) *DryRunReport {
+	r.Commands = append(r.Commands, fmt.Sprintf(format, args...))
+	return r
+}
+
+// AddPackage records a package update in this report
+func (r *DryRunReport) AddPackage(format string, args ...interface{}) *DryRunReport {
+	r.Packages = append(r.Packages, fmt.Sprintf(format, args...))
+	return r
+}
+
+// AddObject records a Kubernetes object in this report
+func (r *DryRunReport) AddObject(kind, name string) *DryRunReport {
+	r.Objects = append(r.Objects, fmt.Sprintf("%v/%v", kind, name))
+	return r
+}
+
+// AddFile records a file in this report
+func (r *DryRunReport) AddFile(path string) *DryRunReport {
+	r.Files = append(r.Files, path)
+	return r
+}
+
+// IsEmpty returns true if the report does not describe any changes
+func (r DryRunReport) IsEmpty() bool {
+	return len(r.Commands) == 0 && len(r.Packages) == 0 &&
+		len(r.Objects) == 0 && len(r.Files) == 0
+}
+
+// PhaseDryRun is the result of a dry-run of a single phase
+type PhaseDryRun struct {
+	// PhaseID is the ID of the phase
+	PhaseID string `json:"phase"`
+	// Description is the phase description
+	Description string `json:"description,omitempty"`
+	// Node is the address of the node the phase executes on
+	Node string `json:"node,omitempty"`
+	// Report describes the phase changes.
+	// It is nil if the phase executor does not support dry-run
+	Report *DryRunReport `json:"report,omitempty"`
+	// Error is set if the report could not be generated
+	Error string `json:"error,omitempty"`
+}
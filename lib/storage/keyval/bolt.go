@@ -44,11 +44,15 @@ func NewBolt(cfg BoltConfig) (storage.Backend, error) {
 	var engine kvengine
 	if cfg.Multi {
 		engine, err = newMultiBolt(cfg)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
 	} else {
-		engine, err = newBolt(cfg, &v1codec{})
-	}
-	if err != nil {
-		return nil, trace.Wrap(err)
+		bolt, err := newBolt(cfg, &v1codec{})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		engine = newNotifyingEngine(bolt)
 	}
 	clock := cfg.Clock
 	if clock == nil {
@@ -202,7 +206,7 @@ func (b *blt) initDatafile(path string) error {
 }
 
 func (b *blt) key(prefix string, keys ...string) key {
-	return append([]string{"root", prefix}, keys...)
+	return append([]string{rootBucket, prefix}, keys...)
 }
 
 func (b *blt) split(key key) ([]string, string) {
@@ -149,6 +149,22 @@ func (s *BSuite) TestOperationsCRUD(c *C) {
 	s.suite.OperationsCRUD(c)
 }
 
+func (s *BSuite) TestOperationsQuery(c *C) {
+	s.suite.OperationsQuery(c)
+}
+
+func (s *BSuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
+func (s *BSuite) TestOperationQueueCRUD(c *C) {
+	s.suite.OperationQueueCRUD(c)
+}
+
+func (s *BSuite) TestConcurrentCompareAndSwap(c *C) {
+	s.suite.ConcurrentCompareAndSwap(c)
+}
+
 func (s *BSuite) TestCreatesApplication(c *C) {
 	s.suite.CreatesApplication(c)
 }
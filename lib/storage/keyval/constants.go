@@ -73,6 +73,9 @@ const (
 	dnsP                        = "dns"
 	chartsP                     = "charts"
 	indexP                      = "index"
+	trustPolicyP                = "trustpolicy"
+	maintenanceWindowP          = "maintenancewindow"
+	queueP                      = "queue"
 
 	// AllCollectionIDs identifies a collection without a specification (an ID)
 	AllCollectionIDs = "__all__"
@@ -173,6 +173,18 @@ func (s *ESuite) TestOperationsCRUD(c *C) {
 	s.suite.OperationsCRUD(c)
 }
 
+func (s *ESuite) TestOperationsQuery(c *C) {
+	s.suite.OperationsQuery(c)
+}
+
+func (s *ESuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
+func (s *ESuite) TestOperationQueueCRUD(c *C) {
+	s.suite.OperationQueueCRUD(c)
+}
+
 func (s *ESuite) TestCreatesApplication(c *C) {
 	s.suite.CreatesApplication(c)
 }
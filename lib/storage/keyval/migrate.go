@@ -0,0 +1,438 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/etcd/client"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// MigrateConfig defines the configuration of a backend migration
+type MigrateConfig struct {
+	// Source is the backend to copy data from
+	Source storage.Backend
+	// Target is the backend to copy data to
+	Target storage.Backend
+	// DryRun only computes the changes without applying them
+	DryRun bool
+	// Resume allows to migrate into a non-empty target backend
+	// to continue an interrupted migration
+	Resume bool
+	// Progress is used to report migration progress
+	Progress utils.Printer
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates this configuration and sets defaults
+func (r *MigrateConfig) CheckAndSetDefaults() error {
+	if r.Source == nil {
+		return trace.BadParameter("missing Source")
+	}
+	if r.Target == nil {
+		return trace.BadParameter("missing Target")
+	}
+	if r.Progress == nil {
+		r.Progress = utils.DiscardPrinter
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "migrate")
+	}
+	return nil
+}
+
+// MigrationReport describes the result of a backend migration
+type MigrationReport struct {
+	// Collections lists the migrated collections
+	Collections []CollectionReport
+	// Differences lists the differences between the source and the target
+	// backends remaining after the migration
+	Differences []Difference
+}
+
+// CollectionReport describes the migration of a single collection
+type CollectionReport struct {
+	// Name is the collection name
+	Name string
+	// Items is the number of items in the source collection
+	Items int
+	// Copied is the number of items copied (or to be copied, in dry-run mode)
+	Copied int
+}
+
+// String returns a text representation of this collection report
+func (r CollectionReport) String() string {
+	return fmt.Sprintf("%v: %v items, %v copied", r.Name, r.Items, r.Copied)
+}
+
+// Difference describes a single mismatch between two backends
+type Difference struct {
+	// Key is the item key relative to the backend root
+	Key string
+	// Type is the type of the mismatch
+	Type DifferenceType
+}
+
+// String returns a text representation of this difference
+func (r Difference) String() string {
+	return fmt.Sprintf("%v: %v", r.Key, r.Type)
+}
+
+// DifferenceType defines the type of mismatch between two backends
+type DifferenceType string
+
+const (
+	// DifferenceMissing means the item is missing from the target
+	DifferenceMissing DifferenceType = "missing"
+	// DifferenceChanged means the item has a different value in the target
+	DifferenceChanged DifferenceType = "changed"
+	// DifferenceExtra means the item only exists in the target
+	DifferenceExtra DifferenceType = "extra"
+)
+
+// Migrate copies every collection from the source backend into the target
+// backend preserving item TTLs and verifies the result by comparing
+// the contents of both backends.
+//
+// Items that already exist in the target with the same value are skipped,
+// so an interrupted migration can be resumed by running it again with Resume set
+func Migrate(config MigrateConfig) (*MigrationReport, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	source, err := getRawEngine(config.Source)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	target, err := getRawEngine(config.Target)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sourceItems, err := walkAll(source)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	targetItems, err := walkAll(target)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(targetItems) != 0 && !config.Resume {
+		return nil, trace.AlreadyExists("target backend is not empty, " +
+			"resume the migration to copy the remaining items")
+	}
+	existing := make(map[string]rawItem, len(targetItems))
+	for _, item := range targetItems {
+		existing[item.path()] = item
+	}
+	var report MigrationReport
+	for _, collection := range groupByCollection(sourceItems) {
+		collectionReport := CollectionReport{
+			Name:  collection.name,
+			Items: len(collection.items),
+		}
+		for _, item := range collection.items {
+			if existingItem, ok := existing[item.path()]; ok && existingItem.equals(item) {
+				continue
+			}
+			collectionReport.Copied++
+			if config.DryRun {
+				continue
+			}
+			if err := copyItem(target, item); err != nil {
+				return nil, trace.Wrap(err, "failed to copy %v", item.path())
+			}
+		}
+		config.WithFields(logrus.Fields{
+			"collection": collectionReport.Name,
+			"items":      collectionReport.Items,
+			"copied":     collectionReport.Copied,
+			"dry-run":    config.DryRun,
+		}).Info("Migrated collection.")
+		config.Progress.PrintStep("Migrated %v", collectionReport)
+		report.Collections = append(report.Collections, collectionReport)
+	}
+	if config.DryRun {
+		return &report, nil
+	}
+	report.Differences, err = Diff(config.Source, config.Target)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, diff := range report.Differences {
+		if diff.Type != DifferenceExtra {
+			return &report, trace.CompareFailed("target backend does not match "+
+				"the source after migration: %v", diff)
+		}
+	}
+	return &report, nil
+}
+
+// Diff compares the contents of the specified backends and returns the differences
+// from the source backend's perspective
+func Diff(source, target storage.Backend) ([]Difference, error) {
+	sourceEngine, err := getRawEngine(source)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	targetEngine, err := getRawEngine(target)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sourceItems, err := walkAll(sourceEngine)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	targetItems, err := walkAll(targetEngine)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	remaining := make(map[string]rawItem, len(targetItems))
+	for _, item := range targetItems {
+		remaining[item.path()] = item
+	}
+	var diffs []Difference
+	for _, item := range sourceItems {
+		targetItem, ok := remaining[item.path()]
+		switch {
+		case !ok:
+			diffs = append(diffs, Difference{Key: item.path(), Type: DifferenceMissing})
+		case !targetItem.equals(item):
+			diffs = append(diffs, Difference{Key: item.path(), Type: DifferenceChanged})
+		}
+		delete(remaining, item.path())
+	}
+	for _, item := range targetItems {
+		if _, ok := remaining[item.path()]; ok {
+			diffs = append(diffs, Difference{Key: item.path(), Type: DifferenceExtra})
+		}
+	}
+	return diffs, nil
+}
+
+// rawEngine is an engine that can enumerate its contents
+type rawEngine interface {
+	kvengine
+	// walkItems calls fn for every item stored under the engine root key.
+	// Directories are reported before their contents
+	walkItems(fn func(rawItem) error) error
+}
+
+// rawItem is a single key or directory stored in an engine
+type rawItem struct {
+	// key is the item key relative to the engine root key
+	key key
+	// value is the item value, nil for directories
+	value []byte
+	// ttl is the item time to live
+	ttl time.Duration
+}
+
+func (r rawItem) path() string {
+	return strings.Join(r.key, "/")
+}
+
+func (r rawItem) isDir() bool {
+	return r.value == nil
+}
+
+// equals returns true if the other item has the same kind and value.
+// TTLs are not compared as they decrease over time
+func (r rawItem) equals(other rawItem) bool {
+	if r.isDir() || other.isDir() {
+		return r.isDir() == other.isDir()
+	}
+	return bytes.Equal(r.value, other.value)
+}
+
+type collection struct {
+	name  string
+	items []rawItem
+}
+
+// groupByCollection groups items by their top-level key
+// preserving the order of items within each collection
+func groupByCollection(items []rawItem) (collections []collection) {
+	indexes := make(map[string]int)
+	for _, item := range items {
+		name := item.key[0]
+		index, ok := indexes[name]
+		if !ok {
+			index = len(collections)
+			indexes[name] = index
+			collections = append(collections, collection{name: name})
+		}
+		collections[index].items = append(collections[index].items, item)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].name < collections[j].name
+	})
+	return collections
+}
+
+func copyItem(target rawEngine, item rawItem) error {
+	key := target.key(item.key[0], item.key[1:]...)
+	if item.isDir() {
+		return trace.Wrap(target.upsertDir(key, item.ttl))
+	}
+	return trace.Wrap(target.upsertValBytes(key, item.value, item.ttl))
+}
+
+func walkAll(engine rawEngine) (items []rawItem, err error) {
+	err = engine.walkItems(func(item rawItem) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return items, nil
+}
+
+func getRawEngine(b storage.Backend) (rawEngine, error) {
+	switch b := b.(type) {
+	case *electingBackend:
+		return getRawEngine(b.Backend)
+	case *backend:
+		engine := b.kvengine
+		if notifier, ok := engine.(*notifyingEngine); ok {
+			engine = notifier.kvengine
+		}
+		if engine, ok := engine.(rawEngine); ok {
+			return engine, nil
+		}
+	}
+	return nil, trace.BadParameter("backend %T does not support migration", b)
+}
+
+// walkItems calls fn for every item stored in the root bucket.
+// Since bolt does not support TTLs, they are computed from
+// the expiration times of the stored objects
+func (b *blt) walkItems(fn func(rawItem) error) error {
+	return b.walk(func(k key, value []byte) error {
+		// skip the root bucket itself and anything outside of it
+		if len(k) < 2 || k[0] != rootBucket {
+			return nil
+		}
+		item := rawItem{key: k[1:]}
+		if value != nil {
+			// values are only valid for the life of the transaction
+			item.value = append([]byte{}, value...)
+			var expired bool
+			item.ttl, expired = valueTTL(b.clock, value)
+			if expired {
+				// expired items are only kept until they are
+				// next accessed and should not be migrated
+				return nil
+			}
+		}
+		return fn(item)
+	})
+}
+
+func (b *multiBolt) walkItems(fn func(rawItem) error) error {
+	return trace.Wrap(b.withBolt(func(b *blt) error {
+		return trace.Wrap(b.walkItems(fn))
+	}))
+}
+
+// walkItems calls fn for every node stored under the engine root key
+func (e *engine) walkItems(fn func(rawItem) error) error {
+	re, err := e.Get(context.TODO(), ekey(e.etcdKey), &client.GetOptions{Recursive: true, Sort: true})
+	if err != nil {
+		err = convertErr(err)
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	var walk func(nodes client.Nodes) error
+	walk = func(nodes client.Nodes) error {
+		for _, node := range nodes {
+			k, err := e.relativeKey(node.Key)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			item := rawItem{
+				key: k,
+				ttl: time.Duration(node.TTL) * time.Second,
+			}
+			if !node.Dir {
+				item.value, err = e.codec.DecodeBytesFromString(node.Value)
+				if err != nil {
+					return trace.Wrap(err)
+				}
+				// make sure empty values are not reported as directories
+				if item.value == nil {
+					item.value = []byte{}
+				}
+			}
+			if err := fn(item); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := walk(node.Nodes); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		return nil
+	}
+	return walk(re.Node.Nodes)
+}
+
+// valueTTL returns the time to live of the object encoded in value
+// based on its expiration time, if it has one.
+// The second return value is true if the object has already expired
+func valueTTL(clock clockwork.Clock, value []byte) (ttl time.Duration, expired bool) {
+	var object struct {
+		Expires  time.Time `json:"expires"`
+		Metadata struct {
+			Expires *time.Time `json:"expires"`
+		} `json:"metadata"`
+	}
+	if len(value) == 0 || value[0] != '{' {
+		return forever, false
+	}
+	if err := json.Unmarshal(value, &object); err != nil {
+		return forever, false
+	}
+	expires := object.Expires
+	if object.Metadata.Expires != nil {
+		expires = *object.Metadata.Expires
+	}
+	if expires.IsZero() {
+		return forever, false
+	}
+	if !expires.After(clock.Now()) {
+		return 0, true
+	}
+	return expires.UTC().Sub(clock.Now().UTC()), false
+}
+
+// rootBucket is the name of the bolt bucket with all data
+const rootBucket = "root"
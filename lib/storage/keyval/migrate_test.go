@@ -0,0 +1,215 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+type MigrateSuite struct {
+	clock  clockwork.FakeClock
+	source storage.Backend
+	target storage.Backend
+}
+
+var _ = Suite(&MigrateSuite{})
+
+func (s *MigrateSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	s.clock = clockwork.NewFakeClockAt(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	var err error
+	s.source, err = NewBolt(BoltConfig{Clock: s.clock, Path: filepath.Join(dir, "source.db")})
+	c.Assert(err, IsNil)
+	s.target, err = NewBolt(BoltConfig{Clock: s.clock, Path: filepath.Join(dir, "target.db")})
+	c.Assert(err, IsNil)
+}
+
+func (s *MigrateSuite) TearDownTest(c *C) {
+	c.Assert(s.source.Close(), IsNil)
+	c.Assert(s.target.Close(), IsNil)
+}
+
+func (s *MigrateSuite) TestMigratesBackend(c *C) {
+	site, operation := s.createCluster(c)
+	token, err := s.source.CreateProvisioningToken(storage.ProvisioningToken{
+		Token:      "token",
+		Type:       storage.ProvisioningTokenTypeInstall,
+		AccountID:  site.AccountID,
+		SiteDomain: site.Domain,
+		Expires:    s.clock.Now().Add(time.Hour),
+	})
+	c.Assert(err, IsNil)
+
+	report, err := Migrate(MigrateConfig{Source: s.source, Target: s.target, DryRun: true})
+	c.Assert(err, IsNil)
+	c.Assert(report.Collections, Not(HasLen), 0)
+	diffs, err := Diff(s.source, s.target)
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, countCopied(report), Commentf("dry-run should not modify the target"))
+
+	_, err = Migrate(MigrateConfig{Source: s.source, Target: s.target})
+	c.Assert(err, IsNil)
+	diffs, err = Diff(s.source, s.target)
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 0)
+
+	migratedSite, err := s.target.GetSite(site.Domain)
+	c.Assert(err, IsNil)
+	c.Assert(migratedSite.AccountID, Equals, site.AccountID)
+	migratedOperation, err := s.target.GetSiteOperation(site.Domain, operation.ID)
+	c.Assert(err, IsNil)
+	c.Assert(migratedOperation.Type, Equals, operation.Type)
+	migratedToken, err := s.target.GetProvisioningToken(token.Token)
+	c.Assert(err, IsNil)
+	c.Assert(migratedToken.Expires.Equal(token.Expires), Equals, true)
+}
+
+func (s *MigrateSuite) TestResumesMigration(c *C) {
+	site, operation := s.createCluster(c)
+	_, err := Migrate(MigrateConfig{Source: s.source, Target: s.target})
+	c.Assert(err, IsNil)
+
+	// simulate an interrupted migration
+	err = s.target.DeleteSiteOperation(site.Domain, operation.ID)
+	c.Assert(err, IsNil)
+
+	_, err = Migrate(MigrateConfig{Source: s.source, Target: s.target})
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("expected AlreadyExists, got %v", err))
+
+	report, err := Migrate(MigrateConfig{Source: s.source, Target: s.target, Resume: true})
+	c.Assert(err, IsNil)
+	c.Assert(countCopied(report), Not(Equals), 0)
+	_, err = s.target.GetSiteOperation(site.Domain, operation.ID)
+	c.Assert(err, IsNil)
+
+	report, err = Migrate(MigrateConfig{Source: s.source, Target: s.target, Resume: true})
+	c.Assert(err, IsNil)
+	c.Assert(countCopied(report), Equals, 0)
+}
+
+func (s *MigrateSuite) TestReportsChangedItems(c *C) {
+	site, operation := s.createCluster(c)
+	_, err := Migrate(MigrateConfig{Source: s.source, Target: s.target})
+	c.Assert(err, IsNil)
+
+	operation.State = "failed"
+	_, err = s.target.UpdateSiteOperation(*operation)
+	c.Assert(err, IsNil)
+	_, err = s.target.CreateAccount(storage.Account{Org: "other"})
+	c.Assert(err, IsNil)
+
+	diffs, err := Diff(s.source, s.target)
+	c.Assert(err, IsNil)
+	var types []DifferenceType
+	for _, diff := range diffs {
+		types = append(types, diff.Type)
+	}
+	// the new account is reported as an extra directory and an extra value
+	c.Assert(types, DeepEquals, []DifferenceType{DifferenceChanged, DifferenceExtra, DifferenceExtra},
+		Commentf("unexpected differences for cluster %v: %v", site.Domain, diffs))
+}
+
+func (s *MigrateSuite) TestSkipsExpiredItems(c *C) {
+	site, _ := s.createCluster(c)
+	_, err := s.source.CreateProvisioningToken(storage.ProvisioningToken{
+		Token:      "token",
+		Type:       storage.ProvisioningTokenTypeInstall,
+		AccountID:  site.AccountID,
+		SiteDomain: site.Domain,
+		Expires:    s.clock.Now().Add(time.Hour),
+	})
+	c.Assert(err, IsNil)
+	s.clock.Advance(2 * time.Hour)
+
+	_, err = Migrate(MigrateConfig{Source: s.source, Target: s.target})
+	c.Assert(err, IsNil)
+	target, err := getRawEngine(s.target)
+	c.Assert(err, IsNil)
+	err = target.walkItems(func(item rawItem) error {
+		c.Assert(item.key[len(item.key)-1], Not(Equals), "token", Commentf("expired item was migrated"))
+		return nil
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *MigrateSuite) TestComputesValueTTL(c *C) {
+	expires := s.clock.Now().Add(time.Hour).Format(time.RFC3339)
+	expired := s.clock.Now().Add(-time.Hour).Format(time.RFC3339)
+	testCases := []struct {
+		value   string
+		ttl     time.Duration
+		expired bool
+		comment string
+	}{
+		{value: `{"expires":"` + expires + `"}`, ttl: time.Hour, comment: "expiration time"},
+		{value: `{"metadata":{"expires":"` + expires + `"}}`, ttl: time.Hour, comment: "resource expiration time"},
+		{value: `{"expires":"` + expired + `"}`, expired: true, comment: "past expiration time"},
+		{value: `{"metadata":{"expires":"` + expired + `"}}`, expired: true, comment: "past resource expiration time"},
+		{value: `{"expires":"0001-01-01T00:00:00Z"}`, ttl: forever, comment: "zero expiration time"},
+		{value: `{"name":"value"}`, ttl: forever, comment: "no expiration time"},
+		{value: `"locked"`, ttl: forever, comment: "not an object"},
+	}
+	for _, tc := range testCases {
+		ttl, expired := valueTTL(s.clock, []byte(tc.value))
+		c.Assert(expired, Equals, tc.expired, Commentf(tc.comment))
+		c.Assert(ttl, Equals, tc.ttl, Commentf(tc.comment))
+	}
+}
+
+func (s *MigrateSuite) createCluster(c *C) (*storage.Site, *storage.SiteOperation) {
+	account, err := s.source.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.source.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.source.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Type:       string(storage.AppUser),
+	})
+	c.Assert(err, IsNil)
+	site, err := s.source.CreateSite(storage.Site{
+		AccountID: account.ID,
+		Created:   s.clock.Now(),
+		Domain:    "example.com",
+		App:       *app,
+	})
+	c.Assert(err, IsNil)
+	operation, err := s.source.CreateSiteOperation(storage.SiteOperation{
+		AccountID:  account.ID,
+		SiteDomain: site.Domain,
+		Type:       "install",
+		Created:    s.clock.Now(),
+		State:      "completed",
+	})
+	c.Assert(err, IsNil)
+	return site, operation
+}
+
+func countCopied(report *MigrationReport) (copied int) {
+	for _, collection := range report.Collections {
+		copied += collection.Copied
+	}
+	return copied
+}
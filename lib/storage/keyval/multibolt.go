@@ -162,7 +162,7 @@ func (b *multiBolt) getKeys(key key) (keys []string, err error) {
 }
 
 func (b *multiBolt) key(prefix string, keys ...string) key {
-	return append([]string{"root", prefix}, keys...)
+	return append([]string{rootBucket, prefix}, keys...)
 }
 
 func (b *multiBolt) Close() error {
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// CreateQueuedOperation adds a new entry to the cluster operation queue.
+// The entry is removed automatically once it expires
+func (b *backend) CreateQueuedOperation(op storage.QueuedOperation) (*storage.QueuedOperation, error) {
+	if err := op.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if op.ID == "" {
+		op.ID = uuid.New()
+	}
+	if _, err := b.GetSite(op.SiteDomain); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.createVal(b.key(sitesP, op.SiteDomain, queueP, op.ID), op, b.ttl(op.Expires))
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return nil, trace.AlreadyExists("queued operation(%v) already exists", op.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// GetQueuedOperations returns the operation queue of the specified cluster
+// sorted by creation time (oldest entries come first)
+func (b *backend) GetQueuedOperations(siteDomain string) ([]storage.QueuedOperation, error) {
+	if siteDomain == "" {
+		return nil, trace.BadParameter("missing parameter SiteDomain")
+	}
+	ids, err := b.getKeys(b.key(sitesP, siteDomain, queueP))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var out []storage.QueuedOperation
+	for _, id := range ids {
+		var op storage.QueuedOperation
+		err = b.getVal(b.key(sitesP, siteDomain, queueP, id), &op)
+		if err != nil {
+			if !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+			continue
+		}
+		utils.UTC(&op.Created)
+		utils.UTC(&op.Expires)
+		utils.UTC(&op.LastSeen)
+		out = append(out, op)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+// GetQueuedOperation returns the operation queue entry with the specified ID
+func (b *backend) GetQueuedOperation(siteDomain, id string) (*storage.QueuedOperation, error) {
+	if siteDomain == "" {
+		return nil, trace.BadParameter("missing parameter SiteDomain")
+	}
+	if id == "" {
+		return nil, trace.BadParameter("missing parameter ID")
+	}
+	var op storage.QueuedOperation
+	err := b.getVal(b.key(sitesP, siteDomain, queueP, id), &op)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("queued operation(%v) not found", id)
+		}
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&op.Created)
+	utils.UTC(&op.Expires)
+	utils.UTC(&op.LastSeen)
+	return &op, nil
+}
+
+// UpdateQueuedOperation updates an existing entry in the operation queue
+func (b *backend) UpdateQueuedOperation(op storage.QueuedOperation) (*storage.QueuedOperation, error) {
+	if err := op.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if op.ID == "" {
+		return nil, trace.BadParameter("missing parameter ID")
+	}
+	err := b.updateVal(b.key(sitesP, op.SiteDomain, queueP, op.ID), op, b.ttl(op.Expires))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("queued operation(%v) not found", op.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// DeleteQueuedOperation removes the specified entry from the operation queue
+func (b *backend) DeleteQueuedOperation(siteDomain, id string) error {
+	if siteDomain == "" {
+		return trace.BadParameter("missing parameter SiteDomain")
+	}
+	if id == "" {
+		return trace.BadParameter("missing parameter ID")
+	}
+	err := b.deleteKey(b.key(sitesP, siteDomain, queueP, id))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("queued operation(%v) not found", id)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
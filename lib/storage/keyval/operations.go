@@ -114,6 +114,39 @@ func (b *backend) GetSiteOperations(siteDomain string) ([]storage.SiteOperation,
 	return out, nil
 }
 
+// QuerySiteOperations returns operations matching the specified filter
+// sorted by time (latest operations come first)
+func (b *backend) QuerySiteOperations(filter storage.OperationsFilter) ([]storage.SiteOperation, error) {
+	clusters := []string{filter.SiteDomain}
+	if filter.SiteDomain == "" {
+		sites, err := b.GetAllSites()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		clusters = clusters[:0]
+		for _, site := range sites {
+			clusters = append(clusters, site.Domain)
+		}
+	}
+	var out []storage.SiteOperation
+	for _, cluster := range clusters {
+		operations, err := b.GetSiteOperations(cluster)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, op := range operations {
+			if filter.Matches(op) {
+				out = append(out, op)
+			}
+		}
+	}
+	sort.Sort(operationsSorter(out))
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
 // UpdateSiteOperation updates site operation state
 func (b *backend) UpdateSiteOperation(op storage.SiteOperation) (*storage.SiteOperation, error) {
 	if err := op.Check(); err != nil {
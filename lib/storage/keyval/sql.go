@@ -0,0 +1,611 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"database/sql"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/jmoiron/sqlx"
+	"github.com/jonboulle/clockwork"
+	"github.com/lib/pq"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// SQLConfig is the relational database backend configuration
+type SQLConfig struct {
+	// Driver is the database driver, either sqlite3 or postgres
+	Driver string `json:"driver" yaml:"driver"`
+	// DataSource is the driver-specific data source name, e.g. a path
+	// to the database file for sqlite3 or a connection URI for postgres
+	DataSource string `json:"data_source" yaml:"data_source"`
+	// Clock is a clock interface, used in tests
+	Clock clockwork.Clock `json:"-" yaml:"-"`
+}
+
+// CheckAndSetDefaults validates this configuration and sets defaults
+func (c *SQLConfig) CheckAndSetDefaults() error {
+	switch c.Driver {
+	case SQLDriverSQLite, SQLDriverPostgres:
+	case "":
+		return trace.BadParameter("missing Driver parameter")
+	default:
+		return trace.BadParameter("unsupported SQL driver %q, expected %q or %q",
+			c.Driver, SQLDriverSQLite, SQLDriverPostgres)
+	}
+	if c.DataSource == "" {
+		return trace.BadParameter("missing DataSource parameter")
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	return nil
+}
+
+// sqlEngine is a kvengine on top of a relational database.
+//
+// Every key is stored as a row with the escaped key path, its parent path
+// and the name of the last key element so keys of a directory are retrieved
+// with an indexed lookup by parent. Directories are stored as rows without
+// values, similar to bolt buckets.
+type sqlEngine struct {
+	logrus.FieldLogger
+	db    *sqlx.DB
+	codec Codec
+	clock clockwork.Clock
+}
+
+// newSQLEngine opens the database described with cfg and creates the schema
+func newSQLEngine(cfg SQLConfig, codec Codec) (*sqlEngine, error) {
+	db, err := sqlx.Open(cfg.Driver, cfg.DataSource)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if cfg.Driver == SQLDriverSQLite {
+		// sqlite does not support concurrent writers so serialize
+		// all access through a single connection
+		db.SetMaxOpenConns(1)
+	}
+	engine := &sqlEngine{
+		FieldLogger: logrus.WithFields(logrus.Fields{
+			trace.Component: "sql",
+			"driver":        cfg.Driver,
+		}),
+		db:    db,
+		codec: codec,
+		clock: cfg.Clock,
+	}
+	if err := engine.migrate(); err != nil {
+		db.Close()
+		return nil, trace.Wrap(err)
+	}
+	return engine, nil
+}
+
+// migrate creates the database schema if it does not exist
+func (b *sqlEngine) migrate() error {
+	blobType := "BLOB"
+	pathType := "TEXT"
+	if b.db.DriverName() == SQLDriverPostgres {
+		blobType = "BYTEA"
+		// compare paths bytewise like bolt does
+		pathType = `TEXT COLLATE "C"`
+	}
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS kv (
+			path ` + pathType + ` PRIMARY KEY,
+			parent ` + pathType + ` NOT NULL,
+			name TEXT NOT NULL,
+			dir BOOLEAN NOT NULL,
+			value ` + blobType + `,
+			expires BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS kv_parent ON kv (parent)`,
+		`CREATE TABLE IF NOT EXISTS operations (
+			cluster TEXT NOT NULL,
+			id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			state TEXT NOT NULL,
+			created BIGINT NOT NULL,
+			PRIMARY KEY (cluster, id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS operations_cluster ON operations (cluster, created)`,
+		`CREATE INDEX IF NOT EXISTS operations_type ON operations (type, created)`,
+		`CREATE INDEX IF NOT EXISTS operations_state ON operations (state, created)`,
+		`CREATE INDEX IF NOT EXISTS operations_created ON operations (created)`,
+		`CREATE TABLE IF NOT EXISTS progress_entries (
+			cluster TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			id TEXT NOT NULL,
+			created BIGINT NOT NULL,
+			PRIMARY KEY (cluster, operation, id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS progress_entries_created ON progress_entries (cluster, operation, created)`,
+	}
+	for _, statement := range statements {
+		if _, err := b.db.Exec(statement); err != nil {
+			return trace.Wrap(convertSQLErr(err), "failed to create database schema")
+		}
+	}
+	return nil
+}
+
+func (b *sqlEngine) key(prefix string, keys ...string) key {
+	return append([]string{"root", prefix}, keys...)
+}
+
+func (b *sqlEngine) createDir(k key, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		if err := b.upsertParents(tx, k); err != nil {
+			return trace.Wrap(err)
+		}
+		row, err := b.getRow(tx, k)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if row != nil {
+			return trace.AlreadyExists("%v already exists", k)
+		}
+		return trace.Wrap(b.insertRow(tx, k, true, nil, 0))
+	})
+}
+
+func (b *sqlEngine) upsertDir(k key, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		if err := b.upsertParents(tx, k); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(b.upsertDirRow(tx, k))
+	})
+}
+
+func (b *sqlEngine) deleteDir(k key) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !row.Dir {
+			return trace.NotFound("%v is not found", k)
+		}
+		prefix := encodePath(k) + pathSeparator
+		_, err = tx.Exec(tx.Rebind(`DELETE FROM kv WHERE path = ? OR substr(path, 1, ?) = ?`),
+			encodePath(k), len(prefix), prefix)
+		return trace.Wrap(convertSQLErr(err))
+	})
+}
+
+func (b *sqlEngine) createVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := b.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return b.createValBytes(k, encoded, ttl)
+}
+
+func (b *sqlEngine) createValBytes(k key, data []byte, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		if err := b.upsertParents(tx, k); err != nil {
+			return trace.Wrap(err)
+		}
+		row, err := b.getRow(tx, k)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if row != nil {
+			return trace.AlreadyExists("'%v' already exists", k[len(k)-1])
+		}
+		return trace.Wrap(b.insertRow(tx, k, false, data, ttl))
+	})
+}
+
+func (b *sqlEngine) upsertVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := b.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return b.upsertValBytes(k, encoded, ttl)
+}
+
+func (b *sqlEngine) upsertValBytes(k key, data []byte, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		if err := b.upsertParents(tx, k); err != nil {
+			return trace.Wrap(err)
+		}
+		row, err := b.getRow(tx, k)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if row == nil {
+			return trace.Wrap(b.insertRow(tx, k, false, data, ttl))
+		}
+		if row.Dir {
+			return trace.BadParameter("key %q is a bucket", k[len(k)-1])
+		}
+		return trace.Wrap(b.updateRow(tx, k, data, ttl))
+	})
+}
+
+func (b *sqlEngine) updateVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := b.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return b.updateValBytes(k, encoded, ttl)
+}
+
+func (b *sqlEngine) updateValBytes(k key, data []byte, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if row.Dir {
+			return trace.NotFound("%q not found", k[len(k)-1])
+		}
+		return trace.Wrap(b.updateRow(tx, k, data, ttl))
+	})
+}
+
+func (b *sqlEngine) updateTTL(k key, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if row.Dir {
+			// directories do not expire
+			return nil
+		}
+		_, err = tx.Exec(tx.Rebind(`UPDATE kv SET expires = ? WHERE path = ?`),
+			b.expires(ttl), encodePath(k))
+		return trace.Wrap(convertSQLErr(err))
+	})
+}
+
+func (b *sqlEngine) compareAndSwap(k key, val interface{}, prevVal interface{}, outVal interface{}, ttl time.Duration) error {
+	encoded, err := b.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var prevEncoded []byte
+	if prevVal != nil {
+		prevEncoded, err = b.codec.EncodeToBytes(prevVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	var outEncoded []byte
+	err = b.compareAndSwapBytes(k, encoded, prevEncoded, &outEncoded, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal != nil {
+		err = b.codec.DecodeFromBytes(outEncoded, outVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (b *sqlEngine) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		if err := b.upsertParents(tx, k); err != nil {
+			return trace.Wrap(err)
+		}
+		row, err := b.getRow(tx, k)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if prevVal == nil { // we don't expect the value to exist
+			if row != nil {
+				return trace.AlreadyExists("key %q already exists", k[len(k)-1])
+			}
+			return trace.Wrap(b.insertRow(tx, k, false, val, ttl))
+		}
+		// we expect the previous value to exist
+		if row == nil || row.Dir {
+			return trace.NotFound("key %q not found", k[len(k)-1])
+		}
+		if !bytes.Equal(row.Value, prevVal) {
+			return trace.CompareFailed("expected %q got %q",
+				string(prevVal), string(row.Value))
+		}
+		// the row is not locked by the read above, so the update only
+		// applies if the value has not been changed concurrently
+		updated, err := b.compareAndUpdateRow(tx, k, val, prevVal, ttl)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !updated {
+			return trace.CompareFailed("%q has been updated concurrently", k[len(k)-1])
+		}
+		*outVal = row.Value
+		return nil
+	})
+}
+
+func (b *sqlEngine) getValBytes(k key) ([]byte, error) {
+	var out []byte
+	err := b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if row.Dir {
+			return trace.BadParameter("key %q is a bucket", k[len(k)-1])
+		}
+		out = row.Value
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+func (b *sqlEngine) getVal(k key, outVal interface{}) error {
+	data, err := b.getValBytes(k)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return b.codec.DecodeFromBytes(data, outVal)
+}
+
+func (b *sqlEngine) compareAndDelete(k key, prevVal interface{}) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if row.Dir {
+			return trace.NotFound("%v is not found", k[len(k)-1])
+		}
+		var outVal interface{}
+		err = b.codec.DecodeFromBytes(row.Value, &outVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if outVal != prevVal {
+			return trace.BadParameter("%v: expected %v, but got %v", k[len(k)-1], prevVal, outVal)
+		}
+		deleted, err := b.compareAndDeleteRow(tx, k, row.Value)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !deleted {
+			return trace.CompareFailed("%q has been updated concurrently", k[len(k)-1])
+		}
+		return nil
+	})
+}
+
+func (b *sqlEngine) deleteKey(k key) error {
+	return b.withTx(func(tx *sqlx.Tx) error {
+		row, err := b.getRow(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if row.Dir {
+			return trace.NotFound("%v is not found", k[len(k)-1])
+		}
+		return trace.Wrap(b.deleteRow(tx, k))
+	})
+}
+
+func (b *sqlEngine) acquireLock(token key, ttl time.Duration) error {
+	for {
+		err := b.tryAcquireLock(token, ttl)
+		if err != nil {
+			if !trace.IsCompareFailed(err) && !trace.IsAlreadyExists(err) {
+				return trace.Wrap(err)
+			}
+			time.Sleep(delayBetweenLockAttempts)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (b *sqlEngine) tryAcquireLock(key key, ttl time.Duration) error {
+	return b.createVal(key, "locked", ttl)
+}
+
+func (b *sqlEngine) releaseLock(key key) error {
+	return b.deleteKey(key)
+}
+
+func (b *sqlEngine) getKeys(k key) ([]string, error) {
+	out := []string{}
+	err := b.db.Select(&out, b.db.Rebind(
+		`SELECT name FROM kv WHERE parent = ? AND (expires = 0 OR expires > ?)`),
+		encodePath(k), b.clock.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, trace.Wrap(convertSQLErr(err))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Close closes the database
+func (b *sqlEngine) Close() error {
+	return trace.Wrap(b.db.Close())
+}
+
+// withTx executes fn in a transaction which is committed if fn succeeds
+// and rolled back otherwise
+func (b *sqlEngine) withTx(fn func(tx *sqlx.Tx) error) error {
+	tx, err := b.db.Beginx()
+	if err != nil {
+		return trace.Wrap(convertSQLErr(err))
+	}
+	if err := fn(tx); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			b.WithError(errRollback).Warn("Failed to roll back transaction.")
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(convertSQLErr(tx.Commit()))
+}
+
+// getRow returns the row for the specified key.
+// Expired rows are removed and reported as not found
+func (b *sqlEngine) getRow(tx *sqlx.Tx, k key) (*sqlRow, error) {
+	var row sqlRow
+	err := tx.Get(&row, tx.Rebind(`SELECT dir, value, expires FROM kv WHERE path = ?`), encodePath(k))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, trace.NotFound("%q not found", k[len(k)-1])
+		}
+		return nil, trace.Wrap(convertSQLErr(err))
+	}
+	if row.Expires != 0 && row.Expires <= b.clock.Now().UTC().UnixNano() {
+		if err := b.deleteRow(tx, k); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return nil, trace.NotFound("%q not found", k[len(k)-1])
+	}
+	return &row, nil
+}
+
+// upsertParents creates all missing parent directories of the specified key
+func (b *sqlEngine) upsertParents(tx *sqlx.Tx, k key) error {
+	for i := 1; i < len(k); i++ {
+		if err := b.upsertDirRow(tx, k[:i]); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// upsertDirRow creates the directory row for the specified key if it does not exist
+func (b *sqlEngine) upsertDirRow(tx *sqlx.Tx, k key) error {
+	_, err := tx.Exec(tx.Rebind(`INSERT INTO kv (path, parent, name, dir, value, expires)
+		VALUES (?, ?, ?, ?, NULL, 0) ON CONFLICT (path) DO NOTHING`),
+		encodePath(k), encodePath(k[:len(k)-1]), k[len(k)-1], true)
+	return trace.Wrap(convertSQLErr(err))
+}
+
+func (b *sqlEngine) insertRow(tx *sqlx.Tx, k key, dir bool, data []byte, ttl time.Duration) error {
+	_, err := tx.Exec(tx.Rebind(`INSERT INTO kv (path, parent, name, dir, value, expires)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		encodePath(k), encodePath(k[:len(k)-1]), k[len(k)-1], dir, data, b.expires(ttl))
+	return trace.Wrap(convertSQLErr(err))
+}
+
+func (b *sqlEngine) updateRow(tx *sqlx.Tx, k key, data []byte, ttl time.Duration) error {
+	_, err := tx.Exec(tx.Rebind(`UPDATE kv SET value = ?, expires = ? WHERE path = ?`),
+		data, b.expires(ttl), encodePath(k))
+	return trace.Wrap(convertSQLErr(err))
+}
+
+// compareAndUpdateRow updates the value of the specified key only if it is
+// set to prevVal. Returns false if no row has been updated
+func (b *sqlEngine) compareAndUpdateRow(tx *sqlx.Tx, k key, data, prevVal []byte, ttl time.Duration) (bool, error) {
+	result, err := tx.Exec(tx.Rebind(`UPDATE kv SET value = ?, expires = ? WHERE path = ? AND value = ?`),
+		data, b.expires(ttl), encodePath(k), prevVal)
+	if err != nil {
+		return false, trace.Wrap(convertSQLErr(err))
+	}
+	return rowsAffected(result)
+}
+
+// compareAndDeleteRow deletes the specified key only if it is set to prevVal.
+// Returns false if no row has been deleted
+func (b *sqlEngine) compareAndDeleteRow(tx *sqlx.Tx, k key, prevVal []byte) (bool, error) {
+	result, err := tx.Exec(tx.Rebind(`DELETE FROM kv WHERE path = ? AND value = ?`),
+		encodePath(k), prevVal)
+	if err != nil {
+		return false, trace.Wrap(convertSQLErr(err))
+	}
+	return rowsAffected(result)
+}
+
+func rowsAffected(result sql.Result) (bool, error) {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, trace.Wrap(convertSQLErr(err))
+	}
+	return count != 0, nil
+}
+
+func (b *sqlEngine) deleteRow(tx *sqlx.Tx, k key) error {
+	_, err := tx.Exec(tx.Rebind(`DELETE FROM kv WHERE path = ?`), encodePath(k))
+	return trace.Wrap(convertSQLErr(err))
+}
+
+// expires returns the expiration time for the specified TTL
+// in nanoseconds or 0 if the key does not expire
+func (b *sqlEngine) expires(ttl time.Duration) int64 {
+	if ttl == forever {
+		return 0
+	}
+	return b.clock.Now().UTC().Add(ttl).UnixNano()
+}
+
+type sqlRow struct {
+	Dir     bool   `db:"dir"`
+	Value   []byte `db:"value"`
+	Expires int64  `db:"expires"`
+}
+
+// encodePath returns the path of the specified key.
+// Key elements are escaped so the path can be split unambiguously
+func encodePath(k key) string {
+	elements := make([]string, 0, len(k))
+	for _, element := range k {
+		elements = append(elements, url.PathEscape(element))
+	}
+	return strings.Join(elements, pathSeparator)
+}
+
+// convertSQLErr converts database errors to trace errors
+func convertSQLErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch e := err.(type) {
+	case sqlite3.Error:
+		if e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || e.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return trace.AlreadyExists("%v", e)
+		}
+		if e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked {
+			return trace.ConnectionProblem(e, "database is locked")
+		}
+	case *pq.Error:
+		if e.Code.Name() == "unique_violation" {
+			return trace.AlreadyExists("%v", e)
+		}
+	}
+	return err
+}
+
+const (
+	// SQLDriverSQLite is the name of the sqlite database driver
+	SQLDriverSQLite = "sqlite3"
+	// SQLDriverPostgres is the name of the PostgreSQL database driver
+	SQLDriverPostgres = "postgres"
+
+	pathSeparator = "/"
+)
@@ -0,0 +1,282 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/suite"
+
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+// SQLSuite runs the storage suite against sqlite or,
+// if enabled, against PostgreSQL
+type SQLSuite struct {
+	backend *SQLBackend
+	clock   clockwork.FakeClock
+	suite   suite.StorageSuite
+}
+
+var _ = Suite(&SQLSuite{})
+
+func (s *SQLSuite) SetUpTest(c *C) {
+	s.clock = clockwork.NewFakeClock()
+	cfg := SQLConfig{
+		Driver:     SQLDriverSQLite,
+		DataSource: filepath.Join(c.MkDir(), "gravity.sqlite"),
+		Clock:      s.clock,
+	}
+	if ok, _ := strconv.ParseBool(os.Getenv(defaults.TestPostgres)); ok {
+		cfg.Driver = SQLDriverPostgres
+		cfg.DataSource = os.Getenv(defaults.TestPostgresURI)
+	}
+	var err error
+	s.backend, err = NewSQL(cfg)
+	c.Assert(err, IsNil)
+	s.suite.Backend = s.backend
+	s.suite.Clock = s.clock
+}
+
+func (s *SQLSuite) TearDownTest(c *C) {
+	if s.backend == nil {
+		return
+	}
+	if s.backend.engine.db.DriverName() == SQLDriverPostgres {
+		for _, table := range []string{"kv", "operations", "progress_entries"} {
+			_, err := s.backend.engine.db.Exec("DROP TABLE " + table)
+			c.Assert(err, IsNil)
+		}
+	}
+	c.Assert(s.backend.Close(), IsNil)
+}
+
+func (s *SQLSuite) TestExpiresKeys(c *C) {
+	err := s.backend.createValBytes(s.backend.key("test", "key"), []byte("value"), time.Minute)
+	c.Assert(err, IsNil)
+	keys, err := s.backend.getKeys(s.backend.key("test"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{"key"})
+
+	s.clock.Advance(2 * time.Minute)
+	keys, err = s.backend.getKeys(s.backend.key("test"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{})
+	_, err = s.backend.getValBytes(s.backend.key("test", "key"))
+	c.Assert(err, NotNil)
+	err = s.backend.createValBytes(s.backend.key("test", "key"), []byte("value"), forever)
+	c.Assert(err, IsNil)
+}
+
+func (s *SQLSuite) TestEscapesKeys(c *C) {
+	err := s.backend.upsertValBytes(s.backend.key("test", "a/b", "c"), []byte("1"), forever)
+	c.Assert(err, IsNil)
+	err = s.backend.upsertValBytes(s.backend.key("test", "a", "b", "c"), []byte("2"), forever)
+	c.Assert(err, IsNil)
+	keys, err := s.backend.getKeys(s.backend.key("test"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{"a", "a/b"})
+
+	err = s.backend.deleteDir(s.backend.key("test", "a"))
+	c.Assert(err, IsNil)
+	value, err := s.backend.getValBytes(s.backend.key("test", "a/b", "c"))
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "1")
+}
+
+func (s *SQLSuite) TestImportsBolt(c *C) {
+	bolt, err := newTempBolt()
+	c.Assert(err, IsNil)
+	defer bolt.Delete()
+	src := suite.StorageSuite{Backend: bolt.backend, Clock: bolt.clock}
+	src.OperationsQuery(c)
+	expected, err := bolt.backend.QuerySiteOperations(storage.OperationsFilter{})
+	c.Assert(err, IsNil)
+	c.Assert(bolt.backend.Close(), IsNil)
+	bolt.backend = nil
+
+	err = s.backend.ImportBolt(filepath.Join(bolt.dir, "bolt.db"))
+	c.Assert(err, IsNil)
+	operations, err := s.backend.QuerySiteOperations(storage.OperationsFilter{})
+	c.Assert(err, IsNil)
+	c.Assert(operations, DeepEquals, expected)
+	operations, err = s.backend.QuerySiteOperations(storage.OperationsFilter{States: []string{"failed"}})
+	c.Assert(err, IsNil)
+	c.Assert(operations, DeepEquals, []storage.SiteOperation{expected[0]})
+}
+
+func (s *SQLSuite) TestAccountsCRUD(c *C) {
+	s.suite.AccountsCRUD(c)
+}
+
+func (s *SQLSuite) TestRepositoriesCRUD(c *C) {
+	s.suite.RepositoriesCRUD(c)
+}
+
+func (s *SQLSuite) TestSitesCRUD(c *C) {
+	s.suite.SitesCRUD(c)
+}
+
+func (s *SQLSuite) TestProgressEntriesCRUD(c *C) {
+	s.suite.ProgressEntriesCRUD(c)
+}
+
+func (s *SQLSuite) TestConnectorsCRUD(c *C) {
+	s.suite.ConnectorsCRUD(c)
+}
+
+func (s *SQLSuite) TestUsersCRUD(c *C) {
+	s.suite.UsersCRUD(c)
+}
+
+func (s *SQLSuite) TestUserTokensCRUD(c *C) {
+	s.suite.UserTokensCRUD(c)
+}
+
+func (s *SQLSuite) TestProvisioningTokensCRUD(c *C) {
+	s.suite.ProvisioningTokensCRUD(c)
+}
+
+func (s *SQLSuite) TestAPIKeys(c *C) {
+	s.suite.APIKeysCRUD(c)
+}
+
+func (s *SQLSuite) TestUserInvites(c *C) {
+	s.suite.UserInvitesCRUD(c)
+}
+
+func (s *SQLSuite) TestLoginEntriesCRUD(c *C) {
+	s.suite.LoginEntriesCRUD(c)
+}
+
+func (s *SQLSuite) TestPermissionsCRUD(c *C) {
+	s.suite.PermissionsCRUD(c)
+}
+
+func (s *SQLSuite) TestOperationsCRUD(c *C) {
+	s.suite.OperationsCRUD(c)
+}
+
+func (s *SQLSuite) TestOperationsQuery(c *C) {
+	s.suite.OperationsQuery(c)
+}
+
+func (s *SQLSuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
+func (s *SQLSuite) TestOperationQueueCRUD(c *C) {
+	s.suite.OperationQueueCRUD(c)
+}
+
+func (s *SQLSuite) TestConcurrentCompareAndSwap(c *C) {
+	s.suite.ConcurrentCompareAndSwap(c)
+}
+
+func (s *SQLSuite) TestCreatesApplication(c *C) {
+	s.suite.CreatesApplication(c)
+}
+
+func (s *SQLSuite) TestDeletesApplication(c *C) {
+	s.suite.DeletesApplication(c)
+}
+
+func (s *SQLSuite) TestRetrievesApplications(c *C) {
+	s.suite.RetrievesApplications(c)
+}
+
+func (s *SQLSuite) TestCreatesAppImportOperation(c *C) {
+	s.suite.CreatesAppImportOperation(c)
+}
+
+func (s *SQLSuite) TestUpdatesAppImportOperation(c *C) {
+	s.suite.UpdatesAppImportOperation(c)
+}
+
+func (s *SQLSuite) TestWebSessions(c *C) {
+	s.suite.WebSessionsCRUD(c)
+}
+
+func (s *SQLSuite) TestAuthoritiesCRUD(c *C) {
+	s.suite.AuthoritiesCRUD(c)
+}
+
+func (s *SQLSuite) TestNodesCRUD(c *C) {
+	s.suite.NodesCRUD(c)
+}
+
+func (s *SQLSuite) TestReverseTunnelsCRUD(c *C) {
+	s.suite.ReverseTunnelsCRUD(c)
+}
+
+func (s *SQLSuite) TestLocksCRUD(c *C) {
+	s.suite.LocksCRUD(c)
+}
+
+func (s *SQLSuite) TestPeersCRUD(c *C) {
+	s.suite.PeersCRUD(c)
+}
+
+func (s *SQLSuite) TestObjectsCRUD(c *C) {
+	s.suite.ObjectsCRUD(c)
+}
+
+func (s *SQLSuite) TestChangesetsCRUD(c *C) {
+	s.suite.ChangesetsCRUD(c)
+}
+
+func (s *SQLSuite) TestOpsCenterLinksCRUD(c *C) {
+	s.suite.OpsCenterLinksCRUD(c)
+}
+
+func (s *SQLSuite) TestRolesCRUD(c *C) {
+	s.suite.RolesCRUD(c)
+}
+
+func (s *SQLSuite) TestNamespacesCRUD(c *C) {
+	s.suite.NamespacesCRUD(c)
+}
+
+func (s *SQLSuite) TestLoginAttempts(c *C) {
+	s.suite.LoginAttempts(c)
+}
+
+func (s *SQLSuite) TestLocalCluster(c *C) {
+	s.suite.LocalCluster(c)
+}
+
+func (s *SQLSuite) TestSAMLCRUD(c *C) {
+	s.suite.SAMLCRUD(c)
+}
+
+func (s *SQLSuite) TestClusterAgentCreds(c *C) {
+	s.suite.ClusterAgentCreds(c)
+}
+
+func (s *SQLSuite) TestClusterLogin(c *C) {
+	s.suite.ClusterLogin(c)
+}
+
+func (s *SQLSuite) TestIndexFile(c *C) {
+	s.suite.IndexFile(c)
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/boltdb/bolt"
+	"github.com/gravitational/trace"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewSQL returns a new backend on top of the relational database
+// described with the provided configuration
+func NewSQL(cfg SQLConfig) (*SQLBackend, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	engine, err := newSQLEngine(cfg, &v1codec{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &SQLBackend{
+		backend: &backend{
+			Clock:    cfg.Clock,
+			kvengine: newNotifyingEngine(engine),
+		},
+		engine: engine,
+	}, nil
+}
+
+// SQLBackend is the storage backend on top of a relational database.
+//
+// Besides the key/value data, it maintains indexes of operations and
+// progress entries so they can be queried without scanning all clusters.
+// The indexes only reference the key/value data and can be rebuilt from it
+type SQLBackend struct {
+	*backend
+	engine *sqlEngine
+}
+
+// CreateSiteOperation creates a new site operation
+func (b *SQLBackend) CreateSiteOperation(op storage.SiteOperation) (*storage.SiteOperation, error) {
+	created, err := b.backend.CreateSiteOperation(op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := b.indexOperation(b.engine.db, *created); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return created, nil
+}
+
+// UpdateSiteOperation updates site operation state
+func (b *SQLBackend) UpdateSiteOperation(op storage.SiteOperation) (*storage.SiteOperation, error) {
+	updated, err := b.backend.UpdateSiteOperation(op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := b.indexOperation(b.engine.db, *updated); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return updated, nil
+}
+
+// DeleteSiteOperation deletes an inactive site operation
+func (b *SQLBackend) DeleteSiteOperation(siteDomain, operationID string) error {
+	if err := b.backend.DeleteSiteOperation(siteDomain, operationID); err != nil {
+		return trace.Wrap(err)
+	}
+	db := b.engine.db
+	_, err := db.Exec(db.Rebind(`DELETE FROM operations WHERE cluster = ? AND id = ?`),
+		siteDomain, operationID)
+	if err != nil {
+		return trace.Wrap(convertSQLErr(err))
+	}
+	_, err = db.Exec(db.Rebind(`DELETE FROM progress_entries WHERE cluster = ? AND operation = ?`),
+		siteDomain, operationID)
+	return trace.Wrap(convertSQLErr(err))
+}
+
+// GetSiteOperations returns a list of operations performed on this
+// site sorted by time (latest operations come first)
+func (b *SQLBackend) GetSiteOperations(siteDomain string) ([]storage.SiteOperation, error) {
+	if siteDomain == "" {
+		return nil, trace.BadParameter("missing parameter SiteDomain")
+	}
+	return b.QuerySiteOperations(storage.OperationsFilter{SiteDomain: siteDomain})
+}
+
+// QuerySiteOperations returns operations matching the specified filter
+// sorted by time (latest operations come first)
+func (b *SQLBackend) QuerySiteOperations(filter storage.OperationsFilter) ([]storage.SiteOperation, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.SiteDomain != "" {
+		conditions = append(conditions, "cluster = ?")
+		args = append(args, filter.SiteDomain)
+	}
+	if len(filter.Types) != 0 {
+		conditions = append(conditions, "type IN (?)")
+		args = append(args, filter.Types)
+	}
+	if len(filter.States) != 0 {
+		conditions = append(conditions, "state IN (?)")
+		args = append(args, filter.States)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created > ?")
+		args = append(args, filter.CreatedAfter.UTC().UnixNano())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created < ?")
+		args = append(args, filter.CreatedBefore.UTC().UnixNano())
+	}
+	query := `SELECT cluster, id FROM operations`
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var refs []operationRef
+	db := b.engine.db
+	if err := db.Select(&refs, db.Rebind(query), args...); err != nil {
+		return nil, trace.Wrap(convertSQLErr(err))
+	}
+	var out []storage.SiteOperation
+	for _, ref := range refs {
+		op, err := b.backend.GetSiteOperation(ref.Cluster, ref.ID)
+		if err != nil {
+			if !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+			// the operation has been removed with its cluster
+			continue
+		}
+		out = append(out, *op)
+	}
+	return out, nil
+}
+
+// CreateProgressEntry creates a new progress entry for an operation
+func (b *SQLBackend) CreateProgressEntry(p storage.ProgressEntry) (*storage.ProgressEntry, error) {
+	created, err := b.backend.CreateProgressEntry(p)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := b.indexProgressEntry(b.engine.db, *created); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return created, nil
+}
+
+// GetLastProgressEntry returns the latest progress entry for the specified operation
+func (b *SQLBackend) GetLastProgressEntry(siteDomain, operationID string) (*storage.ProgressEntry, error) {
+	if siteDomain == "" {
+		return nil, trace.BadParameter("missing site domain")
+	}
+	if operationID == "" {
+		return nil, trace.BadParameter("missing operation id")
+	}
+	var ids []string
+	db := b.engine.db
+	err := db.Select(&ids, db.Rebind(`SELECT id FROM progress_entries
+		WHERE cluster = ? AND operation = ? ORDER BY created DESC LIMIT 1`),
+		siteDomain, operationID)
+	if err != nil {
+		return nil, trace.Wrap(convertSQLErr(err))
+	}
+	if len(ids) == 0 {
+		return nil, trace.NotFound("no progress entries for %v %v found", siteDomain, operationID)
+	}
+	var entry storage.ProgressEntry
+	err = b.getVal(b.key(sitesP, siteDomain, operationsP, operationID, progressP, ids[0]), &entry)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &entry, nil
+}
+
+// DeleteSite deletes the cluster with all its operations
+func (b *SQLBackend) DeleteSite(domain string) error {
+	if err := b.backend.DeleteSite(domain); err != nil {
+		return trace.Wrap(err)
+	}
+	db := b.engine.db
+	_, err := db.Exec(db.Rebind(`DELETE FROM operations WHERE cluster = ?`), domain)
+	if err != nil {
+		return trace.Wrap(convertSQLErr(err))
+	}
+	_, err = db.Exec(db.Rebind(`DELETE FROM progress_entries WHERE cluster = ?`), domain)
+	return trace.Wrap(convertSQLErr(err))
+}
+
+// ImportBolt copies the contents of the bolt database at the specified path
+// into this backend and rebuilds the indexes.
+// Existing keys are overwritten
+func (b *SQLBackend) ImportBolt(path string) error {
+	cfg := BoltConfig{Path: path, Readonly: true}
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	src, err := newBolt(cfg, &v1codec{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer src.Close()
+	var count int
+	err = src.walk(func(k key, value []byte) error {
+		if value == nil {
+			return trace.Wrap(b.engine.upsertDir(k, forever))
+		}
+		count++
+		return trace.Wrap(b.engine.upsertValBytes(k, value, forever))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.engine.WithField("path", path).Infof("Imported %v keys.", count)
+	return trace.Wrap(b.Reindex())
+}
+
+// Reindex rebuilds the indexes of operations and progress entries
+// from the key/value data
+func (b *SQLBackend) Reindex() error {
+	sites, err := b.GetAllSites()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var operations []storage.SiteOperation
+	var entries []storage.ProgressEntry
+	for _, site := range sites {
+		siteOperations, err := b.backend.GetSiteOperations(site.Domain)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, op := range siteOperations {
+			opEntries, err := b.getProgressEntries(op)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			entries = append(entries, opEntries...)
+		}
+		operations = append(operations, siteOperations...)
+	}
+	return b.engine.withTx(func(tx *sqlx.Tx) error {
+		for _, table := range []string{"operations", "progress_entries"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return trace.Wrap(convertSQLErr(err))
+			}
+		}
+		for _, op := range operations {
+			if err := b.indexOperation(tx, op); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		for _, entry := range entries {
+			if err := b.indexProgressEntry(tx, entry); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (b *SQLBackend) getProgressEntries(op storage.SiteOperation) ([]storage.ProgressEntry, error) {
+	ids, err := b.getKeys(b.key(sitesP, op.SiteDomain, operationsP, op.ID, progressP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var entries []storage.ProgressEntry
+	for _, id := range ids {
+		var entry storage.ProgressEntry
+		err := b.getVal(b.key(sitesP, op.SiteDomain, operationsP, op.ID, progressP, id), &entry)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (b *SQLBackend) indexOperation(db sqlx.Execer, op storage.SiteOperation) error {
+	_, err := db.Exec(b.engine.db.Rebind(`INSERT INTO operations (cluster, id, type, state, created)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (cluster, id) DO UPDATE SET type = excluded.type, state = excluded.state`),
+		op.SiteDomain, op.ID, op.Type, op.State, op.Created.UTC().UnixNano())
+	return trace.Wrap(convertSQLErr(err))
+}
+
+func (b *SQLBackend) indexProgressEntry(db sqlx.Execer, entry storage.ProgressEntry) error {
+	_, err := db.Exec(b.engine.db.Rebind(`INSERT INTO progress_entries (cluster, operation, id, created)
+		VALUES (?, ?, ?, ?) ON CONFLICT (cluster, operation, id) DO NOTHING`),
+		entry.SiteDomain, entry.OperationID, entry.ID, entry.Created.UTC().UnixNano())
+	return trace.Wrap(convertSQLErr(err))
+}
+
+type operationRef struct {
+	Cluster string `db:"cluster"`
+	ID      string `db:"id"`
+}
+
+// walk calls fn for every key in the database in depth-first order.
+// Buckets are reported with nil values before their contents
+func (b *blt) walk(fn func(k key, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bkt *bolt.Bucket) error {
+			return walkBucket(key{string(name)}, bkt, fn)
+		})
+	})
+}
+
+func walkBucket(prefix key, bkt *bolt.Bucket, fn func(k key, value []byte) error) error {
+	if err := fn(prefix, nil); err != nil {
+		return trace.Wrap(err)
+	}
+	return bkt.ForEach(func(name, value []byte) error {
+		k := append(append(key{}, prefix...), string(name))
+		if value == nil {
+			return walkBucket(k, bkt.Bucket(name), fn)
+		}
+		return fn(k, value)
+	})
+}
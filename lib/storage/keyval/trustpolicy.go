@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// GetTrustPolicy returns the cluster trust policy
+func (b *backend) GetTrustPolicy() (storage.TrustPolicy, error) {
+	data, err := b.getValBytes(b.key(systemP, trustPolicyP))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("trust policy not found")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalTrustPolicy(data)
+}
+
+// UpsertTrustPolicy creates or updates the cluster trust policy
+func (b *backend) UpsertTrustPolicy(policy storage.TrustPolicy) error {
+	if err := policy.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := storage.MarshalTrustPolicy(policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(systemP, trustPolicyP), data, forever)
+	return trace.Wrap(err)
+}
+
+// DeleteTrustPolicy deletes the cluster trust policy
+func (b *backend) DeleteTrustPolicy() error {
+	err := b.deleteKey(b.key(systemP, trustPolicyP))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("trust policy not found")
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
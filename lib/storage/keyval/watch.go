@@ -0,0 +1,535 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/boltdb/bolt"
+	"github.com/coreos/etcd/client"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// Watch returns a channel that receives events about changes to the items
+// with keys under the specified prefix until the context is cancelled
+func (b *backend) Watch(ctx context.Context, prefix []string) (<-chan storage.WatchEvent, error) {
+	if len(prefix) == 0 {
+		return nil, trace.BadParameter("missing watch prefix")
+	}
+	watcher, ok := b.kvengine.(watcher)
+	if !ok {
+		return nil, trace.NotImplemented("%T does not support watches", b.kvengine)
+	}
+	return watcher.watch(ctx, prefix)
+}
+
+// watcher is implemented by engines that can watch for changes
+type watcher interface {
+	// watch returns a channel of events about changes to the items
+	// under the specified prefix relative to the engine root key
+	watch(ctx context.Context, prefix key) (<-chan storage.WatchEvent, error)
+}
+
+// newNotifyingEngine returns a new engine that publishes events about
+// changes made through the specified engine to in-process watchers
+func newNotifyingEngine(engine kvengine) *notifyingEngine {
+	return &notifyingEngine{
+		kvengine: engine,
+		bus:      newEventBus(),
+		rootLen:  len(engine.key("")) - 1,
+	}
+}
+
+// notifyingEngine implements watches for engines that do not support them
+// natively.
+// Only the changes made through this engine instance are observed
+type notifyingEngine struct {
+	kvengine
+	bus *eventBus
+	// rootLen is the number of elements in the engine root key
+	rootLen int
+}
+
+func (e *notifyingEngine) watch(ctx context.Context, prefix key) (<-chan storage.WatchEvent, error) {
+	return e.bus.subscribe(ctx, prefix), nil
+}
+
+func (e *notifyingEngine) createVal(k key, val interface{}, ttl time.Duration) error {
+	if err := e.kvengine.createVal(k, val, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publishVal(storage.WatchEventCreated, k, val)
+	return nil
+}
+
+func (e *notifyingEngine) createValBytes(k key, data []byte, ttl time.Duration) error {
+	if err := e.kvengine.createValBytes(k, data, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventCreated, k, data)
+	return nil
+}
+
+func (e *notifyingEngine) upsertVal(k key, val interface{}, ttl time.Duration) error {
+	eventType := e.upsertEventType(k)
+	if err := e.kvengine.upsertVal(k, val, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publishVal(eventType, k, val)
+	return nil
+}
+
+func (e *notifyingEngine) upsertValBytes(k key, data []byte, ttl time.Duration) error {
+	eventType := e.upsertEventType(k)
+	if err := e.kvengine.upsertValBytes(k, data, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(eventType, k, data)
+	return nil
+}
+
+func (e *notifyingEngine) updateVal(k key, val interface{}, ttl time.Duration) error {
+	if err := e.kvengine.updateVal(k, val, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publishVal(storage.WatchEventUpdated, k, val)
+	return nil
+}
+
+func (e *notifyingEngine) updateValBytes(k key, data []byte, ttl time.Duration) error {
+	if err := e.kvengine.updateValBytes(k, data, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventUpdated, k, data)
+	return nil
+}
+
+func (e *notifyingEngine) compareAndSwap(k key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	if err := e.kvengine.compareAndSwap(k, val, prevVal, outVal, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal == nil {
+		e.publishVal(storage.WatchEventCreated, k, val)
+	} else {
+		e.publishVal(storage.WatchEventUpdated, k, val)
+	}
+	return nil
+}
+
+func (e *notifyingEngine) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	if err := e.kvengine.compareAndSwapBytes(k, val, prevVal, outVal, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal == nil {
+		e.publish(storage.WatchEventCreated, k, val)
+	} else {
+		e.publish(storage.WatchEventUpdated, k, val)
+	}
+	return nil
+}
+
+func (e *notifyingEngine) deleteKey(k key) error {
+	if err := e.kvengine.deleteKey(k); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventDeleted, k, nil)
+	return nil
+}
+
+func (e *notifyingEngine) compareAndDelete(k key, prevVal interface{}) error {
+	if err := e.kvengine.compareAndDelete(k, prevVal); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventDeleted, k, nil)
+	return nil
+}
+
+func (e *notifyingEngine) deleteDir(k key) error {
+	if err := e.kvengine.deleteDir(k); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventDeleted, k, nil)
+	return nil
+}
+
+func (e *notifyingEngine) acquireLock(token key, ttl time.Duration) error {
+	if err := e.kvengine.acquireLock(token, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventCreated, token, nil)
+	return nil
+}
+
+func (e *notifyingEngine) tryAcquireLock(token key, ttl time.Duration) error {
+	if err := e.kvengine.tryAcquireLock(token, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventCreated, token, nil)
+	return nil
+}
+
+func (e *notifyingEngine) releaseLock(token key) error {
+	if err := e.kvengine.releaseLock(token); err != nil {
+		return trace.Wrap(err)
+	}
+	e.publish(storage.WatchEventDeleted, token, nil)
+	return nil
+}
+
+// upsertEventType returns the type of event for the upsert of the specified key
+func (e *notifyingEngine) upsertEventType(k key) storage.WatchEventType {
+	if !e.bus.hasSubscribers() {
+		return storage.WatchEventUpdated
+	}
+	if _, err := e.kvengine.getValBytes(k); trace.IsNotFound(err) {
+		return storage.WatchEventCreated
+	}
+	return storage.WatchEventUpdated
+}
+
+func (e *notifyingEngine) publishVal(eventType storage.WatchEventType, k key, val interface{}) {
+	if !e.bus.hasSubscribers() {
+		return
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to encode value of %v.", k)
+	}
+	e.publish(eventType, k, data)
+}
+
+func (e *notifyingEngine) publish(eventType storage.WatchEventType, k key, data []byte) {
+	e.bus.publish(storage.WatchEvent{
+		Type:  eventType,
+		Key:   append(key{}, k[e.rootLen:]...),
+		Value: data,
+	})
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// eventBus delivers events to in-process subscribers
+type eventBus struct {
+	sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	prefix  key
+	eventsC chan storage.WatchEvent
+}
+
+// subscribe returns a channel that receives events for keys under prefix
+// until the context is cancelled
+func (b *eventBus) subscribe(ctx context.Context, prefix key) <-chan storage.WatchEvent {
+	s := &subscriber{
+		prefix:  prefix,
+		eventsC: make(chan storage.WatchEvent, defaults.WatchBufferSize),
+	}
+	b.Lock()
+	b.subscribers[s] = struct{}{}
+	b.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(s)
+	}()
+	return s.eventsC
+}
+
+func (b *eventBus) unsubscribe(s *subscriber) {
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.eventsC)
+	}
+}
+
+func (b *eventBus) hasSubscribers() bool {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.subscribers) != 0
+}
+
+// publish delivers the event to all interested subscribers.
+// Subscribers that cannot keep up with events are dropped
+// to avoid blocking the writers
+func (b *eventBus) publish(event storage.WatchEvent) {
+	b.Lock()
+	defer b.Unlock()
+	for s := range b.subscribers {
+		if !watchMatches(s.prefix, event.Key) {
+			continue
+		}
+		select {
+		case s.eventsC <- event:
+		default:
+			log.Warnf("Watcher for %v is too slow, closing.", s.prefix)
+			delete(b.subscribers, s)
+			close(s.eventsC)
+		}
+	}
+}
+
+// watchMatches returns true if the event for the specified key should be
+// delivered to a watcher with the given prefix: either the key is under
+// the prefix, or the key is a deleted directory that contains the prefix
+func watchMatches(prefix, k key) bool {
+	return hasKeyPrefix(k, prefix) || hasKeyPrefix(prefix, k)
+}
+
+func hasKeyPrefix(k, prefix key) bool {
+	if len(prefix) > len(k) {
+		return false
+	}
+	for i := range prefix {
+		if k[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// watch returns a channel of events about changes to the items under
+// the specified prefix.
+// Since the database can be modified by other processes, it is checked
+// for modifications periodically and the changes are computed by comparing
+// the contents of the prefix with the previous state
+func (b *multiBolt) watch(ctx context.Context, prefix key) (<-chan storage.WatchEvent, error) {
+	modified, err := b.modified()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	items, err := b.snapshot(prefix)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	eventsC := make(chan storage.WatchEvent, defaults.WatchBufferSize)
+	go func() {
+		defer close(eventsC)
+		ticker := time.NewTicker(defaults.WatchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			lastModified, err := b.modified()
+			if err != nil {
+				log.WithError(err).Warn("Failed to check database for changes.")
+				return
+			}
+			if lastModified.Equal(modified) {
+				continue
+			}
+			modified = lastModified
+			newItems, err := b.snapshot(prefix)
+			if err != nil {
+				log.WithError(err).Warn("Failed to read database changes.")
+				return
+			}
+			for _, event := range diffSnapshots(items, newItems) {
+				select {
+				case eventsC <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			items = newItems
+		}
+	}()
+	return eventsC, nil
+}
+
+func (b *multiBolt) modified() (time.Time, error) {
+	fi, err := os.Stat(b.cfg.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, trace.ConvertSystemError(err)
+	}
+	return fi.ModTime(), nil
+}
+
+func (b *multiBolt) snapshot(prefix key) (items map[string]rawItem, err error) {
+	err = b.withBolt(func(b *blt) error {
+		items, err = b.snapshot(prefix)
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return items, nil
+}
+
+// snapshot returns all values stored under the specified prefix
+func (b *blt) snapshot(prefix key) (map[string]rawItem, error) {
+	items := make(map[string]rawItem)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		buckets := b.key(prefix[0], prefix[1:]...)
+		bkt, err := getBucket(tx, buckets)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		return walkBucket(buckets, bkt, func(k key, value []byte) error {
+			if value == nil {
+				return nil
+			}
+			item := rawItem{
+				key:   k[1:],
+				value: append([]byte{}, value...),
+			}
+			items[item.path()] = item
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return items, nil
+}
+
+// diffSnapshots returns events that describe the changes between
+// the specified database snapshots
+func diffSnapshots(prev, next map[string]rawItem) (events []storage.WatchEvent) {
+	for path, item := range next {
+		prevItem, ok := prev[path]
+		switch {
+		case !ok:
+			events = append(events, storage.WatchEvent{
+				Type:  storage.WatchEventCreated,
+				Key:   item.key,
+				Value: item.value,
+			})
+		case !prevItem.equals(item):
+			events = append(events, storage.WatchEvent{
+				Type:  storage.WatchEventUpdated,
+				Key:   item.key,
+				Value: item.value,
+			})
+		}
+	}
+	for path, item := range prev {
+		if _, ok := next[path]; !ok {
+			events = append(events, storage.WatchEvent{
+				Type: storage.WatchEventDeleted,
+				Key:  item.key,
+			})
+		}
+	}
+	return events
+}
+
+// watch returns a channel of events about changes to the items
+// under the specified prefix using the native etcd watch
+func (e *engine) watch(ctx context.Context, prefix key) (<-chan storage.WatchEvent, error) {
+	watcher := e.Watcher(ekey(e.key(prefix[0], prefix[1:]...)), &client.WatcherOptions{Recursive: true})
+	eventsC := make(chan storage.WatchEvent, defaults.WatchBufferSize)
+	go func() {
+		defer close(eventsC)
+		for {
+			re, err := watcher.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.WithError(convertErr(err)).Warn("Etcd watch failed.")
+				}
+				return
+			}
+			event, err := e.newWatchEvent(re)
+			if err != nil {
+				log.WithError(err).Warnf("Failed to convert etcd event for %v.", re.Node.Key)
+				continue
+			}
+			if event == nil {
+				continue
+			}
+			select {
+			case eventsC <- *event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return eventsC, nil
+}
+
+// newWatchEvent returns the watch event for the specified etcd response
+// or nil if the response does not describe a change to a value
+func (e *engine) newWatchEvent(re *client.Response) (*storage.WatchEvent, error) {
+	k, err := e.relativeKey(re.Node.Key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	event := storage.WatchEvent{Key: k}
+	switch re.Action {
+	case "delete", "expire", "compareAndDelete":
+		event.Type = storage.WatchEventDeleted
+		return &event, nil
+	case "create":
+		event.Type = storage.WatchEventCreated
+	case "set":
+		event.Type = storage.WatchEventUpdated
+		if re.PrevNode == nil {
+			event.Type = storage.WatchEventCreated
+		}
+	case "update", "compareAndSwap":
+		event.Type = storage.WatchEventUpdated
+	default:
+		return nil, nil
+	}
+	if re.Node.Dir {
+		return nil, nil
+	}
+	event.Value, err = e.codec.DecodeBytesFromString(re.Node.Value)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &event, nil
+}
+
+// relativeKey returns the key relative to the engine root
+// for the specified etcd key
+func (e *engine) relativeKey(etcdKey string) (key, error) {
+	prefix := "/" + strings.Trim(ekey(e.etcdKey), "/") + "/"
+	path := "/" + strings.TrimPrefix(etcdKey, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return nil, trace.BadParameter("unexpected key %v outside of %v", etcdKey, prefix)
+	}
+	var k key
+	for _, element := range strings.Split(strings.TrimPrefix(path, prefix), "/") {
+		k = append(k, strings.Replace(element, "%2F", "/", -1))
+	}
+	return k, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+type WatchSuite struct{}
+
+var _ = Suite(&WatchSuite{})
+
+func (s *WatchSuite) TestWatchesSharedBolt(c *C) {
+	path := filepath.Join(c.MkDir(), "bolt.db")
+	watched, err := NewBolt(BoltConfig{Path: path, Multi: true})
+	c.Assert(err, IsNil)
+	defer watched.Close()
+	// the other backend simulates another process using the same database
+	other, err := NewBolt(BoltConfig{Path: path, Multi: true})
+	c.Assert(err, IsNil)
+	defer other.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventsC, err := watched.Watch(ctx, []string{loginsP})
+	c.Assert(err, IsNil)
+
+	entry := storage.LoginEntry{OpsCenterURL: "https://example.com", Password: "password"}
+	_, err = other.UpsertLoginEntry(entry)
+	c.Assert(err, IsNil)
+	event := receiveEvent(c, eventsC)
+	c.Assert(event.Type, Equals, storage.WatchEventCreated)
+	c.Assert(event.Key[0], Equals, loginsP)
+
+	err = other.DeleteLoginEntry(entry.OpsCenterURL)
+	c.Assert(err, IsNil)
+	event = receiveEvent(c, eventsC)
+	c.Assert(event.Type, Equals, storage.WatchEventDeleted)
+}
+
+func (s *WatchSuite) TestClosesSlowSubscribers(c *C) {
+	bus := newEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slowC := bus.subscribe(ctx, key{"sites"})
+	otherC := bus.subscribe(ctx, key{"users"})
+	for i := 0; i <= defaults.WatchBufferSize; i++ {
+		bus.publish(storage.WatchEvent{Type: storage.WatchEventCreated, Key: key{"sites", "example.com"}})
+	}
+	for i := 0; i < defaults.WatchBufferSize; i++ {
+		<-slowC
+	}
+	_, ok := <-slowC
+	c.Assert(ok, Equals, false, Commentf("expected slow subscriber to be closed"))
+	c.Assert(bus.hasSubscribers(), Equals, true)
+
+	cancel()
+	_, ok = <-otherC
+	c.Assert(ok, Equals, false)
+}
+
+func (s *WatchSuite) TestMatchesDeletedDirectories(c *C) {
+	prefix := key{"sites", "example.com", "ops", "1"}
+	c.Assert(watchMatches(prefix, key{"sites", "example.com", "ops", "1", "val"}), Equals, true)
+	c.Assert(watchMatches(prefix, key{"sites", "example.com"}), Equals, true)
+	c.Assert(watchMatches(prefix, key{"sites", "example.com", "ops", "2", "val"}), Equals, false)
+}
+
+func receiveEvent(c *C, eventsC <-chan storage.WatchEvent) storage.WatchEvent {
+	select {
+	case event, ok := <-eventsC:
+		c.Assert(ok, Equals, true, Commentf("watch closed unexpectedly"))
+		return event
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for watch event")
+	}
+	return storage.WatchEvent{}
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	teledefaults "github.com/gravitational/teleport/lib/defaults"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// MaintenanceWindow defines the recurring time windows during which
+// disruptive cluster operations (updates, runtime environment and
+// configuration changes, garbage collection) are allowed to start.
+// There is only a single instance of the resource in a cluster
+type MaintenanceWindow interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates this resource and sets defaults
+	CheckAndSetDefaults() error
+	// GetTimezone returns the name of the timezone the windows are defined in
+	GetTimezone() string
+	// GetWindows returns the list of configured windows
+	GetWindows() []Window
+	// IsOpen returns true if the specified time is within one of the windows
+	IsOpen(time.Time) bool
+	// NextOpen returns the time the next window opens after the specified time.
+	// Returns the specified time if it is within one of the windows
+	NextOpen(time.Time) time.Time
+}
+
+// NewMaintenanceWindow creates a new maintenance window resource from the provided spec
+func NewMaintenanceWindow(spec MaintenanceWindowSpecV2) MaintenanceWindow {
+	return &MaintenanceWindowV2{
+		Kind:    KindMaintenanceWindow,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindMaintenanceWindow,
+			Namespace: teledefaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// MaintenanceWindowV2 defines the maintenance window resource
+type MaintenanceWindowV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the maintenance windows
+	Spec MaintenanceWindowSpecV2 `json:"spec"`
+}
+
+// MaintenanceWindowSpecV2 defines the maintenance window specification
+type MaintenanceWindowSpecV2 struct {
+	// Timezone is the IANA name of the timezone the windows are defined in.
+	// Defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+	// Windows lists the recurring windows
+	Windows []Window `json:"windows"`
+}
+
+// Window is a single recurring maintenance window
+type Window struct {
+	// Days lists the days of week the window opens on, e.g. "sat".
+	// If empty, the window opens every day
+	Days []string `json:"days,omitempty"`
+	// Start is the time of day the window opens at in HH:MM format
+	Start string `json:"start"`
+	// Duration is how long the window stays open
+	Duration teleservices.Duration `json:"duration"`
+}
+
+// String returns a textual representation of this window
+func (r Window) String() string {
+	days := "daily"
+	if len(r.Days) != 0 {
+		days = strings.Join(r.Days, ",")
+	}
+	return fmt.Sprintf("%v %v for %v", days, r.Start, r.Duration.Duration)
+}
+
+// GetTimezone returns the name of the timezone the windows are defined in
+func (r *MaintenanceWindowV2) GetTimezone() string {
+	return r.Spec.Timezone
+}
+
+// GetWindows returns the list of configured windows
+func (r *MaintenanceWindowV2) GetWindows() []Window {
+	return r.Spec.Windows
+}
+
+// IsOpen returns true if the specified time is within one of the windows
+func (r *MaintenanceWindowV2) IsOpen(now time.Time) bool {
+	return !r.NextOpen(now).After(now)
+}
+
+// NextOpen returns the time the next window opens after the specified time.
+// Returns the specified time if it is within one of the windows
+func (r *MaintenanceWindowV2) NextOpen(now time.Time) time.Time {
+	location, err := r.location()
+	if err != nil {
+		// the resource has been validated so this should not happen
+		return time.Time{}
+	}
+	now = now.In(location)
+	var next time.Time
+	for _, window := range r.Spec.Windows {
+		start, err := window.nextOpen(now)
+		if err != nil {
+			continue
+		}
+		if start.Equal(now) {
+			return now
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// CheckAndSetDefaults validates this resource and sets defaults
+func (r *MaintenanceWindowV2) CheckAndSetDefaults() error {
+	r.Metadata.Name = KindMaintenanceWindow
+	if err := r.Metadata.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Spec.Timezone == "" {
+		r.Spec.Timezone = time.UTC.String()
+	}
+	if _, err := r.location(); err != nil {
+		return trace.Wrap(err)
+	}
+	if len(r.Spec.Windows) == 0 {
+		return trace.BadParameter("maintenance window should specify at least one window")
+	}
+	for _, window := range r.Spec.Windows {
+		if err := window.check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (r *MaintenanceWindowV2) location() (*time.Location, error) {
+	location, err := time.LoadLocation(r.Spec.Timezone)
+	if err != nil {
+		return nil, trace.BadParameter("invalid timezone %q: %v", r.Spec.Timezone, err)
+	}
+	return location, nil
+}
+
+func (r Window) check() error {
+	if _, _, err := r.startTime(); err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := r.weekdays(); err != nil {
+		return trace.Wrap(err)
+	}
+	duration := r.Duration.Duration
+	if duration <= 0 || duration > week {
+		return trace.BadParameter("window %v: duration should be positive and not exceed a week",
+			r.Start)
+	}
+	return nil
+}
+
+// nextOpen returns the time this window opens next after now
+// or now, if the window is open
+func (r Window) nextOpen(now time.Time) (time.Time, error) {
+	hour, minute, err := r.startTime()
+	if err != nil {
+		return time.Time{}, trace.Wrap(err)
+	}
+	weekdays, err := r.weekdays()
+	if err != nil {
+		return time.Time{}, trace.Wrap(err)
+	}
+	year, month, day := now.Date()
+	// windows that opened during the past week might still be open
+	for days := -7; days <= 7; days++ {
+		start := time.Date(year, month, day+days, hour, minute, 0, 0, now.Location())
+		if len(weekdays) != 0 && !weekdays[start.Weekday()] {
+			continue
+		}
+		end := start.Add(r.Duration.Duration)
+		if !now.Before(start) && now.Before(end) {
+			return now, nil
+		}
+		if start.After(now) {
+			return start, nil
+		}
+	}
+	return time.Time{}, trace.NotFound("window %v never opens", r)
+}
+
+// startTime returns the time of day the window opens at
+func (r Window) startTime() (hour, minute int, err error) {
+	start, err := time.Parse("15:04", r.Start)
+	if err != nil {
+		return 0, 0, trace.BadParameter("invalid window start %q, expected HH:MM", r.Start)
+	}
+	return start.Hour(), start.Minute(), nil
+}
+
+// weekdays returns the set of days of week this window opens on
+func (r Window) weekdays() (map[time.Weekday]bool, error) {
+	weekdays := make(map[time.Weekday]bool, len(r.Days))
+	for _, day := range r.Days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return nil, trace.BadParameter("invalid day of week %q", day)
+		}
+		weekdays[weekday] = true
+	}
+	return weekdays, nil
+}
+
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(day)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+// UnmarshalMaintenanceWindow unmarshals the maintenance window resource from YAML/JSON given with data
+func UnmarshalMaintenanceWindow(data []byte) (MaintenanceWindow, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch hdr.Version {
+	case teleservices.V2:
+		var window MaintenanceWindowV2
+		err := teleutils.UnmarshalWithSchema(GetMaintenanceWindowSchema(), &window, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		if err := window.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &window, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindMaintenanceWindow, hdr.Version)
+}
+
+// MarshalMaintenanceWindow marshals the maintenance window resource into JSON
+func MarshalMaintenanceWindow(window MaintenanceWindow, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(window)
+}
+
+// MaintenanceWindowSpecV2Schema is JSON schema for the maintenance window resource
+const MaintenanceWindowSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["windows"],
+  "properties": {
+    "timezone": {"type": "string"},
+    "windows": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["start", "duration"],
+        "properties": {
+          "days": {"type": "array", "items": {"type": "string"}},
+          "start": {"type": "string"},
+          "duration": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
+
+// GetMaintenanceWindowSchema returns the maintenance window resource schema for version V2
+func GetMaintenanceWindowSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		MaintenanceWindowSpecV2Schema, "")
+}
+
+const week = 7 * 24 * time.Hour
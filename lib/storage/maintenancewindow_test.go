@@ -0,0 +1,103 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	. "gopkg.in/check.v1"
+)
+
+type MaintenanceWindowSuite struct{}
+
+var _ = Suite(&MaintenanceWindowSuite{})
+
+func (*MaintenanceWindowSuite) TestParsesMaintenanceWindow(c *C) {
+	window, err := UnmarshalMaintenanceWindow([]byte(`kind: maintenancewindow
+version: v2
+spec:
+  windows:
+  - days: [sat, Sunday]
+    start: "01:00"
+    duration: 4h
+`))
+	c.Assert(err, IsNil)
+	c.Assert(window.GetName(), Equals, KindMaintenanceWindow)
+	c.Assert(window.GetTimezone(), Equals, "UTC")
+	c.Assert(window.GetWindows()[0].String(), Equals, "sat,Sunday 01:00 for 4h0m0s")
+
+	data, err := MarshalMaintenanceWindow(window)
+	c.Assert(err, IsNil)
+	parsed, err := UnmarshalMaintenanceWindow(data)
+	c.Assert(err, IsNil)
+	c.Assert(parsed, compare.DeepEquals, window)
+}
+
+func (*MaintenanceWindowSuite) TestValidatesMaintenanceWindow(c *C) {
+	var testCases = []struct {
+		spec    string
+		comment string
+	}{
+		{spec: `windows: []`, comment: "no windows"},
+		{spec: `{timezone: Mars/Olympus, windows: [{start: "01:00", duration: 1h}]}`, comment: "invalid timezone"},
+		{spec: `windows: [{start: "25:00", duration: 1h}]`, comment: "invalid start"},
+		{spec: `windows: [{days: [someday], start: "01:00", duration: 1h}]`, comment: "invalid day"},
+		{spec: `windows: [{start: "01:00", duration: 0s}]`, comment: "empty duration"},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalMaintenanceWindow([]byte("kind: maintenancewindow\nversion: v2\nspec: " + tc.spec))
+		c.Assert(err, NotNil, Commentf(tc.comment))
+	}
+}
+
+func (*MaintenanceWindowSuite) TestComputesWindows(c *C) {
+	window := NewMaintenanceWindow(MaintenanceWindowSpecV2{
+		Timezone: "America/New_York",
+		Windows: []Window{
+			// Saturday 22:00 through Sunday 02:00
+			{Days: []string{"sat"}, Start: "22:00", Duration: teleservices.NewDuration(4 * time.Hour)},
+		},
+	})
+	c.Assert(window.CheckAndSetDefaults(), IsNil)
+	location, err := time.LoadLocation("America/New_York")
+	c.Assert(err, IsNil)
+	at := func(day, hour, minute int) time.Time {
+		// June 1st 2019 is a Saturday
+		return time.Date(2019, time.June, day, hour, minute, 0, 0, location)
+	}
+
+	var testCases = []struct {
+		now     time.Time
+		open    bool
+		next    time.Time
+		comment string
+	}{
+		{now: at(1, 21, 59), next: at(1, 22, 0), comment: "before the window"},
+		{now: at(1, 22, 0), open: true, next: at(1, 22, 0), comment: "window opens"},
+		{now: at(2, 1, 30), open: true, next: at(2, 1, 30), comment: "window spans midnight"},
+		{now: at(2, 2, 0), next: at(8, 22, 0), comment: "window closed"},
+		{now: at(5, 12, 0).UTC(), next: at(8, 22, 0), comment: "time in another timezone"},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		c.Assert(window.IsOpen(tc.now), Equals, tc.open, comment)
+		c.Assert(window.NextOpen(tc.now).Equal(tc.next), Equals, true, comment)
+	}
+}
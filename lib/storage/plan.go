@@ -81,6 +81,8 @@ type OperationPhase struct {
 	Requires []string `json:"requires,omitempty" yaml:"requires,omitempty"`
 	// Parallel enables parallel execution of sub-phases
 	Parallel bool `json:"parallel"`
+	// Started is the time the phase last started executing
+	Started time.Time `json:"started,omitempty" yaml:"started,omitempty"`
 	// Updated is the last phase update time
 	Updated time.Time `json:"updated,omitempty" yaml:"updated,omitempty"`
 	// Data is optional phase-specific data attached to the phase
@@ -265,6 +267,21 @@ func (c PlanChangelog) Latest(phaseID string) *PlanChange {
 	return latest
 }
 
+// LatestWithState returns the most recent plan change entry that moved
+// the specified phase into the given state
+func (c PlanChangelog) LatestWithState(phaseID, state string) *PlanChange {
+	var latest *PlanChange
+	for i, change := range c {
+		if change.PhaseID != phaseID || change.NewState != state {
+			continue
+		}
+		if latest == nil || change.Created.After(latest.Created) {
+			latest = &(c[i])
+		}
+	}
+	return latest
+}
+
 // HasSubphases returns true if the phase has 1 or more subphases
 func (p OperationPhase) HasSubphases() bool {
 	return len(p.Phases) > 0
@@ -309,6 +326,25 @@ func (p OperationPhase) GetLastUpdateTime() time.Time {
 	return last
 }
 
+// GetStartTime returns the time the phase started executing.
+// For a phase with subphases, it is the earliest start time of its subphases
+func (p OperationPhase) GetStartTime() time.Time {
+	if len(p.Phases) == 0 {
+		return p.Started
+	}
+	var first time.Time
+	for _, phase := range p.Phases {
+		started := phase.GetStartTime()
+		if started.IsZero() {
+			continue
+		}
+		if first.IsZero() || started.Before(first) {
+			first = started
+		}
+	}
+	return first
+}
+
 // GetState returns the phase state based on the states of all its subphases
 func (p OperationPhase) GetState() string {
 	// if the phase doesn't have subphases, then just return its state from property
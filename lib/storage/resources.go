@@ -75,6 +75,11 @@ const (
 	KindRelease = "release"
 	// KindInvite defines the user invite token.
 	KindInvite = "invite"
+	// KindTrustPolicy defines the resource that manages trusted package signers
+	KindTrustPolicy = "trustpolicy"
+	// KindMaintenanceWindow defines the resource that manages the time windows
+	// disruptive cluster operations are allowed in
+	KindMaintenanceWindow = "maintenancewindow"
 )
 
 // CanonicalKind translates the specified kind to canonical form.
@@ -109,6 +114,10 @@ func CanonicalKind(kind string) string {
 		return KindPersistentStorage
 	case KindAuthGateway, "gw":
 		return KindAuthGateway
+	case KindTrustPolicy, "trust":
+		return KindTrustPolicy
+	case KindMaintenanceWindow, "maintenance", "mw":
+		return KindMaintenanceWindow
 	}
 	return kind
 }
@@ -176,6 +185,8 @@ var SupportedGravityResources = []string{
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
 	KindPersistentStorage,
+	KindTrustPolicy,
+	KindMaintenanceWindow,
 }
 
 // SupportedGravityResourcesToRemove is a list of resources supported by
@@ -191,6 +202,8 @@ var SupportedGravityResourcesToRemove = []string{
 	KindTLSKeyPair,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
+	KindTrustPolicy,
+	KindMaintenanceWindow,
 }
 
 // MetadataSchema is a copy of teleport/lib/services.MetadataSchema but with
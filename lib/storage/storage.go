@@ -436,6 +436,12 @@ type SiteOperation struct {
 	UpdateConfig *UpdateConfigOperationState `json:"update_config,omitempty"`
 	// Reconfigure contains reconfiguration operation state
 	Reconfigure *ReconfigureOperationState `json:"reconfigure,omitempty"`
+	// IgnoreMaintenanceWindow is set when the operation has been allowed
+	// to start outside of the cluster maintenance window
+	IgnoreMaintenanceWindow bool `json:"ignore_maintenance_window,omitempty"`
+	// QueueID is the ID of the operation queue entry this operation
+	// has been created from
+	QueueID string `json:"queue_id,omitempty"`
 }
 
 func (s *SiteOperation) Check() error {
@@ -496,6 +502,46 @@ type SiteOperations interface {
 	CreateOperationPlanChange(PlanChange) (*PlanChange, error)
 	// GetOperationPlanChangelog returns all state transition entries for a plan
 	GetOperationPlanChangelog(clusterName, operationID string) (PlanChangelog, error)
+	// QuerySiteOperations returns operations matching the specified filter
+	// sorted by time (latest operations come first)
+	QuerySiteOperations(OperationsFilter) ([]SiteOperation, error)
+}
+
+// OperationsFilter defines the criteria for querying operations.
+// Empty criteria match all operations
+type OperationsFilter struct {
+	// SiteDomain limits operations to the specified cluster
+	SiteDomain string `json:"site_domain,omitempty"`
+	// Types limits operations to the specified types
+	Types []string `json:"types,omitempty"`
+	// States limits operations to the specified states
+	States []string `json:"states,omitempty"`
+	// CreatedAfter limits operations to those created after the specified time
+	CreatedAfter time.Time `json:"created_after,omitempty"`
+	// CreatedBefore limits operations to those created before the specified time
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+	// Limit limits the number of returned operations
+	Limit int `json:"limit,omitempty"`
+}
+
+// Matches returns true if the specified operation matches this filter
+func (f OperationsFilter) Matches(op SiteOperation) bool {
+	if f.SiteDomain != "" && f.SiteDomain != op.SiteDomain {
+		return false
+	}
+	if len(f.Types) != 0 && !utils.StringInSlice(f.Types, op.Type) {
+		return false
+	}
+	if len(f.States) != 0 && !utils.StringInSlice(f.States, op.State) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !op.Created.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !op.Created.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
 }
 
 // Reason details the reason a site is in a particular state
@@ -901,6 +947,22 @@ type Package struct {
 	Manifest []byte `json:"manifest"`
 	// Base refers to the package this application is based on
 	Base *Package `json:"base,omitempty"`
+	// Signatures lists signatures of the package producers
+	Signatures []PackageSignature `json:"signatures,omitempty"`
+}
+
+// PackageSignature is a signature of a package produced with the
+// key of the package producer
+type PackageSignature struct {
+	// Type is the signature algorithm, ed25519 or x509
+	Type string `json:"type"`
+	// KeyID identifies the key that produced the signature
+	KeyID string `json:"key_id"`
+	// Certificates is the PEM-encoded certificate chain of the signer.
+	// Only set for x509 signatures
+	Certificates []byte `json:"certificates,omitempty"`
+	// Value is the signature value
+	Value []byte `json:"value"`
 }
 
 // Locator returns new locator from the package repository, name and version
@@ -1498,7 +1560,11 @@ type Backend interface {
 	ClusterImport
 	LegacyRoles
 	SystemMetadata
+	TrustPolicies
+	MaintenanceWindows
+	OperationQueue
 	Charts
+	Watches
 }
 
 const (
@@ -1842,6 +1908,10 @@ type InstallExpandOperationState struct {
 	Vars OperationVariables `json:"vars"`
 	// Package is the application being installed
 	Package loc.Locator `json:"package"`
+	// Masters is the number of nodes joining with an expand operation
+	// that become masters although their node profile does not specify
+	// the cluster role. It is reserved when the operation is created
+	Masters int `json:"masters,omitempty"`
 }
 
 // OperationVariables is operation-specific set of variables
@@ -2107,6 +2177,39 @@ type UpdateOperationState struct {
 	Manual bool `json:"manual"`
 	// Vars are variables specific to this operation
 	Vars OperationVariables `json:"vars"`
+	// Strategy defines the order in which the regular nodes are updated.
+	// If unspecified, the regular nodes are updated one at a time
+	Strategy *UpdateStrategy `json:"strategy,omitempty"`
+	// RollbackOnFailure specifies whether the completed phases are automatically
+	// rolled back if a phase or a cluster health check fails
+	RollbackOnFailure bool `json:"rollback_on_failure,omitempty"`
+}
+
+// UpdateStrategy defines the order in which the regular nodes
+// of the cluster are updated.
+//
+// The master nodes are always updated one at a time to keep etcd
+// quorum and are verified before the regular nodes are updated
+type UpdateStrategy struct {
+	// Canary specifies whether a single regular node is updated
+	// and verified before the rest of the regular nodes
+	Canary bool `json:"canary,omitempty"`
+	// BatchSize is the maximum number of regular nodes updated concurrently.
+	// The cluster health is verified after each batch
+	BatchSize int `json:"batch_size,omitempty"`
+}
+
+// Check validates this update strategy
+func (r UpdateStrategy) Check() error {
+	if r.BatchSize < 0 {
+		return trace.BadParameter("batch size must be positive, got %v", r.BatchSize)
+	}
+	return nil
+}
+
+// String returns a textual representation of this update strategy
+func (r UpdateStrategy) String() string {
+	return fmt.Sprintf("strategy(canary=%v, batch=%v)", r.Canary, r.BatchSize)
 }
 
 // UpdateEnvarsOperationState describes the state of the operation to update cluster environment variables.
@@ -2181,6 +2284,93 @@ type ClusterImport interface {
 	SetClusterImported() error
 }
 
+// TrustPolicies manages the cluster trust policy for package signatures
+type TrustPolicies interface {
+	// GetTrustPolicy returns the cluster trust policy
+	GetTrustPolicy() (TrustPolicy, error)
+	// UpsertTrustPolicy creates or updates the cluster trust policy
+	UpsertTrustPolicy(TrustPolicy) error
+	// DeleteTrustPolicy deletes the cluster trust policy
+	DeleteTrustPolicy() error
+}
+
+// MaintenanceWindows manages the cluster maintenance window
+type MaintenanceWindows interface {
+	// GetMaintenanceWindow returns the cluster maintenance window
+	GetMaintenanceWindow() (MaintenanceWindow, error)
+	// UpsertMaintenanceWindow creates or updates the cluster maintenance window
+	UpsertMaintenanceWindow(MaintenanceWindow) error
+	// DeleteMaintenanceWindow deletes the cluster maintenance window
+	DeleteMaintenanceWindow() error
+}
+
+// OperationQueue manages operations waiting for the cluster to become idle
+type OperationQueue interface {
+	// CreateQueuedOperation adds a new entry to the cluster operation queue
+	CreateQueuedOperation(QueuedOperation) (*QueuedOperation, error)
+	// GetQueuedOperations returns the operation queue of the specified cluster
+	// sorted by creation time (oldest entries come first)
+	GetQueuedOperations(siteDomain string) ([]QueuedOperation, error)
+	// GetQueuedOperation returns the operation queue entry with the specified ID
+	GetQueuedOperation(siteDomain, id string) (*QueuedOperation, error)
+	// UpdateQueuedOperation updates an existing entry in the operation queue
+	UpdateQueuedOperation(QueuedOperation) (*QueuedOperation, error)
+	// DeleteQueuedOperation removes the specified entry from the operation queue
+	DeleteQueuedOperation(siteDomain, id string) error
+}
+
+// QueuedOperation is an operation waiting in the cluster operation queue
+// for the cluster to become idle
+type QueuedOperation struct {
+	// ID is the queue entry ID
+	ID string `json:"id"`
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// SiteDomain is the name of the cluster
+	SiteDomain string `json:"site_domain"`
+	// Type is the type of the queued operation
+	Type string `json:"type"`
+	// Description is an optional human-readable description of the operation
+	Description string `json:"description,omitempty"`
+	// Created is the time the entry has been queued at
+	Created time.Time `json:"created"`
+	// CreatedBy is the user who queued the operation
+	CreatedBy string `json:"created_by,omitempty"`
+	// Expires is the time the entry is removed from the queue
+	// if the operation has not been started by then
+	Expires time.Time `json:"expires"`
+	// LastSeen is the time the client waiting for the operation has last
+	// renewed the entry. Entries whose client has gone away are removed
+	LastSeen time.Time `json:"last_seen"`
+	// Request is the serialized request the operation is created from
+	Request json.RawMessage `json:"request,omitempty"`
+	// Reason is the reason the operation is waiting, if it is at the head
+	// of the queue
+	Reason string `json:"reason,omitempty"`
+	// Error is the reason the operation has failed to start
+	Error string `json:"error,omitempty"`
+}
+
+// Check validates this queue entry
+func (r QueuedOperation) Check() error {
+	if r.SiteDomain == "" {
+		return trace.BadParameter("missing SiteDomain")
+	}
+	if r.Type == "" {
+		return trace.BadParameter("missing Type")
+	}
+	if r.Expires.IsZero() {
+		return trace.BadParameter("missing Expires")
+	}
+	return nil
+}
+
+// String returns a textual representation of this queue entry
+func (r QueuedOperation) String() string {
+	return fmt.Sprintf("queued operation(%v(%v), cluster=%v, created=%v)",
+		r.Type, r.ID, r.SiteDomain, r.Created.Format(constants.HumanDateFormat))
+}
+
 // ClusterConfiguration stores the cluster configuration in the DB.
 type ClusterConfiguration interface {
 	// SetClusterName gets services.ClusterName
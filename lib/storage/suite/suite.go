@@ -19,8 +19,11 @@ limitations under the License.
 package suite
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
@@ -824,6 +827,57 @@ func (s *StorageSuite) SitesCRUD(c *C) {
 	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("unexpected type: %T", err))
 }
 
+// ConcurrentCompareAndSwap verifies that only one of several concurrent
+// compare-and-swap updates of the same value succeeds
+func (s *StorageSuite) ConcurrentCompareAndSwap(c *C) {
+	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.Backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.Backend.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Type:       string(storage.AppUser),
+		Manifest:   []byte("a"),
+		Created:    now,
+	})
+	c.Assert(err, IsNil)
+	site, err := s.Backend.CreateSite(storage.Site{
+		AccountID:       a.ID,
+		Created:         now,
+		Provider:        "virsh",
+		State:           "created",
+		Domain:          "a.example.com",
+		App:             *app,
+		NextUpdateCheck: now,
+	})
+	c.Assert(err, IsNil)
+
+	const updaters = 10
+	errC := make(chan error, updaters)
+	var wg sync.WaitGroup
+	for i := 0; i < updaters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errC <- s.Backend.CompareAndSwapSiteState(site.Domain, "created", fmt.Sprintf("state-%v", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errC)
+
+	var succeeded int
+	for err := range errC {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("unexpected error: %v", err))
+	}
+	c.Assert(succeeded, Equals, 1)
+}
+
 func (s *StorageSuite) ProgressEntriesCRUD(c *C) {
 	// Create account
 	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
@@ -995,6 +1049,89 @@ func (s *StorageSuite) OperationsCRUD(c *C) {
 	})
 }
 
+func (s *StorageSuite) OperationsQuery(c *C) {
+	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.Backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.Backend.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Manifest:   []byte("1"),
+		Type:       string(storage.AppUser),
+	})
+	c.Assert(err, IsNil)
+
+	now := time.Date(2015, 11, 16, 1, 2, 3, 0, time.UTC)
+	var ops []storage.SiteOperation
+	for i, domain := range []string{"a.example.com", "b.example.com"} {
+		_, err := s.Backend.CreateSite(storage.Site{
+			AccountID: a.ID,
+			Created:   now,
+			Domain:    domain,
+			App:       *app,
+		})
+		c.Assert(err, IsNil)
+		for j, opType := range []string{"install", "expand", "update"} {
+			created := now.Add(time.Duration(i*3+j) * time.Hour)
+			op, err := s.Backend.CreateSiteOperation(storage.SiteOperation{
+				AccountID:  a.ID,
+				SiteDomain: domain,
+				Type:       opType,
+				Created:    created,
+				Updated:    created,
+				State:      "completed",
+			})
+			c.Assert(err, IsNil)
+			ops = append(ops, *op)
+		}
+	}
+	ops[5].State = "failed"
+	_, err = s.Backend.UpdateSiteOperation(ops[5])
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		filter   storage.OperationsFilter
+		expected []storage.SiteOperation
+		comment  string
+	}{
+		{
+			filter:   storage.OperationsFilter{},
+			expected: []storage.SiteOperation{ops[5], ops[4], ops[3], ops[2], ops[1], ops[0]},
+			comment:  "empty filter matches all operations",
+		},
+		{
+			filter:   storage.OperationsFilter{SiteDomain: "a.example.com", Types: []string{"install", "update"}},
+			expected: []storage.SiteOperation{ops[2], ops[0]},
+			comment:  "filter by cluster and type",
+		},
+		{
+			filter:   storage.OperationsFilter{States: []string{"failed"}},
+			expected: []storage.SiteOperation{ops[5]},
+			comment:  "filter by state",
+		},
+		{
+			filter: storage.OperationsFilter{
+				CreatedAfter:  now.Add(time.Hour),
+				CreatedBefore: now.Add(4 * time.Hour),
+			},
+			expected: []storage.SiteOperation{ops[3], ops[2]},
+			comment:  "filter by time",
+		},
+		{
+			filter:   storage.OperationsFilter{Types: []string{"expand"}, Limit: 1},
+			expected: []storage.SiteOperation{ops[4]},
+			comment:  "limit the number of operations",
+		},
+	}
+	for _, tc := range testCases {
+		out, err := s.Backend.QuerySiteOperations(tc.filter)
+		c.Assert(err, IsNil, Commentf(tc.comment))
+		c.Assert(out, DeepEquals, tc.expected, Commentf(tc.comment))
+	}
+}
+
 func (s *StorageSuite) LoginEntriesCRUD(c *C) {
 	// Create
 	entry := storage.LoginEntry{
@@ -1286,6 +1423,183 @@ func (s *StorageSuite) APIKeysCRUD(c *C) {
 	c.Assert(len(keys), Equals, 1)
 }
 
+// Watch verifies that changes to an operation are delivered to watchers
+func (s *StorageSuite) Watch(c *C) {
+	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.Backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.Backend.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Manifest:   []byte("1"),
+		Type:       string(storage.AppUser),
+	})
+	c.Assert(err, IsNil)
+	site, err := s.Backend.CreateSite(storage.Site{
+		AccountID: a.ID,
+		Created:   now,
+		Domain:    "a.example.com",
+		App:       *app,
+	})
+	c.Assert(err, IsNil)
+	op, err := s.Backend.CreateSiteOperation(storage.SiteOperation{
+		AccountID:  a.ID,
+		SiteDomain: site.Domain,
+		Type:       "install",
+		Created:    now,
+		State:      "ready",
+	})
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventsC, err := s.Backend.Watch(ctx, storage.OperationWatchPrefix(site.Domain, op.ID))
+	c.Assert(err, IsNil)
+
+	op.State = "in_progress"
+	_, err = s.Backend.UpdateSiteOperation(*op)
+	c.Assert(err, IsNil)
+	event := receiveEvent(c, eventsC)
+	c.Assert(event.Type, Equals, storage.WatchEventUpdated)
+	change, ok := storage.NewClusterChange(event)
+	c.Assert(ok, Equals, true)
+	c.Assert(*change, DeepEquals, storage.ClusterChange{
+		Type:        storage.WatchEventUpdated,
+		Kind:        storage.ClusterChangeOperation,
+		ClusterName: site.Domain,
+		OperationID: op.ID,
+	})
+	var updated storage.SiteOperation
+	c.Assert(json.Unmarshal(event.Value, &updated), IsNil)
+	c.Assert(updated.State, Equals, op.State)
+
+	// changes to other operations are not delivered
+	_, err = s.Backend.CreateSiteOperation(storage.SiteOperation{
+		AccountID:  a.ID,
+		SiteDomain: site.Domain,
+		Type:       "expand",
+		Created:    now,
+		State:      "ready",
+	})
+	c.Assert(err, IsNil)
+	_, err = s.Backend.CreateProgressEntry(storage.ProgressEntry{
+		SiteDomain:  site.Domain,
+		OperationID: op.ID,
+		Created:     now,
+		Completion:  10,
+		State:       "in_progress",
+	})
+	c.Assert(err, IsNil)
+	event = receiveEvent(c, eventsC)
+	c.Assert(event.Type, Equals, storage.WatchEventCreated)
+	change, ok = storage.NewClusterChange(event)
+	c.Assert(ok, Equals, true)
+	c.Assert(change.Kind, Equals, storage.ClusterChangeProgress)
+
+	err = s.Backend.DeleteSiteOperation(site.Domain, op.ID)
+	c.Assert(err, IsNil)
+	event = receiveEvent(c, eventsC)
+	c.Assert(event.Type, Equals, storage.WatchEventDeleted)
+
+	cancel()
+	for {
+		select {
+		case _, ok := <-eventsC:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			c.Fatal("timeout waiting for the watch to stop")
+		}
+	}
+}
+
+func receiveEvent(c *C, eventsC <-chan storage.WatchEvent) storage.WatchEvent {
+	select {
+	case event, ok := <-eventsC:
+		c.Assert(ok, Equals, true, Commentf("watch closed unexpectedly"))
+		return event
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for watch event")
+	}
+	return storage.WatchEvent{}
+}
+
+// OperationQueueCRUD verifies that operation queue entries are
+// returned in the order they have been queued in
+func (s *StorageSuite) OperationQueueCRUD(c *C) {
+	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	repo, err := s.Backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+	app, err := s.Backend.CreatePackage(storage.Package{
+		Repository: repo.GetName(),
+		Name:       "app",
+		Version:    "0.0.1",
+		Manifest:   []byte("1"),
+		Type:       string(storage.AppUser),
+	})
+	c.Assert(err, IsNil)
+	site, err := s.Backend.CreateSite(storage.Site{
+		AccountID: a.ID,
+		Created:   now,
+		Domain:    "a.example.com",
+		App:       *app,
+	})
+	c.Assert(err, IsNil)
+
+	queued, err := s.Backend.GetQueuedOperations(site.Domain)
+	c.Assert(err, IsNil)
+	c.Assert(queued, HasLen, 0)
+
+	// queue the entries in reverse order
+	ops := make([]storage.QueuedOperation, 3)
+	for i := len(ops) - 1; i >= 0; i-- {
+		op, err := s.Backend.CreateQueuedOperation(storage.QueuedOperation{
+			AccountID:  a.ID,
+			SiteDomain: site.Domain,
+			Type:       "operation_gc",
+			Created:    now.Add(time.Duration(i) * time.Minute),
+			Expires:    now.Add(time.Hour),
+		})
+		c.Assert(err, IsNil)
+		c.Assert(op.ID, Not(Equals), "")
+		ops[i] = *op
+	}
+	_, err = s.Backend.CreateQueuedOperation(ops[0])
+	c.Assert(trace.IsAlreadyExists(err), Equals, true)
+
+	queued, err = s.Backend.GetQueuedOperations(site.Domain)
+	c.Assert(err, IsNil)
+	c.Assert(queued, DeepEquals, ops)
+
+	op, err := s.Backend.GetQueuedOperation(site.Domain, ops[1].ID)
+	c.Assert(err, IsNil)
+	c.Assert(*op, DeepEquals, ops[1])
+
+	ops[1].Reason = "cluster is busy"
+	updated, err := s.Backend.UpdateQueuedOperation(ops[1])
+	c.Assert(err, IsNil)
+	c.Assert(*updated, DeepEquals, ops[1])
+	op, err = s.Backend.GetQueuedOperation(site.Domain, ops[1].ID)
+	c.Assert(err, IsNil)
+	c.Assert(*op, DeepEquals, ops[1])
+
+	c.Assert(s.Backend.DeleteQueuedOperation(site.Domain, ops[0].ID), IsNil)
+	err = s.Backend.DeleteQueuedOperation(site.Domain, ops[0].ID)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	_, err = s.Backend.GetQueuedOperation(site.Domain, ops[0].ID)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+	_, err = s.Backend.UpdateQueuedOperation(ops[0])
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	queued, err = s.Backend.GetQueuedOperations(site.Domain)
+	c.Assert(err, IsNil)
+	c.Assert(queued, DeepEquals, ops[1:])
+}
+
 func (s *StorageSuite) ProvisioningTokensCRUD(c *C) {
 	// Create account
 	a, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	teledefaults "github.com/gravitational/teleport/lib/defaults"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// TrustPolicy defines the cluster policy for verifying package signatures.
+// There is only a single instance of the resource in a cluster
+type TrustPolicy interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates this resource and sets defaults
+	CheckAndSetDefaults() error
+	// GetMode returns the policy enforcement mode
+	GetMode() string
+	// IsEnforced returns true if packages failing verification should be rejected
+	IsEnforced() bool
+	// GetKeys returns the trusted public signing keys
+	GetKeys() []TrustedKey
+	// GetCertificateAuthorities returns the PEM-encoded certificate authorities
+	// trusted to issue signing certificates
+	GetCertificateAuthorities() []string
+}
+
+// NewTrustPolicy creates a new trust policy resource from the provided spec
+func NewTrustPolicy(spec TrustPolicySpecV2) TrustPolicy {
+	return &TrustPolicyV2{
+		Kind:    KindTrustPolicy,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindTrustPolicy,
+			Namespace: teledefaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// TrustPolicyV2 defines the trust policy resource
+type TrustPolicyV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the trust policy
+	Spec TrustPolicySpecV2 `json:"spec"`
+}
+
+// TrustPolicySpecV2 defines the trust policy specification
+type TrustPolicySpecV2 struct {
+	// Mode is the policy enforcement mode, either enforce or audit.
+	// In audit mode packages that fail verification are only logged
+	Mode string `json:"mode,omitempty"`
+	// Keys lists trusted Ed25519 public keys
+	Keys []TrustedKey `json:"keys,omitempty"`
+	// CertificateAuthorities lists PEM-encoded certificate authorities
+	// trusted to issue x509 signing certificates
+	CertificateAuthorities []string `json:"certificate_authorities,omitempty"`
+}
+
+// TrustedKey is a trusted public signing key
+type TrustedKey struct {
+	// Name is the key name used in verification messages
+	Name string `json:"name"`
+	// PublicKey is the PEM-encoded public key
+	PublicKey string `json:"public_key"`
+}
+
+// GetMode returns the policy enforcement mode
+func (r *TrustPolicyV2) GetMode() string {
+	return r.Spec.Mode
+}
+
+// IsEnforced returns true if packages failing verification should be rejected
+func (r *TrustPolicyV2) IsEnforced() bool {
+	return r.Spec.Mode == TrustPolicyModeEnforce
+}
+
+// GetKeys returns the trusted public signing keys
+func (r *TrustPolicyV2) GetKeys() []TrustedKey {
+	return r.Spec.Keys
+}
+
+// GetCertificateAuthorities returns the trusted certificate authorities
+func (r *TrustPolicyV2) GetCertificateAuthorities() []string {
+	return r.Spec.CertificateAuthorities
+}
+
+// CheckAndSetDefaults validates this resource and sets defaults
+func (r *TrustPolicyV2) CheckAndSetDefaults() error {
+	r.Metadata.Name = KindTrustPolicy
+	if err := r.Metadata.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	switch r.Spec.Mode {
+	case "":
+		r.Spec.Mode = TrustPolicyModeEnforce
+	case TrustPolicyModeEnforce, TrustPolicyModeAudit:
+	default:
+		return trace.BadParameter("unsupported trust policy mode %q, expected %q or %q",
+			r.Spec.Mode, TrustPolicyModeEnforce, TrustPolicyModeAudit)
+	}
+	if len(r.Spec.Keys) == 0 && len(r.Spec.CertificateAuthorities) == 0 {
+		return trace.BadParameter("trust policy should specify at least one key or certificate authority")
+	}
+	for _, key := range r.Spec.Keys {
+		if key.Name == "" {
+			return trace.BadParameter("missing trusted key name")
+		}
+		if block, _ := pem.Decode([]byte(key.PublicKey)); block == nil {
+			return trace.BadParameter("trusted key %v is not PEM-encoded", key.Name)
+		}
+	}
+	for _, ca := range r.Spec.CertificateAuthorities {
+		if block, _ := pem.Decode([]byte(ca)); block == nil {
+			return trace.BadParameter("certificate authority is not PEM-encoded")
+		}
+	}
+	return nil
+}
+
+// UnmarshalTrustPolicy unmarshals the trust policy resource from YAML/JSON given with data
+func UnmarshalTrustPolicy(data []byte) (TrustPolicy, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch hdr.Version {
+	case teleservices.V2:
+		var policy TrustPolicyV2
+		err := teleutils.UnmarshalWithSchema(GetTrustPolicySchema(), &policy, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		if err := policy.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &policy, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindTrustPolicy, hdr.Version)
+}
+
+// MarshalTrustPolicy marshals the trust policy resource into JSON
+func MarshalTrustPolicy(policy TrustPolicy, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(policy)
+}
+
+// TrustPolicySpecV2Schema is JSON schema for the trust policy resource
+const TrustPolicySpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "mode": {"type": "string"},
+    "keys": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name", "public_key"],
+        "properties": {
+          "name": {"type": "string"},
+          "public_key": {"type": "string"}
+        }
+      }
+    },
+    "certificate_authorities": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+// GetTrustPolicySchema returns the trust policy resource schema for version V2
+func GetTrustPolicySchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		TrustPolicySpecV2Schema, "")
+}
+
+const (
+	// TrustPolicyModeEnforce rejects packages that fail signature verification
+	TrustPolicyModeEnforce = "enforce"
+	// TrustPolicyModeAudit only logs packages that fail signature verification
+	TrustPolicyModeAudit = "audit"
+)
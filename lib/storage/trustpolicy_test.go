@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	. "gopkg.in/check.v1"
+)
+
+type TrustPolicySuite struct{}
+
+var _ = Suite(&TrustPolicySuite{})
+
+func (*TrustPolicySuite) TestParsesTrustPolicy(c *C) {
+	policy, err := UnmarshalTrustPolicy([]byte(`kind: trustpolicy
+version: v2
+metadata:
+  name: policy
+spec:
+  keys:
+  - name: release
+    public_key: |
+      -----BEGIN PUBLIC KEY-----
+      MCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=
+      -----END PUBLIC KEY-----
+`))
+	c.Assert(err, IsNil)
+	c.Assert(policy.GetName(), Equals, KindTrustPolicy)
+	c.Assert(policy.GetMode(), Equals, TrustPolicyModeEnforce)
+	c.Assert(policy.IsEnforced(), Equals, true)
+	c.Assert(policy.GetKeys(), compare.DeepEquals, []TrustedKey{{
+		Name: "release",
+		PublicKey: `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=
+-----END PUBLIC KEY-----
+`,
+	}})
+
+	data, err := MarshalTrustPolicy(policy)
+	c.Assert(err, IsNil)
+	parsed, err := UnmarshalTrustPolicy(data)
+	c.Assert(err, IsNil)
+	c.Assert(parsed, compare.DeepEquals, policy)
+}
+
+func (*TrustPolicySuite) TestValidatesTrustPolicy(c *C) {
+	testCases := []struct {
+		in      string
+		comment string
+	}{
+		{
+			in:      `{"kind": "trustpolicy", "version": "v2", "metadata": {"name": "policy"}, "spec": {}}`,
+			comment: "requires a key or a certificate authority",
+		},
+		{
+			in:      `{"kind": "trustpolicy", "version": "v2", "metadata": {"name": "policy"}, "spec": {"mode": "ignore", "certificate_authorities": ["-----BEGIN CERTIFICATE-----\nAA==\n-----END CERTIFICATE-----\n"]}}`,
+			comment: "rejects unknown modes",
+		},
+		{
+			in:      `{"kind": "trustpolicy", "version": "v2", "metadata": {"name": "policy"}, "spec": {"keys": [{"name": "release", "public_key": "key"}]}}`,
+			comment: "requires PEM-encoded keys",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalTrustPolicy([]byte(tc.in))
+		c.Assert(err, NotNil, Commentf(tc.comment))
+	}
+}
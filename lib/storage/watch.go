@@ -0,0 +1,167 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// Watches defines the interface to watch for changes in the backend
+type Watches interface {
+	// Watch returns a channel that receives events about changes to the items
+	// with keys under the specified prefix until the context is cancelled.
+	// The prefix is a list of key elements relative to the backend root,
+	// see ClusterWatchPrefix and OperationWatchPrefix.
+	//
+	// The channel is closed when the watch stops: either because the context
+	// has been cancelled or because the watcher could not keep up with events,
+	// in which case the watch should be restarted
+	Watch(ctx context.Context, prefix []string) (<-chan WatchEvent, error)
+}
+
+// WatchEvent describes a change to a single backend item
+type WatchEvent struct {
+	// Type is the event type
+	Type WatchEventType `json:"type"`
+	// Key is the item key relative to the backend root
+	Key []string `json:"key"`
+	// Value is the new item value, empty for deleted items
+	Value []byte `json:"value,omitempty"`
+}
+
+// String returns a text representation of this event
+func (r WatchEvent) String() string {
+	return string(r.Type) + " " + strings.Join(r.Key, "/")
+}
+
+// WatchEventType defines the type of a watch event
+type WatchEventType string
+
+const (
+	// WatchEventCreated is emitted when a new item has been created
+	WatchEventCreated WatchEventType = "created"
+	// WatchEventUpdated is emitted when an existing item has been updated
+	WatchEventUpdated WatchEventType = "updated"
+	// WatchEventDeleted is emitted when an item has been deleted or has expired
+	WatchEventDeleted WatchEventType = "deleted"
+)
+
+// ClusterWatchPrefix returns the watch prefix for changes to the specified
+// cluster and all its operations
+func ClusterWatchPrefix(clusterName string) []string {
+	return []string{"sites", clusterName}
+}
+
+// OperationWatchPrefix returns the watch prefix for changes to the specified
+// operation including its progress and plan
+func OperationWatchPrefix(clusterName, operationID string) []string {
+	return []string{"sites", clusterName, "ops", operationID}
+}
+
+// ClusterChange describes a change to a cluster or one of its operations
+type ClusterChange struct {
+	// Type is the change type
+	Type WatchEventType `json:"type"`
+	// Kind is the kind of the changed item
+	Kind ClusterChangeKind `json:"kind"`
+	// ClusterName is the name of the changed cluster
+	ClusterName string `json:"cluster_name"`
+	// OperationID is the ID of the changed operation
+	OperationID string `json:"operation_id,omitempty"`
+	// QueueID is the ID of the changed operation queue entry
+	QueueID string `json:"queue_id,omitempty"`
+}
+
+// ClusterChangeKind defines the kind of item a cluster change refers to
+type ClusterChangeKind string
+
+const (
+	// ClusterChangeCluster is a change to the cluster itself
+	ClusterChangeCluster ClusterChangeKind = "cluster"
+	// ClusterChangeOperation is a change to a cluster operation
+	ClusterChangeOperation ClusterChangeKind = "operation"
+	// ClusterChangeProgress is a new operation progress entry
+	ClusterChangeProgress ClusterChangeKind = "progress"
+	// ClusterChangePlan is a change to the operation plan
+	ClusterChangePlan ClusterChangeKind = "plan"
+	// ClusterChangeQueue is a change to the cluster operation queue
+	ClusterChangeQueue ClusterChangeKind = "queue"
+)
+
+// NewClusterChange returns the cluster change described by the specified event.
+// Returns false if the event does not describe a cluster change
+func NewClusterChange(event WatchEvent) (*ClusterChange, bool) {
+	key := event.Key
+	if len(key) < 3 || key[0] != "sites" {
+		return nil, false
+	}
+	change := ClusterChange{
+		Type:        event.Type,
+		ClusterName: key[1],
+	}
+	switch {
+	case len(key) == 3 && key[2] == "val":
+		change.Kind = ClusterChangeCluster
+	case len(key) >= 5 && key[2] == "ops":
+		change.OperationID = key[3]
+		switch key[4] {
+		case "val":
+			change.Kind = ClusterChangeOperation
+		case "progress":
+			change.Kind = ClusterChangeProgress
+		case "plan", "changelog":
+			change.Kind = ClusterChangePlan
+		default:
+			return nil, false
+		}
+	case len(key) == 4 && key[2] == "queue":
+		change.Kind = ClusterChangeQueue
+		change.QueueID = key[3]
+	default:
+		return nil, false
+	}
+	return &change, true
+}
+
+// WatchClusterChanges returns a channel that receives changes to the cluster
+// items under the specified prefix until the context is cancelled.
+// Events that do not describe cluster changes are skipped
+func WatchClusterChanges(ctx context.Context, watches Watches, prefix []string) (<-chan ClusterChange, error) {
+	eventsC, err := watches.Watch(ctx, prefix)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	changesC := make(chan ClusterChange)
+	go func() {
+		defer close(changesC)
+		for event := range eventsC {
+			change, ok := NewClusterChange(event)
+			if !ok {
+				continue
+			}
+			select {
+			case changesC <- *change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changesC, nil
+}
@@ -0,0 +1,429 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testutils
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// S3Server is an in-memory stand-in for an S3-compatible object store
+// serving path-style requests over HTTP, in the spirit of MinIO.
+//
+// It supports the subset of the API used by the BLOB storage:
+// put, copy, get (with ranges), head, delete, list (v2) and multipart
+// uploads including part copies. Content-MD5 of uploaded payloads
+// is verified. Request signatures are not verified
+type S3Server struct {
+	*httptest.Server
+	sync.Mutex
+	// Objects maps bucket/key to the stored object
+	Objects map[string]*S3ServerObject
+	uploads map[string]*s3Upload
+	// Requests counts requests by S3 operation name
+	Requests map[string]int
+	// OnWrite is called with every object created by a put, copy or
+	// complete multipart upload request if set, e.g. to simulate faults
+	OnWrite func(path string, object *S3ServerObject)
+}
+
+// S3ServerObject is an object stored by S3Server
+type S3ServerObject struct {
+	// Data is the object data
+	Data []byte
+	// Metadata is the user-defined object metadata
+	Metadata map[string]string
+	// Modified is the object modification time
+	Modified time.Time
+}
+
+// NewS3Server starts a new S3 stand-in server. The caller is
+// responsible for closing the server
+func NewS3Server() *S3Server {
+	s := &S3Server{
+		Objects:  make(map[string]*S3ServerObject),
+		uploads:  make(map[string]*s3Upload),
+		Requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	if !strings.Contains(path, "/") {
+		if r.Method == http.MethodGet {
+			s.count("ListObjectsV2")
+			s.listObjects(w, path, query.Get("prefix"), query.Get("continuation-token"))
+			return
+		}
+		s3Error(w, http.StatusNotImplemented, "NotImplemented", "bucket operation is not supported")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		source, err := url.PathUnescape(r.Header.Get("x-amz-copy-source"))
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source")
+			return
+		}
+		switch {
+		case query.Get("uploadId") != "" && source != "":
+			s.count("UploadPartCopy")
+			s.uploadPartCopy(w, r, source)
+		case query.Get("uploadId") != "":
+			s.count("UploadPart")
+			s.uploadPart(w, r)
+		case source != "":
+			s.count("CopyObject")
+			s.copyObject(w, r, path, source)
+		default:
+			s.count("PutObject")
+			s.putObject(w, r, path)
+		}
+	case http.MethodPost:
+		if _, ok := query["uploads"]; ok {
+			s.count("CreateMultipartUpload")
+			s.createUpload(w, r, path)
+			return
+		}
+		s.count("CompleteMultipartUpload")
+		s.completeUpload(w, r, path)
+	case http.MethodGet:
+		s.count("GetObject")
+		s.getObject(w, r, path, true)
+	case http.MethodHead:
+		s.count("HeadObject")
+		s.getObject(w, r, path, false)
+	case http.MethodDelete:
+		if uploadID := query.Get("uploadId"); uploadID != "" {
+			s.count("AbortMultipartUpload")
+			delete(s.uploads, uploadID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.count("DeleteObject")
+		delete(s.Objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func (s *S3Server) count(operation string) {
+	s.Requests[operation]++
+}
+
+func (s *S3Server) writeObject(path string, object *S3ServerObject) {
+	if s.OnWrite != nil {
+		s.OnWrite(path, object)
+	}
+	s.Objects[path] = object
+}
+
+func (s *S3Server) putObject(w http.ResponseWriter, r *http.Request, path string) {
+	data, ok := readVerified(w, r)
+	if !ok {
+		return
+	}
+	s.writeObject(path, &S3ServerObject{
+		Data:     data,
+		Metadata: metadataFromHeaders(r.Header),
+		Modified: time.Now().UTC(),
+	})
+	w.Header().Set("ETag", etag(data))
+}
+
+func (s *S3Server) copyObject(w http.ResponseWriter, r *http.Request, path, source string) {
+	object, ok := s.Objects[strings.TrimPrefix(source, "/")]
+	if !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchKey", "source not found")
+		return
+	}
+	metadata := object.Metadata
+	if r.Header.Get("x-amz-metadata-directive") == "REPLACE" {
+		metadata = metadataFromHeaders(r.Header)
+	}
+	s.writeObject(path, &S3ServerObject{
+		Data:     append([]byte{}, object.Data...),
+		Metadata: metadata,
+		Modified: time.Now().UTC(),
+	})
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CopyObjectResult"`
+		ETag    string
+	}{ETag: etag(object.Data)})
+}
+
+func (s *S3Server) createUpload(w http.ResponseWriter, r *http.Request, path string) {
+	uploadID := uuid.New()
+	s.uploads[uploadID] = &s3Upload{
+		parts:    make(map[int64][]byte),
+		metadata: metadataFromHeaders(r.Header),
+	}
+	writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Key: path, UploadId: uploadID})
+}
+
+func (s *S3Server) uploadPart(w http.ResponseWriter, r *http.Request) {
+	parts, partNumber, ok := s.getPart(w, r)
+	if !ok {
+		return
+	}
+	data, ok := readVerified(w, r)
+	if !ok {
+		return
+	}
+	parts[partNumber] = data
+	w.Header().Set("ETag", etag(data))
+}
+
+func (s *S3Server) uploadPartCopy(w http.ResponseWriter, r *http.Request, source string) {
+	parts, partNumber, ok := s.getPart(w, r)
+	if !ok {
+		return
+	}
+	object, ok := s.Objects[strings.TrimPrefix(source, "/")]
+	if !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchKey", "source not found")
+		return
+	}
+	var first, last int
+	_, err := fmt.Sscanf(r.Header.Get("x-amz-copy-source-range"), "bytes=%d-%d", &first, &last)
+	if err != nil || first > last || last >= len(object.Data) {
+		s3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source range")
+		return
+	}
+	data := append([]byte{}, object.Data[first:last+1]...)
+	parts[partNumber] = data
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CopyPartResult"`
+		ETag    string
+	}{ETag: etag(data)})
+}
+
+func (s *S3Server) getPart(w http.ResponseWriter, r *http.Request) (map[int64][]byte, int64, bool) {
+	upload, ok := s.uploads[r.URL.Query().Get("uploadId")]
+	if !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+		return nil, 0, false
+	}
+	partNumber, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 64)
+	if err != nil || partNumber < 1 {
+		s3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return nil, 0, false
+	}
+	return upload.parts, partNumber, true
+}
+
+func (s *S3Server) completeUpload(w http.ResponseWriter, r *http.Request, path string) {
+	uploadID := r.URL.Query().Get("uploadId")
+	upload, ok := s.uploads[uploadID]
+	if !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+		return
+	}
+	var request struct {
+		Parts []struct {
+			PartNumber int64
+			ETag       string
+		} `xml:"Part"`
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = xml.Unmarshal(body, &request)
+	}
+	if err != nil {
+		s3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	var data []byte
+	for _, part := range request.Parts {
+		partData, ok := upload.parts[part.PartNumber]
+		if !ok || etag(partData) != part.ETag {
+			s3Error(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %v", part.PartNumber))
+			return
+		}
+		data = append(data, partData...)
+	}
+	s.writeObject(path, &S3ServerObject{
+		Data:     data,
+		Metadata: upload.metadata,
+		Modified: time.Now().UTC(),
+	})
+	delete(s.uploads, uploadID)
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string
+		ETag    string
+	}{Key: path, ETag: etag(data)})
+}
+
+func (s *S3Server) getObject(w http.ResponseWriter, r *http.Request, path string, withBody bool) {
+	object, ok := s.Objects[path]
+	if !ok {
+		if !withBody {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	for key, value := range object.Metadata {
+		w.Header().Set("x-amz-meta-"+key, value)
+	}
+	w.Header().Set("Last-Modified", object.Modified.Format(http.TimeFormat))
+	w.Header().Set("ETag", etag(object.Data))
+	data := object.Data
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && withBody {
+		var first int
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &first); err != nil || first >= len(data) {
+			s3Error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", rangeHeader)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v", first, len(data)-1, len(data)))
+		data = data[first:]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if withBody {
+		w.Write(data)
+	}
+}
+
+func (s *S3Server) listObjects(w http.ResponseWriter, bucket, prefix, token string) {
+	const pageSize = 2
+	var keys []string
+	for path := range s.Objects {
+		key := strings.TrimPrefix(path, bucket+"/")
+		if key == path || !strings.HasPrefix(key, prefix) || key <= token {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	type content struct {
+		Key          string
+		Size         int
+		LastModified string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string    `xml:",omitempty"`
+		Contents              []content `xml:"Contents"`
+	}{Name: bucket, Prefix: prefix}
+	if len(keys) > pageSize {
+		keys = keys[:pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		object := s.Objects[bucket+"/"+key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			Size:         len(object.Data),
+			LastModified: object.Modified.Format(time.RFC3339),
+		})
+	}
+	result.KeyCount = len(result.Contents)
+	writeXML(w, result)
+}
+
+// readVerified reads the request body verifying its Content-MD5
+func readVerified(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return nil, false
+	}
+	if expected := r.Header.Get("Content-MD5"); expected != "" {
+		sum := md5.Sum(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != expected {
+			s3Error(w, http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received.")
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func metadataFromHeaders(headers http.Header) map[string]string {
+	metadata := make(map[string]string)
+	for key, values := range headers {
+		if strings.HasPrefix(strings.ToLower(key), "x-amz-meta-") && len(values) != 0 {
+			metadata[strings.ToLower(strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-"))] = values[0]
+		}
+	}
+	return metadata
+}
+
+// s3Upload is a multipart upload in progress
+type s3Upload struct {
+	parts    map[int64][]byte
+	metadata map[string]string
+}
+
+func etag(data []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(data))
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buf.Bytes())
+}
+
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transfer
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// CopyPackageRequest describes a request to copy a package
+// between package services
+type CopyPackageRequest struct {
+	// Src is the package service to copy the package from
+	Src pack.PackageService
+	// Dst is the package service to copy the package to
+	Dst pack.PackageService
+	// Package is the package to copy
+	Package loc.Locator
+	// Upsert is whether to create or upsert the package
+	Upsert bool
+	// Options specifies the attributes of the copied package
+	Options []pack.PackageOption
+	// Progress is optional progress reporter
+	Progress pack.ProgressReporter
+}
+
+// CheckAndSetDefaults validates the request and sets some defaults
+func (r *CopyPackageRequest) CheckAndSetDefaults() error {
+	if r.Src == nil {
+		return trace.BadParameter("missing parameter Src")
+	}
+	if r.Dst == nil {
+		return trace.BadParameter("missing parameter Dst")
+	}
+	return nil
+}
+
+// CopyPackage copies the package from one package service to another.
+//
+// If the destination already has an older version of the package and either
+// of the services supports deltas, only the delta between the versions is
+// transferred and the package is reconstructed and verified against its
+// checksum on the destination side. Otherwise, the whole package is copied
+func CopyPackage(req CopyPackageRequest) (*pack.PackageEnvelope, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	env, err := req.Src.ReadPackageEnvelope(req.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	progress := newProgressWriter(*env, req.Progress)
+	if dst, ok := req.Dst.(pack.DeltaService); ok {
+		base, err := findDeltaBase(req.Dst, *env)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if base != nil {
+			env, err := uploadPackageDelta(req, dst, *env, *base, progress)
+			if err == nil || trace.IsAlreadyExists(err) {
+				return env, trace.Wrap(err)
+			}
+			log.Warnf("Failed to upload %v as a delta against %v, will upload the whole package: %v.",
+				req.Package, base, trace.DebugReport(err))
+			// the package is read again from the start
+			if progress != nil {
+				progress.Reset()
+			}
+		}
+	}
+	env, reader, err := readPackage(req.Src, req.Dst, *env)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	return req.writePackage(withProgress(reader, progress))
+}
+
+// ReadPackage returns the contents of the specified package from src.
+//
+// If src supports deltas and dst already has an older version of the package,
+// only the delta between the versions is downloaded and the package is
+// reconstructed from the version in dst and verified against its checksum.
+// Otherwise, the whole package is read from src
+func ReadPackage(src, dst pack.PackageService, locator loc.Locator) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	env, err := src.ReadPackageEnvelope(locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return readPackage(src, dst, *env)
+}
+
+func readPackage(src, dst pack.PackageService, env pack.PackageEnvelope) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	if srcDelta, ok := src.(pack.DeltaService); ok {
+		base, err := findDeltaBase(dst, env)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		if base != nil {
+			reader, err := downloadPackageDelta(srcDelta, dst, env, *base)
+			if err == nil {
+				return &env, reader, nil
+			}
+			log.Warnf("Failed to download %v as a delta against %v, will download the whole package: %v.",
+				env.Locator, base, trace.DebugReport(err))
+		}
+	}
+	return src.ReadPackage(env.Locator)
+}
+
+// uploadPackageDelta uploads the package as a delta against the base package
+// found in the destination package service
+func uploadPackageDelta(req CopyPackageRequest, dst pack.DeltaService, env pack.PackageEnvelope, base loc.Locator, progress *pack.ProgressWriter) (*pack.PackageEnvelope, error) {
+	signature, err := dst.ReadPackageSignature(base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	_, reader, err := req.Src.ReadPackage(req.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	deltaReader := delta.NewReader(*signature, withProgress(reader, progress))
+	defer deltaReader.Close()
+	log.Infof("Uploading %v as a delta against %v.", req.Package, base)
+	return dst.WritePackageDelta(pack.PackageDeltaRequest{
+		Locator: req.Package,
+		Base:    base,
+		SHA512:  env.SHA512,
+		Delta:   deltaReader,
+		Upsert:  req.Upsert,
+		Options: req.Options,
+	})
+}
+
+// downloadPackageDelta downloads the package as a delta against the base package
+// found in the destination package service and returns the reconstructed package.
+// The package is reconstructed into a temporary file so that a package which
+// fails verification is detected before it is returned
+func downloadPackageDelta(src pack.DeltaService, dst pack.PackageService, env pack.PackageEnvelope, base loc.Locator) (io.ReadCloser, error) {
+	signature, err := pack.ReadPackageSignature(dst, base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	log.Infof("Downloading %v as a delta against %v.", env.Locator, base)
+	_, deltaReader, err := src.ReadPackageDelta(env.Locator, *signature)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer deltaReader.Close()
+	reader, err := pack.ApplyPackageDelta(dst, base, env.SHA512, deltaReader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	file, err := ioutil.TempFile("", "package-delta")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	cleanup := func() {
+		if err := os.Remove(file.Name()); err != nil {
+			log.Warnf("Failed to remove %v: %v.", file.Name(), err)
+		}
+	}
+	_, err = io.Copy(file, reader)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		cleanup()
+		return nil, trace.Wrap(err)
+	}
+	return &utils.CleanupReadCloser{
+		ReadCloser: file,
+		Cleanup:    cleanup,
+	}, nil
+}
+
+// findDeltaBase returns the package in packages that can be used as a base
+// for the delta of the package specified with env.
+// Returns nil if there is no suitable package
+func findDeltaBase(packages pack.PackageService, env pack.PackageEnvelope) (*loc.Locator, error) {
+	if env.Encrypted {
+		return nil, nil
+	}
+	base, err := pack.FindDeltaBase(packages, env.Locator)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	return base, nil
+}
+
+func (r CopyPackageRequest) writePackage(reader io.Reader) (*pack.PackageEnvelope, error) {
+	if r.Upsert {
+		return r.Dst.UpsertPackage(r.Package, reader, r.Options...)
+	}
+	return r.Dst.CreatePackage(r.Package, reader, r.Options...)
+}
+
+// newProgressWriter returns a writer that reports the progress of reading
+// the package specified with env to progress.
+// Returns nil if progress is nil
+func newProgressWriter(env pack.PackageEnvelope, progress pack.ProgressReporter) *pack.ProgressWriter {
+	if progress == nil {
+		return nil
+	}
+	return &pack.ProgressWriter{
+		Size: env.SizeBytes,
+		R:    progress,
+	}
+}
+
+func withProgress(reader io.ReadCloser, progress *pack.ProgressWriter) io.ReadCloser {
+	if progress == nil {
+		return reader
+	}
+	return utils.TeeReadCloser(reader, progress)
+}
+
+var log = logrus.WithField(trace.Component, "transfer")
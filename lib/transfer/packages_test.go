@@ -0,0 +1,377 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/pack/webpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/users"
+	"github.com/gravitational/gravity/lib/users/usersservice"
+
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type PackagesSuite struct {
+	local     pack.PackageService
+	remote    pack.PackageService
+	client    *webpack.Client
+	webServer *httptest.Server
+	// sent counts the bytes of package data sent to the remote service
+	sent int64
+	// received counts the bytes of package data received from the remote service
+	received int64
+}
+
+var _ = Suite(&PackagesSuite{})
+
+func (s *PackagesSuite) SetUpTest(c *C) {
+	s.local = newPackageService(c)
+	s.remote = newPackageService(c)
+
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, IsNil)
+	identity, err := usersservice.New(usersservice.Config{Backend: backend})
+	c.Assert(err, IsNil)
+	role, err := users.NewAdminRole()
+	c.Assert(err, IsNil)
+	c.Assert(identity.UpsertRole(role, storage.Forever), IsNil)
+	c.Assert(identity.UpsertUser(storage.NewUser("admin@example.com", storage.UserSpecV2{
+		Password: "admin-password",
+		Type:     storage.AdminUser,
+		Roles:    []string{role.GetName()},
+	})), IsNil)
+
+	handler, err := webpack.NewHandler(webpack.Config{
+		Users:    identity,
+		Packages: s.remote,
+	})
+	c.Assert(err, IsNil)
+	s.sent, s.received = 0, 0
+	s.webServer = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/packages") ||
+			strings.HasSuffix(r.URL.Path, "/deltas") {
+			r.Body = &countingReader{ReadCloser: r.Body, count: &s.sent}
+		}
+		if strings.HasSuffix(r.URL.Path, "/file") || strings.HasSuffix(r.URL.Path, "/delta") {
+			w = &countingWriter{ResponseWriter: w, count: &s.received}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+
+	s.client, err = webpack.NewAuthenticatedClient(
+		s.webServer.URL, "admin@example.com", "admin-password",
+		roundtrip.HTTPClient(s.webServer.Client()))
+	c.Assert(err, IsNil)
+}
+
+func (s *PackagesSuite) TearDownTest(c *C) {
+	if s.webServer != nil {
+		s.webServer.Close()
+	}
+}
+
+func (s *PackagesSuite) TestUploadsDelta(c *C) {
+	base := randomData(1, 512*1024)
+	target := concat(base[:200000], []byte("update"), base[200000:])
+	s.createPackage(c, s.local, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.0.0", base)
+	s.createPackage(c, s.local, "app:2.0.0", target)
+
+	env, err := CopyPackage(CopyPackageRequest{
+		Src:     s.local,
+		Dst:     s.client,
+		Package: loc.MustParseLocator("example.com/app:2.0.0"),
+		Options: []pack.PackageOption{pack.WithLabels(map[string]string{"key": "value"})},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(env.SHA512, Equals, s.envelope(c, s.local, "app:2.0.0").SHA512)
+	c.Assert(env.RuntimeLabels, DeepEquals, map[string]string{"key": "value"})
+	s.assertContents(c, s.remote, "app:2.0.0", target)
+	c.Assert(atomic.LoadInt64(&s.sent) < int64(len(target))/10, Equals, true,
+		Commentf("sent %v bytes", s.sent))
+}
+
+func (s *PackagesSuite) TestRestartsProgressWhenDeltaFails(c *C) {
+	base := randomData(1, 512*1024)
+	target := concat(base[:200000], []byte("update"), base[200000:])
+	s.createPackage(c, s.local, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.0.0", base)
+	s.createPackage(c, s.local, "app:2.0.0", target)
+
+	var reports []int64
+	_, err := CopyPackage(CopyPackageRequest{
+		Src:     s.local,
+		Dst:     failingDeltaService{s.remote},
+		Package: loc.MustParseLocator("example.com/app:2.0.0"),
+		Progress: pack.ProgressReporterFn(func(current, target int64) {
+			reports = append(reports, current)
+		}),
+	})
+	c.Assert(err, IsNil)
+	s.assertContents(c, s.remote, "app:2.0.0", target)
+
+	// progress restarts from zero for the whole package upload
+	// and is not counted twice
+	restarts := 0
+	for i, current := range reports {
+		c.Assert(current <= int64(len(target)), Equals, true, Commentf("reported %v", current))
+		if i > 0 && current < reports[i-1] {
+			c.Assert(current, Equals, int64(0))
+			restarts++
+		}
+	}
+	c.Assert(restarts, Equals, 1)
+	c.Assert(reports[len(reports)-1], Equals, int64(len(target)))
+}
+
+func (s *PackagesSuite) TestDownloadsDelta(c *C) {
+	base := randomData(1, 512*1024)
+	target := concat(base[:100000], base[101000:], []byte("appended"))
+	s.createPackage(c, s.local, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.1.0", target)
+
+	env, err := CopyPackage(CopyPackageRequest{
+		Src:     s.client,
+		Dst:     s.local,
+		Package: loc.MustParseLocator("example.com/app:1.1.0"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(env.SHA512, Equals, s.envelope(c, s.remote, "app:1.1.0").SHA512)
+	s.assertContents(c, s.local, "app:1.1.0", target)
+	c.Assert(atomic.LoadInt64(&s.received) < int64(len(target))/10, Equals, true,
+		Commentf("received %v bytes", s.received))
+}
+
+func (s *PackagesSuite) TestCopiesWholePackageWithoutBase(c *C) {
+	data := randomData(1, 64*1024)
+	s.createPackage(c, s.remote, "app:1.0.0", data)
+	// newer versions cannot be used as a base
+	s.createPackage(c, s.local, "app:2.0.0", randomData(2, 64*1024))
+
+	_, err := CopyPackage(CopyPackageRequest{
+		Src:     s.client,
+		Dst:     s.local,
+		Package: loc.MustParseLocator("example.com/app:1.0.0"),
+	})
+	c.Assert(err, IsNil)
+	s.assertContents(c, s.local, "app:1.0.0", data)
+	c.Assert(atomic.LoadInt64(&s.received), Equals, int64(len(data)))
+}
+
+func (s *PackagesSuite) TestDeltaAgainstUnrelatedBase(c *C) {
+	base := randomData(1, 128*1024)
+	target := concat([]byte("prefix"), base)
+	// remote has different contents for the base version so the
+	// delta carries the whole package
+	s.createPackage(c, s.local, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.0.0", randomData(2, 128*1024))
+	s.createPackage(c, s.local, "app:2.0.0", target)
+
+	_, err := CopyPackage(CopyPackageRequest{
+		Src:     s.local,
+		Dst:     s.client,
+		Package: loc.MustParseLocator("example.com/app:2.0.0"),
+	})
+	c.Assert(err, IsNil)
+	s.assertContents(c, s.remote, "app:2.0.0", target)
+}
+
+func (s *PackagesSuite) TestDeltasOfCompressedPackages(c *C) {
+	base := packageTarball(c, "1.0.0")
+	target := packageTarball(c, "2.0.0")
+	s.createPackage(c, s.local, "app:1.0.0", base)
+	s.createPackage(c, s.remote, "app:1.0.0", base)
+	s.createPackage(c, s.local, "app:2.0.0", target)
+
+	_, err := CopyPackage(CopyPackageRequest{
+		Src:     s.local,
+		Dst:     s.client,
+		Package: loc.MustParseLocator("example.com/app:2.0.0"),
+	})
+	c.Assert(err, IsNil)
+	s.assertContents(c, s.remote, "app:2.0.0", target)
+	c.Assert(atomic.LoadInt64(&s.sent) < int64(len(target))/10, Equals, true,
+		Commentf("sent %v bytes of %v", s.sent, len(target)))
+
+	s.createPackage(c, s.remote, "app:3.0.0", packageTarball(c, "3.0.0"))
+	_, err = CopyPackage(CopyPackageRequest{
+		Src:     s.client,
+		Dst:     s.local,
+		Package: loc.MustParseLocator("example.com/app:3.0.0"),
+	})
+	c.Assert(err, IsNil)
+	s.assertContents(c, s.local, "app:3.0.0", packageTarball(c, "3.0.0"))
+	c.Assert(atomic.LoadInt64(&s.received) < int64(len(target))/10, Equals, true,
+		Commentf("received %v bytes of %v", s.received, len(target)))
+}
+
+func (s *PackagesSuite) TestRejectsDeltaForAnotherRepository(c *C) {
+	data := randomData(1, 64*1024)
+	s.createPackage(c, s.remote, "app:1.0.0", data)
+	c.Assert(s.remote.UpsertRepository("other.com", time.Time{}), IsNil)
+
+	values := url.Values{
+		"locator": []string{"example.com/app:2.0.0"},
+		"base":    []string{"example.com/app:1.0.0"},
+		"sha512":  []string{"checksum"},
+	}
+	_, err := s.client.PostForm(s.client.Endpoint("repositories", "other.com", "deltas"), values,
+		roundtrip.File{Name: "package", Filename: "app", Reader: bytes.NewReader(nil)})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("expected BadParameter, got %v", err))
+}
+
+func (s *PackagesSuite) createPackage(c *C, packages pack.PackageService, name string, data []byte) {
+	locator := loc.MustParseLocator("example.com/" + name)
+	c.Assert(packages.UpsertRepository(locator.Repository, time.Time{}), IsNil)
+	_, err := packages.CreatePackage(locator, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+}
+
+func (s *PackagesSuite) envelope(c *C, packages pack.PackageService, name string) *pack.PackageEnvelope {
+	env, err := packages.ReadPackageEnvelope(loc.MustParseLocator("example.com/" + name))
+	c.Assert(err, IsNil)
+	return env
+}
+
+func (s *PackagesSuite) assertContents(c *C, packages pack.PackageService, name string, data []byte) {
+	_, reader, err := packages.ReadPackage(loc.MustParseLocator("example.com/" + name))
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	out, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(out, data), Equals, true)
+}
+
+// failingDeltaService is a package service that fails to create packages
+// from deltas after consuming the delta
+type failingDeltaService struct {
+	pack.PackageService
+}
+
+func (r failingDeltaService) ReadPackageSignature(locator loc.Locator) (*delta.Signature, error) {
+	return pack.ReadPackageSignature(r.PackageService, locator)
+}
+
+func (r failingDeltaService) ReadPackageDelta(loc.Locator, delta.Signature) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	return nil, nil, trace.NotImplemented("not implemented")
+}
+
+func (r failingDeltaService) WritePackageDelta(req pack.PackageDeltaRequest) (*pack.PackageEnvelope, error) {
+	if _, err := io.Copy(ioutil.Discard, req.Delta); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return nil, trace.ConnectionProblem(nil, "connection reset")
+}
+
+func newPackageService(c *C) pack.PackageService {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(dir, "bolt.db")})
+	c.Assert(err, IsNil)
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	return packages
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.count, int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	count *int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	atomic.AddInt64(w.count, int64(n))
+	return n, err
+}
+
+// packageTarball returns a gzip-compressed package tarball with
+// the application manifest for the specified version and a few
+// container image layers shared by all versions
+func packageTarball(c *C, version string) []byte {
+	var buf bytes.Buffer
+	compressed := gzip.NewWriter(&buf)
+	tarball := archive.NewReproducibleTarAppender(compressed, time.Unix(0, 0))
+	items := []*archive.Item{
+		archive.ItemFromString("resources/app.yaml", fmt.Sprintf(
+			"apiVersion: bundle.gravitational.io/v2\nkind: Bundle\nmetadata:\n  name: app\n  resourceVersion: %v\n", version)),
+	}
+	for i := 0; i < 4; i++ {
+		items = append(items, archive.ItemFromString(
+			fmt.Sprintf("registry/docker/registry/v2/blobs/sha256/layer-%v/data", i),
+			base64.StdEncoding.EncodeToString(randomData(int64(i), 128*1024))))
+	}
+	c.Assert(tarball.Add(items...), IsNil)
+	c.Assert(tarball.Close(), IsNil)
+	c.Assert(compressed.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
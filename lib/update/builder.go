@@ -21,6 +21,7 @@ import (
 	"path"
 
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 )
 
 // AddSequential will append sub-phases which depend one upon another
@@ -51,6 +52,21 @@ func (p *Phase) AddWithDependency(dep PhaseIder, subs ...Phase) {
 	}
 }
 
+// RequirePreceding makes each sub-phase that declares its own requirements
+// also depend on the sub-phase preceding it, so the sub-phases are executed
+// in order even when the plan is executed as a dependency graph.
+// Sub-phases without requirements already depend on their preceding sibling
+func (p *Phase) RequirePreceding() {
+	for i := 1; i < len(p.Phases); i++ {
+		phase := &p.Phases[i]
+		previous := p.Phases[i-1].ID
+		if len(phase.Requires) == 0 || utils.StringInSlice(phase.Requires, previous) {
+			continue
+		}
+		phase.Requires = append(phase.Requires, previous)
+	}
+}
+
 // Child formats sub as a child of this phase and returns the path
 func (p *Phase) Child(sub Phase) string {
 	return p.ChildLiteral(sub.ID)
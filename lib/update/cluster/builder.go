@@ -69,14 +69,16 @@ func (r phaseBuilder) checks() *update.Phase {
 	return &phase
 }
 
-func (r phaseBuilder) bootstrap() *update.Phase {
+// bootstrap returns a new phase that bootstraps the update operation on all nodes.
+// The nodes only depend on the specified phase and can be bootstrapped concurrently
+func (r phaseBuilder) bootstrap(dep update.PhaseIder) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "bootstrap",
 		Description: "Bootstrap update operation on nodes",
 	})
 
 	for i, server := range r.servers {
-		root.AddParallel(update.Phase{
+		root.AddWithDependency(dep, update.Phase{
 			ID:          root.ChildLiteral(server.Hostname),
 			Executor:    updateBootstrap,
 			Description: fmt.Sprintf("Bootstrap node %q", server.Hostname),
@@ -105,6 +107,21 @@ func (r phaseBuilder) preUpdate() *update.Phase {
 	return &phase
 }
 
+// status returns a new phase that verifies the updated application
+// using its status hook
+func (r phaseBuilder) status(leadMaster storage.UpdateServer) *update.Phase {
+	phase := update.RootPhase(update.Phase{
+		ID:          "status",
+		Description: "Verify application status",
+		Executor:    appStatus,
+		Data: &storage.OperationPhaseData{
+			ExecServer: &leadMaster.Server,
+			Package:    &r.updateApp.Package,
+		},
+	})
+	return &phase
+}
+
 func (r phaseBuilder) corednsPhase(leadMaster storage.Server) *update.Phase {
 	phase := update.RootPhase(update.Phase{
 		ID:          "coredns",
@@ -202,14 +219,15 @@ func (r phaseBuilder) earlyDNSApp(locator loc.Locator) *update.Phase {
 	return &phase
 }
 
-// config returns phase that pulls system configuration on provided nodes
-func (r phaseBuilder) config(nodes []storage.Server) *update.Phase {
+// config returns phase that pulls system configuration on provided nodes.
+// The nodes only depend on the specified phase and can be configured concurrently
+func (r phaseBuilder) config(nodes []storage.Server, dep update.PhaseIder) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "config",
 		Description: "Update system configuration on nodes",
 	})
 	for i, node := range nodes {
-		root.AddParallel(update.Phase{
+		root.AddWithDependency(dep, update.Phase{
 			ID:       root.ChildLiteral(node.Hostname),
 			Executor: config,
 			Description: fmt.Sprintf("Update system configuration on node %q",
@@ -304,7 +322,10 @@ func (r phaseBuilder) masters(leadMaster storage.UpdateServer, otherMasters []st
 	return &root
 }
 
-func (r phaseBuilder) nodes(leadMaster storage.UpdateServer, nodes []storage.UpdateServer, supportsTaints bool) *update.Phase {
+// nodes returns a new phase for updating regular servers.
+// The nodes only depend on the specified phase and can be updated concurrently
+func (r phaseBuilder) nodes(leadMaster storage.UpdateServer, nodes []storage.UpdateServer, supportsTaints bool,
+	dep update.PhaseIder) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "nodes",
 		Description: "Update regular nodes",
@@ -314,11 +335,96 @@ func (r phaseBuilder) nodes(leadMaster storage.UpdateServer, nodes []storage.Upd
 		node := r.node(server.Server, &root, "Update system software on node %q")
 		node.AddSequential(r.commonNode(nodes[i], leadMaster, supportsTaints,
 			waitsForEndpoints(true))...)
-		root.AddParallel(node)
+		root.AddWithDependency(dep, node)
+	}
+	return &root
+}
+
+// nodesWithStrategy returns a new phase for updating regular servers
+// according to the specified update strategy.
+//
+// With canary enabled, the first node is updated on its own before any other node.
+// The rest of the nodes are updated in batches of the configured size with nodes
+// of the same batch updated in parallel. The cluster health is verified after
+// the canary and after each batch
+func (r phaseBuilder) nodesWithStrategy(leadMaster storage.UpdateServer, nodes []storage.UpdateServer,
+	supportsTaints bool, strategy storage.UpdateStrategy) *update.Phase {
+	root := update.RootPhase(update.Phase{
+		ID:          "nodes",
+		Description: "Update regular nodes",
+	})
+	if strategy.Canary && len(nodes) != 0 {
+		root.AddSequential(r.nodeGroup(&root, "canary", "Update canary node",
+			leadMaster, nodes[:1], supportsTaints)...)
+		nodes = nodes[1:]
+	}
+	batchSize := strategy.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	for batch := 1; len(nodes) != 0; batch++ {
+		size := batchSize
+		if size > len(nodes) {
+			size = len(nodes)
+		}
+		root.AddSequential(r.nodeGroup(&root, fmt.Sprintf("batch-%v", batch),
+			fmt.Sprintf("Update batch %v of regular nodes", batch),
+			leadMaster, nodes[:size], supportsTaints)...)
+		nodes = nodes[size:]
 	}
 	return &root
 }
 
+// nodeGroup returns the phases that update the specified nodes in parallel
+// and then verify the cluster health
+func (r phaseBuilder) nodeGroup(parent *update.Phase, id, description string,
+	leadMaster storage.UpdateServer, nodes []storage.UpdateServer, supportsTaints bool) []update.Phase {
+	group := update.Phase{
+		ID:          parent.ChildLiteral(id),
+		Description: description,
+		Parallel:    len(nodes) > 1,
+	}
+	for i, server := range nodes {
+		node := r.node(server.Server, &group, "Update system software on node %q")
+		node.AddSequential(r.commonNode(nodes[i], leadMaster, supportsTaints,
+			waitsForEndpoints(true))...)
+		group.AddParallel(node)
+	}
+	health := r.health(parent.ChildLiteral(id+"-health"), leadMaster, nodes)
+	return []update.Phase{group, *health}
+}
+
+// health returns a new phase that verifies the cluster health
+// after the specified nodes have been updated
+func (r phaseBuilder) health(id string, leadMaster storage.UpdateServer, nodes []storage.UpdateServer) *update.Phase {
+	var hostnames []string
+	for _, node := range nodes {
+		hostnames = append(hostnames, node.Hostname)
+	}
+	return &update.Phase{
+		ID:       id,
+		Executor: clusterHealth,
+		Description: fmt.Sprintf("Verify cluster health after updating %v",
+			strings.Join(hostnames, ", ")),
+		Data: &storage.OperationPhaseData{
+			ExecServer: &leadMaster.Server,
+			Update: &storage.UpdateOperationData{
+				Servers: nodes,
+			},
+		},
+	}
+}
+
+// addHealth adds the phase that verifies the cluster health after
+// all subphases of the specified phase have completed
+func (r phaseBuilder) addHealth(phase *update.Phase, leadMaster storage.UpdateServer, nodes []storage.UpdateServer) {
+	health := r.health(phase.ChildLiteral("health"), leadMaster, nodes)
+	for _, sub := range phase.Phases {
+		health.Require(update.Phase(sub))
+	}
+	phase.Add(*health)
+}
+
 func (r phaseBuilder) etcdPlan(
 	leadMaster storage.Server,
 	otherMasters []storage.Server,
@@ -558,7 +664,9 @@ func (r phaseBuilder) commonNode(server, leadMaster storage.UpdateServer, suppor
 	return phases
 }
 
-func (r phaseBuilder) cleanup() *update.Phase {
+// cleanup returns a new phase that runs cleanup tasks on all nodes.
+// The nodes only depend on the specified phase and can be cleaned up concurrently
+func (r phaseBuilder) cleanup(dep update.PhaseIder) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "gc",
 		Description: "Run cleanup tasks",
@@ -570,7 +678,7 @@ func (r phaseBuilder) cleanup() *update.Phase {
 		node.Data = &storage.OperationPhaseData{
 			Server: &r.servers[i].Server,
 		}
-		root.AddParallel(node)
+		root.AddWithDependency(dep, node)
 	}
 	return &root
 }
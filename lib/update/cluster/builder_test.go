@@ -19,11 +19,13 @@ package cluster
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/app"
 	apptest "github.com/gravitational/gravity/lib/app/service/test"
 	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
@@ -31,6 +33,7 @@ import (
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	"gopkg.in/check.v1"
@@ -256,6 +259,240 @@ func (s *PlanSuite) TestCorrectlyDeterminesWhetherToUpdateEtcd(c *check.C) {
 	c.Assert(updateVersion, check.Equals, "3.3.3")
 }
 
+func (s *PlanSuite) TestUpdatesNodesWithCanaryAndBatches(c *check.C) {
+	var nodes []storage.UpdateServer
+	for i := 1; i <= 4; i++ {
+		nodes = append(nodes, storage.UpdateServer{
+			Server: storage.Server{
+				AdvertiseIP: fmt.Sprintf("192.168.1.%v", 10+i),
+				Hostname:    fmt.Sprintf("worker-%v", i),
+				Role:        "node",
+				ClusterRole: string(schema.ServiceRoleNode),
+			},
+		})
+	}
+	builder := phaseBuilder{}
+	phase := builder.nodesWithStrategy(updates[0], nodes, false,
+		storage.UpdateStrategy{Canary: true, BatchSize: 2})
+	plan := storage.OperationPlan{Phases: []storage.OperationPhase{storage.OperationPhase(*phase)}}
+	update.ResolvePlan(&plan)
+
+	groups := plan.Phases[0].Phases
+	var ids []string
+	for _, group := range groups {
+		ids = append(ids, group.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/nodes/canary",
+		"/nodes/canary-health",
+		"/nodes/batch-1",
+		"/nodes/batch-1-health",
+		"/nodes/batch-2",
+		"/nodes/batch-2-health",
+	})
+	// canary is updated on its own and verified before the batches
+	c.Assert(groups[0].Parallel, check.Equals, false)
+	c.Assert(groups[0].Phases, check.HasLen, 1)
+	c.Assert(groups[0].Phases[0].ID, check.Equals, "/nodes/canary/worker-1")
+	c.Assert(groups[1].Executor, check.Equals, clusterHealth)
+	c.Assert(groups[1].Requires, check.DeepEquals, []string{"/nodes/canary"})
+	c.Assert(groups[1].Data.Update.Servers, check.DeepEquals, nodes[:1])
+	// nodes of the same batch are updated in parallel
+	c.Assert(groups[2].Parallel, check.Equals, true)
+	c.Assert(groups[2].Requires, check.DeepEquals, []string{"/nodes/canary-health"})
+	c.Assert(groups[2].Phases, check.HasLen, 2)
+	c.Assert(groups[3].Data.Update.Servers, check.DeepEquals, nodes[1:3])
+	// the last batch holds the remaining node
+	c.Assert(groups[4].Parallel, check.Equals, false)
+	c.Assert(groups[4].Phases[0].ID, check.Equals, "/nodes/batch-2/worker-4")
+}
+
+func (s *PlanSuite) TestVerifiesClusterWhenRollbackOnFailure(c *check.C) {
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+
+	params := params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc1,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    installedRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+		dnsConfig:                storage.DefaultDNSConfig,
+		leadMaster:               updates[0],
+	}
+	config := newTestPlan(c, params)
+	config.operation.Update = &storage.UpdateOperationState{RollbackOnFailure: true}
+
+	plan, err := newOperationPlan(config)
+	c.Assert(err, check.IsNil)
+	update.ResolvePlan(plan)
+
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/init",
+		"/checks",
+		"/pre-update",
+		"/app",
+		"/health",
+		"/status",
+		"/gc",
+	})
+	// the cluster and the application are verified after the update
+	// and before the cleanup
+	health, status := plan.Phases[4], plan.Phases[5]
+	c.Assert(health.Executor, check.Equals, clusterHealth)
+	c.Assert(health.Requires, check.DeepEquals, []string{"/app"})
+	c.Assert(health.Data.Update.Servers, check.DeepEquals, updates)
+	c.Assert(status.Executor, check.Equals, appStatus)
+	c.Assert(status.Requires, check.DeepEquals, []string{"/health"})
+	c.Assert(*status.Data.Package, check.DeepEquals, appLoc2)
+	c.Assert(plan.Phases[6].Requires, check.DeepEquals, []string{"/status"})
+}
+
+func (s *PlanSuite) TestVerifiesClusterAfterEachStepWhenRollbackOnFailure(c *check.C) {
+	params := params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+		dnsConfig:                storage.DefaultDNSConfig,
+		leadMaster:               updates[0],
+	}
+	config := newTestPlan(c, params)
+	config.operation.Update = &storage.UpdateOperationState{RollbackOnFailure: true}
+
+	plan, err := newOperationPlan(config)
+	c.Assert(err, check.IsNil)
+	update.ResolvePlan(plan)
+	graph, err := fsm.NewPlanGraph(*plan)
+	c.Assert(err, check.IsNil)
+
+	// the cluster health is verified as the last step of each phase
+	// that updates the nodes, etcd or the system applications
+	for _, id := range []string{"/masters", "/nodes", "/etcd", "/runtime"} {
+		phase, err := fsm.FindPhase(plan, id)
+		c.Assert(err, check.IsNil)
+		health := phase.Phases[len(phase.Phases)-1]
+		c.Assert(health.ID, check.Equals, id+"/health")
+		c.Assert(health.Executor, check.Equals, clusterHealth)
+		for _, sub := range leafPhases(graph, id) {
+			if sub != health.ID {
+				c.Assert(dependsOn(graph, health.ID, sub), check.Equals, true,
+					check.Commentf("%v should run after %v", health.ID, sub))
+			}
+		}
+	}
+}
+
+func (s *PlanSuite) TestPlanGraphKeepsPhaseOrder(c *check.C) {
+	params := params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+		updateCoreDNS:            true,
+		dnsConfig:                storage.DefaultDNSConfig,
+		leadMaster:               updates[0],
+	}
+	plan, err := newOperationPlan(newTestPlan(c, params))
+	c.Assert(err, check.IsNil)
+	update.ResolvePlan(plan)
+	graph, err := fsm.NewPlanGraph(*plan)
+	c.Assert(err, check.IsNil)
+
+	// top-level phases run in plan order even if they only declare
+	// a dependency on an earlier phase
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/init", "/checks", "/pre-update", "/coredns", "/bootstrap", "/masters",
+		"/nodes", "/etcd", "/migration", "/config", "/runtime", "/app", "/gc",
+	})
+	for i := 1; i < len(ids); i++ {
+		assertRunsAfter(c, graph, ids[i], ids[i-1])
+	}
+	// subphases of the master update run one after another
+	masters := plan.Phases[5].Phases
+	for i := 1; i < len(masters); i++ {
+		assertRunsAfter(c, graph, masters[i].ID, masters[i-1].ID)
+	}
+	// per-node phases do not depend on each other
+	for _, phase := range []storage.OperationPhase{plan.Phases[4], plan.Phases[9], plan.Phases[12]} {
+		for _, node := range phase.Phases {
+			for _, other := range phase.Phases {
+				c.Assert(utils.StringInSlice(graph.Requires(node.ID), other.ID), check.Equals, false,
+					check.Commentf("%v should not depend on %v", node.ID, other.ID))
+			}
+		}
+	}
+}
+
+// assertRunsAfter verifies that all executable phases of the specified phase
+// directly depend on all executable phases of the other phase
+func assertRunsAfter(c *check.C, graph *fsm.PlanGraph, phaseID, otherID string) {
+	phases, others := leafPhases(graph, phaseID), leafPhases(graph, otherID)
+	c.Assert(phases, check.Not(check.HasLen), 0)
+	c.Assert(others, check.Not(check.HasLen), 0)
+	for _, phase := range phases {
+		requires := make(map[string]bool)
+		for _, required := range graph.Requires(phase) {
+			requires[required] = true
+		}
+		for _, other := range others {
+			c.Assert(requires[other], check.Equals, true,
+				check.Commentf("%v should run after %v", phase, other))
+		}
+	}
+}
+
+// dependsOn returns true if the specified executable phase depends
+// on the other executable phase directly or transitively
+func dependsOn(graph *fsm.PlanGraph, phaseID, otherID string) bool {
+	visited := make(map[string]bool)
+	queue := []string{phaseID}
+	for len(queue) != 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, required := range graph.Requires(id) {
+			if required == otherID {
+				return true
+			}
+			if !visited[required] {
+				visited[required] = true
+				queue = append(queue, required)
+			}
+		}
+	}
+	return false
+}
+
+// leafPhases returns IDs of the executable phases of the specified phase
+func leafPhases(graph *fsm.PlanGraph, phaseID string) (result []string) {
+	for _, id := range graph.Phases {
+		if id == phaseID || strings.HasPrefix(id, phaseID+"/") {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 func newTestPlan(c *check.C, params params) planConfig {
 	config := planConfig{
 		operator:  testOperator,
@@ -344,7 +581,7 @@ func (r *params) preUpdate() storage.OperationPhase {
 		ID:          "/pre-update",
 		Executor:    preUpdate,
 		Description: "Run pre-update application hook",
-		Requires:    []string{"/init"},
+		Requires:    []string{"/init", "/checks"},
 		Data: &storage.OperationPhaseData{
 			Package: &r.updateApp,
 		},
@@ -366,12 +603,13 @@ func (r *params) bootstrap() storage.OperationPhase {
 	return storage.OperationPhase{
 		ID:          "/bootstrap",
 		Description: "Bootstrap update operation on nodes",
-		Requires:    []string{"/init"},
+		Requires:    []string{"/init", "/coredns"},
 		Phases: []storage.OperationPhase{
 			{
 				ID:          "/bootstrap/node-1",
 				Executor:    updateBootstrap,
 				Description: `Bootstrap node "node-1"`,
+				Requires:    []string{"/init"},
 				Data: &storage.OperationPhaseData{
 					ExecServer:       &servers[0],
 					Package:          &r.updateApp,
@@ -385,6 +623,7 @@ func (r *params) bootstrap() storage.OperationPhase {
 				ID:          "/bootstrap/node-2",
 				Executor:    updateBootstrap,
 				Description: `Bootstrap node "node-2"`,
+				Requires:    []string{"/init"},
 				Data: &storage.OperationPhaseData{
 					ExecServer:       &servers[1],
 					Package:          &r.updateApp,
@@ -398,6 +637,7 @@ func (r *params) bootstrap() storage.OperationPhase {
 				ID:          "/bootstrap/node-3",
 				Executor:    updateBootstrap,
 				Description: `Bootstrap node "node-3"`,
+				Requires:    []string{"/init"},
 				Data: &storage.OperationPhaseData{
 					ExecServer:       &servers[2],
 					Package:          &r.updateApp,
@@ -587,6 +827,7 @@ func (r *params) nodePhase(server storage.UpdateServer) storage.OperationPhase {
 	return storage.OperationPhase{
 		ID:          t("/nodes/%v"),
 		Description: t("Update system software on node %q"),
+		Requires:    []string{"/masters"},
 		Phases: []storage.OperationPhase{
 			{
 				ID:          t("/nodes/%v/drain"),
@@ -820,12 +1061,13 @@ func (r params) config() storage.OperationPhase {
 	return storage.OperationPhase{
 		ID:          "/config",
 		Description: "Update system configuration on nodes",
-		Requires:    []string{"/masters"},
+		Requires:    []string{"/masters", "/migration"},
 		Phases: []storage.OperationPhase{
 			{
 				ID:          "/config/node-1",
 				Executor:    config,
 				Description: `Update system configuration on node "node-1"`,
+				Requires:    []string{"/masters"},
 				Data: &storage.OperationPhaseData{
 					Server: &servers[0],
 				},
@@ -834,6 +1076,7 @@ func (r params) config() storage.OperationPhase {
 				ID:          "/config/node-2",
 				Executor:    config,
 				Description: `Update system configuration on node "node-2"`,
+				Requires:    []string{"/masters"},
 				Data: &storage.OperationPhaseData{
 					Server: &servers[1],
 				},
@@ -849,7 +1092,7 @@ func (r params) runtime() storage.OperationPhase {
 	return storage.OperationPhase{
 		ID:          "/runtime",
 		Description: "Update application runtime",
-		Requires:    []string{"/masters"},
+		Requires:    []string{"/masters", "/config"},
 		Phases: []storage.OperationPhase{
 			{
 				ID:          "/runtime/rbac-app",
@@ -919,6 +1162,7 @@ func (r params) cleanup() storage.OperationPhase {
 				ID:          "/gc/node-1",
 				Executor:    cleanupNode,
 				Description: `Clean up node "node-1"`,
+				Requires:    []string{"/app"},
 				Data: &storage.OperationPhaseData{
 					Server: &updates[0].Server,
 				},
@@ -927,6 +1171,7 @@ func (r params) cleanup() storage.OperationPhase {
 				ID:          "/gc/node-2",
 				Executor:    cleanupNode,
 				Description: `Clean up node "node-2"`,
+				Requires:    []string{"/app"},
 				Data: &storage.OperationPhaseData{
 					Server: &updates[1].Server,
 				},
@@ -935,6 +1180,7 @@ func (r params) cleanup() storage.OperationPhase {
 				ID:          "/gc/node-3",
 				Executor:    cleanupNode,
 				Description: `Clean up node "node-3"`,
+				Requires:    []string{"/app"},
 				Data: &storage.OperationPhaseData{
 					Server: &updates[2].Server,
 				},
@@ -101,9 +101,10 @@ func newMachine(ctx context.Context, c Config) (*fsm.FSM, error) {
 		return nil, trace.Wrap(err)
 	}
 	fsm, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Logger: logger,
-		Runner: c.Runner,
+		Engine:   engine,
+		Logger:   logger,
+		Runner:   c.Runner,
+		Parallel: c.Parallel,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
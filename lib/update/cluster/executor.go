@@ -80,6 +80,12 @@ const (
 	cleanupNode = "cleanup_node"
 	// openebs is the phase that creates OpenEBS configuration
 	openebs = "openebs"
+	// clusterHealth is the phase that verifies the cluster health
+	// after a group of nodes has been updated
+	clusterHealth = "cluster_health"
+	// appStatus is the phase that verifies the application status
+	// using its status hook
+	appStatus = "app_status"
 )
 
 // fsmSpec returns the function that returns an appropriate phase executor
@@ -158,11 +164,15 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 		case updateEtcdRestart:
 			return libphase.NewPhaseUpgradeEtcdRestart(p.Phase, logger)
 		case updateEtcdRestartGravity:
-			return libphase.NewPhaseUpgradeGravitySiteRestart(p.Phase, c.Client, logger)
+			return libphase.NewPhaseUpgradeGravitySiteRestart(p, c.Client, logger)
 		case cleanupNode:
 			return libphase.NewGarbageCollectPhase(p, remote, logger)
 		case openebs:
 			return installphases.NewOpenEBS(p, c.Operator, c.Client)
+		case clusterHealth:
+			return libphase.NewPhaseHealth(p, logger)
+		case appStatus:
+			return libphase.NewUpdatePhaseStatus(p, c.Apps, c.Client, logger)
 		default:
 			return nil, trace.BadParameter(
 				"phase %q requires executor %q (potential mismatch between upgrade versions)",
@@ -20,7 +20,9 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/resources"
@@ -34,6 +36,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
+	"github.com/cenkalti/backoff"
 	dockerarchive "github.com/docker/docker/pkg/archive"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
@@ -100,6 +103,29 @@ func (p *updatePhaseApp) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// HealthChecks verifies that the system pods are healthy after
+// a system application has been updated
+func (p *updatePhaseApp) HealthChecks() fsm.HealthChecks {
+	if path.Dir(p.Phase.ID) != runtimePhase {
+		return fsm.HealthChecks{}
+	}
+	return fsm.HealthChecks{
+		Checks: []fsm.HealthCheck{fsm.PodsHealthy(p.Client.CoreV1(), constants.KubeSystemNamespace)},
+	}
+}
+
+// DryRun describes the application hooks that would run
+func (p *updatePhaseApp) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	if p.Package.Name == constants.BootstrapConfigPackage {
+		return report.AddCommand("create bootstrap resources from %v", p.Package), nil
+	}
+	for _, hook := range []schema.HookType{schema.HookNetworkUpdate, schema.HookUpdate, schema.HookUpdated} {
+		report.AddCommand("run %v hook for %v", hook, p.Package)
+	}
+	return &report, nil
+}
+
 func (p *updatePhaseApp) createBootstrapResources() error {
 	reader, err := p.Apps.GetAppResources(p.Package)
 	if err != nil {
@@ -159,6 +185,73 @@ func (p *updatePhaseBeforeApp) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the application pre-update hook that would run
+func (p *updatePhaseBeforeApp) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("run %v hook for %v", schema.HookBeforeUpdate, p.Package), nil
+}
+
+// NewUpdatePhaseStatus returns a new executor that verifies the updated
+// application using its status hook
+func NewUpdatePhaseStatus(
+	p fsm.ExecutorParams,
+	apps app.Applications,
+	client *kubernetes.Clientset,
+	logger log.FieldLogger,
+) (*updatePhaseStatus, error) {
+	if p.Phase.Data == nil || p.Phase.Data.Package == nil {
+		return nil, trace.NotFound("no package specified for phase %q", p.Phase.ID)
+	}
+	return &updatePhaseStatus{
+		phaseApp: phaseApp{
+			FieldLogger:    logger,
+			ExecutorParams: p,
+			Apps:           apps,
+			Client:         client,
+			GravityPackage: p.Plan.GravityPackage,
+			Package:        *p.Phase.Data.Package,
+			Servers:        p.Plan.Servers,
+		},
+		timeout:  defaults.UpdateHealthTimeout,
+		interval: defaults.RetryInterval,
+	}, nil
+}
+
+// updatePhaseStatus is the executor that runs the status hook of the
+// updated application until it succeeds or the timeout expires
+type updatePhaseStatus struct {
+	phaseApp
+	// timeout specifies the maximum time to wait for the application to become healthy
+	timeout time.Duration
+	// interval specifies the interval between status hook runs
+	interval time.Duration
+}
+
+// Execute runs the application status hook
+func (p *updatePhaseStatus) Execute(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	var lastErr error
+	err := utils.RetryWithInterval(ctx, backoff.NewConstantBackOff(p.interval), func() error {
+		lastErr = p.runHooks(ctx, schema.HookStatus)
+		return lastErr
+	})
+	if err != nil && lastErr != nil {
+		return trace.Wrap(lastErr, "application did not become healthy within %v", p.timeout)
+	}
+	return trace.Wrap(err)
+}
+
+// Rollback is a no-op for this phase
+func (p *updatePhaseStatus) Rollback(context.Context) error {
+	return nil
+}
+
+// DryRun reports no changes as this phase only verifies the application status
+func (p *updatePhaseStatus) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 type phaseApp struct {
 	// Apps is the cluster apps service
 	Apps app.Applications
@@ -232,3 +325,6 @@ func streamHook(hook schema.HookType, reader io.ReadCloser, logger log.FieldLogg
 		logger.Warnf("Failed to stream logs for hook %v: %v.", hook, err)
 	}
 }
+
+// runtimePhase is the ID of the phase that updates the system applications
+const runtimePhase = "/runtime"
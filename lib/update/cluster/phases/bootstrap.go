@@ -175,6 +175,17 @@ func (p *updatePhaseBootstrap) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the gravity binary exported to the node and
+// the system packages pulled into the local package service
+func (p *updatePhaseBootstrap) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddFile(p.GravityPath)
+	for _, update := range p.systemUpdates() {
+		report.AddPackage("%v", update)
+	}
+	return &report, nil
+}
+
 func (p *updatePhaseBootstrap) configureNode() error {
 	err := p.Operator.ConfigureNode(ops.ConfigureNodeRequest{
 		AccountID:   p.Operation.AccountID,
@@ -215,23 +226,7 @@ func (p *updatePhaseBootstrap) updateDNSConfig() error {
 
 func (p *updatePhaseBootstrap) pullSystemUpdates(ctx context.Context) error {
 	p.Info("Pull system updates.")
-	updates := []loc.Locator{p.GravityPackage}
-	if p.Server.Runtime.SecretsPackage != nil {
-		updates = append(updates, *p.Server.Runtime.SecretsPackage)
-	}
-	if p.Server.Runtime.Update != nil {
-		updates = append(updates,
-			p.Server.Runtime.Update.Package,
-			p.Server.Runtime.Update.ConfigPackage,
-		)
-	}
-	if p.Server.Teleport.Update != nil {
-		updates = append(updates, p.Server.Teleport.Update.Package)
-		if p.Server.Teleport.Update.NodeConfigPackage != nil {
-			updates = append(updates, *p.Server.Teleport.Update.NodeConfigPackage)
-		}
-	}
-	for _, update := range updates {
+	for _, update := range p.systemUpdates() {
 		p.Infof("Pulling package update: %v.", update)
 		existingLabels, err := queryPackageLabels(update, p.LocalPackages)
 		if err != nil {
@@ -263,6 +258,27 @@ func (p *updatePhaseBootstrap) pullSystemUpdates(ctx context.Context) error {
 	return nil
 }
 
+// systemUpdates returns the list of packages to pull to the node
+func (p *updatePhaseBootstrap) systemUpdates() []loc.Locator {
+	updates := []loc.Locator{p.GravityPackage}
+	if p.Server.Runtime.SecretsPackage != nil {
+		updates = append(updates, *p.Server.Runtime.SecretsPackage)
+	}
+	if p.Server.Runtime.Update != nil {
+		updates = append(updates,
+			p.Server.Runtime.Update.Package,
+			p.Server.Runtime.Update.ConfigPackage,
+		)
+	}
+	if p.Server.Teleport.Update != nil {
+		updates = append(updates, p.Server.Teleport.Update.Package)
+		if p.Server.Teleport.Update.NodeConfigPackage != nil {
+			updates = append(updates, *p.Server.Teleport.Update.NodeConfigPackage)
+		}
+	}
+	return updates
+}
+
 func (p *updatePhaseBootstrap) syncPlan() error {
 	p.Info("Synchronize operation plan from cluster.")
 	site, err := p.Backend.GetSite(p.Operation.SiteDomain)
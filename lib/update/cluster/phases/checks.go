@@ -102,3 +102,8 @@ func (p *updatePhaseChecks) Execute(ctx context.Context) error {
 func (p *updatePhaseChecks) Rollback(context.Context) error {
 	return nil
 }
+
+// DryRun reports no changes as this phase only runs the preflight checks
+func (p *updatePhaseChecks) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
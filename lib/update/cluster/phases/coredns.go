@@ -125,6 +125,14 @@ func (p *updatePhaseCoreDNS) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the CoreDNS RBAC resources and configuration created by this phase
+func (p *updatePhaseCoreDNS) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddObject("clusterrole", CoreDNSResourceName)
+	report.AddObject("clusterrolebinding", CoreDNSResourceName)
+	return report.AddObject("configmap", "coredns"), nil
+}
+
 // generateCorefile will generate a coredns corefile, only if not already present on the system
 // with settings from the cluster configuration and local system. It should not overwrite an existing
 // Corefile, as that may have been modified by a user.
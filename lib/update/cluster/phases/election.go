@@ -111,10 +111,8 @@ func (p *phaseElectionChange) waitForMasterMigration(rollback bool) error {
 }
 
 func (p *phaseElectionChange) setElectionStatus(server storage.Server, enable bool) error {
-	key := fmt.Sprintf("/planet/cluster/%s/election/%s", p.ClusterName, server.AdvertiseIP)
-
 	out, err := fsm.RunCommand(utils.PlanetCommandArgs(defaults.EtcdCtlBin,
-		"set", key, fmt.Sprintf("%v", enable)))
+		"set", p.electionKey(server), fmt.Sprintf("%v", enable)))
 	return trace.Wrap(err, "setting leader election on %q to %v: %s", server.AdvertiseIP, enable, out)
 }
 
@@ -138,6 +136,23 @@ func (p *phaseElectionChange) Rollback(ctx context.Context) error {
 	return trace.Wrap(p.updateElectionStatus(true))
 }
 
+// DryRun describes the leader election changes made by this phase
+func (p *phaseElectionChange) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, server := range p.ElectionChange.DisableServers {
+		report.AddCommand("%v set %v false", defaults.EtcdCtlBin, p.electionKey(server))
+	}
+	for _, server := range p.ElectionChange.EnableServers {
+		report.AddCommand("%v set %v true", defaults.EtcdCtlBin, p.electionKey(server))
+	}
+	return &report, nil
+}
+
+// electionKey returns the etcd key with the leader election status of the specified server
+func (p *phaseElectionChange) electionKey(server storage.Server) string {
+	return fmt.Sprintf("/planet/cluster/%s/election/%s", p.ClusterName, server.AdvertiseIP)
+}
+
 func (p *phaseElectionChange) updateElectionStatus(rollback bool) error {
 	for _, server := range p.ElectionChange.DisableServers {
 		err := p.setElectionStatus(server, rollback)
@@ -18,11 +18,13 @@ package phases
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/kubernetes"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
@@ -99,6 +101,17 @@ func (p *PhaseUpgradeEtcdBackup) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the etcd backup command
+func (p *PhaseUpgradeEtcdBackup) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	backupFile, err := backupFile()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	report.AddCommand("planet etcd backup %v", backupFile)
+	return report.AddFile(backupFile), nil
+}
+
 func (*PhaseUpgradeEtcdBackup) PreCheck(context.Context) error {
 	// TODO(knisbet) should we check that there is enough free space available to hold the backup?
 	return nil
@@ -154,6 +167,12 @@ func (p *PhaseUpgradeEtcdShutdown) PreCheck(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the command that stops etcd on the node
+func (p *PhaseUpgradeEtcdShutdown) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddCommand("planet etcd disable --stop-api"), nil
+}
+
 func (*PhaseUpgradeEtcdShutdown) PostCheck(context.Context) error {
 	return nil
 }
@@ -217,6 +236,13 @@ func (*PhaseUpgradeEtcd) PostCheck(context.Context) error {
 	return nil
 }
 
+// DryRun describes the commands that upgrade etcd on the node
+func (p *PhaseUpgradeEtcd) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddCommand("planet etcd upgrade")
+	return report.AddCommand("planet etcd enable --upgrade"), nil
+}
+
 // PhaseUpgradeRestore restores etcd data from backup, if it was wiped by the upgrade stage
 type PhaseUpgradeEtcdRestore struct {
 	log.FieldLogger
@@ -266,6 +292,16 @@ func (*PhaseUpgradeEtcdRestore) PostCheck(context.Context) error {
 	return nil
 }
 
+// DryRun describes the command that restores etcd data from backup
+func (p *PhaseUpgradeEtcdRestore) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	backupFile, err := backupFile()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	return report.AddCommand("planet etcd restore %v", backupFile), nil
+}
+
 // PhaseUpgradeEtcdRestart disables the etcd-upgrade service, and starts the etcd service
 type PhaseUpgradeEtcdRestart struct {
 	log.FieldLogger
@@ -320,20 +356,33 @@ func (*PhaseUpgradeEtcdRestart) PostCheck(context.Context) error {
 	return nil
 }
 
+// DryRun describes the commands that restart etcd after the upgrade
+func (p *PhaseUpgradeEtcdRestart) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddCommand("planet etcd disable --upgrade")
+	return report.AddCommand("planet etcd enable"), nil
+}
+
 // PhaseUpgradeGravitySiteRestart restarts gravity-site pod
 type PhaseUpgradeGravitySiteRestart struct {
 	log.FieldLogger
 	Client *kubeapi.Clientset
+	// DNSConfig is the cluster DNS configuration used to resolve the cluster controller service
+	DNSConfig storage.DNSConfig
 }
 
-func NewPhaseUpgradeGravitySiteRestart(phase storage.OperationPhase, client *kubeapi.Clientset, logger log.FieldLogger) (fsm.PhaseExecutor, error) {
+func NewPhaseUpgradeGravitySiteRestart(p fsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (fsm.PhaseExecutor, error) {
 	if client == nil {
-		return nil, trace.BadParameter("phase %q must be run from a master node (requires kubernetes client)", phase.ID)
+		return nil, trace.BadParameter("phase %q must be run from a master node (requires kubernetes client)", p.Phase.ID)
+	}
+	dnsConfig := p.Plan.DNSConfig
+	if dnsConfig.IsEmpty() {
+		dnsConfig = storage.LegacyDNSConfig
 	}
-
 	return &PhaseUpgradeGravitySiteRestart{
 		FieldLogger: logger,
 		Client:      client,
+		DNSConfig:   dnsConfig,
 	}, nil
 }
 
@@ -353,6 +402,25 @@ func (*PhaseUpgradeGravitySiteRestart) PostCheck(context.Context) error {
 	return nil
 }
 
+// HealthChecks verifies that etcd is healthy and the cluster controller
+// is serving requests after the restart
+func (p *PhaseUpgradeGravitySiteRestart) HealthChecks() fsm.HealthChecks {
+	client := httplib.GetClient(true, httplib.WithLocalResolver(p.DNSConfig.Addr()))
+	return fsm.HealthChecks{
+		Checks: []fsm.HealthCheck{
+			fsm.EtcdHealthy(p.FieldLogger),
+			fsm.HTTPProbe(client, defaults.GravityServiceURL+"/healthz"),
+		},
+	}
+}
+
+// DryRun describes the cluster controller pods that would be restarted
+func (p *PhaseUpgradeGravitySiteRestart) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddObject("pods", fmt.Sprintf("%v/app=%v",
+		constants.KubeSystemNamespace, constants.GravityServiceName)), nil
+}
+
 func restartGravitySite(ctx context.Context, client *kubeapi.Clientset, l log.FieldLogger) error {
 	l.Info("Restart cluster controller.")
 	// wait for etcd to form a cluster
@@ -18,6 +18,7 @@ package phases
 
 import (
 	"context"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
@@ -53,6 +54,15 @@ func (*phaseGC) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the commands that trim the container journal
+func (*phaseGC) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, command := range journalCommands() {
+		report.AddCommand("%v", strings.Join(command, " "))
+	}
+	return &report, nil
+}
+
 // PreCheck is no-op for this phase
 func (*phaseGC) PreCheck(context.Context) error {
 	return nil
@@ -65,14 +75,7 @@ func (*phaseGC) PostCheck(context.Context) error {
 
 func trimJournalFiles(remote fsm.Remote, logger log.FieldLogger) error {
 	logger.Info("Gabrage collect obsolete journal files.")
-	commands := [][]string{
-		// Force flush journal buffers and rotate files
-		utils.PlanetCommandArgs(defaults.JournalctlBin, "--flush", "--rotate"),
-		// Discard stale journal directories left from previous container starts
-		utils.PlanetCommandArgs(defaults.GravityBin,
-			"system", "gc", "journal", "--debug"),
-	}
-	for _, command := range commands {
+	for _, command := range journalCommands() {
 		out, err := fsm.RunCommand(command)
 		if err != nil {
 			return trace.Wrap(err, "failed to execute %q: %s", command, out)
@@ -82,6 +85,17 @@ func trimJournalFiles(remote fsm.Remote, logger log.FieldLogger) error {
 	return nil
 }
 
+// journalCommands returns the commands that trim the container journal
+func journalCommands() [][]string {
+	return [][]string{
+		// Force flush journal buffers and rotate files
+		utils.PlanetCommandArgs(defaults.JournalctlBin, "--flush", "--rotate"),
+		// Discard stale journal directories left from previous container starts
+		utils.PlanetCommandArgs(defaults.GravityBin,
+			"system", "gc", "journal", "--debug"),
+	}
+}
+
 // phaseGC is the phase that executes clean up tasks after the upgrade
 type phaseGC struct {
 	log.FieldLogger
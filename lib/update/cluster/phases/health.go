@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewPhaseHealth returns a new executor that verifies the cluster health
+// after a group of nodes has been updated
+func NewPhaseHealth(p fsm.ExecutorParams, logger log.FieldLogger) (*phaseHealth, error) {
+	if p.Phase.Data == nil || p.Phase.Data.Update == nil {
+		return nil, trace.NotFound("no servers specified for phase %q", p.Phase.ID)
+	}
+	var servers []storage.Server
+	for _, server := range p.Phase.Data.Update.Servers {
+		servers = append(servers, server.Server)
+	}
+	return &phaseHealth{
+		FieldLogger:    logger,
+		servers:        servers,
+		clusterServers: p.Plan.Servers,
+		getStatus:      status.FromPlanetAgent,
+		timeout:        defaults.UpdateHealthTimeout,
+		interval:       defaults.RetryInterval,
+	}, nil
+}
+
+// phaseHealth waits for the planet agents to report the cluster as healthy.
+//
+// The phase fails and the operation halts if the cluster does not become
+// healthy within the timeout so the update of the next batch of nodes
+// is not started on a degraded cluster
+type phaseHealth struct {
+	log.FieldLogger
+	// servers lists the nodes that have just been updated
+	servers []storage.Server
+	// clusterServers lists all cluster nodes
+	clusterServers []storage.Server
+	// getStatus queries the status of the cluster nodes
+	getStatus func(context.Context, []storage.Server) (*status.Agent, error)
+	// timeout specifies the maximum time to wait for the cluster to become healthy
+	timeout time.Duration
+	// interval specifies the interval between health checks
+	interval time.Duration
+}
+
+// PreCheck makes sure the phase is executed on a master node
+func (p *phaseHealth) PreCheck(context.Context) error {
+	return trace.Wrap(fsm.CheckMasterServer(p.clusterServers))
+}
+
+// PostCheck is no-op for this phase
+func (p *phaseHealth) PostCheck(context.Context) error {
+	return nil
+}
+
+// Execute waits for the cluster to become healthy
+func (p *phaseHealth) Execute(ctx context.Context) error {
+	p.Infof("Verify cluster health after updating %v.", formatServers(p.servers))
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	var lastErr error
+	err := utils.RetryWithInterval(ctx, backoff.NewConstantBackOff(p.interval), func() error {
+		lastErr = p.checkHealth(ctx)
+		return lastErr
+	})
+	if err != nil && lastErr != nil {
+		return trace.Wrap(lastErr, "cluster did not become healthy within %v, "+
+			"the operation has been halted", p.timeout)
+	}
+	return trace.Wrap(err)
+}
+
+// Rollback is no-op for this phase
+func (p *phaseHealth) Rollback(context.Context) error {
+	return nil
+}
+
+// DryRun reports no changes as this phase only verifies the cluster health
+func (p *phaseHealth) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
+func (p *phaseHealth) checkHealth(ctx context.Context) error {
+	agent, err := p.getStatus(ctx, p.clusterServers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := fsm.CheckPlanetStatus(*agent); err != nil {
+		return trace.Wrap(err)
+	}
+	p.Info("Cluster is healthy.")
+	return nil
+}
+
+func formatServers(servers []storage.Server) string {
+	var hostnames []string
+	for _, server := range servers {
+		hostnames = append(hostnames, server.Hostname)
+	}
+	return strings.Join(hostnames, ", ")
+}
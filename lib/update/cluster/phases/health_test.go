@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestPhases(t *testing.T) { check.TestingT(t) }
+
+type PhaseHealthSuite struct{}
+
+var _ = check.Suite(&PhaseHealthSuite{})
+
+func (s *PhaseHealthSuite) TestWaitsForClusterToBecomeHealthy(c *check.C) {
+	var attempts int
+	phase := newTestPhaseHealth(func(context.Context, []storage.Server) (*status.Agent, error) {
+		attempts++
+		if attempts < 2 {
+			return degradedAgent("docker"), nil
+		}
+		return healthyAgent(), nil
+	})
+	c.Assert(phase.Execute(context.TODO()), check.IsNil)
+	c.Assert(attempts, check.Equals, 2)
+}
+
+func (s *PhaseHealthSuite) TestHaltsOnDegradedCluster(c *check.C) {
+	phase := newTestPhaseHealth(func(context.Context, []storage.Server) (*status.Agent, error) {
+		return degradedAgent("docker"), nil
+	})
+	err := phase.Execute(context.TODO())
+	c.Assert(err, check.NotNil)
+	c.Assert(err, check.ErrorMatches, "(?s).*node-1 is degraded: docker.*")
+}
+
+func (s *PhaseHealthSuite) TestVerifiesSystemPodsAfterRuntimeUpdates(c *check.C) {
+	newPhase := func(id string) *updatePhaseApp {
+		return &updatePhaseApp{phaseApp: phaseApp{
+			ExecutorParams: fsm.ExecutorParams{Phase: storage.OperationPhase{ID: id}},
+			Client:         &kubernetes.Clientset{},
+		}}
+	}
+	checks := newPhase("/runtime/dns-app").HealthChecks().Checks
+	c.Assert(checks, check.HasLen, 1)
+	c.Assert(checks[0].Name(), check.Equals, "pods in namespace kube-system are healthy")
+	c.Assert(newPhase("/app/example").HealthChecks().Checks, check.HasLen, 0)
+}
+
+func newTestPhaseHealth(getStatus func(context.Context, []storage.Server) (*status.Agent, error)) *phaseHealth {
+	return &phaseHealth{
+		FieldLogger: logrus.WithField("phase", "health"),
+		servers:     []storage.Server{{Hostname: "node-1"}},
+		getStatus:   getStatus,
+		timeout:     time.Second,
+		interval:    10 * time.Millisecond,
+	}
+}
+
+func healthyAgent() *status.Agent {
+	return &status.Agent{
+		SystemStatus: status.SystemStatus(agentpb.SystemStatus_Running),
+		Nodes: []status.ClusterServer{
+			{Hostname: "node-1", Status: status.NodeHealthy},
+		},
+	}
+}
+
+func degradedAgent(probes ...string) *status.Agent {
+	return &status.Agent{
+		SystemStatus: status.SystemStatus(agentpb.SystemStatus_Degraded),
+		Nodes: []status.ClusterServer{
+			{Hostname: "node-1", Status: status.NodeDegraded, FailedProbes: probes},
+		},
+	}
+}
@@ -211,6 +211,26 @@ func (p *updatePhaseInit) Execute(context.Context) error {
 	return nil
 }
 
+// DryRun describes the cluster state and configuration packages
+// the init phase would update
+func (p *updatePhaseInit) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddFile(legacyUpdateDir)
+	report.AddObject("ConfigMap", constants.ClusterInfoMap)
+	for _, server := range p.Servers {
+		if server.Runtime.Update != nil {
+			if server.Runtime.SecretsPackage != nil {
+				report.AddPackage("%v", *server.Runtime.SecretsPackage)
+			}
+			report.AddPackage("%v", server.Runtime.Update.ConfigPackage)
+		}
+		if server.Teleport.Update != nil && server.Teleport.Update.NodeConfigPackage != nil {
+			report.AddPackage("%v", *server.Teleport.Update.NodeConfigPackage)
+		}
+	}
+	return &report, nil
+}
+
 func (p *updatePhaseInit) initRPCCredentials() error {
 	// FIXME: the secrets package is currently only generated once.
 	// Even though the package is generated with some time buffer in advance,
@@ -448,9 +468,7 @@ func masterIPs(servers []storage.UpdateServer) (addrs []string) {
 }
 
 func removeLegacyUpdateDirectory(log log.FieldLogger) error {
-	const updateDir = "/var/lib/gravity/site/update/gravity"
-
-	fi, err := os.Stat(updateDir)
+	fi, err := os.Stat(legacyUpdateDir)
 	err = trace.ConvertSystemError(err)
 	if trace.IsNotFound(err) {
 		return nil
@@ -464,8 +482,8 @@ func removeLegacyUpdateDirectory(log log.FieldLogger) error {
 		return nil
 	}
 
-	log.Debugf("Removing legacy update directory %v.", updateDir)
-	err = os.RemoveAll(updateDir)
+	log.Debugf("Removing legacy update directory %v.", legacyUpdateDir)
+	err = os.RemoveAll(legacyUpdateDir)
 	return trace.ConvertSystemError(err)
 }
 
@@ -491,3 +509,6 @@ func (p *updatePhaseInit) removeConfiguredPackages() error {
 			return nil
 		})
 }
+
+// legacyUpdateDir is the update directory used by older versions
+const legacyUpdateDir = "/var/lib/gravity/site/update/gravity"
@@ -70,6 +70,12 @@ func (p *phaseTaint) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the taint to add on the node
+func (p *phaseTaint) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // phaseUntaint defines the operation of removing a taint from the node
 type phaseUntaint struct {
 	kubernetesOperation
@@ -102,6 +108,12 @@ func (p *phaseUntaint) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the taint to remove from the node
+func (p *phaseUntaint) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // phaseDrain defines the operation of draining a node
 type phaseDrain struct {
 	kubernetesOperation
@@ -136,6 +148,13 @@ func (p *phaseDrain) Rollback(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// DryRun describes the node to drain
+func (p *phaseDrain) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddCommand("kubectl drain %v", p.Server.KubeNodeID())
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // phaseKubeletPermissions defines the operation to bootstrap additional permissions for kubelet.
 // This is necessary for a master node that is upgraded first and needs to update node status (via patch)
 // on an older api server.
@@ -166,6 +185,13 @@ func (p *phaseKubeletPermissions) Rollback(context.Context) error {
 	return trace.Wrap(removeKubeletPermissions(p.Client))
 }
 
+// DryRun describes the RBAC resources created for kubelet
+func (p *phaseKubeletPermissions) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddObject("clusterrole", defaults.KubeletUpdatePermissionsRole)
+	return report.AddObject("clusterrolebinding", defaults.KubeletUpdatePermissionsRole), nil
+}
+
 // phaseUncordon defines the operation of uncordoning a node
 type phaseUncordon struct {
 	kubernetesOperation
@@ -195,6 +221,22 @@ func (p *phaseUncordon) Rollback(context.Context) error {
 	return nil
 }
 
+// HealthChecks verifies that the node is ready after it has been uncordoned
+func (p *phaseUncordon) HealthChecks() fsm.HealthChecks {
+	return fsm.HealthChecks{
+		Checks: []fsm.HealthCheck{
+			fsm.NodeReady(p.Client.CoreV1(), p.Server.KubeNodeID()),
+		},
+	}
+}
+
+// DryRun describes the node to uncordon
+func (p *phaseUncordon) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddCommand("kubectl uncordon %v", p.Server.KubeNodeID())
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // phaseEndpoints defines the operation waiting for DNS/cluster endpoints after
 // a node has been drained
 type phaseEndpoints struct {
@@ -224,6 +266,11 @@ func (p *phaseEndpoints) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only waits for endpoints
+func (p *phaseEndpoints) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	return &fsm.DryRunReport{}, nil
+}
+
 func newKubernetesOperation(p fsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*kubernetesOperation, error) {
 	if p.Phase.Data == nil || p.Phase.Data.Server == nil {
 		return nil, trace.NotFound("no server specified for phase %q", p.Phase.ID)
@@ -121,6 +121,20 @@ func (p *phaseMigrateLinks) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the trusted cluster created out of the remote support link
+func (p *phaseMigrateLinks) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	links, err := p.Backend.GetOpsCenterLinks(p.ClusterName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	remoteLinks, _ := p.sortOutLinks(links)
+	if len(remoteLinks) != 0 {
+		report.AddObject("trustedcluster", remoteLinks[0].Hostname)
+	}
+	return &report, nil
+}
+
 // PreCheck is no-op for this phase
 func (*phaseMigrateLinks) PreCheck(context.Context) error {
 	return nil
@@ -195,6 +209,15 @@ func (p *phaseUpdateLabels) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the nodes which labels would be updated
+func (p *phaseUpdateLabels) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	for _, server := range p.Servers {
+		report.AddObject("node", server.KubeNodeID())
+	}
+	return &report, nil
+}
+
 // PreCheck makes sure this phase is being executed on a master node
 func (p *phaseUpdateLabels) PreCheck(context.Context) error {
 	return trace.Wrap(fsm.CheckMasterServer(p.Servers))
@@ -247,6 +270,21 @@ func (p *phaseMigrateRoles) Execute(context.Context) error {
 	return nil
 }
 
+// DryRun describes the cluster roles that would be migrated
+func (p *phaseMigrateRoles) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	roles, err := p.Backend.GetRoles()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var report fsm.DryRunReport
+	for _, role := range roles {
+		if needMigrateRole(role) {
+			report.AddObject("role", role.GetName())
+		}
+	}
+	return &report, nil
+}
+
 // Rollback rolls back role migration changes
 func (p *phaseMigrateRoles) Rollback(context.Context) error {
 	roles, err := p.Backend.GetRoles()
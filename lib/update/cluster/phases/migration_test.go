@@ -87,9 +87,17 @@ func (s *PhaseMigrationSuite) TestMigrateRoles(c *check.C) {
 	err = s.backend.UpsertRole(role, storage.Forever)
 	c.Assert(err, check.IsNil)
 
+	report, err := phase.DryRun(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(report.Objects, check.DeepEquals, []string{"role/" + role.GetName()})
+
 	err = phase.Execute(context.TODO())
 	c.Assert(err, check.IsNil)
 
+	report, err = phase.DryRun(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(report.IsEmpty(), check.Equals, true)
+
 	convertedRole, err := s.backend.GetRole(role.GetName())
 	c.Assert(err, check.IsNil)
 	c.Assert(convertedRole.GetKubeGroups(teleservices.Allow), check.DeepEquals,
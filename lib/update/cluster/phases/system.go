@@ -189,6 +189,37 @@ func (p *updatePhaseSystem) Rollback(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// HealthChecks verifies that the node reports healthy and the cluster
+// is running once the runtime on the node has been updated
+func (p *updatePhaseSystem) HealthChecks() fsm.HealthChecks {
+	if p.Server.Runtime.Update == nil {
+		return fsm.HealthChecks{}
+	}
+	return fsm.HealthChecks{
+		Checks: []fsm.HealthCheck{fsm.PlanetRunning([]storage.Server{p.Server.Server})},
+	}
+}
+
+// DryRun describes the system packages that would be updated on the node
+func (p *updatePhaseSystem) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	report.AddPackage("%v", p.GravityPackage)
+	if p.Server.Runtime.Update != nil {
+		report.AddPackage("%v -> %v", p.Server.Runtime.Installed, p.Server.Runtime.Update.Package)
+		report.AddPackage("%v", p.Server.Runtime.Update.ConfigPackage)
+	}
+	if p.Server.Teleport.Update != nil {
+		report.AddPackage("%v -> %v", p.Server.Teleport.Installed, p.Server.Teleport.Update.Package)
+		if p.Server.Teleport.Update.NodeConfigPackage != nil {
+			report.AddPackage("%v", *p.Server.Teleport.Update.NodeConfigPackage)
+		}
+	}
+	if p.Server.Runtime.SecretsPackage != nil {
+		report.AddPackage("%v", *p.Server.Runtime.SecretsPackage)
+	}
+	return &report, nil
+}
+
 type updatePhaseConfig struct {
 	// Packages is the cluster package service
 	Packages pack.PackageService
@@ -264,6 +295,24 @@ func (p *updatePhaseConfig) Rollback(context.Context) error {
 	})
 }
 
+// DryRun describes the teleport master configuration package pulled to the node
+func (p *updatePhaseConfig) DryRun(context.Context) (*fsm.DryRunReport, error) {
+	var report fsm.DryRunReport
+	update, err := pack.FindLatestPackageWithLabels(
+		p.Packages, p.Plan.ClusterName, map[string]string{
+			pack.AdvertiseIPLabel: p.Phase.Data.Server.AdvertiseIP,
+			pack.OperationIDLabel: p.Plan.OperationID,
+			pack.PurposeLabel:     pack.PurposeTeleportMasterConfig,
+		})
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if update != nil {
+		report.AddPackage("%v", *update)
+	}
+	return &report, nil
+}
+
 // PreCheck makes sure the phase is being executed on the correct server
 func (p *updatePhaseConfig) PreCheck(ctx context.Context) error {
 	return trace.Wrap(p.remote.CheckServer(ctx, *p.Phase.Data.Server))
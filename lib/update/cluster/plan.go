@@ -218,6 +218,7 @@ func NewOperationPlan(config PlanConfig) (*storage.OperationPlan, error) {
 		updateDNSAppEarly: updateDNSAppEarly,
 		roles:             roles,
 		leadMaster:        *leader,
+		strategy:          config.Operation.Update.Strategy,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -298,6 +299,8 @@ type planConfig struct {
 	roles []teleservices.Role
 	// leader refers to the master server running the update operation
 	leadMaster storage.UpdateServer
+	// strategy optionally defines the order in which the regular nodes are updated
+	strategy *storage.UpdateStrategy
 }
 
 func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
@@ -310,7 +313,7 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 	initPhase := *builder.init(p.leadMaster.Server)
 	checksPhase := *builder.checks().Require(initPhase)
 	preUpdatePhase := *builder.preUpdate().Require(initPhase)
-	bootstrapPhase := *builder.bootstrap().Require(initPhase)
+	bootstrapPhase := *builder.bootstrap(initPhase).Require(initPhase)
 
 	installedGravityPackage, err := p.installedRuntime.Manifest.Dependencies.ByName(
 		constants.GravityPackage)
@@ -326,10 +329,29 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		log.Debugf("No support for taints/tolerations for %v.", installedGravityPackage)
 	}
 
+	// if the operation is rolled back automatically, the cluster health
+	// is verified after each phase that can break the cluster so the
+	// rollback starts as soon as possible
+	rollbackOnFailure := p.operation.Update != nil && p.operation.Update.RollbackOnFailure
+
 	mastersPhase := *builder.masters(p.leadMaster, otherMasters, supportsTaints).
 		Require(checksPhase, bootstrapPhase, preUpdatePhase)
-	nodesPhase := *builder.nodes(p.leadMaster, nodes, supportsTaints).
-		Require(mastersPhase)
+	if p.strategy != nil || rollbackOnFailure {
+		// masters are still updated one at a time but the cluster health
+		// is verified before any of the regular nodes are updated
+		mastersPhase.AddSequential(*builder.health(mastersPhase.ChildLiteral("health"),
+			p.leadMaster, masters))
+	}
+	var nodesPhase update.Phase
+	if p.strategy != nil {
+		nodesPhase = *builder.nodesWithStrategy(p.leadMaster, nodes, supportsTaints, *p.strategy)
+	} else {
+		nodesPhase = *builder.nodes(p.leadMaster, nodes, supportsTaints, mastersPhase)
+		if rollbackOnFailure && len(nodesPhase.Phases) != 0 {
+			builder.addHealth(&nodesPhase, p.leadMaster, nodes)
+		}
+	}
+	nodesPhase.Require(mastersPhase)
 
 	runtimeUpdates, err := app.GetUpdatedDependencies(p.installedRuntime, p.updateRuntime, p.installedApp.Manifest, p.updateApp.Manifest)
 	if err != nil && !trace.IsNotFound(err) {
@@ -379,6 +401,9 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 				serversToStorage(otherMasters...),
 				serversToStorage(nodes...),
 				currentVersion, desiredVersion)
+			if rollbackOnFailure {
+				builder.addHealth(&etcdPhase, p.leadMaster, p.servers)
+			}
 			// This does not depend on previous on purpose - when the etcd block is executed,
 			// remote agents might be able to sync the plan before the shutdown of etcd instances
 			// has begun
@@ -394,7 +419,7 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		// upgrade phase to make sure that old gravity-sites start up fine
 		// in case new configuration is incompatible, but *before* runtime
 		// phase so new gravity-sites can find it after they start
-		configPhase := *builder.config(serversToStorage(masters...)).Require(mastersPhase)
+		configPhase := *builder.config(serversToStorage(masters...), mastersPhase).Require(mastersPhase)
 		root.Add(configPhase)
 
 		// if OpenEBS has been just enabled, create its configuration before
@@ -405,10 +430,24 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		}
 
 		runtimePhase := *builder.runtime(runtimeUpdates).Require(mastersPhase)
+		if rollbackOnFailure {
+			builder.addHealth(&runtimePhase, p.leadMaster, p.servers)
+		}
 		root.Add(runtimePhase)
 	}
 
-	root.AddSequential(*builder.app(appUpdates), *builder.cleanup())
+	root.AddSequential(*builder.app(appUpdates))
+	if rollbackOnFailure {
+		// the operation is rolled back automatically if the cluster
+		// or the application do not become healthy after the update
+		root.AddSequential(*builder.health("/health", p.leadMaster, p.servers),
+			*builder.status(p.leadMaster))
+	}
+	lastPhase := update.PhaseRef(root.Phases[len(root.Phases)-1].ID)
+	root.AddSequential(*builder.cleanup(lastPhase))
+	// per-node phases only depend on the phases they need which allows
+	// executing them concurrently but the top-level phases always run in order
+	root.RequirePreceding()
 	plan := p.plan
 	plan.Phases = root.Phases
 	update.ResolvePlan(&plan)
@@ -40,8 +40,9 @@ func NewMachine(ctx context.Context, config Config, engine *Engine) (*fsm.FSM, e
 		return nil, trace.Wrap(err)
 	}
 	machine, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
+		Engine:   engine,
+		Runner:   config.Runner,
+		Parallel: config.Parallel,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
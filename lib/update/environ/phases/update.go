@@ -109,6 +109,15 @@ func (r *updateConfig) Rollback(context.Context) error {
 	return trace.Wrap(err)
 }
 
+// DryRun describes the runtime configuration packages that would be generated
+func (r *updateConfig) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	for _, update := range r.updates {
+		report.AddPackage("%v", update.Runtime.Update.ConfigPackage)
+	}
+	return &report, nil
+}
+
 // PreCheck is a no-op
 func (r *updateConfig) PreCheck(context.Context) error {
 	return nil
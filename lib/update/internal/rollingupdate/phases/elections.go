@@ -84,10 +84,8 @@ func (r *elections) setElectionStatus(server storage.Server, enable bool) error
 	} else {
 		r.progress.NextStep("Disable leader elections on %v", server.Hostname)
 	}
-	key := fmt.Sprintf("/planet/cluster/%s/election/%s", r.clusterName, server.AdvertiseIP)
-
 	out, err := libfsm.RunCommand(utils.PlanetCommandArgs(defaults.EtcdCtlBin,
-		"set", key, strconv.FormatBool(enable)))
+		"set", r.electionKey(server), strconv.FormatBool(enable)))
 	return trace.Wrap(err, "setting leader election on %q to %v: %s", server.AdvertiseIP, enable, out)
 }
 
@@ -111,6 +109,23 @@ func (r *elections) Rollback(ctx context.Context) error {
 	return trace.Wrap(r.updateElectionStatus(true))
 }
 
+// DryRun describes the leader election changes made by this phase
+func (r *elections) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	for _, server := range r.ElectionChange.DisableServers {
+		report.AddCommand("%v set %v false", defaults.EtcdCtlBin, r.electionKey(server))
+	}
+	for _, server := range r.ElectionChange.EnableServers {
+		report.AddCommand("%v set %v true", defaults.EtcdCtlBin, r.electionKey(server))
+	}
+	return &report, nil
+}
+
+// electionKey returns the etcd key with the leader election status of the specified server
+func (r *elections) electionKey(server storage.Server) string {
+	return fmt.Sprintf("/planet/cluster/%s/election/%s", r.clusterName, server.AdvertiseIP)
+}
+
 func (r *elections) updateElectionStatus(rollback bool) error {
 	for _, server := range r.ElectionChange.DisableServers {
 		err := r.setElectionStatus(server, rollback)
@@ -64,6 +64,12 @@ func (p *tainter) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// DryRun describes the taint to add on the node
+func (p *tainter) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // NewUntaint returns a new executor for removing a taint from a node
 func NewUntaint(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*untainter, error) {
 	op, err := newKubernetesOperation(params, client, logger)
@@ -91,6 +97,12 @@ func (*untainter) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the taint to remove from the node
+func (p *untainter) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // NewDrain returns a new executor for draining a node
 func NewDrain(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*drainer, error) {
 	op, err := newKubernetesOperation(params, client, logger)
@@ -120,6 +132,13 @@ func (p *drainer) Rollback(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// DryRun describes the node to drain
+func (p *drainer) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	report.AddCommand("kubectl drain %v", p.Server.KubeNodeID())
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // NewUncordon returns a new executor for uncordoning a node
 func NewUncordon(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*uncordoner, error) {
 	op, err := newKubernetesOperation(params, client, logger)
@@ -144,6 +163,13 @@ func (*uncordoner) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun describes the node to uncordon
+func (p *uncordoner) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	report.AddCommand("kubectl uncordon %v", p.Server.KubeNodeID())
+	return report.AddObject("node", p.Server.KubeNodeID()), nil
+}
+
 // NewEndpoints returns a new executor for waiting for cluster controller endpoints
 // to become active
 func NewEndpoints(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*endpoints, error) {
@@ -168,6 +194,11 @@ func (*endpoints) Rollback(context.Context) error {
 	return nil
 }
 
+// DryRun reports no changes as this phase only waits for endpoints
+func (*endpoints) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	return &libfsm.DryRunReport{}, nil
+}
+
 func newKubernetesOperation(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*kubernetesOperation, error) {
 	if params.Phase.Data == nil || params.Phase.Data.Server == nil {
 		return nil, trace.NotFound("no server specified for phase %q", params.Phase.ID)
@@ -103,6 +103,14 @@ func (r *restart) Rollback(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// DryRun describes the runtime packages that would be installed on the node
+func (r *restart) DryRun(context.Context) (*libfsm.DryRunReport, error) {
+	var report libfsm.DryRunReport
+	report.AddPackage("%v -> %v", r.update.Runtime.Installed, r.update.Runtime.Update.Package)
+	report.AddPackage("%v", r.update.Runtime.Update.ConfigPackage)
+	return &report, nil
+}
+
 // PreCheck is a no-op
 func (*restart) PreCheck(context.Context) error {
 	return nil
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// rollbackOnFailure returns true if the operation has been configured
+// to roll back automatically if it fails
+func (r *Updater) rollbackOnFailure() bool {
+	return r.Operation.Update != nil && r.Operation.Update.RollbackOnFailure
+}
+
+// rollbackPlan rolls back all phases of the plan that have been executed
+// in the reverse order after the plan failed with planErr.
+//
+// The rollback stops at the first phase that fails to roll back as the
+// phases executed before it might depend on it.
+// The outcome of the rollback is logged and displayed with progress.
+// Returns an error with the rollback report as the message that wraps
+// the error the plan failed with
+func (r *Updater) rollbackPlan(ctx context.Context, progress utils.Progress, planErr error) error {
+	plan, err := r.machine.GetPlan()
+	if err != nil {
+		return trace.NewAggregate(planErr, err)
+	}
+	report := rollbackReport{
+		planErr:      planErr,
+		failedPhases: failedPhases(plan),
+	}
+	phases := fsm.RollbackPhases(plan)
+	r.WithField("phases", len(phases)).Warn("Rolling back the operation.")
+	for i, phase := range phases {
+		err := r.rollbackPhase(ctx, progress, phase)
+		if err != nil {
+			r.WithError(err).WithField("phase", phase.ID).Warn("Failed to roll back phase.")
+			report.rollbackErr = err
+			report.failedRollback = phase.ID
+			for _, phase := range phases[i+1:] {
+				report.remaining = append(report.remaining, phase.ID)
+			}
+			break
+		}
+		report.rolledBack = append(report.rolledBack, phase.ID)
+	}
+	r.WithField("report", report.String()).Warn("Rolled back the operation.")
+	progress.PrintWarn(nil, "%v", report)
+	return trace.Wrap(&rollbackError{report: report})
+}
+
+// rollbackPhase rolls back the specified phase on the node it has been executed on.
+//
+// The phases executed on a remote node are rolled back by the agent on that node
+func (r *Updater) rollbackPhase(ctx context.Context, progress utils.Progress, phase storage.OperationPhase) error {
+	server := phaseServer(phase)
+	if server == nil || isLocalServer(*server) {
+		return trace.Wrap(r.machine.RollbackPhase(ctx, fsm.Params{
+			PhaseID:  phase.ID,
+			Progress: progress,
+		}))
+	}
+	progress.NextStep("Rolling back %q on remote node %v", phase.ID, server.Hostname)
+	err := r.Runner.Run(ctx, *server, "plan", "rollback",
+		"--phase", phase.ID,
+		"--operation-id", r.Operation.ID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// Record the change locally as etcd might not be available to
+	// synchronize the changes back from the remote node
+	return trace.Wrap(r.machine.ChangePhaseState(ctx, fsm.StateChange{
+		Phase: phase.ID,
+		State: storage.OperationPhaseStateRolledBack,
+	}))
+}
+
+// rollbackReport describes the outcome of the automatic rollback
+type rollbackReport struct {
+	// planErr is the error that failed the operation
+	planErr error
+	// failedPhases lists the phases that failed the operation
+	failedPhases []string
+	// rolledBack lists the phases that have been rolled back
+	rolledBack []string
+	// failedRollback names the phase that failed to roll back
+	failedRollback string
+	// rollbackErr is the error the rollback failed with
+	rollbackErr error
+	// remaining lists the phases that have not been rolled back
+	remaining []string
+}
+
+// String formats the report for the operation failure message
+func (r rollbackReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "operation failed: %v", trace.UserMessage(r.planErr))
+	if len(r.failedPhases) != 0 {
+		fmt.Fprintf(&buf, "\nfailed phases: %v", strings.Join(r.failedPhases, ", "))
+	}
+	if len(r.rolledBack) != 0 {
+		fmt.Fprintf(&buf, "\nrolled back phases: %v", strings.Join(r.rolledBack, ", "))
+	}
+	if r.rollbackErr == nil {
+		buf.WriteString("\nthe operation has been rolled back")
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "\nfailed to roll back phase %v: %v", r.failedRollback,
+		trace.UserMessage(r.rollbackErr))
+	remaining := append([]string{r.failedRollback}, r.remaining...)
+	fmt.Fprintf(&buf, "\nphases %v need to be rolled back manually with 'gravity plan rollback'",
+		strings.Join(remaining, ", "))
+	return buf.String()
+}
+
+// rollbackError is the error of the operation that has been rolled back.
+// The error message is the rollback report which is recorded as the reason
+// of the operation failure
+type rollbackError struct {
+	report rollbackReport
+}
+
+// Error returns the rollback report.
+// Implements error
+func (r *rollbackError) Error() string {
+	return r.report.String()
+}
+
+// OrigError returns the error the operation failed with
+func (r *rollbackError) OrigError() error {
+	return r.report.planErr
+}
+
+// Unwrap returns the error the operation failed with
+func (r *rollbackError) Unwrap() error {
+	return r.report.planErr
+}
+
+// failedPhases returns the IDs of the failed leaf phases of the plan
+func failedPhases(plan *storage.OperationPlan) (result []string) {
+	for _, phase := range fsm.FlattenPlan(plan) {
+		if !phase.HasSubphases() && phase.IsFailed() {
+			result = append(result, phase.ID)
+		}
+	}
+	return result
+}
+
+// phaseServer returns the server the specified phase is executed on
+// or nil, if the phase is executed locally
+func phaseServer(phase storage.OperationPhase) *storage.Server {
+	if phase.Data == nil {
+		return nil
+	}
+	if phase.Data.ExecServer != nil {
+		return phase.Data.ExecServer
+	}
+	return phase.Data.Server
+}
+
+func isLocalServer(server storage.Server) bool {
+	return systeminfo.HasInterface(server.AdvertiseIP) == nil
+}
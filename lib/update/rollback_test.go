@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestUpdate(t *testing.T) { TestingT(t) }
+
+type RollbackSuite struct{}
+
+var _ = Suite(&RollbackSuite{})
+
+func (*RollbackSuite) TestFailureMessageIsRollbackReport(c *C) {
+	planErr := trace.ConnectionProblem(nil, "node unavailable")
+	report := rollbackReport{
+		planErr:        planErr,
+		failedPhases:   []string{"/masters/node-1/drain"},
+		rolledBack:     []string{"/masters/node-1/taint"},
+		failedRollback: "/init",
+		rollbackErr:    trace.BadParameter("no backup"),
+		remaining:      []string{"/checks"},
+	}
+	err := trace.Wrap(&rollbackError{report: report})
+
+	// Engine.Complete records the message of the unwrapped error
+	// as the reason of the operation failure
+	c.Assert(trace.Unwrap(err).Error(), Equals, `operation failed: node unavailable
+failed phases: /masters/node-1/drain
+rolled back phases: /masters/node-1/taint
+failed to roll back phase /init: no backup
+phases /init, /checks need to be rolled back manually with 'gravity plan rollback'`)
+	c.Assert(trace.IsConnectionProblem(errors.Unwrap(trace.Unwrap(err))), Equals, true)
+}
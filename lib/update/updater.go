@@ -100,6 +100,12 @@ func (r *Updater) RunPhase(ctx context.Context, phase string, phaseTimeout time.
 	}))
 }
 
+// DryRun describes the changes the specified phase would make if executed.
+// The root phase describes the remaining phases of the plan
+func (r *Updater) DryRun(ctx context.Context, phase string) ([]fsm.PhaseDryRun, error) {
+	return r.machine.DryRunPhase(ctx, phase)
+}
+
 // SetPhase sets phase state without executing it.
 func (r *Updater) SetPhase(ctx context.Context, phase, state string) error {
 	return r.machine.ChangePhaseState(ctx, fsm.StateChange{
@@ -173,6 +179,9 @@ func (r *Updater) executePlan(ctx context.Context) error {
 	planErr := r.machine.ExecutePlan(ctx, progress)
 	if planErr != nil {
 		r.WithError(planErr).Warn("Failed to execute plan.")
+		if r.rollbackOnFailure() {
+			planErr = r.rollbackPlan(ctx, progress, planErr)
+		}
 	}
 
 	err := r.machine.Complete(planErr)
@@ -243,6 +252,9 @@ type Config struct {
 	LocalBackend storage.Backend
 	// Runner specifies the runner for remote commands
 	Runner rpc.AgentRepository
+	// Parallel is the maximum number of phases to execute concurrently.
+	// If unspecified, phases are executed sequentially in plan order
+	Parallel int
 	// FieldLogger is the logger to use
 	log.FieldLogger
 	// Silent controls whether the process outputs messages to stdout
@@ -60,6 +60,7 @@ func ToRawTrace(err trace.Error) *trace.RawTrace {
 	if traceErr, ok := err.(*trace.TraceErr); ok {
 		result.Traces = traceErr.Traces
 		result.Message = traceErr.Message
+		result.Fields = traceErr.Fields
 	}
 	bytes, errMarshal := json.Marshal(message{err.OrigError().Error()})
 	if errMarshal != nil {
@@ -300,8 +301,12 @@ func ConvertS3Error(err error) error {
 		return err
 	}
 	switch awsErr.Code() {
-	case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket:
+	case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket, s3.ErrCodeNoSuchUpload:
 		return trace.NotFound(awsErr.Message())
+	case "NotFound":
+		// HEAD requests get no response body so the error
+		// only carries the generic code derived from status
+		return trace.NotFound("object not found")
 	}
 	return err
 }
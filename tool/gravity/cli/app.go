@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -30,12 +31,15 @@ import (
 	"github.com/gravitational/gravity/lib/app/resources"
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/docker"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/sbom"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/systeminfo"
 	"github.com/gravitational/gravity/lib/utils"
@@ -142,8 +146,9 @@ func uninstallAppPackage(env *localenv.LocalEnvironment, appPackage loc.Locator)
 
 // importApp imports an application from the specified directory creating a new
 // package named packageName.
+// If signer is set, the imported application package is signed with it
 func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source string, req *appservice.ImportRequest,
-	opsCenterURL string, silent bool, parallel int) error {
+	opsCenterURL string, silent bool, parallel int, signer pack.Signer) error {
 	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{
 		DockerURL:   dockerURL,
 		RegistryURL: registryURL,
@@ -165,6 +170,9 @@ func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source st
 	if req.Vendor {
 		steps += 1
 	}
+	if signer != nil {
+		steps += 1
+	}
 	progress := utils.NewProgress(context.TODO(), "app import", steps, silent)
 	defer progress.Stop()
 
@@ -225,14 +233,110 @@ func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source st
 		}
 	}
 
+	var app *appservice.Application
+	if signer != nil {
+		app, err = importSignedApp(stream, req, apps, packages, signer, progress)
+	} else {
+		app, err = runImport(stream, req, apps, progress)
+	}
+	if err != nil {
+		return trace.Wrap(err, "failed to import %v", source)
+	}
+	progress.NextStep("%v imported", app.Package)
+	return nil
+}
+
+// importSignedApp imports the application from the specified stream into
+// a temporary local application service and then pulls it into apps signing
+// the application package with the provided signer.
+// The dependencies of the application are expected to exist in apps
+func importSignedApp(stream io.Reader, req *appservice.ImportRequest, apps appservice.Applications,
+	packages pack.PackageService, signer pack.Signer, progress utils.Progress) (*appservice.Application, error) {
+	unpackedDir, err := ioutil.TempDir("", "gravity")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(unpackedDir)
+	if err := dockerarchive.Untar(stream, unpackedDir, archive.DefaultOptions()); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifestBytes, err := ioutil.ReadFile(filepath.Join(unpackedDir, defaults.ResourcesDir, defaults.ManifestFileName))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(manifestBytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	stateDir, err := ioutil.TempDir("", "gravity")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(stateDir)
+	localEnv, err := localenv.NewLocalEnvironment(localenv.LocalEnvironmentArgs{
+		StateDir: stateDir,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer localEnv.Close()
+	localApps, err := localEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// The local import only needs to know about the dependencies
+	err = service.PullAppDeps(service.AppPullRequest{
+		SrcPack:      packages,
+		SrcApp:       apps,
+		DstPack:      localEnv.Packages,
+		DstApp:       localApps,
+		MetadataOnly: true,
+	}, *manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	source, err := dockerarchive.Tar(unpackedDir, dockerarchive.Uncompressed)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := runImport(source, req, localApps, progress)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	progress.NextStep("signing and uploading %v", app.Package)
+	if req.Force {
+		err = packages.DeletePackage(app.Package)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+	}
+	app, err = service.PullApp(service.AppPullRequest{
+		SrcPack: localEnv.Packages,
+		SrcApp:  localApps,
+		DstPack: packages,
+		DstApp:  apps,
+		Package: app.Package,
+		Signer:  signer,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return app, nil
+}
+
+// runImport imports the application from the specified source into apps
+func runImport(source io.ReadCloser, req *appservice.ImportRequest, apps appservice.Applications, progress utils.Progress) (*appservice.Application, error) {
 	progressC := make(chan *appservice.ProgressEntry)
 	errorC := make(chan error, 1)
-	req.Source = stream
+	req.Source = source
 	req.ProgressC = progressC
 	req.ErrorC = errorC
 	op, err := apps.CreateImportOperation(req)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 
 	progress.NextStep("importing application")
@@ -242,15 +346,14 @@ func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source st
 	}
 
 	if err = <-errorC; err != nil {
-		return trace.Wrap(err, "failed to import %v", source)
+		return nil, trace.Wrap(err)
 	}
 
 	app, err := apps.GetImportedApplication(*op)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
-	progress.NextStep("%v imported", app.Package)
-	return nil
+	return app, nil
 }
 
 // exportApp exports containers of the specified application package packageName
@@ -392,6 +495,33 @@ func pushApp(env *localenv.LocalEnvironment, appPackage loc.Locator, portalURL s
 	return nil
 }
 
+// appSBOM outputs the software bill of materials of the specified
+// application image
+func appSBOM(env *localenv.LocalEnvironment, image, opsCenterURL string, format constants.Format) error {
+	locator, err := loc.MakeLocator(image)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	packageService, err := env.PackageService(opsCenterURL, httplib.WithDialTimeout(dialTimeout))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locator, err = pack.ProcessMetadata(packageService, locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, reader, err := packageService.ReadPackage(*locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	bom, err := sbom.FromTarball(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(bom.Write(os.Stdout, format))
+}
+
 func unpackAppResources(env *localenv.LocalEnvironment, loc loc.Locator, dir, opsCenterURL, serviceUID string) error {
 	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{}, httplib.WithDialTimeout(dialTimeout))
 	if err != nil {
@@ -33,12 +33,12 @@ import (
 )
 
 // resetConfig executes the loop to reset cluster configuration to defaults
-func resetConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, manual, confirmed bool) error {
+func resetConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, manual, confirmed, ignoreMaintenanceWindow bool, queue queueConfig) error {
 	config := libclusterconfig.NewEmpty()
-	return trace.Wrap(updateConfig(ctx, localEnv, updateEnv, config, manual, confirmed))
+	return trace.Wrap(updateConfig(ctx, localEnv, updateEnv, config, manual, confirmed, ignoreMaintenanceWindow, queue))
 }
 
-func updateConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, config libclusterconfig.Interface, manual, confirmed bool) error {
+func updateConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, config libclusterconfig.Interface, manual, confirmed, ignoreMaintenanceWindow bool, queue queueConfig) error {
 	if err := validateCloudConfig(localEnv, config); err != nil {
 		return trace.Wrap(err)
 	}
@@ -57,7 +57,7 @@ func updateConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnviro
 			return nil
 		}
 	}
-	updater, err := newConfigUpdater(ctx, localEnv, updateEnv, config)
+	updater, err := newConfigUpdater(ctx, localEnv, updateEnv, config, ignoreMaintenanceWindow, queue)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -70,14 +70,16 @@ func updateConfig(ctx context.Context, localEnv, updateEnv *localenv.LocalEnviro
 	return nil
 }
 
-func newConfigUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, config libclusterconfig.Interface) (*update.Updater, error) {
+func newConfigUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, config libclusterconfig.Interface, ignoreMaintenanceWindow bool, queue queueConfig) (*update.Updater, error) {
 	configBytes, err := libclusterconfig.Marshal(config)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	init := configInitializer{
-		resource: configBytes,
-		config:   config,
+		resource:                configBytes,
+		config:                  config,
+		ignoreMaintenanceWindow: ignoreMaintenanceWindow,
+		queue:                   queue,
 	}
 	return newUpdater(ctx, localEnv, updateEnv, init)
 }
@@ -182,21 +184,25 @@ func (r configInitializer) validatePreconditions(*localenv.LocalEnvironment, ops
 }
 
 func (r configInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
-	key, err := operator.CreateUpdateConfigOperation(context.TODO(),
-		ops.CreateUpdateConfigOperationRequest{
-			ClusterKey: cluster.Key(),
-			Config:     r.resource,
-		},
-	)
-	if err != nil {
-		if trace.IsNotFound(err) {
-			return nil, trace.NotImplemented(
-				"cluster operator does not implement the API required for updating configuration. " +
-					"Please make sure you're running the command on a compatible cluster.")
-		}
-		return nil, trace.Wrap(err)
+	req := ops.CreateUpdateConfigOperationRequest{
+		ClusterKey:              cluster.Key(),
+		Config:                  r.resource,
+		IgnoreMaintenanceWindow: r.ignoreMaintenanceWindow,
 	}
-	return key, nil
+	return r.queue.createOperation(context.TODO(), operator, cluster,
+		ops.QueuedOperationRequest{Config: &req}, "",
+		func() (*ops.SiteOperationKey, error) {
+			key, err := operator.CreateUpdateConfigOperation(context.TODO(), req)
+			if err != nil {
+				if trace.IsNotFound(err) {
+					return nil, trace.NotImplemented(
+						"cluster operator does not implement the API required for updating configuration. " +
+							"Please make sure you're running the command on a compatible cluster.")
+				}
+				return nil, trace.Wrap(err)
+			}
+			return key, nil
+		})
 }
 
 func (r configInitializer) newOperationPlan(
@@ -249,8 +255,10 @@ func (configInitializer) updateDeployRequest(req deployAgentsRequest) deployAgen
 }
 
 type configInitializer struct {
-	resource []byte
-	config   libclusterconfig.Interface
+	resource                []byte
+	config                  libclusterconfig.Interface
+	ignoreMaintenanceWindow bool
+	queue                   queueConfig
 }
 
 func validateCloudConfig(localEnv *localenv.LocalEnvironment, config libclusterconfig.Interface) error {
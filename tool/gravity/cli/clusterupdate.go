@@ -18,6 +18,8 @@ package cli
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
@@ -59,12 +61,26 @@ func newUpgradeConfig(g *Application) (*upgradeConfig, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return &upgradeConfig{
-		UpgradePackage:   *g.UpgradeCmd.App,
-		Manual:           *g.UpgradeCmd.Manual,
-		SkipVersionCheck: *g.UpgradeCmd.SkipVersionCheck,
-		Values:           values,
-	}, nil
+	config := &upgradeConfig{
+		UpgradePackage:          *g.UpgradeCmd.App,
+		Manual:                  *g.UpgradeCmd.Manual,
+		SkipVersionCheck:        *g.UpgradeCmd.SkipVersionCheck,
+		Values:                  values,
+		RollbackOnFailure:       *g.UpgradeCmd.RollbackOnFailure,
+		IgnoreMaintenanceWindow: *g.UpgradeCmd.IgnoreMaintenanceWindow,
+		Queue:                   *g.UpgradeCmd.Queue,
+		QueueTTL:                *g.UpgradeCmd.QueueTTL,
+	}
+	if *g.UpgradeCmd.Canary || *g.UpgradeCmd.BatchSize != 0 {
+		config.Strategy = &storage.UpdateStrategy{
+			Canary:    *g.UpgradeCmd.Canary,
+			BatchSize: *g.UpgradeCmd.BatchSize,
+		}
+		if err := config.Strategy.Check(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return config, nil
 }
 
 // upgradeConfig is the configuration of a triggered upgrade operation.
@@ -77,6 +93,19 @@ type upgradeConfig struct {
 	SkipVersionCheck bool
 	// Values are helm values in a marshaled yaml format.
 	Values []byte
+	// Strategy optionally defines the order in which the regular nodes are updated.
+	Strategy *storage.UpdateStrategy
+	// RollbackOnFailure specifies whether the operation is automatically
+	// rolled back if it fails.
+	RollbackOnFailure bool
+	// IgnoreMaintenanceWindow allows the operation to start outside
+	// of the cluster maintenance window.
+	IgnoreMaintenanceWindow bool
+	// Queue specifies whether the operation is queued to start
+	// once the cluster becomes idle.
+	Queue bool
+	// QueueTTL is how long the operation waits in the queue.
+	QueueTTL time.Duration
 }
 
 func updateTrigger(
@@ -104,9 +133,17 @@ func newClusterUpdater(
 	config upgradeConfig,
 ) (updater, error) {
 	init := &clusterInitializer{
-		updatePackage: config.UpgradePackage,
-		unattended:    !config.Manual,
-		values:        config.Values,
+		updatePackage:           config.UpgradePackage,
+		unattended:              !config.Manual,
+		values:                  config.Values,
+		strategy:                config.Strategy,
+		rollbackOnFailure:       config.RollbackOnFailure,
+		ignoreMaintenanceWindow: config.IgnoreMaintenanceWindow,
+		queue: queueConfig{
+			enabled: config.Queue,
+			ttl:     config.QueueTTL,
+			printer: localEnv,
+		},
 	}
 	updater, err := newUpdater(ctx, localEnv, updateEnv, init)
 	if err != nil {
@@ -127,7 +164,7 @@ func executeUpdatePhase(env *localenv.LocalEnvironment, environ LocalEnvironment
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, params.Parallel)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -142,7 +179,7 @@ func rollbackUpdatePhase(env *localenv.LocalEnvironment, environ LocalEnvironmen
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -157,7 +194,7 @@ func setUpdatePhase(env *localenv.LocalEnvironment, environ LocalEnvironmentFact
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
-	updater, err := getClusterUpdater(env, updateEnv, operation, true)
+	updater, err := getClusterUpdater(env, updateEnv, operation, true, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -171,7 +208,7 @@ func completeUpdatePlan(env *localenv.LocalEnvironment, environ LocalEnvironment
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
-	updater, err := getClusterUpdater(env, updateEnv, operation, true)
+	updater, err := getClusterUpdater(env, updateEnv, operation, true, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -179,7 +216,7 @@ func completeUpdatePlan(env *localenv.LocalEnvironment, environ LocalEnvironment
 	return trace.Wrap(updater.Complete(nil))
 }
 
-func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool) (*update.Updater, error) {
+func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool, parallel int) (*update.Updater, error) {
 	clusterEnv, err := localEnv.NewClusterEnvironment()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -199,6 +236,7 @@ func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation
 			Backend:      clusterEnv.Backend,
 			LocalBackend: updateEnv.Backend,
 			Runner:       runner,
+			Parallel:     parallel,
 			Silent:       localEnv.Silent,
 		},
 		Apps:              clusterEnv.Apps,
@@ -235,14 +273,23 @@ func (r *clusterInitializer) validatePreconditions(localEnv *localenv.LocalEnvir
 }
 
 func (r clusterInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
-	return operator.CreateSiteAppUpdateOperation(context.TODO(), ops.CreateSiteAppUpdateOperationRequest{
+	req := ops.CreateSiteAppUpdateOperationRequest{
 		AccountID:  cluster.AccountID,
 		SiteDomain: cluster.Domain,
 		App:        r.updateLoc.String(),
 		Vars: storage.OperationVariables{
 			Values: r.values,
 		},
-	})
+		Strategy:                r.strategy,
+		RollbackOnFailure:       r.rollbackOnFailure,
+		IgnoreMaintenanceWindow: r.ignoreMaintenanceWindow,
+	}
+	return r.queue.createOperation(context.TODO(), operator, cluster,
+		ops.QueuedOperationRequest{Update: &req},
+		fmt.Sprintf("update to %v", r.updateLoc),
+		func() (*ops.SiteOperationKey, error) {
+			return operator.CreateSiteAppUpdateOperation(context.TODO(), req)
+		})
 }
 
 func (r clusterInitializer) newOperationPlan(
@@ -298,10 +345,14 @@ func (r clusterInitializer) updateDeployRequest(req deployAgentsRequest) deployA
 }
 
 type clusterInitializer struct {
-	updateLoc     loc.Locator
-	updatePackage string
-	unattended    bool
-	values        []byte
+	updateLoc               loc.Locator
+	updatePackage           string
+	unattended              bool
+	values                  []byte
+	strategy                *storage.UpdateStrategy
+	rollbackOnFailure       bool
+	ignoreMaintenanceWindow bool
+	queue                   queueConfig
 }
 
 const (
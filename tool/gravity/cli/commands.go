@@ -66,6 +66,8 @@ type Application struct {
 	LeaveCmd LeaveCmd
 	// RemoveCmd removes the specified node from the cluster
 	RemoveCmd RemoveCmd
+	// ExpandCmd creates an operation that adds several nodes to the cluster
+	ExpandCmd ExpandCmd
 	// StopCmd stops all gravity services on the node
 	StopCmd StopCmd
 	// StartCmd starts all gravity services on the node
@@ -108,6 +110,8 @@ type Application struct {
 	StatusClusterCmd StatusClusterCmd
 	// StatusHistoryCmd displays the cluster status history
 	StatusHistoryCmd StatusHistoryCmd
+	// StatusCancelCmd cancels a queued operation
+	StatusCancelCmd StatusCancelCmd
 	// StatusResetCmd resets the cluster to active state
 	StatusResetCmd StatusResetCmd
 	// RegistryCmd allows to interact with the cluster private registry
@@ -162,6 +166,8 @@ type Application struct {
 	AppHookCmd AppHookCmd
 	// AppUnpackCmd unpacks specified app resources
 	AppUnpackCmd AppUnpackCmd
+	// AppSBOMCmd displays the software bill of materials of an app
+	AppSBOMCmd AppSBOMCmd
 	// WizardCmd starts installer in UI mode
 	WizardCmd WizardCmd
 	// AppPackageCmd displays the name of app in installer tarball
@@ -302,6 +308,10 @@ type Application struct {
 	SystemStreamRuntimeJournalCmd SystemStreamRuntimeJournalCmd
 	// SystemGCJournalCmd cleans up stale journal files
 	SystemGCJournalCmd SystemGCJournalCmd
+	// SystemImportBoltCmd imports a bolt database into a SQL storage backend
+	SystemImportBoltCmd SystemImportBoltCmd
+	// SystemMigrateBackendCmd migrates data between storage backends
+	SystemMigrateBackendCmd SystemMigrateBackendCmd
 	// SystemGCPackageCmd removes unused packages
 	SystemGCPackageCmd SystemGCPackageCmd
 	// SystemGCRegistryCmd removes unused docker images
@@ -447,6 +457,8 @@ type JoinCmd struct {
 	// the client will simply connect to the service and stream its output and errors
 	// and control whether it should stop
 	FromService *bool
+	// Parallel is the maximum number of phases to execute concurrently
+	Parallel *int
 }
 
 // AutoJoinCmd uses cloud provider info to join existing cluster
@@ -474,6 +486,12 @@ type AutoJoinCmd struct {
 	// the client will simply connect to the service and stream its output and errors
 	// and control whether it should stop
 	FromService *bool
+	// Provider is the autoscaling provider used to discover the cluster
+	Provider *string
+	// DiscoveryURL is the discovery endpoint URL for the webhook provider
+	DiscoveryURL *string
+	// DiscoverySecret is the discovery endpoint secret for the webhook provider
+	DiscoverySecret *string
 }
 
 // LeaveCmd removes the current node from the cluster
@@ -488,14 +506,23 @@ type LeaveCmd struct {
 // RemoveCmd removes the specified node from the cluster
 type RemoveCmd struct {
 	*kingpin.CmdClause
-	// Node is the node to remove
-	Node *string
+	// Nodes lists the nodes to remove
+	Nodes *[]string
 	// Force suppresses operation failures
 	Force *bool
 	// Confirm suppresses confirmation prompt
 	Confirm *bool
 }
 
+// ExpandCmd creates an operation that adds several nodes to the cluster
+type ExpandCmd struct {
+	*kingpin.CmdClause
+	// Role is the role of the nodes to add
+	Role *string
+	// Count is the number of nodes to add
+	Count *int
+}
+
 // ResumeCmd resumes active operation
 type ResumeCmd struct {
 	*kingpin.CmdClause
@@ -507,6 +534,8 @@ type ResumeCmd struct {
 	Force *bool
 	// PhaseTimeout is the rollback timeout
 	PhaseTimeout *time.Duration
+	// Parallel is the maximum number of phases to execute concurrently
+	Parallel *int
 }
 
 // PlanCmd manages an operation plan
@@ -525,6 +554,12 @@ type PlanDisplayCmd struct {
 	Output *constants.Format
 	// Short is a shorthand for short output format
 	Short *bool
+	// Follow displays the plan every time it changes until the operation finishes
+	Follow *bool
+	// DryRun displays the changes the remaining phases would make
+	DryRun *bool
+	// Format exports the plan in one of the machine-readable formats
+	Format *string
 }
 
 // PlanExecuteCmd executes a phase of an active operation
@@ -536,6 +571,8 @@ type PlanExecuteCmd struct {
 	Force *bool
 	// PhaseTimeout is the execution timeout
 	PhaseTimeout *time.Duration
+	// DryRun displays the changes the phase would make without executing it
+	DryRun *bool
 }
 
 // PlanRollbackCmd rolls back a phase of an active operation
@@ -565,6 +602,8 @@ type PlanResumeCmd struct {
 	Force *bool
 	// PhaseTimeout is the rollback timeout
 	PhaseTimeout *time.Duration
+	// Parallel is the maximum number of phases to execute concurrently
+	Parallel *int
 }
 
 // PlanCompleteCmd completes the operation plan
@@ -666,12 +705,26 @@ type UpgradeCmd struct {
 	Force *bool
 	// Resume resumes failed upgrade
 	Resume *bool
+	// Parallel is the maximum number of phases to execute concurrently
+	Parallel *int
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
 	// Set is a list of Helm chart values set on the CLI.
 	Set *[]string
 	// Values is a list of YAML files with Helm chart values.
 	Values *[]string
+	// Canary specifies whether a single regular node is updated first
+	Canary *bool
+	// BatchSize is the number of regular nodes updated concurrently
+	BatchSize *int
+	// RollbackOnFailure specifies whether the operation is automatically rolled back if it fails
+	RollbackOnFailure *bool
+	// IgnoreMaintenanceWindow allows the operation to start outside of the maintenance window
+	IgnoreMaintenanceWindow *bool
+	// Queue queues the operation to start once the cluster becomes idle
+	Queue *bool
+	// QueueTTL is how long the operation waits in the queue
+	QueueTTL *time.Duration
 }
 
 // StatusCmd combines subcommands for displaying status information
@@ -690,6 +743,8 @@ type StatusClusterCmd struct {
 	OperationID *string
 	// Seconds displays status continuously
 	Seconds *int
+	// Watch displays status every time the cluster changes
+	Watch *bool
 	// Output is output format
 	Output *constants.Format
 }
@@ -699,6 +754,13 @@ type StatusHistoryCmd struct {
 	*kingpin.CmdClause
 }
 
+// StatusCancelCmd removes an operation from the cluster operation queue
+type StatusCancelCmd struct {
+	*kingpin.CmdClause
+	// QueueID is the ID of the queued operation
+	QueueID *string
+}
+
 // StatusResetCmd resets cluster to active state
 type StatusResetCmd struct {
 	*kingpin.CmdClause
@@ -933,6 +995,10 @@ type AppImportCmd struct {
 	SetDeps *loc.Locators
 	// Parallel defines the number of tasks to execute concurrently
 	Parallel *int
+	// SignKey is the path to the private key to sign the application package with
+	SignKey *string
+	// SignCert is the path to the certificate chain of the signing key
+	SignCert *string
 }
 
 // AppExportCmd exports specified app into registry
@@ -1032,6 +1098,17 @@ type AppUnpackCmd struct {
 	ServiceUID *string
 }
 
+// AppSBOMCmd displays the software bill of materials of an app
+type AppSBOMCmd struct {
+	*kingpin.CmdClause
+	// Image is the application image name
+	Image *string
+	// OpsCenterURL is app service URL to read app from
+	OpsCenterURL *string
+	// Format is the output format
+	Format *constants.Format
+}
+
 // WizardCmd starts installer in UI mode
 type WizardCmd struct {
 	*kingpin.CmdClause
@@ -1659,6 +1736,42 @@ type SystemStreamRuntimeJournalCmd struct {
 	*kingpin.CmdClause
 }
 
+// SystemImportBoltCmd imports a bolt database into a SQL storage backend
+type SystemImportBoltCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the bolt database to import
+	Path *string
+	// Driver is the SQL driver name
+	Driver *string
+	// DataSource is the SQL data source
+	DataSource *string
+}
+
+// SystemMigrateBackendCmd migrates data between storage backends
+type SystemMigrateBackendCmd struct {
+	*kingpin.CmdClause
+	// From is the type of the source backend
+	From *string
+	// To is the type of the target backend
+	To *string
+	// BoltPath is the path to the bolt database
+	BoltPath *string
+	// EtcdNodes lists the etcd endpoints
+	EtcdNodes *[]string
+	// EtcdPrefix is the key under which data is stored in etcd
+	EtcdPrefix *string
+	// EtcdCertFile is the path to the etcd client certificate
+	EtcdCertFile *string
+	// EtcdKeyFile is the path to the etcd client private key
+	EtcdKeyFile *string
+	// EtcdCAFile is the path to the etcd certificate authority
+	EtcdCAFile *string
+	// DryRun displays the changes without applying them
+	DryRun *bool
+	// Resume continues an interrupted migration
+	Resume *bool
+}
+
 // SystemGCJournalCmd manages cleanup of journal files
 type SystemGCJournalCmd struct {
 	*kingpin.CmdClause
@@ -1700,6 +1813,12 @@ type GarbageCollectCmd struct {
 	// Confirmed is whether the user has confirmed the removal of custom docker
 	// images
 	Confirmed *bool
+	// IgnoreMaintenanceWindow allows the operation to start outside of the maintenance window
+	IgnoreMaintenanceWindow *bool
+	// Queue queues the operation to start once the cluster becomes idle
+	Queue *bool
+	// QueueTTL is how long the operation waits in the queue
+	QueueTTL *time.Duration
 }
 
 // GarbageCollectPlanCmd displays the plan of the garbage collection operation
@@ -1769,6 +1888,12 @@ type ResourceCreateCmd struct {
 	Manual *bool
 	// Confirmed suppresses confirmation prompt
 	Confirmed *bool
+	// IgnoreMaintenanceWindow allows the operation to start outside of the maintenance window
+	IgnoreMaintenanceWindow *bool
+	// Queue queues the operation to start once the cluster becomes idle
+	Queue *bool
+	// QueueTTL is how long the operation waits in the queue
+	QueueTTL *time.Duration
 }
 
 // ResourceRemoveCmd removes specified resource
@@ -1788,6 +1913,12 @@ type ResourceRemoveCmd struct {
 	Manual *bool
 	// Confirmed suppresses confirmation prompt
 	Confirmed *bool
+	// IgnoreMaintenanceWindow allows the operation to start outside of the maintenance window
+	IgnoreMaintenanceWindow *bool
+	// Queue queues the operation to start once the cluster becomes idle
+	Queue *bool
+	// QueueTTL is how long the operation waits in the queue
+	QueueTTL *time.Duration
 }
 
 // ResourceGetCmd shows specified resource
@@ -1812,4 +1943,6 @@ type TopCmd struct {
 	Interval *time.Duration
 	// Step is the max time b/w two datapoints.
 	Step *time.Duration
+	// Format is the format to export metrics in instead of interactive display.
+	Format *constants.Format
 }
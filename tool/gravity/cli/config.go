@@ -31,9 +31,10 @@ import (
 	"github.com/fatih/color"
 	"github.com/gravitational/gravity/lib/app"
 	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/autoscale"
 	autoscaleaws "github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/webhook"
 	awscloud "github.com/gravitational/gravity/lib/cloudprovider/aws"
-	cloudaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	cloudgce "github.com/gravitational/gravity/lib/cloudprovider/gce"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -696,6 +697,8 @@ type JoinConfig struct {
 	OperationID string
 	// FromService specifies whether the process runs in service mode
 	FromService bool
+	// Parallel is the maximum number of phases to execute concurrently
+	Parallel int
 	// SkipWizard specifies to the join agents that this join request is not too a wizard,
 	// and as such wizard connectivity should be skipped
 	SkipWizard bool
@@ -716,6 +719,7 @@ func NewJoinConfig(g *Application) JoinConfig {
 		Mounts:        *g.JoinCmd.Mounts,
 		OperationID:   *g.JoinCmd.OperationID,
 		FromService:   *g.JoinCmd.FromService,
+		Parallel:      *g.JoinCmd.Parallel,
 	}
 }
 
@@ -754,6 +758,7 @@ func (j *JoinConfig) NewPeerConfig(env, joinEnv *localenv.LocalEnvironment) (con
 		StateDir:           joinEnv.StateDir,
 		OperationID:        j.OperationID,
 		SkipWizard:         j.SkipWizard,
+		Parallel:           j.Parallel,
 	}, nil
 }
 
@@ -781,14 +786,15 @@ func (j *JoinConfig) GetRuntimeConfig() proto.RuntimeConfig {
 }
 
 func (r *removeConfig) checkAndSetDefaults() error {
-	if r.server == "" {
-		return trace.BadParameter("server flag is required")
+	if len(r.servers) == 0 {
+		return trace.BadParameter("at least one node to remove is required")
 	}
 	return nil
 }
 
 type removeConfig struct {
-	server    string
+	// servers lists the nodes to remove
+	servers   []string
 	force     bool
 	confirmed bool
 }
@@ -810,6 +816,31 @@ func (r *autojoinConfig) newJoinConfig() JoinConfig {
 	}
 }
 
+// newDiscovery returns the cluster discovery for the configured provider
+func (r *autojoinConfig) newDiscovery() (autoscale.Discovery, error) {
+	switch r.provider {
+	case "", autoscaleaws.ProviderName:
+		discovery, err := autoscaleaws.New(autoscaleaws.Config{
+			ClusterName: r.clusterName,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return discovery, nil
+	case webhook.ProviderName:
+		discovery, err := webhook.New(webhook.Config{
+			ClusterName:  r.clusterName,
+			DiscoveryURL: r.discoveryURL,
+			Secret:       r.discoverySecret,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return discovery, nil
+	}
+	return nil, trace.BadParameter("unsupported autoscaling provider %q", r.provider)
+}
+
 func (r *autojoinConfig) checkAndSetDefaults() error {
 	if r.advertiseAddr == "" {
 		return trace.BadParameter("advertise address is required")
@@ -838,6 +869,12 @@ type autojoinConfig struct {
 	serviceURL    string
 	advertiseAddr string
 	token         string
+	// provider is the name of the autoscaling provider used for discovery
+	provider string
+	// discoveryURL is the discovery endpoint URL for the webhook provider
+	discoveryURL string
+	// discoverySecret is the discovery endpoint secret for the webhook provider
+	discoverySecret string
 }
 
 func (r *agentConfig) newServiceArgs(gravityPath string) (args []string) {
@@ -939,26 +976,25 @@ func retryUpdateJoinConfigFromCloudMetadata(ctx context.Context, config *autojoi
 }
 
 func updateJoinConfigFromCloudMetadata(ctx context.Context, config *autojoinConfig) error {
-	instance, err := cloudaws.NewLocalInstance()
+	discovery, err := config.newDiscovery()
 	if err != nil {
-		log.WithError(err).Warn("Failed to fetch instance metadata on AWS.")
-		return trace.BadParameter("autojoin only supports AWS")
+		return trace.Wrap(err)
 	}
-	config.advertiseAddr = instance.PrivateIP
 
-	autoscaler, err := autoscaleaws.New(autoscaleaws.Config{
-		ClusterName: config.clusterName,
-	})
+	instance, err := discovery.LocalInstance(ctx)
 	if err != nil {
-		return trace.Wrap(err)
+		log.WithError(err).Warn("Failed to fetch local instance metadata.")
+		return trace.BadParameter("failed to determine local instance using %v provider",
+			config.provider)
 	}
+	config.advertiseAddr = instance.PrivateIP
 
-	config.serviceURL, err = autoscaler.GetServiceURL(ctx)
+	config.serviceURL, err = discovery.GetServiceURL(ctx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	config.token, err = autoscaler.GetJoinToken(ctx)
+	config.token, err = discovery.GetJoinToken(ctx)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -35,7 +35,8 @@ func updateEnviron(
 	ctx context.Context,
 	localEnv, updateEnv *localenv.LocalEnvironment,
 	env storage.EnvironmentVariables,
-	manual, confirmed bool,
+	manual, confirmed, ignoreMaintenanceWindow bool,
+	queue queueConfig,
 ) error {
 	if !confirmed {
 		if manual {
@@ -52,7 +53,7 @@ func updateEnviron(
 			return nil
 		}
 	}
-	updater, err := newEnvironUpdater(ctx, localEnv, updateEnv, env)
+	updater, err := newEnvironUpdater(ctx, localEnv, updateEnv, env, ignoreMaintenanceWindow, queue)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -65,9 +66,11 @@ func updateEnviron(
 	return nil
 }
 
-func newEnvironUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, environ storage.EnvironmentVariables) (*update.Updater, error) {
+func newEnvironUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, environ storage.EnvironmentVariables, ignoreMaintenanceWindow bool, queue queueConfig) (*update.Updater, error) {
 	init := environInitializer{
-		environ: environ,
+		environ:                 environ,
+		ignoreMaintenanceWindow: ignoreMaintenanceWindow,
+		queue:                   queue,
 	}
 	return newUpdater(ctx, localEnv, updateEnv, init)
 }
@@ -172,21 +175,25 @@ func (r environInitializer) validatePreconditions(*localenv.LocalEnvironment, op
 }
 
 func (r environInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
-	key, err := operator.CreateUpdateEnvarsOperation(context.TODO(),
-		ops.CreateUpdateEnvarsOperationRequest{
-			ClusterKey: cluster.Key(),
-			Env:        r.environ.GetKeyValues(),
-		},
-	)
-	if err != nil {
-		if trace.IsNotFound(err) {
-			return nil, trace.NotImplemented(
-				"cluster operator does not implement the API required for updating runtime environment. " +
-					"Please make sure you're running the command on a compatible cluster.")
-		}
-		return nil, trace.Wrap(err)
+	req := ops.CreateUpdateEnvarsOperationRequest{
+		ClusterKey:              cluster.Key(),
+		Env:                     r.environ.GetKeyValues(),
+		IgnoreMaintenanceWindow: r.ignoreMaintenanceWindow,
 	}
-	return key, nil
+	return r.queue.createOperation(context.TODO(), operator, cluster,
+		ops.QueuedOperationRequest{Environ: &req}, "",
+		func() (*ops.SiteOperationKey, error) {
+			key, err := operator.CreateUpdateEnvarsOperation(context.TODO(), req)
+			if err != nil {
+				if trace.IsNotFound(err) {
+					return nil, trace.NotImplemented(
+						"cluster operator does not implement the API required for updating runtime environment. " +
+							"Please make sure you're running the command on a compatible cluster.")
+				}
+				return nil, trace.Wrap(err)
+			}
+			return key, nil
+		})
 }
 
 func (environInitializer) newOperationPlan(
@@ -239,7 +246,9 @@ func (environInitializer) updateDeployRequest(req deployAgentsRequest) deployAge
 }
 
 type environInitializer struct {
-	environ storage.EnvironmentVariables
+	environ                 storage.EnvironmentVariables
+	ignoreMaintenanceWindow bool
+	queue                   queueConfig
 }
 
 const (
@@ -37,7 +37,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool) error {
+func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed, ignoreMaintenanceWindow bool, queue queueConfig) error {
 	if !confirmed {
 		env.Println("This operation will also remove docker images that " +
 			"you manually pushed to the docker registry. Are you sure?")
@@ -51,7 +51,7 @@ func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool) erro
 		}
 	}
 
-	collector, err := newCollector(env)
+	collector, err := newCollector(env, ignoreMaintenanceWindow, queue)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -84,7 +84,7 @@ $ gravity plan resume`)
 	return nil
 }
 
-func newCollector(env *localenv.LocalEnvironment) (*vacuum.Collector, error) {
+func newCollector(env *localenv.LocalEnvironment, ignoreMaintenanceWindow bool, queue queueConfig) (*vacuum.Collector, error) {
 	clusterPackages, err := env.ClusterPackages()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -115,18 +115,26 @@ func newCollector(env *localenv.LocalEnvironment) (*vacuum.Collector, error) {
 		return nil, trace.Wrap(err, "failed to connect to teleport proxy")
 	}
 
-	key, err := operator.CreateClusterGarbageCollectOperation(context.TODO(),
-		ops.CreateClusterGarbageCollectOperationRequest{
-			AccountID:   cluster.AccountID,
-			ClusterName: cluster.Domain,
-		},
-	)
+	createReq := ops.CreateClusterGarbageCollectOperationRequest{
+		AccountID:               cluster.AccountID,
+		ClusterName:             cluster.Domain,
+		IgnoreMaintenanceWindow: ignoreMaintenanceWindow,
+	}
+	key, err := queue.createOperation(context.TODO(), operator, *cluster,
+		ops.QueuedOperationRequest{GarbageCollect: &createReq}, "",
+		func() (*ops.SiteOperationKey, error) {
+			key, err := operator.CreateClusterGarbageCollectOperation(context.TODO(), createReq)
+			if err != nil {
+				if trace.IsNotFound(err) {
+					return nil, trace.NotImplemented(
+						"cluster operator does not implement the API required for garbage collection. " +
+							"Please make sure you're running the command on a compatible cluster.")
+				}
+				return nil, trace.Wrap(err)
+			}
+			return key, nil
+		})
 	if err != nil {
-		if trace.IsNotFound(err) {
-			return nil, trace.NotImplemented(
-				"cluster operator does not implement the API required for garbage collection. " +
-					"Please make sure you're running the command on a compatible cluster.")
-		}
 		return nil, trace.Wrap(err)
 	}
 
@@ -43,7 +43,9 @@ import (
 	"github.com/gravitational/gravity/lib/process"
 	pb "github.com/gravitational/gravity/lib/rpc/proto"
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/system/environ"
 	"github.com/gravitational/gravity/lib/system/service"
 	"github.com/gravitational/gravity/lib/system/signals"
@@ -344,7 +346,7 @@ func tryLeave(env *localenv.LocalEnvironment, c leaveConfig) error {
 	}
 
 	err = remove(env, removeConfig{
-		server:    server.Hostname,
+		servers:   []string{server.Hostname},
 		confirmed: true,
 		force:     c.force,
 	})
@@ -375,14 +377,20 @@ func remove(env *localenv.LocalEnvironment, c removeConfig) error {
 		return trace.Wrap(err)
 	}
 
-	server, err := findServer(*site, []string{c.server})
-	if err != nil {
-		return trace.Wrap(err)
+	var hostnames, descriptions []string
+	for _, name := range c.servers {
+		server, err := findServer(*site, []string{name})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		hostnames = append(hostnames, server.Hostname)
+		descriptions = append(descriptions,
+			fmt.Sprintf("%v (%v)", server.Hostname, server.AdvertiseIP))
 	}
 
 	if !c.confirmed {
 		err = enforceConfirmation(
-			"Please confirm removing %v (%v) from the cluster", server.Hostname, server.AdvertiseIP)
+			"Please confirm removing %v from the cluster", strings.Join(descriptions, ", "))
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -392,7 +400,7 @@ func remove(env *localenv.LocalEnvironment, c removeConfig) error {
 		ops.CreateSiteShrinkOperationRequest{
 			AccountID:  site.AccountID,
 			SiteDomain: site.Domain,
-			Servers:    []string{server.Hostname},
+			Servers:    hostnames,
 			Force:      c.force,
 		})
 	if err != nil {
@@ -403,6 +411,59 @@ func remove(env *localenv.LocalEnvironment, c removeConfig) error {
 	return nil
 }
 
+// expandCluster creates an expand operation that adds count nodes with
+// the specified role to the cluster and prints the command to join
+// the nodes with
+func expandCluster(env *localenv.LocalEnvironment, role string, count int) error {
+	if count < 1 {
+		return trace.BadParameter("the number of nodes to add should be positive")
+	}
+
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	site, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	masters := storage.Servers(site.ClusterState.Servers).Masters()
+	if len(masters) == 0 {
+		return trace.NotFound("cluster %v has no master nodes", site.Domain)
+	}
+
+	token, err := operator.GetExpandToken(site.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	key, err := operator.CreateSiteExpandOperation(context.TODO(),
+		ops.CreateSiteExpandOperationRequest{
+			AccountID:   site.AccountID,
+			SiteDomain:  site.Domain,
+			Provisioner: schema.ProvisionerOnPrem,
+			Servers:     map[string]int{role: count},
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = operator.SetOperationState(*key, ops.SetOperationStateRequest{
+		State: ops.OperationStateReady,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	fmt.Printf("launched operation %q, run the following command on each of the %v node(-s) to add:\n\n", key.OperationID, count)
+	fmt.Printf("    gravity join %v --token=%v --role=%v --operation-id=%v\n\n",
+		masters[0].AdvertiseIP, token.Token, role, key.OperationID)
+	fmt.Println("the nodes are added once all of them have joined, use 'gravity status' to poll the progress")
+	return nil
+}
+
 func autojoin(env *localenv.LocalEnvironment, environ LocalEnvironmentFactory, d autojoinConfig) (err error) {
 	if d.fromService {
 		return autojoinFromService(env, environ, d)
@@ -568,6 +629,26 @@ func setPhaseFromService(env *localenv.LocalEnvironment, params SetPhaseParams,
 	}, params.State)
 }
 
+// dryRunPhaseFromService describes the changes the specified phase of
+// the install or expand operation would make using the installer service
+func dryRunPhaseFromService(env *localenv.LocalEnvironment, operation ops.SiteOperation, phaseID string) ([]storage.PhaseDryRun, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := signals.NewInterruptHandler(ctx, cancel, clientInterruptSignals)
+	defer interrupt.Close()
+	go clientTerminationHandler(interrupt, env)
+	client, err := installerclient.New(ctx, installerclient.Config{
+		InterruptHandler: interrupt,
+		ConnectStrategy:  &installerclient.ResumeStrategy{},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.DryRunPhase(ctx, installerclient.Phase{
+		Key: operation.Key(),
+		ID:  phaseID,
+	})
+}
+
 func executePhaseFromService(
 	env *localenv.LocalEnvironment,
 	params PhaseParams,
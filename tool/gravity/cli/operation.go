@@ -48,6 +48,9 @@ type PhaseParams struct {
 	Timeout time.Duration
 	// SkipVersionCheck overrides the verification of binary version compatibility
 	SkipVersionCheck bool
+	// Parallel is the maximum number of phases to execute concurrently
+	// when resuming an operation
+	Parallel int
 }
 
 func (r PhaseParams) isResume() bool {
@@ -72,6 +75,7 @@ func resumeOperation(localEnv *localenv.LocalEnvironment, environ LocalEnvironme
 		Timeout:          params.Timeout,
 		SkipVersionCheck: params.SkipVersionCheck,
 		OperationID:      params.OperationID,
+		Parallel:         params.Parallel,
 	})
 	if err == nil {
 		return nil
@@ -103,6 +107,8 @@ func executePhase(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentF
 		return executeConfigPhase(localEnv, environ, params, *op)
 	case ops.OperationGarbageCollect:
 		return executeGarbageCollectPhase(localEnv, params, op)
+	case ops.OperationShrink, ops.OperationUninstall:
+		return executeOperatorPhase(localEnv, params, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support plan execution", op.Type)
 	}
@@ -125,6 +131,8 @@ func setPhase(env *localenv.LocalEnvironment, environ LocalEnvironmentFactory, p
 		err = setConfigPhase(env, environ, params, *op)
 	case ops.OperationGarbageCollect:
 		err = setGarbageCollectPhase(env, params, op)
+	case ops.OperationShrink, ops.OperationUninstall:
+		err = setOperatorPhase(env, params, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support setting phase state", op.Type)
 	}
@@ -152,6 +160,8 @@ func rollbackPhase(localEnv *localenv.LocalEnvironment, environ LocalEnvironment
 		return rollbackEnvironPhase(localEnv, environ, params, *op)
 	case ops.OperationUpdateConfig:
 		return rollbackConfigPhase(localEnv, environ, params, *op)
+	case ops.OperationShrink, ops.OperationUninstall:
+		return rollbackOperatorPhase(localEnv, params, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support plan rollback", op.Type)
 	}
@@ -173,6 +183,8 @@ func completeOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnv
 		err = completeEnvironPlan(localEnv, environ, *op)
 	case ops.OperationUpdateConfig:
 		err = completeConfigPlan(localEnv, environ, *op)
+	case ops.OperationShrink:
+		return completeClusterOperationPlan(localEnv, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support plan completion", op.Type)
 	}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// executeOperatorPhase executes the specified phase of an operation
+// which plan is executed by the cluster operator, such as shrink or uninstall
+func executeOperatorPhase(env *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	return trace.Wrap(runOperatorPhase(env, params, operation, false))
+}
+
+// rollbackOperatorPhase rolls back the specified phase of an operation
+// which plan is executed by the cluster operator
+func rollbackOperatorPhase(env *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	return trace.Wrap(runOperatorPhase(env, params, operation, true))
+}
+
+func runOperatorPhase(env *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation, rollback bool) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx := context.Background()
+	if params.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+	req := ops.RunPhaseRequest{
+		Key:      operation.Key(),
+		PhaseID:  params.PhaseID,
+		Force:    params.Force,
+		Rollback: rollback,
+	}
+	switch operation.Type {
+	case ops.OperationShrink:
+		return trace.Wrap(operator.RunShrinkPhase(ctx, req))
+	case ops.OperationUninstall:
+		return trace.Wrap(operator.RunUninstallPhase(ctx, req))
+	default:
+		return trace.BadParameter("operation type %q is not executed by the cluster operator",
+			operation.Type)
+	}
+}
+
+// dryRunOperatorPhase describes the changes the specified phase of an operation
+// which plan is executed by the cluster operator would make if executed
+func dryRunOperatorPhase(env *localenv.LocalEnvironment, operation ops.SiteOperation, phaseID string) ([]storage.PhaseDryRun, error) {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return operator.DryRunOperationPhase(context.TODO(), ops.RunPhaseRequest{
+		Key:     operation.Key(),
+		PhaseID: phaseID,
+	})
+}
+
+// setOperatorPhase sets the specified phase state of an operation
+// which plan is executed by the cluster operator
+func setOperatorPhase(env *localenv.LocalEnvironment, params SetPhaseParams, operation ops.SiteOperation) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return operator.CreateOperationPlanChange(operation.Key(), storage.PlanChange{
+		ID:          uuid.New(),
+		ClusterName: operation.SiteDomain,
+		OperationID: operation.ID,
+		PhaseID:     params.PhaseID,
+		NewState:    params.State,
+		Created:     time.Now().UTC(),
+	})
+}
@@ -18,6 +18,7 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -27,11 +28,13 @@ import (
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/signals"
 	"github.com/gravitational/gravity/lib/update"
 	clusterupdate "github.com/gravitational/gravity/lib/update/cluster"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/fatih/color"
+	"github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
 )
@@ -65,106 +68,259 @@ func initUpdateOperationPlan(localEnv, updateEnv *localenv.LocalEnvironment) err
 }
 
 func displayOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, operationID string, format constants.Format) error {
+	op, err := getLastPlanOperation(localEnv, environ, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	plan, err := getOperationPlan(localEnv, environ, *op)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(outputPlan(*plan, format))
+}
+
+// exportOperationPlan outputs the operation plan in the specified export format
+func exportOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, operationID string, format string) (err error) {
+	op, err := getLastPlanOperation(localEnv, environ, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	plan, err := getOperationPlan(localEnv, environ, *op)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case fsm.PlanExportFormatDot:
+		err = fsm.FormatOperationPlanDot(os.Stdout, *plan)
+	case fsm.PlanExportFormatMermaid:
+		err = fsm.FormatOperationPlanMermaid(os.Stdout, *plan)
+	case fsm.PlanExportFormatJSON:
+		err = fsm.FormatOperationPlanExport(os.Stdout, *plan)
+	default:
+		return trace.BadParameter("unknown export format %q, supported are: %v",
+			format, fsm.PlanExportFormats)
+	}
+	return trace.Wrap(err)
+}
+
+// followOperationPlan displays the operation plan every time it changes
+// until the operation either completes or fails
+func followOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, operationID string, format constants.Format) error {
+	op, err := getLastPlanOperation(localEnv, environ, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	watch, closeWatch, err := getOperationPlanWatch(localEnv, environ, *op)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer closeWatch()
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := signals.WatchTerminationSignals(ctx, cancel, localEnv)
+	defer interrupt.Close()
+	err = followChanges(ctx, watch, func() (bool, error) {
+		plan, err := getOperationPlan(localEnv, environ, *op)
+		if err != nil {
+			return false, trace.Wrap(err)
+		}
+		if err := outputPlan(*plan, format); err != nil {
+			return false, trace.Wrap(err)
+		}
+		return fsm.IsCompleted(plan) || fsm.HasFailed(plan), nil
+	})
+	return trace.Wrap(err)
+}
+
+// dryRunOperationPhase displays the changes the specified phase of the
+// active operation would make without executing it.
+// The root phase displays the changes of the remaining phases of the plan
+func dryRunOperationPhase(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, operationID, phaseID string, format constants.Format) error {
+	op, err := getActiveOperation(localEnv, environ, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var phases []fsm.PhaseDryRun
+	switch op.Type {
+	case ops.OperationInstall, ops.OperationExpand:
+		phases, err = dryRunPhaseFromService(localEnv, *op, phaseID)
+	case ops.OperationUpdate, ops.OperationUpdateRuntimeEnviron, ops.OperationUpdateConfig:
+		phases, err = dryRunUpdatePhase(localEnv, environ, *op, phaseID)
+	case ops.OperationShrink, ops.OperationUninstall:
+		phases, err = dryRunOperatorPhase(localEnv, *op, phaseID)
+	default:
+		return trace.BadParameter("operation type %q does not support dry-run", op.Type)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(outputDryRun(phases, format))
+}
+
+func dryRunUpdatePhase(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, op ops.SiteOperation, phaseID string) ([]fsm.PhaseDryRun, error) {
+	updateEnv, err := environ.NewUpdateEnv()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer updateEnv.Close()
+	var updater *update.Updater
+	switch op.Type {
+	case ops.OperationUpdate:
+		updater, err = getClusterUpdater(localEnv, updateEnv, op, true, 0)
+	case ops.OperationUpdateRuntimeEnviron:
+		updater, err = getEnvironUpdater(localEnv, updateEnv, op)
+	case ops.OperationUpdateConfig:
+		updater, err = getConfigUpdater(localEnv, updateEnv, op)
+	default:
+		return nil, trace.BadParameter("operation %v is not an update operation", op.Type)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer updater.Close()
+	return updater.DryRun(context.TODO(), phaseID)
+}
+
+func getLastPlanOperation(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, operationID string) (*ops.SiteOperation, error) {
 	op, err := getLastOperation(localEnv, environ, operationID)
 	if err != nil {
 		if trace.IsNotFound(err) {
 			// FIXME(dmitri): better phrasing
-			return trace.NotFound(`no operation found.
+			return nil, trace.NotFound(`no operation found.
 This usually means that the installation has failed to start.
 To restart the installation, use 'gravity resume' after fixing the issues.
 `)
 		}
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
+	return op, nil
+}
+
+func getOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, op ops.SiteOperation) (plan *storage.OperationPlan, err error) {
 	if op.IsCompleted() {
-		return displayClusterOperationPlan(localEnv, op.Key(), format)
+		return getClusterOperationPlan(localEnv, op.Key())
 	}
 	switch op.Type {
 	case ops.OperationInstall, ops.OperationReconfigure:
-		err = displayInstallOperationPlan(op.Key(), format)
+		plan, err = getInstallOperationPlan(op.Key())
 	case ops.OperationExpand:
-		err = displayExpandOperationPlan(environ, op.Key(), format)
+		plan, err = getExpandOperationPlan(environ, op.Key())
 	case ops.OperationUpdate:
-		err = displayUpdateOperationPlan(localEnv, environ, op.Key(), format)
+		plan, err = getUpdateOperationPlan(localEnv, environ, op.Key())
 	case ops.OperationUpdateRuntimeEnviron:
-		err = displayUpdateOperationPlan(localEnv, environ, op.Key(), format)
+		plan, err = getUpdateOperationPlan(localEnv, environ, op.Key())
 	case ops.OperationUpdateConfig:
-		err = displayUpdateOperationPlan(localEnv, environ, op.Key(), format)
-	case ops.OperationGarbageCollect:
-		err = displayClusterOperationPlan(localEnv, op.Key(), format)
+		plan, err = getUpdateOperationPlan(localEnv, environ, op.Key())
+	case ops.OperationGarbageCollect, ops.OperationShrink, ops.OperationUninstall:
+		plan, err = getClusterOperationPlan(localEnv, op.Key())
 	default:
-		return trace.BadParameter("unknown operation type %q", op.Type)
+		return nil, trace.BadParameter("unknown operation type %q", op.Type)
 	}
 	if err != nil && trace.IsNotFound(err) {
 		// Fallback to cluster plan
-		return displayClusterOperationPlan(localEnv, op.Key(), format)
+		return getClusterOperationPlan(localEnv, op.Key())
 	}
-	return trace.Wrap(err)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+// getOperationPlanWatch returns a watch for changes to the plan of the specified
+// operation from the same source getOperationPlan retrieves the plan from.
+// The returned function releases the resources held by the watch
+func getOperationPlanWatch(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, op ops.SiteOperation) (watchFunc, func(), error) {
+	req := ops.WatchClusterRequest{
+		SiteKey:     op.ClusterKey(),
+		OperationID: op.ID,
+	}
+	var env *localenv.LocalEnvironment
+	var err error
+	switch op.Type {
+	case ops.OperationInstall, ops.OperationReconfigure:
+		// prefer the wizard process, same as getInstallOperationPlan
+		if remoteEnv, err := localenv.NewRemoteEnvironment(); err == nil && remoteEnv.Operator != nil {
+			return operatorWatch(remoteEnv.Operator, req), func() {}, nil
+		}
+		env, err = localenv.NewLocalWizardEnvironment()
+	case ops.OperationExpand:
+		env, err = environ.NewJoinEnv()
+	case ops.OperationUpdate, ops.OperationUpdateRuntimeEnviron, ops.OperationUpdateConfig:
+		env, err = environ.NewUpdateEnv()
+	default:
+		operator, err := localEnv.SiteOperator()
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		return operatorWatch(operator, req), func() {}, nil
+	}
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	closer := func() {
+		if err := env.Close(); err != nil {
+			log.WithError(err).Warn("Failed to close environment.")
+		}
+	}
+	return backendWatch(env.Backend, req.Prefix()), closer, nil
 }
 
-func displayClusterOperationPlan(env *localenv.LocalEnvironment, opKey ops.SiteOperationKey, format constants.Format) error {
+func getClusterOperationPlan(env *localenv.LocalEnvironment, opKey ops.SiteOperationKey) (*storage.OperationPlan, error) {
 	clusterEnv, err := env.NewClusterEnvironment()
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	plan, err := clusterEnv.Operator.GetOperationPlan(opKey)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
-	err = outputPlan(*plan, format)
-	return trace.Wrap(err)
+	return plan, nil
 }
 
-func displayUpdateOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, opKey ops.SiteOperationKey, format constants.Format) error {
+func getUpdateOperationPlan(localEnv *localenv.LocalEnvironment, environ LocalEnvironmentFactory, opKey ops.SiteOperationKey) (*storage.OperationPlan, error) {
 	updateEnv, err := environ.NewUpdateEnv()
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	defer updateEnv.Close()
 	plan, err := fsm.GetOperationPlan(updateEnv.Backend, opKey)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	reconciledPlan, err := tryReconcilePlan(context.TODO(), localEnv, updateEnv, *plan)
 	if err != nil {
 		logrus.WithError(err).Warn("Failed to reconcile plan.")
-	} else {
-		plan = reconciledPlan
-	}
-	err = outputPlan(*plan, format)
-	if err != nil {
-		return trace.Wrap(err)
+		return plan, nil
 	}
-	return nil
+	return reconciledPlan, nil
 }
 
-func displayInstallOperationPlan(opKey ops.SiteOperationKey, format constants.Format) error {
+func getInstallOperationPlan(opKey ops.SiteOperationKey) (*storage.OperationPlan, error) {
 	plan, err := getPlanFromWizard(opKey)
 	if err == nil {
 		log.Debug("Showing install operation plan retrieved from wizard process.")
-		return trace.Wrap(outputPlan(*plan, format))
+		return plan, nil
 	}
 	plan, err = getPlanFromWizardBackend(opKey)
 	if err != nil {
-		return trace.Wrap(err, "failed to get plan for the install operation.\n"+
+		return nil, trace.Wrap(err, "failed to get plan for the install operation.\n"+
 			"Make suer you are running 'gravity plan' from the installer node.")
 	}
-	return trace.Wrap(outputPlan(*plan, format))
+	return plan, nil
 }
 
-// displayExpandOperationPlan shows plan of the join operation from the local join backend
-func displayExpandOperationPlan(environ LocalEnvironmentFactory, opKey ops.SiteOperationKey, format constants.Format) error {
+// getExpandOperationPlan returns plan of the join operation from the local join backend
+func getExpandOperationPlan(environ LocalEnvironmentFactory, opKey ops.SiteOperationKey) (*storage.OperationPlan, error) {
 	joinEnv, err := environ.NewJoinEnv()
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	defer joinEnv.Close()
 	plan, err := fsm.GetOperationPlan(joinEnv.Backend, opKey)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	log.Debug("Showing join operation plan retrieved from local join backend.")
-	return outputPlan(*plan, format)
+	return plan, nil
 }
 
 func outputPlan(plan storage.OperationPlan, format constants.Format) (err error) {
@@ -188,6 +344,28 @@ func outputPlan(plan storage.OperationPlan, format constants.Format) (err error)
 	return nil
 }
 
+func outputDryRun(phases []fsm.PhaseDryRun, format constants.Format) error {
+	switch format {
+	case constants.EncodingYAML:
+		bytes, err := yaml.Marshal(phases)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Print(string(bytes))
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(phases, "", "  ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	case constants.EncodingText, constants.EncodingShort:
+		fsm.FormatDryRunText(os.Stdout, phases)
+	default:
+		return trace.BadParameter("unknown output format %q", format)
+	}
+	return nil
+}
+
 func explainPlan(phases []storage.OperationPhase) (err error) {
 	for _, phase := range phases {
 		if phase.State == storage.OperationPhaseStateFailed {
@@ -205,6 +383,13 @@ func explainPlan(phases []storage.OperationPhase) (err error) {
 
 func outputPhaseError(phase storage.OperationPhase) error {
 	fmt.Print(color.RedString("The %v phase (%q) has failed", phase.ID, phase.Description))
+	if failures := fsm.HealthCheckFailures(phase); len(failures) != 0 {
+		fmt.Print(color.RedString("\n\tHealth checks failed:\n"))
+		for _, failure := range failures {
+			fmt.Print(color.RedString("\t  - %v\n", failure))
+		}
+		return nil
+	}
 	if phase.Error != nil {
 		var phaseErr trace.TraceErr
 		if err := utils.UnmarshalError(phase.Error.Err, &phaseErr); err != nil {
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// queueConfig defines whether a cluster operation is queued
+// to start once the cluster becomes idle
+type queueConfig struct {
+	// enabled specifies whether the operation is queued
+	enabled bool
+	// ttl is how long the operation waits in the queue
+	ttl time.Duration
+	// printer outputs the operation queue status
+	printer utils.Printer
+}
+
+// createOperation creates a cluster operation using the provided function.
+// If queueing is enabled, the operation request is added to the cluster
+// operation queue instead and the cluster operator creates the operation
+// once it is its turn to start
+func (r queueConfig) createOperation(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	request ops.QueuedOperationRequest,
+	description string,
+	create func() (*ops.SiteOperationKey, error),
+) (*ops.SiteOperationKey, error) {
+	if !r.enabled {
+		return create()
+	}
+	queued, err := operator.QueueOperation(ctx, ops.QueueOperationRequest{
+		SiteKey:     cluster.Key(),
+		Request:     request,
+		Description: description,
+		TTL:         r.ttl,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if r.printer != nil {
+		r.printer.PrintStep("Queued the operation, use 'gravity status cancel %v' to cancel it", queued.ID)
+	}
+	queueKey := ops.QueuedOperationKey{SiteKey: cluster.Key(), ID: queued.ID}
+	key, err := ops.StartQueuedOperation(ctx, ops.StartQueuedOperationRequest{
+		Operator: operator,
+		Key:      queueKey,
+		Printer:  r.printer,
+	})
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			// Do not leave the operation queued with no client to execute it
+			errCancel := operator.CancelQueuedOperation(context.TODO(), queueKey)
+			if errCancel != nil && !trace.IsNotFound(errCancel) {
+				logrus.WithError(errCancel).Warn("Failed to remove operation from the queue.")
+			}
+		}
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+// cancelQueuedOperation removes the operation with the specified ID
+// from the cluster operation queue
+func cancelQueuedOperation(env *localenv.LocalEnvironment, queueID string) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = operator.CancelQueuedOperation(context.TODO(), ops.QueuedOperationKey{
+		SiteKey: cluster.Key(),
+		ID:      queueID,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Queued operation %v has been cancelled.\n", queueID)
+	return nil
+}
@@ -22,12 +22,16 @@ import (
 	"strings"
 	"time"
 
+	autoscaleaws "github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/webhook"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
 
@@ -109,10 +113,11 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.JoinCmd.ServerAddr = g.JoinCmd.Flag("server-addr", "Address of the agent server.").Hidden().String()
 	g.JoinCmd.Mounts = configure.KeyValParam(g.JoinCmd.Flag("mount", "One or several mounts in form <mount-name>:<path>, e.g. data:/var/lib/data."))
 	g.JoinCmd.CloudProvider = g.JoinCmd.Flag("cloud-provider", "[DEPRECATED] This flag has no effect and will be removed in a future version.").String()
-	g.JoinCmd.OperationID = g.JoinCmd.Flag("operation-id", "ID of the operation that was created via UI.").Hidden().String()
+	g.JoinCmd.OperationID = g.JoinCmd.Flag("operation-id", "ID of an existing expand operation to join.").String()
 	g.JoinCmd.FromService = g.JoinCmd.Flag("from-service", "Run in service mode.").Hidden().Bool()
+	g.JoinCmd.Parallel = g.JoinCmd.Flag("parallel", "Maximum number of operation phases to execute concurrently. If unspecified, phases are executed one at a time in plan order.").Int()
 
-	g.AutoJoinCmd.CmdClause = g.Command("autojoin", "Use cloud provider data to join a node to existing cluster.")
+	g.AutoJoinCmd.CmdClause = g.Command("autojoin", "Use autoscaling provider data to join a node to existing cluster.")
 	g.AutoJoinCmd.ClusterName = g.AutoJoinCmd.Arg("cluster-name", "Cluster name used for discovery.").Required().String()
 	g.AutoJoinCmd.Role = g.AutoJoinCmd.Flag("role", "Role of this node.").String()
 	g.AutoJoinCmd.DockerDevice = g.AutoJoinCmd.Flag("docker-device", "Docker device to use.").Hidden().String()
@@ -122,22 +127,30 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AutoJoinCmd.AdvertiseAddr = g.AutoJoinCmd.Flag("advertise-addr", "IP address this node will advertise to other cluster nodes.").Hidden().String()
 	g.AutoJoinCmd.Token = g.AutoJoinCmd.Flag("token", "Unique token to authorize this node to join the cluster.").Hidden().String()
 	g.AutoJoinCmd.FromService = g.AutoJoinCmd.Flag("from-service", "Run in service mode.").Hidden().Bool()
+	g.AutoJoinCmd.Provider = g.AutoJoinCmd.Flag("provider", "Autoscaling provider to discover the cluster with.").Default(autoscaleaws.ProviderName).Enum(autoscaleaws.ProviderName, webhook.ProviderName)
+	g.AutoJoinCmd.DiscoveryURL = g.AutoJoinCmd.Flag("discovery-url", "Base URL of the cluster discovery endpoint for the webhook provider.").String()
+	g.AutoJoinCmd.DiscoverySecret = g.AutoJoinCmd.Flag("discovery-secret", "Secret to authenticate with the cluster discovery endpoint for the webhook provider.").OverrideDefaultFromEnvar(constants.DiscoverySecretEnvVar).String()
 
 	g.LeaveCmd.CmdClause = g.Command("leave", "Decommission this node from the cluster.")
 	g.LeaveCmd.Force = g.LeaveCmd.Flag("force", "Force local state cleanup.").Bool()
 	g.LeaveCmd.Confirm = g.LeaveCmd.Flag("confirm", "Do not ask for confirmation.").Bool()
 
-	g.RemoveCmd.CmdClause = g.Command("remove", "Remove a node from the cluster.")
-	g.RemoveCmd.Node = g.RemoveCmd.Arg("node", "Node to remove: can be IP address, hostname or name from `kubectl get nodes` output).").
-		Required().String()
-	g.RemoveCmd.Force = g.RemoveCmd.Flag("force", "Force removal of an offline node.").Bool()
+	g.RemoveCmd.CmdClause = g.Command("remove", "Remove one or more nodes from the cluster.")
+	g.RemoveCmd.Nodes = g.RemoveCmd.Arg("node", "Nodes to remove: can be IP address, hostname or name from `kubectl get nodes` output). Multiple nodes are removed by a single operation.").
+		Required().Strings()
+	g.RemoveCmd.Force = g.RemoveCmd.Flag("force", "Force removal of offline nodes.").Bool()
 	g.RemoveCmd.Confirm = g.RemoveCmd.Flag("confirm", "Do not ask for confirmation.").Bool()
 
+	g.ExpandCmd.CmdClause = g.Command("expand", "Create an operation that adds several nodes to the cluster at once. The nodes join the operation with 'gravity join --operation-id'.")
+	g.ExpandCmd.Role = g.ExpandCmd.Flag("role", "Role of the nodes to add.").Required().String()
+	g.ExpandCmd.Count = g.ExpandCmd.Flag("count", "Number of nodes to add.").Default("1").Int()
+
 	g.ResumeCmd.CmdClause = g.Command("resume", "Resume the last aborted operation.")
 	g.ResumeCmd.OperationID = g.ResumeCmd.Flag("operation-id", "ID of the active operation. It not specified, the last operation will be used.").Hidden().String()
 	g.ResumeCmd.SkipVersionCheck = g.ResumeCmd.Flag("skip-version-check", "Bypass version compatibility check.").Hidden().Bool()
 	g.ResumeCmd.Force = g.ResumeCmd.Flag("force", "Force execution of specified phase.").Bool()
 	g.ResumeCmd.PhaseTimeout = g.ResumeCmd.Flag("timeout", "Phase execution timeout.").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.ResumeCmd.Parallel = g.ResumeCmd.Flag("parallel", "Maximum number of operation phases to execute concurrently. If unspecified, phases are executed one at a time in plan order.").Int()
 
 	g.StopCmd.CmdClause = g.Command("stop", "Stop Gravity services on the node.")
 	g.StopCmd.Confirmed = g.StopCmd.Flag("confirm", "Suppress confirmation prompt.").Bool()
@@ -154,10 +167,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.PlanDisplayCmd.CmdClause = g.PlanCmd.Command("display", "Display a plan for an ongoing operation.").Default()
 	g.PlanDisplayCmd.Output = common.Format(g.PlanDisplayCmd.Flag("output", fmt.Sprintf("Output format: %v.", constants.OutputFormats)).Short('o').Default(string(constants.EncodingText)))
 	g.PlanDisplayCmd.Short = g.PlanDisplayCmd.Flag("short", "Short output format.").Bool()
+	g.PlanDisplayCmd.Follow = g.PlanDisplayCmd.Flag("follow", "Display the plan every time it changes until the operation completes or fails.").Short('f').Bool()
+	g.PlanDisplayCmd.DryRun = g.PlanDisplayCmd.Flag("dry-run", "Display the changes the remaining phases of the operation would make without executing them.").Bool()
+	g.PlanDisplayCmd.Format = g.PlanDisplayCmd.Flag("format", fmt.Sprintf("Export the plan with dependencies, states and durations in the specified format: %v.", strings.Join(fsm.PlanExportFormats, ", "))).Enum(fsm.PlanExportFormats...)
 
 	g.PlanExecuteCmd.CmdClause = g.PlanCmd.Command("execute", "Execute the specified operation phase.")
 	g.PlanExecuteCmd.Phase = g.PlanExecuteCmd.Flag("phase", "Phase ID to execute.").String()
 	g.PlanExecuteCmd.Force = g.PlanExecuteCmd.Flag("force", "Force execution of the specified phase.").Bool()
+	g.PlanExecuteCmd.DryRun = g.PlanExecuteCmd.Flag("dry-run", "Display the changes the phase would make without executing it.").Bool()
 	g.PlanExecuteCmd.PhaseTimeout = g.PlanExecuteCmd.Flag("timeout", "Phase execution timeout.").Default(defaults.PhaseTimeout).Hidden().Duration()
 
 	g.PlanRollbackCmd.CmdClause = g.PlanCmd.Command("rollback", "Rollback the specified operation phase.")
@@ -172,6 +189,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.PlanResumeCmd.CmdClause = g.PlanCmd.Command("resume", "Resume the last aborted operation.")
 	g.PlanResumeCmd.Force = g.PlanResumeCmd.Flag("force", "Force execution of the specified phase.").Bool()
 	g.PlanResumeCmd.PhaseTimeout = g.PlanResumeCmd.Flag("timeout", "Phase execution timeout.").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.PlanResumeCmd.Parallel = g.PlanResumeCmd.Flag("parallel", "Maximum number of operation phases to execute concurrently. If unspecified, phases are executed one at a time in plan order.").Int()
 
 	g.PlanCompleteCmd.CmdClause = g.PlanCmd.Command("complete", "Mark the current operation as completed.")
 
@@ -195,7 +213,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpgradeCmd.Timeout = g.UpgradeCmd.Flag("timeout", "Phase execution timeout.").Default(defaults.PhaseTimeout).Hidden().Duration()
 	g.UpgradeCmd.Force = g.UpgradeCmd.Flag("force", "Force phase execution even if pre-conditions are not satisfied.").Bool()
 	g.UpgradeCmd.Resume = g.UpgradeCmd.Flag("resume", "Resume upgrade from the last failed step.").Bool()
+	g.UpgradeCmd.Parallel = g.UpgradeCmd.Flag("parallel", "Maximum number of operation phases to execute concurrently. If unspecified, phases are executed one at a time in plan order.").Int()
 	g.UpgradeCmd.SkipVersionCheck = g.UpgradeCmd.Flag("skip-version-check", "Bypass version compatibility check.").Hidden().Bool()
+	g.UpgradeCmd.Canary = g.UpgradeCmd.Flag("canary", "Update a single regular node and verify the cluster health before updating the rest of the regular nodes.").Bool()
+	g.UpgradeCmd.BatchSize = g.UpgradeCmd.Flag("batch-size", "Number of regular nodes to update concurrently. The cluster health is verified after each batch.").Int()
+	g.UpgradeCmd.RollbackOnFailure = g.UpgradeCmd.Flag("rollback-on-failure", "Automatically roll back the completed phases if a phase or a cluster health check fails.").Bool()
+	g.UpgradeCmd.IgnoreMaintenanceWindow = g.UpgradeCmd.Flag("ignore-maintenance-window", "Start the operation even if the cluster maintenance window is closed. The override is recorded in the audit log.").Bool()
+	g.UpgradeCmd.Queue = g.UpgradeCmd.Flag("queue", "Queue the operation to start once the cluster becomes idle instead of failing if another operation is in progress. Queued operations start in the order they have been queued in.").Bool()
+	g.UpgradeCmd.QueueTTL = g.UpgradeCmd.Flag("queue-ttl", "How long the operation waits in the queue before it expires.").Default(defaults.OperationQueueTTL.String()).Duration()
 	g.UpgradeCmd.Set = g.UpgradeCmd.Flag("set", "Set Helm chart values on the command line. Can be specified multiple times and/or as comma-separated values: key1=val1,key2=val2.").Strings()
 	g.UpgradeCmd.Values = g.UpgradeCmd.Flag("values", "Set Helm chart values from the provided YAML file. Can be specified multiple times.").Strings()
 
@@ -222,11 +247,16 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.StatusClusterCmd.Tail = g.StatusClusterCmd.Flag("tail", "Tail logs of the currently running operation until it completes.").Bool()
 	g.StatusClusterCmd.OperationID = g.StatusClusterCmd.Flag("operation-id", "Check status of the operation with the given ID.").Short('o').String()
 	g.StatusClusterCmd.Seconds = g.StatusClusterCmd.Flag("seconds", "Continuously display status every N seconds.").Short('s').Int()
+	g.StatusClusterCmd.Watch = g.StatusClusterCmd.Flag("watch", "Display status every time the cluster or its operations change.").Short('w').Bool()
 	g.StatusClusterCmd.Output = common.Format(g.StatusClusterCmd.Flag("output", "Output format: json or text.").Default(string(constants.EncodingText)))
 
 	// Display cluster status history
 	g.StatusHistoryCmd.CmdClause = g.StatusCmd.Command("history", "Display cluster status history.")
 
+	// Cancel a queued operation
+	g.StatusCancelCmd.CmdClause = g.StatusCmd.Command("cancel", "Remove an operation from the cluster operation queue.")
+	g.StatusCancelCmd.QueueID = g.StatusCancelCmd.Arg("queue-id", "ID of the queued operation, as displayed by 'gravity status'.").Required().String()
+
 	// reset cluster state, for debugging/emergencies
 	g.StatusResetCmd.CmdClause = g.Command("status-reset", "Reset the cluster state to 'active'").Hidden()
 
@@ -341,6 +371,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppImportCmd.SetImages = loc.ImagesSlice(g.AppImportCmd.Flag("set-image", "rewrite docker image versions in the app's resource files during vendoring, e.g. 'postgres:9.3.4' will rewrite all images with name 'postgres' to 'postgres:9.3.4'"))
 	g.AppImportCmd.SetDeps = loc.LocatorSlice(g.AppImportCmd.Flag("set-dep", "rewrite dependencies section in app's manifest file during vendoring, e.g. 'gravitational.io/site-app:0.0.39' will overwrite dependency to 'gravitational.io/site-app:0.0.39'"))
 	g.AppImportCmd.Parallel = g.AppImportCmd.Flag("parallel", "specifies number of concurrent tasks. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores.").Hidden().Int()
+	g.AppImportCmd.SignKey = g.AppImportCmd.Flag("sign-key", "Sign the application package with the provided PEM-encoded Ed25519, RSA or ECDSA private key.").String()
+	g.AppImportCmd.SignCert = g.AppImportCmd.Flag("sign-cert", "PEM-encoded x509 certificate chain of the signing key, required for RSA and ECDSA keys.").String()
 
 	// export gravity application
 	g.AppExportCmd.CmdClause = g.AppCmd.Command("export", "export gravity application").Hidden()
@@ -395,6 +427,12 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppUnpackCmd.OpsCenterURL = g.AppUnpackCmd.Flag("ops-url", "optional remote Gravity Hub URL").String()
 	g.AppUnpackCmd.ServiceUID = g.AppUnpackCmd.Flag("service-uid", "optional service user ID").String()
 
+	// display application software bill of materials
+	g.AppSBOMCmd.CmdClause = g.AppCmd.Command("sbom", "Display the software bill of materials of an application image.")
+	g.AppSBOMCmd.Image = g.AppSBOMCmd.Arg("image", "Application image in the form of <name>:<version>.").Required().String()
+	g.AppSBOMCmd.OpsCenterURL = g.AppSBOMCmd.Flag("ops-url", "Optional remote Gravity Hub URL.").String()
+	g.AppSBOMCmd.Format = common.Format(g.AppSBOMCmd.Flag("format", "Output format: text or json.").Default(string(constants.EncodingText)))
+
 	g.WizardCmd.CmdClause = g.Command("wizard", "start wizard that will guide you through install process").Hidden()
 	g.WizardCmd.Path = g.WizardCmd.Arg("appdir", "Path to directory with application package. Uses current directory by default").String()
 	g.WizardCmd.ServiceUID = g.WizardCmd.Flag("service-uid", fmt.Sprintf("Service user ID for planet. %q user will created and used if none specified", defaults.ServiceUser)).Default(defaults.ServiceUserID).OverrideDefaultFromEnvar(constants.ServiceUserEnvVar).String()
@@ -706,10 +744,30 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.SystemStreamRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("stream-runtime-journal", "Stream runtime journal to stdout").Hidden()
 
+	g.SystemImportBoltCmd.CmdClause = g.SystemCmd.Command("import-bolt", "Import a bolt database into a SQL storage backend").Hidden()
+	g.SystemImportBoltCmd.Path = g.SystemImportBoltCmd.Arg("path", "Path to the bolt database").Required().String()
+	g.SystemImportBoltCmd.Driver = g.SystemImportBoltCmd.Flag("driver", fmt.Sprintf("SQL driver, one of %v or %v", keyval.SQLDriverSQLite, keyval.SQLDriverPostgres)).Default(keyval.SQLDriverSQLite).String()
+	g.SystemImportBoltCmd.DataSource = g.SystemImportBoltCmd.Flag("data-source", "SQL data source: database file path for sqlite3 or connection string for postgres").Required().String()
+
+	g.SystemMigrateBackendCmd.CmdClause = g.SystemCmd.Command("migrate-backend", "Migrate Ops Center data between storage backends. The Ops Center must be stopped")
+	g.SystemMigrateBackendCmd.From = g.SystemMigrateBackendCmd.Flag("from", "Source backend type").Required().Enum(constants.BoltBackend, constants.ETCDBackend)
+	g.SystemMigrateBackendCmd.To = g.SystemMigrateBackendCmd.Flag("to", "Target backend type").Required().Enum(constants.BoltBackend, constants.ETCDBackend)
+	g.SystemMigrateBackendCmd.BoltPath = g.SystemMigrateBackendCmd.Flag("bolt-path", "Path to the bolt database").String()
+	g.SystemMigrateBackendCmd.EtcdNodes = g.SystemMigrateBackendCmd.Flag("etcd-nodes", "Etcd endpoint, can be specified multiple times").Default(defaults.EtcdLocalAddr).Strings()
+	g.SystemMigrateBackendCmd.EtcdPrefix = g.SystemMigrateBackendCmd.Flag("etcd-prefix", "Key under which the data is stored in etcd").Default(defaults.EtcdKey).String()
+	g.SystemMigrateBackendCmd.EtcdCertFile = g.SystemMigrateBackendCmd.Flag("etcd-cert-file", "Path to the etcd client certificate").String()
+	g.SystemMigrateBackendCmd.EtcdKeyFile = g.SystemMigrateBackendCmd.Flag("etcd-key-file", "Path to the etcd client private key").String()
+	g.SystemMigrateBackendCmd.EtcdCAFile = g.SystemMigrateBackendCmd.Flag("etcd-ca-file", "Path to the etcd certificate authority").String()
+	g.SystemMigrateBackendCmd.DryRun = g.SystemMigrateBackendCmd.Flag("dry-run", "Display the number of items to copy without copying them").Bool()
+	g.SystemMigrateBackendCmd.Resume = g.SystemMigrateBackendCmd.Flag("resume", "Continue an interrupted migration into a non-empty target backend").Bool()
+
 	// pruning cluster resources
 	g.GarbageCollectCmd.CmdClause = g.Command("gc", "Prune cluster resources")
 	g.GarbageCollectCmd.Manual = g.GarbageCollectCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
 	g.GarbageCollectCmd.Confirmed = g.GarbageCollectCmd.Flag("confirm", "Confirm to remove unrelated docker images").Short('c').Bool()
+	g.GarbageCollectCmd.IgnoreMaintenanceWindow = g.GarbageCollectCmd.Flag("ignore-maintenance-window", "Start the operation even if the cluster maintenance window is closed. The override is recorded in the audit log.").Bool()
+	g.GarbageCollectCmd.Queue = g.GarbageCollectCmd.Flag("queue", "Queue the operation to start once the cluster becomes idle instead of failing if another operation is in progress. Queued operations start in the order they have been queued in.").Bool()
+	g.GarbageCollectCmd.QueueTTL = g.GarbageCollectCmd.Flag("queue-ttl", "How long the operation waits in the queue before it expires.").Default(defaults.OperationQueueTTL.String()).Duration()
 
 	// system clean up tasks
 	systemGCCmd := g.SystemCmd.Command("gc", "Run system clean up tasks")
@@ -760,6 +818,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ResourceCreateCmd.User = g.ResourceCreateCmd.Flag("user", "User to create the resource for. Defaults to the currently logged in user.").String()
 	g.ResourceCreateCmd.Manual = g.ResourceCreateCmd.Flag("manual", "Manually execute operation phases for resource which trigger an operation.").Short('m').Bool()
 	g.ResourceCreateCmd.Confirmed = g.ResourceCreateCmd.Flag("confirm", "Do not ask for confirmation.").Bool()
+	g.ResourceCreateCmd.IgnoreMaintenanceWindow = g.ResourceCreateCmd.Flag("ignore-maintenance-window", "Start the operation for resources which trigger an operation even if the cluster maintenance window is closed. The override is recorded in the audit log.").Bool()
+	g.ResourceCreateCmd.Queue = g.ResourceCreateCmd.Flag("queue", "Queue the operation for resources which trigger an operation to start once the cluster becomes idle instead of failing if another operation is in progress.").Bool()
+	g.ResourceCreateCmd.QueueTTL = g.ResourceCreateCmd.Flag("queue-ttl", "How long the operation waits in the queue before it expires.").Default(defaults.OperationQueueTTL.String()).Duration()
 
 	// remove one or many resources
 	g.ResourceRemoveCmd.CmdClause = g.ResourceCmd.Command("rm", fmt.Sprintf("Remove a configuration resource, e.g. gravity resource rm oidc google. Supported resources are: %v.", modules.GetResources().SupportedResourcesToRemove()))
@@ -770,6 +831,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ResourceRemoveCmd.User = g.ResourceRemoveCmd.Flag("user", "User to remove the resource for. Defaults to the currently logged in user.").String()
 	g.ResourceRemoveCmd.Manual = g.ResourceRemoveCmd.Flag("manual", "Manually execute operation phases for resources which trigger an operation.").Short('m').Bool()
 	g.ResourceRemoveCmd.Confirmed = g.ResourceRemoveCmd.Flag("confirm", "Do not ask for confirmation.").Bool()
+	g.ResourceRemoveCmd.IgnoreMaintenanceWindow = g.ResourceRemoveCmd.Flag("ignore-maintenance-window", "Start the operation for resources which trigger an operation even if the cluster maintenance window is closed. The override is recorded in the audit log.").Bool()
+	g.ResourceRemoveCmd.Queue = g.ResourceRemoveCmd.Flag("queue", "Queue the operation for resources which trigger an operation to start once the cluster becomes idle instead of failing if another operation is in progress.").Bool()
+	g.ResourceRemoveCmd.QueueTTL = g.ResourceRemoveCmd.Flag("queue-ttl", "How long the operation waits in the queue before it expires.").Default(defaults.OperationQueueTTL.String()).Duration()
 
 	// get resources returns resources
 	g.ResourceGetCmd.CmdClause = g.ResourceCmd.Command("get", fmt.Sprintf("Get configuration resources, e.g. gravity get oidc. Supported resources are: %v.",
@@ -784,6 +848,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.TopCmd.CmdClause = g.Command("top", "Display cluster monitoring information.")
 	g.TopCmd.Interval = g.TopCmd.Flag("interval", "Interval to display data for, in Go duration format.").Default(defaults.MetricsInterval.String()).Duration()
 	g.TopCmd.Step = g.TopCmd.Flag("step", "Max time b/w two datapoints, in Go duration format.").Default(defaults.MetricsStep.String()).Duration()
+	g.TopCmd.Format = common.Format(g.TopCmd.Flag("format", "Export metrics history with per-node and per-namespace breakdown in the specified format instead of displaying them interactively: json or csv."))
 
 	return g
 }
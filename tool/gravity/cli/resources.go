@@ -37,7 +37,7 @@ import (
 // manual controls whether the operation is created in manual mode if resource creation is implemented
 // as a cluster operation.
 // confirmed specifies if the user has explicitly approved the operation
-func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFactory, filename string, upsert bool, user string, manual, confirmed bool) error {
+func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFactory, filename string, upsert bool, user string, manual, confirmed, ignoreMaintenanceWindow bool, queue queueConfig) error {
 	operator, err := env.SiteOperator()
 	if err != nil {
 		return trace.Wrap(err)
@@ -64,11 +64,14 @@ func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFact
 	control := resources.NewControl(gravityResources)
 	err = resources.ForEach(reader, func(resource storage.UnknownResource) error {
 		req := resources.CreateRequest{
-			SiteKey:   cluster.Key(),
-			Upsert:    upsert,
-			Owner:     user,
-			Manual:    manual,
-			Confirmed: confirmed,
+			SiteKey:                 cluster.Key(),
+			Upsert:                  upsert,
+			Owner:                   user,
+			Manual:                  manual,
+			Confirmed:               confirmed,
+			IgnoreMaintenanceWindow: ignoreMaintenanceWindow,
+			Queue:                   queue.enabled,
+			QueueTTL:                queue.ttl,
 		}
 		return trace.Wrap(control.Create(context.TODO(), bytes.NewReader(resource.Raw), req))
 	})
@@ -82,7 +85,8 @@ func removeResource(
 	kind, name string,
 	force bool,
 	user string,
-	manual, confirmed bool,
+	manual, confirmed, ignoreMaintenanceWindow bool,
+	queue queueConfig,
 ) error {
 	operator, err := env.SiteOperator()
 	if err != nil {
@@ -103,13 +107,16 @@ func removeResource(
 		return trace.Wrap(err)
 	}
 	req := resources.RemoveRequest{
-		SiteKey:   cluster.Key(),
-		Kind:      kind,
-		Name:      name,
-		Force:     force,
-		Owner:     user,
-		Manual:    manual,
-		Confirmed: confirmed,
+		SiteKey:                 cluster.Key(),
+		Kind:                    kind,
+		Name:                    name,
+		Force:                   force,
+		Owner:                   user,
+		Manual:                  manual,
+		Confirmed:               confirmed,
+		IgnoreMaintenanceWindow: ignoreMaintenanceWindow,
+		Queue:                   queue.enabled,
+		QueueTTL:                queue.ttl,
 	}
 	err = resources.NewControl(gravityResources).Remove(context.TODO(), req)
 	return trace.Wrap(err)
@@ -170,12 +177,15 @@ func (r clusterOperationHandler) RemoveResource(req resources.RemoveRequest) err
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
+	queue := queueConfig{enabled: req.Queue, ttl: req.QueueTTL, printer: localEnv}
 	switch req.Kind {
 	case storage.KindRuntimeEnvironment:
 		env := storage.NewEnvironment(nil)
-		return trace.Wrap(updateEnviron(context.TODO(), localEnv, updateEnv, env, req.Manual, req.Confirmed))
+		return trace.Wrap(updateEnviron(context.TODO(), localEnv, updateEnv, env,
+			req.Manual, req.Confirmed, req.IgnoreMaintenanceWindow, queue))
 	case storage.KindClusterConfiguration:
-		return trace.Wrap(resetConfig(context.TODO(), localEnv, updateEnv, req.Manual, req.Confirmed))
+		return trace.Wrap(resetConfig(context.TODO(), localEnv, updateEnv,
+			req.Manual, req.Confirmed, req.IgnoreMaintenanceWindow, queue))
 	}
 	// unreachable
 	return trace.BadParameter("unknown resource kind %q", req.Kind)
@@ -197,6 +207,7 @@ func (r clusterOperationHandler) UpdateResource(req resources.CreateRequest) err
 		return trace.Wrap(err)
 	}
 	defer updateEnv.Close()
+	queue := queueConfig{enabled: req.Queue, ttl: req.QueueTTL, printer: localEnv}
 	switch req.Resource.Kind {
 	case storage.KindRuntimeEnvironment:
 		env, err := storage.UnmarshalEnvironmentVariables(req.Resource.Raw)
@@ -204,14 +215,14 @@ func (r clusterOperationHandler) UpdateResource(req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		return trace.Wrap(updateEnviron(context.TODO(), localEnv, updateEnv,
-			env, req.Manual, req.Confirmed))
+			env, req.Manual, req.Confirmed, req.IgnoreMaintenanceWindow, queue))
 	case storage.KindClusterConfiguration:
 		config, err := clusterconfig.Unmarshal(req.Resource.Raw)
 		if err != nil {
 			return trace.Wrap(err)
 		}
 		return trace.Wrap(updateConfig(context.TODO(), localEnv, updateEnv,
-			config, req.Manual, req.Confirmed))
+			config, req.Manual, req.Confirmed, req.IgnoreMaintenanceWindow, queue))
 	}
 	// unreachable
 	return trace.BadParameter("unknown resource kind %q", req.Resource.Kind)
@@ -36,6 +36,7 @@ import (
 	"github.com/gravitational/gravity/lib/process"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -122,6 +123,7 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.RPCAgentRunCmd.FullCommand(),
 		g.LeaveCmd.FullCommand(),
 		g.RemoveCmd.FullCommand(),
+		g.ExpandCmd.FullCommand(),
 		g.ResumeCmd.FullCommand(),
 		g.PlanResumeCmd.FullCommand(),
 		g.PlanExecuteCmd.FullCommand(),
@@ -154,7 +156,8 @@ func InitAndCheck(g *Application, cmd string) error {
 	switch cmd {
 	case g.UpdateCompleteCmd.FullCommand(),
 		g.UpdateTriggerCmd.FullCommand(),
-		g.RemoveCmd.FullCommand():
+		g.RemoveCmd.FullCommand(),
+		g.ExpandCmd.FullCommand():
 		if err := checkRunningInGravity(g); err != nil {
 			return trace.Wrap(err)
 		}
@@ -189,6 +192,7 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.AutoJoinCmd.FullCommand(),
 		g.LeaveCmd.FullCommand(),
 		g.RemoveCmd.FullCommand(),
+		g.ExpandCmd.FullCommand(),
 		g.SystemDevicemapperMountCmd.FullCommand(),
 		g.SystemDevicemapperUnmountCmd.FullCommand(),
 		g.BackupCmd.FullCommand(),
@@ -325,17 +329,20 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		return join(localEnv, g, NewJoinConfig(g))
 	case g.AutoJoinCmd.FullCommand():
 		return autojoin(localEnv, g, autojoinConfig{
-			systemLogFile: *g.SystemLogFile,
-			userLogFile:   *g.UserLogFile,
-			clusterName:   *g.AutoJoinCmd.ClusterName,
-			role:          *g.AutoJoinCmd.Role,
-			systemDevice:  *g.AutoJoinCmd.SystemDevice,
-			dockerDevice:  *g.AutoJoinCmd.DockerDevice,
-			mounts:        *g.AutoJoinCmd.Mounts,
-			fromService:   *g.AutoJoinCmd.FromService,
-			serviceURL:    *g.AutoJoinCmd.ServiceAddr,
-			token:         *g.AutoJoinCmd.Token,
-			advertiseAddr: *g.AutoJoinCmd.AdvertiseAddr,
+			systemLogFile:   *g.SystemLogFile,
+			userLogFile:     *g.UserLogFile,
+			clusterName:     *g.AutoJoinCmd.ClusterName,
+			role:            *g.AutoJoinCmd.Role,
+			systemDevice:    *g.AutoJoinCmd.SystemDevice,
+			dockerDevice:    *g.AutoJoinCmd.DockerDevice,
+			mounts:          *g.AutoJoinCmd.Mounts,
+			fromService:     *g.AutoJoinCmd.FromService,
+			serviceURL:      *g.AutoJoinCmd.ServiceAddr,
+			token:           *g.AutoJoinCmd.Token,
+			advertiseAddr:   *g.AutoJoinCmd.AdvertiseAddr,
+			provider:        *g.AutoJoinCmd.Provider,
+			discoveryURL:    *g.AutoJoinCmd.DiscoveryURL,
+			discoverySecret: *g.AutoJoinCmd.DiscoverySecret,
 		})
 	case g.UpdateCheckCmd.FullCommand():
 		return updateCheck(localEnv, *g.UpdateCheckCmd.App)
@@ -373,6 +380,7 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 					Force:            *g.UpgradeCmd.Force,
 					Timeout:          *g.UpgradeCmd.Timeout,
 					SkipVersionCheck: *g.UpgradeCmd.SkipVersionCheck,
+					Parallel:         *g.UpgradeCmd.Parallel,
 				})
 		}
 		config, err := newUpgradeConfig(g)
@@ -387,8 +395,13 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 				Timeout:          *g.ResumeCmd.PhaseTimeout,
 				SkipVersionCheck: *g.ResumeCmd.SkipVersionCheck,
 				OperationID:      *g.ResumeCmd.OperationID,
+				Parallel:         *g.ResumeCmd.Parallel,
 			})
 	case g.PlanExecuteCmd.FullCommand():
+		if *g.PlanExecuteCmd.DryRun {
+			return dryRunOperationPhase(localEnv, g,
+				*g.PlanCmd.OperationID, *g.PlanExecuteCmd.Phase, constants.EncodingText)
+		}
 		return executePhase(localEnv, g,
 			PhaseParams{
 				PhaseID:          *g.PlanExecuteCmd.Phase,
@@ -410,6 +423,7 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 				Timeout:          *g.PlanResumeCmd.PhaseTimeout,
 				SkipVersionCheck: *g.PlanCmd.SkipVersionCheck,
 				OperationID:      *g.PlanCmd.OperationID,
+				Parallel:         *g.PlanResumeCmd.Parallel,
 			})
 	case g.PlanRollbackCmd.FullCommand():
 		return rollbackPhase(localEnv, g,
@@ -425,6 +439,18 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		if *g.PlanDisplayCmd.Short {
 			outputFormat = constants.EncodingShort
 		}
+		if *g.PlanDisplayCmd.Format != "" {
+			return exportOperationPlan(localEnv, g,
+				*g.PlanCmd.OperationID, *g.PlanDisplayCmd.Format)
+		}
+		if *g.PlanDisplayCmd.DryRun {
+			return dryRunOperationPhase(localEnv, g,
+				*g.PlanCmd.OperationID, fsm.RootPhase, outputFormat)
+		}
+		if *g.PlanDisplayCmd.Follow {
+			return followOperationPlan(localEnv, g,
+				*g.PlanCmd.OperationID, outputFormat)
+		}
 		return displayOperationPlan(localEnv, g,
 			*g.PlanCmd.OperationID, outputFormat)
 	case g.PlanCompleteCmd.FullCommand():
@@ -436,10 +462,12 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		})
 	case g.RemoveCmd.FullCommand():
 		return remove(localEnv, removeConfig{
-			server:    *g.RemoveCmd.Node,
+			servers:   *g.RemoveCmd.Nodes,
 			force:     *g.RemoveCmd.Force,
 			confirmed: *g.RemoveCmd.Confirm,
 		})
+	case g.ExpandCmd.FullCommand():
+		return expandCluster(localEnv, *g.ExpandCmd.Role, *g.ExpandCmd.Count)
 	case g.StatusClusterCmd.FullCommand():
 		printOptions := printOptions{
 			token:       *g.StatusClusterCmd.Token,
@@ -450,6 +478,9 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		if *g.StatusClusterCmd.Tail {
 			return tailStatus(localEnv, *g.StatusClusterCmd.OperationID)
 		}
+		if *g.StatusClusterCmd.Watch {
+			return statusWatch(localEnv, printOptions)
+		}
 		if *g.StatusClusterCmd.Seconds != 0 {
 			return statusPeriodic(localEnv, printOptions, *g.StatusClusterCmd.Seconds)
 		} else {
@@ -457,6 +488,8 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		}
 	case g.StatusHistoryCmd.FullCommand():
 		return statusHistory()
+	case g.StatusCancelCmd.FullCommand():
+		return cancelQueuedOperation(localEnv, *g.StatusCancelCmd.QueueID)
 	case g.UpdateUploadCmd.FullCommand():
 		return uploadUpdate(localEnv, *g.UpdateUploadCmd.OpsCenterURL)
 	case g.AppPackageCmd.FullCommand():
@@ -568,6 +601,10 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		if *g.AppImportCmd.Vendor && *g.AppImportCmd.RegistryURL == "" {
 			return trace.BadParameter("vendoring mode requires --registry-url")
 		}
+		signer, err := newPackageSigner(*g.AppImportCmd.SignKey, *g.AppImportCmd.SignCert)
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		req := &appapi.ImportRequest{
 			Repository:             *g.AppImportCmd.Repository,
 			PackageName:            *g.AppImportCmd.Name,
@@ -588,7 +625,8 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 			req,
 			*g.AppImportCmd.OpsCenterURL,
 			*g.Silent,
-			*g.AppImportCmd.Parallel)
+			*g.AppImportCmd.Parallel,
+			signer)
 	case g.AppExportCmd.FullCommand():
 		return exportApp(localEnv,
 			*g.AppExportCmd.Locator,
@@ -635,6 +673,11 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 			*g.AppUnpackCmd.Dir,
 			*g.AppUnpackCmd.OpsCenterURL,
 			*g.AppUnpackCmd.ServiceUID)
+	case g.AppSBOMCmd.FullCommand():
+		return appSBOM(localEnv,
+			*g.AppSBOMCmd.Image,
+			*g.AppSBOMCmd.OpsCenterURL,
+			*g.AppSBOMCmd.Format)
 	// package commands
 	case g.PackImportCmd.FullCommand():
 		return importPackage(localEnv,
@@ -855,8 +898,34 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 		return exportRuntimeJournal(localEnv, *g.SystemExportRuntimeJournalCmd.OutputFile)
 	case g.SystemStreamRuntimeJournalCmd.FullCommand():
 		return streamRuntimeJournal(localEnv)
+	case g.SystemImportBoltCmd.FullCommand():
+		return importBolt(localEnv,
+			*g.SystemImportBoltCmd.Path,
+			*g.SystemImportBoltCmd.Driver,
+			*g.SystemImportBoltCmd.DataSource)
+	case g.SystemMigrateBackendCmd.FullCommand():
+		return migrateBackend(localEnv, migrateBackendConfig{
+			from:     *g.SystemMigrateBackendCmd.From,
+			to:       *g.SystemMigrateBackendCmd.To,
+			boltPath: *g.SystemMigrateBackendCmd.BoltPath,
+			etcd: keyval.ETCDConfig{
+				Nodes:       *g.SystemMigrateBackendCmd.EtcdNodes,
+				Key:         *g.SystemMigrateBackendCmd.EtcdPrefix,
+				TLSCertFile: *g.SystemMigrateBackendCmd.EtcdCertFile,
+				TLSKeyFile:  *g.SystemMigrateBackendCmd.EtcdKeyFile,
+				TLSCAFile:   *g.SystemMigrateBackendCmd.EtcdCAFile,
+			},
+			dryRun: *g.SystemMigrateBackendCmd.DryRun,
+			resume: *g.SystemMigrateBackendCmd.Resume,
+		})
 	case g.GarbageCollectCmd.FullCommand():
-		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed)
+		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed,
+			*g.GarbageCollectCmd.IgnoreMaintenanceWindow,
+			queueConfig{
+				enabled: *g.GarbageCollectCmd.Queue,
+				ttl:     *g.GarbageCollectCmd.QueueTTL,
+				printer: localEnv,
+			})
 	case g.SystemGCJournalCmd.FullCommand():
 		return removeUnusedJournalFiles(localEnv,
 			*g.SystemGCJournalCmd.MachineIDFile,
@@ -902,7 +971,12 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 			*g.ResourceCreateCmd.Upsert,
 			*g.ResourceCreateCmd.User,
 			*g.ResourceCreateCmd.Manual,
-			*g.ResourceCreateCmd.Confirmed)
+			*g.ResourceCreateCmd.Confirmed,
+			*g.ResourceCreateCmd.IgnoreMaintenanceWindow,
+			queueConfig{
+				enabled: *g.ResourceCreateCmd.Queue,
+				ttl:     *g.ResourceCreateCmd.QueueTTL,
+			})
 	case g.ResourceRemoveCmd.FullCommand():
 		return removeResource(localEnv, g,
 			*g.ResourceRemoveCmd.Kind,
@@ -910,7 +984,12 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 			*g.ResourceRemoveCmd.Force,
 			*g.ResourceRemoveCmd.User,
 			*g.ResourceRemoveCmd.Manual,
-			*g.ResourceRemoveCmd.Confirmed)
+			*g.ResourceRemoveCmd.Confirmed,
+			*g.ResourceRemoveCmd.IgnoreMaintenanceWindow,
+			queueConfig{
+				enabled: *g.ResourceRemoveCmd.Queue,
+				ttl:     *g.ResourceRemoveCmd.QueueTTL,
+			})
 	case g.ResourceGetCmd.FullCommand():
 		return getResources(localEnv,
 			*g.ResourceGetCmd.Kind,
@@ -945,7 +1024,8 @@ func Execute(g *Application, cmd string, extraArgs []string) (err error) {
 	case g.TopCmd.FullCommand():
 		return top(localEnv,
 			*g.TopCmd.Interval,
-			*g.TopCmd.Step)
+			*g.TopCmd.Step,
+			*g.TopCmd.Format)
 	}
 	return trace.NotFound("unknown command %v", cmd)
 }
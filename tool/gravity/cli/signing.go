@@ -0,0 +1,41 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/signing"
+
+	"github.com/gravitational/trace"
+)
+
+// newPackageSigner returns a package signer for the specified private key
+// and optional certificate chain.
+// Returns nil if no key has been specified
+func newPackageSigner(keyPath, certPath string) (pack.Signer, error) {
+	if keyPath == "" {
+		if certPath != "" {
+			return nil, trace.BadParameter("--sign-cert requires --sign-key")
+		}
+		return nil, nil
+	}
+	signer, err := signing.NewSignerFromFiles(keyPath, certPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signer, nil
+}
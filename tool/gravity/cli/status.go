@@ -33,6 +33,8 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/signals"
 	"github.com/prometheus/alertmanager/api/v2/models"
 
 	"github.com/dustin/go-humanize"
@@ -156,6 +158,38 @@ func statusPeriodic(env *localenv.LocalEnvironment, printOptions printOptions, s
 	return nil
 }
 
+// statusWatch displays cluster status every time the cluster or its operations change
+func statusWatch(env *localenv.LocalEnvironment, printOptions printOptions) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := signals.WatchTerminationSignals(ctx, cancel, env)
+	defer interrupt.Close()
+	watch := operatorWatch(operator, ops.WatchClusterRequest{
+		SiteKey:     cluster.Key(),
+		OperationID: printOptions.operationID,
+	})
+	err = followChanges(ctx, watch, func() (bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, defaults.StatusCollectionTimeout)
+		defer cancel()
+		status, err := statusOnce(ctx, operator, printOptions.operationID, env)
+		if err != nil {
+			log.WithError(err).Warn("Failed to query cluster status.")
+			return false, nil
+		}
+		//nolint:errcheck
+		printStatus(operator, clusterStatus{*status, nil}, printOptions)
+		return false, nil
+	})
+	return trace.Wrap(err)
+}
+
 // statusOnce collects cluster status information
 func statusOnce(ctx context.Context, operator ops.Operator, operationID string, env *localenv.LocalEnvironment) (*statusapi.Status, error) {
 	cluster, err := operator.GetLocalSite()
@@ -314,6 +348,12 @@ func printClusterStatus(cluster statusapi.Cluster, w io.Writer) {
 			printOperation(op, w)
 		}
 	}
+	if len(cluster.QueuedOperations) != 0 {
+		fmt.Fprintf(w, "Queued operations:\n")
+		for _, op := range cluster.QueuedOperations {
+			printQueuedOperation(op, w)
+		}
+	}
 	if cluster.Operation != nil {
 		fmt.Fprintf(w, "Last completed operation:\n")
 		printOperation(cluster.Operation, w)
@@ -345,6 +385,25 @@ func printOperation(operation *statusapi.ClusterOperation, w io.Writer) {
 	}
 }
 
+func printQueuedOperation(operation storage.QueuedOperation, w io.Writer) {
+	fmt.Fprintf(w, "    * %v (%v)\n", operation.Type, operation.ID)
+	if operation.Description != "" {
+		fmt.Fprintf(w, "      %v\n", operation.Description)
+	}
+	fmt.Fprintf(w, "      queued:\t%v (%v)\n",
+		operation.Created.Format(constants.HumanDateFormat),
+		humanize.RelTime(operation.Created, time.Now(), "ago", ""))
+	fmt.Fprintf(w, "      expires:\t%v (%v)\n",
+		operation.Expires.Format(constants.HumanDateFormat),
+		humanize.RelTime(operation.Expires, time.Now(), "ago", "from now"))
+	if operation.Error != "" {
+		fmt.Fprintf(w, "      failed to start:\t%v\n", operation.Error)
+	} else if operation.Reason != "" {
+		fmt.Fprintf(w, "      waiting:\t%v\n", operation.Reason)
+	}
+	fmt.Fprintf(w, "      use 'gravity status cancel %v' to cancel the operation\n", operation.ID)
+}
+
 func printAgentStatus(status statusapi.Agent, w io.Writer) {
 	if len(status.Nodes) == 0 {
 		fmt.Fprintln(w, color.YellowString("Failed to collect system status from nodes"))
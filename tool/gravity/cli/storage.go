@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+)
+
+// importBolt copies the contents of the bolt database at the specified path
+// into the SQL backend and rebuilds the operation indexes
+func importBolt(env *localenv.LocalEnvironment, path, driver, dataSource string) error {
+	backend, err := keyval.NewSQL(keyval.SQLConfig{
+		Driver:     driver,
+		DataSource: dataSource,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer backend.Close()
+	env.PrintStep("Importing %v into %v database", path, driver)
+	if err := backend.ImportBolt(path); err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Import completed")
+	return nil
+}
+
+type migrateBackendConfig struct {
+	// from is the type of the source backend
+	from string
+	// to is the type of the target backend
+	to string
+	// boltPath is the path to the bolt database
+	boltPath string
+	// etcd is the etcd backend configuration
+	etcd keyval.ETCDConfig
+	// dryRun displays the changes without applying them
+	dryRun bool
+	// resume continues an interrupted migration
+	resume bool
+}
+
+// migrateBackend copies all data from the source storage backend
+// into the target backend and verifies the result
+func migrateBackend(env *localenv.LocalEnvironment, config migrateBackendConfig) error {
+	if config.from == config.to {
+		return trace.BadParameter("source and target backends should be different")
+	}
+	source, err := newMigrationBackend(config, config.from)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer source.Close()
+	target, err := newMigrationBackend(config, config.to)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer target.Close()
+	if config.dryRun {
+		env.PrintStep("Computing changes to migrate from %v to %v", config.from, config.to)
+	} else {
+		env.PrintStep("Migrating from %v to %v", config.from, config.to)
+	}
+	report, err := keyval.Migrate(keyval.MigrateConfig{
+		Source:   source,
+		Target:   target,
+		DryRun:   config.dryRun,
+		Resume:   config.resume,
+		Progress: env,
+	})
+	if err != nil {
+		if report != nil {
+			for _, diff := range report.Differences {
+				env.Printf("%v\n", diff)
+			}
+		}
+		return trace.Wrap(err)
+	}
+	for _, diff := range report.Differences {
+		env.PrintStep("WARNING: %v is not present in %v", diff.Key, config.from)
+	}
+	if config.dryRun {
+		env.PrintStep("Dry run completed, no changes were made")
+		return nil
+	}
+	env.PrintStep("Migration completed and verified")
+	return nil
+}
+
+func newMigrationBackend(config migrateBackendConfig, backendType string) (storage.Backend, error) {
+	switch backendType {
+	case constants.BoltBackend:
+		if config.boltPath == "" {
+			return nil, trace.BadParameter("specify the bolt database path with --bolt-path")
+		}
+		return keyval.NewBolt(keyval.BoltConfig{Path: config.boltPath})
+	case constants.ETCDBackend:
+		return keyval.NewETCD(config.etcd)
+	}
+	return nil, trace.BadParameter("unsupported backend type %q", backendType)
+}
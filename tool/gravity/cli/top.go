@@ -18,7 +18,14 @@ package cli
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -35,7 +42,7 @@ import (
 	"github.com/gravitational/trace"
 )
 
-func top(env *localenv.LocalEnvironment, interval, step time.Duration) error {
+func top(env *localenv.LocalEnvironment, interval, step time.Duration, format constants.Format) error {
 	prometheusAddr, err := utils.ResolveAddr(env.DNS.Addr(), defaults.PrometheusServiceAddr)
 	if err != nil {
 		return trace.Wrap(err)
@@ -46,82 +53,205 @@ func top(env *localenv.LocalEnvironment, interval, step time.Duration) error {
 		return trace.Wrap(err)
 	}
 
+	if format != "" {
+		return exportMetrics(env, context.TODO(), prometheusClient, interval, step, format)
+	}
+
 	err = termui.Init()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer termui.Close()
 
-	go render(env, context.TODO(), prometheusClient, interval, step)
+	view := newTopView(interval, step)
+	go render(env, context.TODO(), prometheusClient, view)
 
 	termui.Handle("/sys/kbd/q", func(termui.Event) {
 		termui.StopLoop()
 	})
+	termui.Handle("/sys/kbd/b", func(termui.Event) {
+		view.toggleBreakdown()
+	})
+	for i, interval := range topIntervals {
+		interval := interval
+		termui.Handle(fmt.Sprintf("/sys/kbd/%v", i+1), func(termui.Event) {
+			view.setInterval(interval)
+		})
+	}
 	termui.Loop()
 
 	return nil
 }
 
+// exportMetrics retrieves cluster metrics including per-node and per-namespace
+// breakdown and writes them to stdout in the specified format.
+func exportMetrics(env *localenv.LocalEnvironment, ctx context.Context, client monitoring.Metrics, interval, step time.Duration, format constants.Format) error {
+	cluster, err := env.LocalCluster()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	metrics, err := opsservice.GetClusterMetrics(ctx, client, ops.ClusterMetricsRequest{
+		SiteKey:   cluster.Key(),
+		Interval:  interval,
+		Step:      step,
+		Breakdown: true,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(metrics, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+		return nil
+	case constants.EncodingCSV:
+		return trace.Wrap(writeMetricsCSV(os.Stdout, *metrics))
+	}
+	return trace.BadParameter("unsupported format %q, supported are: %v",
+		format, []constants.Format{constants.EncodingJSON, constants.EncodingCSV})
+}
+
+// writeMetricsCSV writes all time series from the provided metrics
+// to w as CSV records of metric name, node or namespace, time and value.
+func writeMetricsCSV(w io.Writer, metrics ops.ClusterMetricsResponse) error {
+	out := csv.NewWriter(w)
+	records := [][]string{{"metric", "label", "time", "value"}}
+	add := func(metric, label string, series monitoring.Series) {
+		for _, point := range series {
+			records = append(records, []string{metric, label,
+				point.Time.UTC().Format(time.RFC3339), strconv.Itoa(point.Value)})
+		}
+	}
+	add("cpu_rate", "cluster", metrics.CPURates.Historic)
+	add("memory_rate", "cluster", metrics.MemoryRates.Historic)
+	if breakdown := metrics.Breakdown; breakdown != nil {
+		for _, m := range []struct {
+			metric string
+			series []monitoring.LabeledSeries
+		}{
+			{"node_cpu_rate", breakdown.NodeCPURates},
+			{"node_memory_rate", breakdown.NodeMemoryRates},
+			{"namespace_cpu_millicores", breakdown.NamespaceCPUUsage},
+			{"namespace_memory_bytes", breakdown.NamespaceMemoryUsage},
+			{"disk_read_bytes_per_second", breakdown.DiskReadRates},
+			{"disk_write_bytes_per_second", breakdown.DiskWriteRates},
+			{"network_receive_bytes_per_second", breakdown.NetworkReceiveRates},
+			{"network_transmit_bytes_per_second", breakdown.NetworkTransmitRates},
+		} {
+			for _, series := range m.series {
+				add(m.metric, series.Label, series.Series)
+			}
+		}
+	}
+	if err := out.WriteAll(records); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// topView holds the settings of the interactive display
+// that can be changed with keyboard shortcuts.
+type topView struct {
+	sync.Mutex
+	// interval is the interval to display metrics for.
+	interval time.Duration
+	// minStep is the minimum time b/w two datapoints.
+	minStep time.Duration
+	// breakdown indicates whether per-node/namespace breakdown is displayed.
+	breakdown bool
+	// refreshC triggers an immediate refresh of the display.
+	refreshC chan struct{}
+}
+
+func newTopView(interval, step time.Duration) *topView {
+	return &topView{
+		interval: interval,
+		minStep:  step,
+		refreshC: make(chan struct{}, 1),
+	}
+}
+
+func (r *topView) setInterval(interval time.Duration) {
+	r.Lock()
+	r.interval = interval
+	r.Unlock()
+	r.refresh()
+}
+
+func (r *topView) toggleBreakdown() {
+	r.Lock()
+	r.breakdown = !r.breakdown
+	r.Unlock()
+	r.refresh()
+}
+
+func (r *topView) refresh() {
+	select {
+	case r.refreshC <- struct{}{}:
+	default:
+	}
+}
+
+// request returns the metrics request for the current view settings.
+// The step is scaled with the interval to limit the number of datapoints.
+func (r *topView) request(key ops.SiteKey) ops.ClusterMetricsRequest {
+	r.Lock()
+	defer r.Unlock()
+	step := r.interval / maxChartPoints
+	if step < r.minStep {
+		step = r.minStep
+	}
+	return ops.ClusterMetricsRequest{
+		SiteKey:   key,
+		Interval:  r.interval,
+		Step:      step,
+		Breakdown: r.breakdown,
+	}
+}
+
 // render continuously spins in a loop retrieving cluster metrics and rendering
 // terminal widgets at a certain interval.
-func render(env *localenv.LocalEnvironment, ctx context.Context, client monitoring.Metrics, interval, step time.Duration) {
+func render(env *localenv.LocalEnvironment, ctx context.Context, client monitoring.Metrics, view *topView) {
 	ticker := time.NewTicker(refreshInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			cluster, err := env.LocalCluster()
-			if err != nil {
-				log.Errorf(trace.DebugReport(err))
-				continue
-			}
-			metrics, err := opsservice.GetClusterMetrics(ctx, client, ops.ClusterMetricsRequest{
-				SiteKey:  cluster.Key(),
-				Interval: interval,
-				Step:     step,
-			})
-			if err != nil {
-				log.Errorf(trace.DebugReport(err))
-				continue
-			}
-			reRender(*cluster, *metrics)
+		case <-view.refreshC:
 		case <-ctx.Done():
 			return
 		}
+		cluster, err := env.LocalCluster()
+		if err != nil {
+			log.Errorf(trace.DebugReport(err))
+			continue
+		}
+		req := view.request(cluster.Key())
+		metrics, err := opsservice.GetClusterMetrics(ctx, client, req)
+		if err != nil {
+			log.Errorf(trace.DebugReport(err))
+			continue
+		}
+		if metrics.Breakdown != nil {
+			reRenderBreakdown(*cluster, req.Interval, *metrics)
+		} else {
+			reRender(*cluster, req.Interval, *metrics)
+		}
 	}
 }
 
 // reRender renders terminal widgets for the provided metrics data.
-func reRender(cluster ops.Site, metrics ops.ClusterMetricsResponse) {
-	var cpuData []float64
-	var cpuLabels []string
-	for _, point := range metrics.CPURates.Historic {
-		cpuData = append(cpuData, float64(point.Value))
-		cpuLabels = append(cpuLabels, point.Time.Format(constants.TimeFormat))
-	}
-
-	var ramData []float64
-	var ramLabels []string
-	for _, point := range metrics.MemoryRates.Historic {
-		ramData = append(ramData, float64(point.Value))
-		ramLabels = append(ramLabels, point.Time.Format(constants.TimeFormat))
-	}
+func reRender(cluster ops.Site, interval time.Duration, metrics ops.ClusterMetricsResponse) {
+	cpuData, cpuLabels := chartData(metrics.CPURates.Historic)
+	ramData, ramLabels := chartData(metrics.MemoryRates.Historic)
 
 	dim := getDimensions()
 
 	widgets := []termui.Bufferer{
-		getTitle(titleParams{
-			Title: fmt.Sprintf("Totals / Last Updated: %v",
-				time.Now().Format(constants.HumanDateFormatSeconds)),
-			Text: fmt.Sprintf("Nodes: %v\tCPU Cores: %v\tMemory: %v",
-				len(cluster.ClusterState.Servers),
-				metrics.TotalCPUCores,
-				humanize.Bytes(uint64(metrics.TotalMemoryBytes))),
-			H: dim.TitleH,
-			W: dim.TitleW,
-			X: 0,
-			Y: 0,
-		}),
+		getTitle(getTotalsTitle(cluster, interval, metrics, dim)),
 		getGauge(gaugeParams{
 			Title:   "Current CPU",
 			Percent: metrics.CPURates.Current,
@@ -178,6 +308,170 @@ func reRender(cluster ops.Site, metrics ops.ClusterMetricsResponse) {
 	termui.Render(widgets...)
 }
 
+// reRenderBreakdown renders terminal widgets with per-node and per-namespace
+// breakdown for the provided metrics data.
+func reRenderBreakdown(cluster ops.Site, interval time.Duration, metrics ops.ClusterMetricsResponse) {
+	breakdown := metrics.Breakdown
+	nodeRows := [][]string{{"Node", "CPU", "RAM", "Disk Read", "Disk Write", "Net Receive", "Net Transmit"}}
+	for _, series := range breakdown.NodeCPURates {
+		node := series.Label
+		nodeRows = append(nodeRows, []string{
+			node,
+			fmt.Sprintf("%v%%", series.Series.Last()),
+			fmt.Sprintf("%v%%", lastValue(breakdown.NodeMemoryRates, node)),
+			formatRate(lastValue(breakdown.DiskReadRates, node)),
+			formatRate(lastValue(breakdown.DiskWriteRates, node)),
+			formatRate(lastValue(breakdown.NetworkReceiveRates, node)),
+			formatRate(lastValue(breakdown.NetworkTransmitRates, node)),
+		})
+	}
+	namespaceRows := [][]string{{"Namespace", "CPU", "RAM"}}
+	for _, series := range breakdown.NamespaceCPUUsage {
+		namespace := series.Label
+		namespaceRows = append(namespaceRows, []string{
+			namespace,
+			fmt.Sprintf("%vm", series.Series.Last()),
+			humanize.Bytes(uint64(lastValue(breakdown.NamespaceMemoryUsage, namespace))),
+		})
+	}
+
+	dim := getDimensions()
+	nodesH := len(nodeRows) + 2
+	namespacesH := len(namespaceRows) + 2
+	chartY := dim.TitleH + nodesH + namespacesH
+	chartH := goterm.Height() - chartY
+	diskData, diskLabels := chartData(sumSeries(breakdown.DiskReadRates, breakdown.DiskWriteRates))
+	networkData, networkLabels := chartData(sumSeries(breakdown.NetworkReceiveRates, breakdown.NetworkTransmitRates))
+
+	widgets := []termui.Bufferer{
+		getTitle(getTotalsTitle(cluster, interval, metrics, dim)),
+		getTable(tableParams{
+			Title: "Nodes",
+			Rows:  nodeRows,
+			H:     nodesH,
+			W:     dim.TitleW,
+			X:     0,
+			Y:     dim.TitleH,
+		}),
+		getTable(tableParams{
+			Title: "Namespaces",
+			Rows:  namespaceRows,
+			H:     namespacesH,
+			W:     dim.TitleW,
+			X:     0,
+			Y:     dim.TitleH + nodesH,
+		}),
+	}
+	if chartH > 0 {
+		widgets = append(widgets,
+			getChart(chartParams{
+				Title:  "Disk I/O, bytes/s",
+				Data:   diskData,
+				Labels: diskLabels,
+				H:      chartH,
+				W:      dim.TitleW / 2,
+				X:      0,
+				Y:      chartY,
+			}),
+			getChart(chartParams{
+				Title:  "Network I/O, bytes/s",
+				Data:   networkData,
+				Labels: networkLabels,
+				H:      chartH,
+				W:      dim.TitleW - dim.TitleW/2,
+				X:      dim.TitleW / 2,
+				Y:      chartY,
+			}))
+	}
+
+	termui.Clear()
+	termui.Render(widgets...)
+}
+
+// getTotalsTitle returns parameters of the title widget with cluster totals.
+func getTotalsTitle(cluster ops.Site, interval time.Duration, metrics ops.ClusterMetricsResponse, dim Dimensions) titleParams {
+	return titleParams{
+		Title: fmt.Sprintf("Totals / Last %v / Last Updated: %v",
+			interval, time.Now().Format(constants.HumanDateFormatSeconds)),
+		Text: fmt.Sprintf("Nodes: %v\tCPU Cores: %v\tMemory: %v\n%v",
+			len(cluster.ClusterState.Servers),
+			metrics.TotalCPUCores,
+			humanize.Bytes(uint64(metrics.TotalMemoryBytes)),
+			topHelp),
+		H: dim.TitleH,
+		W: dim.TitleW,
+		X: 0,
+		Y: 0,
+	}
+}
+
+// lastValue returns the latest value of the series with the specified label.
+func lastValue(series []monitoring.LabeledSeries, label string) int {
+	for _, s := range series {
+		if s.Label == label {
+			return s.Series.Last()
+		}
+	}
+	return 0
+}
+
+// sumSeries returns a single series with values of all provided series
+// summed up at each timestamp.
+func sumSeries(series ...[]monitoring.LabeledSeries) (result monitoring.Series) {
+	sums := make(map[time.Time]int)
+	for _, list := range series {
+		for _, s := range list {
+			for _, point := range s.Series {
+				if _, ok := sums[point.Time]; !ok {
+					result = append(result, monitoring.Point{Time: point.Time})
+				}
+				sums[point.Time] += point.Value
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	for i := range result {
+		result[i].Value = sums[result[i].Time]
+	}
+	return result
+}
+
+// chartData returns chart data and labels for the provided series.
+func chartData(series monitoring.Series) (data []float64, labels []string) {
+	for _, point := range series {
+		data = append(data, float64(point.Value))
+		labels = append(labels, point.Time.Format(constants.TimeFormat))
+	}
+	return data, labels
+}
+
+// formatRate formats the provided throughput value in bytes per second.
+func formatRate(bytesPerSecond int) string {
+	return fmt.Sprintf("%v/s", humanize.Bytes(uint64(bytesPerSecond)))
+}
+
+type tableParams struct {
+	Title string
+	Rows  [][]string
+	H, W  int
+	X, Y  int
+}
+
+// getTable returns table widget with specified parameters.
+func getTable(p tableParams) *termui.Table {
+	table := termui.NewTable()
+	table.BorderLabel = p.Title
+	table.Rows = p.Rows
+	table.Separator = false
+	table.Height = p.H
+	table.Width = p.W
+	table.X = p.X
+	table.Y = p.Y
+	return table
+}
+
 type titleParams struct {
 	Title string
 	Text  string
@@ -282,5 +576,14 @@ func getColor(percent int) termui.Attribute {
 	return termui.ColorYellow
 }
 
-// refreshInterval is how often terminal widges are refreshed.
-const refreshInterval = 2 * time.Second
+const (
+	// refreshInterval is how often terminal widges are refreshed.
+	refreshInterval = 2 * time.Second
+	// maxChartPoints is the maximum number of datapoints requested for charts.
+	maxChartPoints = 240
+	// topHelp describes keyboard shortcuts of the interactive display.
+	topHelp = "[1-4] last 15m/1h/6h/24h  [b] toggle node/namespace breakdown  [q] quit"
+)
+
+// topIntervals lists the intervals selectable with keyboard shortcuts.
+var topIntervals = []time.Duration{15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// watchFunc starts a watch for cluster changes
+type watchFunc func(ctx context.Context) (<-chan storage.ClusterChange, error)
+
+// operatorWatch returns a watch for the cluster changes served by the operator
+func operatorWatch(operator ops.Operator, req ops.WatchClusterRequest) watchFunc {
+	return func(ctx context.Context) (<-chan storage.ClusterChange, error) {
+		return operator.WatchCluster(ctx, req)
+	}
+}
+
+// backendWatch returns a watch for the cluster changes stored in the backend
+func backendWatch(backend storage.Backend, prefix []string) watchFunc {
+	return func(ctx context.Context) (<-chan storage.ClusterChange, error) {
+		return storage.WatchClusterChanges(ctx, backend, prefix)
+	}
+}
+
+// followChanges invokes refresh once and then every time the watch reports
+// changes until either refresh returns true or the context is cancelled.
+//
+// Changes arriving in quick succession are coalesced into a single refresh.
+// If the watch stops, it is restarted after refreshing to pick up any changes
+// that might have been missed in between
+func followChanges(ctx context.Context, watch watchFunc, refresh func() (done bool, err error)) error {
+	for {
+		changesC, err := watch(ctx)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		done, err := refresh()
+		if err != nil || done {
+			return trace.Wrap(err)
+		}
+		for changesC != nil {
+			select {
+			case _, ok := <-changesC:
+				if !ok {
+					changesC = nil
+					break
+				}
+				if !drainChanges(ctx, changesC) {
+					changesC = nil
+				}
+				done, err := refresh()
+				if err != nil || done {
+					return trace.Wrap(err)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		select {
+		case <-time.After(defaults.WatchPollInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// drainChanges consumes the changes received within a short interval after
+// the first one. Returns false if the watch has been closed in the meantime
+func drainChanges(ctx context.Context, changesC <-chan storage.ClusterChange) bool {
+	timeout := time.After(defaults.WatchCoalesceInterval)
+	for {
+		select {
+		case _, ok := <-changesC:
+			if !ok {
+				return false
+			}
+		case <-timeout:
+			return true
+		case <-ctx.Done():
+			return true
+		}
+	}
+}
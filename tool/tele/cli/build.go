@@ -18,9 +18,15 @@ package cli
 
 import (
 	"context"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/builder"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/signing"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
@@ -44,6 +50,12 @@ type BuildParameters struct {
 	Verbose bool
 	// Insecure turns on insecure verify mode
 	Insecure bool
+	// SignKeyPath is the path to the private key to sign packages with
+	SignKeyPath string
+	// SignCertPath is the path to the certificate chain of the signing key
+	SignCertPath string
+	// Reproducible enables reproducible builds
+	Reproducible bool
 }
 
 // Level returns level at which the progress should be reported based on the CLI parameters.
@@ -58,6 +70,24 @@ func (p BuildParameters) Level() utils.ProgressLevel {
 
 // build builds an installer tarball according to the provided parameters
 func build(ctx context.Context, params BuildParameters, req service.VendorRequest) error {
+	var signer pack.Signer
+	if params.SignKeyPath != "" {
+		packageSigner, err := signing.NewSignerFromFiles(params.SignKeyPath, params.SignCertPath)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		signer = packageSigner
+	} else if params.SignCertPath != "" {
+		return trace.BadParameter("--sign-cert requires --sign-key")
+	}
+	var sourceDate time.Time
+	if params.Reproducible {
+		var err error
+		sourceDate, err = getSourceDate()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	installerBuilder, err := builder.New(builder.Config{
 		Context:          ctx,
 		StateDir:         params.StateDir,
@@ -68,6 +98,8 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 		SkipVersionCheck: params.SkipVersionCheck,
 		VendorReq:        req,
 		Level:            params.Level(),
+		Signer:           signer,
+		SourceDate:       sourceDate,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -75,3 +107,19 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 	defer installerBuilder.Close()
 	return builder.Build(ctx, installerBuilder)
 }
+
+// getSourceDate returns the time to use for timestamps in reproducible
+// builds: the Unix time from SOURCE_DATE_EPOCH environment variable or
+// the Unix epoch if the variable is not set
+func getSourceDate() (time.Time, error) {
+	value := os.Getenv(constants.EnvSourceDateEpoch)
+	if value == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, trace.BadParameter("invalid %v value %q, expected Unix time in seconds",
+			constants.EnvSourceDateEpoch, value)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
@@ -41,6 +41,12 @@ type Application struct {
 	ListCmd ListCmd
 	// PullCmd downloads app installer from Ops Center
 	PullCmd PullCmd
+	// SBOMCmd displays the software bill of materials of an image
+	SBOMCmd SBOMCmd
+	// DiffCmd compares two images
+	DiffCmd DiffCmd
+	// InspectCmd displays the contents of an image
+	InspectCmd InspectCmd
 }
 
 // VersionCmd outputs the binary version
@@ -83,6 +89,18 @@ type BuildCmd struct {
 	Set *[]string
 	// Values is a list of YAML files with Helm chart values.
 	Values *[]string
+	// SignKey is the path to the private key to sign packages with
+	SignKey *string
+	// SignCert is the path to the certificate chain of the signing key
+	SignCert *string
+	// Daemonless enables vendoring images without a Docker daemon
+	Daemonless *bool
+	// ImageSources lists local image layouts and archives to vendor images from
+	ImageSources *[]string
+	// InsecureRegistries lists registries to pull images from over plain HTTP
+	InsecureRegistries *[]string
+	// Reproducible enables reproducible builds
+	Reproducible *bool
 }
 
 type ListCmd struct {
@@ -107,3 +125,32 @@ type PullCmd struct {
 	// Quiet allows to suppress console output
 	Quiet *bool
 }
+
+// SBOMCmd displays the software bill of materials of an image
+type SBOMCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the image tarball
+	Path *string
+	// Format is the output format
+	Format *constants.Format
+}
+
+// DiffCmd compares two images
+type DiffCmd struct {
+	*kingpin.CmdClause
+	// Old is the path to the old image tarball
+	Old *string
+	// New is the path to the new image tarball
+	New *string
+	// Format is the output format
+	Format *constants.Format
+}
+
+// InspectCmd displays the contents of an image
+type InspectCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the image tarball
+	Path *string
+	// Format is the output format
+	Format *constants.Format
+}
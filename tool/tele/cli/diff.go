@@ -0,0 +1,44 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/gravitational/gravity/lib/app/diff"
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/gravitational/trace"
+)
+
+// diffImages outputs the differences between the image tarballs
+// at the specified paths
+func diffImages(oldPath, newPath string, format constants.Format) error {
+	old, err := diff.ReadImage(oldPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	new, err := diff.ReadImage(newPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result, err := diff.Compare(*old, *new)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(result.Write(os.Stdout, format))
+}
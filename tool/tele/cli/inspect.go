@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/gravitational/gravity/lib/app/inspect"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+
+	"github.com/gravitational/trace"
+)
+
+// inspectImage outputs the contents of the image tarball at the specified
+// path and verifies integrity of the packages in it
+func inspectImage(path string, format constants.Format) error {
+	env, err := localenv.NewTarballEnvironment(localenv.TarballEnvironmentArgs{
+		Tarball: path,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer env.Close()
+	manifest, err := env.Manifest()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	report, err := inspect.Inspect(inspect.Config{
+		Manifest: *manifest,
+		Packages: env.Packages,
+		Apps:     env.Apps,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := report.Write(os.Stdout, format); err != nil {
+		return trace.Wrap(err)
+	}
+	if !report.Verified() {
+		return trace.BadParameter("some packages in %v failed verification", path)
+	}
+	return nil
+}
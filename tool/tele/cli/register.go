@@ -56,6 +56,12 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.BuildCmd.Verbose = tele.BuildCmd.Flag("verbose", "Produce more detailed build output, can be useful for troubleshooting.").Short('v').Bool()
 	tele.BuildCmd.Set = tele.BuildCmd.Flag("set", "Set Helm chart values on the command line. Can be specified multiple times and/or as comma-separated values: key1=val1,key2=val2.").Strings()
 	tele.BuildCmd.Values = tele.BuildCmd.Flag("values", "Set Helm chart values from the provided YAML file. Can be specified multiple times.").Strings()
+	tele.BuildCmd.SignKey = tele.BuildCmd.Flag("sign-key", "Sign the image packages with the provided PEM-encoded Ed25519, RSA or ECDSA private key.").String()
+	tele.BuildCmd.SignCert = tele.BuildCmd.Flag("sign-cert", "PEM-encoded x509 certificate chain of the signing key, required for RSA and ECDSA keys.").String()
+	tele.BuildCmd.Daemonless = tele.BuildCmd.Flag("daemonless", "Vendor container images without a Docker daemon by fetching them directly from registries and image sources. Custom base images (systemOptions.baseImage) cannot be vendored in this mode.").Bool()
+	tele.BuildCmd.ImageSources = tele.BuildCmd.Flag("image-source", "OCI image layout directory or 'docker save' tarball to look up container images in before pulling them from registries. Implies --daemonless. Can be specified multiple times.").Strings()
+	tele.BuildCmd.InsecureRegistries = tele.BuildCmd.Flag("insecure-registry", "Registry to pull container images from over plain HTTP when vendoring without a Docker daemon. Can be specified multiple times.").Strings()
+	tele.BuildCmd.Reproducible = tele.BuildCmd.Flag("reproducible", fmt.Sprintf("Build the image reproducibly so that identical inputs produce an identical image. Timestamps are set to the Unix time in %v or to the Unix epoch if it is not set.", constants.EnvSourceDateEpoch)).Bool()
 
 	tele.ListCmd.CmdClause = app.Command("ls", "List cluster and application images published to Gravity Hub.")
 	tele.ListCmd.Runtimes = tele.ListCmd.Flag("runtimes", "Show only runtimes.").Short('r').Hidden().Bool()
@@ -68,5 +74,18 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.PullCmd.Force = tele.PullCmd.Flag("force", "Overwrite the existing image file.").Short('f').Bool()
 	tele.PullCmd.Quiet = tele.PullCmd.Flag("quiet", "Suppress any output to stdout.").Short('q').Bool()
 
+	tele.SBOMCmd.CmdClause = app.Command("sbom", "Display the software bill of materials of a cluster or application image.")
+	tele.SBOMCmd.Path = tele.SBOMCmd.Arg("path", "Path to the cluster or application image file.").Required().String()
+	tele.SBOMCmd.Format = common.Format(tele.SBOMCmd.Flag("format", "Output format: text or json.").Default(string(constants.EncodingText)))
+
+	tele.DiffCmd.CmdClause = app.Command("diff", "Display changes between two cluster or application images.")
+	tele.DiffCmd.Old = tele.DiffCmd.Arg("old", "Path to the old cluster or application image file.").Required().String()
+	tele.DiffCmd.New = tele.DiffCmd.Arg("new", "Path to the new cluster or application image file.").Required().String()
+	tele.DiffCmd.Format = common.Format(tele.DiffCmd.Flag("format", "Output format: text or json.").Default(string(constants.EncodingText)))
+
+	tele.InspectCmd.CmdClause = app.Command("inspect", "Display the contents of a cluster or application image and verify its packages.")
+	tele.InspectCmd.Path = tele.InspectCmd.Arg("path", "Path to the cluster or application image file.").Required().String()
+	tele.InspectCmd.Format = common.Format(tele.InspectCmd.Flag("format", "Output format: text or json.").Default(string(constants.EncodingText)))
+
 	return tele
 }
@@ -60,6 +60,9 @@ func Run(tele Application) error {
 			Silent:           *tele.BuildCmd.Quiet,
 			Verbose:          *tele.BuildCmd.Verbose,
 			Insecure:         *tele.Insecure,
+			SignKeyPath:      *tele.BuildCmd.SignKey,
+			SignCertPath:     *tele.BuildCmd.SignCert,
+			Reproducible:     *tele.BuildCmd.Reproducible,
 		}, service.VendorRequest{
 			PackageName:            *tele.BuildCmd.Name,
 			PackageVersion:         *tele.BuildCmd.Version,
@@ -73,7 +76,16 @@ func Run(tele Application) error {
 				Values: *tele.BuildCmd.Values,
 				Set:    *tele.BuildCmd.Set,
 			},
+			Daemonless:         *tele.BuildCmd.Daemonless || len(*tele.BuildCmd.ImageSources) != 0,
+			ImageSources:       *tele.BuildCmd.ImageSources,
+			InsecureRegistries: *tele.BuildCmd.InsecureRegistries,
 		})
+	case tele.SBOMCmd.FullCommand():
+		return printSBOM(*tele.SBOMCmd.Path, *tele.SBOMCmd.Format)
+	case tele.DiffCmd.FullCommand():
+		return diffImages(*tele.DiffCmd.Old, *tele.DiffCmd.New, *tele.DiffCmd.Format)
+	case tele.InspectCmd.FullCommand():
+		return inspectImage(*tele.InspectCmd.Path, *tele.InspectCmd.Format)
 	}
 
 	keystoreDir := *tele.StateDir
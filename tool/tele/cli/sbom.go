@@ -0,0 +1,41 @@
+/*
+Copyright 2020 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/sbom"
+
+	"github.com/gravitational/trace"
+)
+
+// printSBOM outputs the software bill of materials embedded into the
+// image tarball at the specified path
+func printSBOM(path string, format constants.Format) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	bom, err := sbom.FromTarball(f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(bom.Write(os.Stdout, format))
+}